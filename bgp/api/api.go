@@ -63,6 +63,18 @@ func SendBfdNotification(DestIp string, State bool, Oper config.Operation) {
 	}
 }
 
+/*  Send local EVPN VNI and MAC information from vxlan daemon to server
+ */
+func SendEVPNNotification(evpnInfo config.EVPNInfo) {
+	bgpapi.server.EVPNCh <- evpnInfo
+}
+
+/*  Send the IGP topology from the OSPF daemon to server
+ */
+func SendLinkStateNotification(linkStateInfo config.LinkStateInfo) {
+	bgpapi.server.LinkStateCh <- linkStateInfo
+}
+
 /*  Send interface state notification to server
  */
 func SendIntfNotification(ifIndex int32, ipAddr string, linklocalIp string, state config.Operation) {
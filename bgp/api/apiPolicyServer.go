@@ -88,3 +88,35 @@ func RemovePolicyDefinition(definitionName string) {
 func UpdatePolicyDefinition(definition utilspolicy.PolicyDefinitionConfig) {
 	return
 }
+
+func AddCommunityCondition(condition bgppolicy.CommunityConditionConfig) {
+	bgppolicyapi.policyManager.CommunityConditionCfgCh <- condition
+}
+
+func RemoveCommunityCondition(conditionName string) {
+	bgppolicyapi.policyManager.CommunityConditionDelCh <- conditionName
+}
+
+func AddCommunityAction(action bgppolicy.CommunityActionConfig) {
+	bgppolicyapi.policyManager.CommunityActionCfgCh <- action
+}
+
+func RemoveCommunityAction(actionName string) {
+	bgppolicyapi.policyManager.CommunityActionDelCh <- actionName
+}
+
+func AddRPKICondition(condition bgppolicy.RPKIConditionConfig) {
+	bgppolicyapi.policyManager.RPKIConditionCfgCh <- condition
+}
+
+func RemoveRPKICondition(conditionName string) {
+	bgppolicyapi.policyManager.RPKIConditionDelCh <- conditionName
+}
+
+func AddDampeningAction(action bgppolicy.DampeningActionConfig) {
+	bgppolicyapi.policyManager.DampeningActionCfgCh <- action
+}
+
+func RemoveDampeningAction(actionName string) {
+	bgppolicyapi.policyManager.DampeningActionDelCh <- actionName
+}
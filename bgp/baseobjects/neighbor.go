@@ -27,9 +27,11 @@ package base
 import (
 	"fmt"
 	"l3/bgp/config"
+	"l3/bgp/keychain"
 	"l3/bgp/packet"
 	"models/events"
 	"net"
+	"sync"
 	"time"
 	"utils/eventUtils"
 	"utils/logging"
@@ -45,9 +47,28 @@ type NeighborConf struct {
 	RunningConf          config.NeighborConfig
 	BGPId                net.IP
 	ASSize               uint8
+	RouteRefresh         bool
+	GracefulRestartCap   *packet.BGPCapGracefulRestart
+	RestartDeadline      time.Time
 	AfiSafiMap           map[uint32]bool
+	PeerAfiSafiMap       map[uint32]bool
+	ExtNextHopFamily     map[uint32]bool
+	ORFReceiveFamily     map[uint32]bool
+	AddPathsRxFamily     map[uint32]bool
+	AddPathsTxFamily     map[uint32]config.AddPathsConfig
+	SentOpen             *packet.BGPMessage
+	ReceivedOpen         *packet.BGPMessage
+	LastNotification     *packet.BGPMessage
+	LastNotificationSent bool
 	MaxPrefixesThreshold uint32
+	keyChain             *keychain.KeyChain
+	keyChainMutex        sync.RWMutex
 	ignoreBfdFaultsTimer *time.Timer
+	shutdownMutex        sync.Mutex
+	ceaseSubcode         uint8
+	shutdownMsg          string
+	gracefulShutdown     bool
+	adminShutdown        bool
 }
 
 func NewNeighborConf(logger *logging.Writer, globalConf *config.GlobalConfig, peerGroup *config.PeerGroupConfig,
@@ -57,6 +78,11 @@ func NewNeighborConf(logger *logging.Writer, globalConf *config.GlobalConfig, pe
 		Global:               globalConf,
 		Group:                peerGroup,
 		AfiSafiMap:           make(map[uint32]bool),
+		PeerAfiSafiMap:       make(map[uint32]bool),
+		ExtNextHopFamily:     make(map[uint32]bool),
+		ORFReceiveFamily:     make(map[uint32]bool),
+		AddPathsRxFamily:     make(map[uint32]bool),
+		AddPathsTxFamily:     make(map[uint32]config.AddPathsConfig),
 		BGPId:                net.IP{},
 		MaxPrefixesThreshold: 0,
 		RunningConf:          config.NeighborConfig{},
@@ -70,6 +96,10 @@ func NewNeighborConf(logger *logging.Writer, globalConf *config.GlobalConfig, pe
 	conf.SetNeighborState(&conf.RunningConf)
 	conf.setOtherStates()
 	conf.AfiSafiMap, _ = packet.GetProtocolFromConfig(&conf.Neighbor.AfiSafis, conf.Neighbor.NeighborAddress)
+	if conf.RunningConf.ExtendedNextHop && conf.RunningConf.PeerAddressType == config.PeerAddressV6 {
+		// IPv4 routes are carried over the IPv6 session with IPv6 next hops
+		conf.AfiSafiMap[packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)] = true
+	}
 	return &conf
 }
 
@@ -108,6 +138,8 @@ func (n *NeighborConf) SetNeighborState(peerConf *config.NeighborConfig) {
 		PeerGroup:               peerConf.PeerGroup,
 		AddPathsRx:              false,
 		AddPathsMaxTx:           0,
+		AddPathsTxMode:          peerConf.AddPathsTxMode,
+		AddPathsFamilies:        nil,
 		MaxPrefixes:             peerConf.MaxPrefixes,
 		MaxPrefixesThresholdPct: peerConf.MaxPrefixesThresholdPct,
 		MaxPrefixesDisconnect:   peerConf.MaxPrefixesDisconnect,
@@ -115,6 +147,27 @@ func (n *NeighborConf) SetNeighborState(peerConf *config.NeighborConfig) {
 		TotalPrefixes:           0,
 		AdjRIBInFilter:          peerConf.AdjRIBInFilter,
 		AdjRIBOutFilter:         peerConf.AdjRIBOutFilter,
+		EndOfRIBReceived:        make(map[uint32]bool),
+		EndOfRIBSent:            make(map[uint32]bool),
+		Dynamic:                 peerConf.Dynamic,
+		ExtendedNextHop:         false,
+		DefaultOriginate:        peerConf.DefaultOriginate,
+		DefaultOriginateMap:     peerConf.DefaultOriginateMap,
+		AdvertiseMap:            peerConf.AdvertiseMap,
+		ExistMap:                peerConf.ExistMap,
+		NonExistMap:             peerConf.NonExistMap,
+		ORFPrefixReceive:        false,
+		Dampening:               peerConf.Dampening,
+		KeyChain:                peerConf.KeyChain,
+		TCPAO:                   peerConf.TCPAO,
+		AuthType:                "",
+		ActiveKeyId:             -1,
+		TTLSecurity:             peerConf.TTLSecurity,
+		TTLSecurityHops:         peerConf.TTLSecurityHops,
+		TTLSecurityDrops:        0,
+		RouteServerClient:       peerConf.RouteServerClient,
+		GracefulShutdown:        n.gracefulShutdown,
+		AdminShutdown:           n.adminShutdown,
 	}
 	n.MaxPrefixesThreshold = uint32(float64(peerConf.MaxPrefixes*uint32(peerConf.MaxPrefixesThresholdPct)) / 100)
 }
@@ -130,6 +183,9 @@ func (n *NeighborConf) setOtherStates() {
 	} else {
 		n.Neighbor.State.BfdNeighborState = "down"
 	}
+	// Dynamic neighbors only wait for connections from the listen range
+	n.Neighbor.Transport.Config.PassiveMode = n.RunningConf.Dynamic
+	n.Neighbor.Transport.State.PassiveMode = n.RunningConf.Dynamic
 }
 
 func (n *NeighborConf) copyNonKeyNeighConfAttrs(nConf config.NeighborConfig) {
@@ -257,6 +313,14 @@ func (n *NeighborConf) GetConfFromNeighbor(inConf *config.NeighborConfig, outCon
 		outConf.AddPathsMaxTx = inConf.AddPathsMaxTx
 	}
 
+	if inConf.AddPathsTxMode != "" {
+		outConf.AddPathsTxMode = inConf.AddPathsTxMode
+	}
+
+	if inConf.AddPathsFamilies != nil {
+		outConf.AddPathsFamilies = inConf.AddPathsFamilies
+	}
+
 	if inConf.BfdEnable != false {
 		outConf.BfdEnable = inConf.BfdEnable
 	}
@@ -289,6 +353,58 @@ func (n *NeighborConf) GetConfFromNeighbor(inConf *config.NeighborConfig, outCon
 		outConf.AdjRIBOutFilter = inConf.AdjRIBOutFilter
 	}
 
+	if inConf.ExtendedNextHop != false {
+		outConf.ExtendedNextHop = inConf.ExtendedNextHop
+	}
+
+	if inConf.DefaultOriginate != false {
+		outConf.DefaultOriginate = inConf.DefaultOriginate
+	}
+
+	if inConf.DefaultOriginateMap != "" {
+		outConf.DefaultOriginateMap = inConf.DefaultOriginateMap
+	}
+
+	if inConf.AdvertiseMap != "" {
+		outConf.AdvertiseMap = inConf.AdvertiseMap
+	}
+
+	if inConf.ExistMap != "" {
+		outConf.ExistMap = inConf.ExistMap
+	}
+
+	if inConf.NonExistMap != "" {
+		outConf.NonExistMap = inConf.NonExistMap
+	}
+
+	if inConf.ORFPrefixReceive != false {
+		outConf.ORFPrefixReceive = inConf.ORFPrefixReceive
+	}
+
+	if inConf.Dampening != "" {
+		outConf.Dampening = inConf.Dampening
+	}
+
+	if inConf.KeyChain != "" {
+		outConf.KeyChain = inConf.KeyChain
+	}
+
+	if inConf.TCPAO != false {
+		outConf.TCPAO = inConf.TCPAO
+	}
+
+	if inConf.TTLSecurity != false {
+		outConf.TTLSecurity = inConf.TTLSecurity
+	}
+
+	if inConf.TTLSecurityHops != 0 {
+		outConf.TTLSecurityHops = inConf.TTLSecurityHops
+	}
+
+	if inConf.RouteServerClient != false {
+		outConf.RouteServerClient = inConf.RouteServerClient
+	}
+
 	n.setDefaults(outConf)
 	outConf.PeerAddressType = inConf.PeerAddressType
 	outConf.NeighborAddress = inConf.NeighborAddress
@@ -296,6 +412,7 @@ func (n *NeighborConf) GetConfFromNeighbor(inConf *config.NeighborConfig, outCon
 	outConf.IfName = inConf.IfName
 	outConf.PeerGroup = inConf.PeerGroup
 	outConf.Disabled = inConf.Disabled
+	outConf.Dynamic = inConf.Dynamic
 }
 
 func (n *NeighborConf) setDefaults(nConf *config.NeighborConfig) {
@@ -312,6 +429,10 @@ func (n *NeighborConf) setDefaults(nConf *config.NeighborConfig) {
 	if nConf.KeepaliveTime == 0 { // default keep alive time is 60 seconds
 		nConf.KeepaliveTime = nConf.HoldTime / 3
 	}
+
+	if nConf.TTLSecurity && nConf.TTLSecurityHops == 0 {
+		nConf.TTLSecurityHops = config.BGPTTLSecurityHopsDefault
+	}
 }
 
 func (n *NeighborConf) IsInternal() bool {
@@ -388,22 +509,189 @@ func (n *NeighborConf) SetPeerAttrs(bgpId net.IP, asSize uint8, holdTime uint32,
 	n.ASSize = asSize
 	n.Neighbor.State.HoldTime = holdTime
 	n.Neighbor.State.KeepaliveTime = keepaliveTime
+	n.AddPathsRxFamily = make(map[uint32]bool)
+	n.AddPathsTxFamily = make(map[uint32]config.AddPathsConfig)
+	n.Neighbor.State.AddPathsFamilies = make([]config.AddPathsConfig, 0)
 	for afi, safiMap := range addPathFamily {
-		if afi == packet.AfiIP || afi == packet.AfiIP6 {
-			for _, val := range safiMap {
-				if (val & packet.BGPCapAddPathRx) != 0 {
-					n.logger.Infof("SetPeerAttrs - Neighbor %s set add paths maxtx to %d",
-						n.Neighbor.NeighborAddress, n.RunningConf.AddPathsMaxTx)
-					n.Neighbor.State.AddPathsMaxTx = n.RunningConf.AddPathsMaxTx
-				}
-				if (val & packet.BGPCapAddPathTx) != 0 {
-					n.logger.Infof("SetPeerAttrs - Neighbor %s set add paths rx to %s",
-						n.Neighbor.NeighborAddress, n.RunningConf.AddPathsRx)
-					n.Neighbor.State.AddPathsRx = true
+		for safi, val := range safiMap {
+			protoFamily := packet.GetProtocolFamily(afi, safi)
+			if !isAddPathsFamily(protoFamily) || !n.AfiSafiMap[protoFamily] {
+				continue
+			}
+
+			addPathsConf := n.GetAddPathsConfig(protoFamily)
+			stateConf := config.AddPathsConfig{AfiSafiName: addPathsConf.AfiSafiName}
+			if (val&packet.BGPCapAddPathRx) != 0 && addPathsConf.MaxTx > 0 {
+				n.logger.Infof("SetPeerAttrs - Neighbor %s family %s set add paths maxtx to %d mode %s",
+					n.Neighbor.NeighborAddress, addPathsConf.AfiSafiName, addPathsConf.MaxTx, addPathsConf.TxMode)
+				n.AddPathsTxFamily[protoFamily] = addPathsConf
+				stateConf.MaxTx = addPathsConf.MaxTx
+				stateConf.TxMode = addPathsConf.TxMode
+				if addPathsConf.MaxTx > n.Neighbor.State.AddPathsMaxTx {
+					n.Neighbor.State.AddPathsMaxTx = addPathsConf.MaxTx
 				}
 			}
+			if (val&packet.BGPCapAddPathTx) != 0 && addPathsConf.Rx {
+				n.logger.Infof("SetPeerAttrs - Neighbor %s family %s set add paths rx",
+					n.Neighbor.NeighborAddress, addPathsConf.AfiSafiName)
+				n.AddPathsRxFamily[protoFamily] = true
+				stateConf.Rx = true
+				n.Neighbor.State.AddPathsRx = true
+			}
+			if stateConf.Rx || stateConf.MaxTx > 0 {
+				n.Neighbor.State.AddPathsFamilies = append(n.Neighbor.State.AddPathsFamilies, stateConf)
+			}
+		}
+	}
+}
+
+// Path ids are supported for the IPv4 and IPv6 unicast and multicast families
+func isAddPathsFamily(protoFamily uint32) bool {
+	afi, safi := packet.GetAfiSafi(protoFamily)
+	return (afi == packet.AfiIP || afi == packet.AfiIP6) && (safi == packet.SafiUnicast || safi == packet.SafiMulticast)
+}
+
+// GetAddPathsConfig returns the Add-Path configuration of the family. The neighbor's Add-Path configuration
+// applies to all the families when no family is configured.
+func (n *NeighborConf) GetAddPathsConfig(protoFamily uint32) config.AddPathsConfig {
+	addPathsConf := config.AddPathsConfig{AfiSafiName: packet.GetProtocolFamilyStr(protoFamily)}
+	if len(n.RunningConf.AddPathsFamilies) == 0 {
+		addPathsConf.Rx = n.RunningConf.AddPathsRx
+		addPathsConf.MaxTx = n.RunningConf.AddPathsMaxTx
+		addPathsConf.TxMode = n.RunningConf.AddPathsTxMode
+		// The neighbor's AddPathsMaxTx has always sent one path less than configured. The family MaxTx is the total
+		// number of paths, so it is converted to keep the number of paths sent with the existing configuration.
+		if addPathsConf.MaxTx > 1 {
+			addPathsConf.MaxTx--
+		}
+	} else {
+		found := false
+		for _, familyConf := range n.RunningConf.AddPathsFamilies {
+			if familyProto, ok := packet.ProtocolFamilyMap[familyConf.AfiSafiName]; ok && familyProto == protoFamily {
+				addPathsConf.Rx = familyConf.Rx
+				addPathsConf.MaxTx = familyConf.MaxTx
+				addPathsConf.TxMode = familyConf.TxMode
+				found = true
+				break
+			}
+		}
+		if !found {
+			addPathsConf.TxMode = config.AddPathsTxModeBestN
+			return addPathsConf
+		}
+		if addPathsConf.TxMode == "" {
+			addPathsConf.TxMode = n.RunningConf.AddPathsTxMode
+		}
+	}
+
+	switch addPathsConf.TxMode {
+	case config.AddPathsTxModeAll:
+		addPathsConf.MaxTx = config.AddPathsMaxTxAll
+	case config.AddPathsTxModeECMP:
+		if addPathsConf.MaxTx == 0 {
+			addPathsConf.MaxTx = config.AddPathsMaxTxAll
+		}
+	case config.AddPathsTxModeBestExternal:
+		// Best path and the best external path
+		addPathsConf.MaxTx = 2
+	default:
+		addPathsConf.TxMode = config.AddPathsTxModeBestN
+	}
+	return addPathsConf
+}
+
+// GetAddPathsFlags returns the Add-Path capability flags to send for the families of the neighbor
+func (n *NeighborConf) GetAddPathsFlags() map[uint32]uint8 {
+	addPathsFlags := make(map[uint32]uint8)
+	for protoFamily, ok := range n.AfiSafiMap {
+		if !ok || !isAddPathsFamily(protoFamily) {
+			continue
+		}
+
+		flags := uint8(0)
+		addPathsConf := n.GetAddPathsConfig(protoFamily)
+		if addPathsConf.Rx {
+			flags |= packet.BGPCapAddPathRx
+		}
+		if addPathsConf.MaxTx > 0 {
+			flags |= packet.BGPCapAddPathTx
+		}
+		if flags != 0 {
+			addPathsFlags[protoFamily] = flags
+		}
+	}
+	return addPathsFlags
+}
+
+func (n *NeighborConf) SetExtendedNextHop(peerExtNHFamily map[uint32]bool) {
+	n.ExtNextHopFamily = make(map[uint32]bool)
+	if n.RunningConf.ExtendedNextHop {
+		for protoFamily, _ := range peerExtNHFamily {
+			if n.AfiSafiMap[protoFamily] {
+				n.ExtNextHopFamily[protoFamily] = true
+			}
+		}
+	}
+	n.Neighbor.State.ExtendedNextHop = len(n.ExtNextHopFamily) > 0
+
+	ipv4Unicast := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
+	if n.RunningConf.PeerAddressType == config.PeerAddressV6 && !n.ExtNextHopFamily[ipv4Unicast] {
+		// IPv4 routes can't be carried on the IPv6 session without IPv6 next hops
+		delete(n.PeerAfiSafiMap, ipv4Unicast)
+	}
+}
+
+func (n *NeighborConf) SetORFPrefixReceive(peerORFFamily map[uint32]bool) {
+	n.ORFReceiveFamily = make(map[uint32]bool)
+	if n.RunningConf.ORFPrefixReceive {
+		for protoFamily, _ := range peerORFFamily {
+			if n.AfiSafiMap[protoFamily] {
+				n.ORFReceiveFamily[protoFamily] = true
+			}
 		}
 	}
+	n.Neighbor.State.ORFPrefixReceive = len(n.ORFReceiveFamily) > 0
+}
+
+func (n *NeighborConf) IsORFPrefixReceive(protoFamily uint32) bool {
+	return n.ORFReceiveFamily[protoFamily]
+}
+
+// SetKeyChain sets the key chain that authenticates the connections to the neighbor
+func (n *NeighborConf) SetKeyChain(keyChain *keychain.KeyChain) {
+	n.keyChainMutex.Lock()
+	defer n.keyChainMutex.Unlock()
+	n.keyChain = keyChain
+}
+
+func (n *NeighborConf) GetKeyChain() *keychain.KeyChain {
+	n.keyChainMutex.RLock()
+	defer n.keyChainMutex.RUnlock()
+	return n.keyChain
+}
+
+// IsAuthReady returns false if the key chain configured for the neighbor is not available yet, in which case no
+// connection should be made with the neighbor
+func (n *NeighborConf) IsAuthReady() bool {
+	return n.RunningConf.KeyChain == "" || n.GetKeyChain() != nil
+}
+
+func (n *NeighborConf) IsExtendedNextHop(protoFamily uint32) bool {
+	return n.ExtNextHopFamily[protoFamily]
+}
+
+// CanSendFamily returns false for IPv4 unicast on an IPv6 session without the extended next hop, since the
+// NEXT_HOP attribute can't carry the IPv6 address of the session
+func (n *NeighborConf) CanSendFamily(protoFamily uint32) bool {
+	if !n.AfiSafiMap[protoFamily] {
+		return false
+	}
+
+	if n.RunningConf.PeerAddressType == config.PeerAddressV6 && !n.ExtNextHopFamily[protoFamily] &&
+		protoFamily == packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast) {
+		return false
+	}
+	return true
 }
 
 func (n *NeighborConf) BfdFaultSet() {
@@ -426,15 +714,130 @@ func (n *NeighborConf) IgnoreBfdFaultsTimerExpired() {
 	n.Neighbor.State.UseBfdState = false
 }
 
+func (n *NeighborConf) GetNegotiatedAfiSafiMap() map[uint32]bool {
+	afiSafiMap := make(map[uint32]bool)
+	ipv4Unicast := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
+	for protoFamily, _ := range n.AfiSafiMap {
+		if n.PeerAfiSafiMap[protoFamily] || (len(n.PeerAfiSafiMap) == 0 && protoFamily == ipv4Unicast &&
+			n.RunningConf.PeerAddressType != config.PeerAddressV6) {
+			afiSafiMap[protoFamily] = true
+		}
+	}
+	return afiSafiMap
+}
+
+func (n *NeighborConf) SetEndOfRIBReceived(protoFamily uint32) {
+	n.Neighbor.State.EndOfRIBReceived[protoFamily] = true
+}
+
+func (n *NeighborConf) SetEndOfRIBSent(protoFamily uint32) {
+	n.Neighbor.State.EndOfRIBSent[protoFamily] = true
+}
+
+func (n *NeighborConf) IsEndOfRIBReceived() bool {
+	for protoFamily, _ := range n.GetNegotiatedAfiSafiMap() {
+		if !n.Neighbor.State.EndOfRIBReceived[protoFamily] {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *NeighborConf) resetEndOfRIB() {
+	n.Neighbor.State.EndOfRIBReceived = make(map[uint32]bool)
+	n.Neighbor.State.EndOfRIBSent = make(map[uint32]bool)
+}
+
 func (n *NeighborConf) PeerConnEstablished() {
 	n.Neighbor.State.UseBfdState = true
+	n.resetEndOfRIB()
+	n.LastNotification = nil
+	n.LastNotificationSent = false
+}
+
+func (n *NeighborConf) SetLastNotification(notification *packet.BGPMessage, sent bool) {
+	n.LastNotification = notification
+	n.LastNotificationSent = sent
+
+	body, ok := notification.Body.(*packet.BGPNotification)
+	if !ok {
+		return
+	}
+
+	shutdownMsg, err := body.GetShutdownCommunication()
+	if err != nil {
+		n.logger.Warningf("Neighbor %s failed to decode shutdown communication, error: %s",
+			n.RunningConf.NeighborAddress, err)
+	}
+	if sent {
+		n.Neighbor.State.LastErrorSent = body.String()
+		n.Neighbor.State.ShutdownMsgSent = shutdownMsg
+	} else {
+		n.Neighbor.State.LastErrorRcvd = body.String()
+		n.Neighbor.State.ShutdownMsgRcvd = shutdownMsg
+		if shutdownMsg != "" {
+			n.logger.Infof("Neighbor %s sent %s with shutdown communication \"%s\"",
+				n.RunningConf.NeighborAddress, body, shutdownMsg)
+		}
+	}
+}
+
+// SetShutdownCommunication sets the cease subcode and the shutdown communication sent in the NOTIFICATION when the
+// session is stopped next.
+func (n *NeighborConf) SetShutdownCommunication(subcode uint8, msg string) {
+	n.shutdownMutex.Lock()
+	defer n.shutdownMutex.Unlock()
+	n.ceaseSubcode = subcode
+	n.shutdownMsg = msg
+}
+
+// TakeShutdownCommunication returns the cease subcode and the shutdown communication and clears them, it is called
+// by the FSM when the NOTIFICATION is sent.
+func (n *NeighborConf) TakeShutdownCommunication() (uint8, string) {
+	n.shutdownMutex.Lock()
+	defer n.shutdownMutex.Unlock()
+	subcode, msg := n.ceaseSubcode, n.shutdownMsg
+	n.ceaseSubcode = 0
+	n.shutdownMsg = ""
+	return subcode, msg
+}
+
+func (n *NeighborConf) SetGracefulShutdown(enable bool) {
+	n.gracefulShutdown = enable
+	n.Neighbor.State.GracefulShutdown = enable
+}
+
+func (n *NeighborConf) IsGracefulShutdown() bool {
+	return n.gracefulShutdown
+}
+
+// SetAdminShutdown marks the neighbor as administratively down. The neighbor is not started again, by a reset or
+// a config update, till the admin shutdown is cleared.
+func (n *NeighborConf) SetAdminShutdown(enable bool) {
+	n.adminShutdown = enable
+	n.Neighbor.State.AdminShutdown = enable
+}
+
+func (n *NeighborConf) IsAdminShutdown() bool {
+	return n.adminShutdown
 }
 
 func (n *NeighborConf) PeerConnBroken() {
+	n.RouteRefresh = false
 	n.Neighbor.State.ConnectRetryTime = n.RunningConf.ConnectRetryTime
 	n.Neighbor.State.HoldTime = n.RunningConf.HoldTime
 	n.Neighbor.State.KeepaliveTime = n.RunningConf.KeepaliveTime
 	n.Neighbor.State.AddPathsRx = false
 	n.Neighbor.State.AddPathsMaxTx = 0
+	n.Neighbor.State.AddPathsFamilies = nil
+	n.AddPathsRxFamily = make(map[uint32]bool)
+	n.AddPathsTxFamily = make(map[uint32]config.AddPathsConfig)
 	n.Neighbor.State.TotalPrefixes = 0
+	n.Neighbor.State.ExtendedNextHop = false
+	n.ExtNextHopFamily = make(map[uint32]bool)
+	n.Neighbor.State.ORFPrefixReceive = false
+	n.ORFReceiveFamily = make(map[uint32]bool)
+	n.Neighbor.State.AuthType = ""
+	n.Neighbor.State.ActiveKeyId = -1
+	n.resetEndOfRIB()
 }
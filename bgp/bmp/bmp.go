@@ -0,0 +1,346 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// bmp.go
+package bmp
+
+import (
+	"encoding/binary"
+	"l3/bgp/packet"
+	"net"
+	"time"
+)
+
+const BMPVersion uint8 = 3
+
+const (
+	BMPCommonHeaderLen  = 6
+	BMPPerPeerHeaderLen = 42
+	BMPTLVHeaderLen     = 4
+	BMPAddressLen       = 16
+)
+
+const (
+	BMPMsgTypeRouteMonitoring uint8 = iota
+	BMPMsgTypeStatisticsReport
+	BMPMsgTypePeerDown
+	BMPMsgTypePeerUp
+	BMPMsgTypeInitiation
+	BMPMsgTypeTermination
+	BMPMsgTypeRouteMirroring
+)
+
+const (
+	BMPPeerTypeGlobal uint8 = iota
+	BMPPeerTypeRD
+	BMPPeerTypeLocal
+)
+
+const (
+	BMPPeerFlagASPath2Byte uint8 = 0x20
+	BMPPeerFlagPostPolicy  uint8 = 0x40
+	BMPPeerFlagIPv6        uint8 = 0x80
+)
+
+const (
+	_ uint8 = iota
+	BMPPeerDownLocalNotification
+	BMPPeerDownLocalNoNotification
+	BMPPeerDownRemoteNotification
+	BMPPeerDownRemoteNoNotification
+	BMPPeerDownDeconfigured
+)
+
+const (
+	BMPInfoTypeString uint16 = iota
+	BMPInfoTypeSysDescr
+	BMPInfoTypeSysName
+)
+
+const (
+	BMPTermTypeString uint16 = iota
+	BMPTermTypeReason
+)
+
+const (
+	BMPTermReasonAdminClose uint16 = iota
+	BMPTermReasonUnspecified
+	BMPTermReasonOutOfResources
+	BMPTermReasonRedundantConn
+	BMPTermReasonPermAdminClose
+)
+
+type BMPBody interface {
+	Encode() ([]byte, error)
+}
+
+type BMPCommonHeader struct {
+	Version uint8
+	Length  uint32
+	Type    uint8
+}
+
+func (header *BMPCommonHeader) Encode() ([]byte, error) {
+	pkt := make([]byte, BMPCommonHeaderLen)
+	pkt[0] = header.Version
+	binary.BigEndian.PutUint32(pkt[1:5], header.Length)
+	pkt[5] = header.Type
+	return pkt, nil
+}
+
+func encodeAddress(ip net.IP) []byte {
+	pkt := make([]byte, BMPAddressLen)
+	if ip.To4() != nil {
+		copy(pkt[12:], ip.To4())
+	} else if ip.To16() != nil {
+		copy(pkt, ip.To16())
+	}
+	return pkt
+}
+
+type BMPPerPeerHeader struct {
+	PeerType      uint8
+	Flags         uint8
+	Distinguisher uint64
+	Address       net.IP
+	AS            uint32
+	BGPId         net.IP
+	Timestamp     time.Time
+}
+
+func NewBMPPerPeerHeader(address net.IP, as uint32, bgpId net.IP, postPolicy bool, asSize uint8) *BMPPerPeerHeader {
+	header := &BMPPerPeerHeader{
+		PeerType:  BMPPeerTypeGlobal,
+		Address:   address,
+		AS:        as,
+		BGPId:     bgpId,
+		Timestamp: time.Now(),
+	}
+
+	if address.To4() == nil {
+		header.Flags |= BMPPeerFlagIPv6
+	}
+	if postPolicy {
+		header.Flags |= BMPPeerFlagPostPolicy
+	}
+	if asSize == 2 {
+		header.Flags |= BMPPeerFlagASPath2Byte
+	}
+	return header
+}
+
+func (header *BMPPerPeerHeader) Encode() ([]byte, error) {
+	pkt := make([]byte, BMPPerPeerHeaderLen)
+	pkt[0] = header.PeerType
+	pkt[1] = header.Flags
+	binary.BigEndian.PutUint64(pkt[2:10], header.Distinguisher)
+	copy(pkt[10:26], encodeAddress(header.Address))
+	binary.BigEndian.PutUint32(pkt[26:30], header.AS)
+	if header.BGPId.To4() != nil {
+		copy(pkt[30:34], header.BGPId.To4())
+	}
+	if !header.Timestamp.IsZero() {
+		binary.BigEndian.PutUint32(pkt[34:38], uint32(header.Timestamp.Unix()))
+		binary.BigEndian.PutUint32(pkt[38:42], uint32(header.Timestamp.Nanosecond()/1000))
+	}
+	return pkt, nil
+}
+
+type BMPTLV struct {
+	Type  uint16
+	Value []byte
+}
+
+func (tlv *BMPTLV) Encode() ([]byte, error) {
+	pkt := make([]byte, BMPTLVHeaderLen, BMPTLVHeaderLen+len(tlv.Value))
+	binary.BigEndian.PutUint16(pkt[0:2], tlv.Type)
+	binary.BigEndian.PutUint16(pkt[2:4], uint16(len(tlv.Value)))
+	pkt = append(pkt, tlv.Value...)
+	return pkt, nil
+}
+
+func encodeTLVs(tlvs []BMPTLV) ([]byte, error) {
+	pkt := make([]byte, 0)
+	for i := 0; i < len(tlvs); i++ {
+		bytes, err := tlvs[i].Encode()
+		if err != nil {
+			return nil, err
+		}
+		pkt = append(pkt, bytes...)
+	}
+	return pkt, nil
+}
+
+type BMPRouteMonitoring struct {
+	PeerHeader BMPPerPeerHeader
+	Update     *packet.BGPMessage
+}
+
+func (msg *BMPRouteMonitoring) Encode() ([]byte, error) {
+	pkt, err := msg.PeerHeader.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	// The received update may have been modified when decoded, don't rely on the length in its header
+	body, err := msg.Update.Body.Encode()
+	if err != nil {
+		return nil, err
+	}
+	header := packet.BGPHeader{Length: packet.BGPMsgHeaderLen + uint16(len(body)), Type: msg.Update.Header.Type}
+	bytes, _ := header.Encode()
+	pkt = append(pkt, bytes...)
+	return append(pkt, body...), nil
+}
+
+type BMPPeerUp struct {
+	PeerHeader   BMPPerPeerHeader
+	LocalAddress net.IP
+	LocalPort    uint16
+	RemotePort   uint16
+	SentOpen     *packet.BGPMessage
+	ReceivedOpen *packet.BGPMessage
+}
+
+func (msg *BMPPeerUp) Encode() ([]byte, error) {
+	pkt, err := msg.PeerHeader.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	pkt = append(pkt, encodeAddress(msg.LocalAddress)...)
+	ports := make([]byte, 4)
+	binary.BigEndian.PutUint16(ports[0:2], msg.LocalPort)
+	binary.BigEndian.PutUint16(ports[2:4], msg.RemotePort)
+	pkt = append(pkt, ports...)
+
+	for _, openMsg := range []*packet.BGPMessage{msg.SentOpen, msg.ReceivedOpen} {
+		bytes, err := openMsg.Encode()
+		if err != nil {
+			return nil, err
+		}
+		pkt = append(pkt, bytes...)
+	}
+	return pkt, nil
+}
+
+type BMPPeerDown struct {
+	PeerHeader BMPPerPeerHeader
+	Reason     uint8
+	Data       []byte
+}
+
+func (msg *BMPPeerDown) Encode() ([]byte, error) {
+	pkt, err := msg.PeerHeader.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	pkt = append(pkt, msg.Reason)
+	pkt = append(pkt, msg.Data...)
+	return pkt, nil
+}
+
+type BMPInitiation struct {
+	TLVs []BMPTLV
+}
+
+func (msg *BMPInitiation) Encode() ([]byte, error) {
+	return encodeTLVs(msg.TLVs)
+}
+
+type BMPTermination struct {
+	TLVs []BMPTLV
+}
+
+func (msg *BMPTermination) Encode() ([]byte, error) {
+	return encodeTLVs(msg.TLVs)
+}
+
+type BMPMessage struct {
+	Header BMPCommonHeader
+	Body   BMPBody
+}
+
+func (msg *BMPMessage) Encode() ([]byte, error) {
+	body, err := msg.Body.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Header.Length = uint32(BMPCommonHeaderLen + len(body))
+	header, err := msg.Header.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return append(header, body...), nil
+}
+
+func NewBMPRouteMonitoringMessage(peerHeader *BMPPerPeerHeader, update *packet.BGPMessage) *BMPMessage {
+	return &BMPMessage{
+		Header: BMPCommonHeader{Version: BMPVersion, Type: BMPMsgTypeRouteMonitoring},
+		Body:   &BMPRouteMonitoring{*peerHeader, update},
+	}
+}
+
+func NewBMPPeerUpMessage(peerHeader *BMPPerPeerHeader, localAddress net.IP, localPort, remotePort uint16,
+	sentOpen, receivedOpen *packet.BGPMessage) *BMPMessage {
+	return &BMPMessage{
+		Header: BMPCommonHeader{Version: BMPVersion, Type: BMPMsgTypePeerUp},
+		Body:   &BMPPeerUp{*peerHeader, localAddress, localPort, remotePort, sentOpen, receivedOpen},
+	}
+}
+
+func NewBMPPeerDownMessage(peerHeader *BMPPerPeerHeader, reason uint8, data []byte) *BMPMessage {
+	return &BMPMessage{
+		Header: BMPCommonHeader{Version: BMPVersion, Type: BMPMsgTypePeerDown},
+		Body:   &BMPPeerDown{*peerHeader, reason, data},
+	}
+}
+
+func NewBMPPeerDownFSMEventMessage(peerHeader *BMPPerPeerHeader, fsmEvent uint16) *BMPMessage {
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, fsmEvent)
+	return NewBMPPeerDownMessage(peerHeader, BMPPeerDownLocalNoNotification, data)
+}
+
+func NewBMPInitiationMessage(sysName, sysDescr string) *BMPMessage {
+	return &BMPMessage{
+		Header: BMPCommonHeader{Version: BMPVersion, Type: BMPMsgTypeInitiation},
+		Body: &BMPInitiation{
+			TLVs: []BMPTLV{
+				BMPTLV{BMPInfoTypeSysDescr, []byte(sysDescr)},
+				BMPTLV{BMPInfoTypeSysName, []byte(sysName)},
+			},
+		},
+	}
+}
+
+func NewBMPTerminationMessage(reason uint16) *BMPMessage {
+	value := make([]byte, 2)
+	binary.BigEndian.PutUint16(value, reason)
+	return &BMPMessage{
+		Header: BMPCommonHeader{Version: BMPVersion, Type: BMPMsgTypeTermination},
+		Body:   &BMPTermination{TLVs: []BMPTLV{BMPTLV{BMPTermTypeReason, value}}},
+	}
+}
@@ -0,0 +1,188 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// bmp_test.go
+package bmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"l3/bgp/packet"
+	"net"
+	"testing"
+	"utils/logging"
+)
+
+func TestBMPPerPeerHeader(t *testing.T) {
+	header := NewBMPPerPeerHeader(net.ParseIP("10.1.1.1"), 65001, net.ParseIP("1.1.1.1"), true, 2)
+	pkt, err := header.Encode()
+	if err != nil {
+		t.Fatal("BMPPerPeerHeader.Encode failed with error", err)
+	}
+
+	if len(pkt) != BMPPerPeerHeaderLen {
+		t.Fatal("Per peer header length expected", BMPPerPeerHeaderLen, "got", len(pkt))
+	}
+	if pkt[1] != BMPPeerFlagPostPolicy|BMPPeerFlagASPath2Byte {
+		t.Error("Per peer header flags expected", BMPPeerFlagPostPolicy|BMPPeerFlagASPath2Byte, "got", pkt[1])
+	}
+	if !bytes.Equal(pkt[22:26], []byte{10, 1, 1, 1}) || !bytes.Equal(pkt[10:22], make([]byte, 12)) {
+		t.Error("Per peer header IPv4 address is not encoded in the last 4 bytes, got", pkt[10:26])
+	}
+	if binary.BigEndian.Uint32(pkt[26:30]) != 65001 {
+		t.Error("Per peer header AS expected 65001, got", binary.BigEndian.Uint32(pkt[26:30]))
+	}
+	if !bytes.Equal(pkt[30:34], []byte{1, 1, 1, 1}) {
+		t.Error("Per peer header BGP id expected 1.1.1.1, got", pkt[30:34])
+	}
+
+	header = NewBMPPerPeerHeader(net.ParseIP("2001:db8::1"), 65001, net.ParseIP("1.1.1.1"), false, 4)
+	pkt, _ = header.Encode()
+	if pkt[1] != BMPPeerFlagIPv6 {
+		t.Error("Per peer header flags expected", BMPPeerFlagIPv6, "got", pkt[1])
+	}
+	if !bytes.Equal(pkt[10:26], net.ParseIP("2001:db8::1").To16()) {
+		t.Error("Per peer header IPv6 address expected 2001:db8::1, got", pkt[10:26])
+	}
+}
+
+func TestBMPRouteMonitoringMessage(t *testing.T) {
+	pathAttrs := packet.ConstructPathAttrForConnRoutes(65001)
+	nlri := []packet.NLRI{packet.NewIPPrefix(net.ParseIP("20.1.1.0").To4(), 24)}
+	update := packet.NewBGPUpdateMessage(nil, pathAttrs, nlri)
+	updatePkt, err := update.Clone().Encode()
+	if err != nil {
+		t.Fatal("BGPMessage.Encode failed with error", err)
+	}
+
+	header := NewBMPPerPeerHeader(net.ParseIP("10.1.1.1"), 65001, net.ParseIP("1.1.1.1"), false, 4)
+	pkt, err := NewBMPRouteMonitoringMessage(header, update).Encode()
+	if err != nil {
+		t.Fatal("BMPMessage.Encode failed with error", err)
+	}
+
+	expectedLen := BMPCommonHeaderLen + BMPPerPeerHeaderLen + len(updatePkt)
+	if pkt[0] != BMPVersion || pkt[5] != BMPMsgTypeRouteMonitoring {
+		t.Error("Common header expected version", BMPVersion, "type", BMPMsgTypeRouteMonitoring, "got", pkt[0], pkt[5])
+	}
+	if len(pkt) != expectedLen || binary.BigEndian.Uint32(pkt[1:5]) != uint32(expectedLen) {
+		t.Error("Route monitoring message length expected", expectedLen, "got", len(pkt),
+			binary.BigEndian.Uint32(pkt[1:5]))
+	}
+	if !bytes.Equal(pkt[BMPCommonHeaderLen+BMPPerPeerHeaderLen:], updatePkt) {
+		t.Error("Route monitoring message does not carry the BGP update")
+	}
+}
+
+func TestBMPPeerUpMessage(t *testing.T) {
+	optParams := packet.ConstructOptParams(65001, map[uint32]bool{}, false, 0, nil, false)
+	sentOpen := packet.NewBGPOpenMessage(65001, 180, "1.1.1.1", optParams)
+	rcvdOpen := packet.NewBGPOpenMessage(65002, 90, "2.2.2.2", optParams)
+	sentPkt, _ := sentOpen.Clone().Encode()
+	rcvdPkt, _ := rcvdOpen.Clone().Encode()
+
+	header := NewBMPPerPeerHeader(net.ParseIP("10.1.1.2"), 65002, net.ParseIP("2.2.2.2"), false, 4)
+	pkt, err := NewBMPPeerUpMessage(header, net.ParseIP("10.1.1.1"), 179, 54321, sentOpen, rcvdOpen).Encode()
+	if err != nil {
+		t.Fatal("BMPMessage.Encode failed with error", err)
+	}
+
+	offset := BMPCommonHeaderLen + BMPPerPeerHeaderLen
+	if !bytes.Equal(pkt[offset+12:offset+16], []byte{10, 1, 1, 1}) {
+		t.Error("Peer up local address expected 10.1.1.1, got", pkt[offset:offset+16])
+	}
+	offset += BMPAddressLen
+	if binary.BigEndian.Uint16(pkt[offset:offset+2]) != 179 || binary.BigEndian.Uint16(pkt[offset+2:offset+4]) != 54321 {
+		t.Error("Peer up ports expected 179 and 54321, got", pkt[offset:offset+4])
+	}
+	offset += 4
+	if !bytes.Equal(pkt[offset:], append(sentPkt, rcvdPkt...)) {
+		t.Error("Peer up message does not carry the sent and received OPEN messages")
+	}
+}
+
+func TestBMPPeerDownMessage(t *testing.T) {
+	header := NewBMPPerPeerHeader(net.ParseIP("10.1.1.2"), 65002, net.ParseIP("2.2.2.2"), false, 4)
+	pkt, _ := NewBMPPeerDownFSMEventMessage(header, 0).Encode()
+	offset := BMPCommonHeaderLen + BMPPerPeerHeaderLen
+	if pkt[5] != BMPMsgTypePeerDown || len(pkt) != offset+3 {
+		t.Fatal("Peer down message type expected", BMPMsgTypePeerDown, "length", offset+3, "got", pkt[5], len(pkt))
+	}
+	if pkt[offset] != BMPPeerDownLocalNoNotification {
+		t.Error("Peer down reason expected", BMPPeerDownLocalNoNotification, "got", pkt[offset])
+	}
+
+	notification := packet.NewBGPNotificationMessage(packet.BGPCease, 0, nil)
+	data, _ := notification.Encode()
+	pkt, _ = NewBMPPeerDownMessage(header, BMPPeerDownRemoteNotification, data).Encode()
+	if pkt[offset] != BMPPeerDownRemoteNotification || !bytes.Equal(pkt[offset+1:], data) {
+		t.Error("Peer down message does not carry the received notification, got", pkt[offset:])
+	}
+}
+
+func TestBMPInitiationAndTerminationMessages(t *testing.T) {
+	pkt, _ := NewBMPInitiationMessage("router1", "bgpd").Encode()
+	expected := []byte{0, 1, 0, 4, 'b', 'g', 'p', 'd', 0, 2, 0, 7, 'r', 'o', 'u', 't', 'e', 'r', '1'}
+	if pkt[5] != BMPMsgTypeInitiation || !bytes.Equal(pkt[BMPCommonHeaderLen:], expected) {
+		t.Error("Initiation message TLVs expected", expected, "got", pkt[BMPCommonHeaderLen:])
+	}
+
+	pkt, _ = NewBMPTerminationMessage(BMPTermReasonAdminClose).Encode()
+	expected = []byte{0, 1, 0, 2, 0, 0}
+	if pkt[5] != BMPMsgTypeTermination || !bytes.Equal(pkt[BMPCommonHeaderLen:], expected) {
+		t.Error("Termination message TLVs expected", expected, "got", pkt[BMPCommonHeaderLen:])
+	}
+}
+
+func TestBMPStationQueueOverflow(t *testing.T) {
+	logger, err := logging.NewLogger("bgpd", "BGP", true)
+	if err != nil {
+		t.Fatal("Failed to create logger with error", err)
+	}
+
+	station := NewBMPStation(logger, "127.0.0.1:5000", nil, make(chan string))
+	for i := 0; i < BMPStationMsgQueueSize; i++ {
+		station.Send([]byte{byte(i)})
+	}
+	select {
+	case <-station.overflowCh:
+		t.Fatal("Station queue overflowed before it was full")
+	default:
+	}
+
+	station.Send([]byte{0})
+	station.Send([]byte{1})
+	select {
+	case <-station.overflowCh:
+	default:
+		t.Fatal("Station queue overflow did not reset the connection")
+	}
+	if len(station.msgCh) != BMPStationMsgQueueSize {
+		t.Error("Station queue expected", BMPStationMsgQueueSize, "messages, got", len(station.msgCh))
+	}
+
+	station.drain()
+	if len(station.msgCh) != 0 || len(station.overflowCh) != 0 {
+		t.Error("Station queue is not empty after drain")
+	}
+}
@@ -0,0 +1,130 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// manager.go
+package bmp
+
+import (
+	"l3/bgp/config"
+	"net"
+	"strconv"
+	"utils/logging"
+)
+
+type BMPManager struct {
+	logger      *logging.Writer
+	sysName     string
+	sysDescr    string
+	stations    map[string]*BMPStation
+	StationUpCh chan string
+}
+
+func NewBMPManager(logger *logging.Writer, sysName string, sysDescr string) *BMPManager {
+	return &BMPManager{
+		logger:      logger,
+		sysName:     sysName,
+		sysDescr:    sysDescr,
+		stations:    make(map[string]*BMPStation),
+		StationUpCh: make(chan string),
+	}
+}
+
+func (m *BMPManager) SetStations(stations []config.BMPStation) {
+	addrMap := make(map[string]bool)
+	for _, station := range stations {
+		if net.ParseIP(station.Address) == nil || station.Port == 0 {
+			m.logger.Errf("BMP station %s port %d is not valid", station.Address, station.Port)
+			continue
+		}
+		addrMap[net.JoinHostPort(station.Address, strconv.Itoa(int(station.Port)))] = true
+	}
+
+	for addr, station := range m.stations {
+		if !addrMap[addr] {
+			m.logger.Infof("Stop BMP station %s", addr)
+			station.Stop()
+			delete(m.stations, addr)
+		}
+	}
+
+	initMsg, err := NewBMPInitiationMessage(m.sysName, m.sysDescr).Encode()
+	if err != nil {
+		m.logger.Errf("Failed to encode BMP initiation message with error %s", err)
+		return
+	}
+
+	for addr, _ := range addrMap {
+		if _, ok := m.stations[addr]; !ok {
+			m.logger.Infof("Start BMP station %s", addr)
+			m.stations[addr] = NewBMPStation(m.logger, addr, initMsg, m.StationUpCh)
+			m.stations[addr].Start()
+		}
+	}
+}
+
+func (m *BMPManager) IsEnabled() bool {
+	return len(m.stations) > 0
+}
+
+func (m *BMPManager) Send(msg *BMPMessage) {
+	if len(m.stations) == 0 {
+		return
+	}
+
+	pkt, err := msg.Encode()
+	if err != nil {
+		m.logger.Errf("Failed to encode BMP message type %d with error %s", msg.Header.Type, err)
+		return
+	}
+
+	for _, station := range m.stations {
+		station.Send(pkt)
+	}
+}
+
+// SendToStation queues the messages to a single station as one write, so a table dump takes one slot in the
+// station's queue
+func (m *BMPManager) SendToStation(addr string, msgs []*BMPMessage) {
+	station, ok := m.stations[addr]
+	if !ok || len(msgs) == 0 {
+		return
+	}
+
+	pkts := make([]byte, 0)
+	for _, msg := range msgs {
+		pkt, err := msg.Encode()
+		if err != nil {
+			m.logger.Errf("Failed to encode BMP message type %d with error %s", msg.Header.Type, err)
+			continue
+		}
+		pkts = append(pkts, pkt...)
+	}
+	station.Send(pkts)
+}
+
+func (m *BMPManager) Stop() {
+	for addr, station := range m.stations {
+		station.Stop()
+		delete(m.stations, addr)
+	}
+}
@@ -0,0 +1,169 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// station.go
+package bmp
+
+import (
+	"net"
+	"time"
+	"utils/logging"
+)
+
+const (
+	BMPStationConnectTimeout     = 10 // seconds
+	BMPStationReconnectTime      = 30 // seconds
+	BMPStationMsgQueueSize   int = 1024
+)
+
+type BMPStation struct {
+	logger     *logging.Writer
+	Address    string
+	initMsg    []byte
+	upCh       chan string
+	msgCh      chan []byte
+	overflowCh chan bool
+	stopCh     chan bool
+}
+
+func NewBMPStation(logger *logging.Writer, address string, initMsg []byte, upCh chan string) *BMPStation {
+	return &BMPStation{
+		logger:     logger,
+		Address:    address,
+		initMsg:    initMsg,
+		upCh:       upCh,
+		msgCh:      make(chan []byte, BMPStationMsgQueueSize),
+		overflowCh: make(chan bool, 1),
+		stopCh:     make(chan bool),
+	}
+}
+
+func (s *BMPStation) Start() {
+	go s.run()
+}
+
+func (s *BMPStation) Stop() {
+	close(s.stopCh)
+}
+
+// Send never blocks the caller, messages are dropped when the station is down. When the queue is full the
+// message is dropped and the connection is reset, the collector gets the full table again when it reconnects.
+func (s *BMPStation) Send(pkt []byte) {
+	select {
+	case s.msgCh <- pkt:
+	default:
+		select {
+		case s.overflowCh <- true:
+			s.logger.Warningf("BMP station %s: message queue is full, dropping message and resetting the connection",
+				s.Address)
+		default:
+		}
+	}
+}
+
+func (s *BMPStation) drain() {
+	for {
+		select {
+		case <-s.msgCh:
+		case <-s.overflowCh:
+		default:
+			return
+		}
+	}
+}
+
+func (s *BMPStation) run() {
+	reconnectTimer := time.NewTimer(0)
+	for {
+		select {
+		case <-reconnectTimer.C:
+			conn, err := net.DialTimeout("tcp", s.Address, BMPStationConnectTimeout*time.Second)
+			if err != nil {
+				s.logger.Infof("BMP station %s: connect failed with error %s, retry in %d seconds", s.Address,
+					err, BMPStationReconnectTime)
+				reconnectTimer.Reset(BMPStationReconnectTime * time.Second)
+				continue
+			}
+
+			s.logger.Infof("BMP station %s: connected", s.Address)
+			if stop := s.serve(conn); stop {
+				return
+			}
+			reconnectTimer.Reset(BMPStationReconnectTime * time.Second)
+
+		case <-s.msgCh:
+			// Drop messages while the station is not connected
+
+		case <-s.stopCh:
+			reconnectTimer.Stop()
+			return
+		}
+	}
+}
+
+func (s *BMPStation) serve(conn net.Conn) bool {
+	defer conn.Close()
+
+	s.drain()
+	if _, err := conn.Write(s.initMsg); err != nil {
+		s.logger.Errf("BMP station %s: failed to send initiation message with error %s", s.Address, err)
+		return false
+	}
+	select {
+	case s.upCh <- s.Address:
+	case <-s.stopCh:
+		return true
+	}
+
+	// Collectors don't send any messages, a read returns only when the connection is closed
+	closeCh := make(chan bool, 1)
+	go func() {
+		buf := make([]byte, 1)
+		conn.Read(buf)
+		closeCh <- true
+	}()
+
+	for {
+		select {
+		case pkt := <-s.msgCh:
+			if _, err := conn.Write(pkt); err != nil {
+				s.logger.Errf("BMP station %s: write failed with error %s", s.Address, err)
+				return false
+			}
+
+		case <-closeCh:
+			s.logger.Infof("BMP station %s: connection closed by the collector", s.Address)
+			return false
+
+		case <-s.overflowCh:
+			s.logger.Errf("BMP station %s: message queue overflowed, closing the connection", s.Address)
+			return false
+
+		case <-s.stopCh:
+			if pkt, err := NewBMPTerminationMessage(BMPTermReasonAdminClose).Encode(); err == nil {
+				conn.Write(pkt)
+			}
+			return true
+		}
+	}
+}
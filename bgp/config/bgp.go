@@ -34,20 +34,103 @@ type SourcePolicyMap struct {
 	Policy  string
 }
 
+type BMPStation struct {
+	Address string
+	Port    uint16
+}
+
+type MRTConfig struct {
+	DumpDir        string
+	DumpInterval   uint32
+	LogUpdates     bool
+	RotateInterval uint32
+}
+
+type RPKICacheServer struct {
+	Address string
+	Port    uint16
+}
+
+type RPKIConfig struct {
+	CacheServers []RPKICacheServer
+	PreferValid  bool
+}
+
+// BestPathConfig has the best path selection options. The default is the selection without the MED step, the
+// router id tie-break for eBGP paths and ECMP across the paths with the same AS path length.
+type BestPathConfig struct {
+	CompareMED           bool // compare the MEDs, the other MED options apply only when it is set
+	AlwaysCompareMED     bool
+	DeterministicMED     bool
+	MEDMissingAsWorst    bool
+	ASPathIgnore         bool
+	MultipathSameASPath  bool // ECMP only across the paths with the same AS path as the best path
+	ASPathMultipathRelax bool
+	PreferOldestEBGP     bool // keep the oldest eBGP path instead of comparing the router ids
+	CompareRouterId      bool
+}
+
+type DampeningConfig struct {
+	HalfLife          uint32
+	ReuseThreshold    uint32
+	SuppressThreshold uint32
+	MaxSuppressTime   uint32
+}
+
+const (
+	AddPathsTxModeBestN        = "best-n"
+	AddPathsTxModeAll          = "all"
+	AddPathsTxModeECMP         = "ecmp"
+	AddPathsTxModeBestExternal = "best-external"
+)
+
+const AddPathsMaxTxAll uint8 = 255
+
+type AddPathsConfig struct {
+	AfiSafiName string
+	Rx          bool
+	MaxTx       uint8
+	TxMode      string
+}
+
+type KeyChainKey struct {
+	KeyId               uint8
+	Secret              string
+	Algorithm           string
+	SendLifetimeStart   time.Time
+	SendLifetimeEnd     time.Time
+	AcceptLifetimeStart time.Time
+	AcceptLifetimeEnd   time.Time
+}
+
+type KeyChainConfig struct {
+	Name string
+	Keys []KeyChainKey
+}
+
 type GlobalBase struct {
-	Vrf                 string
-	AS                  uint32
-	RouterId            net.IP
-	Disabled            bool
-	UseMultiplePaths    bool
-	EBGPMaxPaths        uint32
-	EBGPAllowMultipleAS bool
-	IBGPMaxPaths        uint32
+	Vrf                     string
+	AS                      uint32
+	RouterId                net.IP
+	Disabled                bool
+	UseMultiplePaths        bool
+	EBGPMaxPaths            uint32
+	EBGPAllowMultipleAS     bool
+	IBGPMaxPaths            uint32
+	GracefulRestart         bool
+	RestartTime             uint32
+	StalePathTime           uint32
+	PreserveForwardingState bool
+	InstallBackupPath       bool
 }
 
 type GlobalConfig struct {
 	GlobalBase
 	Redistribution []SourcePolicyMap
+	BMPStations    []BMPStation
+	MRT            MRTConfig
+	RPKI           RPKIConfig
+	BestPath       BestPathConfig
 }
 
 type GlobalState struct {
@@ -110,13 +193,28 @@ type BaseConfig struct {
 	BfdEnable               bool
 	BfdSessionParam         string
 	AddPathsRx              bool
-	AddPathsMaxTx           uint8
+	AddPathsMaxTx           uint8 // sends up to AddPathsMaxTx-1 paths, MaxTx of AddPathsFamilies is the total
+	AddPathsTxMode          string
+	AddPathsFamilies        []AddPathsConfig
 	MaxPrefixes             uint32
 	MaxPrefixesThresholdPct uint8
 	MaxPrefixesDisconnect   bool
 	MaxPrefixesRestartTimer uint8
 	AdjRIBInFilter          string
 	AdjRIBOutFilter         string
+	ExtendedNextHop         bool
+	DefaultOriginate        bool
+	DefaultOriginateMap     string
+	AdvertiseMap            string
+	ExistMap                string
+	NonExistMap             string
+	ORFPrefixReceive        bool
+	Dampening               string
+	KeyChain                string
+	TCPAO                   bool
+	TTLSecurity             bool
+	TTLSecurityHops         uint8
+	RouteServerClient       bool
 }
 
 type NeighborConfig struct {
@@ -126,6 +224,8 @@ type NeighborConfig struct {
 	IfName          string
 	PeerGroup       string
 	Disabled        bool
+	Vrf             string
+	Dynamic         bool
 }
 
 type NeighborState struct {
@@ -154,6 +254,8 @@ type NeighborState struct {
 	PeerGroup               string
 	AddPathsRx              bool
 	AddPathsMaxTx           uint8
+	AddPathsTxMode          string
+	AddPathsFamilies        []AddPathsConfig
 	MaxPrefixes             uint32
 	MaxPrefixesThresholdPct uint8
 	MaxPrefixesDisconnect   bool
@@ -162,6 +264,31 @@ type NeighborState struct {
 	AdjRIBInFilter          string
 	AdjRIBOutFilter         string
 	SessionStateUpdatedTime time.Time
+	EndOfRIBReceived        map[uint32]bool
+	EndOfRIBSent            map[uint32]bool
+	Dynamic                 bool
+	ExtendedNextHop         bool
+	DefaultOriginate        bool
+	DefaultOriginateMap     string
+	AdvertiseMap            string
+	ExistMap                string
+	NonExistMap             string
+	ORFPrefixReceive        bool
+	Dampening               string
+	KeyChain                string
+	TCPAO                   bool
+	AuthType                string
+	ActiveKeyId             int32
+	TTLSecurity             bool
+	TTLSecurityHops         uint8
+	TTLSecurityDrops        uint32 // connection requests rejected by bgpd, not the kernel drops
+	RouteServerClient       bool
+	LastErrorSent           string
+	LastErrorRcvd           string
+	ShutdownMsgSent         string
+	ShutdownMsgRcvd         string
+	GracefulShutdown        bool
+	AdminShutdown           bool
 }
 
 type TransportConfig struct {
@@ -231,6 +358,13 @@ type AfiSafiConfig struct {
 type PeerCommand struct {
 	IP      net.IP
 	Command int
+	Reason  string
+}
+
+type PeerShutdown struct {
+	IP     net.IP
+	Reason string
+	Time   uint32
 }
 
 type Neighbor struct {
@@ -259,6 +393,21 @@ type BGPAggregate struct {
 	AddressFamily   uint32
 }
 
+type ListenRangeConfig struct {
+	Prefix          string
+	PeerGroup       string
+	PeerAddressType PeerAddressType
+	Limit           uint32
+}
+
+type VrfConfig struct {
+	Name     string
+	RD       string
+	Label    uint32
+	ImportRT []string
+	ExportRT []string
+}
+
 type AddressFamily struct {
 	BgpAggs map[string]*BGPAggregate
 }
@@ -287,4 +436,41 @@ type RouteConfig struct {
 	OutgoingInterface string
 	IsIPv6            bool
 	NullRoute         bool
+	Vrf               string
+	NextHopGroupId    int32
+}
+
+// NextHopGroupConfig is a next hop with its pre-installed backup next hop. Routes that resolve over the same pair
+// share the group, so RIBd switches all of them to the backup with a single update when the next hop fails.
+type NextHopGroupConfig struct {
+	Id                      int32
+	Vrf                     string
+	NextHopIp               string
+	OutgoingInterface       string
+	BackupNextHopIp         string
+	BackupOutgoingInterface string
+}
+
+type FlowSpecMatch struct {
+	Type  uint8
+	Name  string
+	Value string
+}
+
+type FlowSpecRule struct {
+	Rule          string
+	Protocol      string
+	IsIPv6        bool
+	DestinationNw string
+	SourceNw      string
+	Matches       []FlowSpecMatch
+	NLRI          []byte
+	RateLimit     bool
+	Rate          float32
+	RateInPackets bool
+	Sample        bool
+	Terminal      bool
+	Redirect      string
+	MarkDSCP      bool
+	DSCP          uint8
 }
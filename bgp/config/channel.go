@@ -63,6 +63,10 @@ const (
 	NOTIFY_POLICY_DEFINITION_CREATED
 	NOTIFY_POLICY_DEFINITION_DELETED
 	NOTIFY_POLICY_DEFINITION_UPDATED
+	EVPN_VNI_CREATED
+	EVPN_VNI_DELETED
+	EVPN_MAC_CREATED
+	EVPN_MAC_DELETED
 )
 
 type BfdInfo struct {
@@ -71,6 +75,71 @@ type BfdInfo struct {
 	State  bool
 }
 
+type EVPNVtepInfo struct {
+	Vni    uint32
+	VtepIP string
+}
+
+type EVPNMacInfo struct {
+	Vni    uint32
+	MAC    string
+	IP     string
+	VtepIP string
+}
+
+type EVPNInfo struct {
+	Oper Operation
+	Vtep *EVPNVtepInfo
+	Mac  *EVPNMacInfo
+}
+
+type LinkStateNode struct {
+	RouterId string
+	DRIP     string
+	ABR      bool
+	ASBR     bool
+}
+
+type LinkStateLink struct {
+	LocalRouterId  string
+	LocalDRIP      string
+	RemoteRouterId string
+	RemoteDRIP     string
+	LocalIP        string
+	RemoteIP       string
+	LocalIfIndex   uint32
+	Metric         uint32
+}
+
+const (
+	LinkStateRouteIntraArea uint8 = iota + 1
+	LinkStateRouteInterArea
+	LinkStateRouteExternal1
+	LinkStateRouteExternal2
+	LinkStateRouteNSSA1
+	LinkStateRouteNSSA2
+)
+
+type LinkStatePrefix struct {
+	RouterId  string
+	DRIP      string
+	Prefix    string
+	RouteType uint8
+	Metric    uint32
+}
+
+type LinkStateArea struct {
+	AreaId   string
+	Nodes    []LinkStateNode
+	Links    []LinkStateLink
+	Prefixes []LinkStatePrefix
+}
+
+type LinkStateInfo struct {
+	RouterId string
+	Areas    []LinkStateArea
+}
+
 type IntfStateInfo struct {
 	Idx         int32
 	IPAddr      string
@@ -26,6 +26,11 @@ package config
 
 const BGPConnectRetryTime uint32 = 120 // seconds
 const BGPHoldTimeDefault uint32 = 180  // 180 seconds
+const BGPTTLSecurityHopsDefault uint8 = 1
+const BGPRestartTimeDefault uint32 = 120   // seconds
+const BGPStalePathTimeDefault uint32 = 360 // seconds
+
+const BGPGracefulShutdownTimeDefault uint32 = 60 // seconds
 
 type BGPFSMState int
 
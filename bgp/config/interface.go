@@ -52,8 +52,11 @@ type RouteMgrIntf interface {
 	CreateRoute(*RouteConfig)
 	DeleteRoute(*RouteConfig)
 	UpdateRoute(cfg *RouteConfig, op string)
+	CreateNextHopGroup(*NextHopGroupConfig)
+	DeleteNextHopGroup(*NextHopGroupConfig)
 	ApplyPolicy(applyList []*ApplyPolicyInfo, undoList []*ApplyPolicyInfo)
 	GetRoutes() ([]*RouteInfo, []*RouteInfo)
+	GetInstalledRoutes() []*RouteConfig
 }
 
 /*  Interface for handling policy related operations
@@ -70,6 +73,30 @@ type BfdMgrIntf interface {
 	DeleteBfdSession(ipAddr string, iface string) (bool, error)
 }
 
+/*  Installing flowspec traffic filters in the dataplane
+ */
+type FlowSpecMgrIntf interface {
+	Start()
+	CreateFlowSpecRule(*FlowSpecRule)
+	DeleteFlowSpecRule(*FlowSpecRule)
+}
+
+/*  Learning local VNIs and MACs from the VXLAN daemon and programming the remote VTEPs and MACs
+ */
+type EVPNMgrIntf interface {
+	Start()
+	CreateRemoteVtep(*EVPNVtepInfo)
+	DeleteRemoteVtep(*EVPNVtepInfo)
+	CreateRemoteMac(*EVPNMacInfo)
+	DeleteRemoteMac(*EVPNMacInfo)
+}
+
+/*  Learning the IGP topology from the OSPF daemon for BGP-LS
+ */
+type LinkStateMgrIntf interface {
+	Start()
+}
+
 type ModelRouteIntf interface {
 	GetModelObject() objects.ConfigObj
 	GetThriftObject() interface{}
@@ -0,0 +1,211 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package FSMgr
+
+import (
+	"encoding/json"
+	"l3/bgp/api"
+	"l3/bgp/config"
+	"l3/tunnel/vxlan/vxlandCommonDefs"
+	"utils/logging"
+
+	nanomsg "github.com/op/go-nanomsg"
+)
+
+/*  Init EVPN manager, local VNIs and MACs are received from vxland and remote VTEPs and MACs are published
+ *  back to vxland
+ */
+func NewFSEVPNMgr(logger *logging.Writer, fileName string) *FSEVPNMgr {
+	mgr := &FSEVPNMgr{
+		plugin: "ovsdb",
+		logger: logger,
+	}
+
+	return mgr
+}
+
+/*  Start nano msg publisher socket for remote VTEPs and listen for vxland notifications
+ */
+func (mgr *FSEVPNMgr) Start() {
+	mgr.pubSocket, _ = mgr.setupPubSocket(vxlandCommonDefs.PUB_SOCKET_BGPD_EVPN_ADDR)
+	mgr.subSocket, _ = mgr.setupSubSocket(vxlandCommonDefs.PUB_SOCKET_EVPN_ADDR)
+	if mgr.subSocket != nil {
+		go mgr.listenForVxlandNotifications()
+	}
+}
+
+func (mgr *FSEVPNMgr) setupPubSocket(address string) (*nanomsg.PubSocket, error) {
+	var err error
+	var socket *nanomsg.PubSocket
+	if socket, err = nanomsg.NewPubSocket(); err != nil {
+		mgr.logger.Errf("Failed to create publisher socket %s error:%s", address, err)
+		return nil, err
+	}
+
+	if _, err = socket.Bind(address); err != nil {
+		mgr.logger.Errf("Failed to bind publisher socket %s, error:%s", address, err)
+		return nil, err
+	}
+
+	if err = socket.SetSendBuffer(1024 * 1024); err != nil {
+		mgr.logger.Errf("Failed to set the buffer size for publisher socket %s, error:%s", address, err)
+		return nil, err
+	}
+	mgr.logger.Infof("Bound publisher socket %s", address)
+	return socket, nil
+}
+
+func (mgr *FSEVPNMgr) setupSubSocket(address string) (*nanomsg.SubSocket, error) {
+	var err error
+	var socket *nanomsg.SubSocket
+	if socket, err = nanomsg.NewSubSocket(); err != nil {
+		mgr.logger.Errf("Failed to create subscribe socket %s, error:%s", address, err)
+		return nil, err
+	}
+
+	if err = socket.Subscribe(""); err != nil {
+		mgr.logger.Errf("Failed to subscribe to \"\" on subscribe socket %s, error:%s", address, err)
+		return nil, err
+	}
+
+	if _, err = socket.Connect(address); err != nil {
+		mgr.logger.Errf("Failed to connect to publisher socket %s, error:%s", address, err)
+		return nil, err
+	}
+
+	mgr.logger.Infof("Connected to publisher socket %s", address)
+	if err = socket.SetRecvBuffer(1024 * 1024); err != nil {
+		mgr.logger.Errf("Failed to set the buffer size for subsriber socket %s, error:%s", address, err)
+		return nil, err
+	}
+	return socket, nil
+}
+
+/*  Listen for local VNI and MAC notifications from vxland
+ */
+func (mgr *FSEVPNMgr) listenForVxlandNotifications() {
+	for {
+		rxBuf, err := mgr.subSocket.Recv(0)
+		if err != nil {
+			mgr.logger.Err("Recv on vxland subscriber socket failed with error:", err)
+			continue
+		}
+		mgr.handleVxlandNotifications(rxBuf)
+	}
+}
+
+func (mgr *FSEVPNMgr) handleVxlandNotifications(rxBuf []byte) {
+	msg := vxlandCommonDefs.VxlandNotifyMsg{}
+	if err := json.Unmarshal(rxBuf, &msg); err != nil {
+		mgr.logger.Errf("Unmarshal vxland notification failed with err %s", err)
+		return
+	}
+
+	switch msg.MsgType {
+	case vxlandCommonDefs.NOTIFY_EVPN_LOCAL_VNI_CREATE, vxlandCommonDefs.NOTIFY_EVPN_LOCAL_VNI_DELETE:
+		vniInfo := vxlandCommonDefs.EVPNVniInfo{}
+		if err := json.Unmarshal(msg.MsgBuf, &vniInfo); err != nil {
+			mgr.logger.Errf("Unmarshal EVPN VNI info failed with err %s", err)
+			return
+		}
+		oper := config.EVPN_VNI_CREATED
+		if msg.MsgType == vxlandCommonDefs.NOTIFY_EVPN_LOCAL_VNI_DELETE {
+			oper = config.EVPN_VNI_DELETED
+		}
+		api.SendEVPNNotification(config.EVPNInfo{
+			Oper: oper,
+			Vtep: &config.EVPNVtepInfo{
+				Vni:    vniInfo.Vni,
+				VtepIP: vniInfo.VtepIp,
+			},
+		})
+
+	case vxlandCommonDefs.NOTIFY_EVPN_LOCAL_MAC_CREATE, vxlandCommonDefs.NOTIFY_EVPN_LOCAL_MAC_DELETE:
+		macInfo := vxlandCommonDefs.EVPNMacInfo{}
+		if err := json.Unmarshal(msg.MsgBuf, &macInfo); err != nil {
+			mgr.logger.Errf("Unmarshal EVPN MAC info failed with err %s", err)
+			return
+		}
+		oper := config.EVPN_MAC_CREATED
+		if msg.MsgType == vxlandCommonDefs.NOTIFY_EVPN_LOCAL_MAC_DELETE {
+			oper = config.EVPN_MAC_DELETED
+		}
+		api.SendEVPNNotification(config.EVPNInfo{
+			Oper: oper,
+			Mac: &config.EVPNMacInfo{
+				Vni:    macInfo.Vni,
+				MAC:    macInfo.Mac,
+				IP:     macInfo.Ip,
+				VtepIP: macInfo.VtepIp,
+			},
+		})
+	}
+}
+
+func (mgr *FSEVPNMgr) publish(msgType uint16, info interface{}) {
+	if mgr.pubSocket == nil {
+		mgr.logger.Err("EVPN publisher socket not found, can't publish message type", msgType)
+		return
+	}
+
+	msgBuf, err := json.Marshal(info)
+	if err != nil {
+		mgr.logger.Err("Failed to marshal EVPN info", info, "error:", err)
+		return
+	}
+
+	buf, err := json.Marshal(vxlandCommonDefs.VxlandNotifyMsg{MsgType: msgType, MsgBuf: msgBuf})
+	if err != nil {
+		mgr.logger.Err("Failed to marshal EVPN message type", msgType, "error:", err)
+		return
+	}
+
+	if _, err = mgr.pubSocket.Send(buf, nanomsg.DontWait); err != nil {
+		mgr.logger.Err("Failed to publish EVPN message type", msgType, "error:", err)
+	}
+}
+
+func (mgr *FSEVPNMgr) CreateRemoteVtep(vtep *config.EVPNVtepInfo) {
+	mgr.logger.Info("Create remote vtep", vtep.VtepIP, "VNI", vtep.Vni)
+	mgr.publish(vxlandCommonDefs.NOTIFY_EVPN_REMOTE_VTEP_CREATE,
+		vxlandCommonDefs.EVPNVniInfo{Vni: vtep.Vni, VtepIp: vtep.VtepIP})
+}
+
+func (mgr *FSEVPNMgr) DeleteRemoteVtep(vtep *config.EVPNVtepInfo) {
+	mgr.logger.Info("Delete remote vtep", vtep.VtepIP, "VNI", vtep.Vni)
+	mgr.publish(vxlandCommonDefs.NOTIFY_EVPN_REMOTE_VTEP_DELETE,
+		vxlandCommonDefs.EVPNVniInfo{Vni: vtep.Vni, VtepIp: vtep.VtepIP})
+}
+
+func (mgr *FSEVPNMgr) CreateRemoteMac(mac *config.EVPNMacInfo) {
+	mgr.logger.Info("Create remote mac", mac.MAC, "VNI", mac.Vni, "vtep", mac.VtepIP)
+	mgr.publish(vxlandCommonDefs.NOTIFY_EVPN_REMOTE_MAC_CREATE,
+		vxlandCommonDefs.EVPNMacInfo{Vni: mac.Vni, Mac: mac.MAC, Ip: mac.IP, VtepIp: mac.VtepIP})
+}
+
+func (mgr *FSEVPNMgr) DeleteRemoteMac(mac *config.EVPNMacInfo) {
+	mgr.logger.Info("Delete remote mac", mac.MAC, "VNI", mac.Vni, "vtep", mac.VtepIP)
+	mgr.publish(vxlandCommonDefs.NOTIFY_EVPN_REMOTE_MAC_DELETE,
+		vxlandCommonDefs.EVPNMacInfo{Vni: mac.Vni, Mac: mac.MAC, Ip: mac.IP, VtepIp: mac.VtepIP})
+}
@@ -0,0 +1,115 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package FSMgr
+
+import (
+	"encoding/json"
+	"l3/bgp/config"
+	"utils/logging"
+
+	nanomsg "github.com/op/go-nanomsg"
+)
+
+const (
+	FLOWSPEC_PUB_SOCKET_ADDR = "ipc:///tmp/bgpd_flowspec.ipc"
+)
+
+const (
+	FLOWSPEC_NOTIFY_RULE_CREATE uint16 = iota + 1
+	FLOWSPEC_NOTIFY_RULE_DELETE
+)
+
+type FlowSpecNotifyMsg struct {
+	MsgType uint16
+	Rule    config.FlowSpecRule
+}
+
+/*  Init flowspec manager, the rules are published to the plugins subscribed to the flowspec socket
+ */
+func NewFSFlowSpecMgr(logger *logging.Writer, fileName string) *FSFlowSpecMgr {
+	mgr := &FSFlowSpecMgr{
+		plugin: "ovsdb",
+		logger: logger,
+	}
+
+	return mgr
+}
+
+/*  Start nano msg publisher socket for the flowspec rules
+ */
+func (mgr *FSFlowSpecMgr) Start() {
+	mgr.pubSocket, _ = mgr.setupPubSocket(FLOWSPEC_PUB_SOCKET_ADDR)
+}
+
+func (mgr *FSFlowSpecMgr) setupPubSocket(address string) (*nanomsg.PubSocket, error) {
+	var err error
+	var socket *nanomsg.PubSocket
+	if socket, err = nanomsg.NewPubSocket(); err != nil {
+		mgr.logger.Errf("Failed to create publisher socket %s error:%s", address, err)
+		return nil, err
+	}
+
+	if _, err = socket.Bind(address); err != nil {
+		mgr.logger.Errf("Failed to bind publisher socket %s, error:%s", address, err)
+		return nil, err
+	}
+
+	if err = socket.SetSendBuffer(1024 * 1024); err != nil {
+		mgr.logger.Errf("Failed to set the buffer size for publisher socket %s, error:%s", address, err)
+		return nil, err
+	}
+	mgr.logger.Infof("Bound publisher socket %s", address)
+	return socket, nil
+}
+
+func (mgr *FSFlowSpecMgr) publishRule(msgType uint16, rule *config.FlowSpecRule) {
+	if mgr.pubSocket == nil {
+		mgr.logger.Err("FlowSpec publisher socket not found, can't publish rule", rule.Rule)
+		return
+	}
+
+	msg := FlowSpecNotifyMsg{
+		MsgType: msgType,
+		Rule:    *rule,
+	}
+	msgBuf, err := json.Marshal(msg)
+	if err != nil {
+		mgr.logger.Err("Failed to marshal flowspec rule", rule.Rule, "error:", err)
+		return
+	}
+
+	if _, err = mgr.pubSocket.Send(msgBuf, nanomsg.DontWait); err != nil {
+		mgr.logger.Err("Failed to publish flowspec rule", rule.Rule, "error:", err)
+	}
+}
+
+func (mgr *FSFlowSpecMgr) CreateFlowSpecRule(rule *config.FlowSpecRule) {
+	mgr.logger.Info("Create flowspec rule", rule.Rule)
+	mgr.publishRule(FLOWSPEC_NOTIFY_RULE_CREATE, rule)
+}
+
+func (mgr *FSFlowSpecMgr) DeleteFlowSpecRule(rule *config.FlowSpecRule) {
+	mgr.logger.Info("Delete flowspec rule", rule.Rule)
+	mgr.publishRule(FLOWSPEC_NOTIFY_RULE_DELETE, rule)
+}
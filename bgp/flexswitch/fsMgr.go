@@ -71,6 +71,31 @@ type FSBfdMgr struct {
 	bfdSubSocket *nanomsg.SubSocket
 }
 
+/*  FlowSpec manager will publish the flowspec rules for the dataplane plugins
+ */
+type FSFlowSpecMgr struct {
+	plugin    string
+	logger    *logging.Writer
+	pubSocket *nanomsg.PubSocket
+}
+
+/*  EVPN manager will handle all the communication with vxlan daemon
+ */
+type FSEVPNMgr struct {
+	plugin    string
+	logger    *logging.Writer
+	subSocket *nanomsg.SubSocket
+	pubSocket *nanomsg.PubSocket
+}
+
+/*  Link state manager will receive the IGP topology from ospf daemon
+ */
+type FSLinkStateMgr struct {
+	plugin    string
+	logger    *logging.Writer
+	subSocket *nanomsg.SubSocket
+}
+
 func (mgr *FSIntfMgr) PortStateChange() {
 
 }
@@ -0,0 +1,165 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package FSMgr
+
+import (
+	"encoding/json"
+	"l3/bgp/api"
+	"l3/bgp/config"
+	"l3/ospf/ospfdCommonDefs"
+	"utils/logging"
+
+	nanomsg "github.com/op/go-nanomsg"
+)
+
+var ospfRouteTypeToLinkStateMap = map[uint8]uint8{
+	ospfdCommonDefs.LINK_STATE_ROUTE_INTRA_AREA: config.LinkStateRouteIntraArea,
+	ospfdCommonDefs.LINK_STATE_ROUTE_INTER_AREA: config.LinkStateRouteInterArea,
+	ospfdCommonDefs.LINK_STATE_ROUTE_EXTERNAL1:  config.LinkStateRouteExternal1,
+	ospfdCommonDefs.LINK_STATE_ROUTE_EXTERNAL2:  config.LinkStateRouteExternal2,
+	ospfdCommonDefs.LINK_STATE_ROUTE_NSSA1:      config.LinkStateRouteNSSA1,
+	ospfdCommonDefs.LINK_STATE_ROUTE_NSSA2:      config.LinkStateRouteNSSA2,
+}
+
+/*  Init link state manager, the LSDB topology is received from ospfd
+ */
+func NewFSLinkStateMgr(logger *logging.Writer, fileName string) *FSLinkStateMgr {
+	mgr := &FSLinkStateMgr{
+		plugin: "ovsdb",
+		logger: logger,
+	}
+
+	return mgr
+}
+
+/*  Listen for ospfd topology notifications
+ */
+func (mgr *FSLinkStateMgr) Start() {
+	mgr.subSocket, _ = mgr.setupSubSocket(ospfdCommonDefs.PUB_SOCKET_LINK_STATE_ADDR)
+	if mgr.subSocket != nil {
+		go mgr.listenForOspfdNotifications()
+	}
+}
+
+func (mgr *FSLinkStateMgr) setupSubSocket(address string) (*nanomsg.SubSocket, error) {
+	var err error
+	var socket *nanomsg.SubSocket
+	if socket, err = nanomsg.NewSubSocket(); err != nil {
+		mgr.logger.Errf("Failed to create subscribe socket %s, error:%s", address, err)
+		return nil, err
+	}
+
+	if err = socket.Subscribe(""); err != nil {
+		mgr.logger.Errf("Failed to subscribe to \"\" on subscribe socket %s, error:%s", address, err)
+		return nil, err
+	}
+
+	if _, err = socket.Connect(address); err != nil {
+		mgr.logger.Errf("Failed to connect to publisher socket %s, error:%s", address, err)
+		return nil, err
+	}
+
+	mgr.logger.Infof("Connected to publisher socket %s", address)
+	if err = socket.SetRecvBuffer(1024 * 1024); err != nil {
+		mgr.logger.Errf("Failed to set the buffer size for subsriber socket %s, error:%s", address, err)
+		return nil, err
+	}
+	return socket, nil
+}
+
+func (mgr *FSLinkStateMgr) listenForOspfdNotifications() {
+	for {
+		rxBuf, err := mgr.subSocket.Recv(0)
+		if err != nil {
+			mgr.logger.Err("Recv on ospfd subscriber socket failed with error:", err)
+			continue
+		}
+		mgr.handleOspfdNotifications(rxBuf)
+	}
+}
+
+func (mgr *FSLinkStateMgr) handleOspfdNotifications(rxBuf []byte) {
+	msg := ospfdCommonDefs.OspfdNotifyMsg{}
+	if err := json.Unmarshal(rxBuf, &msg); err != nil {
+		mgr.logger.Errf("Unmarshal ospfd notification failed with err %s", err)
+		return
+	}
+
+	switch msg.MsgType {
+	case ospfdCommonDefs.NOTIFY_LINK_STATE_TOPOLOGY:
+		topology := ospfdCommonDefs.LinkStateTopology{}
+		if err := json.Unmarshal(msg.MsgBuf, &topology); err != nil {
+			mgr.logger.Errf("Unmarshal link state topology failed with err %s", err)
+			return
+		}
+		api.SendLinkStateNotification(convertLinkStateTopology(&topology))
+	}
+}
+
+func convertLinkStateTopology(topology *ospfdCommonDefs.LinkStateTopology) config.LinkStateInfo {
+	info := config.LinkStateInfo{
+		RouterId: topology.RouterId,
+		Areas:    make([]config.LinkStateArea, 0, len(topology.Areas)),
+	}
+
+	for _, area := range topology.Areas {
+		lsArea := config.LinkStateArea{
+			AreaId:   area.AreaId,
+			Nodes:    make([]config.LinkStateNode, 0, len(area.Nodes)),
+			Links:    make([]config.LinkStateLink, 0, len(area.Links)),
+			Prefixes: make([]config.LinkStatePrefix, 0, len(area.Prefixes)),
+		}
+		for _, node := range area.Nodes {
+			lsArea.Nodes = append(lsArea.Nodes, config.LinkStateNode{
+				RouterId: node.RouterId,
+				DRIP:     node.DRIp,
+				ABR:      node.ABR,
+				ASBR:     node.ASBR,
+			})
+		}
+		for _, link := range area.Links {
+			lsArea.Links = append(lsArea.Links, config.LinkStateLink{
+				LocalRouterId:  link.LocalRouterId,
+				LocalDRIP:      link.LocalDRIp,
+				RemoteRouterId: link.RemoteRouterId,
+				RemoteDRIP:     link.RemoteDRIp,
+				LocalIP:        link.LocalIp,
+				RemoteIP:       link.RemoteIp,
+				LocalIfIndex:   link.LocalIfIndex,
+				Metric:         link.Metric,
+			})
+		}
+		for _, prefix := range area.Prefixes {
+			lsArea.Prefixes = append(lsArea.Prefixes, config.LinkStatePrefix{
+				RouterId:  prefix.RouterId,
+				DRIP:      prefix.DRIp,
+				Prefix:    prefix.Prefix,
+				RouteType: ospfRouteTypeToLinkStateMap[prefix.RouteType],
+				Metric:    prefix.Metric,
+			})
+		}
+		info.Areas = append(info.Areas, lsArea)
+	}
+	return info
+}
@@ -32,6 +32,7 @@ import (
 	"l3/bgp/config"
 	"l3/bgp/rpc"
 	"l3/rib/ribdCommonDefs"
+	"net"
 	"ribd"
 	"ribdInt"
 	"utils/logging"
@@ -184,6 +185,7 @@ func (mgr *FSRouteMgr) createRibdIPv4RouteCfg(cfg *config.RouteConfig, create bo
 		NetworkMask:   cfg.NetworkMask,
 		DestinationNw: cfg.DestinationNw,
 		NullRoute:     cfg.NullRoute,
+		Vrf:           cfg.Vrf,
 	}
 	nextHop := ribd.NextHopInfo{
 		NextHopIp:     cfg.NextHopIp,
@@ -201,6 +203,7 @@ func (mgr *FSRouteMgr) createRibdIPv6RouteCfg(cfg *config.RouteConfig, create bo
 		NetworkMask:   cfg.NetworkMask,
 		DestinationNw: cfg.DestinationNw,
 		NullRoute:     cfg.NullRoute,
+		Vrf:           cfg.Vrf,
 	}
 	nextHop := ribd.NextHopInfo{
 		NextHopIp:     cfg.NextHopIp,
@@ -217,6 +220,9 @@ func (mgr *FSRouteMgr) CreateRoute(cfg *config.RouteConfig) {
 	} else {
 		mgr.ribdClient.OnewayCreateIPv4Route(mgr.createRibdIPv4RouteCfg(cfg, true /*create*/))
 	}
+	if cfg.NextHopGroupId != 0 {
+		mgr.ribdClient.OnewayAddNextHopGroupRoute(mgr.createRibdNextHopGroupRouteCfg(cfg))
+	}
 }
 
 func (mgr *FSRouteMgr) DeleteRoute(cfg *config.RouteConfig) {
@@ -234,6 +240,7 @@ func (mgr *FSRouteMgr) UpdateV4Route(cfg *config.RouteConfig, nhInfo []*ribd.Nex
 		NetworkMask:   cfg.NetworkMask,
 		DestinationNw: cfg.DestinationNw,
 		NullRoute:     cfg.NullRoute,
+		Vrf:           cfg.Vrf,
 	}
 	rCfg.NextHop = nhInfo
 	mgr.ribdClient.UpdateIPv4Route(&rCfg, &rCfg, nil, patch)
@@ -246,6 +253,7 @@ func (mgr *FSRouteMgr) UpdateV6Route(cfg *config.RouteConfig, nhInfo []*ribd.Nex
 		NetworkMask:   cfg.NetworkMask,
 		DestinationNw: cfg.DestinationNw,
 		NullRoute:     cfg.NullRoute,
+		Vrf:           cfg.Vrf,
 	}
 	rCfg.NextHop = nhInfo
 	mgr.ribdClient.UpdateIPv6Route(&rCfg, &rCfg, nil, patch)
@@ -276,6 +284,51 @@ func (mgr *FSRouteMgr) UpdateRoute(cfg *config.RouteConfig, op string) {
 	} else {
 		mgr.UpdateV4Route(cfg, nextHopInfo, patchOp)
 	}
+
+	// Keep the next hop group the route is installed with in sync, RIBd switches the routes of the group to the
+	// backup next hop when the next hop fails
+	if op == "remove" {
+		if cfg.NextHopGroupId != 0 {
+			mgr.ribdClient.OnewayDeleteNextHopGroupRoute(mgr.createRibdNextHopGroupRouteCfg(cfg))
+		}
+	} else if cfg.NextHopGroupId != 0 {
+		mgr.ribdClient.OnewayAddNextHopGroupRoute(mgr.createRibdNextHopGroupRouteCfg(cfg))
+	} else if op == "replace" {
+		mgr.ribdClient.OnewayDeleteNextHopGroupRoute(mgr.createRibdNextHopGroupRouteCfg(cfg))
+	}
+}
+
+func (mgr *FSRouteMgr) createRibdNextHopGroupCfg(cfg *config.NextHopGroupConfig) *ribdInt.NextHopGroup {
+	return &ribdInt.NextHopGroup{
+		GroupId: cfg.Id,
+		Vrf:     cfg.Vrf,
+		NextHop: &ribdInt.RouteNextHopInfo{
+			NextHopIp:     cfg.NextHopIp,
+			NextHopIntRef: cfg.OutgoingInterface,
+		},
+		BackupNextHop: &ribdInt.RouteNextHopInfo{
+			NextHopIp:     cfg.BackupNextHopIp,
+			NextHopIntRef: cfg.BackupOutgoingInterface,
+		},
+	}
+}
+
+func (mgr *FSRouteMgr) createRibdNextHopGroupRouteCfg(cfg *config.RouteConfig) *ribdInt.NextHopGroupRoute {
+	return &ribdInt.NextHopGroupRoute{
+		DestinationNw: cfg.DestinationNw,
+		NetworkMask:   cfg.NetworkMask,
+		Protocol:      cfg.Protocol,
+		NextHopIp:     cfg.NextHopIp,
+		GroupId:       cfg.NextHopGroupId,
+	}
+}
+
+func (mgr *FSRouteMgr) CreateNextHopGroup(cfg *config.NextHopGroupConfig) {
+	mgr.ribdClient.OnewayCreateNextHopGroup(mgr.createRibdNextHopGroupCfg(cfg))
+}
+
+func (mgr *FSRouteMgr) DeleteNextHopGroup(cfg *config.NextHopGroupConfig) {
+	mgr.ribdClient.OnewayDeleteNextHopGroup(mgr.createRibdNextHopGroupCfg(cfg))
 }
 
 func (mgr *FSRouteMgr) ApplyPolicy(applyList []*config.ApplyPolicyInfo, undoList []*config.ApplyPolicyInfo) {
@@ -350,3 +403,77 @@ func (mgr *FSRouteMgr) GetRoutes() ([]*config.RouteInfo, []*config.RouteInfo) {
 
 	return routes, (make([]*config.RouteInfo, 0))
 }
+
+// populateInstalledRoutes returns a route config for each next hop of the BGP route in RIBd
+func (mgr *FSRouteMgr) populateInstalledRoutes(protocol, destNw string, nextHops []*ribd.NextHopInfo,
+	isIPv6 bool) []*config.RouteConfig {
+	routes := make([]*config.RouteConfig, 0)
+	if protocol != "EBGP" && protocol != "IBGP" {
+		return routes
+	}
+	ip, ipNet, err := net.ParseCIDR(destNw)
+	if err != nil {
+		mgr.logger.Errf("Failed to parse the destination %s of the RIB route, error: %s", destNw, err)
+		return routes
+	}
+
+	for _, nextHop := range nextHops {
+		route := &config.RouteConfig{
+			Protocol:          protocol,
+			NextHopIp:         nextHop.NextHopIp,
+			NetworkMask:       net.IP(ipNet.Mask).String(),
+			DestinationNw:     ip.String(),
+			OutgoingInterface: nextHop.NextHopIntRef,
+			IsIPv6:            isIPv6,
+		}
+		if nextHop.NextHopIp == "Null0" {
+			route.NextHopIp = "255.255.255.255"
+			route.OutgoingInterface = ""
+			route.NullRoute = true
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// GetInstalledRoutes returns the BGP routes in RIBd. RIBd keeps the routes when bgpd goes down, so the routes found
+// at startup were installed before bgpd restarted.
+func (mgr *FSRouteMgr) GetInstalledRoutes() []*config.RouteConfig {
+	var currMarker ribd.Int
+	var count ribd.Int = 100
+	routes := make([]*config.RouteConfig, 0)
+	for {
+		getBulkInfo, err := mgr.ribdClient.GetBulkIPv4RouteState(currMarker, count)
+		if err != nil {
+			mgr.logger.Info("GetBulkIPv4RouteState with err ", err)
+			break
+		}
+		for _, route := range getBulkInfo.IPv4RouteStateList {
+			routes = append(routes, mgr.populateInstalledRoutes(route.Protocol, route.DestinationNw,
+				route.NextHopList, false)...)
+		}
+		if getBulkInfo.Count == 0 || getBulkInfo.More == false {
+			break
+		}
+		currMarker = getBulkInfo.EndIdx
+	}
+
+	currMarker = 0
+	for {
+		getBulkInfo, err := mgr.ribdClient.GetBulkIPv6RouteState(currMarker, count)
+		if err != nil {
+			mgr.logger.Info("GetBulkIPv6RouteState with err ", err)
+			break
+		}
+		for _, route := range getBulkInfo.IPv6RouteStateList {
+			routes = append(routes, mgr.populateInstalledRoutes(route.Protocol, route.DestinationNw,
+				route.NextHopList, true)...)
+		}
+		if getBulkInfo.Count == 0 || getBulkInfo.More == false {
+			break
+		}
+		currMarker = getBulkInfo.EndIdx
+	}
+	mgr.logger.Info("Found", len(routes), "BGP routes in RIBd")
+	return routes
+}
@@ -146,7 +146,25 @@ func (o *OutTCPConn) Connect(seconds uint32, remote, local string, connCh chan n
 		return
 	}
 
-	if o.fsm.pConf.AuthPassword != "" {
+	if o.fsm.pConf.KeyChain != "" {
+		keyChain := o.fsm.neighborConf.GetKeyChain()
+		if keyChain == nil {
+			o.logger.Err("Neighbor:", o.fsm.pConf.NeighborAddress, "FSM", o.fsm.id, "Key chain",
+				o.fsm.pConf.KeyChain, "is not configured, refuse to connect without authentication")
+			errCh <- errors.New(fmt.Sprintf("Key chain %s is not configured", o.fsm.pConf.KeyChain))
+			return
+		}
+
+		keyId, err := keyChain.SetKeys(socket, net.ParseIP(remoteIP), o.fsm.pConf.TCPAO, false, time.Now())
+		if err != nil {
+			o.logger.Err("Neighbor:", o.fsm.pConf.NeighborAddress, "FSM", o.fsm.id,
+				"Set keys from key chain", keyChain.Name, "on the socket failed with error", err)
+			errCh <- err
+			return
+		}
+		o.logger.Info("Neighbor:", o.fsm.pConf.NeighborAddress, "FSM", o.fsm.id, "Set key", keyId,
+			"from key chain", keyChain.Name, "on the socket:", socket)
+	} else if o.fsm.pConf.AuthPassword != "" {
 		o.logger.Info("Neighbor:", o.fsm.pConf.NeighborAddress, "FSM", o.fsm.id, "Set MD5 option on the socket:",
 			socket, "password:", o.fsm.pConf.AuthPassword)
 		err = netUtils.SetSockoptTCPMD5(socket, remoteIP, o.fsm.pConf.AuthPassword)
@@ -158,6 +176,16 @@ func (o *OutTCPConn) Connect(seconds uint32, remote, local string, connCh chan n
 		}
 	}
 
+	if o.fsm.pConf.TTLSecurity {
+		err = setTTLSecurity(socket, net.ParseIP(remoteIP).To4() == nil, o.fsm.pConf.TTLSecurityHops)
+		if err != nil {
+			o.logger.Err("Neighbor:", o.fsm.pConf.NeighborAddress, "FSM", o.fsm.id,
+				"Set TTL security on the socket failed with error", err)
+			errCh <- err
+			return
+		}
+	}
+
 	duration := uint32(10)
 	if duration < seconds {
 		duration = seconds
@@ -176,7 +204,9 @@ func (o *OutTCPConn) Connect(seconds uint32, remote, local string, connCh chan n
 	} else {
 		packetConn := ipv4.NewConn(conn)
 		ttl := 1
-		if o.fsm.pConf.MultiHopEnable {
+		if o.fsm.pConf.TTLSecurity {
+			ttl = GTSMMaxTTL
+		} else if o.fsm.pConf.MultiHopEnable {
 			ttl = int(o.fsm.pConf.MultiHopTTL)
 		}
 		if err = packetConn.SetTTL(ttl); err != nil {
@@ -357,11 +387,18 @@ func (p *PeerConn) DecodeMessage(header *packet.BGPHeader, buf []byte) (*packet.
 	} else if header.Type == packet.BGPMsgTypeOpen {
 		p.peerAttrs.ASSize = packet.GetASSize(msg.Body.(*packet.BGPOpen))
 		p.peerAttrs.AddPathFamily = packet.GetAddPathFamily(msg.Body.(*packet.BGPOpen))
-		addPathsTxFarEnd := packet.IsAddPathsTxEnabledForIPv4(p.peerAttrs.AddPathFamily)
-		p.logger.Info("Neighbor:", p.fsm.pConf.NeighborAddress, "Far end can send add paths")
-		if addPathsTxFarEnd && p.fsm.pConf.AddPathsRx {
-			p.peerAttrs.AddPathsRxActual = true
-			p.logger.Info("Neighbor:", p.fsm.pConf.NeighborAddress, "negotiated to recieve add paths from far end")
+		p.peerAttrs.AddPathsRxFamily = make(map[uint32]bool)
+		addPathsFlags := p.fsm.neighborConf.GetAddPathsFlags()
+		for afi, safiMap := range p.peerAttrs.AddPathFamily {
+			for safi, flags := range safiMap {
+				protoFamily := packet.GetProtocolFamily(afi, safi)
+				if flags&packet.BGPCapAddPathTx != 0 && addPathsFlags[protoFamily]&packet.BGPCapAddPathRx != 0 {
+					p.peerAttrs.AddPathsRxFamily[protoFamily] = true
+					p.peerAttrs.AddPathsRxActual = true
+					p.logger.Info("Neighbor:", p.fsm.pConf.NeighborAddress,
+						"negotiated to recieve add paths from far end for family", packet.GetProtocolFamilyStr(protoFamily))
+				}
+			}
 		}
 	}
 
@@ -391,6 +428,7 @@ func (p *PeerConn) ReadPkt(doneCh chan bool, stopCh chan bool, exitCh chan bool)
 				}
 			}
 
+			headerBuf := buf
 			header = packet.NewBGPHeader()
 			err = header.Decode(buf)
 			if err != nil {
@@ -425,7 +463,11 @@ func (p *PeerConn) ReadPkt(doneCh chan bool, stopCh chan bool, exitCh chan bool)
 			}
 
 			msg, msgErr, msgOk := p.DecodeMessage(header, buf)
-			p.fsm.pktRxCh <- packet.NewBGPPktInfo(msg, msgErr)
+			pktInfo := packet.NewBGPPktInfo(msg, msgErr)
+			if p.fsm.Manager.mrtWriter.IsEnabled() {
+				pktInfo.Data = append(append(make([]byte, 0, len(headerBuf)+len(buf)), headerBuf...), buf...)
+			}
+			p.fsm.pktRxCh <- pktInfo
 			doneCh <- msgOk
 
 		case <-stopCh:
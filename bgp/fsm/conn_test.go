@@ -30,8 +30,11 @@ import (
 	"fmt"
 	"l3/bgp/baseobjects"
 	"l3/bgp/config"
+	"l3/bgp/keychain"
 	"l3/bgp/packet"
 	"math"
+	"net"
+	"syscall"
 	"testing"
 	"utils/logging"
 )
@@ -54,7 +57,7 @@ func TestDecodeMessageBGPOpen(t *testing.T) {
 	pConf := config.NeighborConfig{}
 	nConf := base.NewNeighborConf(logger, gConf, peerGroup, pConf)
 	fsmMgr := NewFSMManager(logger, nConf, make(chan *packet.BGPPktSrc), make(chan PeerFSMConn),
-		make(chan config.ReachabilityInfo))
+		make(chan config.ReachabilityInfo), nil)
 	stateMachine := NewFSM(fsmMgr, 0, nConf)
 	peerConn := NewPeerConn(stateMachine, config.ConnDirOut, nil, 1)
 
@@ -90,3 +93,58 @@ func TestDecodeMessageBGPOpen(t *testing.T) {
 		}
 	}
 }
+
+func acceptWithTTL(t *testing.T, listener *net.TCPListener, ttl int) net.Conn {
+	dialer := net.Dialer{
+		Control: func(network, address string, c syscall.RawConn) error {
+			return c.Control(func(fd uintptr) {
+				syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+			})
+		},
+	}
+	client, err := dialer.Dial("tcp4", listener.Addr().String())
+	if err != nil {
+		t.Fatal("Dial failed with error", err)
+	}
+	defer client.Close()
+
+	conn, err := listener.AcceptTCP()
+	if err != nil {
+		t.Fatal("Accept failed with error", err)
+	}
+	return conn
+}
+
+func TestTTLSecurity(t *testing.T) {
+	listener, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal("Listen failed with error", err)
+	}
+	defer listener.Close()
+
+	if err = EnableSavedSyn(listener); err != nil {
+		t.Skip("Saved SYN is not supported by the kernel:", err)
+	}
+
+	conn := acceptWithTTL(t, listener, 64)
+	err = CheckTTLSecurity(conn, 1)
+	conn.Close()
+	if _, ok := err.(TTLSecurityError); !ok {
+		t.Fatal("Expected TTL security error for TTL 64, got", err)
+	}
+
+	conn = acceptWithTTL(t, listener, GTSMMaxTTL)
+	defer conn.Close()
+	if err = CheckTTLSecurity(conn, 1); err != nil {
+		t.Fatal("TTL security check failed for TTL 255 with error", err)
+	}
+
+	var minTTL int
+	err = keychain.ControlFd(conn.(*net.TCPConn), func(fd int) (err error) {
+		minTTL, err = syscall.GetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_MINTTL)
+		return err
+	})
+	if err != nil || minTTL != GTSMMaxTTL-1 {
+		t.Error("Expected minimum TTL", GTSMMaxTTL-1, "found", minTTL, "error", err)
+	}
+}
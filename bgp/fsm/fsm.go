@@ -29,6 +29,7 @@ import (
 	"fmt"
 	"l3/bgp/baseobjects"
 	"l3/bgp/config"
+	"l3/bgp/mrt"
 	"l3/bgp/packet"
 	"math/rand"
 	"net"
@@ -84,6 +85,7 @@ const (
 	BGPEventKeepAliveMsg
 	BGPEventUpdateMsg
 	BGPEventUpdateMsgErr
+	BGPEventRouteRefreshMsg
 )
 
 var BGPEventTypeToStr = map[BGPFSMEvent]string{
@@ -115,6 +117,7 @@ var BGPEventTypeToStr = map[BGPFSMEvent]string{
 	BGPEventKeepAliveMsg:                    "KeepAliveMsg",
 	BGPEventUpdateMsg:                       "UpdateMsg",
 	BGPEventUpdateMsgErr:                    "UpdateMsgErr",
+	BGPEventRouteRefreshMsg:                 "RouteRefreshMsg",
 }
 
 type BaseStateIface interface {
@@ -287,7 +290,7 @@ func (st *ConnectState) processEvent(event BGPFSMEvent, data interface{}) {
 
 	case BGPEventAutoStop, BGPEventHoldTimerExp, BGPEventKeepAliveTimerExp, BGPEventIdleHoldTimerExp,
 		BGPEventBGPOpen, BGPEventOpenCollisionDump, BGPEventNotifMsg, BGPEventKeepAliveMsg,
-		BGPEventUpdateMsg, BGPEventUpdateMsgErr: // 8, 10, 11, 13, 19, 23, 25-28
+		BGPEventUpdateMsg, BGPEventUpdateMsgErr, BGPEventRouteRefreshMsg: // 8, 10, 11, 13, 19, 23, 25-28
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.StopConnToPeer()
 		st.fsm.IncrConnectRetryCounter()
@@ -345,6 +348,9 @@ func (st *ActiveState) processEvent(event BGPFSMEvent, data interface{}) {
 
 	case BGPEventConnRetryTimerExp:
 		st.fsm.StartConnectRetryTimer()
+		if st.fsm.IsPassive() {
+			break
+		}
 		st.fsm.ChangeState(NewConnectState(st.fsm))
 
 	case BGPEventDelayOpenTimerExp: // Supported later
@@ -387,7 +393,7 @@ func (st *ActiveState) processEvent(event BGPFSMEvent, data interface{}) {
 
 	case BGPEventAutoStop, BGPEventHoldTimerExp, BGPEventKeepAliveTimerExp, BGPEventIdleHoldTimerExp,
 		BGPEventBGPOpen, BGPEventOpenCollisionDump, BGPEventNotifMsg, BGPEventKeepAliveMsg,
-		BGPEventUpdateMsg, BGPEventUpdateMsgErr: // 8, 10, 11, 13, 19, 23, 25-28
+		BGPEventUpdateMsg, BGPEventUpdateMsgErr, BGPEventRouteRefreshMsg: // 8, 10, 11, 13, 19, 23, 25-28
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.ClearPeerConn()
 		st.fsm.StopConnToPeer()
@@ -430,7 +436,7 @@ func (st *OpenSentState) processEvent(event BGPFSMEvent, data interface{}) {
 
 	switch event {
 	case BGPEventManualStop:
-		st.fsm.SendNotificationMessage(packet.BGPCease, 0, nil)
+		st.fsm.SendCeaseNotificationMessage()
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.ClearPeerConn()
 		st.fsm.StopConnToPeer()
@@ -438,7 +444,7 @@ func (st *OpenSentState) processEvent(event BGPFSMEvent, data interface{}) {
 		st.fsm.ChangeState(NewIdleState(st.fsm))
 
 	case BGPEventAutoStop:
-		st.fsm.SendNotificationMessage(packet.BGPCease, 0, nil)
+		st.fsm.SendCeaseNotificationMessage()
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.ClearPeerConn()
 		st.fsm.StopConnToPeer()
@@ -483,7 +489,7 @@ func (st *OpenSentState) processEvent(event BGPFSMEvent, data interface{}) {
 		st.fsm.ChangeState(NewIdleState(st.fsm))
 
 	case BGPEventOpenCollisionDump:
-		st.fsm.SendNotificationMessage(packet.BGPCease, 0, nil)
+		st.fsm.SendNotificationMessage(packet.BGPCease, packet.BGPCeaseConnCollision, nil)
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.ClearPeerConn()
 		st.fsm.StopConnToPeer()
@@ -498,7 +504,8 @@ func (st *OpenSentState) processEvent(event BGPFSMEvent, data interface{}) {
 
 	case BGPEventConnRetryTimerExp, BGPEventKeepAliveTimerExp, BGPEventDelayOpenTimerExp,
 		BGPEventIdleHoldTimerExp, BGPEventBGPOpenDelayOpenTimer, BGPEventNotifMsg,
-		BGPEventKeepAliveMsg, BGPEventUpdateMsg, BGPEventUpdateMsgErr: // 9, 11, 12, 13, 20, 25-28
+		BGPEventKeepAliveMsg, BGPEventUpdateMsg, BGPEventUpdateMsgErr,
+		BGPEventRouteRefreshMsg: // 9, 11, 12, 13, 20, 25-28
 		st.fsm.SendNotificationMessage(packet.BGPFSMError, 0, nil)
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.ClearPeerConn()
@@ -542,7 +549,7 @@ func (st *OpenConfirmState) processEvent(event BGPFSMEvent, data interface{}) {
 
 	switch event {
 	case BGPEventManualStop:
-		st.fsm.SendNotificationMessage(packet.BGPCease, 0, nil)
+		st.fsm.SendCeaseNotificationMessage()
 		st.fsm.ClearPeerConn()
 		st.fsm.StopConnToPeer()
 		st.fsm.StopConnectRetryTimer()
@@ -550,7 +557,7 @@ func (st *OpenConfirmState) processEvent(event BGPFSMEvent, data interface{}) {
 		st.fsm.ChangeState(NewIdleState(st.fsm))
 
 	case BGPEventAutoStop:
-		st.fsm.SendNotificationMessage(packet.BGPCease, 0, nil)
+		st.fsm.SendCeaseNotificationMessage()
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.ClearPeerConn()
 		st.fsm.StopConnToPeer()
@@ -592,7 +599,7 @@ func (st *OpenConfirmState) processEvent(event BGPFSMEvent, data interface{}) {
 		st.fsm.ChangeState(NewIdleState(st.fsm))
 
 	case BGPEventOpenCollisionDump:
-		st.fsm.SendNotificationMessage(packet.BGPCease, 0, nil)
+		st.fsm.SendNotificationMessage(packet.BGPCease, packet.BGPCeaseConnCollision, nil)
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.ClearPeerConn()
 		st.fsm.StopConnToPeer()
@@ -610,7 +617,8 @@ func (st *OpenConfirmState) processEvent(event BGPFSMEvent, data interface{}) {
 		st.fsm.ChangeState(NewEstablishedState(st.fsm))
 
 	case BGPEventConnRetryTimerExp, BGPEventDelayOpenTimerExp, BGPEventIdleHoldTimerExp,
-		BGPEventBGPOpenDelayOpenTimer, BGPEventUpdateMsg, BGPEventUpdateMsgErr: // 9, 12, 13, 20, 27, 28
+		BGPEventBGPOpenDelayOpenTimer, BGPEventUpdateMsg, BGPEventUpdateMsgErr,
+		BGPEventRouteRefreshMsg: // 9, 12, 13, 20, 27, 28
 		st.fsm.SendNotificationMessage(packet.BGPCease, 0, nil)
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.ClearPeerConn()
@@ -657,7 +665,7 @@ func (st *EstablishedState) processEvent(event BGPFSMEvent, data interface{}) {
 
 	switch event {
 	case BGPEventManualStop:
-		st.fsm.SendNotificationMessage(packet.BGPCease, 0, nil)
+		st.fsm.SendCeaseNotificationMessage()
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.ClearPeerConn()
 		st.fsm.StopConnToPeer()
@@ -665,7 +673,7 @@ func (st *EstablishedState) processEvent(event BGPFSMEvent, data interface{}) {
 		st.fsm.ChangeState(NewIdleState(st.fsm))
 
 	case BGPEventAutoStop:
-		st.fsm.SendNotificationMessage(packet.BGPCease, 0, nil)
+		st.fsm.SendCeaseNotificationMessage()
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.ClearPeerConn()
 		st.fsm.StopConnToPeer()
@@ -699,7 +707,7 @@ func (st *EstablishedState) processEvent(event BGPFSMEvent, data interface{}) {
 	case BGPEventBGPOpen: // Collistion detection... needs work
 
 	case BGPEventOpenCollisionDump:
-		st.fsm.SendNotificationMessage(packet.BGPCease, 0, nil)
+		st.fsm.SendNotificationMessage(packet.BGPCease, packet.BGPCeaseConnCollision, nil)
 		st.fsm.StopConnectRetryTimer()
 		st.fsm.ClearPeerConn()
 		st.fsm.StopConnToPeer()
@@ -714,6 +722,11 @@ func (st *EstablishedState) processEvent(event BGPFSMEvent, data interface{}) {
 		bgpMsg := data.(*packet.BGPMessage)
 		st.fsm.ProcessUpdateMessage(bgpMsg)
 
+	case BGPEventRouteRefreshMsg:
+		st.fsm.StartHoldTimer()
+		bgpMsg := data.(*packet.BGPMessage)
+		st.fsm.ProcessRouteRefreshMessage(bgpMsg)
+
 	case BGPEventUpdateMsgErr:
 		bgpMsgErr := data.(*packet.BGPMessageError)
 		st.fsm.SendNotificationMessage(bgpMsgErr.TypeCode, bgpMsgErr.SubTypeCode, bgpMsgErr.Data)
@@ -828,6 +841,8 @@ type FSM struct {
 	delayOpenTimer *time.Timer
 
 	afiSafiMap  map[uint32]bool
+	sentOpen    *packet.BGPMessage
+	rcvdOpen    *packet.BGPMessage
 	pktTxCh     chan *packet.BGPMessage
 	pktRxCh     chan *packet.BGPPktInfo
 	eventRxCh   chan PeerFSMEvent
@@ -859,7 +874,7 @@ func NewFSM(fsmManager *FSMManager, id uint8, neighborConf *base.NeighborConf) *
 		outTCPConn:       nil,
 		autoStart:        true,
 		autoStop:         true,
-		passiveTcpEst:    false,
+		passiveTcpEst:    neighborConf.Neighbor.Transport.Config.PassiveMode,
 		passiveTcpEstCh:  make(chan bool, 2),
 		dampPeerOscl:     false,
 		idleHoldTime:     BGPIdleHoldTimeDefault,
@@ -903,7 +918,7 @@ func (fsm *FSM) StartFSM() {
 		fsm.State = NewIdleState(fsm)
 	}
 	fsm.State.enter()
-	fsm.ProcessEvent(BGPEventAutoStart, nil)
+	fsm.sendAutoStartEvent()
 
 	for {
 		select {
@@ -954,10 +969,14 @@ func (fsm *FSM) StartFSM() {
 					"is not in Established state, can't send the UPDATE message")
 				continue
 			}
-			fsm.sendUpdateMessage(bgpMsg)
+			if bgpMsg.Header.Type == packet.BGPMsgTypeRouteRefresh {
+				fsm.sendRouteRefreshMessage(bgpMsg)
+			} else {
+				fsm.sendUpdateMessage(bgpMsg)
+			}
 
 		case bgpPktInfo := <-fsm.pktRxCh:
-			fsm.ProcessPacket(bgpPktInfo.Msg, bgpPktInfo.MsgError)
+			fsm.ProcessPacket(bgpPktInfo.Msg, bgpPktInfo.MsgError, bgpPktInfo.Data)
 
 		case fsmEvent := <-fsm.eventRxCh:
 			fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id, "Received event", fsmEvent.event,
@@ -1000,10 +1019,14 @@ func (fsm *FSM) ProcessEvent(event BGPFSMEvent, data interface{}) {
 	fsm.State.processEvent(event, data)
 }
 
-func (fsm *FSM) ProcessPacket(msg *packet.BGPMessage, msgErr *packet.BGPMessageError) {
+func (fsm *FSM) ProcessPacket(msg *packet.BGPMessage, msgErr *packet.BGPMessageError, pkt []byte) {
 	var event BGPFSMEvent
 	var data interface{}
 
+	if pkt != nil && fsm.Manager.mrtWriter.IsEnabled() {
+		fsm.logMRTMessage(pkt)
+	}
+
 	if msgErr != nil {
 		data = msgErr
 		switch msgErr.TypeCode {
@@ -1031,9 +1054,13 @@ func (fsm *FSM) ProcessPacket(msg *packet.BGPMessage, msgErr *packet.BGPMessageE
 			notifyMsg := msg.Body.(*packet.BGPNotification)
 			fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id, "Received notification message:",
 				notifyMsg.ErrorCode, notifyMsg.ErrorSubcode, notifyMsg.Data)
+			fsm.neighborConf.SetLastNotification(msg, false)
 
 		case packet.BGPMsgTypeKeepAlive:
 			event = BGPEventKeepAliveMsg
+
+		case packet.BGPMsgTypeRouteRefresh:
+			event = BGPEventRouteRefreshMsg
 		}
 	}
 	if event != BGPEventKeepAliveMsg {
@@ -1059,6 +1086,9 @@ func (fsm *FSM) ChangeState(newState BaseStateIface) {
 		fsm.ConnEstablished()
 	}
 	fsm.Manager.fsmStateChange(fsm.id, fsm.State.state())
+	if oldState != config.BGPFSMEstablished && fsm.State.state() == config.BGPFSMIdle && !fsm.close {
+		fsm.Manager.fsmIdle(fsm.id)
+	}
 }
 
 func (fsm *FSM) sendAutoStartEvent() {
@@ -1094,6 +1124,10 @@ func (fsm *FSM) SetPassiveTcpEstablishment(flag bool) {
 	fsm.passiveTcpEst = flag
 }
 
+func (fsm *FSM) IsPassive() bool {
+	return fsm.neighborConf.Neighbor.Transport.Config.PassiveMode
+}
+
 func (fsm *FSM) StartConnectRetryTimer() {
 	fsm.StopConnectRetryTimer()
 	fsm.connectRetryTimer.Reset(time.Duration(fsm.connectRetryTime) * time.Second)
@@ -1171,8 +1205,26 @@ func (fsm *FSM) HandleAnotherConnection(data interface{}) {
 	fsm.Manager.newConnCh <- PeerFSMConnState{false, fsm.id, pConnDir.connDir, pConnDir.conn}
 }
 
+// logMRTMessage logs the message as it was received on the wire
+func (fsm *FSM) logMRTMessage(pkt []byte) {
+	var localIP net.IP
+	if fsm.peerConn != nil {
+		if host, _, err := net.SplitHostPort((*fsm.peerConn.conn).LocalAddr().String()); err == nil {
+			localIP = net.ParseIP(strings.Split(host, "%")[0])
+		}
+	}
+
+	mrtMsg := mrt.NewMRTBGP4MPMessage(time.Now(), fsm.pConf.PeerAS, fsm.pConf.LocalAS, fsm.pConf.NeighborAddress,
+		localIP, pkt)
+	if err := fsm.Manager.mrtWriter.Write(mrtMsg); err != nil {
+		fsm.logger.Err("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id, "Failed to write MRT message, error:",
+			err)
+	}
+}
+
 func (fsm *FSM) ProcessOpenMessage(pkt *packet.BGPMessage) bool {
 	body := pkt.Body.(*packet.BGPOpen)
+	fsm.rcvdOpen = pkt
 	if uint32(body.HoldTime) < fsm.holdTime {
 		fsm.SetHoldTime(uint32(body.HoldTime), uint32(body.HoldTime/3))
 	}
@@ -1211,6 +1263,47 @@ func (fsm *FSM) ProcessUpdateMessage(pkt *packet.BGPMessage) {
 	}()
 }
 
+func (fsm *FSM) ProcessRouteRefreshMessage(pkt *packet.BGPMessage) {
+	refreshMsg := pkt.Body.(*packet.BGPRouteRefresh)
+	protoFamily := packet.GetProtocolFamily(refreshMsg.AFI, refreshMsg.SAFI)
+	if !fsm.afiSafiMap[protoFamily] {
+		fsm.logger.Warning("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id,
+			"Received ROUTE-REFRESH for AFI", refreshMsg.AFI, "SAFI", refreshMsg.SAFI, "that was not negotiated")
+		return
+	}
+
+	if refreshMsg.SubType != packet.BGPRouteRefreshNormal {
+		fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id,
+			"Ignore ROUTE-REFRESH with subtype", refreshMsg.SubType)
+		return
+	}
+
+	fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id,
+		"ProcessRouteRefreshMessage: send message to server")
+	go func() {
+		fsm.Manager.bgpPktSrcCh <- packet.NewBGPPktSrc(fsm.Manager.neighborConf.Neighbor.NeighborAddress.String(), pkt)
+	}()
+}
+
+func (fsm *FSM) sendRouteRefreshMessage(bgpMsg *packet.BGPMessage) {
+	packet, err := bgpMsg.Encode()
+	if err != nil {
+		fsm.logger.Errf("Neighbor:%s FSM %d Failed to encode ROUTE-REFRESH message", fsm.pConf.NeighborAddress,
+			fsm.id)
+		return
+	}
+
+	num, err := (*fsm.peerConn.conn).Write(packet)
+	if err != nil {
+		fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id,
+			"Conn.Write failed to send Route Refresh message with error:", err)
+		return
+	}
+	fsm.StartKeepAliveTimer()
+	fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id,
+		"Conn.Write succeeded. sent Route Refresh message of", num, "bytes")
+}
+
 func (fsm *FSM) sendUpdateMessage(bgpMsg *packet.BGPMessage) {
 	fsm.logger.Infof("Neighbor:%s FSM %d Send BGP update message %+v", fsm.pConf.NeighborAddress, fsm.id, bgpMsg)
 	updateMsgs := packet.ConstructMaxSizedUpdatePackets(bgpMsg)
@@ -1240,9 +1333,28 @@ func (fsm *FSM) sendUpdateMessage(bgpMsg *packet.BGPMessage) {
 func (fsm *FSM) sendOpenMessage() {
 	fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id,
 		"sendOpenMessage: send address family", fsm.neighborConf.AfiSafiMap)
-	optParams := packet.ConstructOptParams(uint32(fsm.pConf.LocalAS), fsm.neighborConf.AfiSafiMap,
-		fsm.neighborConf.RunningConf.AddPathsRx, fsm.neighborConf.RunningConf.AddPathsMaxTx)
+	var grCap *packet.BGPCapGracefulRestart
+	if fsm.gConf.GracefulRestart {
+		grCap = packet.NewBGPCapGracefulRestart(time.Now().Before(fsm.neighborConf.RestartDeadline),
+			uint16(fsm.gConf.RestartTime))
+	}
+	optParams := packet.ConstructOptParams(uint32(fsm.pConf.LocalAS), fsm.neighborConf.AfiSafiMap, false, 0, grCap,
+		fsm.gConf.PreserveForwardingState)
+	if addPathCap := packet.ConstructAddPathCap(fsm.neighborConf.GetAddPathsFlags()); addPathCap != nil {
+		optParams = append(optParams, packet.NewBGPOptParamCapability([]packet.BGPCapability{addPathCap}))
+	}
+	if fsm.neighborConf.RunningConf.ExtendedNextHop {
+		if extNHCap := packet.ConstructExtendedNextHopCap(fsm.neighborConf.AfiSafiMap); extNHCap != nil {
+			optParams = append(optParams, packet.NewBGPOptParamCapability([]packet.BGPCapability{extNHCap}))
+		}
+	}
+	if fsm.neighborConf.RunningConf.ORFPrefixReceive {
+		if orfCap := packet.ConstructORFCap(fsm.neighborConf.AfiSafiMap); orfCap != nil {
+			optParams = append(optParams, packet.NewBGPOptParamCapability([]packet.BGPCapability{orfCap}))
+		}
+	}
 	bgpOpenMsg := packet.NewBGPOpenMessage(fsm.pConf.LocalAS, uint16(fsm.holdTime), fsm.gConf.RouterId.To4().String(), optParams)
+	fsm.sentOpen = bgpOpenMsg
 	packet, _ := bgpOpenMsg.Encode()
 	num, err := (*fsm.peerConn.conn).Write(packet)
 	if err != nil {
@@ -1275,10 +1387,20 @@ func (fsm *FSM) SendNotificationMessage(code uint8, subCode uint8, data []byte)
 		return
 	}
 	fsm.neighborConf.Neighbor.State.Messages.Sent.Notification++
+	fsm.neighborConf.SetLastNotification(bgpNotifMsg, true)
 	fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id,
 		"Conn.Write succeeded. sent Notification message with", num, "bytes")
 }
 
+func (fsm *FSM) SendCeaseNotificationMessage() {
+	subCode, msg := fsm.neighborConf.TakeShutdownCommunication()
+	var data []byte
+	if subCode == packet.BGPCeaseAdminShutdown || subCode == packet.BGPCeaseAdminReset {
+		data = packet.NewBGPShutdownCommunication(msg)
+	}
+	fsm.SendNotificationMessage(packet.BGPCease, subCode, data)
+}
+
 func (fsm *FSM) SetPeerConn(data interface{}) {
 	fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id, "SetPeerConn called")
 	if fsm.peerConn != nil {
@@ -1319,13 +1441,16 @@ func (fsm *FSM) ClearPeerConn() {
 
 func (fsm *FSM) ConnEstablished() {
 	fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id, "ConnEstablished - start")
+	fsm.neighborConf.SentOpen = fsm.sentOpen
+	fsm.neighborConf.ReceivedOpen = fsm.rcvdOpen
 	fsm.Manager.fsmEstablished(fsm.id, fsm.peerConn.conn)
 	fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id, "ConnEstablished - end")
 }
 
 func (fsm *FSM) ConnBroken() {
 	fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id, "ConnBroken - start")
-	fsm.Manager.fsmBroken(fsm.id, false)
+	// Only an unexpected TCP failure keeps the routes as stale, a NOTIFICATION ends the session for good
+	fsm.Manager.fsmBroken(fsm.id, false, fsm.event == BGPEventTcpConnFails)
 	fsm.logger.Info("Neighbor:", fsm.pConf.NeighborAddress, "FSM", fsm.id, "ConnBroken - end")
 }
 
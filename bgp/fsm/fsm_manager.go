@@ -28,16 +28,20 @@ import (
 	_ "fmt"
 	"l3/bgp/baseobjects"
 	"l3/bgp/config"
+	"l3/bgp/mrt"
 	"l3/bgp/packet"
 	"net"
 	"sync"
+	"sync/atomic"
 	"utils/logging"
 )
 
 type PeerFSMConn struct {
-	PeerIP      string
-	Established bool
-	Conn        *net.Conn
+	PeerIP          string
+	Established     bool
+	Conn            *net.Conn
+	GracefulRestart bool
+	Idle            bool
 }
 
 type PeerFSMState struct {
@@ -74,6 +78,7 @@ type FSMManager struct {
 	peerAttrsCh    chan PeerAttrs
 	bgpPktSrcCh    chan *packet.BGPPktSrc
 	reachabilityCh chan config.ReachabilityInfo
+	mrtWriter      *mrt.MRTWriter
 	fsms           map[uint8]*FSM
 	AcceptCh       chan net.Conn
 	tcpConnFailCh  chan uint8
@@ -88,7 +93,7 @@ type FSMManager struct {
 }
 
 func NewFSMManager(logger *logging.Writer, neighborConf *base.NeighborConf, bgpPktSrcCh chan *packet.BGPPktSrc,
-	fsmConnCh chan PeerFSMConn, reachabilityCh chan config.ReachabilityInfo) *FSMManager {
+	fsmConnCh chan PeerFSMConn, reachabilityCh chan config.ReachabilityInfo, mrtWriter *mrt.MRTWriter) *FSMManager {
 	mgr := FSMManager{
 		logger:         logger,
 		neighborConf:   neighborConf,
@@ -97,6 +102,7 @@ func NewFSMManager(logger *logging.Writer, neighborConf *base.NeighborConf, bgpP
 		fsmConnCh:      fsmConnCh,
 		bgpPktSrcCh:    bgpPktSrcCh,
 		reachabilityCh: reachabilityCh,
+		mrtWriter:      mrtWriter,
 	}
 	mgr.fsms = make(map[uint8]*FSM)
 	mgr.AcceptCh = make(chan net.Conn)
@@ -124,6 +130,18 @@ func (mgr *FSMManager) Init() {
 		select {
 		case inConn := <-mgr.AcceptCh:
 			mgr.logger.Infof("Neighbor %s: Received a connection OPEN from far end", mgr.pConf.NeighborAddress)
+			if mgr.pConf.TTLSecurity {
+				if err := CheckTTLSecurity(inConn, mgr.pConf.TTLSecurityHops); err != nil {
+					mgr.logger.Infof("Neighbor %s: Reject connection, TTL security check failed with error %s",
+						mgr.pConf.NeighborAddress, err)
+					if _, ok := err.(TTLSecurityError); ok {
+						atomic.AddUint32(&mgr.neighborConf.Neighbor.State.TTLSecurityDrops, 1)
+					}
+					inConn.Close()
+					break
+				}
+			}
+
 			if !mgr.acceptConn {
 				mgr.logger.Info("Can't accept connection from ", mgr.pConf.NeighborAddress, "yet.")
 				inConn.Close()
@@ -221,7 +239,7 @@ func (mgr *FSMManager) fsmClose(id uint8) {
 	if closeFSM, ok := mgr.fsms[id]; ok {
 		mgr.logger.Infof("FSMManager: Peer %s, close FSM %d", mgr.pConf.NeighborAddress.String(), id)
 		closeFSM.closeCh <- true
-		mgr.fsmBroken(id, false)
+		mgr.fsmBroken(id, false, false)
 		mgr.fsms[id] = nil
 		delete(mgr.fsms, id)
 		mgr.logger.Infof("FSMManager: Peer %s, closed FSM %d", mgr.pConf.NeighborAddress.String(), id)
@@ -235,7 +253,7 @@ func (mgr *FSMManager) fsmEstablished(id uint8, conn *net.Conn) {
 	mgr.logger.Infof("FSMManager: Peer %s FSM %d connection established", mgr.pConf.NeighborAddress.String(), id)
 	if _, ok := mgr.fsms[id]; ok {
 		mgr.activeFSM = id
-		mgr.fsmConnCh <- PeerFSMConn{mgr.neighborConf.Neighbor.NeighborAddress.String(), true, conn}
+		mgr.fsmConnCh <- PeerFSMConn{mgr.neighborConf.Neighbor.NeighborAddress.String(), true, conn, false, false}
 	} else {
 		mgr.logger.Infof("FSMManager: Peer %s FSM %d not found in fsms dict %v", mgr.pConf.NeighborAddress.String(),
 			id, mgr.fsms)
@@ -243,15 +261,29 @@ func (mgr *FSMManager) fsmEstablished(id uint8, conn *net.Conn) {
 	//mgr.Peer.PeerConnEstablished(conn)
 }
 
-func (mgr *FSMManager) fsmBroken(id uint8, fsmDelete bool) {
-	mgr.logger.Infof("FSMManager: Peer %s FSM %d connection broken", mgr.pConf.NeighborAddress.String(), id)
+func (mgr *FSMManager) fsmBroken(id uint8, fsmDelete bool, gracefulRestart bool) {
+	mgr.logger.Infof("FSMManager: Peer %s FSM %d connection broken, graceful restart %t",
+		mgr.pConf.NeighborAddress.String(), id, gracefulRestart)
 	if mgr.activeFSM == id {
 		mgr.activeFSM = uint8(config.ConnDirInvalid)
-		mgr.fsmConnCh <- PeerFSMConn{mgr.neighborConf.Neighbor.NeighborAddress.String(), false, nil}
+		mgr.fsmConnCh <- PeerFSMConn{mgr.neighborConf.Neighbor.NeighborAddress.String(), false, nil,
+			gracefulRestart, false}
 		//mgr.Peer.PeerConnBroken(fsmDelete)
 	}
 }
 
+func (mgr *FSMManager) fsmIdle(id uint8) {
+	mgr.fsmMutex.RLock()
+	defer mgr.fsmMutex.RUnlock()
+
+	if !mgr.pConf.Dynamic || mgr.activeFSM != uint8(config.ConnDirInvalid) {
+		return
+	}
+	mgr.logger.Infof("FSMManager: Dynamic neighbor %s FSM %d moved to idle without establishing a session",
+		mgr.pConf.NeighborAddress, id)
+	mgr.fsmConnCh <- PeerFSMConn{mgr.neighborConf.Neighbor.NeighborAddress.String(), false, nil, false, true}
+}
+
 func (mgr *FSMManager) fsmStateChange(id uint8, state config.BGPFSMState) {
 	mgr.fsmMutex.Lock()
 	defer mgr.fsmMutex.Unlock()
@@ -273,6 +305,19 @@ func (mgr *FSMManager) SendUpdateMsg(bgpMsg *packet.BGPMessage) {
 	mgr.fsms[mgr.activeFSM].pktTxCh <- bgpMsg
 }
 
+func (mgr *FSMManager) SendRouteRefreshMsg(afi packet.AFI, safi packet.SAFI) {
+	mgr.fsmMutex.RLock()
+	defer mgr.fsmMutex.RUnlock()
+
+	if mgr.activeFSM == uint8(config.ConnDirInvalid) {
+		mgr.logger.Infof("FSMManager: Neighbor %s FSM is not in ESTABLISHED state", mgr.pConf.NeighborAddress)
+		return
+	}
+	mgr.logger.Infof("FSMManager: Neighbor %s FSM %d - send route refresh for afi %d safi %d",
+		mgr.pConf.NeighborAddress, mgr.activeFSM, afi, safi)
+	mgr.fsms[mgr.activeFSM].pktTxCh <- packet.NewBGPRouteRefreshMessage(afi, safi, packet.BGPRouteRefreshNormal)
+}
+
 func (mgr *FSMManager) Cleanup() {
 	mgr.fsmMutex.Lock()
 	defer mgr.fsmMutex.Unlock()
@@ -282,7 +327,7 @@ func (mgr *FSMManager) Cleanup() {
 			mgr.logger.Infof("FSMManager: Neighbor %s FSM %d - cleanup FSM", mgr.pConf.NeighborAddress, id)
 			fsm.closeCh <- true
 			fsm = nil
-			mgr.fsmBroken(id, true)
+			mgr.fsmBroken(id, true, false)
 			mgr.fsmStateChange(id, config.BGPFSMIdle)
 			mgr.fsms[id] = nil
 			delete(mgr.fsms, id)
@@ -298,7 +343,7 @@ func (mgr *FSMManager) StopFSM(stopMsg string) {
 		if fsm != nil {
 			mgr.logger.Infof("FSMManager: Neighbor %s FSM %d - Stop FSM", mgr.pConf.NeighborAddress, id)
 			fsm.eventRxCh <- PeerFSMEvent{BGPEventTcpConnFails, BGPCmdReasonNone}
-			mgr.fsmBroken(id, false)
+			mgr.fsmBroken(id, false, false)
 		}
 	}
 }
@@ -379,10 +424,19 @@ func (mgr *FSMManager) receivedBGPOpenMessage(id uint8, connDir config.ConnDir,
 	if closeFSMId == uint8(config.ConnDirInvalid) || closeFSMId != id {
 		asSize := packet.GetASSize(openMsg)
 		addPathFamily := packet.GetAddPathFamily(openMsg)
+		routeRefresh := packet.IsRouteRefreshSupported(openMsg)
+		grCap := packet.GetGracefulRestartCap(openMsg)
+		extNHFamily := packet.GetExtendedNextHopFamily(openMsg)
+		orfFamily := packet.GetORFPrefixSendFamily(openMsg)
 		if mgr.fsms[id] != nil {
 			mgr.logger.Infof("FSMManager - Neighbor %s: FSM %d set peer attr", mgr.pConf.NeighborAddress, id)
 			mgr.neighborConf.SetPeerAttrs(openMsg.BGPId, asSize, mgr.fsms[id].holdTime, mgr.fsms[id].keepAliveTime,
 				addPathFamily)
+			mgr.neighborConf.RouteRefresh = routeRefresh
+			mgr.neighborConf.GracefulRestartCap = grCap
+			mgr.neighborConf.PeerAfiSafiMap = packet.GetProtocolFromOpenMsg(openMsg)
+			mgr.neighborConf.SetExtendedNextHop(extNHFamily)
+			mgr.neighborConf.SetORFPrefixReceive(orfFamily)
 		}
 	}
 
@@ -0,0 +1,160 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// gtsm.go
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"l3/bgp/keychain"
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	GTSMMaxTTL = 255
+
+	sockoptIPv6MinHopCount = 73 // IPV6_MINHOPCOUNT
+	sockoptTCPSaveSyn      = 27 // TCP_SAVE_SYN
+	sockoptTCPSavedSyn     = 28 // TCP_SAVED_SYN
+	savedSynMaxLen         = 512
+)
+
+type TTLSecurityError struct {
+	TTL    uint8
+	MinTTL uint8
+}
+
+func (e TTLSecurityError) Error() string {
+	return fmt.Sprintf("TTL %d of the connection request is less than %d", e.TTL, e.MinTTL)
+}
+
+func getGTSMMinTTL(hops uint8) uint8 {
+	return uint8(GTSMMaxTTL - int(hops))
+}
+
+// setTTLSecurity sends the packets on the socket with the maximum TTL and makes the kernel drop the segments
+// that were received with a TTL less than 255 - hops, as described in RFC 5082
+func setTTLSecurity(fd int, ipv6 bool, hops uint8) error {
+	minTTL := int(getGTSMMinTTL(hops))
+	if ipv6 {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, GTSMMaxTTL); err != nil {
+			return err
+		}
+		return syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, sockoptIPv6MinHopCount, minTTL)
+	}
+
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_TTL, GTSMMaxTTL); err != nil {
+		return err
+	}
+	return syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_MINTTL, minTTL)
+}
+
+func clearTTLSecurity(fd int, ipv6 bool) error {
+	if ipv6 {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, -1); err != nil {
+			return err
+		}
+		return syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, sockoptIPv6MinHopCount, 0)
+	}
+
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_TTL, -1); err != nil {
+		return err
+	}
+	return syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_MINTTL, 0)
+}
+
+// SetListenerTTLSecurity sets TTL security on the listener so that the SYN-ACKs are sent with the maximum TTL
+// and the accepted connections inherit it. TTL security is removed from the listener if enable is false.
+func SetListenerTTLSecurity(listener *net.TCPListener, ipv6 bool, enable bool, hops uint8) error {
+	return keychain.ControlFd(listener, func(fd int) error {
+		if !enable {
+			return clearTTLSecurity(fd, ipv6)
+		}
+		return setTTLSecurity(fd, ipv6, hops)
+	})
+}
+
+// EnableSavedSyn makes the kernel save the SYN of the connections accepted on the listener so that the TTL of
+// the connection request can be checked for the peers with TTL security
+func EnableSavedSyn(listener *net.TCPListener) error {
+	return keychain.ControlFd(listener, func(fd int) error {
+		return syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, sockoptTCPSaveSyn, 1)
+	})
+}
+
+func getSavedSynTTL(fd int) (uint8, error) {
+	buf := make([]byte, savedSynMaxLen)
+	length := uint32(len(buf))
+	_, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT, uintptr(fd), syscall.IPPROTO_TCP, sockoptTCPSavedSyn,
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&length)), 0)
+	if errno != 0 {
+		return 0, errno
+	}
+
+	if length == 0 {
+		return 0, errors.New("SYN is not saved for the connection")
+	}
+
+	switch buf[0] >> 4 {
+	case 4:
+		if length >= 20 {
+			return buf[8], nil
+		}
+	case 6:
+		if length >= 40 {
+			return buf[7], nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("Saved SYN with length %d is not valid", length))
+}
+
+func isIPv6Conn(conn net.Conn) bool {
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP.To4() == nil
+	}
+	return false
+}
+
+// CheckTTLSecurity checks the TTL of the SYN of an accepted connection and sets TTL security on the connection.
+// TTLSecurityError is returned if the connection request came from more than hops away.
+func CheckTTLSecurity(conn net.Conn, hops uint8) error {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return errors.New("Connection is not a TCP connection")
+	}
+
+	return keychain.ControlFd(tcpConn, func(fd int) error {
+		ttl, err := getSavedSynTTL(fd)
+		if err != nil {
+			return err
+		}
+
+		if minTTL := getGTSMMinTTL(hops); ttl < minTTL {
+			return TTLSecurityError{ttl, minTTL}
+		}
+		return setTTLSecurity(fd, isIPv6Conn(conn), hops)
+	})
+}
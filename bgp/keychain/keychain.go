@@ -0,0 +1,172 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// keychain.go
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"l3/bgp/config"
+	"sort"
+	"time"
+)
+
+const (
+	AlgorithmMD5        = "md5"
+	AlgorithmHMACSHA1   = "hmac-sha-1-96"
+	AlgorithmAESCMAC    = "aes-128-cmac-96"
+	AlgorithmHMACSHA256 = "hmac-sha-256"
+)
+
+const MaxKeyLen = 80
+
+const (
+	AuthTypeMD5   = "md5"
+	AuthTypeTCPAO = "tcp-ao"
+)
+
+// Crypto API names of the TCP-AO algorithms and their MAC lengths
+var tcpAOAlgorithms = map[string]struct {
+	name   string
+	macLen uint8
+}{
+	AlgorithmHMACSHA1:   {"hmac(sha1)", 12},
+	AlgorithmAESCMAC:    {"cmac(aes128)", 12},
+	AlgorithmHMACSHA256: {"hmac(sha256)", 16},
+}
+
+type KeyChain struct {
+	Name string
+	Keys []config.KeyChainKey
+}
+
+type keyList []config.KeyChainKey
+
+func (k keyList) Len() int {
+	return len(k)
+}
+
+func (k keyList) Swap(i, j int) {
+	k[i], k[j] = k[j], k[i]
+}
+
+func (k keyList) Less(i, j int) bool {
+	return k[i].KeyId < k[j].KeyId
+}
+
+func NewKeyChain(cfg config.KeyChainConfig) (*KeyChain, error) {
+	keys := make(keyList, 0, len(cfg.Keys))
+	keyIds := make(map[uint8]bool)
+	for _, key := range cfg.Keys {
+		if keyIds[key.KeyId] {
+			return nil, errors.New(fmt.Sprintf("Key chain %s has duplicate key id %d", cfg.Name, key.KeyId))
+		}
+		keyIds[key.KeyId] = true
+
+		if key.Secret == "" || len(key.Secret) > MaxKeyLen {
+			return nil, errors.New(fmt.Sprintf("Key chain %s key %d secret length %d is not valid", cfg.Name,
+				key.KeyId, len(key.Secret)))
+		}
+
+		if key.Algorithm == "" {
+			key.Algorithm = AlgorithmMD5
+		}
+		if _, ok := tcpAOAlgorithms[key.Algorithm]; !ok && key.Algorithm != AlgorithmMD5 {
+			return nil, errors.New(fmt.Sprintf("Key chain %s key %d algorithm %s is not supported", cfg.Name,
+				key.KeyId, key.Algorithm))
+		}
+		keys = append(keys, key)
+	}
+	sort.Sort(keys)
+
+	return &KeyChain{
+		Name: cfg.Name,
+		Keys: keys,
+	}, nil
+}
+
+func isActive(start, end, now time.Time) bool {
+	return (start.IsZero() || !now.Before(start)) && (end.IsZero() || now.Before(end))
+}
+
+func isTCPAOKey(key *config.KeyChainKey) bool {
+	_, ok := tcpAOAlgorithms[key.Algorithm]
+	return ok
+}
+
+// GetSendKey returns the key to sign the outgoing segments with. When more than one key is in its send
+// lifetime, the key with the latest send start time is used. Only the keys of the TCP-AO algorithms are
+// used for TCP-AO and only the MD5 keys are used otherwise.
+func (k *KeyChain) GetSendKey(now time.Time, tcpAO bool) *config.KeyChainKey {
+	var sendKey *config.KeyChainKey
+	for idx := range k.Keys {
+		key := &k.Keys[idx]
+		if isTCPAOKey(key) != tcpAO || !isActive(key.SendLifetimeStart, key.SendLifetimeEnd, now) {
+			continue
+		}
+		if sendKey == nil || !key.SendLifetimeStart.Before(sendKey.SendLifetimeStart) {
+			sendKey = key
+		}
+	}
+	return sendKey
+}
+
+// GetAcceptKeys returns the keys that the incoming segments can be verified with
+func (k *KeyChain) GetAcceptKeys(now time.Time, tcpAO bool) []*config.KeyChainKey {
+	keys := make([]*config.KeyChainKey, 0)
+	for idx := range k.Keys {
+		key := &k.Keys[idx]
+		if isTCPAOKey(key) == tcpAO && isActive(key.AcceptLifetimeStart, key.AcceptLifetimeEnd, now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// HasMD5AcceptLifetimes returns whether an MD5 key has an accept lifetime different from its send lifetime. MD5
+// uses a single key for both directions, the accept lifetimes of the MD5 keys are not used.
+func (k *KeyChain) HasMD5AcceptLifetimes() bool {
+	for idx := range k.Keys {
+		key := &k.Keys[idx]
+		if !isTCPAOKey(key) && (!key.AcceptLifetimeStart.Equal(key.SendLifetimeStart) ||
+			!key.AcceptLifetimeEnd.Equal(key.SendLifetimeEnd)) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNextChange returns the time at which the next send or accept lifetime starts or ends
+func (k *KeyChain) GetNextChange(now time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, key := range k.Keys {
+		for _, t := range []time.Time{key.SendLifetimeStart, key.SendLifetimeEnd, key.AcceptLifetimeStart,
+			key.AcceptLifetimeEnd} {
+			if t.After(now) && (next.IsZero() || t.Before(next)) {
+				next = t
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
@@ -0,0 +1,248 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// keychain_test.go
+package keychain
+
+import (
+	"l3/bgp/config"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestKeyChainLifetimes(t *testing.T) {
+	now := time.Now()
+	cfg := config.KeyChainConfig{
+		Name: "peers",
+		Keys: []config.KeyChainKey{
+			{
+				KeyId:             2,
+				Secret:            "second",
+				Algorithm:         AlgorithmHMACSHA1,
+				SendLifetimeStart: now.Add(time.Hour),
+			},
+			{
+				KeyId:             1,
+				Secret:            "first",
+				Algorithm:         AlgorithmHMACSHA1,
+				SendLifetimeEnd:   now.Add(2 * time.Hour),
+				AcceptLifetimeEnd: now.Add(3 * time.Hour),
+			},
+			{
+				KeyId:  3,
+				Secret: "md5",
+			},
+		},
+	}
+	keyChain, err := NewKeyChain(cfg)
+	if err != nil {
+		t.Fatal("Failed to create key chain with error", err)
+	}
+	if keyChain.Keys[0].KeyId != 1 || keyChain.Keys[2].Algorithm != AlgorithmMD5 {
+		t.Fatal("Key chain keys not sorted or defaults not set", keyChain.Keys)
+	}
+
+	if key := keyChain.GetSendKey(now, true); key == nil || key.KeyId != 1 {
+		t.Fatal("Expected send key 1, found", key)
+	}
+	if key := keyChain.GetSendKey(now.Add(90*time.Minute), true); key == nil || key.KeyId != 2 {
+		t.Fatal("Expected send key 2 after its send start time, found", key)
+	}
+	if key := keyChain.GetSendKey(now, false); key == nil || key.KeyId != 3 {
+		t.Fatal("Expected MD5 send key 3, found", key)
+	}
+	if keys := keyChain.GetAcceptKeys(now, true); len(keys) != 2 {
+		t.Fatal("Expected 2 accept keys, found", len(keys))
+	}
+	if keys := keyChain.GetAcceptKeys(now.Add(4*time.Hour), true); len(keys) != 1 || keys[0].KeyId != 2 {
+		t.Fatal("Expected only accept key 2 after key 1 expired, found", keys)
+	}
+	if next, ok := keyChain.GetNextChange(now); !ok || !next.Equal(now.Add(time.Hour)) {
+		t.Fatal("Expected next key change after an hour, found", next)
+	}
+	if keyChain.HasMD5AcceptLifetimes() {
+		t.Fatal("MD5 key 3 without lifetimes reported with an accept lifetime")
+	}
+	cfg.Keys[2].AcceptLifetimeEnd = now.Add(time.Hour)
+	if keyChain, _ = NewKeyChain(cfg); !keyChain.HasMD5AcceptLifetimes() {
+		t.Fatal("MD5 key 3 with an accept lifetime not reported")
+	}
+	cfg.Keys[2].AcceptLifetimeEnd = time.Time{}
+
+	cfg.Keys[2].KeyId = 1
+	if _, err = NewKeyChain(cfg); err == nil {
+		t.Fatal("Key chain with duplicate key ids created")
+	}
+	cfg.Keys[2].KeyId = 3
+	cfg.Keys[2].Algorithm = "sha3"
+	if _, err = NewKeyChain(cfg); err == nil {
+		t.Fatal("Key chain with unknown algorithm created")
+	}
+}
+
+func listenWithKeys(t *testing.T, f func(fd int) error) *net.TCPListener {
+	listener, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal("Listen failed with error", err)
+	}
+	if err = ControlFd(listener, f); err != nil {
+		listener.Close()
+		if err == syscall.ENOPROTOOPT || err == syscall.ENOENT || err == syscall.EINVAL {
+			t.Skip("Socket option is not supported by the kernel:", err)
+		}
+		t.Fatal("Failed to set the listener keys with error", err)
+	}
+	return listener
+}
+
+func dialWithKeys(listener *net.TCPListener, f func(fd int) error) (net.Conn, error) {
+	var fErr error
+	dialer := net.Dialer{
+		Timeout: 2 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			if err := c.Control(func(fd uintptr) {
+				fErr = f(int(fd))
+			}); err != nil {
+				return err
+			}
+			return fErr
+		},
+	}
+	return dialer.Dial("tcp4", listener.Addr().String())
+}
+
+func exchange(t *testing.T, client net.Conn, listener *net.TCPListener) net.Conn {
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatal("Accept failed with error", err)
+	}
+
+	if _, err = client.Write([]byte("open")); err != nil {
+		t.Fatal("Write failed with error", err)
+	}
+	buf := make([]byte, 4)
+	server.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err = server.Read(buf); err != nil || string(buf) != "open" {
+		t.Fatal("Read failed with error", err, "data", string(buf))
+	}
+	return server
+}
+
+func TestTCPMD5Loopback(t *testing.T) {
+	loopback := net.ParseIP("127.0.0.1")
+	now := time.Now()
+	keyChain, _ := NewKeyChain(config.KeyChainConfig{
+		Name: "md5",
+		Keys: []config.KeyChainKey{
+			{KeyId: 1, Secret: "expired", SendLifetimeEnd: now.Add(-time.Hour)},
+			{KeyId: 2, Secret: "secret"},
+		},
+	})
+	listener := listenWithKeys(t, func(fd int) error {
+		_, err := keyChain.SetKeys(fd, loopback, false, true, now)
+		return err
+	})
+	defer listener.Close()
+
+	if conn, err := dialWithKeys(listener, func(fd int) error {
+		return SetTCPMD5Key(fd, loopback, "wrong")
+	}); err == nil {
+		conn.Close()
+		t.Fatal("Connected with the wrong MD5 key")
+	}
+
+	client, err := dialWithKeys(listener, func(fd int) error {
+		keyId, err := keyChain.SetKeys(fd, loopback, false, false, now)
+		if err == nil && keyId != 2 {
+			t.Error("Expected MD5 key 2, found", keyId)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal("Connect with MD5 key failed with error", err)
+	}
+	defer client.Close()
+	server := exchange(t, client, listener)
+	server.Close()
+}
+
+func TestTCPAOLoopback(t *testing.T) {
+	loopback := net.ParseIP("127.0.0.1")
+	key1 := &config.KeyChainKey{KeyId: 1, Secret: "first", Algorithm: AlgorithmHMACSHA1}
+	key2 := &config.KeyChainKey{KeyId: 2, Secret: "second", Algorithm: AlgorithmAESCMAC}
+	listener := listenWithKeys(t, func(fd int) error {
+		if err := AddTCPAOKey(fd, loopback, key1, false); err != nil {
+			return err
+		}
+		return AddTCPAOKey(fd, loopback, key2, false)
+	})
+	defer listener.Close()
+
+	client, err := dialWithKeys(listener, func(fd int) error {
+		return AddTCPAOKey(fd, loopback, key1, true)
+	})
+	if err != nil {
+		t.Fatal("Connect with TCP-AO key failed with error", err)
+	}
+	defer client.Close()
+	server := exchange(t, client, listener)
+	defer server.Close()
+
+	var keyId uint8
+	if err = ControlFd(client.(*net.TCPConn), func(fd int) (err error) {
+		keyId, err = GetTCPAOCurrentKey(fd)
+		return err
+	}); err != nil || keyId != 1 {
+		t.Fatal("Expected current key 1, found", keyId, "error", err)
+	}
+
+	// Rotate to key 2 without resetting the connection
+	if err = ControlFd(client.(*net.TCPConn), func(fd int) error {
+		if err := AddTCPAOKey(fd, loopback, key2, false); err != nil {
+			return err
+		}
+		if err := SetTCPAOCurrentKey(fd, key2.KeyId); err != nil {
+			return err
+		}
+		return DeleteTCPAOKey(fd, loopback, key1.KeyId)
+	}); err != nil {
+		t.Fatal("Failed to rotate the TCP-AO key with error", err)
+	}
+
+	if _, err = server.Write([]byte("next")); err != nil {
+		t.Fatal("Write failed with error", err)
+	}
+	buf := make([]byte, 4)
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err = client.Read(buf); err != nil || string(buf) != "next" {
+		t.Fatal("Read after key rotation failed with error", err, "data", string(buf))
+	}
+	if err = ControlFd(client.(*net.TCPConn), func(fd int) (err error) {
+		keyId, err = GetTCPAOCurrentKey(fd)
+		return err
+	}); err != nil || keyId != 2 {
+		t.Fatal("Expected current key 2 after rotation, found", keyId, "error", err)
+	}
+}
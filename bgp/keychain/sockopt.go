@@ -0,0 +1,297 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// sockopt.go
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"l3/bgp/config"
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// TCP socket options from linux/tcp.h
+const (
+	tcpMD5Sig   = 14
+	tcpAOAddKey = 38
+	tcpAODelKey = 39
+	tcpAOInfo   = 40
+)
+
+const (
+	tcpAOFlagSetCurrent = 1 << 0
+	tcpAOFlagSetRNext   = 1 << 1
+)
+
+type sockaddrStorage struct {
+	Family uint16
+	Data   [126]byte
+}
+
+type tcpMD5SigOpt struct {
+	Addr      sockaddrStorage
+	Flags     uint8
+	PrefixLen uint8
+	KeyLen    uint16
+	IfIndex   int32
+	Key       [MaxKeyLen]byte
+}
+
+type tcpAOAddOpt struct {
+	Addr      sockaddrStorage
+	AlgName   [64]byte
+	IfIndex   int32
+	Flags     uint32
+	Reserved2 uint16
+	Prefix    uint8
+	SndId     uint8
+	RcvId     uint8
+	MacLen    uint8
+	KeyFlags  uint8
+	KeyLen    uint8
+	Key       [MaxKeyLen]byte
+}
+
+type tcpAODelOpt struct {
+	Addr       sockaddrStorage
+	IfIndex    int32
+	Flags      uint32
+	Reserved2  uint16
+	Prefix     uint8
+	SndId      uint8
+	RcvId      uint8
+	CurrentKey uint8
+	RNext      uint8
+	KeyFlags   uint8
+}
+
+type tcpAOInfoOpt struct {
+	Flags          uint32
+	Reserved2      uint16
+	CurrentKey     uint8
+	RNext          uint8
+	PktGood        uint64
+	PktBad         uint64
+	PktKeyNotFound uint64
+	PktAORequired  uint64
+	PktDroppedICMP uint64
+}
+
+func newSockaddrStorage(ip net.IP) (addr sockaddrStorage, prefixLen uint8, err error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		addr.Family = syscall.AF_INET
+		copy(addr.Data[2:6], ip4)
+		return addr, 32, nil
+	}
+	if ip16 := ip.To16(); ip16 != nil {
+		addr.Family = syscall.AF_INET6
+		copy(addr.Data[6:22], ip16)
+		return addr, 128, nil
+	}
+	return addr, 0, errors.New(fmt.Sprintf("IP address %s is not valid", ip))
+}
+
+func setsockopt(fd, opt int, val unsafe.Pointer, size uintptr) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_SETSOCKOPT, uintptr(fd), syscall.IPPROTO_TCP, uintptr(opt),
+		uintptr(val), size, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func getsockopt(fd, opt int, val unsafe.Pointer, size uintptr) error {
+	length := uint32(size)
+	_, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT, uintptr(fd), syscall.IPPROTO_TCP, uintptr(opt),
+		uintptr(val), uintptr(unsafe.Pointer(&length)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// ControlFd calls f with the file descriptor of the listener or the connection
+func ControlFd(c syscall.Conn, f func(fd int) error) error {
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var fErr error
+	if err = rawConn.Control(func(fd uintptr) {
+		fErr = f(int(fd))
+	}); err != nil {
+		return err
+	}
+	return fErr
+}
+
+// SetTCPMD5Key sets the TCP MD5 signature key for the remote address. An empty secret removes the key.
+func SetTCPMD5Key(fd int, remote net.IP, secret string) error {
+	var opt tcpMD5SigOpt
+	var err error
+	if len(secret) > MaxKeyLen {
+		return errors.New(fmt.Sprintf("MD5 key length %d is more than %d", len(secret), MaxKeyLen))
+	}
+
+	if opt.Addr, _, err = newSockaddrStorage(remote); err != nil {
+		return err
+	}
+	opt.KeyLen = uint16(len(secret))
+	copy(opt.Key[:], secret)
+	return setsockopt(fd, tcpMD5Sig, unsafe.Pointer(&opt), unsafe.Sizeof(opt))
+}
+
+// AddTCPAOKey adds the TCP-AO master key for the remote address. The key id is used as both the send and
+// the receive id. Current can't be set on listening sockets.
+func AddTCPAOKey(fd int, remote net.IP, key *config.KeyChainKey, current bool) error {
+	var opt tcpAOAddOpt
+	var err error
+	alg, ok := tcpAOAlgorithms[key.Algorithm]
+	if !ok {
+		return errors.New(fmt.Sprintf("Algorithm %s is not supported by TCP-AO", key.Algorithm))
+	}
+	if len(key.Secret) > MaxKeyLen {
+		return errors.New(fmt.Sprintf("TCP-AO key length %d is more than %d", len(key.Secret), MaxKeyLen))
+	}
+
+	if opt.Addr, opt.Prefix, err = newSockaddrStorage(remote); err != nil {
+		return err
+	}
+	copy(opt.AlgName[:], alg.name)
+	if current {
+		opt.Flags = tcpAOFlagSetCurrent | tcpAOFlagSetRNext
+	}
+	opt.SndId = key.KeyId
+	opt.RcvId = key.KeyId
+	opt.MacLen = alg.macLen
+	opt.KeyLen = uint8(len(key.Secret))
+	copy(opt.Key[:], key.Secret)
+	return setsockopt(fd, tcpAOAddKey, unsafe.Pointer(&opt), unsafe.Sizeof(opt))
+}
+
+// DeleteTCPAOKey removes the TCP-AO master key for the remote address. The current key of a connection
+// can't be removed.
+func DeleteTCPAOKey(fd int, remote net.IP, keyId uint8) error {
+	var opt tcpAODelOpt
+	var err error
+	if opt.Addr, opt.Prefix, err = newSockaddrStorage(remote); err != nil {
+		return err
+	}
+	opt.SndId = keyId
+	opt.RcvId = keyId
+	return setsockopt(fd, tcpAODelKey, unsafe.Pointer(&opt), unsafe.Sizeof(opt))
+}
+
+// SetTCPAOCurrentKey signs the outgoing segments with the key and asks the peer to do the same
+func SetTCPAOCurrentKey(fd int, keyId uint8) error {
+	opt := tcpAOInfoOpt{
+		Flags:      tcpAOFlagSetCurrent | tcpAOFlagSetRNext,
+		CurrentKey: keyId,
+		RNext:      keyId,
+	}
+	return setsockopt(fd, tcpAOInfo, unsafe.Pointer(&opt), unsafe.Sizeof(opt))
+}
+
+// GetTCPAOCurrentKey returns the id of the key that signs the outgoing segments of the connection
+func GetTCPAOCurrentKey(fd int) (uint8, error) {
+	var opt tcpAOInfoOpt
+	if err := getsockopt(fd, tcpAOInfo, unsafe.Pointer(&opt), unsafe.Sizeof(opt)); err != nil {
+		return 0, err
+	}
+	if opt.Flags&tcpAOFlagSetCurrent == 0 {
+		return 0, errors.New("TCP-AO current key is not set")
+	}
+	return opt.CurrentKey, nil
+}
+
+// SetKeys installs the keys of the key chain that are valid now for the remote address and removes the
+// expired ones. For TCP-AO connections the send key is made the current key. It returns the id of the key
+// used to sign the outgoing segments, -1 if there is none.
+func (k *KeyChain) SetKeys(fd int, remote net.IP, tcpAO, listening bool, now time.Time) (int32, error) {
+	sendKey := k.GetSendKey(now, tcpAO)
+	if !tcpAO {
+		if sendKey == nil {
+			if err := SetTCPMD5Key(fd, remote, ""); err != nil && err != syscall.ENOENT {
+				return -1, err
+			}
+			return -1, nil
+		}
+		return int32(sendKey.KeyId), SetTCPMD5Key(fd, remote, sendKey.Secret)
+	}
+
+	keys := make(map[*config.KeyChainKey]bool)
+	for _, key := range k.GetAcceptKeys(now, true) {
+		keys[key] = true
+	}
+	if sendKey != nil {
+		keys[sendKey] = true
+	}
+
+	for key, _ := range keys {
+		if err := AddTCPAOKey(fd, remote, key, false); err != nil && err != syscall.EEXIST {
+			return -1, err
+		}
+	}
+
+	activeKeyId := int32(-1)
+	if sendKey != nil && !listening {
+		if err := SetTCPAOCurrentKey(fd, sendKey.KeyId); err != nil {
+			return -1, err
+		}
+		activeKeyId = int32(sendKey.KeyId)
+	}
+
+	for idx := range k.Keys {
+		key := &k.Keys[idx]
+		if !keys[key] && isTCPAOKey(key) {
+			if err := DeleteTCPAOKey(fd, remote, key.KeyId); err != nil && err != syscall.ENOENT {
+				return activeKeyId, err
+			}
+		}
+	}
+	return activeKeyId, nil
+}
+
+// RemoveKeys removes all the keys of the key chain for the remote address
+func (k *KeyChain) RemoveKeys(fd int, remote net.IP, tcpAO bool) error {
+	if !tcpAO {
+		if err := SetTCPMD5Key(fd, remote, ""); err != nil && err != syscall.ENOENT {
+			return err
+		}
+		return nil
+	}
+
+	for idx := range k.Keys {
+		if key := &k.Keys[idx]; isTCPAOKey(key) {
+			if err := DeleteTCPAOKey(fd, remote, key.KeyId); err != nil && err != syscall.ENOENT {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -112,6 +112,9 @@ func main() {
 		pMgr := ovsMgr.NewOvsPolicyMgr()
 		iMgr := ovsMgr.NewOvsIntfMgr()
 		bMgr := ovsMgr.NewOvsBfdMgr()
+		fsMgr := ovsMgr.NewOvsFlowSpecMgr()
+		evpnMgr := ovsMgr.NewOvsEVPNMgr()
+		lsMgr := ovsMgr.NewOvsLinkStateMgr()
 		sDBMgr, err := statedbclient.NewStateDBClient(statedbclient.OVSPlugin, logger)
 		if err != nil {
 			logger.Info(fmt.Sprintln("Starting OVDB state DB client failed ERROR:", err))
@@ -121,7 +124,7 @@ func main() {
 		// starting bgp policy engine...
 		logger.Info(fmt.Sprintln("Starting BGP policy engine..."))
 		bgpPolicyMgr := bgppolicy.NewPolicyManager(logger, pMgr)
-		bgpServer := server.NewBGPServer(logger, bgpPolicyMgr, iMgr, rMgr, bMgr, sDBMgr)
+		bgpServer := server.NewBGPServer(logger, bgpPolicyMgr, iMgr, rMgr, bMgr, fsMgr, evpnMgr, lsMgr, sDBMgr)
 
 		doneCh := make(chan bool)
 		go bgpPolicyMgr.StartPolicyEngine(dbUtil, doneCh)
@@ -160,6 +163,9 @@ func main() {
 		if err != nil {
 			return
 		}
+		fsMgr := FSMgr.NewFSFlowSpecMgr(logger, fileName)
+		evpnMgr := FSMgr.NewFSEVPNMgr(logger, fileName)
+		lsMgr := FSMgr.NewFSLinkStateMgr(logger, fileName)
 		sDBMgr, err := statedbclient.NewStateDBClient(statedbclient.FlexSwitchPlugin, logger)
 		if err != nil {
 			return
@@ -169,7 +175,7 @@ func main() {
 		pMgr := FSMgr.NewFSPolicyMgr(logger, fileName)
 		bgpPolicyMgr := bgppolicy.NewPolicyManager(logger, pMgr)
 		logger.Info(fmt.Sprintln("Starting BGP Server..."))
-		bgpServer := server.NewBGPServer(logger, bgpPolicyMgr, iMgr, rMgr, bMgr, sDBMgr)
+		bgpServer := server.NewBGPServer(logger, bgpPolicyMgr, iMgr, rMgr, bMgr, fsMgr, evpnMgr, lsMgr, sDBMgr)
 
 		doneCh := make(chan bool)
 		go bgpPolicyMgr.StartPolicyEngine(dbUtil, doneCh)
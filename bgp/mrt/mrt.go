@@ -0,0 +1,582 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// mrt.go
+package mrt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"l3/bgp/packet"
+	"net"
+	"time"
+)
+
+const MRTCommonHeaderLen = 12
+
+const (
+	MRTTypeTableDumpV2 uint16 = 13
+	MRTTypeBGP4MP      uint16 = 16
+)
+
+const (
+	_ uint16 = iota
+	MRTSubTypePeerIndexTable
+	MRTSubTypeRIBIPv4Unicast
+	MRTSubTypeRIBIPv4Multicast
+	MRTSubTypeRIBIPv6Unicast
+	MRTSubTypeRIBIPv6Multicast
+)
+
+const (
+	MRTSubTypeBGP4MPStateChange    uint16 = 0
+	MRTSubTypeBGP4MPMessage        uint16 = 1
+	MRTSubTypeBGP4MPMessageAS4     uint16 = 4
+	MRTSubTypeBGP4MPStateChangeAS4 uint16 = 5
+)
+
+const (
+	MRTPeerTypeIPv6 uint8 = 0x01
+	MRTPeerTypeAS4  uint8 = 0x02
+)
+
+type MRTBody interface {
+	Encode() ([]byte, error)
+	Decode(*MRTHeader, []byte) error
+}
+
+type MRTHeader struct {
+	Timestamp uint32
+	Type      uint16
+	SubType   uint16
+	Length    uint32
+}
+
+func (header *MRTHeader) Encode() ([]byte, error) {
+	pkt := make([]byte, MRTCommonHeaderLen)
+	binary.BigEndian.PutUint32(pkt[0:4], header.Timestamp)
+	binary.BigEndian.PutUint16(pkt[4:6], header.Type)
+	binary.BigEndian.PutUint16(pkt[6:8], header.SubType)
+	binary.BigEndian.PutUint32(pkt[8:12], header.Length)
+	return pkt, nil
+}
+
+func (header *MRTHeader) Decode(pkt []byte) error {
+	if len(pkt) < MRTCommonHeaderLen {
+		return errors.New(fmt.Sprintf("MRT header is %d bytes long", len(pkt)))
+	}
+	header.Timestamp = binary.BigEndian.Uint32(pkt[0:4])
+	header.Type = binary.BigEndian.Uint16(pkt[4:6])
+	header.SubType = binary.BigEndian.Uint16(pkt[6:8])
+	header.Length = binary.BigEndian.Uint32(pkt[8:12])
+	return nil
+}
+
+func encodeIP(ip net.IP) []byte {
+	if ip.To4() != nil {
+		return []byte(ip.To4())
+	}
+	return []byte(ip.To16())
+}
+
+func decodeIP(pkt []byte, ipv6 bool) (net.IP, int, error) {
+	ipLen := net.IPv4len
+	if ipv6 {
+		ipLen = net.IPv6len
+	}
+	if len(pkt) < ipLen {
+		return nil, 0, errors.New("Not enough data to decode IP address")
+	}
+
+	ip := make(net.IP, ipLen)
+	copy(ip, pkt[:ipLen])
+	return ip, ipLen, nil
+}
+
+type MRTPeerEntry struct {
+	BGPId   net.IP
+	Address net.IP
+	AS      uint32
+}
+
+type MRTPeerIndexTable struct {
+	CollectorBGPId net.IP
+	ViewName       string
+	Peers          []MRTPeerEntry
+}
+
+func (t *MRTPeerIndexTable) Encode() ([]byte, error) {
+	pkt := make([]byte, 8+len(t.ViewName))
+	copy(pkt[0:4], t.CollectorBGPId.To4())
+	binary.BigEndian.PutUint16(pkt[4:6], uint16(len(t.ViewName)))
+	copy(pkt[6:], t.ViewName)
+	binary.BigEndian.PutUint16(pkt[6+len(t.ViewName):], uint16(len(t.Peers)))
+
+	for _, peer := range t.Peers {
+		peerType := MRTPeerTypeAS4
+		if peer.Address.To4() == nil {
+			peerType |= MRTPeerTypeIPv6
+		}
+		pkt = append(pkt, peerType)
+		bgpId := make([]byte, 4)
+		copy(bgpId, peer.BGPId.To4())
+		pkt = append(pkt, bgpId...)
+		pkt = append(pkt, encodeIP(peer.Address)...)
+		as := make([]byte, 4)
+		binary.BigEndian.PutUint32(as, peer.AS)
+		pkt = append(pkt, as...)
+	}
+	return pkt, nil
+}
+
+func (t *MRTPeerIndexTable) Decode(header *MRTHeader, pkt []byte) error {
+	if len(pkt) < 6 {
+		return errors.New("Not enough data to decode peer index table")
+	}
+	t.CollectorBGPId = net.IP(pkt[0:4])
+	viewLen := int(binary.BigEndian.Uint16(pkt[4:6]))
+	if len(pkt) < 8+viewLen {
+		return errors.New("Not enough data to decode peer index table")
+	}
+	t.ViewName = string(pkt[6 : 6+viewLen])
+	count := int(binary.BigEndian.Uint16(pkt[6+viewLen : 8+viewLen]))
+
+	ptr := 8 + viewLen
+	t.Peers = make([]MRTPeerEntry, 0, count)
+	for i := 0; i < count; i++ {
+		if len(pkt) < ptr+5 {
+			return errors.New("Not enough data to decode peer entry")
+		}
+		peerType := pkt[ptr]
+		peer := MRTPeerEntry{BGPId: net.IP(pkt[ptr+1 : ptr+5])}
+		ptr += 5
+
+		ip, ipLen, err := decodeIP(pkt[ptr:], peerType&MRTPeerTypeIPv6 != 0)
+		if err != nil {
+			return err
+		}
+		peer.Address = ip
+		ptr += ipLen
+
+		if peerType&MRTPeerTypeAS4 != 0 {
+			if len(pkt) < ptr+4 {
+				return errors.New("Not enough data to decode peer AS")
+			}
+			peer.AS = binary.BigEndian.Uint32(pkt[ptr : ptr+4])
+			ptr += 4
+		} else {
+			if len(pkt) < ptr+2 {
+				return errors.New("Not enough data to decode peer AS")
+			}
+			peer.AS = uint32(binary.BigEndian.Uint16(pkt[ptr : ptr+2]))
+			ptr += 2
+		}
+		t.Peers = append(t.Peers, peer)
+	}
+	return nil
+}
+
+type MRTRIBEntry struct {
+	PeerIndex      uint16
+	OriginatedTime uint32
+	PathAttrs      []packet.BGPPathAttr
+}
+
+// RIB entries carry only the next hop in the MP_REACH_NLRI attribute, RFC 6396 section 4.3.4
+func encodeRIBPathAttrs(pathAttrs []packet.BGPPathAttr) ([]byte, error) {
+	pkt := make([]byte, 0)
+	for _, pa := range pathAttrs {
+		if pa.GetCode() == packet.BGPPathAttrTypeMPReachNLRI {
+			nextHop := pa.(*packet.BGPPathAttrMPReachNLRI).NextHop
+			bytes := make([]byte, 3+nextHop.Len())
+			bytes[0] = uint8(packet.BGPPathAttrFlagOptional)
+			bytes[1] = uint8(packet.BGPPathAttrTypeMPReachNLRI)
+			bytes[2] = nextHop.Len()
+			if err := nextHop.Encode(bytes[3:]); err != nil {
+				return nil, err
+			}
+			pkt = append(pkt, bytes...)
+			continue
+		}
+
+		bytes, err := pa.Encode()
+		if err != nil {
+			return nil, err
+		}
+		pkt = append(pkt, bytes...)
+	}
+	return pkt, nil
+}
+
+func decodeRIBPathAttrs(pkt []byte, afi packet.AFI, safi packet.SAFI) ([]packet.BGPPathAttr, error) {
+	peerAttrs := packet.BGPPeerAttrs{ASSize: 4}
+	pathAttrs := make([]packet.BGPPathAttr, 0)
+	for len(pkt) > 0 {
+		if len(pkt) < 3 {
+			return nil, errors.New("Not enough data to decode path attribute")
+		}
+
+		if packet.BGPPathAttrType(pkt[1]) == packet.BGPPathAttrTypeMPReachNLRI {
+			length := int(pkt[2])
+			if len(pkt) < 3+length || length == 0 {
+				return nil, errors.New("Not enough data to decode MP_REACH_NLRI next hop")
+			}
+			nextHop := packet.BGPGetMPNextHop(afi)
+			if err := nextHop.Decode(pkt[3 : 3+length]); err != nil {
+				return nil, err
+			}
+			mpReach := packet.NewBGPPathAttrMPReachNLRI()
+			mpReach.AFI = afi
+			mpReach.SAFI = safi
+			mpReach.SetNextHop(nextHop)
+			pathAttrs = append(pathAttrs, mpReach)
+			pkt = pkt[3+length:]
+			continue
+		}
+
+		pa := packet.BGPGetPathAttr(pkt)
+		if err := pa.Decode(pkt, peerAttrs); err != nil {
+			return nil, err
+		}
+		pathAttrs = append(pathAttrs, pa)
+		pkt = pkt[pa.TotalLen():]
+	}
+	return pathAttrs, nil
+}
+
+type MRTRIB struct {
+	SequenceNumber uint32
+	Prefix         *packet.IPPrefix
+	Entries        []MRTRIBEntry
+}
+
+func getRIBAfiSafi(subType uint16) (packet.AFI, packet.SAFI, error) {
+	switch subType {
+	case MRTSubTypeRIBIPv4Unicast:
+		return packet.AfiIP, packet.SafiUnicast, nil
+
+	case MRTSubTypeRIBIPv4Multicast:
+		return packet.AfiIP, packet.SafiMulticast, nil
+
+	case MRTSubTypeRIBIPv6Unicast:
+		return packet.AfiIP6, packet.SafiUnicast, nil
+
+	case MRTSubTypeRIBIPv6Multicast:
+		return packet.AfiIP6, packet.SafiMulticast, nil
+	}
+	return 0, 0, errors.New(fmt.Sprintf("MRT RIB subtype %d is not supported", subType))
+}
+
+func GetRIBSubType(protoFamily uint32) (uint16, bool) {
+	afi, safi := packet.GetAfiSafi(protoFamily)
+	switch {
+	case afi == packet.AfiIP && safi == packet.SafiUnicast:
+		return MRTSubTypeRIBIPv4Unicast, true
+
+	case afi == packet.AfiIP && safi == packet.SafiMulticast:
+		return MRTSubTypeRIBIPv4Multicast, true
+
+	case afi == packet.AfiIP6 && safi == packet.SafiUnicast:
+		return MRTSubTypeRIBIPv6Unicast, true
+
+	case afi == packet.AfiIP6 && safi == packet.SafiMulticast:
+		return MRTSubTypeRIBIPv6Multicast, true
+	}
+	return 0, false
+}
+
+func (r *MRTRIB) encode(afi packet.AFI) ([]byte, error) {
+	pkt := make([]byte, 4)
+	binary.BigEndian.PutUint32(pkt, r.SequenceNumber)
+	prefix, err := r.Prefix.Encode(afi)
+	if err != nil {
+		return nil, err
+	}
+	pkt = append(pkt, prefix...)
+
+	count := make([]byte, 2)
+	binary.BigEndian.PutUint16(count, uint16(len(r.Entries)))
+	pkt = append(pkt, count...)
+
+	for _, entry := range r.Entries {
+		attrs, err := encodeRIBPathAttrs(entry.PathAttrs)
+		if err != nil {
+			return nil, err
+		}
+		bytes := make([]byte, 8)
+		binary.BigEndian.PutUint16(bytes[0:2], entry.PeerIndex)
+		binary.BigEndian.PutUint32(bytes[2:6], entry.OriginatedTime)
+		binary.BigEndian.PutUint16(bytes[6:8], uint16(len(attrs)))
+		pkt = append(pkt, bytes...)
+		pkt = append(pkt, attrs...)
+	}
+	return pkt, nil
+}
+
+type MRTRIBIPv4 struct {
+	MRTRIB
+}
+
+func (r *MRTRIBIPv4) Encode() ([]byte, error) {
+	return r.encode(packet.AfiIP)
+}
+
+type MRTRIBIPv6 struct {
+	MRTRIB
+}
+
+func (r *MRTRIBIPv6) Encode() ([]byte, error) {
+	return r.encode(packet.AfiIP6)
+}
+
+func (r *MRTRIB) Decode(header *MRTHeader, pkt []byte) error {
+	afi, safi, err := getRIBAfiSafi(header.SubType)
+	if err != nil {
+		return err
+	}
+
+	if len(pkt) < 5 {
+		return errors.New("Not enough data to decode RIB")
+	}
+	r.SequenceNumber = binary.BigEndian.Uint32(pkt[0:4])
+	r.Prefix = &packet.IPPrefix{}
+	if err = r.Prefix.Decode(pkt[4:], afi); err != nil {
+		return err
+	}
+	ptr := 4 + int(r.Prefix.Len())
+	if len(pkt) < ptr+2 {
+		return errors.New("Not enough data to decode RIB entry count")
+	}
+	count := int(binary.BigEndian.Uint16(pkt[ptr : ptr+2]))
+	ptr += 2
+
+	r.Entries = make([]MRTRIBEntry, 0, count)
+	for i := 0; i < count; i++ {
+		if len(pkt) < ptr+8 {
+			return errors.New("Not enough data to decode RIB entry")
+		}
+		entry := MRTRIBEntry{
+			PeerIndex:      binary.BigEndian.Uint16(pkt[ptr : ptr+2]),
+			OriginatedTime: binary.BigEndian.Uint32(pkt[ptr+2 : ptr+6]),
+		}
+		attrLen := int(binary.BigEndian.Uint16(pkt[ptr+6 : ptr+8]))
+		ptr += 8
+		if len(pkt) < ptr+attrLen {
+			return errors.New("Not enough data to decode RIB entry path attributes")
+		}
+		if entry.PathAttrs, err = decodeRIBPathAttrs(pkt[ptr:ptr+attrLen], afi, safi); err != nil {
+			return err
+		}
+		ptr += attrLen
+		r.Entries = append(r.Entries, entry)
+	}
+	return nil
+}
+
+type MRTBGP4MPMessage struct {
+	PeerAS  uint32
+	LocalAS uint32
+	IfIndex uint16
+	PeerIP  net.IP
+	LocalIP net.IP
+	Data    []byte // BGP message including the header, as received
+	Msg     *packet.BGPMessage
+}
+
+func (m *MRTBGP4MPMessage) Encode() ([]byte, error) {
+	pkt := make([]byte, 12)
+	binary.BigEndian.PutUint32(pkt[0:4], m.PeerAS)
+	binary.BigEndian.PutUint32(pkt[4:8], m.LocalAS)
+	binary.BigEndian.PutUint16(pkt[8:10], m.IfIndex)
+	afi := packet.AfiIP
+	if m.PeerIP.To4() == nil {
+		afi = packet.AfiIP6
+	}
+	binary.BigEndian.PutUint16(pkt[10:12], uint16(afi))
+
+	localIP := m.LocalIP
+	if localIP == nil {
+		localIP = net.IPv4zero
+		if afi == packet.AfiIP6 {
+			localIP = net.IPv6zero
+		}
+	}
+	pkt = append(pkt, encodeIP(m.PeerIP)...)
+	pkt = append(pkt, encodeIP(localIP)...)
+
+	if len(m.Data) < packet.BGPMsgHeaderLen {
+		return nil, errors.New("BGP4MP message does not have the BGP message")
+	}
+	return append(pkt, m.Data...), nil
+}
+
+func (m *MRTBGP4MPMessage) Decode(header *MRTHeader, pkt []byte) error {
+	asLen := 2
+	if header.SubType == MRTSubTypeBGP4MPMessageAS4 {
+		asLen = 4
+	}
+	if len(pkt) < 2*asLen+4 {
+		return errors.New("Not enough data to decode BGP4MP message")
+	}
+
+	ptr := 0
+	if asLen == 4 {
+		m.PeerAS = binary.BigEndian.Uint32(pkt[0:4])
+		m.LocalAS = binary.BigEndian.Uint32(pkt[4:8])
+	} else {
+		m.PeerAS = uint32(binary.BigEndian.Uint16(pkt[0:2]))
+		m.LocalAS = uint32(binary.BigEndian.Uint16(pkt[2:4]))
+	}
+	ptr += 2 * asLen
+	m.IfIndex = binary.BigEndian.Uint16(pkt[ptr : ptr+2])
+	afi := packet.AFI(binary.BigEndian.Uint16(pkt[ptr+2 : ptr+4]))
+	ptr += 4
+
+	var ipLen int
+	var err error
+	if m.PeerIP, ipLen, err = decodeIP(pkt[ptr:], afi == packet.AfiIP6); err != nil {
+		return err
+	}
+	ptr += ipLen
+	if m.LocalIP, ipLen, err = decodeIP(pkt[ptr:], afi == packet.AfiIP6); err != nil {
+		return err
+	}
+	ptr += ipLen
+
+	if len(pkt) < ptr+packet.BGPMsgHeaderLen {
+		return errors.New("Not enough data to decode BGP message header")
+	}
+	bgpHeader := packet.NewBGPHeader()
+	bgpHeader.Decode(pkt[ptr:])
+	if len(pkt) < ptr+int(bgpHeader.Len()) {
+		return errors.New("Not enough data to decode BGP message")
+	}
+
+	peerAttrs := packet.BGPPeerAttrs{ASSize: uint8(asLen)}
+	m.Data = pkt[ptr : ptr+int(bgpHeader.Len())]
+	m.Msg = packet.NewBGPMessage()
+	return m.Msg.Decode(bgpHeader, pkt[ptr+packet.BGPMsgHeaderLen:ptr+int(bgpHeader.Len())], peerAttrs)
+}
+
+type MRTUnknown struct {
+	Data []byte
+}
+
+func (u *MRTUnknown) Encode() ([]byte, error) {
+	return u.Data, nil
+}
+
+func (u *MRTUnknown) Decode(header *MRTHeader, pkt []byte) error {
+	u.Data = pkt
+	return nil
+}
+
+type MRTMessage struct {
+	Header MRTHeader
+	Body   MRTBody
+}
+
+func (msg *MRTMessage) Encode() ([]byte, error) {
+	body, err := msg.Body.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Header.Length = uint32(len(body))
+	header, err := msg.Header.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return append(header, body...), nil
+}
+
+func (msg *MRTMessage) Decode(header *MRTHeader, pkt []byte) error {
+	msg.Header = *header
+	switch header.Type {
+	case MRTTypeTableDumpV2:
+		switch header.SubType {
+		case MRTSubTypePeerIndexTable:
+			msg.Body = &MRTPeerIndexTable{}
+
+		case MRTSubTypeRIBIPv4Unicast, MRTSubTypeRIBIPv4Multicast:
+			msg.Body = &MRTRIBIPv4{}
+
+		case MRTSubTypeRIBIPv6Unicast, MRTSubTypeRIBIPv6Multicast:
+			msg.Body = &MRTRIBIPv6{}
+
+		default:
+			msg.Body = &MRTUnknown{}
+		}
+
+	case MRTTypeBGP4MP:
+		switch header.SubType {
+		case MRTSubTypeBGP4MPMessage, MRTSubTypeBGP4MPMessageAS4:
+			msg.Body = &MRTBGP4MPMessage{}
+
+		default:
+			msg.Body = &MRTUnknown{}
+		}
+
+	default:
+		msg.Body = &MRTUnknown{}
+	}
+
+	return msg.Body.Decode(header, pkt)
+}
+
+func NewMRTPeerIndexTableMessage(timestamp time.Time, collectorBGPId net.IP, viewName string,
+	peers []MRTPeerEntry) *MRTMessage {
+	return &MRTMessage{
+		Header: MRTHeader{Timestamp: uint32(timestamp.Unix()), Type: MRTTypeTableDumpV2,
+			SubType: MRTSubTypePeerIndexTable},
+		Body: &MRTPeerIndexTable{collectorBGPId, viewName, peers},
+	}
+}
+
+func NewMRTRIBMessage(timestamp time.Time, protoFamily uint32, seqNum uint32, prefix *packet.IPPrefix,
+	entries []MRTRIBEntry) (*MRTMessage, error) {
+	subType, ok := GetRIBSubType(protoFamily)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("MRT RIB dump is not supported for protocol family %d", protoFamily))
+	}
+
+	rib := MRTRIB{seqNum, prefix, entries}
+	var body MRTBody = &MRTRIBIPv4{rib}
+	if afi, _ := packet.GetAfiSafi(protoFamily); afi == packet.AfiIP6 {
+		body = &MRTRIBIPv6{rib}
+	}
+	return &MRTMessage{
+		Header: MRTHeader{Timestamp: uint32(timestamp.Unix()), Type: MRTTypeTableDumpV2, SubType: subType},
+		Body:   body,
+	}, nil
+}
+
+func NewMRTBGP4MPMessage(timestamp time.Time, peerAS, localAS uint32, peerIP, localIP net.IP,
+	data []byte) *MRTMessage {
+	return &MRTMessage{
+		Header: MRTHeader{Timestamp: uint32(timestamp.Unix()), Type: MRTTypeBGP4MP,
+			SubType: MRTSubTypeBGP4MPMessageAS4},
+		Body: &MRTBGP4MPMessage{PeerAS: peerAS, LocalAS: localAS, PeerIP: peerIP, LocalIP: localIP, Data: data},
+	}
+}
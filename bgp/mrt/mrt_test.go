@@ -0,0 +1,183 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// mrt_test.go
+package mrt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"l3/bgp/packet"
+	"l3/bgp/utils"
+	"net"
+	"os"
+	"testing"
+	"time"
+	"utils/logging"
+)
+
+func getLogger(t *testing.T) *logging.Writer {
+	logger, err := logging.NewLogger("bgpd", "BGP", true)
+	if err != nil {
+		t.Fatal("Failed to start the logger. Exiting!!")
+	}
+	utils.SetLogger(logger)
+	return logger
+}
+
+func TestMRTTableDump(t *testing.T) {
+	logger := getLogger(t)
+	dir, err := ioutil.TempDir("", "mrt")
+	if err != nil {
+		t.Fatal("Failed to create temp dir, error:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	peers := []MRTPeerEntry{
+		MRTPeerEntry{net.ParseIP("2.2.2.2"), net.ParseIP("10.1.1.2"), 65002},
+		MRTPeerEntry{net.ParseIP("3.3.3.3"), net.ParseIP("2001:db8::3"), 4200000000},
+	}
+	pathAttrs := packet.ConstructPathAttrForConnRoutes(65001)
+	ipv4Family := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
+	ipv6Family := packet.GetProtocolFamily(packet.AfiIP6, packet.SafiUnicast)
+	ipv4RIB, err := NewMRTRIBMessage(now, ipv4Family, 0, packet.NewIPPrefix(net.ParseIP("20.1.1.0").To4(), 24),
+		[]MRTRIBEntry{MRTRIBEntry{0, uint32(now.Unix()), pathAttrs}})
+	if err != nil {
+		t.Fatal("NewMRTRIBMessage failed with error:", err)
+	}
+
+	mpReach := packet.ConstructIPv6MPReachNLRI(ipv6Family, net.ParseIP("2001:db8::3"), nil, nil)
+	ipv6PathAttrs := packet.AddMPReachNLRIToPathAttrs(packet.CopyPathAttrs(pathAttrs), mpReach)
+	ipv6RIB, err := NewMRTRIBMessage(now, ipv6Family, 1, packet.NewIPPrefix(net.ParseIP("2001:db8:1::"), 48),
+		[]MRTRIBEntry{MRTRIBEntry{1, uint32(now.Unix()), ipv6PathAttrs}})
+	if err != nil {
+		t.Fatal("NewMRTRIBMessage failed with error:", err)
+	}
+
+	writer := NewMRTWriter(logger)
+	if err = writer.Open(dir, "bgp-rib", 0); err != nil {
+		t.Fatal("MRTWriter.Open failed with error:", err)
+	}
+	fileName := writer.GetFileName()
+	for _, msg := range []*MRTMessage{NewMRTPeerIndexTableMessage(now, net.ParseIP("1.1.1.1"), "", peers), ipv4RIB,
+		ipv6RIB} {
+		if err = writer.Write(msg); err != nil {
+			t.Fatal("MRTWriter.Write failed with error:", err)
+		}
+	}
+	writer.Close()
+
+	msgs, err := ReadFile(fileName)
+	if err != nil {
+		t.Fatal("ReadFile failed with error:", err)
+	}
+	if len(msgs) != 3 {
+		t.Fatal("Expected 3 MRT messages, got", len(msgs))
+	}
+
+	table, ok := msgs[0].Body.(*MRTPeerIndexTable)
+	if !ok || len(table.Peers) != 2 {
+		t.Fatal("Expected peer index table with 2 peers, got", msgs[0].Body)
+	}
+	if !table.Peers[1].Address.Equal(net.ParseIP("2001:db8::3")) || table.Peers[1].AS != 4200000000 {
+		t.Error("Peer index table entry expected address 2001:db8::3 AS 4200000000, got", table.Peers[1])
+	}
+
+	rib, ok := msgs[1].Body.(*MRTRIBIPv4)
+	if !ok || rib.Prefix.GetCIDR() != "20.1.1.0/24" || len(rib.Entries) != 1 {
+		t.Fatal("Expected IPv4 RIB for 20.1.1.0/24 with one entry, got", msgs[1].Body)
+	}
+	if len(rib.Entries[0].PathAttrs) != len(pathAttrs) {
+		t.Error("Expected", len(pathAttrs), "path attrs, got", len(rib.Entries[0].PathAttrs))
+	}
+
+	ipv6, ok := msgs[2].Body.(*MRTRIBIPv6)
+	if !ok || ipv6.SequenceNumber != 1 || len(ipv6.Entries) != 1 || ipv6.Entries[0].PeerIndex != 1 {
+		t.Fatal("Expected IPv6 RIB with sequence 1 and one entry for peer 1, got", msgs[2].Body)
+	}
+	mpReach, _ = packet.GetMPAttrs(ipv6.Entries[0].PathAttrs)
+	if mpReach == nil || !mpReach.NextHop.GetNextHop().Equal(net.ParseIP("2001:db8::3")) {
+		t.Error("Expected MP_REACH_NLRI with next hop 2001:db8::3, got", mpReach)
+	}
+}
+
+func TestMRTBGP4MPMessage(t *testing.T) {
+	logger := getLogger(t)
+	dir, err := ioutil.TempDir("", "mrt")
+	if err != nil {
+		t.Fatal("Failed to create temp dir, error:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	pathAttrs := packet.ConstructPathAttrForConnRoutes(65002)
+	nlri := []packet.NLRI{packet.NewIPPrefix(net.ParseIP("30.1.0.0").To4(), 16)}
+	msgs := []*packet.BGPMessage{packet.NewBGPUpdateMessage(nil, pathAttrs, nlri), packet.NewBGPKeepAliveMessage()}
+
+	writer := NewMRTWriter(logger)
+	if err = writer.Open(dir, "bgp-updates", 3600); err != nil {
+		t.Fatal("MRTWriter.Open failed with error:", err)
+	}
+	pkts := make([][]byte, 0)
+	for _, msg := range msgs {
+		pkt, _ := msg.Encode()
+		pkts = append(pkts, pkt)
+		err = writer.Write(NewMRTBGP4MPMessage(time.Now(), 65002, 65001, net.ParseIP("10.1.1.2"),
+			net.ParseIP("10.1.1.1"), pkt))
+		if err != nil {
+			t.Fatal("MRTWriter.Write failed with error:", err)
+		}
+	}
+	fileName := writer.GetFileName()
+	writer.Close()
+
+	mrtMsgs, err := ReadFile(fileName)
+	if err != nil || len(mrtMsgs) != len(msgs) {
+		t.Fatal("ReadFile expected", len(msgs), "messages, got", len(mrtMsgs), "error:", err)
+	}
+
+	bgp4mp, ok := mrtMsgs[0].Body.(*MRTBGP4MPMessage)
+	if !ok || bgp4mp.PeerAS != 65002 || bgp4mp.LocalAS != 65001 || !bgp4mp.PeerIP.Equal(net.ParseIP("10.1.1.2")) {
+		t.Fatal("Expected BGP4MP message from peer 10.1.1.2 AS 65002, got", mrtMsgs[0].Body)
+	}
+	if !bytes.Equal(bgp4mp.Data, pkts[0]) {
+		t.Error("Expected BGP4MP message with the received bytes", pkts[0], "got", bgp4mp.Data)
+	}
+	update, ok := bgp4mp.Msg.Body.(*packet.BGPUpdate)
+	if !ok || len(update.NLRI) != 1 || update.NLRI[0].GetCIDR() != "30.1.0.0/16" {
+		t.Error("Expected BGP update with NLRI 30.1.0.0/16, got", bgp4mp.Msg.Body)
+	}
+
+	bgp4mp, ok = mrtMsgs[1].Body.(*MRTBGP4MPMessage)
+	if !ok || bgp4mp.Msg.Header.Type != packet.BGPMsgTypeKeepAlive {
+		t.Error("Expected BGP4MP message with a keepalive, got", mrtMsgs[1].Body)
+	}
+}
+
+func TestMRTReaderMaxLength(t *testing.T) {
+	header := MRTHeader{Type: MRTTypeBGP4MP, SubType: MRTSubTypeBGP4MPMessageAS4, Length: MRTMaxMessageLen + 1}
+	pkt, _ := header.Encode()
+	if _, err := NewMRTReader(bytes.NewReader(pkt)).Next(); err == nil {
+		t.Error("MRT message with length", header.Length, "is not rejected")
+	}
+}
@@ -0,0 +1,98 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// reader.go
+package mrt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// MRTMaxMessageLen bounds the length of a message read from a file, longer messages are treated as corrupt
+const MRTMaxMessageLen uint32 = 4 * 1024 * 1024
+
+type MRTReader struct {
+	reader *bufio.Reader
+}
+
+func NewMRTReader(reader io.Reader) *MRTReader {
+	return &MRTReader{
+		reader: bufio.NewReader(reader),
+	}
+}
+
+// Next returns the next MRT message, io.EOF is returned when there are no more messages
+func (r *MRTReader) Next() (*MRTMessage, error) {
+	buf := make([]byte, MRTCommonHeaderLen)
+	if _, err := io.ReadFull(r.reader, buf); err != nil {
+		return nil, err
+	}
+
+	header := &MRTHeader{}
+	if err := header.Decode(buf); err != nil {
+		return nil, err
+	}
+
+	if header.Length > MRTMaxMessageLen {
+		return nil, errors.New(fmt.Sprintf("MRT message length %d exceeds the maximum %d", header.Length,
+			MRTMaxMessageLen))
+	}
+
+	buf = make([]byte, header.Length)
+	if _, err := io.ReadFull(r.reader, buf); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	msg := &MRTMessage{}
+	if err := msg.Decode(header, buf); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func ReadFile(fileName string) ([]*MRTMessage, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	msgs := make([]*MRTMessage, 0)
+	reader := NewMRTReader(file)
+	for {
+		msg, err := reader.Next()
+		if err == io.EOF {
+			return msgs, nil
+		} else if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
@@ -0,0 +1,147 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// writer.go
+package mrt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"utils/logging"
+)
+
+const MRTFileTimeFormat = "20060102.150405"
+
+// MRTWriter writes MRT messages to files named <prefix>.<time> in a directory. A new file is started every
+// rotate interval, a zero interval writes to a single file till the writer is closed.
+type MRTWriter struct {
+	logger         *logging.Writer
+	mutex          sync.Mutex
+	dir            string
+	prefix         string
+	rotateInterval time.Duration
+	rotateTime     time.Time
+	file           *os.File
+	enabled        bool
+}
+
+func NewMRTWriter(logger *logging.Writer) *MRTWriter {
+	return &MRTWriter{
+		logger: logger,
+	}
+}
+
+func (w *MRTWriter) Open(dir string, prefix string, rotateInterval uint32) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.closeFile()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	w.dir = dir
+	w.prefix = prefix
+	w.rotateInterval = time.Duration(rotateInterval) * time.Second
+	w.enabled = true
+	return w.openFile(time.Now())
+}
+
+func (w *MRTWriter) Close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.enabled = false
+	w.closeFile()
+}
+
+func (w *MRTWriter) IsEnabled() bool {
+	if w == nil {
+		return false
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.enabled
+}
+
+func (w *MRTWriter) GetFileName() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		return ""
+	}
+	return w.file.Name()
+}
+
+func (w *MRTWriter) openFile(now time.Time) error {
+	fileName := filepath.Join(w.dir, fmt.Sprintf("%s.%s", w.prefix, now.Format(MRTFileTimeFormat)))
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		w.logger.Errf("MRT: Failed to open file %s with error %s", fileName, err)
+		return err
+	}
+
+	w.logger.Infof("MRT: Writing to file %s", fileName)
+	w.file = file
+	if w.rotateInterval != 0 {
+		w.rotateTime = now.Add(w.rotateInterval)
+	}
+	return nil
+}
+
+func (w *MRTWriter) closeFile() {
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+}
+
+func (w *MRTWriter) Write(msg *MRTMessage) error {
+	pkt, err := msg.Encode()
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.enabled {
+		return nil
+	}
+
+	now := time.Now()
+	if w.file == nil || (w.rotateInterval != 0 && !now.Before(w.rotateTime)) {
+		w.closeFile()
+		if err = w.openFile(now); err != nil {
+			return err
+		}
+	}
+
+	_, err = w.file.Write(pkt)
+	return err
+}
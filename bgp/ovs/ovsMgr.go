@@ -38,3 +38,15 @@ type OvsPolicyMgr struct {
 type OvsBfdMgr struct {
 	plugin string
 }
+
+type OvsFlowSpecMgr struct {
+	plugin string
+}
+
+type OvsEVPNMgr struct {
+	plugin string
+}
+
+type OvsLinkStateMgr struct {
+	plugin string
+}
@@ -54,6 +54,14 @@ func (mgr *OvsRouteMgr) UpdateRoute(cfg *config.RouteConfig, op string) {
 
 }
 
+func (mgr *OvsRouteMgr) CreateNextHopGroup(cfg *config.NextHopGroupConfig) {
+
+}
+
+func (mgr *OvsRouteMgr) DeleteNextHopGroup(cfg *config.NextHopGroupConfig) {
+
+}
+
 func (mgr *OvsRouteMgr) GetNextHopInfo(ipAddr string, ifIndex int32) (*config.NextHopInfo, error) {
 	return nil, nil
 }
@@ -64,3 +72,7 @@ func (mgr *OvsRouteMgr) ApplyPolicy(applyList []*config.ApplyPolicyInfo, undoLis
 func (mgr *OvsRouteMgr) GetRoutes() ([]*config.RouteInfo, []*config.RouteInfo) {
 	return nil, nil
 }
+
+func (mgr *OvsRouteMgr) GetInstalledRoutes() []*config.RouteConfig {
+	return nil
+}
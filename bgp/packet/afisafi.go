@@ -45,9 +45,17 @@ const (
 	SafiMulticast
 )
 
+const SafiFlowSpec SAFI = 133
+
 var ProtocolFamilyMap = map[string]uint32{
-	"ipv4-unicast": GetProtocolFamily(AfiIP, SafiUnicast),
-	"ipv6-unicast": GetProtocolFamily(AfiIP6, SafiUnicast),
+	"ipv4-unicast":  GetProtocolFamily(AfiIP, SafiUnicast),
+	"ipv6-unicast":  GetProtocolFamily(AfiIP6, SafiUnicast),
+	"ipv4-flowspec": GetProtocolFamily(AfiIP, SafiFlowSpec),
+	"ipv6-flowspec": GetProtocolFamily(AfiIP6, SafiFlowSpec),
+	"l2vpn-evpn":    GetProtocolFamily(AfiL2VPN, SafiEVPN),
+	"ipv4-vpn":      GetProtocolFamily(AfiIP, SafiMPLSVPN),
+	"ipv6-vpn":      GetProtocolFamily(AfiIP6, SafiMPLSVPN),
+	"link-state":    GetProtocolFamily(AfiLinkState, SafiLinkState),
 	//"ipv4-multicast": GetProtocolFamily(AfiIP, SafiMulticast),
 	//"ipv6-multicast": GetProtocolFamily(AfiIP6, SafiMulticast),
 }
@@ -95,7 +103,38 @@ func GetProtocolFromConfig(afiSafis *[]config.AfiSafiConfig, neighborAddress net
 }
 
 func GetProtocolFamily(afi AFI, safi SAFI) uint32 {
-	return uint32(afi<<8) | uint32(safi)
+	return uint32(afi)<<8 | uint32(safi)
+}
+
+func IsFlowSpecFamily(protoFamily uint32) bool {
+	_, safi := GetAfiSafi(protoFamily)
+	return safi == SafiFlowSpec
+}
+
+func IsEVPNFamily(protoFamily uint32) bool {
+	afi, safi := GetAfiSafi(protoFamily)
+	return afi == AfiL2VPN && safi == SafiEVPN
+}
+
+func IsLinkStateFamily(protoFamily uint32) bool {
+	afi, safi := GetAfiSafi(protoFamily)
+	return afi == AfiLinkState && safi == SafiLinkState
+}
+
+func IsVPNFamily(protoFamily uint32) bool {
+	_, safi := GetAfiSafi(protoFamily)
+	return safi == SafiMPLSVPN
+}
+
+func GetProtocolFamilyStr(protoFamily uint32) string {
+	for name, pf := range ProtocolFamilyMap {
+		if pf == protoFamily {
+			return name
+		}
+	}
+
+	afi, safi := GetAfiSafi(protoFamily)
+	return fmt.Sprintf("afi-%d-safi-%d", afi, safi)
 }
 
 func GetAfiSafi(protocolFamily uint32) (AFI, SAFI) {
@@ -36,10 +36,11 @@ import (
 type BGPPktInfo struct {
 	Msg      *BGPMessage
 	MsgError *BGPMessageError
+	Data     []byte // Raw message as received, set only when the message is logged
 }
 
 func NewBGPPktInfo(msg *BGPMessage, msgError *BGPMessageError) *BGPPktInfo {
-	return &BGPPktInfo{msg, msgError}
+	return &BGPPktInfo{msg, msgError, nil}
 }
 
 type BGPPktSrc struct {
@@ -55,6 +56,16 @@ type BGPPeerAttrs struct {
 	ASSize           uint8
 	AddPathFamily    map[AFI]map[SAFI]uint8
 	AddPathsRxActual bool
+	AddPathsRxFamily map[uint32]bool
+}
+
+// IsAddPathsRx returns true if the NLRIs of the family are received with path ids. Path ids are received for all
+// the families when the families are not set.
+func (p BGPPeerAttrs) IsAddPathsRx(afi AFI, safi SAFI) bool {
+	if p.AddPathsRxFamily != nil {
+		return p.AddPathsRxFamily[GetProtocolFamily(afi, safi)]
+	}
+	return p.AddPathsRxActual
 }
 
 const BGPASTrans uint16 = 23456
@@ -67,6 +78,7 @@ const (
 	BGPMsgTypeUpdate
 	BGPMsgTypeNotification
 	BGPMsgTypeKeepAlive
+	BGPMsgTypeRouteRefresh
 )
 
 const (
@@ -137,14 +149,24 @@ type BGPCapabilityType uint8
 const (
 	_ BGPCapabilityType = iota
 	BGPCapTypeMPExt
-	BGPCapTypeAS4Path BGPCapabilityType = 65
-	BGPCapTypeAddPath BGPCapabilityType = 69
+	BGPCapTypeRouteRefresh
+	BGPCapTypeORF
+	BGPCapTypeExtendedNextHop      BGPCapabilityType = 5
+	BGPCapTypeGracefulRestart      BGPCapabilityType = 64
+	BGPCapTypeAS4Path              BGPCapabilityType = 65
+	BGPCapTypeAddPath              BGPCapabilityType = 69
+	BGPCapTypeEnhancedRouteRefresh BGPCapabilityType = 70
 )
 
 var BGPCapTypeToStruct = map[BGPCapabilityType]BGPCapability{
-	BGPCapTypeMPExt:   &BGPCapMPExt{},
-	BGPCapTypeAS4Path: &BGPCapAS4Path{},
-	BGPCapTypeAddPath: &BGPCapAddPath{},
+	BGPCapTypeMPExt:                &BGPCapMPExt{},
+	BGPCapTypeRouteRefresh:         &BGPCapRouteRefresh{},
+	BGPCapTypeORF:                  &BGPCapORF{},
+	BGPCapTypeExtendedNextHop:      &BGPCapExtendedNextHop{},
+	BGPCapTypeGracefulRestart:      &BGPCapGracefulRestart{},
+	BGPCapTypeAS4Path:              &BGPCapAS4Path{},
+	BGPCapTypeAddPath:              &BGPCapAddPath{},
+	BGPCapTypeEnhancedRouteRefresh: &BGPCapRouteRefresh{},
 }
 
 const (
@@ -152,6 +174,12 @@ const (
 	BGPCapAddPathTx
 )
 
+const (
+	BGPCapGracefulRestartFlagRestart     uint8  = 0x8
+	BGPCapGracefulRestartForwardingState uint8  = 0x80
+	BGPCapGracefulRestartMaxRestartTime  uint16 = 0xFFF
+)
+
 type BGPPathAttrFlag uint8
 
 const (
@@ -176,7 +204,7 @@ const (
 	BGPPathAttrTypeLocalPref
 	BGPPathAttrTypeAtomicAggregate
 	BGPPathAttrTypeAggregator
-	_
+	BGPPathAttrTypeCommunities
 	BGPPathAttrTypeOriginatorId
 	BGPPathAttrTypeClusterList
 	_
@@ -184,10 +212,13 @@ const (
 	_
 	BGPPathAttrTypeMPReachNLRI
 	BGPPathAttrTypeMPUnreachNLRI
-	_
+	BGPPathAttrTypeExtCommunities
 	BGPPathAttrTypeAS4Path
 	BGPPathAttrTypeAS4Aggregator
 	BGPPathAttrTypeUnknown
+	BGPPathAttrTypePMSITunnel     BGPPathAttrType = 22
+	BGPPathAttrTypeLinkState      BGPPathAttrType = 29
+	BGPPathAttrTypeLargeCommunity BGPPathAttrType = 32
 )
 
 type BGPPathAttrOriginType uint8
@@ -225,12 +256,17 @@ var BGPPathAttrTypeToStructMap = map[BGPPathAttrType]BGPPathAttr{
 	BGPPathAttrTypeLocalPref:       &BGPPathAttrLocalPref{},
 	BGPPathAttrTypeAtomicAggregate: &BGPPathAttrAtomicAggregate{},
 	BGPPathAttrTypeAggregator:      &BGPPathAttrAggregator{},
+	BGPPathAttrTypeCommunities:     &BGPPathAttrCommunities{},
 	BGPPathAttrTypeOriginatorId:    &BGPPathAttrOriginatorId{},
 	BGPPathAttrTypeClusterList:     &BGPPathAttrClusterList{},
 	BGPPathAttrTypeMPReachNLRI:     &BGPPathAttrMPReachNLRI{},
 	BGPPathAttrTypeMPUnreachNLRI:   &BGPPathAttrMPUnreachNLRI{},
+	BGPPathAttrTypeExtCommunities:  &BGPPathAttrExtCommunities{},
 	BGPPathAttrTypeAS4Path:         &BGPPathAttrAS4Path{},
 	BGPPathAttrTypeAS4Aggregator:   &BGPPathAttrAS4Aggregator{},
+	BGPPathAttrTypePMSITunnel:      &BGPPathAttrPMSITunnel{},
+	BGPPathAttrTypeLinkState:       &BGPPathAttrLinkState{},
+	BGPPathAttrTypeLargeCommunity:  &BGPPathAttrLargeCommunities{},
 }
 
 var BGPPathAttrTypeFlagsMap = map[BGPPathAttrType][]BGPPathAttrFlag{
@@ -241,12 +277,17 @@ var BGPPathAttrTypeFlagsMap = map[BGPPathAttrType][]BGPPathAttrFlag{
 	BGPPathAttrTypeLocalPref:       []BGPPathAttrFlag{BGPPathAttrFlagTransitive, BGPPathAttrFlagAllMinusExtendedLen},
 	BGPPathAttrTypeAtomicAggregate: []BGPPathAttrFlag{BGPPathAttrFlagTransitive, BGPPathAttrFlagAllMinusExtendedLen},
 	BGPPathAttrTypeAggregator:      []BGPPathAttrFlag{BGPPathAttrFlagOptional | BGPPathAttrFlagTransitive, BGPPathAttrFlagAllMinusExtendedLen},
+	BGPPathAttrTypeCommunities:     []BGPPathAttrFlag{BGPPathAttrFlagOptional | BGPPathAttrFlagTransitive, BGPPathAttrFlagAllMinusExtendedLen},
 	BGPPathAttrTypeOriginatorId:    []BGPPathAttrFlag{BGPPathAttrFlagOptional, BGPPathAttrFlagAllMinusExtendedLen},
 	BGPPathAttrTypeClusterList:     []BGPPathAttrFlag{BGPPathAttrFlagOptional, BGPPathAttrFlagAllMinusExtendedLen},
 	BGPPathAttrTypeMPReachNLRI:     []BGPPathAttrFlag{BGPPathAttrFlagOptional, BGPPathAttrFlagAllMinusExtendedLen},
 	BGPPathAttrTypeMPUnreachNLRI:   []BGPPathAttrFlag{BGPPathAttrFlagOptional, BGPPathAttrFlagAllMinusExtendedLen},
+	BGPPathAttrTypeExtCommunities:  []BGPPathAttrFlag{BGPPathAttrFlagOptional | BGPPathAttrFlagTransitive, BGPPathAttrFlagAllMinusExtendedLen},
 	BGPPathAttrTypeAS4Path:         []BGPPathAttrFlag{BGPPathAttrFlagOptional | BGPPathAttrFlagTransitive, BGPPathAttrFlagAllMinusExtendedLen},
 	BGPPathAttrTypeAS4Aggregator:   []BGPPathAttrFlag{BGPPathAttrFlagOptional | BGPPathAttrFlagTransitive, BGPPathAttrFlagAllMinusExtendedLen},
+	BGPPathAttrTypePMSITunnel:      []BGPPathAttrFlag{BGPPathAttrFlagOptional | BGPPathAttrFlagTransitive, BGPPathAttrFlagAllMinusExtendedLen},
+	BGPPathAttrTypeLinkState:       []BGPPathAttrFlag{BGPPathAttrFlagOptional, BGPPathAttrFlagAllMinusExtendedLen},
+	BGPPathAttrTypeLargeCommunity:  []BGPPathAttrFlag{BGPPathAttrFlagOptional | BGPPathAttrFlagTransitive, BGPPathAttrFlagAllMinusExtendedLen},
 }
 
 var BGPPathAttrTypeLenMap = map[BGPPathAttrType]uint16{
@@ -399,6 +440,31 @@ func NewBGPCapMPExt(afi AFI, safi SAFI) *BGPCapMPExt {
 	}
 }
 
+type BGPCapRouteRefresh struct {
+	BGPCapabilityBase
+}
+
+func (msg *BGPCapRouteRefresh) New() BGPCapability {
+	return &BGPCapRouteRefresh{}
+}
+
+func (msg *BGPCapRouteRefresh) Encode() ([]byte, error) {
+	return msg.BGPCapabilityBase.Encode()
+}
+
+func (msg *BGPCapRouteRefresh) Decode(pkt []byte) error {
+	return msg.BGPCapabilityBase.Decode(pkt)
+}
+
+func NewBGPCapRouteRefresh() *BGPCapRouteRefresh {
+	return &BGPCapRouteRefresh{
+		BGPCapabilityBase: BGPCapabilityBase{
+			Type: BGPCapTypeRouteRefresh,
+			Len:  0,
+		},
+	}
+}
+
 type BGPCapAS4Path struct {
 	BGPCapabilityBase
 	Value uint32
@@ -535,6 +601,217 @@ func NewBGPCapAddPath() *BGPCapAddPath {
 	}
 }
 
+type GracefulRestartAFISAFI struct {
+	AFI   AFI
+	SAFI  SAFI
+	Flags uint8
+}
+
+func (g *GracefulRestartAFISAFI) Encode(pkt []byte) error {
+	binary.BigEndian.PutUint16(pkt, uint16(g.AFI))
+	pkt[2] = uint8(g.SAFI)
+	pkt[3] = g.Flags
+	return nil
+}
+
+func (g *GracefulRestartAFISAFI) Decode(pkt []byte) error {
+	if len(pkt) < 4 {
+		return BGPMessageError{BGPOpenMsgError, BGPUnspecific, nil,
+			"Not enough data to decode graceful restart capability"}
+	}
+
+	g.AFI = AFI(binary.BigEndian.Uint16(pkt))
+	g.SAFI = SAFI(pkt[2])
+	g.Flags = pkt[3]
+	return nil
+}
+
+func (g *GracefulRestartAFISAFI) Len() uint8 {
+	return 4
+}
+
+func (g *GracefulRestartAFISAFI) IsForwardingStatePreserved() bool {
+	return g.Flags&BGPCapGracefulRestartForwardingState != 0
+}
+
+type BGPCapGracefulRestart struct {
+	BGPCapabilityBase
+	Flags       uint8
+	RestartTime uint16
+	Value       []GracefulRestartAFISAFI
+}
+
+func (msg *BGPCapGracefulRestart) New() BGPCapability {
+	return &BGPCapGracefulRestart{}
+}
+
+func (msg *BGPCapGracefulRestart) Encode() ([]byte, error) {
+	pkt, err := msg.BGPCapabilityBase.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	binary.BigEndian.PutUint16(pkt[2:], uint16(msg.Flags)<<12|(msg.RestartTime&BGPCapGracefulRestartMaxRestartTime))
+	offset := uint8(4)
+	for _, val := range msg.Value {
+		val.Encode(pkt[offset:])
+		offset += val.Len()
+	}
+	return pkt, nil
+}
+
+func (msg *BGPCapGracefulRestart) Decode(pkt []byte) error {
+	err := msg.BGPCapabilityBase.Decode(pkt)
+	if err != nil {
+		return err
+	}
+
+	if msg.Len < 2 || (msg.Len-2)%4 != 0 {
+		return BGPMessageError{BGPOpenMsgError, BGPUnspecific, nil,
+			fmt.Sprintf("Graceful restart capability length %d is not valid", msg.Len)}
+	}
+
+	restartFlagsAndTime := binary.BigEndian.Uint16(pkt[2:])
+	msg.Flags = uint8(restartFlagsAndTime >> 12)
+	msg.RestartTime = restartFlagsAndTime & BGPCapGracefulRestartMaxRestartTime
+	msg.Value = make([]GracefulRestartAFISAFI, 0)
+
+	offset := uint16(4)
+	for offset < msg.TotalLen() {
+		grAFISAFI := GracefulRestartAFISAFI{}
+		err := grAFISAFI.Decode(pkt[offset:])
+		if err != nil {
+			return err
+		}
+		msg.Value = append(msg.Value, grAFISAFI)
+		offset += uint16(grAFISAFI.Len())
+	}
+	return nil
+}
+
+func (msg *BGPCapGracefulRestart) IsRestarting() bool {
+	return msg.Flags&BGPCapGracefulRestartFlagRestart != 0
+}
+
+func (msg *BGPCapGracefulRestart) AddGracefulRestartAFISAFI(afi AFI, safi SAFI, flags uint8) {
+	grAFISAFI := GracefulRestartAFISAFI{afi, safi, flags}
+	msg.Value = append(msg.Value, grAFISAFI)
+	msg.Len += grAFISAFI.Len()
+}
+
+func NewBGPCapGracefulRestart(restarting bool, restartTime uint16) *BGPCapGracefulRestart {
+	flags := uint8(0)
+	if restarting {
+		flags |= BGPCapGracefulRestartFlagRestart
+	}
+	if restartTime > BGPCapGracefulRestartMaxRestartTime {
+		restartTime = BGPCapGracefulRestartMaxRestartTime
+	}
+
+	return &BGPCapGracefulRestart{
+		BGPCapabilityBase: BGPCapabilityBase{
+			Type: BGPCapTypeGracefulRestart,
+			Len:  2,
+		},
+		Flags:       flags,
+		RestartTime: restartTime,
+		Value:       make([]GracefulRestartAFISAFI, 0),
+	}
+}
+
+type ExtNextHopAFISAFI struct {
+	AFI        AFI
+	SAFI       uint16
+	NextHopAFI AFI
+}
+
+func (e *ExtNextHopAFISAFI) Encode(pkt []byte) error {
+	binary.BigEndian.PutUint16(pkt, uint16(e.AFI))
+	binary.BigEndian.PutUint16(pkt[2:], e.SAFI)
+	binary.BigEndian.PutUint16(pkt[4:], uint16(e.NextHopAFI))
+	return nil
+}
+
+func (e *ExtNextHopAFISAFI) Decode(pkt []byte) error {
+	if len(pkt) < 6 {
+		return BGPMessageError{BGPOpenMsgError, BGPUnspecific, nil,
+			"Not enough data to decode extended next hop capability"}
+	}
+
+	e.AFI = AFI(binary.BigEndian.Uint16(pkt))
+	e.SAFI = binary.BigEndian.Uint16(pkt[2:])
+	e.NextHopAFI = AFI(binary.BigEndian.Uint16(pkt[4:]))
+	return nil
+}
+
+func (e *ExtNextHopAFISAFI) Len() uint8 {
+	return 6
+}
+
+type BGPCapExtendedNextHop struct {
+	BGPCapabilityBase
+	Value []ExtNextHopAFISAFI
+}
+
+func (msg *BGPCapExtendedNextHop) New() BGPCapability {
+	return &BGPCapExtendedNextHop{}
+}
+
+func (msg *BGPCapExtendedNextHop) Encode() ([]byte, error) {
+	pkt, err := msg.BGPCapabilityBase.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := uint8(2)
+	for _, val := range msg.Value {
+		val.Encode(pkt[offset:])
+		offset += val.Len()
+	}
+	return pkt, nil
+}
+
+func (msg *BGPCapExtendedNextHop) Decode(pkt []byte) error {
+	err := msg.BGPCapabilityBase.Decode(pkt)
+	if err != nil {
+		return err
+	}
+
+	if msg.Len%6 != 0 {
+		return BGPMessageError{BGPOpenMsgError, BGPUnspecific, nil,
+			fmt.Sprintf("Extended next hop capability length %d is not valid", msg.Len)}
+	}
+
+	msg.Value = make([]ExtNextHopAFISAFI, 0)
+	offset := uint16(2)
+	for offset < msg.TotalLen() {
+		extNHAFISAFI := ExtNextHopAFISAFI{}
+		err := extNHAFISAFI.Decode(pkt[offset:])
+		if err != nil {
+			return err
+		}
+		msg.Value = append(msg.Value, extNHAFISAFI)
+		offset += uint16(extNHAFISAFI.Len())
+	}
+	return nil
+}
+
+func (msg *BGPCapExtendedNextHop) AddExtNextHopAFISAFI(afi AFI, safi SAFI, nextHopAFI AFI) {
+	extNHAFISAFI := ExtNextHopAFISAFI{afi, uint16(safi), nextHopAFI}
+	msg.Value = append(msg.Value, extNHAFISAFI)
+	msg.Len += extNHAFISAFI.Len()
+}
+
+func NewBGPCapExtendedNextHop() *BGPCapExtendedNextHop {
+	return &BGPCapExtendedNextHop{
+		BGPCapabilityBase: BGPCapabilityBase{
+			Type: BGPCapTypeExtendedNextHop,
+			Len:  0,
+		},
+		Value: make([]ExtNextHopAFISAFI, 0),
+	}
+}
+
 type BGPCapUnknown struct {
 	BGPCapabilityBase
 	Value []byte
@@ -862,6 +1139,98 @@ func NewBGPNotificationMessage(errorCode uint8, errorSubCode uint8, data []byte)
 	}
 }
 
+const (
+	BGPRouteRefreshNormal uint8 = iota
+	BGPRouteRefreshBoRR
+	BGPRouteRefreshEoRR
+)
+
+const BGPRouteRefreshMsgLen = 4
+
+type BGPRouteRefresh struct {
+	AFI           AFI
+	SubType       uint8
+	SAFI          SAFI
+	WhenToRefresh uint8
+	ORFs          []*ORFEntries
+}
+
+func (msg *BGPRouteRefresh) Clone() BGPBody {
+	x := *msg
+	if msg.ORFs != nil {
+		x.ORFs = make([]*ORFEntries, len(msg.ORFs))
+		for idx, orf := range msg.ORFs {
+			x.ORFs[idx] = orf.Clone()
+		}
+	}
+	return &x
+}
+
+func (msg *BGPRouteRefresh) Encode() ([]byte, error) {
+	pkt := make([]byte, BGPRouteRefreshMsgLen)
+	binary.BigEndian.PutUint16(pkt[0:], uint16(msg.AFI))
+	pkt[2] = msg.SubType
+	pkt[3] = uint8(msg.SAFI)
+	if len(msg.ORFs) == 0 {
+		return pkt, nil
+	}
+
+	pkt = append(pkt, msg.WhenToRefresh)
+	for _, orf := range msg.ORFs {
+		bytes, err := orf.Encode(msg.AFI)
+		if err != nil {
+			return nil, err
+		}
+		pkt = append(pkt, bytes...)
+	}
+	return pkt, nil
+}
+
+func (msg *BGPRouteRefresh) Decode(header *BGPHeader, pkt []byte, data interface{}) error {
+	if len(pkt) != BGPRouteRefreshMsgLen && len(pkt) < BGPRouteRefreshMsgLen+4 {
+		lenBytes := make([]byte, 2)
+		binary.BigEndian.PutUint16(lenBytes, header.Length)
+		return BGPMessageError{BGPMsgHeaderError, BGPBadMessageLen, lenBytes,
+			fmt.Sprintf("ROUTE-REFRESH message length is %d", header.Length)}
+	}
+
+	msg.AFI = AFI(binary.BigEndian.Uint16(pkt[0:]))
+	msg.SubType = pkt[2]
+	msg.SAFI = SAFI(pkt[3])
+	if len(pkt) == BGPRouteRefreshMsgLen {
+		return nil
+	}
+
+	msg.WhenToRefresh = pkt[4]
+	msg.ORFs = make([]*ORFEntries, 0)
+	offset := BGPRouteRefreshMsgLen + 1
+	for offset < len(pkt) {
+		orf := &ORFEntries{}
+		orfLen, err := orf.Decode(pkt[offset:], msg.AFI)
+		if err != nil {
+			return err
+		}
+		msg.ORFs = append(msg.ORFs, orf)
+		offset += orfLen
+	}
+	return nil
+}
+
+func NewBGPRouteRefreshMessage(afi AFI, safi SAFI, subType uint8) *BGPMessage {
+	return &BGPMessage{
+		Header: BGPHeader{Length: BGPMsgHeaderLen + BGPRouteRefreshMsgLen, Type: BGPMsgTypeRouteRefresh},
+		Body:   &BGPRouteRefresh{AFI: afi, SubType: subType, SAFI: safi},
+	}
+}
+
+func NewBGPRouteRefreshORFMessage(afi AFI, safi SAFI, whenToRefresh uint8, orfs []*ORFEntries) *BGPMessage {
+	return &BGPMessage{
+		Header: BGPHeader{Type: BGPMsgTypeRouteRefresh},
+		Body: &BGPRouteRefresh{AFI: afi, SubType: BGPRouteRefreshNormal, SAFI: safi, WhenToRefresh: whenToRefresh,
+			ORFs: orfs},
+	}
+}
+
 type NLRI interface {
 	Clone() NLRI
 	Encode(AFI) ([]byte, error)
@@ -2313,7 +2682,15 @@ func decodeNLRI(pkt []byte, ipPrefix *[]NLRI, length uint32, afi AFI, safi SAFI,
 	peerAttrs := data.(BGPPeerAttrs)
 
 	for ptr < length {
-		if peerAttrs.AddPathsRxActual {
+		if safi == SafiFlowSpec {
+			ip = &FlowSpecNLRI{}
+		} else if safi == SafiEVPN {
+			ip = &EVPNNLRI{}
+		} else if safi == SafiMPLSVPN {
+			ip = &VPNNLRI{}
+		} else if safi == SafiLinkState {
+			ip = &LinkStateNLRI{}
+		} else if peerAttrs.IsAddPathsRx(afi, safi) {
 			ip = &ExtNLRI{}
 		} else {
 			ip = &IPPrefix{}
@@ -2464,6 +2841,9 @@ func (msg *BGPMessage) Decode(header *BGPHeader, pkt []byte, data interface{}) e
 	case BGPMsgTypeNotification:
 		msg.Body = &BGPNotification{}
 
+	case BGPMsgTypeRouteRefresh:
+		msg.Body = &BGPRouteRefresh{}
+
 	default:
 		return nil
 	}
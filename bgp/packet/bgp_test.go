@@ -436,3 +436,289 @@ func TestBGPUpdateEncode(t *testing.T) {
 		t.Fatal("Cloned update message is not the same as the original message")
 	}
 }
+
+func TestBGPRouteRefreshEncodeDecode(t *testing.T) {
+	msg := NewBGPRouteRefreshMessage(AfiIP6, SafiUnicast, BGPRouteRefreshNormal)
+	pkt, err := msg.Encode()
+	if err != nil {
+		t.Fatal("BGP route refresh message encode failed with error", err)
+	}
+
+	expected := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0x00, 0x17, 0x05, 0x00, 0x02, 0x00, 0x01}
+	if !bytes.Equal(pkt, expected) {
+		t.Fatalf("BGP route refresh message encode - expected %x, got %x", expected, pkt)
+	}
+
+	bgpHeader := NewBGPHeader()
+	err = bgpHeader.Decode(pkt[:BGPMsgHeaderLen])
+	if err != nil {
+		t.Fatal("BGP packet header decode failed with error", err)
+	}
+
+	bgpMessage := NewBGPMessage()
+	err = bgpMessage.Decode(bgpHeader, pkt[BGPMsgHeaderLen:], BGPPeerAttrs{ASSize: 4})
+	if err != nil {
+		t.Fatal("BGP route refresh message decode failed with error", err)
+	}
+
+	refresh, ok := bgpMessage.Body.(*BGPRouteRefresh)
+	if !ok {
+		t.Fatalf("BGP route refresh message decode - expected body type BGPRouteRefresh, got %T", bgpMessage.Body)
+	}
+	if refresh.AFI != AfiIP6 || refresh.SAFI != SafiUnicast || refresh.SubType != BGPRouteRefreshNormal {
+		t.Fatalf("BGP route refresh message decode - got %+v", refresh)
+	}
+}
+
+func TestBGPRouteRefreshBadLength(t *testing.T) {
+	pkt := []byte{0x00, 0x01, 0x00, 0x01, 0x00}
+	bgpHeader := &BGPHeader{Length: uint16(BGPMsgHeaderLen + len(pkt)), Type: BGPMsgTypeRouteRefresh}
+	bgpMessage := NewBGPMessage()
+	err := bgpMessage.Decode(bgpHeader, pkt, BGPPeerAttrs{ASSize: 4})
+	if err == nil {
+		t.Fatal("BGP route refresh message decode called... expected failure, got NO error")
+	}
+
+	msgErr := err.(BGPMessageError)
+	if msgErr.TypeCode != BGPMsgHeaderError || msgErr.SubTypeCode != BGPBadMessageLen {
+		t.Fatalf("BGP route refresh message decode - expected bad message length error, got %+v", msgErr)
+	}
+}
+
+func TestBGPOpenRouteRefreshCapability(t *testing.T) {
+	afiSafiMap := map[uint32]bool{GetProtocolFamily(AfiIP, SafiUnicast): true}
+	optParams := ConstructOptParams(65000, afiSafiMap, false, 0, nil, false)
+	openMsg := NewBGPOpenMessage(65000, 90, "10.1.1.1", optParams)
+	pkt, err := openMsg.Encode()
+	if err != nil {
+		t.Fatal("BGP open message encode failed with error", err)
+	}
+
+	bgpHeader := NewBGPHeader()
+	err = bgpHeader.Decode(pkt[:BGPMsgHeaderLen])
+	if err != nil {
+		t.Fatal("BGP packet header decode failed with error", err)
+	}
+
+	bgpMessage := NewBGPMessage()
+	err = bgpMessage.Decode(bgpHeader, pkt[BGPMsgHeaderLen:], BGPPeerAttrs{ASSize: 2})
+	if err != nil {
+		t.Fatal("BGP open message decode failed with error", err)
+	}
+
+	if !IsRouteRefreshSupported(bgpMessage.Body.(*BGPOpen)) {
+		t.Fatal("BGP open message does not advertise route refresh capability")
+	}
+}
+
+func TestBGPCapGracefulRestartEncodeDecode(t *testing.T) {
+	grCap := NewBGPCapGracefulRestart(true, 5000)
+	grCap.AddGracefulRestartAFISAFI(AfiIP, SafiUnicast, BGPCapGracefulRestartForwardingState)
+	grCap.AddGracefulRestartAFISAFI(AfiIP6, SafiUnicast, 0)
+	pkt, err := grCap.Encode()
+	if err != nil {
+		t.Fatal("BGP graceful restart capability encode failed with error", err)
+	}
+
+	expected := []byte{0x40, 0x0a, 0x8f, 0xff, 0x00, 0x01, 0x01, 0x80, 0x00, 0x02, 0x01, 0x00}
+	if !bytes.Equal(pkt, expected) {
+		t.Fatalf("BGP graceful restart capability encode - expected %x, got %x", expected, pkt)
+	}
+
+	decoded := &BGPCapGracefulRestart{}
+	err = decoded.Decode(pkt)
+	if err != nil {
+		t.Fatal("BGP graceful restart capability decode failed with error", err)
+	}
+
+	if !decoded.IsRestarting() || decoded.RestartTime != BGPCapGracefulRestartMaxRestartTime ||
+		len(decoded.Value) != 2 {
+		t.Fatalf("BGP graceful restart capability decode - got %+v", decoded)
+	}
+	if !decoded.Value[0].IsForwardingStatePreserved() || decoded.Value[1].IsForwardingStatePreserved() {
+		t.Fatalf("BGP graceful restart capability decode - wrong forwarding state flags %+v", decoded.Value)
+	}
+
+	err = decoded.Decode([]byte{0x40, 0x03, 0x00, 0x78, 0x00})
+	if err == nil {
+		t.Fatal("BGP graceful restart capability decode called... expected failure, got NO error")
+	}
+}
+
+func TestBGPOpenGracefulRestartCapability(t *testing.T) {
+	afiSafiMap := map[uint32]bool{GetProtocolFamily(AfiIP, SafiUnicast): true}
+	for _, forwarding := range []bool{false, true} {
+		optParams := ConstructOptParams(65000, afiSafiMap, false, 0, NewBGPCapGracefulRestart(false, 120), forwarding)
+		openMsg := NewBGPOpenMessage(65000, 90, "10.1.1.1", optParams)
+		pkt, err := openMsg.Encode()
+		if err != nil {
+			t.Fatal("BGP open message encode failed with error", err)
+		}
+
+		bgpHeader := NewBGPHeader()
+		bgpHeader.Decode(pkt[:BGPMsgHeaderLen])
+		bgpMessage := NewBGPMessage()
+		err = bgpMessage.Decode(bgpHeader, pkt[BGPMsgHeaderLen:], BGPPeerAttrs{ASSize: 2})
+		if err != nil {
+			t.Fatal("BGP open message decode failed with error", err)
+		}
+
+		grCap := GetGracefulRestartCap(bgpMessage.Body.(*BGPOpen))
+		if grCap == nil {
+			t.Fatal("BGP open message does not advertise graceful restart capability")
+		}
+		if grCap.IsRestarting() || grCap.RestartTime != 120 || len(grCap.Value) != 1 ||
+			grCap.Value[0].AFI != AfiIP || grCap.Value[0].SAFI != SafiUnicast {
+			t.Fatalf("BGP open message graceful restart capability - got %+v", grCap)
+		}
+		if (grCap.Value[0].Flags&BGPCapGracefulRestartForwardingState != 0) != forwarding {
+			t.Errorf("BGP open message graceful restart forwarding state expected %t - got %+v", forwarding, grCap)
+		}
+	}
+}
+
+func TestBGPCapExtendedNextHopEncodeDecode(t *testing.T) {
+	extNHCap := NewBGPCapExtendedNextHop()
+	extNHCap.AddExtNextHopAFISAFI(AfiIP, SafiUnicast, AfiIP6)
+	pkt, err := extNHCap.Encode()
+	if err != nil {
+		t.Fatal("BGP extended next hop capability encode failed with error", err)
+	}
+
+	expected := []byte{0x05, 0x06, 0x00, 0x01, 0x00, 0x01, 0x00, 0x02}
+	if !bytes.Equal(pkt, expected) {
+		t.Fatalf("BGP extended next hop capability encode - expected %x, got %x", expected, pkt)
+	}
+
+	decoded := &BGPCapExtendedNextHop{}
+	err = decoded.Decode(pkt)
+	if err != nil {
+		t.Fatal("BGP extended next hop capability decode failed with error", err)
+	}
+
+	if len(decoded.Value) != 1 || decoded.Value[0].AFI != AfiIP || decoded.Value[0].SAFI != uint16(SafiUnicast) ||
+		decoded.Value[0].NextHopAFI != AfiIP6 {
+		t.Fatalf("BGP extended next hop capability decode - got %+v", decoded)
+	}
+
+	err = decoded.Decode([]byte{0x05, 0x04, 0x00, 0x01, 0x00, 0x01})
+	if err == nil {
+		t.Fatal("BGP extended next hop capability decode called... expected failure, got NO error")
+	}
+}
+
+func TestBGPOpenExtendedNextHopCapability(t *testing.T) {
+	afiSafiMap := map[uint32]bool{
+		GetProtocolFamily(AfiIP, SafiUnicast):  true,
+		GetProtocolFamily(AfiIP6, SafiUnicast): true,
+	}
+	optParams := ConstructOptParams(65000, afiSafiMap, false, 0, nil, false)
+	extNHCap := ConstructExtendedNextHopCap(afiSafiMap)
+	if extNHCap == nil {
+		t.Fatal("Extended next hop capability not constructed for IPv4 unicast")
+	}
+	optParams = append(optParams, NewBGPOptParamCapability([]BGPCapability{extNHCap}))
+	openMsg := NewBGPOpenMessage(65000, 90, "10.1.1.1", optParams)
+	pkt, err := openMsg.Encode()
+	if err != nil {
+		t.Fatal("BGP open message encode failed with error", err)
+	}
+
+	bgpHeader := NewBGPHeader()
+	bgpHeader.Decode(pkt[:BGPMsgHeaderLen])
+	bgpMessage := NewBGPMessage()
+	err = bgpMessage.Decode(bgpHeader, pkt[BGPMsgHeaderLen:], BGPPeerAttrs{ASSize: 2})
+	if err != nil {
+		t.Fatal("BGP open message decode failed with error", err)
+	}
+
+	extNHFamily := GetExtendedNextHopFamily(bgpMessage.Body.(*BGPOpen))
+	if len(extNHFamily) != 1 || !extNHFamily[GetProtocolFamily(AfiIP, SafiUnicast)] {
+		t.Fatalf("BGP open message extended next hop families - got %+v", extNHFamily)
+	}
+
+	ipv6Only := map[uint32]bool{GetProtocolFamily(AfiIP6, SafiUnicast): true}
+	if ConstructExtendedNextHopCap(ipv6Only) != nil {
+		t.Fatal("Extended next hop capability constructed without IPv4 unicast")
+	}
+}
+
+func TestBGPOpenAddPathCapabilityPerFamily(t *testing.T) {
+	afiSafiMap := map[uint32]bool{
+		GetProtocolFamily(AfiIP, SafiUnicast):  true,
+		GetProtocolFamily(AfiIP6, SafiUnicast): true,
+	}
+	optParams := ConstructOptParams(65000, afiSafiMap, false, 0, nil, false)
+	if ConstructAddPathCap(map[uint32]uint8{}) != nil {
+		t.Fatal("Add path capability constructed without any family")
+	}
+	addPathCap := ConstructAddPathCap(map[uint32]uint8{
+		GetProtocolFamily(AfiIP, SafiUnicast):  BGPCapAddPathRx | BGPCapAddPathTx,
+		GetProtocolFamily(AfiIP6, SafiUnicast): BGPCapAddPathRx,
+	})
+	optParams = append(optParams, NewBGPOptParamCapability([]BGPCapability{addPathCap}))
+	openMsg := NewBGPOpenMessage(65000, 90, "10.1.1.1", optParams)
+	pkt, err := openMsg.Encode()
+	if err != nil {
+		t.Fatal("BGP open message encode failed with error", err)
+	}
+
+	bgpHeader := NewBGPHeader()
+	bgpHeader.Decode(pkt[:BGPMsgHeaderLen])
+	bgpMessage := NewBGPMessage()
+	err = bgpMessage.Decode(bgpHeader, pkt[BGPMsgHeaderLen:], BGPPeerAttrs{ASSize: 2})
+	if err != nil {
+		t.Fatal("BGP open message decode failed with error", err)
+	}
+
+	addPathFamily := GetAddPathFamily(bgpMessage.Body.(*BGPOpen))
+	if addPathFamily[AfiIP][SafiUnicast] != BGPCapAddPathRx|BGPCapAddPathTx ||
+		addPathFamily[AfiIP6][SafiUnicast] != BGPCapAddPathRx {
+		t.Fatalf("BGP open message add path families - got %+v", addPathFamily)
+	}
+}
+
+func TestBGPUpdateDecodeAddPathsPerFamily(t *testing.T) {
+	prefix := NewIPPrefix(net.ParseIP("10.1.0.0"), 16)
+	peerAttrs := BGPPeerAttrs{
+		ASSize:           4,
+		AddPathsRxFamily: map[uint32]bool{GetProtocolFamily(AfiIP6, SafiUnicast): true},
+	}
+	if peerAttrs.IsAddPathsRx(AfiIP, SafiUnicast) || !peerAttrs.IsAddPathsRx(AfiIP6, SafiUnicast) {
+		t.Fatal("Add paths receive families not set, got", peerAttrs.AddPathsRxFamily)
+	}
+
+	decode := func(withdrawn NLRI, peerAttrs BGPPeerAttrs) NLRI {
+		updateMsg := NewBGPUpdateMessage([]NLRI{withdrawn}, nil, nil)
+		pkt, err := updateMsg.Encode()
+		if err != nil {
+			t.Fatal("BGP update message encode failed with error:", err)
+		}
+
+		bgpHeader := NewBGPHeader()
+		bgpHeader.Decode(pkt[:BGPMsgHeaderLen])
+		bgpMessage := NewBGPMessage()
+		err = bgpMessage.Decode(bgpHeader, pkt[BGPMsgHeaderLen:], peerAttrs)
+		if err != nil {
+			t.Fatal("BGP update message decode failed with error", err)
+		}
+
+		withdrawnRoutes := bgpMessage.Body.(*BGPUpdate).WithdrawnRoutes
+		if len(withdrawnRoutes) != 1 {
+			t.Fatal("BGP update message decode - expected 1 withdrawn route, got", withdrawnRoutes)
+		}
+		return withdrawnRoutes[0]
+	}
+
+	// Path ids are not negotiated for IPv4 unicast
+	if _, ok := decode(prefix, peerAttrs).(*IPPrefix); !ok {
+		t.Fatal("IPv4 withdrawn route decoded with path id")
+	}
+
+	peerAttrs.AddPathsRxFamily[GetProtocolFamily(AfiIP, SafiUnicast)] = true
+	nlri, ok := decode(NewExtNLRI(7, prefix), peerAttrs).(*ExtNLRI)
+	if !ok || nlri.PathId != 7 || nlri.GetCIDR() != "10.1.0.0/16" {
+		t.Fatal("IPv4 withdrawn route decoded without path id 7, got", nlri)
+	}
+}
@@ -0,0 +1,593 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// community.go
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	BGPCommunityGracefulShutdown  uint32 = 0xFFFF0000
+	BGPCommunityNoExport          uint32 = 0xFFFFFF01
+	BGPCommunityNoAdvertise       uint32 = 0xFFFFFF02
+	BGPCommunityNoExportSubconfed uint32 = 0xFFFFFF03
+)
+
+var BGPWellKnownCommunityToStrMap = map[uint32]string{
+	BGPCommunityGracefulShutdown:  "graceful-shutdown",
+	BGPCommunityNoExport:          "no-export",
+	BGPCommunityNoAdvertise:       "no-advertise",
+	BGPCommunityNoExportSubconfed: "no-export-subconfed",
+}
+
+const (
+	BGPExtCommunityTypeAS2      uint8 = 0x00
+	BGPExtCommunityTypeIPv4     uint8 = 0x01
+	BGPExtCommunityTypeAS4      uint8 = 0x02
+	BGPExtCommunitySubTypeRT    uint8 = 0x02
+	BGPExtCommunitySubTypeSOO   uint8 = 0x03
+	BGPExtCommunityTypeNonTrans uint8 = 0x40
+)
+
+var BGPExtCommunitySubTypeToStrMap = map[uint8]string{
+	BGPExtCommunitySubTypeRT:  "rt",
+	BGPExtCommunitySubTypeSOO: "soo",
+}
+
+func (pa *BGPPathAttrBase) setValueLength(length uint16) {
+	pa.Length = length
+	if length > 255 {
+		pa.Flags |= BGPPathAttrFlagExtendedLen
+		pa.BGPPathAttrLen = 4
+	} else {
+		pa.Flags &^= BGPPathAttrFlagExtendedLen
+		pa.BGPPathAttrLen = 3
+	}
+}
+
+type BGPPathAttrCommunities struct {
+	BGPPathAttrBase
+	Value []uint32
+}
+
+func (c *BGPPathAttrCommunities) Clone() BGPPathAttr {
+	x := *c
+	x.BGPPathAttrBase = c.BGPPathAttrBase.Clone()
+	x.Value = make([]uint32, len(c.Value))
+	copy(x.Value, c.Value)
+	return &x
+}
+
+func (c *BGPPathAttrCommunities) Encode() ([]byte, error) {
+	pkt, err := c.BGPPathAttrBase.Encode()
+	if err != nil {
+		return pkt, err
+	}
+
+	for i, community := range c.Value {
+		binary.BigEndian.PutUint32(pkt[int(c.BGPPathAttrLen)+(4*i):], community)
+	}
+	return pkt, nil
+}
+
+func (c *BGPPathAttrCommunities) Decode(pkt []byte, data interface{}) error {
+	err := c.BGPPathAttrBase.Decode(pkt, data)
+	if err != nil {
+		return err
+	}
+
+	if c.Length%4 != 0 {
+		return BGPMessageError{BGPUpdateMsgError, BGPOptionalAttrError, pkt[:c.TotalLen()],
+			fmt.Sprintf("COMMUNITIES length %d is not a multiple of 4", c.Length)}
+	}
+
+	c.Value = make([]uint32, c.Length/4)
+	for i := 0; i < len(c.Value); i++ {
+		c.Value[i] = binary.BigEndian.Uint32(pkt[int(c.BGPPathAttrLen)+(4*i):])
+	}
+	return nil
+}
+
+func (c *BGPPathAttrCommunities) New() BGPPathAttr {
+	return &BGPPathAttrCommunities{}
+}
+
+func (c *BGPPathAttrCommunities) String() string {
+	strList := make([]string, 0, len(c.Value))
+	for _, community := range c.Value {
+		strList = append(strList, CommunityToStr(community))
+	}
+	return fmt.Sprintf("{COMMUNITIES %s}", strings.Join(strList, " "))
+}
+
+func (c *BGPPathAttrCommunities) HasCommunity(community uint32) bool {
+	for _, val := range c.Value {
+		if val == community {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *BGPPathAttrCommunities) AddCommunity(community uint32) bool {
+	if c.HasCommunity(community) {
+		return false
+	}
+
+	c.Value = append(c.Value, community)
+	c.setValueLength(uint16(len(c.Value) * 4))
+	return true
+}
+
+func (c *BGPPathAttrCommunities) RemoveCommunity(community uint32) bool {
+	for idx, val := range c.Value {
+		if val == community {
+			c.Value = append(c.Value[:idx], c.Value[idx+1:]...)
+			c.setValueLength(uint16(len(c.Value) * 4))
+			return true
+		}
+	}
+	return false
+}
+
+func NewBGPPathAttrCommunities() *BGPPathAttrCommunities {
+	return &BGPPathAttrCommunities{
+		BGPPathAttrBase: BGPPathAttrBase{
+			Flags:          BGPPathAttrFlagOptional | BGPPathAttrFlagTransitive,
+			Code:           BGPPathAttrTypeCommunities,
+			Length:         0,
+			BGPPathAttrLen: 3,
+		},
+		Value: make([]uint32, 0),
+	}
+}
+
+type BGPPathAttrExtCommunities struct {
+	BGPPathAttrBase
+	Value []uint64
+}
+
+func (e *BGPPathAttrExtCommunities) Clone() BGPPathAttr {
+	x := *e
+	x.BGPPathAttrBase = e.BGPPathAttrBase.Clone()
+	x.Value = make([]uint64, len(e.Value))
+	copy(x.Value, e.Value)
+	return &x
+}
+
+func (e *BGPPathAttrExtCommunities) Encode() ([]byte, error) {
+	pkt, err := e.BGPPathAttrBase.Encode()
+	if err != nil {
+		return pkt, err
+	}
+
+	for i, community := range e.Value {
+		binary.BigEndian.PutUint64(pkt[int(e.BGPPathAttrLen)+(8*i):], community)
+	}
+	return pkt, nil
+}
+
+func (e *BGPPathAttrExtCommunities) Decode(pkt []byte, data interface{}) error {
+	err := e.BGPPathAttrBase.Decode(pkt, data)
+	if err != nil {
+		return err
+	}
+
+	if e.Length%8 != 0 {
+		return BGPMessageError{BGPUpdateMsgError, BGPOptionalAttrError, pkt[:e.TotalLen()],
+			fmt.Sprintf("EXTENDED COMMUNITIES length %d is not a multiple of 8", e.Length)}
+	}
+
+	e.Value = make([]uint64, e.Length/8)
+	for i := 0; i < len(e.Value); i++ {
+		e.Value[i] = binary.BigEndian.Uint64(pkt[int(e.BGPPathAttrLen)+(8*i):])
+	}
+	return nil
+}
+
+func (e *BGPPathAttrExtCommunities) New() BGPPathAttr {
+	return &BGPPathAttrExtCommunities{}
+}
+
+func (e *BGPPathAttrExtCommunities) String() string {
+	strList := make([]string, 0, len(e.Value))
+	for _, community := range e.Value {
+		strList = append(strList, ExtCommunityToStr(community))
+	}
+	return fmt.Sprintf("{EXTENDED COMMUNITIES %s}", strings.Join(strList, " "))
+}
+
+func (e *BGPPathAttrExtCommunities) HasCommunity(community uint64) bool {
+	for _, val := range e.Value {
+		if val == community {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *BGPPathAttrExtCommunities) AddCommunity(community uint64) bool {
+	if e.HasCommunity(community) {
+		return false
+	}
+
+	e.Value = append(e.Value, community)
+	e.setValueLength(uint16(len(e.Value) * 8))
+	return true
+}
+
+func (e *BGPPathAttrExtCommunities) RemoveCommunity(community uint64) bool {
+	for idx, val := range e.Value {
+		if val == community {
+			e.Value = append(e.Value[:idx], e.Value[idx+1:]...)
+			e.setValueLength(uint16(len(e.Value) * 8))
+			return true
+		}
+	}
+	return false
+}
+
+func NewBGPPathAttrExtCommunities() *BGPPathAttrExtCommunities {
+	return &BGPPathAttrExtCommunities{
+		BGPPathAttrBase: BGPPathAttrBase{
+			Flags:          BGPPathAttrFlagOptional | BGPPathAttrFlagTransitive,
+			Code:           BGPPathAttrTypeExtCommunities,
+			Length:         0,
+			BGPPathAttrLen: 3,
+		},
+		Value: make([]uint64, 0),
+	}
+}
+
+type LargeCommunity struct {
+	GlobalAdmin uint32
+	LocalData1  uint32
+	LocalData2  uint32
+}
+
+func (l LargeCommunity) String() string {
+	return fmt.Sprintf("%d:%d:%d", l.GlobalAdmin, l.LocalData1, l.LocalData2)
+}
+
+type BGPPathAttrLargeCommunities struct {
+	BGPPathAttrBase
+	Value []LargeCommunity
+}
+
+func (l *BGPPathAttrLargeCommunities) Clone() BGPPathAttr {
+	x := *l
+	x.BGPPathAttrBase = l.BGPPathAttrBase.Clone()
+	x.Value = make([]LargeCommunity, len(l.Value))
+	copy(x.Value, l.Value)
+	return &x
+}
+
+func (l *BGPPathAttrLargeCommunities) Encode() ([]byte, error) {
+	pkt, err := l.BGPPathAttrBase.Encode()
+	if err != nil {
+		return pkt, err
+	}
+
+	for i, community := range l.Value {
+		offset := int(l.BGPPathAttrLen) + (12 * i)
+		binary.BigEndian.PutUint32(pkt[offset:], community.GlobalAdmin)
+		binary.BigEndian.PutUint32(pkt[offset+4:], community.LocalData1)
+		binary.BigEndian.PutUint32(pkt[offset+8:], community.LocalData2)
+	}
+	return pkt, nil
+}
+
+func (l *BGPPathAttrLargeCommunities) Decode(pkt []byte, data interface{}) error {
+	err := l.BGPPathAttrBase.Decode(pkt, data)
+	if err != nil {
+		return err
+	}
+
+	if l.Length%12 != 0 {
+		return BGPMessageError{BGPUpdateMsgError, BGPOptionalAttrError, pkt[:l.TotalLen()],
+			fmt.Sprintf("LARGE_COMMUNITY length %d is not a multiple of 12", l.Length)}
+	}
+
+	l.Value = make([]LargeCommunity, l.Length/12)
+	for i := 0; i < len(l.Value); i++ {
+		offset := int(l.BGPPathAttrLen) + (12 * i)
+		l.Value[i].GlobalAdmin = binary.BigEndian.Uint32(pkt[offset:])
+		l.Value[i].LocalData1 = binary.BigEndian.Uint32(pkt[offset+4:])
+		l.Value[i].LocalData2 = binary.BigEndian.Uint32(pkt[offset+8:])
+	}
+	return nil
+}
+
+func (l *BGPPathAttrLargeCommunities) New() BGPPathAttr {
+	return &BGPPathAttrLargeCommunities{}
+}
+
+func (l *BGPPathAttrLargeCommunities) String() string {
+	strList := make([]string, 0, len(l.Value))
+	for _, community := range l.Value {
+		strList = append(strList, community.String())
+	}
+	return fmt.Sprintf("{LARGE_COMMUNITY %s}", strings.Join(strList, " "))
+}
+
+func (l *BGPPathAttrLargeCommunities) HasCommunity(community LargeCommunity) bool {
+	for _, val := range l.Value {
+		if val == community {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *BGPPathAttrLargeCommunities) AddCommunity(community LargeCommunity) bool {
+	if l.HasCommunity(community) {
+		return false
+	}
+
+	l.Value = append(l.Value, community)
+	l.setValueLength(uint16(len(l.Value) * 12))
+	return true
+}
+
+func (l *BGPPathAttrLargeCommunities) RemoveCommunity(community LargeCommunity) bool {
+	for idx, val := range l.Value {
+		if val == community {
+			l.Value = append(l.Value[:idx], l.Value[idx+1:]...)
+			l.setValueLength(uint16(len(l.Value) * 12))
+			return true
+		}
+	}
+	return false
+}
+
+func NewBGPPathAttrLargeCommunities() *BGPPathAttrLargeCommunities {
+	return &BGPPathAttrLargeCommunities{
+		BGPPathAttrBase: BGPPathAttrBase{
+			Flags:          BGPPathAttrFlagOptional | BGPPathAttrFlagTransitive,
+			Code:           BGPPathAttrTypeLargeCommunity,
+			Length:         0,
+			BGPPathAttrLen: 3,
+		},
+		Value: make([]LargeCommunity, 0),
+	}
+}
+
+func CommunityToStr(community uint32) string {
+	if str, ok := BGPWellKnownCommunityToStrMap[community]; ok {
+		return str
+	}
+	return fmt.Sprintf("%d:%d", community>>16, community&0xFFFF)
+}
+
+func ParseCommunity(str string) (uint32, error) {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for community, name := range BGPWellKnownCommunityToStrMap {
+		if str == name {
+			return community, nil
+		}
+	}
+
+	parts := strings.Split(str, ":")
+	if len(parts) != 2 {
+		return 0, errors.New(fmt.Sprintf("Community %s is not in AS:VAL format", str))
+	}
+
+	as, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Community %s has invalid AS %s", str, parts[0]))
+	}
+	val, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Community %s has invalid value %s", str, parts[1]))
+	}
+
+	return uint32(as)<<16 | uint32(val), nil
+}
+
+func ExtCommunityToStr(community uint64) string {
+	extType := uint8(community >> 56)
+	subType := uint8(community >> 48)
+	subTypeStr, ok := BGPExtCommunitySubTypeToStrMap[subType]
+	if !ok {
+		return fmt.Sprintf("0x%016x", community)
+	}
+
+	switch extType {
+	case BGPExtCommunityTypeAS2:
+		return fmt.Sprintf("%s:%d:%d", subTypeStr, (community>>32)&0xFFFF, community&0xFFFFFFFF)
+
+	case BGPExtCommunityTypeIPv4:
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(community>>16))
+		return fmt.Sprintf("%s:%s:%d", subTypeStr, ip, community&0xFFFF)
+
+	case BGPExtCommunityTypeAS4:
+		return fmt.Sprintf("%s:%d:%d", subTypeStr, (community>>16)&0xFFFFFFFF, community&0xFFFF)
+	}
+
+	return fmt.Sprintf("0x%016x", community)
+}
+
+func ParseExtCommunity(str string) (uint64, error) {
+	str = strings.ToLower(strings.TrimSpace(str))
+	parts := strings.Split(str, ":")
+	if len(parts) != 3 {
+		return 0, errors.New(fmt.Sprintf("Extended community %s is not in rt|soo:ADMIN:VAL format", str))
+	}
+
+	var subType uint8
+	found := false
+	for key, val := range BGPExtCommunitySubTypeToStrMap {
+		if val == parts[0] {
+			subType = key
+			found = true
+			break
+		}
+	}
+	if !found {
+		return 0, errors.New(fmt.Sprintf("Extended community %s has unknown type %s", str, parts[0]))
+	}
+
+	if ip := net.ParseIP(parts[1]); ip != nil {
+		if ip.To4() == nil {
+			return 0, errors.New(fmt.Sprintf("Extended community %s has non IPv4 admin %s", str, parts[1]))
+		}
+		val, err := strconv.ParseUint(parts[2], 10, 16)
+		if err != nil {
+			return 0, errors.New(fmt.Sprintf("Extended community %s has invalid value %s", str, parts[2]))
+		}
+		return uint64(BGPExtCommunityTypeIPv4)<<56 | uint64(subType)<<48 |
+			uint64(binary.BigEndian.Uint32(ip.To4()))<<16 | val, nil
+	}
+
+	as, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Extended community %s has invalid AS %s", str, parts[1]))
+	}
+
+	if as > 0xFFFF {
+		val, err := strconv.ParseUint(parts[2], 10, 16)
+		if err != nil {
+			return 0, errors.New(fmt.Sprintf("Extended community %s has invalid value %s", str, parts[2]))
+		}
+		return uint64(BGPExtCommunityTypeAS4)<<56 | uint64(subType)<<48 | as<<16 | val, nil
+	}
+
+	val, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Extended community %s has invalid value %s", str, parts[2]))
+	}
+	return uint64(BGPExtCommunityTypeAS2)<<56 | uint64(subType)<<48 | as<<32 | val, nil
+}
+
+func NewRouteTargetCommunity(as, val uint32) uint64 {
+	if as > 0xFFFF {
+		return uint64(BGPExtCommunityTypeAS4)<<56 | uint64(BGPExtCommunitySubTypeRT)<<48 | uint64(as)<<16 |
+			uint64(val&0xFFFF)
+	}
+	return uint64(BGPExtCommunityTypeAS2)<<56 | uint64(BGPExtCommunitySubTypeRT)<<48 | uint64(as)<<32 | uint64(val)
+}
+
+func ParseRouteTarget(str string) (uint64, error) {
+	str = strings.ToLower(strings.TrimSpace(str))
+	if !strings.HasPrefix(str, BGPExtCommunitySubTypeToStrMap[BGPExtCommunitySubTypeRT]+":") {
+		str = BGPExtCommunitySubTypeToStrMap[BGPExtCommunitySubTypeRT] + ":" + str
+	}
+	return ParseExtCommunity(str)
+}
+
+func ParseLargeCommunity(str string) (LargeCommunity, error) {
+	var community LargeCommunity
+	parts := strings.Split(strings.TrimSpace(str), ":")
+	if len(parts) != 3 {
+		return community, errors.New(fmt.Sprintf("Large community %s is not in ASN:VAL1:VAL2 format", str))
+	}
+
+	vals := make([]uint32, 3)
+	for idx, part := range parts {
+		val, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return community, errors.New(fmt.Sprintf("Large community %s has invalid field %s", str, part))
+		}
+		vals[idx] = uint32(val)
+	}
+
+	community.GlobalAdmin = vals[0]
+	community.LocalData1 = vals[1]
+	community.LocalData2 = vals[2]
+	return community, nil
+}
+
+func GetCommunities(pathAttrs []BGPPathAttr) []uint32 {
+	if attr := getTypeFromPathAttrs(pathAttrs, BGPPathAttrTypeCommunities); attr != nil {
+		return attr.(*BGPPathAttrCommunities).Value
+	}
+	return nil
+}
+
+func GetExtCommunities(pathAttrs []BGPPathAttr) []uint64 {
+	if attr := getTypeFromPathAttrs(pathAttrs, BGPPathAttrTypeExtCommunities); attr != nil {
+		return attr.(*BGPPathAttrExtCommunities).Value
+	}
+	return nil
+}
+
+func GetLargeCommunities(pathAttrs []BGPPathAttr) []LargeCommunity {
+	if attr := getTypeFromPathAttrs(pathAttrs, BGPPathAttrTypeLargeCommunity); attr != nil {
+		return attr.(*BGPPathAttrLargeCommunities).Value
+	}
+	return nil
+}
+
+func HasCommunity(pathAttrs []BGPPathAttr, community uint32) bool {
+	if attr := getTypeFromPathAttrs(pathAttrs, BGPPathAttrTypeCommunities); attr != nil {
+		return attr.(*BGPPathAttrCommunities).HasCommunity(community)
+	}
+	return false
+}
+
+func SetCommunities(pathAttrs []BGPPathAttr, communities []uint32) []BGPPathAttr {
+	removeTypeFromPathAttrs(&pathAttrs, BGPPathAttrTypeCommunities)
+	if len(communities) == 0 {
+		return pathAttrs
+	}
+
+	attr := NewBGPPathAttrCommunities()
+	for _, community := range communities {
+		attr.AddCommunity(community)
+	}
+	return AddPathAttrToPathAttrsByCode(pathAttrs, BGPPathAttrTypeCommunities, attr)
+}
+
+func SetExtCommunities(pathAttrs []BGPPathAttr, communities []uint64) []BGPPathAttr {
+	removeTypeFromPathAttrs(&pathAttrs, BGPPathAttrTypeExtCommunities)
+	if len(communities) == 0 {
+		return pathAttrs
+	}
+
+	attr := NewBGPPathAttrExtCommunities()
+	for _, community := range communities {
+		attr.AddCommunity(community)
+	}
+	return AddPathAttrToPathAttrsByCode(pathAttrs, BGPPathAttrTypeExtCommunities, attr)
+}
+
+func SetLargeCommunities(pathAttrs []BGPPathAttr, communities []LargeCommunity) []BGPPathAttr {
+	removeTypeFromPathAttrs(&pathAttrs, BGPPathAttrTypeLargeCommunity)
+	if len(communities) == 0 {
+		return pathAttrs
+	}
+
+	attr := NewBGPPathAttrLargeCommunities()
+	for _, community := range communities {
+		attr.AddCommunity(community)
+	}
+	return AddPathAttrToPathAttrsByCode(pathAttrs, BGPPathAttrTypeLargeCommunity, attr)
+}
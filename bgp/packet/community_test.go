@@ -0,0 +1,214 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// community_test.go
+package packet
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestBGPPathAttrCommunitiesEncodeDecode(t *testing.T) {
+	communities := NewBGPPathAttrCommunities()
+	communities.AddCommunity(0xFDE80064)
+	communities.AddCommunity(BGPCommunityNoExport)
+	if communities.AddCommunity(BGPCommunityNoExport) {
+		t.Fatal("BGPPathAttrCommunities.AddCommunity added a duplicate community")
+	}
+
+	pkt, err := communities.Encode()
+	if err != nil {
+		t.Fatal("BGPPathAttrCommunities.Encode failed with error:", err)
+	}
+	if hex.EncodeToString(pkt) != "c00808fde80064ffffff01" {
+		t.Fatal("BGPPathAttrCommunities.Encode returned unexpected packet:", hex.EncodeToString(pkt))
+	}
+
+	decoded := &BGPPathAttrCommunities{}
+	err = decoded.Decode(pkt, nil)
+	if err != nil {
+		t.Fatal("BGPPathAttrCommunities.Decode failed with error:", err)
+	}
+	if len(decoded.Value) != 2 || decoded.Value[0] != 0xFDE80064 || decoded.Value[1] != BGPCommunityNoExport {
+		t.Fatal("BGPPathAttrCommunities.Decode returned unexpected communities:", decoded.Value)
+	}
+	t.Log("Decoded communities:", decoded)
+
+	if !decoded.RemoveCommunity(BGPCommunityNoExport) || decoded.Length != 4 {
+		t.Fatal("BGPPathAttrCommunities.RemoveCommunity failed, communities:", decoded.Value, "length:",
+			decoded.Length)
+	}
+}
+
+func TestBGPPathAttrCommunitiesBadLength(t *testing.T) {
+	pkt, _ := hex.DecodeString("c00806fde80064ffff")
+	communities := &BGPPathAttrCommunities{}
+	err := communities.Decode(pkt, nil)
+	if err == nil {
+		t.Error("BGPPathAttrCommunities.Decode with bad length, expected failure, got NO error")
+	} else {
+		t.Log("BGPPathAttrCommunities.Decode with bad length, expected failure, error:", err)
+	}
+}
+
+func TestBGPPathAttrCommunitiesExtendedLen(t *testing.T) {
+	communities := NewBGPPathAttrCommunities()
+	for i := 0; i < 100; i++ {
+		communities.AddCommunity(uint32(65000<<16 | i))
+	}
+
+	if communities.Flags&BGPPathAttrFlagExtendedLen == 0 || communities.BGPPathAttrLen != 4 {
+		t.Fatal("BGPPathAttrCommunities extended length not set for length", communities.Length)
+	}
+
+	pkt, err := communities.Encode()
+	if err != nil {
+		t.Fatal("BGPPathAttrCommunities.Encode failed with error:", err)
+	}
+
+	decoded := &BGPPathAttrCommunities{}
+	err = decoded.Decode(pkt, nil)
+	if err != nil {
+		t.Fatal("BGPPathAttrCommunities.Decode failed with error:", err)
+	}
+	if len(decoded.Value) != 100 || decoded.Value[99] != uint32(65000<<16|99) {
+		t.Fatal("BGPPathAttrCommunities.Decode returned unexpected communities:", decoded.Value)
+	}
+}
+
+func TestBGPPathAttrExtCommunitiesEncodeDecode(t *testing.T) {
+	strs := []string{"rt:65000:100", "soo:10.1.1.1:20", "rt:4200000000:30"}
+	expected := []string{"0002fde800000064", "01030a0101010014", "0202fa56ea00001e"}
+	extCommunities := NewBGPPathAttrExtCommunities()
+	for idx, str := range strs {
+		community, err := ParseExtCommunity(str)
+		if err != nil {
+			t.Fatal("ParseExtCommunity failed for", str, "with error:", err)
+		}
+		if hex.EncodeToString([]byte{byte(community >> 56), byte(community >> 48), byte(community >> 40),
+			byte(community >> 32), byte(community >> 24), byte(community >> 16), byte(community >> 8),
+			byte(community)}) != expected[idx] {
+			t.Fatalf("ParseExtCommunity for %s returned unexpected value %016x", str, community)
+		}
+		if ExtCommunityToStr(community) != str {
+			t.Fatal("ExtCommunityToStr expected", str, "got", ExtCommunityToStr(community))
+		}
+		extCommunities.AddCommunity(community)
+	}
+
+	pkt, err := extCommunities.Encode()
+	if err != nil {
+		t.Fatal("BGPPathAttrExtCommunities.Encode failed with error:", err)
+	}
+
+	decoded := &BGPPathAttrExtCommunities{}
+	err = decoded.Decode(pkt, nil)
+	if err != nil {
+		t.Fatal("BGPPathAttrExtCommunities.Decode failed with error:", err)
+	}
+	if len(decoded.Value) != len(strs) {
+		t.Fatal("BGPPathAttrExtCommunities.Decode returned unexpected communities:", decoded)
+	}
+	t.Log("Decoded extended communities:", decoded)
+}
+
+func TestBGPPathAttrLargeCommunitiesEncodeDecode(t *testing.T) {
+	community, err := ParseLargeCommunity("4200000000:1:2")
+	if err != nil {
+		t.Fatal("ParseLargeCommunity failed with error:", err)
+	}
+
+	largeCommunities := NewBGPPathAttrLargeCommunities()
+	largeCommunities.AddCommunity(community)
+	pkt, err := largeCommunities.Encode()
+	if err != nil {
+		t.Fatal("BGPPathAttrLargeCommunities.Encode failed with error:", err)
+	}
+	if hex.EncodeToString(pkt) != "c0200cfa56ea000000000100000002" {
+		t.Fatal("BGPPathAttrLargeCommunities.Encode returned unexpected packet:", hex.EncodeToString(pkt))
+	}
+
+	decoded := &BGPPathAttrLargeCommunities{}
+	err = decoded.Decode(pkt, nil)
+	if err != nil {
+		t.Fatal("BGPPathAttrLargeCommunities.Decode failed with error:", err)
+	}
+	if len(decoded.Value) != 1 || decoded.Value[0] != community {
+		t.Fatal("BGPPathAttrLargeCommunities.Decode returned unexpected communities:", decoded.Value)
+	}
+	if decoded.Value[0].String() != "4200000000:1:2" {
+		t.Fatal("LargeCommunity.String returned unexpected value", decoded.Value[0].String())
+	}
+}
+
+func TestParseCommunity(t *testing.T) {
+	valid := map[string]uint32{
+		"65000:100":         0xFDE80064,
+		"no-export":         BGPCommunityNoExport,
+		"NO-ADVERTISE":      BGPCommunityNoAdvertise,
+		"graceful-shutdown": BGPCommunityGracefulShutdown,
+	}
+	for str, expected := range valid {
+		community, err := ParseCommunity(str)
+		if err != nil || community != expected {
+			t.Fatal("ParseCommunity for", str, "expected", expected, "got", community, "error", err)
+		}
+	}
+
+	invalid := []string{"65000", "70000:1", "1:70000", "a:b", "1:2:3"}
+	for _, str := range invalid {
+		if _, err := ParseCommunity(str); err == nil {
+			t.Error("ParseCommunity for", str, "expected failure, got NO error")
+		}
+	}
+}
+
+func TestParseRouteTarget(t *testing.T) {
+	for _, str := range []string{"65000:100", "rt:65000:100", " RT:65000:100"} {
+		rt, err := ParseRouteTarget(str)
+		if err != nil || rt != NewRouteTargetCommunity(65000, 100) {
+			t.Fatalf("ParseRouteTarget for %s returned %016x, error %v", str, rt, err)
+		}
+	}
+
+	for _, str := range []string{"soo:65000:100", "65000", "a:b"} {
+		if _, err := ParseRouteTarget(str); err == nil {
+			t.Error("ParseRouteTarget for", str, "expected failure, got NO error")
+		}
+	}
+}
+
+func TestSetCommunities(t *testing.T) {
+	pathAttrs := ConstructPathAttrForConnRoutes(65000)
+	numAttrs := len(pathAttrs)
+	pathAttrs = SetCommunities(pathAttrs, []uint32{BGPCommunityNoAdvertise})
+	if len(pathAttrs) != numAttrs+1 || !HasCommunity(pathAttrs, BGPCommunityNoAdvertise) {
+		t.Fatal("SetCommunities failed to add COMMUNITIES, path attrs:", pathAttrs)
+	}
+
+	pathAttrs = SetCommunities(pathAttrs, nil)
+	if len(pathAttrs) != numAttrs || GetCommunities(pathAttrs) != nil {
+		t.Fatal("SetCommunities failed to remove COMMUNITIES, path attrs:", pathAttrs)
+	}
+}
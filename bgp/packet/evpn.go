@@ -0,0 +1,763 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// evpn.go
+package packet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	AfiL2VPN AFI  = 25
+	SafiEVPN SAFI = 70
+)
+
+const (
+	EVPNRouteTypeEthernetAD uint8 = iota + 1
+	EVPNRouteTypeMacIPAdvertisement
+	EVPNRouteTypeInclusiveMulticast
+	EVPNRouteTypeEthernetSegment
+	EVPNRouteTypeIPPrefix
+)
+
+var EVPNRouteTypeToStrMap = map[uint8]string{
+	EVPNRouteTypeEthernetAD:         "ethernet-ad",
+	EVPNRouteTypeMacIPAdvertisement: "mac-ip",
+	EVPNRouteTypeInclusiveMulticast: "multicast",
+	EVPNRouteTypeEthernetSegment:    "ethernet-segment",
+	EVPNRouteTypeIPPrefix:           "ip-prefix",
+}
+
+const (
+	EVPNESILen   = 10
+	EVPNLabelLen = 3
+	EVPNMacLen   = 6
+)
+
+const (
+	BGPExtCommunityTypeOpaque uint8 = 0x03
+	BGPExtCommunityTypeEVPN   uint8 = 0x06
+
+	BGPExtCommunitySubTypeEncapsulation uint8 = 0x0C
+	BGPExtCommunitySubTypeMacMobility   uint8 = 0x00
+	BGPExtCommunitySubTypeRouterMac     uint8 = 0x03
+)
+
+const (
+	TunnelTypeVXLAN uint16 = 8
+)
+
+const (
+	PMSITunnelTypeNone               uint8 = 0
+	PMSITunnelTypeIngressReplication uint8 = 6
+)
+
+type EVPNESI [EVPNESILen]byte
+
+func (e EVPNESI) String() string {
+	strList := make([]string, 0, EVPNESILen)
+	for _, b := range e {
+		strList = append(strList, hex.EncodeToString([]byte{b}))
+	}
+	return strings.Join(strList, ":")
+}
+
+func encodeEVPNLabel(pkt []byte, label uint32) {
+	pkt[0] = uint8(label >> 16)
+	pkt[1] = uint8(label >> 8)
+	pkt[2] = uint8(label)
+}
+
+func decodeEVPNLabel(pkt []byte) uint32 {
+	return uint32(pkt[0])<<16 | uint32(pkt[1])<<8 | uint32(pkt[2])
+}
+
+func evpnIPLen(ip net.IP) int {
+	if len(ip) == 0 {
+		return 0
+	}
+	if ip.To4() != nil {
+		return net.IPv4len
+	}
+	return net.IPv6len
+}
+
+func evpnIPBytes(ip net.IP) []byte {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip.To16()
+}
+
+func evpnDecodeIP(pkt []byte, ipLen int) net.IP {
+	if ipLen == net.IPv4len {
+		return net.IPv4(pkt[0], pkt[1], pkt[2], pkt[3])
+	}
+	ip := make(net.IP, ipLen)
+	copy(ip, pkt[:ipLen])
+	return ip
+}
+
+func evpnIPStr(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+type EVPNRoute interface {
+	Clone() EVPNRoute
+	Encode() ([]byte, error)
+	Decode(pkt []byte) error
+	Len() uint8
+	GetRD() *RouteDistinguisher
+	GetPrefix() net.IP
+	GetLength() uint8
+	String() string
+}
+
+type EVPNMacIPAdvertisement struct {
+	RD     RouteDistinguisher
+	ESI    EVPNESI
+	EthTag uint32
+	MAC    net.HardwareAddr
+	IP     net.IP
+	Labels []uint32
+}
+
+func (m *EVPNMacIPAdvertisement) Clone() EVPNRoute {
+	x := *m
+	x.MAC = make(net.HardwareAddr, len(m.MAC))
+	copy(x.MAC, m.MAC)
+	if m.IP != nil {
+		x.IP = make(net.IP, len(m.IP))
+		copy(x.IP, m.IP)
+	}
+	x.Labels = make([]uint32, len(m.Labels))
+	copy(x.Labels, m.Labels)
+	return &x
+}
+
+func (m *EVPNMacIPAdvertisement) Encode() ([]byte, error) {
+	if len(m.MAC) != EVPNMacLen {
+		return nil, BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN MAC %s is not %d bytes", m.MAC, EVPNMacLen)}
+	}
+	if len(m.Labels) == 0 || len(m.Labels) > 2 {
+		return nil, BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN MAC/IP route should have 1 or 2 labels, has %d", len(m.Labels))}
+	}
+
+	pkt := make([]byte, 0, m.Len())
+	pkt = append(pkt, m.RD.Encode()...)
+	pkt = append(pkt, m.ESI[:]...)
+	pkt = append(pkt, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(pkt[len(pkt)-4:], m.EthTag)
+	pkt = append(pkt, EVPNMacLen*8)
+	pkt = append(pkt, m.MAC...)
+	ipLen := evpnIPLen(m.IP)
+	pkt = append(pkt, uint8(ipLen*8))
+	if ipLen > 0 {
+		pkt = append(pkt, evpnIPBytes(m.IP)...)
+	}
+	for _, label := range m.Labels {
+		pkt = append(pkt, 0, 0, 0)
+		encodeEVPNLabel(pkt[len(pkt)-EVPNLabelLen:], label)
+	}
+	return pkt, nil
+}
+
+func (m *EVPNMacIPAdvertisement) Decode(pkt []byte) error {
+	minLen := RouteDistinguisherLen + EVPNESILen + 4 + 1 + EVPNMacLen + 1 + EVPNLabelLen
+	if len(pkt) < minLen {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN MAC/IP route length %d is less than %d", len(pkt), minLen)}
+	}
+
+	if err := m.RD.Decode(pkt); err != nil {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil, err.Error()}
+	}
+	ptr := RouteDistinguisherLen
+	copy(m.ESI[:], pkt[ptr:ptr+EVPNESILen])
+	ptr += EVPNESILen
+	m.EthTag = binary.BigEndian.Uint32(pkt[ptr : ptr+4])
+	ptr += 4
+	if pkt[ptr] != EVPNMacLen*8 {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN MAC length %d is not %d", pkt[ptr], EVPNMacLen*8)}
+	}
+	ptr++
+	m.MAC = make(net.HardwareAddr, EVPNMacLen)
+	copy(m.MAC, pkt[ptr:ptr+EVPNMacLen])
+	ptr += EVPNMacLen
+	ipLen := int(pkt[ptr]) / 8
+	ptr++
+	if ipLen != 0 && ipLen != net.IPv4len && ipLen != net.IPv6len {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN MAC/IP route has invalid IP length %d", ipLen*8)}
+	}
+
+	labelsLen := len(pkt) - ptr - ipLen
+	if labelsLen != EVPNLabelLen && labelsLen != 2*EVPNLabelLen {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN MAC/IP route has invalid labels length %d", labelsLen)}
+	}
+
+	m.IP = nil
+	if ipLen > 0 {
+		m.IP = evpnDecodeIP(pkt[ptr:], ipLen)
+		ptr += ipLen
+	}
+
+	m.Labels = make([]uint32, 0, 2)
+	for ; ptr < len(pkt); ptr += EVPNLabelLen {
+		m.Labels = append(m.Labels, decodeEVPNLabel(pkt[ptr:]))
+	}
+	return nil
+}
+
+func (m *EVPNMacIPAdvertisement) Len() uint8 {
+	return uint8(RouteDistinguisherLen + EVPNESILen + 4 + 1 + EVPNMacLen + 1 + evpnIPLen(m.IP) +
+		EVPNLabelLen*len(m.Labels))
+}
+
+func (m *EVPNMacIPAdvertisement) GetRD() *RouteDistinguisher {
+	return &m.RD
+}
+
+func (m *EVPNMacIPAdvertisement) GetPrefix() net.IP {
+	return m.IP
+}
+
+func (m *EVPNMacIPAdvertisement) GetLength() uint8 {
+	return uint8(evpnIPLen(m.IP) * 8)
+}
+
+func (m *EVPNMacIPAdvertisement) GetVNI() uint32 {
+	if len(m.Labels) == 0 {
+		return 0
+	}
+	return m.Labels[0]
+}
+
+func (m *EVPNMacIPAdvertisement) String() string {
+	return fmt.Sprintf("[%s]:[%d]:[%d]:[%s]:[%d]:[%s]", m.RD.String(), m.EthTag, EVPNMacLen*8, m.MAC,
+		m.GetLength(), evpnIPStr(m.IP))
+}
+
+type EVPNInclusiveMulticast struct {
+	RD     RouteDistinguisher
+	EthTag uint32
+	IP     net.IP
+}
+
+func (i *EVPNInclusiveMulticast) Clone() EVPNRoute {
+	x := *i
+	x.IP = make(net.IP, len(i.IP))
+	copy(x.IP, i.IP)
+	return &x
+}
+
+func (i *EVPNInclusiveMulticast) Encode() ([]byte, error) {
+	ipLen := evpnIPLen(i.IP)
+	if ipLen == 0 {
+		return nil, BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			"EVPN inclusive multicast route does not have originating router IP"}
+	}
+
+	pkt := make([]byte, 0, i.Len())
+	pkt = append(pkt, i.RD.Encode()...)
+	pkt = append(pkt, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(pkt[len(pkt)-4:], i.EthTag)
+	pkt = append(pkt, uint8(ipLen*8))
+	pkt = append(pkt, evpnIPBytes(i.IP)...)
+	return pkt, nil
+}
+
+func (i *EVPNInclusiveMulticast) Decode(pkt []byte) error {
+	minLen := RouteDistinguisherLen + 4 + 1
+	if len(pkt) < minLen {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN inclusive multicast route length %d is less than %d", len(pkt), minLen)}
+	}
+
+	if err := i.RD.Decode(pkt); err != nil {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil, err.Error()}
+	}
+	ptr := RouteDistinguisherLen
+	i.EthTag = binary.BigEndian.Uint32(pkt[ptr : ptr+4])
+	ptr += 4
+	ipLen := int(pkt[ptr]) / 8
+	ptr++
+	if (ipLen != net.IPv4len && ipLen != net.IPv6len) || len(pkt) != ptr+ipLen {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN inclusive multicast route has invalid IP length %d", ipLen*8)}
+	}
+	i.IP = evpnDecodeIP(pkt[ptr:], ipLen)
+	return nil
+}
+
+func (i *EVPNInclusiveMulticast) Len() uint8 {
+	return uint8(RouteDistinguisherLen + 4 + 1 + evpnIPLen(i.IP))
+}
+
+func (i *EVPNInclusiveMulticast) GetRD() *RouteDistinguisher {
+	return &i.RD
+}
+
+func (i *EVPNInclusiveMulticast) GetPrefix() net.IP {
+	return i.IP
+}
+
+func (i *EVPNInclusiveMulticast) GetLength() uint8 {
+	return uint8(evpnIPLen(i.IP) * 8)
+}
+
+func (i *EVPNInclusiveMulticast) String() string {
+	return fmt.Sprintf("[%s]:[%d]:[%d]:[%s]", i.RD.String(), i.EthTag, i.GetLength(), evpnIPStr(i.IP))
+}
+
+type EVPNIPPrefix struct {
+	RD        RouteDistinguisher
+	ESI       EVPNESI
+	EthTag    uint32
+	Length    uint8
+	Prefix    net.IP
+	GatewayIP net.IP
+	Label     uint32
+}
+
+func (p *EVPNIPPrefix) Clone() EVPNRoute {
+	x := *p
+	x.Prefix = make(net.IP, len(p.Prefix))
+	copy(x.Prefix, p.Prefix)
+	x.GatewayIP = make(net.IP, len(p.GatewayIP))
+	copy(x.GatewayIP, p.GatewayIP)
+	return &x
+}
+
+func (p *EVPNIPPrefix) ipLen() int {
+	if p.Prefix.To4() != nil {
+		return net.IPv4len
+	}
+	return net.IPv6len
+}
+
+func (p *EVPNIPPrefix) Encode() ([]byte, error) {
+	ipLen := p.ipLen()
+	if int(p.Length) > ipLen*8 {
+		return nil, BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN IP prefix length %d is more than %d", p.Length, ipLen*8)}
+	}
+
+	pkt := make([]byte, 0, p.Len())
+	pkt = append(pkt, p.RD.Encode()...)
+	pkt = append(pkt, p.ESI[:]...)
+	pkt = append(pkt, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(pkt[len(pkt)-4:], p.EthTag)
+	pkt = append(pkt, p.Length)
+	pkt = append(pkt, evpnIPBytes(p.Prefix.Mask(net.CIDRMask(int(p.Length), ipLen*8)))...)
+	gwIP := p.GatewayIP
+	if gwIP == nil {
+		gwIP = net.IPv4zero
+		if ipLen == net.IPv6len {
+			gwIP = net.IPv6zero
+		}
+	}
+	if ipLen == net.IPv4len {
+		gwIP = gwIP.To4()
+	} else {
+		gwIP = gwIP.To16()
+	}
+	if gwIP == nil {
+		return nil, BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN IP prefix %s and gateway %s are not of the same family", p.Prefix, p.GatewayIP)}
+	}
+	pkt = append(pkt, gwIP...)
+	pkt = append(pkt, 0, 0, 0)
+	encodeEVPNLabel(pkt[len(pkt)-EVPNLabelLen:], p.Label)
+	return pkt, nil
+}
+
+func (p *EVPNIPPrefix) Decode(pkt []byte) error {
+	var ipLen int
+	switch len(pkt) {
+	case RouteDistinguisherLen + EVPNESILen + 4 + 1 + 2*net.IPv4len + EVPNLabelLen:
+		ipLen = net.IPv4len
+	case RouteDistinguisherLen + EVPNESILen + 4 + 1 + 2*net.IPv6len + EVPNLabelLen:
+		ipLen = net.IPv6len
+	default:
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN IP prefix route has invalid length %d", len(pkt))}
+	}
+
+	if err := p.RD.Decode(pkt); err != nil {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil, err.Error()}
+	}
+	ptr := RouteDistinguisherLen
+	copy(p.ESI[:], pkt[ptr:ptr+EVPNESILen])
+	ptr += EVPNESILen
+	p.EthTag = binary.BigEndian.Uint32(pkt[ptr : ptr+4])
+	ptr += 4
+	p.Length = pkt[ptr]
+	ptr++
+	if int(p.Length) > ipLen*8 {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN IP prefix length %d is more than %d", p.Length, ipLen*8)}
+	}
+	p.Prefix = evpnDecodeIP(pkt[ptr:], ipLen)
+	ptr += ipLen
+	p.GatewayIP = evpnDecodeIP(pkt[ptr:], ipLen)
+	ptr += ipLen
+	p.Label = decodeEVPNLabel(pkt[ptr:])
+	return nil
+}
+
+func (p *EVPNIPPrefix) Len() uint8 {
+	return uint8(RouteDistinguisherLen + EVPNESILen + 4 + 1 + 2*p.ipLen() + EVPNLabelLen)
+}
+
+func (p *EVPNIPPrefix) GetRD() *RouteDistinguisher {
+	return &p.RD
+}
+
+func (p *EVPNIPPrefix) GetPrefix() net.IP {
+	return p.Prefix
+}
+
+func (p *EVPNIPPrefix) GetLength() uint8 {
+	return p.Length
+}
+
+func (p *EVPNIPPrefix) String() string {
+	return fmt.Sprintf("[%s]:[%d]:[%d]:[%s]", p.RD.String(), p.EthTag, p.Length, evpnIPStr(p.Prefix))
+}
+
+type EVPNUnknownRoute struct {
+	RD    RouteDistinguisher
+	Value []byte
+}
+
+func (u *EVPNUnknownRoute) Clone() EVPNRoute {
+	x := *u
+	x.Value = make([]byte, len(u.Value))
+	copy(x.Value, u.Value)
+	return &x
+}
+
+func (u *EVPNUnknownRoute) Encode() ([]byte, error) {
+	pkt := make([]byte, len(u.Value))
+	copy(pkt, u.Value)
+	return pkt, nil
+}
+
+func (u *EVPNUnknownRoute) Decode(pkt []byte) error {
+	if len(pkt) >= RouteDistinguisherLen {
+		u.RD.Decode(pkt)
+	}
+	u.Value = make([]byte, len(pkt))
+	copy(u.Value, pkt)
+	return nil
+}
+
+func (u *EVPNUnknownRoute) Len() uint8 {
+	return uint8(len(u.Value))
+}
+
+func (u *EVPNUnknownRoute) GetRD() *RouteDistinguisher {
+	return &u.RD
+}
+
+func (u *EVPNUnknownRoute) GetPrefix() net.IP {
+	return nil
+}
+
+func (u *EVPNUnknownRoute) GetLength() uint8 {
+	return 0
+}
+
+func (u *EVPNUnknownRoute) String() string {
+	return "[" + hex.EncodeToString(u.Value) + "]"
+}
+
+type EVPNNLRI struct {
+	RouteType uint8
+	Route     EVPNRoute
+}
+
+func (n *EVPNNLRI) Clone() NLRI {
+	x := *n
+	x.Route = n.Route.Clone()
+	return &x
+}
+
+func (n *EVPNNLRI) Encode(afi AFI) ([]byte, error) {
+	bytes, err := n.Route.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	pkt := make([]byte, 0, n.Len())
+	pkt = append(pkt, n.RouteType, uint8(len(bytes)))
+	pkt = append(pkt, bytes...)
+	return pkt, nil
+}
+
+func (n *EVPNNLRI) Decode(pkt []byte, afi AFI) error {
+	if len(pkt) < 2 {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			"EVPN NLRI does not contain route type and length"}
+	}
+
+	n.RouteType = pkt[0]
+	length := int(pkt[1])
+	if len(pkt) < 2+length {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("EVPN NLRI length %d is more than the available data", length)}
+	}
+
+	switch n.RouteType {
+	case EVPNRouteTypeMacIPAdvertisement:
+		n.Route = &EVPNMacIPAdvertisement{}
+	case EVPNRouteTypeInclusiveMulticast:
+		n.Route = &EVPNInclusiveMulticast{}
+	case EVPNRouteTypeIPPrefix:
+		n.Route = &EVPNIPPrefix{}
+	default:
+		n.Route = &EVPNUnknownRoute{}
+	}
+	return n.Route.Decode(pkt[2 : 2+length])
+}
+
+func (n *EVPNNLRI) Len() uint32 {
+	return uint32(n.Route.Len()) + 2
+}
+
+func (n *EVPNNLRI) GetIPPrefix() *IPPrefix {
+	return NewIPPrefix(n.Route.GetPrefix(), n.Route.GetLength())
+}
+
+func (n *EVPNNLRI) GetPrefix() net.IP {
+	return n.Route.GetPrefix()
+}
+
+func (n *EVPNNLRI) GetLength() uint8 {
+	return n.Route.GetLength()
+}
+
+func (n *EVPNNLRI) GetPathId() uint32 {
+	return 0
+}
+
+func (n *EVPNNLRI) GetCIDR() string {
+	return fmt.Sprintf("[%d]:%s", n.RouteType, n.Route.String())
+}
+
+func (n *EVPNNLRI) String() string {
+	return "{" + n.GetCIDR() + "}"
+}
+
+func NewEVPNMacIPNLRI(rd *RouteDistinguisher, ethTag uint32, mac net.HardwareAddr, ip net.IP,
+	vni uint32) *EVPNNLRI {
+	return &EVPNNLRI{
+		RouteType: EVPNRouteTypeMacIPAdvertisement,
+		Route: &EVPNMacIPAdvertisement{
+			RD:     *rd,
+			EthTag: ethTag,
+			MAC:    mac,
+			IP:     ip,
+			Labels: []uint32{vni},
+		},
+	}
+}
+
+func NewEVPNInclusiveMulticastNLRI(rd *RouteDistinguisher, ethTag uint32, ip net.IP) *EVPNNLRI {
+	return &EVPNNLRI{
+		RouteType: EVPNRouteTypeInclusiveMulticast,
+		Route: &EVPNInclusiveMulticast{
+			RD:     *rd,
+			EthTag: ethTag,
+			IP:     ip,
+		},
+	}
+}
+
+func NewEVPNIPPrefixNLRI(rd *RouteDistinguisher, ethTag uint32, prefix net.IP, length uint8, gatewayIP net.IP,
+	vni uint32) *EVPNNLRI {
+	return &EVPNNLRI{
+		RouteType: EVPNRouteTypeIPPrefix,
+		Route: &EVPNIPPrefix{
+			RD:        *rd,
+			EthTag:    ethTag,
+			Length:    length,
+			Prefix:    prefix,
+			GatewayIP: gatewayIP,
+			Label:     vni,
+		},
+	}
+}
+
+type BGPPathAttrPMSITunnel struct {
+	BGPPathAttrBase
+	TunnelFlags uint8
+	TunnelType  uint8
+	Label       uint32
+	TunnelId    net.IP
+}
+
+func (p *BGPPathAttrPMSITunnel) Clone() BGPPathAttr {
+	x := *p
+	x.BGPPathAttrBase = p.BGPPathAttrBase.Clone()
+	x.TunnelId = make(net.IP, len(p.TunnelId))
+	copy(x.TunnelId, p.TunnelId)
+	return &x
+}
+
+func (p *BGPPathAttrPMSITunnel) Encode() ([]byte, error) {
+	pkt, err := p.BGPPathAttrBase.Encode()
+	if err != nil {
+		return pkt, err
+	}
+
+	idx := int(p.BGPPathAttrLen)
+	pkt[idx] = p.TunnelFlags
+	pkt[idx+1] = p.TunnelType
+	encodeEVPNLabel(pkt[idx+2:], p.Label)
+	copy(pkt[idx+5:], evpnIPBytes(p.TunnelId))
+	return pkt, nil
+}
+
+func (p *BGPPathAttrPMSITunnel) Decode(pkt []byte, data interface{}) error {
+	err := p.BGPPathAttrBase.Decode(pkt, data)
+	if err != nil {
+		return err
+	}
+
+	if p.Length < 5 {
+		return BGPMessageError{BGPUpdateMsgError, BGPOptionalAttrError, pkt[:p.TotalLen()],
+			fmt.Sprintf("PMSI_TUNNEL length %d is less than 5", p.Length)}
+	}
+
+	idx := int(p.BGPPathAttrLen)
+	p.TunnelFlags = pkt[idx]
+	p.TunnelType = pkt[idx+1]
+	p.Label = decodeEVPNLabel(pkt[idx+2:])
+	idLen := int(p.Length) - 5
+	p.TunnelId = nil
+	if idLen == net.IPv4len || idLen == net.IPv6len {
+		p.TunnelId = evpnDecodeIP(pkt[idx+5:], idLen)
+	}
+	return nil
+}
+
+func (p *BGPPathAttrPMSITunnel) New() BGPPathAttr {
+	return &BGPPathAttrPMSITunnel{}
+}
+
+func (p *BGPPathAttrPMSITunnel) String() string {
+	return fmt.Sprintf("{PMSI_TUNNEL type:%d label:%d id:%s}", p.TunnelType, p.Label, evpnIPStr(p.TunnelId))
+}
+
+func NewBGPPathAttrPMSITunnel(tunnelType uint8, label uint32, tunnelId net.IP) *BGPPathAttrPMSITunnel {
+	pmsi := &BGPPathAttrPMSITunnel{
+		BGPPathAttrBase: BGPPathAttrBase{
+			Flags:          BGPPathAttrFlagOptional | BGPPathAttrFlagTransitive,
+			Code:           BGPPathAttrTypePMSITunnel,
+			BGPPathAttrLen: 3,
+		},
+		TunnelType: tunnelType,
+		Label:      label,
+		TunnelId:   tunnelId,
+	}
+	pmsi.setValueLength(uint16(5 + evpnIPLen(tunnelId)))
+	return pmsi
+}
+
+func GetPMSITunnel(pathAttrs []BGPPathAttr) *BGPPathAttrPMSITunnel {
+	if attr := getTypeFromPathAttrs(pathAttrs, BGPPathAttrTypePMSITunnel); attr != nil {
+		return attr.(*BGPPathAttrPMSITunnel)
+	}
+	return nil
+}
+
+// NewEVPNRouteDistinguisher returns the RD auto derived for the VNI. It is the router id and the VNI (RFC 7432) when
+// the VNI fits in the 2 byte value of a type 1 RD, otherwise a type 0 RD with the 2 byte AS (AS_TRANS for a 4 byte
+// AS) and the full VNI.
+func NewEVPNRouteDistinguisher(routerId net.IP, as, vni uint32) *RouteDistinguisher {
+	if vni <= 0xFFFF && routerId.To4() != nil {
+		return NewRouteDistinguisher(RouteDistinguisherTypeIPv4, binary.BigEndian.Uint32(routerId.To4()), vni)
+	}
+	if as > 0xFFFF {
+		as = uint32(BGPASTrans)
+	}
+	return NewRouteDistinguisher(RouteDistinguisherTypeAS2, as, vni)
+}
+
+// NewEVPNRouteTargetCommunity returns the RT auto derived for the VNI (RFC 8365 section 5.1.2.1). It is a 2 byte
+// AS route target with the full 24 bit VNI, the lower 2 bytes of a 4 byte AS are used.
+func NewEVPNRouteTargetCommunity(as, vni uint32) uint64 {
+	return uint64(BGPExtCommunityTypeAS2)<<56 | uint64(BGPExtCommunitySubTypeRT)<<48 | uint64(as&0xFFFF)<<32 |
+		uint64(vni&0xFFFFFF)
+}
+
+func NewEncapsulationCommunity(tunnelType uint16) uint64 {
+	return uint64(BGPExtCommunityTypeOpaque)<<56 | uint64(BGPExtCommunitySubTypeEncapsulation)<<48 |
+		uint64(tunnelType)
+}
+
+func NewRouterMacCommunity(mac net.HardwareAddr) uint64 {
+	val := uint64(0)
+	for _, b := range mac {
+		val = val<<8 | uint64(b)
+	}
+	return uint64(BGPExtCommunityTypeEVPN)<<56 | uint64(BGPExtCommunitySubTypeRouterMac)<<48 | val&0xFFFFFFFFFFFF
+}
+
+func GetEncapsulation(pathAttrs []BGPPathAttr) (uint16, bool) {
+	for _, community := range GetExtCommunities(pathAttrs) {
+		if uint8(community>>56) == BGPExtCommunityTypeOpaque &&
+			uint8(community>>48) == BGPExtCommunitySubTypeEncapsulation {
+			return uint16(community), true
+		}
+	}
+	return 0, false
+}
+
+func GetRouterMac(pathAttrs []BGPPathAttr) net.HardwareAddr {
+	for _, community := range GetExtCommunities(pathAttrs) {
+		if uint8(community>>56) == BGPExtCommunityTypeEVPN &&
+			uint8(community>>48) == BGPExtCommunitySubTypeRouterMac {
+			mac := make(net.HardwareAddr, EVPNMacLen)
+			for i := 0; i < EVPNMacLen; i++ {
+				mac[i] = uint8(community >> uint(8*(EVPNMacLen-1-i)))
+			}
+			return mac
+		}
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// evpn_test.go
+package packet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+)
+
+func TestRouteDistinguisherParse(t *testing.T) {
+	rdStrs := map[string]uint16{
+		"65000:100":    RouteDistinguisherTypeAS2,
+		"10.0.0.1:5":   RouteDistinguisherTypeIPv4,
+		"4200000000:7": RouteDistinguisherTypeAS4,
+	}
+
+	for str, rdType := range rdStrs {
+		rd, err := ParseRouteDistinguisher(str)
+		if err != nil {
+			t.Fatal("Parse route distinguisher", str, "failed with error:", err)
+		}
+		if rd.Type != rdType || rd.String() != str {
+			t.Fatal("Parse route distinguisher", str, "expected type", rdType, "got", rd.Type, rd.String())
+		}
+
+		newRD := &RouteDistinguisher{}
+		if err = newRD.Decode(rd.Encode()); err != nil || *newRD != *rd {
+			t.Fatal("Route distinguisher", str, "encode/decode mismatch, got:", newRD, "error:", err)
+		}
+	}
+
+	for _, str := range []string{"65000", "abc:1", "10.0.0.1:70000", "2001::1:1", "4200000000:70000"} {
+		if _, err := ParseRouteDistinguisher(str); err == nil {
+			t.Fatal("Parse route distinguisher", str, "expected failure, got NO errors")
+		}
+	}
+}
+
+func TestEVPNNLRIDecode(t *testing.T) {
+	pkts := map[string]string{
+		// MAC/IP advertisement, RD 65000:100, MAC 00:11:22:33:44:55, IP 10.0.0.1, VNI 100
+		"02250000fde800000064000000000000000000000000000030001122334455200a000001000064": "[2]:[65000:100]:[0]:[48]:[00:11:22:33:44:55]:[32]:[10.0.0.1]",
+		// MAC only advertisement
+		"02210000fde80000006400000000000000000000000000003000112233445500000064": "[2]:[65000:100]:[0]:[48]:[00:11:22:33:44:55]:[0]:[]",
+		// Inclusive multicast ethernet tag, originating router 10.0.0.1
+		"03110000fde80000006400000000200a000001": "[3]:[65000:100]:[0]:[32]:[10.0.0.1]",
+		// IP prefix 10.1.1.0/24, RD 10.0.0.1:5, VNI 5000
+		"052200010a00000100050000000000000000000000000000180a01010000000000001388": "[5]:[10.0.0.1:5]:[0]:[24]:[10.1.1.0]",
+	}
+
+	for strPkt, expected := range pkts {
+		pkt, _ := hex.DecodeString(strPkt)
+		nlri := &EVPNNLRI{}
+		err := nlri.Decode(pkt, AfiL2VPN)
+		if err != nil {
+			t.Fatal("EVPN NLRI decode for", strPkt, "failed with error:", err)
+		}
+
+		if nlri.Len() != uint32(len(pkt)) {
+			t.Fatal("EVPN NLRI length mismatch for", strPkt, "expected:", len(pkt), "got:", nlri.Len())
+		}
+
+		if nlri.GetCIDR() != expected {
+			t.Fatal("EVPN NLRI mismatch, expected:", expected, "got:", nlri.GetCIDR())
+		}
+
+		encoded, err := nlri.Encode(AfiL2VPN)
+		if err != nil {
+			t.Fatal("EVPN NLRI encode for", expected, "failed with error:", err)
+		}
+		if !bytes.Equal(encoded, pkt) {
+			t.Fatalf("EVPN NLRI encode mismatch, expected: %x got: %x", pkt, encoded)
+		}
+	}
+}
+
+func TestEVPNNLRIBadPackets(t *testing.T) {
+	pkts := []string{
+		"02",
+		"0225",
+		"02250000fde800000064",
+		// bad MAC length
+		"02250000fde800000064000000000000000000000000000028001122334455200a000001000064",
+		// bad IP length
+		"02250000fde800000064000000000000000000000000000030001122334455180a000001000064",
+		// missing originating router IP
+		"030d0000fde80000006400000000",
+		// IP prefix route with a wrong length
+		"052100010a00000100050000000000000000000000000000180a010100000000000013",
+	}
+
+	for _, strPkt := range pkts {
+		pkt, _ := hex.DecodeString(strPkt)
+		nlri := &EVPNNLRI{}
+		err := nlri.Decode(pkt, AfiL2VPN)
+		if err == nil {
+			t.Fatal("EVPN NLRI decode called for", strPkt, "expected failure, got NO errors")
+		} else {
+			t.Log("EVPN NLRI decode called for", strPkt, "expected failure, got error:", err)
+		}
+	}
+}
+
+func TestEVPNNLRIEncodeDecode(t *testing.T) {
+	rd, _ := ParseRouteDistinguisher("10.0.0.1:100")
+	mac, _ := net.ParseMAC("00:aa:bb:cc:dd:ee")
+	nlris := []*EVPNNLRI{
+		NewEVPNMacIPNLRI(rd, 0, mac, nil, 100),
+		NewEVPNMacIPNLRI(rd, 10, mac, net.ParseIP("2001:db8::1"), 100),
+		NewEVPNInclusiveMulticastNLRI(rd, 0, net.ParseIP("10.0.0.1")),
+		NewEVPNIPPrefixNLRI(rd, 0, net.ParseIP("2001:db8:1::"), 48, nil, 5000),
+	}
+
+	for _, nlri := range nlris {
+		pkt, err := nlri.Encode(AfiL2VPN)
+		if err != nil {
+			t.Fatal("EVPN NLRI encode for", nlri, "failed with error:", err)
+		}
+		if uint32(len(pkt)) != nlri.Len() {
+			t.Fatal("EVPN NLRI length mismatch for", nlri, "expected:", nlri.Len(), "got:", len(pkt))
+		}
+
+		newNLRI := &EVPNNLRI{}
+		if err = newNLRI.Decode(pkt, AfiL2VPN); err != nil {
+			t.Fatal("EVPN NLRI decode for", nlri, "failed with error:", err)
+		}
+		if newNLRI.GetCIDR() != nlri.GetCIDR() {
+			t.Fatal("EVPN NLRI mismatch, expected:", nlri.GetCIDR(), "got:", newNLRI.GetCIDR())
+		}
+		if newNLRI.Clone().GetCIDR() != nlri.GetCIDR() {
+			t.Fatal("EVPN NLRI clone mismatch, expected:", nlri.GetCIDR(), "got:", newNLRI.Clone().GetCIDR())
+		}
+	}
+
+	macIP := nlris[1].Route.(*EVPNMacIPAdvertisement)
+	if macIP.GetVNI() != 100 {
+		t.Fatal("EVPN MAC/IP route VNI mismatch, expected 100 got:", macIP.GetVNI())
+	}
+}
+
+func TestMPReachNLRIEVPNDecode(t *testing.T) {
+	hexPkt, _ := hex.DecodeString("800E1C001946040a0000010003110000fde80000006400000000200a000001")
+	mpReach := NewBGPPathAttrMPReachNLRI()
+	peerAttrs := BGPPeerAttrs{
+		ASSize:           4,
+		AddPathsRxActual: false,
+	}
+	err := mpReach.Decode(hexPkt, peerAttrs)
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI decode for EVPN failed with error:", err)
+	}
+
+	if !mpReach.NextHop.GetNextHop().Equal(net.ParseIP("10.0.0.1")) || len(mpReach.NLRI) != 1 {
+		t.Fatal("BGP MPReachNLRI decode for EVPN, expected next hop 10.0.0.1 and 1 NLRI, got:", mpReach.NextHop,
+			mpReach.NLRI)
+	}
+	if _, ok := mpReach.NLRI[0].(*EVPNNLRI); !ok {
+		t.Fatal("BGP MPReachNLRI decode for EVPN, expected EVPN NLRI, got:", mpReach.NLRI[0])
+	}
+
+	pkt, err := mpReach.Encode()
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI encode for EVPN failed with error:", err)
+	}
+	if !bytes.Equal(pkt, hexPkt) {
+		t.Fatalf("BGP MPReachNLRI encode for EVPN mismatch, expected: %x got: %x", hexPkt, pkt)
+	}
+
+	mpReach = ConstructIPv6MPReachNLRI(GetProtocolFamily(AfiL2VPN, SafiEVPN), net.ParseIP("10.0.0.1"), nil,
+		mpReach.NLRI)
+	pkt, err = mpReach.Encode()
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI encode for constructed EVPN failed with error:", err)
+	}
+	if !bytes.Equal(pkt[4:], hexPkt[3:]) {
+		t.Fatalf("BGP MPReachNLRI encode for constructed EVPN mismatch, expected: %x got: %x", hexPkt, pkt)
+	}
+}
+
+func TestPMSITunnelEncodeDecode(t *testing.T) {
+	pmsi := NewBGPPathAttrPMSITunnel(PMSITunnelTypeIngressReplication, 100, net.ParseIP("10.0.0.1"))
+	pkt, err := pmsi.Encode()
+	if err != nil {
+		t.Fatal("PMSI tunnel encode failed with error:", err)
+	}
+
+	expected, _ := hex.DecodeString("c0160900060000640a000001")
+	if !bytes.Equal(pkt, expected) {
+		t.Fatalf("PMSI tunnel encode mismatch, expected: %x got: %x", expected, pkt)
+	}
+
+	newPMSI := BGPGetPathAttr(pkt)
+	if err = newPMSI.Decode(pkt, BGPPeerAttrs{ASSize: 4}); err != nil {
+		t.Fatal("PMSI tunnel decode failed with error:", err)
+	}
+	pathAttrs := []BGPPathAttr{newPMSI}
+	decoded := GetPMSITunnel(pathAttrs)
+	if decoded == nil || decoded.TunnelType != PMSITunnelTypeIngressReplication || decoded.Label != 100 ||
+		!decoded.TunnelId.Equal(net.ParseIP("10.0.0.1")) {
+		t.Fatal("PMSI tunnel decode mismatch, got:", newPMSI)
+	}
+}
+
+func TestEVPNExtCommunities(t *testing.T) {
+	mac, _ := net.ParseMAC("00:aa:bb:cc:dd:ee")
+	pathAttrs := SetExtCommunities(nil, []uint64{NewEncapsulationCommunity(TunnelTypeVXLAN),
+		NewRouterMacCommunity(mac)})
+
+	if encap, ok := GetEncapsulation(pathAttrs); !ok || encap != TunnelTypeVXLAN {
+		t.Fatal("Encapsulation community mismatch, expected", TunnelTypeVXLAN, "got", encap, ok)
+	}
+
+	if routerMac := GetRouterMac(pathAttrs); routerMac.String() != mac.String() {
+		t.Fatal("Router MAC community mismatch, expected", mac, "got", routerMac)
+	}
+}
+
+func TestRouteTargetCommunity(t *testing.T) {
+	if rt := ExtCommunityToStr(NewRouteTargetCommunity(65000, 10100)); rt != "rt:65000:10100" {
+		t.Fatal("Route target mismatch, expected rt:65000:10100 got", rt)
+	}
+
+	if rt := ExtCommunityToStr(NewRouteTargetCommunity(4200000000, 100)); rt != "rt:4200000000:100" {
+		t.Fatal("Route target mismatch, expected rt:4200000000:100 got", rt)
+	}
+}
+
+func TestEVPNRouteTargetAndDistinguisher(t *testing.T) {
+	vni := uint32(0xABCDEF)
+	if rt := ExtCommunityToStr(NewEVPNRouteTargetCommunity(65000, vni)); rt != "rt:65000:11259375" {
+		t.Error("Route target mismatch, expected rt:65000:11259375 got", rt)
+	}
+	if rt := ExtCommunityToStr(NewEVPNRouteTargetCommunity(4200000000, vni)); rt != "rt:59904:11259375" {
+		t.Error("Route target mismatch, expected rt:59904:11259375 got", rt)
+	}
+
+	routerId := net.ParseIP("10.1.1.1")
+	if rd := NewEVPNRouteDistinguisher(routerId, 65000, 100).String(); rd != "10.1.1.1:100" {
+		t.Error("Route distinguisher mismatch, expected 10.1.1.1:100 got", rd)
+	}
+
+	// The VNI doesn't fit in the type 1 RD, it is sent in full in a type 0 RD
+	for _, as := range []uint32{65000, 4200000000} {
+		rd := NewEVPNRouteDistinguisher(routerId, as, vni)
+		decoded := &RouteDistinguisher{}
+		if err := decoded.Decode(rd.Encode()); err != nil {
+			t.Fatal("Failed to decode route distinguisher", rd, "with error", err)
+		}
+		if decoded.Type != RouteDistinguisherTypeAS2 || decoded.Assigned != vni {
+			t.Error("Route distinguisher", decoded, "does not carry the VNI", vni)
+		}
+	}
+}
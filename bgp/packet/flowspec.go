@@ -0,0 +1,697 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// flowspec.go
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	FlowSpecTypeDstPrefix uint8 = iota + 1
+	FlowSpecTypeSrcPrefix
+	FlowSpecTypeIPProto
+	FlowSpecTypePort
+	FlowSpecTypeDstPort
+	FlowSpecTypeSrcPort
+	FlowSpecTypeICMPType
+	FlowSpecTypeICMPCode
+	FlowSpecTypeTCPFlags
+	FlowSpecTypePktLen
+	FlowSpecTypeDSCP
+	FlowSpecTypeFragment
+	FlowSpecTypeFlowLabel
+)
+
+var FlowSpecTypeToStrMap = map[uint8]string{
+	FlowSpecTypeDstPrefix: "dst",
+	FlowSpecTypeSrcPrefix: "src",
+	FlowSpecTypeIPProto:   "proto",
+	FlowSpecTypePort:      "port",
+	FlowSpecTypeDstPort:   "dport",
+	FlowSpecTypeSrcPort:   "sport",
+	FlowSpecTypeICMPType:  "icmp-type",
+	FlowSpecTypeICMPCode:  "icmp-code",
+	FlowSpecTypeTCPFlags:  "tcp-flags",
+	FlowSpecTypePktLen:    "pkt-len",
+	FlowSpecTypeDSCP:      "dscp",
+	FlowSpecTypeFragment:  "fragment",
+	FlowSpecTypeFlowLabel: "flow-label",
+}
+
+const (
+	FlowSpecOpEnd   uint8 = 0x80
+	FlowSpecOpAnd   uint8 = 0x40
+	FlowSpecOpLen   uint8 = 0x30
+	FlowSpecOpLt    uint8 = 0x04
+	FlowSpecOpGt    uint8 = 0x02
+	FlowSpecOpEq    uint8 = 0x01
+	FlowSpecOpNot   uint8 = 0x02
+	FlowSpecOpMatch uint8 = 0x01
+)
+
+const (
+	FlowSpecFragmentDontFrag  uint64 = 0x01
+	FlowSpecFragmentIsFrag    uint64 = 0x02
+	FlowSpecFragmentFirstFrag uint64 = 0x04
+	FlowSpecFragmentLastFrag  uint64 = 0x08
+)
+
+const FlowSpecNLRIMaxLen = 0xFFF
+
+const (
+	BGPExtCommunityTypeFlowSpec     uint8 = 0x80
+	BGPExtCommunityTypeFlowSpecIPv4 uint8 = 0x81
+	BGPExtCommunityTypeFlowSpecAS4  uint8 = 0x82
+
+	BGPExtCommunitySubTypeTrafficRateBytes   uint8 = 0x06
+	BGPExtCommunitySubTypeTrafficAction      uint8 = 0x07
+	BGPExtCommunitySubTypeRedirect           uint8 = 0x08
+	BGPExtCommunitySubTypeTrafficMarking     uint8 = 0x09
+	BGPExtCommunitySubTypeTrafficRatePackets uint8 = 0x0C
+)
+
+const (
+	FlowSpecTrafficActionTerminal uint8 = 0x01
+	FlowSpecTrafficActionSample   uint8 = 0x02
+)
+
+type FlowSpecComponent interface {
+	Clone() FlowSpecComponent
+	Encode(afi AFI) ([]byte, error)
+	Decode(pkt []byte, afi AFI) error
+	Len() uint32
+	GetType() uint8
+	String() string
+}
+
+type FlowSpecPrefix struct {
+	Type   uint8
+	Length uint8
+	Offset uint8
+	Prefix net.IP
+}
+
+func (f *FlowSpecPrefix) Clone() FlowSpecComponent {
+	x := *f
+	x.Prefix = make(net.IP, len(f.Prefix))
+	copy(x.Prefix, f.Prefix)
+	return &x
+}
+
+func (f *FlowSpecPrefix) patternLen() uint8 {
+	return (f.Length - f.Offset + 7) / 8
+}
+
+func (f *FlowSpecPrefix) Encode(afi AFI) ([]byte, error) {
+	if f.Offset > f.Length {
+		return nil, errors.New(fmt.Sprintf("FlowSpec prefix offset %d is greater than length %d", f.Offset,
+			f.Length))
+	}
+
+	pkt := make([]byte, 0, f.Len())
+	pkt = append(pkt, f.Type, f.Length)
+	if afi == AfiIP6 {
+		pkt = append(pkt, f.Offset)
+	}
+
+	prefix := f.Prefix.To16()
+	if afi == AfiIP {
+		prefix = f.Prefix.To4()
+	}
+	if prefix == nil {
+		return nil, errors.New(fmt.Sprintf("FlowSpec prefix %s does not match AFI %d", f.Prefix, afi))
+	}
+
+	pattern := make([]byte, f.patternLen())
+	for i := f.Offset; i < f.Length; i++ {
+		bit := (prefix[i/8] >> (7 - i%8)) & 1
+		j := i - f.Offset
+		pattern[j/8] |= bit << (7 - j%8)
+	}
+	return append(pkt, pattern...), nil
+}
+
+func (f *FlowSpecPrefix) Decode(pkt []byte, afi AFI) error {
+	hdrLen := 2
+	if afi == AfiIP6 {
+		hdrLen = 3
+	}
+	if len(pkt) < hdrLen {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			"FlowSpec prefix component does not contain prefix length"}
+	}
+
+	f.Type = pkt[0]
+	f.Length = pkt[1]
+	f.Offset = 0
+	ipLen := net.IPv4len
+	if afi == AfiIP6 {
+		f.Offset = pkt[2]
+		ipLen = net.IPv6len
+	}
+	if int(f.Length) > ipLen*8 || f.Offset > f.Length {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("FlowSpec prefix length %d offset %d is invalid", f.Length, f.Offset)}
+	}
+
+	patternLen := int(f.patternLen())
+	if len(pkt) < hdrLen+patternLen {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			"FlowSpec prefix component length invalid"}
+	}
+
+	pattern := pkt[hdrLen : hdrLen+patternLen]
+	f.Prefix = make(net.IP, ipLen)
+	for i := f.Offset; i < f.Length; i++ {
+		j := i - f.Offset
+		bit := (pattern[j/8] >> (7 - j%8)) & 1
+		f.Prefix[i/8] |= bit << (7 - i%8)
+	}
+	return nil
+}
+
+func (f *FlowSpecPrefix) Len() uint32 {
+	hdrLen := uint32(2)
+	if len(f.Prefix) == net.IPv6len {
+		hdrLen = 3
+	}
+	return hdrLen + uint32(f.patternLen())
+}
+
+func (f *FlowSpecPrefix) GetType() uint8 {
+	return f.Type
+}
+
+func (f *FlowSpecPrefix) GetIPPrefix() *IPPrefix {
+	return NewIPPrefix(f.Prefix, f.Length)
+}
+
+func (f *FlowSpecPrefix) String() string {
+	str := FlowSpecTypeToStrMap[f.Type] + ":" + f.Prefix.String() + "/" + strconv.Itoa(int(f.Length))
+	if f.Offset > 0 {
+		str += "-" + strconv.Itoa(int(f.Offset))
+	}
+	return str
+}
+
+func NewFlowSpecPrefix(componentType uint8, prefix net.IP, length uint8) *FlowSpecPrefix {
+	if ip := prefix.To4(); ip != nil {
+		prefix = ip
+	}
+	return &FlowSpecPrefix{
+		Type:   componentType,
+		Length: length,
+		Prefix: prefix.Mask(net.CIDRMask(int(length), len(prefix)*8)),
+	}
+}
+
+type FlowSpecOpValue struct {
+	Op    uint8
+	Value uint64
+}
+
+func (o FlowSpecOpValue) valueLen() int {
+	if o.Value > math.MaxUint32 {
+		return 8
+	} else if o.Value > math.MaxUint16 {
+		return 4
+	} else if o.Value > math.MaxUint8 {
+		return 2
+	}
+	return 1
+}
+
+func (o FlowSpecOpValue) String(bitmask bool) string {
+	op := ""
+	if bitmask {
+		if o.Op&FlowSpecOpNot != 0 {
+			op = "!"
+		}
+		if o.Op&FlowSpecOpMatch != 0 {
+			op += "="
+		}
+		return op + fmt.Sprintf("0x%x", o.Value)
+	}
+
+	switch o.Op & (FlowSpecOpLt | FlowSpecOpGt | FlowSpecOpEq) {
+	case 0:
+		return "false"
+	case FlowSpecOpLt | FlowSpecOpGt | FlowSpecOpEq:
+		return "true"
+	case FlowSpecOpLt | FlowSpecOpGt:
+		op = "!="
+	case FlowSpecOpLt | FlowSpecOpEq:
+		op = "<="
+	case FlowSpecOpGt | FlowSpecOpEq:
+		op = ">="
+	case FlowSpecOpLt:
+		op = "<"
+	case FlowSpecOpGt:
+		op = ">"
+	case FlowSpecOpEq:
+		op = "="
+	}
+	return op + strconv.FormatUint(o.Value, 10)
+}
+
+type FlowSpecOpComponent struct {
+	Type   uint8
+	Values []FlowSpecOpValue
+	// Length of the values decoded from the wire. A value can be sent in more bytes than it needs, the component
+	// is encoded back with the same lengths.
+	valueLens []int
+}
+
+func (f *FlowSpecOpComponent) Clone() FlowSpecComponent {
+	x := *f
+	x.Values = make([]FlowSpecOpValue, len(f.Values))
+	copy(x.Values, f.Values)
+	x.valueLens = make([]int, len(f.valueLens))
+	copy(x.valueLens, f.valueLens)
+	return &x
+}
+
+func (f *FlowSpecOpComponent) valueLen(idx int) int {
+	valLen := f.Values[idx].valueLen()
+	if idx < len(f.valueLens) && f.valueLens[idx] > valLen {
+		return f.valueLens[idx]
+	}
+	return valLen
+}
+
+func (f *FlowSpecOpComponent) Encode(afi AFI) ([]byte, error) {
+	if len(f.Values) == 0 {
+		return nil, errors.New(fmt.Sprintf("FlowSpec component %d does not have any values", f.Type))
+	}
+
+	pkt := make([]byte, 0, f.Len())
+	pkt = append(pkt, f.Type)
+	for idx, val := range f.Values {
+		valLen := f.valueLen(idx)
+		op := val.Op &^ (FlowSpecOpEnd | FlowSpecOpLen)
+		if idx == len(f.Values)-1 {
+			op |= FlowSpecOpEnd
+		}
+		switch valLen {
+		case 2:
+			op |= 0x10
+		case 4:
+			op |= 0x20
+		case 8:
+			op |= 0x30
+		}
+		pkt = append(pkt, op)
+		bytes := make([]byte, 8)
+		binary.BigEndian.PutUint64(bytes, val.Value)
+		pkt = append(pkt, bytes[8-valLen:]...)
+	}
+	return pkt, nil
+}
+
+func (f *FlowSpecOpComponent) Decode(pkt []byte, afi AFI) error {
+	if len(pkt) < 1 {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			"FlowSpec component does not contain type"}
+	}
+
+	f.Type = pkt[0]
+	f.Values = make([]FlowSpecOpValue, 0)
+	f.valueLens = make([]int, 0)
+	ptr := 1
+	for {
+		if ptr >= len(pkt) {
+			return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+				fmt.Sprintf("FlowSpec component %d does not contain end of list", f.Type)}
+		}
+
+		op := pkt[ptr]
+		valLen := 1 << ((op & FlowSpecOpLen) >> 4)
+		ptr++
+		if ptr+valLen > len(pkt) {
+			return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+				fmt.Sprintf("FlowSpec component %d value length invalid", f.Type)}
+		}
+
+		bytes := make([]byte, 8)
+		copy(bytes[8-valLen:], pkt[ptr:ptr+valLen])
+		ptr += valLen
+		f.Values = append(f.Values, FlowSpecOpValue{
+			Op:    op &^ (FlowSpecOpEnd | FlowSpecOpLen),
+			Value: binary.BigEndian.Uint64(bytes),
+		})
+		f.valueLens = append(f.valueLens, valLen)
+		if op&FlowSpecOpEnd != 0 {
+			break
+		}
+	}
+	return nil
+}
+
+func (f *FlowSpecOpComponent) Len() uint32 {
+	length := uint32(1)
+	for idx := range f.Values {
+		length += uint32(1 + f.valueLen(idx))
+	}
+	return length
+}
+
+func (f *FlowSpecOpComponent) GetType() uint8 {
+	return f.Type
+}
+
+func (f *FlowSpecOpComponent) IsBitmask() bool {
+	return f.Type == FlowSpecTypeTCPFlags || f.Type == FlowSpecTypeFragment
+}
+
+func (f *FlowSpecOpComponent) ValueString() string {
+	strList := make([]string, 0, len(f.Values))
+	for idx, val := range f.Values {
+		str := val.String(f.IsBitmask())
+		if idx > 0 {
+			if val.Op&FlowSpecOpAnd != 0 {
+				str = "&" + str
+			} else {
+				str = "," + str
+			}
+		}
+		strList = append(strList, str)
+	}
+	return strings.Join(strList, "")
+}
+
+func (f *FlowSpecOpComponent) String() string {
+	name, ok := FlowSpecTypeToStrMap[f.Type]
+	if !ok {
+		name = "type-" + strconv.Itoa(int(f.Type))
+	}
+	return name + ":" + f.ValueString()
+}
+
+func NewFlowSpecOpComponent(componentType uint8, values ...FlowSpecOpValue) *FlowSpecOpComponent {
+	return &FlowSpecOpComponent{
+		Type:   componentType,
+		Values: values,
+	}
+}
+
+type FlowSpecComponents []FlowSpecComponent
+
+func (c FlowSpecComponents) Len() int {
+	return len(c)
+}
+
+func (c FlowSpecComponents) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
+
+func (c FlowSpecComponents) Less(i, j int) bool {
+	return c[i].GetType() < c[j].GetType()
+}
+
+type FlowSpecNLRI struct {
+	Components []FlowSpecComponent
+}
+
+func (n *FlowSpecNLRI) Clone() NLRI {
+	x := *n
+	x.Components = make([]FlowSpecComponent, len(n.Components))
+	for idx, component := range n.Components {
+		x.Components[idx] = component.Clone()
+	}
+	return &x
+}
+
+func (n *FlowSpecNLRI) valueLen() uint32 {
+	length := uint32(0)
+	for _, component := range n.Components {
+		length += component.Len()
+	}
+	return length
+}
+
+func (n *FlowSpecNLRI) Encode(afi AFI) ([]byte, error) {
+	length := n.valueLen()
+	if length > FlowSpecNLRIMaxLen {
+		return nil, errors.New(fmt.Sprintf("FlowSpec NLRI length %d is more than max length %d", length,
+			FlowSpecNLRIMaxLen))
+	}
+
+	pkt := make([]byte, 0, n.Len())
+	if length < 240 {
+		pkt = append(pkt, uint8(length))
+	} else {
+		pkt = append(pkt, 0xF0|uint8(length>>8), uint8(length))
+	}
+
+	for _, component := range n.Components {
+		bytes, err := component.Encode(afi)
+		if err != nil {
+			return nil, err
+		}
+		pkt = append(pkt, bytes...)
+	}
+	return pkt, nil
+}
+
+func (n *FlowSpecNLRI) Decode(pkt []byte, afi AFI) error {
+	if len(pkt) < 1 {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil, "FlowSpec NLRI does not contain length"}
+	}
+
+	ptr := uint32(1)
+	length := uint32(pkt[0])
+	if length >= 0xF0 {
+		if len(pkt) < 2 {
+			return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+				"FlowSpec NLRI does not contain length"}
+		}
+		length = uint32(binary.BigEndian.Uint16(pkt[:2]) & FlowSpecNLRIMaxLen)
+		ptr = 2
+	}
+	if uint32(len(pkt)) < ptr+length {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("FlowSpec NLRI length %d is more than the available data", length)}
+	}
+
+	end := ptr + length
+	lastType := uint8(0)
+	n.Components = make([]FlowSpecComponent, 0)
+	for ptr < end {
+		var component FlowSpecComponent
+		componentType := pkt[ptr]
+		if componentType <= lastType {
+			return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+				fmt.Sprintf("FlowSpec component type %d is out of order", componentType)}
+		}
+
+		switch componentType {
+		case FlowSpecTypeDstPrefix, FlowSpecTypeSrcPrefix:
+			component = &FlowSpecPrefix{}
+		case FlowSpecTypeIPProto, FlowSpecTypePort, FlowSpecTypeDstPort, FlowSpecTypeSrcPort,
+			FlowSpecTypeICMPType, FlowSpecTypeICMPCode, FlowSpecTypeTCPFlags, FlowSpecTypePktLen,
+			FlowSpecTypeDSCP, FlowSpecTypeFragment, FlowSpecTypeFlowLabel:
+			component = &FlowSpecOpComponent{}
+		default:
+			return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+				fmt.Sprintf("FlowSpec component type %d is not supported", componentType)}
+		}
+
+		err := component.Decode(pkt[ptr:end], afi)
+		if err != nil {
+			return err
+		}
+		n.Components = append(n.Components, component)
+		ptr += component.Len()
+		lastType = componentType
+	}
+	return nil
+}
+
+func (n *FlowSpecNLRI) Len() uint32 {
+	length := n.valueLen()
+	if length < 240 {
+		return length + 1
+	}
+	return length + 2
+}
+
+func (n *FlowSpecNLRI) GetComponent(componentType uint8) FlowSpecComponent {
+	for _, component := range n.Components {
+		if component.GetType() == componentType {
+			return component
+		}
+	}
+	return nil
+}
+
+func (n *FlowSpecNLRI) GetIPPrefix() *IPPrefix {
+	if component := n.GetComponent(FlowSpecTypeDstPrefix); component != nil {
+		return component.(*FlowSpecPrefix).GetIPPrefix()
+	}
+	return NewIPPrefix(nil, 0)
+}
+
+func (n *FlowSpecNLRI) GetPrefix() net.IP {
+	return n.GetIPPrefix().Prefix
+}
+
+func (n *FlowSpecNLRI) GetLength() uint8 {
+	return n.GetIPPrefix().Length
+}
+
+func (n *FlowSpecNLRI) GetPathId() uint32 {
+	return 0
+}
+
+func (n *FlowSpecNLRI) GetCIDR() string {
+	strList := make([]string, 0, len(n.Components))
+	for _, component := range n.Components {
+		strList = append(strList, component.String())
+	}
+	return strings.Join(strList, " ")
+}
+
+func (n *FlowSpecNLRI) String() string {
+	return "{" + n.GetCIDR() + "}"
+}
+
+func NewFlowSpecNLRI(components ...FlowSpecComponent) *FlowSpecNLRI {
+	sort.Sort(FlowSpecComponents(components))
+	return &FlowSpecNLRI{
+		Components: components,
+	}
+}
+
+type FlowSpecAction struct {
+	SubType  uint8
+	Rate     float32
+	Sample   bool
+	Terminal bool
+	Target   string
+	DSCP     uint8
+}
+
+func (a *FlowSpecAction) String() string {
+	switch a.SubType {
+	case BGPExtCommunitySubTypeTrafficRateBytes:
+		return fmt.Sprintf("rate-limit-bytes:%v", a.Rate)
+	case BGPExtCommunitySubTypeTrafficRatePackets:
+		return fmt.Sprintf("rate-limit-packets:%v", a.Rate)
+	case BGPExtCommunitySubTypeTrafficAction:
+		return fmt.Sprintf("traffic-action:sample=%t,terminal=%t", a.Sample, a.Terminal)
+	case BGPExtCommunitySubTypeRedirect:
+		return "redirect:" + a.Target
+	case BGPExtCommunitySubTypeTrafficMarking:
+		return "mark:" + strconv.Itoa(int(a.DSCP))
+	}
+	return "unknown"
+}
+
+func ParseFlowSpecAction(community uint64) (*FlowSpecAction, bool) {
+	extType := uint8(community >> 56)
+	subType := uint8(community >> 48)
+	action := &FlowSpecAction{
+		SubType: subType,
+	}
+
+	switch extType {
+	case BGPExtCommunityTypeFlowSpec:
+		switch subType {
+		case BGPExtCommunitySubTypeTrafficRateBytes, BGPExtCommunitySubTypeTrafficRatePackets:
+			action.Rate = math.Float32frombits(uint32(community))
+		case BGPExtCommunitySubTypeTrafficAction:
+			action.Sample = uint8(community)&FlowSpecTrafficActionSample != 0
+			action.Terminal = uint8(community)&FlowSpecTrafficActionTerminal != 0
+		case BGPExtCommunitySubTypeRedirect:
+			action.Target = fmt.Sprintf("%d:%d", (community>>32)&0xFFFF, community&0xFFFFFFFF)
+		case BGPExtCommunitySubTypeTrafficMarking:
+			action.DSCP = uint8(community) & 0x3F
+		default:
+			return nil, false
+		}
+
+	case BGPExtCommunityTypeFlowSpecIPv4:
+		if subType != BGPExtCommunitySubTypeRedirect {
+			return nil, false
+		}
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(community>>16))
+		action.Target = fmt.Sprintf("%s:%d", ip, community&0xFFFF)
+
+	case BGPExtCommunityTypeFlowSpecAS4:
+		if subType != BGPExtCommunitySubTypeRedirect {
+			return nil, false
+		}
+		action.Target = fmt.Sprintf("%d:%d", (community>>16)&0xFFFFFFFF, community&0xFFFF)
+
+	default:
+		return nil, false
+	}
+
+	return action, true
+}
+
+func GetFlowSpecActions(pathAttrs []BGPPathAttr) []*FlowSpecAction {
+	actions := make([]*FlowSpecAction, 0)
+	for _, community := range GetExtCommunities(pathAttrs) {
+		if action, ok := ParseFlowSpecAction(community); ok {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+func NewFlowSpecTrafficRateCommunity(as uint16, rate float32) uint64 {
+	return uint64(BGPExtCommunityTypeFlowSpec)<<56 | uint64(BGPExtCommunitySubTypeTrafficRateBytes)<<48 |
+		uint64(as)<<32 | uint64(math.Float32bits(rate))
+}
+
+func NewFlowSpecTrafficActionCommunity(sample, terminal bool) uint64 {
+	val := uint64(0)
+	if sample {
+		val |= uint64(FlowSpecTrafficActionSample)
+	}
+	if terminal {
+		val |= uint64(FlowSpecTrafficActionTerminal)
+	}
+	return uint64(BGPExtCommunityTypeFlowSpec)<<56 | uint64(BGPExtCommunitySubTypeTrafficAction)<<48 | val
+}
+
+func NewFlowSpecRedirectCommunity(as uint16, val uint32) uint64 {
+	return uint64(BGPExtCommunityTypeFlowSpec)<<56 | uint64(BGPExtCommunitySubTypeRedirect)<<48 |
+		uint64(as)<<32 | uint64(val)
+}
+
+func NewFlowSpecTrafficMarkingCommunity(dscp uint8) uint64 {
+	return uint64(BGPExtCommunityTypeFlowSpec)<<56 | uint64(BGPExtCommunitySubTypeTrafficMarking)<<48 |
+		uint64(dscp&0x3F)
+}
@@ -0,0 +1,289 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// flowspec_test.go
+package packet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+)
+
+func TestFlowSpecNLRIDecode(t *testing.T) {
+	// Destination 10.0.1/24, IP protocol TCP, port 25 from RFC 8955
+	pkt, _ := hex.DecodeString("0b01180a0001038106048119")
+	nlri := &FlowSpecNLRI{}
+	err := nlri.Decode(pkt, AfiIP)
+	if err != nil {
+		t.Fatal("FlowSpec NLRI decode failed with error:", err)
+	}
+
+	if nlri.Len() != uint32(len(pkt)) {
+		t.Fatal("FlowSpec NLRI length mismatch, expected:", len(pkt), "got:", nlri.Len())
+	}
+
+	expected := "dst:10.0.1.0/24 proto:=6 port:=25"
+	if nlri.GetCIDR() != expected {
+		t.Fatal("FlowSpec NLRI rule mismatch, expected:", expected, "got:", nlri.GetCIDR())
+	}
+
+	if !nlri.GetPrefix().Equal(net.ParseIP("10.0.1.0")) || nlri.GetLength() != 24 {
+		t.Fatal("FlowSpec NLRI destination prefix mismatch, got:", nlri.GetPrefix(), nlri.GetLength())
+	}
+
+	bytes, err := nlri.Encode(AfiIP)
+	if err != nil {
+		t.Fatal("FlowSpec NLRI encode failed with error:", err)
+	}
+	if hex.EncodeToString(bytes) != hex.EncodeToString(pkt) {
+		t.Fatalf("FlowSpec NLRI encode mismatch, expected: %x got: %x", pkt, bytes)
+	}
+}
+
+func TestFlowSpecNLRIDecodeValueLength(t *testing.T) {
+	// IP protocol TCP with the value in 2 bytes, destination port 80
+	pkt := []byte{7, 3, 0x91, 0x00, 0x06, 5, 0x81, 80}
+	nlri := &FlowSpecNLRI{}
+	err := nlri.Decode(pkt, AfiIP)
+	if err != nil {
+		t.Fatal("FlowSpec NLRI decode failed with error:", err)
+	}
+
+	if nlri.Len() != uint32(len(pkt)) {
+		t.Fatal("FlowSpec NLRI length mismatch, expected:", len(pkt), "got:", nlri.Len())
+	}
+
+	expected := "proto:=6 dport:=80"
+	if nlri.GetCIDR() != expected {
+		t.Fatal("FlowSpec NLRI rule mismatch, expected:", expected, "got:", nlri.GetCIDR())
+	}
+
+	bytes, err := nlri.Encode(AfiIP)
+	if err != nil {
+		t.Fatal("FlowSpec NLRI encode failed with error:", err)
+	}
+	if hex.EncodeToString(bytes) != hex.EncodeToString(pkt) {
+		t.Fatalf("FlowSpec NLRI encode mismatch, expected: %x got: %x", pkt, bytes)
+	}
+}
+
+func TestFlowSpecNLRIBadPackets(t *testing.T) {
+	packets := make([]string, 0)
+	// Length more than the data
+	packets = append(packets, "0c01180a0001038106048119")
+	// Components out of order
+	packets = append(packets, "0b0381060118"+"0a0001048119")
+	// Operator list without end of list
+	packets = append(packets, "03030106")
+	// Unknown component type
+	packets = append(packets, "03ff8106")
+	// Prefix length more than 32 bits
+	packets = append(packets, "0601210a000101")
+
+	for _, strPkt := range packets {
+		pkt, _ := hex.DecodeString(strPkt)
+		nlri := &FlowSpecNLRI{}
+		err := nlri.Decode(pkt, AfiIP)
+		if err == nil {
+			t.Fatal("FlowSpec NLRI decode called for", strPkt, "expected failure, got NO errors")
+		} else {
+			t.Log("FlowSpec NLRI decode called for", strPkt, "expected failure, got error:", err)
+		}
+	}
+}
+
+func TestFlowSpecNLRIEncodeDecode(t *testing.T) {
+	nlris := []*FlowSpecNLRI{
+		NewFlowSpecNLRI(
+			NewFlowSpecOpComponent(FlowSpecTypeDstPort,
+				FlowSpecOpValue{FlowSpecOpGt | FlowSpecOpEq, 1024},
+				FlowSpecOpValue{FlowSpecOpAnd | FlowSpecOpLt | FlowSpecOpEq, 65535}),
+			NewFlowSpecPrefix(FlowSpecTypeSrcPrefix, net.ParseIP("192.168.1.1"), 32),
+			NewFlowSpecOpComponent(FlowSpecTypeTCPFlags, FlowSpecOpValue{FlowSpecOpMatch, 0x12}),
+			NewFlowSpecOpComponent(FlowSpecTypePktLen, FlowSpecOpValue{FlowSpecOpGt, 100000}),
+		),
+		NewFlowSpecNLRI(
+			NewFlowSpecPrefix(FlowSpecTypeDstPrefix, net.ParseIP("2001:db8::"), 32),
+			NewFlowSpecOpComponent(FlowSpecTypeIPProto, FlowSpecOpValue{FlowSpecOpEq, 17}),
+			NewFlowSpecOpComponent(FlowSpecTypeFlowLabel, FlowSpecOpValue{FlowSpecOpEq, 0xFFFFF}),
+		),
+	}
+	afis := []AFI{AfiIP, AfiIP6}
+	expected := []string{
+		"src:192.168.1.1/32 dport:>=1024&<=65535 tcp-flags:=0x12 pkt-len:>100000",
+		"dst:2001:db8::/32 proto:=17 flow-label:=1048575",
+	}
+
+	for idx, nlri := range nlris {
+		if nlri.GetCIDR() != expected[idx] {
+			t.Fatal("FlowSpec NLRI rule mismatch, expected:", expected[idx], "got:", nlri.GetCIDR())
+		}
+
+		pkt, err := nlri.Encode(afis[idx])
+		if err != nil {
+			t.Fatal("FlowSpec NLRI encode failed with error:", err)
+		}
+		if uint32(len(pkt)) != nlri.Len() {
+			t.Fatal("FlowSpec NLRI encoded length mismatch, expected:", nlri.Len(), "got:", len(pkt))
+		}
+
+		decoded := &FlowSpecNLRI{}
+		err = decoded.Decode(pkt, afis[idx])
+		if err != nil {
+			t.Fatal("FlowSpec NLRI decode failed with error:", err)
+		}
+		if decoded.GetCIDR() != nlri.GetCIDR() {
+			t.Fatal("FlowSpec NLRI decode mismatch, expected:", nlri.GetCIDR(), "got:", decoded.GetCIDR())
+		}
+	}
+}
+
+func TestFlowSpecNLRILongLength(t *testing.T) {
+	values := make([]FlowSpecOpValue, 0)
+	for port := uint64(1); port <= 100; port++ {
+		values = append(values, FlowSpecOpValue{FlowSpecOpEq, port * 1000})
+	}
+	nlri := NewFlowSpecNLRI(NewFlowSpecOpComponent(FlowSpecTypePort, values...))
+	pkt, err := nlri.Encode(AfiIP)
+	if err != nil {
+		t.Fatal("FlowSpec NLRI encode failed with error:", err)
+	}
+	if pkt[0]&0xF0 != 0xF0 || uint32(len(pkt)) != nlri.Len() {
+		t.Fatalf("FlowSpec NLRI with length %d not encoded with 2 byte length, got: %x", len(pkt), pkt[:2])
+	}
+
+	decoded := &FlowSpecNLRI{}
+	err = decoded.Decode(pkt, AfiIP)
+	if err != nil {
+		t.Fatal("FlowSpec NLRI decode failed with error:", err)
+	}
+	if decoded.GetCIDR() != nlri.GetCIDR() {
+		t.Fatal("FlowSpec NLRI decode mismatch, expected:", nlri.GetCIDR(), "got:", decoded.GetCIDR())
+	}
+}
+
+func TestFlowSpecPrefixOffset(t *testing.T) {
+	// Offset more than the prefix length
+	pkt, _ := hex.DecodeString("0240411234567890")
+	prefix := &FlowSpecPrefix{}
+	err := prefix.Decode(pkt, AfiIP6)
+	if err == nil {
+		t.Fatal("FlowSpec prefix decode with offset more than length, expected failure, got NO errors")
+	}
+
+	pkt, _ = hex.DecodeString("024020123456789a")
+	prefix = &FlowSpecPrefix{}
+	err = prefix.Decode(pkt, AfiIP6)
+	if err != nil {
+		t.Fatal("FlowSpec prefix decode failed with error:", err)
+	}
+	if !prefix.Prefix.Equal(net.ParseIP("0:0:1234:5678::")) || prefix.Length != 64 || prefix.Offset != 32 {
+		t.Fatal("FlowSpec prefix decode mismatch, got:", prefix)
+	}
+
+	encoded, err := prefix.Encode(AfiIP6)
+	if err != nil {
+		t.Fatal("FlowSpec prefix encode failed with error:", err)
+	}
+	if !bytes.Equal(encoded, pkt[:prefix.Len()]) {
+		t.Fatalf("FlowSpec prefix encode mismatch, expected: %x got: %x", pkt[:prefix.Len()], encoded)
+	}
+}
+
+func TestMPReachNLRIFlowSpecDecode(t *testing.T) {
+	hexPkt, _ := hex.DecodeString("800E11000185000" + "00b01180a0001038106048119")
+	mpReach := NewBGPPathAttrMPReachNLRI()
+	peerAttrs := BGPPeerAttrs{
+		ASSize:           4,
+		AddPathsRxActual: true,
+	}
+	err := mpReach.Decode(hexPkt, peerAttrs)
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI decode for FlowSpec failed with error:", err)
+	}
+
+	if mpReach.NextHop.Len() != 1 || len(mpReach.NLRI) != 1 {
+		t.Fatal("BGP MPReachNLRI decode for FlowSpec, expected empty next hop and 1 NLRI, got:", mpReach.NextHop,
+			mpReach.NLRI)
+	}
+	if _, ok := mpReach.NLRI[0].(*FlowSpecNLRI); !ok {
+		t.Fatal("BGP MPReachNLRI decode for FlowSpec, expected FlowSpec NLRI, got:", mpReach.NLRI[0])
+	}
+
+	pkt, err := mpReach.Encode()
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI encode for FlowSpec failed with error:", err)
+	}
+	if !bytes.Equal(pkt, hexPkt) {
+		t.Fatalf("BGP MPReachNLRI encode for FlowSpec mismatch, expected: %x got: %x", hexPkt, pkt)
+	}
+
+	nlri := mpReach.NLRI[0].(*FlowSpecNLRI)
+	mpReach = ConstructIPv6MPReachNLRI(GetProtocolFamily(AfiIP, SafiFlowSpec), nil, nil, []NLRI{nlri})
+	pkt, err = mpReach.Encode()
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI encode for constructed FlowSpec failed with error:", err)
+	}
+	if !bytes.Equal(pkt[4:], hexPkt[3:]) {
+		t.Fatalf("BGP MPReachNLRI encode for constructed FlowSpec mismatch, expected: %x got: %x", hexPkt, pkt)
+	}
+}
+
+func TestParseFlowSpecAction(t *testing.T) {
+	communities := []uint64{
+		NewFlowSpecTrafficRateCommunity(65001, 0),
+		NewFlowSpecTrafficActionCommunity(true, false),
+		NewFlowSpecRedirectCommunity(65001, 100),
+		NewFlowSpecTrafficMarkingCommunity(46),
+		uint64(BGPExtCommunityTypeFlowSpecIPv4)<<56 | uint64(BGPExtCommunitySubTypeRedirect)<<48 | 0x0A0000010064,
+	}
+	expected := []string{
+		"rate-limit-bytes:0",
+		"traffic-action:sample=true,terminal=false",
+		"redirect:65001:100",
+		"mark:46",
+		"redirect:10.0.0.1:100",
+	}
+
+	for idx, community := range communities {
+		action, ok := ParseFlowSpecAction(community)
+		if !ok {
+			t.Fatalf("ParseFlowSpecAction failed for community 0x%016x", community)
+		}
+		if action.String() != expected[idx] {
+			t.Fatal("ParseFlowSpecAction mismatch, expected:", expected[idx], "got:", action.String())
+		}
+	}
+
+	rt, _ := ParseExtCommunity("rt:65001:100")
+	if _, ok := ParseFlowSpecAction(rt); ok {
+		t.Fatal("ParseFlowSpecAction returned an action for route target", ExtCommunityToStr(rt))
+	}
+
+	pathAttrs := SetExtCommunities(nil, append(communities, rt))
+	if actions := GetFlowSpecActions(pathAttrs); len(actions) != len(communities) {
+		t.Fatal("GetFlowSpecActions expected", len(communities), "actions, got:", actions)
+	}
+}
@@ -316,6 +316,62 @@ func GetNumASes(pathAttrs []BGPPathAttr) uint32 {
 	return total
 }
 
+// GetOriginAS returns the AS that originated the route as defined in RFC 6811. ok is false when the AS path
+// is empty. AS 0 is returned when the last segment of the AS path is an AS_SET.
+func GetOriginAS(pathAttrs []BGPPathAttr) (originAS uint32, ok bool) {
+	for _, attr := range pathAttrs {
+		if attr.GetCode() == BGPPathAttrTypeASPath {
+			asPath := attr.(*BGPPathAttrASPath)
+			for i := len(asPath.Value) - 1; i >= 0; i-- {
+				if asPath.Value[i].GetType() != BGPASPathSegmentSequence {
+					return 0, true
+				}
+				switch seg := asPath.Value[i].(type) {
+				case *BGPAS4PathSegment:
+					if len(seg.AS) > 0 {
+						return seg.AS[len(seg.AS)-1], true
+					}
+				case *BGPAS2PathSegment:
+					if len(seg.AS) > 0 {
+						return uint32(seg.AS[len(seg.AS)-1]), true
+					}
+				}
+			}
+			break
+		}
+	}
+
+	return 0, false
+}
+
+// GetNeighborAS returns the AS from which the route was received, the first AS in the AS path. ok is false
+// when the AS path is empty. AS 0 is returned when the AS path starts with an AS_SET.
+func GetNeighborAS(pathAttrs []BGPPathAttr) (neighborAS uint32, ok bool) {
+	for _, attr := range pathAttrs {
+		if attr.GetCode() == BGPPathAttrTypeASPath {
+			asPath := attr.(*BGPPathAttrASPath)
+			for i := 0; i < len(asPath.Value); i++ {
+				if asPath.Value[i].GetType() != BGPASPathSegmentSequence {
+					return 0, true
+				}
+				switch seg := asPath.Value[i].(type) {
+				case *BGPAS4PathSegment:
+					if len(seg.AS) > 0 {
+						return seg.AS[0], true
+					}
+				case *BGPAS2PathSegment:
+					if len(seg.AS) > 0 {
+						return uint32(seg.AS[0]), true
+					}
+				}
+			}
+			break
+		}
+	}
+
+	return 0, false
+}
+
 func GetOrigin(pathAttrs []BGPPathAttr) uint8 {
 	for _, attr := range pathAttrs {
 		if attr.GetCode() == BGPPathAttrTypeOrigin {
@@ -581,15 +637,53 @@ func ConstructMPUnreachNLRIFromProtoFamily(protoFamily uint32, nlriList []NLRI)
 	return ConstructMPUnreachNLRI(afi, safi, nlriList)
 }
 
+func ConstructWithdrawFromUpdate(bgpMsg *BGPMessage) *BGPMessage {
+	updateMsg := bgpMsg.Body.(*BGPUpdate)
+	var withdrawn []NLRI
+	if len(updateMsg.NLRI) > 0 {
+		withdrawn = make([]NLRI, len(updateMsg.NLRI))
+		copy(withdrawn, updateMsg.NLRI)
+	}
+
+	pathAttrs := make([]BGPPathAttr, 0)
+	if mpReach, _ := GetMPAttrs(updateMsg.PathAttributes); mpReach != nil && len(mpReach.NLRI) > 0 {
+		pathAttrs = append(pathAttrs, ConstructMPUnreachNLRI(mpReach.AFI, mpReach.SAFI, mpReach.NLRI))
+	}
+
+	if len(withdrawn) == 0 && len(pathAttrs) == 0 {
+		return nil
+	}
+	return NewBGPUpdateMessage(withdrawn, pathAttrs, nil)
+}
+
 func ConstructIPv6MPReachNLRI(protoFamily uint32, nextHop, nextHopLinkLocal net.IP,
 	nlriList []NLRI) *BGPPathAttrMPReachNLRI {
 	afi, safi := GetAfiSafi(protoFamily)
 	mpReachNLRI := NewBGPPathAttrMPReachNLRI()
 	mpReachNLRI.AFI = afi
 	mpReachNLRI.SAFI = safi
+	if safi == SafiFlowSpec {
+		mpReachNLRI.SetNextHop(NewMPNextHopUnknown())
+		mpReachNLRI.SetNLRIList(nlriList)
+		return mpReachNLRI
+	}
+	if safi == SafiMPLSVPN {
+		mpNextHop := NewMPNextHopVPN()
+		mpNextHop.SetNextHop(nextHop)
+		mpReachNLRI.SetNextHop(mpNextHop)
+		mpReachNLRI.SetNLRIList(nlriList)
+		return mpReachNLRI
+	}
+	if afi == AfiL2VPN || afi == AfiLinkState {
+		mpNextHop := NewMPNextHopIP()
+		mpNextHop.SetNextHop(nextHop)
+		mpReachNLRI.SetNextHop(mpNextHop)
+		mpReachNLRI.SetNLRIList(nlriList)
+		return mpReachNLRI
+	}
 	mpNextHop := NewMPNextHopIP6()
 	mpNextHop.SetGlobalNextHop(nextHop)
-	if nextHopLinkLocal != nil && nextHopLinkLocal.To16() == nil {
+	if nextHopLinkLocal != nil && nextHopLinkLocal.To4() == nil {
 		mpNextHop.SetLinkLocalNextHop(nextHopLinkLocal)
 	}
 	mpReachNLRI.SetNextHop(mpNextHop)
@@ -597,6 +691,14 @@ func ConstructIPv6MPReachNLRI(protoFamily uint32, nextHop, nextHopLinkLocal net.
 	return mpReachNLRI
 }
 
+// Add path NLRIs carry the path id, other families are advertised as they are stored
+func StripPathId(nlri NLRI) NLRI {
+	if extNLRI, ok := nlri.(*ExtNLRI); ok {
+		return extNLRI.IPPrefix
+	}
+	return nlri
+}
+
 func CloneMPReachNLRIWithNewNLRI(mpReachNLRI *BGPPathAttrMPReachNLRI, nlri []NLRI) *BGPPathAttrMPReachNLRI {
 	newMPReachNLRI := NewBGPPathAttrMPReachNLRI()
 	newMPReachNLRI.AFI = mpReachNLRI.AFI
@@ -687,12 +789,17 @@ func ConvertIPBytesToUint(bytes []byte) uint32 {
 	return uint32(bytes[0])<<24 | uint32(bytes[1]<<16) | uint32(bytes[2]<<8) | uint32(bytes[3])
 }
 
-func ConstructOptParams(as uint32, afiSAfiMap map[uint32]bool, addPathsRx bool, addPathsMaxTx uint8) []BGPOptParam {
+// ConstructOptParams constructs the capabilities sent in the OPEN message. The graceful restart forwarding state
+// bit is set only when grForwarding is set, i.e. the forwarding state is preserved across the restart.
+func ConstructOptParams(as uint32, afiSAfiMap map[uint32]bool, addPathsRx bool, addPathsMaxTx uint8,
+	grCap *BGPCapGracefulRestart, grForwarding bool) []BGPOptParam {
 	optParams := make([]BGPOptParam, 0)
 	capParams := make([]BGPCapability, 0)
 
 	cap4ByteASPath := NewBGPCap4ByteASPath(as)
 	capParams = append(capParams, cap4ByteASPath)
+	capRouteRefresh := NewBGPCapRouteRefresh()
+	capParams = append(capParams, capRouteRefresh)
 	capAddPaths := NewBGPCapAddPath()
 	addPathFlags := uint8(0)
 	if addPathsRx {
@@ -702,6 +809,11 @@ func ConstructOptParams(as uint32, afiSAfiMap map[uint32]bool, addPathsRx bool,
 		addPathFlags |= BGPCapAddPathTx
 	}
 
+	grFlags := uint8(0)
+	if grForwarding {
+		grFlags |= BGPCapGracefulRestartForwardingState
+	}
+
 	for protoFamily, _ := range afiSAfiMap {
 		afi, safi := GetAfiSafi(protoFamily)
 		utils.Logger.Infof("Advertising capability for afi %d safi %d", afi, safi)
@@ -710,6 +822,10 @@ func ConstructOptParams(as uint32, afiSAfiMap map[uint32]bool, addPathsRx bool,
 
 		addPathAfiSafi := NewAddPathAFISAFI(afi, safi, addPathFlags)
 		capAddPaths.AddAddPathAFISAFI(addPathAfiSafi)
+
+		if grCap != nil {
+			grCap.AddGracefulRestartAFISAFI(afi, safi, grFlags)
+		}
 	}
 
 	if addPathFlags != 0 {
@@ -717,12 +833,101 @@ func ConstructOptParams(as uint32, afiSAfiMap map[uint32]bool, addPathsRx bool,
 		capParams = append(capParams, capAddPaths)
 	}
 
+	if grCap != nil {
+		utils.Logger.Infof("Advertising capability for graceful restart %+v", grCap)
+		capParams = append(capParams, grCap)
+	}
+
 	optCapability := NewBGPOptParamCapability(capParams)
 	optParams = append(optParams, optCapability)
 
 	return optParams
 }
 
+func ConstructAddPathCap(addPathFlags map[uint32]uint8) *BGPCapAddPath {
+	if len(addPathFlags) == 0 {
+		return nil
+	}
+
+	capAddPaths := NewBGPCapAddPath()
+	for protoFamily, flags := range addPathFlags {
+		afi, safi := GetAfiSafi(protoFamily)
+		capAddPaths.AddAddPathAFISAFI(NewAddPathAFISAFI(afi, safi, flags))
+	}
+	utils.Logger.Infof("Advertising capability for addPaths %+v", capAddPaths.Value)
+	return capAddPaths
+}
+
+func ConstructExtendedNextHopCap(afiSAfiMap map[uint32]bool) *BGPCapExtendedNextHop {
+	extNHCap := NewBGPCapExtendedNextHop()
+	for protoFamily, _ := range afiSAfiMap {
+		afi, safi := GetAfiSafi(protoFamily)
+		if afi == AfiIP && safi == SafiUnicast {
+			utils.Logger.Infof("Advertising extended next hop capability for afi %d safi %d", afi, safi)
+			extNHCap.AddExtNextHopAFISAFI(afi, safi, AfiIP6)
+		}
+	}
+
+	if len(extNHCap.Value) == 0 {
+		return nil
+	}
+	return extNHCap
+}
+
+func GetExtendedNextHopFamily(openMsg *BGPOpen) map[uint32]bool {
+	extNHFamily := make(map[uint32]bool)
+	for _, optParam := range openMsg.OptParams {
+		if capabilities, ok := optParam.(*BGPOptParamCapability); ok {
+			for _, capability := range capabilities.Value {
+				if extNHCap, ok := capability.(*BGPCapExtendedNextHop); ok {
+					for _, val := range extNHCap.Value {
+						if val.NextHopAFI == AfiIP6 && val.SAFI <= 0xFF {
+							extNHFamily[GetProtocolFamily(val.AFI, SAFI(val.SAFI))] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return extNHFamily
+}
+
+func ConstructORFCap(afiSAfiMap map[uint32]bool) *BGPCapORF {
+	orfCap := NewBGPCapORF()
+	for protoFamily, _ := range afiSAfiMap {
+		afi, safi := GetAfiSafi(protoFamily)
+		if (afi == AfiIP || afi == AfiIP6) && safi == SafiUnicast {
+			utils.Logger.Infof("Advertising address prefix ORF capability for afi %d safi %d", afi, safi)
+			orfCap.AddORF(afi, safi, ORFTypeAddressPrefix, ORFModeReceive)
+		}
+	}
+
+	if len(orfCap.Value) == 0 {
+		return nil
+	}
+	return orfCap
+}
+
+func GetORFPrefixSendFamily(openMsg *BGPOpen) map[uint32]bool {
+	orfFamily := make(map[uint32]bool)
+	for _, optParam := range openMsg.OptParams {
+		if capabilities, ok := optParam.(*BGPOptParamCapability); ok {
+			for _, capability := range capabilities.Value {
+				if orfCap, ok := capability.(*BGPCapORF); ok {
+					for _, val := range orfCap.Value {
+						for _, orf := range val.ORFs {
+							if orf.Type == ORFTypeAddressPrefix && (orf.Mode&ORFModeSend) != 0 {
+								orfFamily[GetProtocolFamily(val.AFI, val.SAFI)] = true
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return orfFamily
+}
+
 func GetASSize(openMsg *BGPOpen) uint8 {
 	for _, optParam := range openMsg.OptParams {
 		if optParam.GetCode() == BGPOptParamTypeCapability {
@@ -738,6 +943,67 @@ func GetASSize(openMsg *BGPOpen) uint8 {
 	return 2
 }
 
+func IsRouteRefreshSupported(openMsg *BGPOpen) bool {
+	for _, optParam := range openMsg.OptParams {
+		if optParam.GetCode() == BGPOptParamTypeCapability {
+			capabilities := optParam.(*BGPOptParamCapability)
+			for _, capability := range capabilities.Value {
+				if capability.GetCode() == BGPCapTypeRouteRefresh ||
+					capability.GetCode() == BGPCapTypeEnhancedRouteRefresh {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func GetGracefulRestartCap(openMsg *BGPOpen) *BGPCapGracefulRestart {
+	for _, optParam := range openMsg.OptParams {
+		if capabilities, ok := optParam.(*BGPOptParamCapability); ok {
+			for _, capability := range capabilities.Value {
+				if grCap, ok := capability.(*BGPCapGracefulRestart); ok {
+					return grCap
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func NewBGPEndOfRIBMessage(protoFamily uint32) *BGPMessage {
+	if protoFamily == GetProtocolFamily(AfiIP, SafiUnicast) {
+		return NewBGPUpdateMessage(make([]NLRI, 0), make([]BGPPathAttr, 0), make([]NLRI, 0))
+	}
+
+	mpUnreachNLRI := ConstructMPUnreachNLRIFromProtoFamily(protoFamily, make([]NLRI, 0))
+	return NewBGPUpdateMessage(make([]NLRI, 0), []BGPPathAttr{mpUnreachNLRI}, make([]NLRI, 0))
+}
+
+// GetEndOfRIBFamily returns the family of an End-of-RIB marker, an empty UPDATE for IPv4 unicast and an UPDATE with
+// only an empty MP_UNREACH_NLRI for the other families
+func GetEndOfRIBFamily(updateMsg *BGPMessage) (uint32, bool) {
+	body, ok := updateMsg.Body.(*BGPUpdate)
+	if !ok || len(body.WithdrawnRoutes) > 0 || len(body.NLRI) > 0 {
+		return 0, false
+	}
+
+	if len(body.PathAttributes) == 0 {
+		return GetProtocolFamily(AfiIP, SafiUnicast), true
+	}
+
+	if len(body.PathAttributes) == 1 {
+		if mpUnreachNLRI, ok := body.PathAttributes[0].(*BGPPathAttrMPUnreachNLRI); ok &&
+			len(mpUnreachNLRI.NLRI) == 0 {
+			return GetProtocolFamily(mpUnreachNLRI.AFI, mpUnreachNLRI.SAFI), true
+		}
+	}
+
+	return 0, false
+}
+
 func GetAddPathFamily(openMsg *BGPOpen) map[AFI]map[SAFI]uint8 {
 	addPathFamily := make(map[AFI]map[SAFI]uint8)
 	for _, optParam := range openMsg.OptParams {
@@ -960,6 +1226,11 @@ func ConstructMaxSizedUpdatePackets(bgpMsg *BGPMessage) []*BGPMessage {
 	updateMsg := bgpMsg.Body.(*BGPUpdate)
 	pathAttrs := make([]BGPPathAttr, 0)
 
+	if _, ok := GetEndOfRIBFamily(bgpMsg); ok {
+		newUpdateMsgs = append(newUpdateMsgs, bgpMsg)
+		return newUpdateMsgs
+	}
+
 	if updateMsg.WithdrawnRoutes != nil {
 		for lastIdx = 0; lastIdx < len(updateMsg.WithdrawnRoutes); lastIdx++ {
 			nlriLen := updateMsg.WithdrawnRoutes[lastIdx].Len()
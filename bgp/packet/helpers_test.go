@@ -26,7 +26,7 @@ package packet
 
 import (
 	"l3/bgp/utils"
-	_ "net"
+	"net"
 	"testing"
 	"utils/logging"
 )
@@ -181,3 +181,159 @@ func TestBGPAggregateASPaths(t *testing.T) {
 		}
 	}
 }
+
+func TestBGPEndOfRIBMessage(t *testing.T) {
+	families := []uint32{GetProtocolFamily(AfiIP, SafiUnicast), GetProtocolFamily(AfiIP6, SafiUnicast)}
+	for _, protoFamily := range families {
+		updateMsgs := ConstructMaxSizedUpdatePackets(NewBGPEndOfRIBMessage(protoFamily))
+		if len(updateMsgs) != 1 {
+			t.Fatal("ConstructMaxSizedUpdatePackets called for End-of-RIB... expected 1 update message, got",
+				len(updateMsgs))
+		}
+
+		pkt, err := updateMsgs[0].Encode()
+		if err != nil {
+			t.Fatal("End-of-RIB message encode failed with error", err)
+		}
+
+		bgpHeader := NewBGPHeader()
+		err = bgpHeader.Decode(pkt[:BGPMsgHeaderLen])
+		if err != nil {
+			t.Fatal("BGP packet header decode failed with error", err)
+		}
+
+		bgpMessage := NewBGPMessage()
+		err = bgpMessage.Decode(bgpHeader, pkt[BGPMsgHeaderLen:], BGPPeerAttrs{ASSize: 4})
+		if err != nil {
+			t.Fatal("End-of-RIB message decode failed with error", err)
+		}
+
+		eorFamily, ok := GetEndOfRIBFamily(bgpMessage)
+		if !ok || eorFamily != protoFamily {
+			t.Fatalf("End-of-RIB message for family %d decoded as family %d, End-of-RIB %t", protoFamily,
+				eorFamily, ok)
+		}
+	}
+}
+
+func TestGetEndOfRIBFamily(t *testing.T) {
+	eor := NewBGPUpdateMessage(make([]NLRI, 0), make([]BGPPathAttr, 0), make([]NLRI, 0))
+	protoFamily, ok := GetEndOfRIBFamily(eor)
+	if !ok || protoFamily != GetProtocolFamily(AfiIP, SafiUnicast) {
+		t.Fatal("Empty UPDATE is not detected as IPv4 unicast End-of-RIB")
+	}
+
+	mpUnreach := ConstructMPUnreachNLRIFromProtoFamily(GetProtocolFamily(AfiIP6, SafiUnicast), make([]NLRI, 0))
+	eor = NewBGPUpdateMessage(make([]NLRI, 0), []BGPPathAttr{mpUnreach}, make([]NLRI, 0))
+	protoFamily, ok = GetEndOfRIBFamily(eor)
+	if !ok || protoFamily != GetProtocolFamily(AfiIP6, SafiUnicast) {
+		t.Fatal("UPDATE with empty MP_UNREACH_NLRI is not detected as IPv6 unicast End-of-RIB")
+	}
+
+	nlri := NewIPPrefix(net.ParseIP("10.1.0.0"), 16)
+	update := NewBGPUpdateMessage([]NLRI{nlri}, make([]BGPPathAttr, 0), make([]NLRI, 0))
+	if _, ok = GetEndOfRIBFamily(update); ok {
+		t.Fatal("UPDATE with withdrawn routes is detected as End-of-RIB")
+	}
+}
+
+func TestGetOriginAS(t *testing.T) {
+	tests := []struct {
+		segments [][]uint32
+		setIdx   int
+		originAS uint32
+		ok       bool
+	}{
+		{[][]uint32{}, -1, 0, false},
+		{[][]uint32{[]uint32{100, 200, 300}}, -1, 300, true},
+		{[][]uint32{[]uint32{100}, []uint32{400, 500}}, -1, 500, true},
+		{[][]uint32{[]uint32{100}, []uint32{400, 500}}, 1, 0, true},
+		{[][]uint32{[]uint32{100}, []uint32{}}, -1, 100, true},
+	}
+
+	for _, test := range tests {
+		asPath := NewBGPPathAttrASPath()
+		for idx, asNums := range test.segments {
+			segType := BGPASPathSegmentSequence
+			if idx == test.setIdx {
+				segType = BGPASPathSegmentSet
+			}
+			seg := NewBGPAS4PathSegment(segType)
+			for _, asNum := range asNums {
+				seg.AppendAS(asNum)
+			}
+			asPath.AppendASPathSegment(seg)
+		}
+
+		originAS, ok := GetOriginAS([]BGPPathAttr{NewBGPPathAttrOrigin(BGPPathAttrOriginIGP), asPath})
+		if originAS != test.originAS || ok != test.ok {
+			t.Error("GetOriginAS for AS path", asPath, "returned", originAS, ok, "expected", test.originAS, test.ok)
+		}
+	}
+}
+
+func TestGetNeighborAS(t *testing.T) {
+	tests := []struct {
+		segments   [][]uint32
+		setIdx     int
+		neighborAS uint32
+		ok         bool
+	}{
+		{[][]uint32{}, -1, 0, false},
+		{[][]uint32{[]uint32{100, 200, 300}}, -1, 100, true},
+		{[][]uint32{[]uint32{}, []uint32{400, 500}}, -1, 400, true},
+		{[][]uint32{[]uint32{100, 200}, []uint32{400, 500}}, 0, 0, true},
+		{[][]uint32{[]uint32{100}, []uint32{400, 500}}, 1, 100, true},
+	}
+
+	for _, test := range tests {
+		asPath := NewBGPPathAttrASPath()
+		for idx, asNums := range test.segments {
+			segType := BGPASPathSegmentSequence
+			if idx == test.setIdx {
+				segType = BGPASPathSegmentSet
+			}
+			seg := NewBGPAS4PathSegment(segType)
+			for _, asNum := range asNums {
+				seg.AppendAS(asNum)
+			}
+			asPath.AppendASPathSegment(seg)
+		}
+
+		neighborAS, ok := GetNeighborAS([]BGPPathAttr{NewBGPPathAttrOrigin(BGPPathAttrOriginIGP), asPath})
+		if neighborAS != test.neighborAS || ok != test.ok {
+			t.Error("GetNeighborAS for AS path", asPath, "returned", neighborAS, ok, "expected", test.neighborAS,
+				test.ok)
+		}
+	}
+}
+
+func TestConstructWithdrawFromUpdate(t *testing.T) {
+	pa := ConstructPathAttrForConnRoutes(1234)
+	v6Prefix, _ := ConstructIPPrefixFromCIDR("2001:db8::/32")
+	mpReach := ConstructIPv6MPReachNLRIForConnRoutes(GetProtocolFamily(AfiIP6, SafiUnicast))
+	mpReach.SetNLRIList([]NLRI{v6Prefix})
+	pa = AddMPReachNLRIToPathAttrs(pa, mpReach)
+	v4Prefix := ConstructIPPrefix("20.1.20.0", "255.255.255.0")
+	updateMsg := NewBGPUpdateMessage(make([]NLRI, 0), pa, []NLRI{v4Prefix})
+
+	withdrawMsg := ConstructWithdrawFromUpdate(updateMsg)
+	if withdrawMsg == nil {
+		t.Fatal("ConstructWithdrawFromUpdate did not construct a withdraw message")
+	}
+
+	withdraw := withdrawMsg.Body.(*BGPUpdate)
+	if len(withdraw.NLRI) != 0 || len(withdraw.WithdrawnRoutes) != 1 ||
+		withdraw.WithdrawnRoutes[0].GetCIDR() != "20.1.20.0/24" {
+		t.Fatalf("ConstructWithdrawFromUpdate withdrawn routes - got %+v", withdraw)
+	}
+
+	mpReach, mpUnreach := GetMPAttrs(withdraw.PathAttributes)
+	if mpReach != nil || mpUnreach == nil || mpUnreach.AFI != AfiIP6 || len(mpUnreach.NLRI) != 1 {
+		t.Fatalf("ConstructWithdrawFromUpdate MP unreach - got %+v", withdraw.PathAttributes)
+	}
+
+	if ConstructWithdrawFromUpdate(NewBGPUpdateMessage(nil, ConstructPathAttrForConnRoutes(1234), nil)) != nil {
+		t.Fatal("ConstructWithdrawFromUpdate constructed a withdraw message for an update without NLRI")
+	}
+}
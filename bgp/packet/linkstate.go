@@ -0,0 +1,787 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// linkstate.go
+package packet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// BGP Link-State (RFC 7752)
+const (
+	AfiLinkState  AFI  = 16388
+	SafiLinkState SAFI = 71
+)
+
+const (
+	LinkStateNLRITypeNode uint16 = iota + 1
+	LinkStateNLRITypeLink
+	LinkStateNLRITypeIPv4Prefix
+	LinkStateNLRITypeIPv6Prefix
+)
+
+var LinkStateNLRITypeToStrMap = map[uint16]string{
+	LinkStateNLRITypeNode:       "node",
+	LinkStateNLRITypeLink:       "link",
+	LinkStateNLRITypeIPv4Prefix: "ipv4-prefix",
+	LinkStateNLRITypeIPv6Prefix: "ipv6-prefix",
+}
+
+const (
+	LinkStateProtoISISL1 uint8 = iota + 1
+	LinkStateProtoISISL2
+	LinkStateProtoOSPFv2
+	LinkStateProtoDirect
+	LinkStateProtoStatic
+	LinkStateProtoOSPFv3
+)
+
+var LinkStateProtoToStrMap = map[uint8]string{
+	LinkStateProtoISISL1: "isis-l1",
+	LinkStateProtoISISL2: "isis-l2",
+	LinkStateProtoOSPFv2: "ospfv2",
+	LinkStateProtoDirect: "direct",
+	LinkStateProtoStatic: "static",
+	LinkStateProtoOSPFv3: "ospfv3",
+}
+
+// NLRI descriptor TLVs
+const (
+	LinkStateTLVLocalNode        uint16 = 256
+	LinkStateTLVRemoteNode       uint16 = 257
+	LinkStateTLVLinkId           uint16 = 258
+	LinkStateTLVIPv4IntfAddr     uint16 = 259
+	LinkStateTLVIPv4NeighborAddr uint16 = 260
+	LinkStateTLVIPv6IntfAddr     uint16 = 261
+	LinkStateTLVIPv6NeighborAddr uint16 = 262
+	LinkStateTLVMultiTopologyId  uint16 = 263
+	LinkStateTLVOSPFRouteType    uint16 = 264
+	LinkStateTLVIPReachability   uint16 = 265
+	LinkStateTLVAS               uint16 = 512
+	LinkStateTLVBGPLSId          uint16 = 513
+	LinkStateTLVOSPFAreaId       uint16 = 514
+	LinkStateTLVIGPRouterId      uint16 = 515
+)
+
+// BGP-LS attribute TLVs
+const (
+	LinkStateAttrNodeFlags      uint16 = 1024
+	LinkStateAttrNodeName       uint16 = 1026
+	LinkStateAttrLocalRouterId  uint16 = 1028
+	LinkStateAttrRemoteRouterId uint16 = 1030
+	LinkStateAttrIGPMetric      uint16 = 1095
+	LinkStateAttrPrefixMetric   uint16 = 1155
+)
+
+const (
+	LinkStateNodeFlagOverload uint8 = 0x80
+	LinkStateNodeFlagAttached uint8 = 0x40
+	LinkStateNodeFlagExternal uint8 = 0x20
+	LinkStateNodeFlagABR      uint8 = 0x10
+)
+
+const (
+	OSPFRouteTypeIntraArea uint8 = iota + 1
+	OSPFRouteTypeInterArea
+	OSPFRouteTypeExternal1
+	OSPFRouteTypeExternal2
+	OSPFRouteTypeNSSA1
+	OSPFRouteTypeNSSA2
+)
+
+const LinkStateTLVHeaderLen = 4
+
+type LinkStateTLV struct {
+	Type  uint16
+	Value []byte
+}
+
+func (t LinkStateTLV) Len() int {
+	return LinkStateTLVHeaderLen + len(t.Value)
+}
+
+func (t LinkStateTLV) String() string {
+	return fmt.Sprintf("%d:%s", t.Type, hex.EncodeToString(t.Value))
+}
+
+func newLinkStateTLV(tlvType uint16, value []byte) LinkStateTLV {
+	return LinkStateTLV{Type: tlvType, Value: value}
+}
+
+func newLinkStateUint32TLV(tlvType uint16, value uint32) LinkStateTLV {
+	bytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(bytes, value)
+	return newLinkStateTLV(tlvType, bytes)
+}
+
+func encodeLinkStateTLVs(tlvs []LinkStateTLV) []byte {
+	pkt := make([]byte, 0)
+	for _, tlv := range tlvs {
+		hdr := make([]byte, LinkStateTLVHeaderLen)
+		binary.BigEndian.PutUint16(hdr[0:], tlv.Type)
+		binary.BigEndian.PutUint16(hdr[2:], uint16(len(tlv.Value)))
+		pkt = append(pkt, hdr...)
+		pkt = append(pkt, tlv.Value...)
+	}
+	return pkt
+}
+
+func decodeLinkStateTLVs(pkt []byte) ([]LinkStateTLV, error) {
+	tlvs := make([]LinkStateTLV, 0)
+	for ptr := 0; ptr < len(pkt); {
+		if len(pkt)-ptr < LinkStateTLVHeaderLen {
+			return nil, BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+				"Link-State TLV does not contain type and length"}
+		}
+
+		tlvType := binary.BigEndian.Uint16(pkt[ptr:])
+		length := int(binary.BigEndian.Uint16(pkt[ptr+2:]))
+		ptr += LinkStateTLVHeaderLen
+		if len(pkt)-ptr < length {
+			return nil, BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+				fmt.Sprintf("Link-State TLV %d length %d is more than the available data", tlvType, length)}
+		}
+
+		value := make([]byte, length)
+		copy(value, pkt[ptr:ptr+length])
+		tlvs = append(tlvs, newLinkStateTLV(tlvType, value))
+		ptr += length
+	}
+	return tlvs, nil
+}
+
+func linkStateTLVError(tlvType uint16, length int) error {
+	return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+		fmt.Sprintf("Link-State TLV %d has invalid length %d", tlvType, length)}
+}
+
+func linkStateCloneIP(ip net.IP) net.IP {
+	if ip == nil {
+		return nil
+	}
+	x := make(net.IP, len(ip))
+	copy(x, ip)
+	return x
+}
+
+const LinkStateISISSystemIdLen = 6
+
+// LinkStateNodeDescriptor identifies a node. For an OSPF pseudonode the IGP router id is the router id of the DR
+// followed by the interface address of the DR. An IS-IS node is identified by its system id and, for a pseudonode,
+// the pseudonode id.
+type LinkStateNodeDescriptor struct {
+	AS       uint32
+	BGPLSId  uint32
+	AreaId   uint32
+	RouterId net.IP
+	DRIP     net.IP
+	SystemId []byte
+	PSNId    uint8
+}
+
+func (n *LinkStateNodeDescriptor) clone() LinkStateNodeDescriptor {
+	x := *n
+	x.RouterId = linkStateCloneIP(n.RouterId)
+	x.DRIP = linkStateCloneIP(n.DRIP)
+	if n.SystemId != nil {
+		x.SystemId = make([]byte, len(n.SystemId))
+		copy(x.SystemId, n.SystemId)
+	}
+	return x
+}
+
+func (n *LinkStateNodeDescriptor) IsPseudonode() bool {
+	return n.DRIP != nil || n.PSNId != 0
+}
+
+func isLinkStateProtoISIS(protoId uint8) bool {
+	return protoId == LinkStateProtoISISL1 || protoId == LinkStateProtoISISL2
+}
+
+func (n *LinkStateNodeDescriptor) encode() []byte {
+	tlvs := make([]LinkStateTLV, 0, 4)
+	if n.AS != 0 {
+		tlvs = append(tlvs, newLinkStateUint32TLV(LinkStateTLVAS, n.AS))
+	}
+	if n.BGPLSId != 0 {
+		tlvs = append(tlvs, newLinkStateUint32TLV(LinkStateTLVBGPLSId, n.BGPLSId))
+	}
+	tlvs = append(tlvs, newLinkStateUint32TLV(LinkStateTLVOSPFAreaId, n.AreaId))
+
+	routerId := make([]byte, 0, 2*net.IPv4len)
+	if n.SystemId != nil {
+		routerId = append(routerId, n.SystemId...)
+		if n.PSNId != 0 {
+			routerId = append(routerId, n.PSNId)
+		}
+	} else {
+		routerId = append(routerId, n.RouterId.To4()...)
+		if n.DRIP != nil {
+			routerId = append(routerId, n.DRIP.To4()...)
+		}
+	}
+	tlvs = append(tlvs, newLinkStateTLV(LinkStateTLVIGPRouterId, routerId))
+	return encodeLinkStateTLVs(tlvs)
+}
+
+// decode uses the protocol id of the NLRI to decode the IGP router id. IS-IS uses a 6 byte system id followed by the
+// pseudonode id for a pseudonode. The other protocols use a 4 byte router id followed by the DR address.
+func (n *LinkStateNodeDescriptor) decode(pkt []byte, protoId uint8) error {
+	tlvs, err := decodeLinkStateTLVs(pkt)
+	if err != nil {
+		return err
+	}
+
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case LinkStateTLVAS, LinkStateTLVBGPLSId, LinkStateTLVOSPFAreaId:
+			if len(tlv.Value) != 4 {
+				return linkStateTLVError(tlv.Type, len(tlv.Value))
+			}
+			val := binary.BigEndian.Uint32(tlv.Value)
+			if tlv.Type == LinkStateTLVAS {
+				n.AS = val
+			} else if tlv.Type == LinkStateTLVBGPLSId {
+				n.BGPLSId = val
+			} else {
+				n.AreaId = val
+			}
+
+		case LinkStateTLVIGPRouterId:
+			if isLinkStateProtoISIS(protoId) {
+				if len(tlv.Value) != LinkStateISISSystemIdLen && len(tlv.Value) != LinkStateISISSystemIdLen+1 {
+					return linkStateTLVError(tlv.Type, len(tlv.Value))
+				}
+				n.SystemId = tlv.Value[:LinkStateISISSystemIdLen]
+				if len(tlv.Value) > LinkStateISISSystemIdLen {
+					n.PSNId = tlv.Value[LinkStateISISSystemIdLen]
+				}
+				continue
+			}
+
+			if len(tlv.Value) != net.IPv4len && len(tlv.Value) != 2*net.IPv4len {
+				return linkStateTLVError(tlv.Type, len(tlv.Value))
+			}
+			n.RouterId = net.IP(tlv.Value[:net.IPv4len]).To16()
+			if len(tlv.Value) == 2*net.IPv4len {
+				n.DRIP = net.IP(tlv.Value[net.IPv4len:]).To16()
+			}
+		}
+	}
+	return nil
+}
+
+func (n *LinkStateNodeDescriptor) String() string {
+	areaId := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(areaId, n.AreaId)
+	if n.SystemId != nil {
+		sysId := hex.EncodeToString(n.SystemId)
+		if len(sysId) == 2*LinkStateISISSystemIdLen {
+			sysId = sysId[0:4] + "." + sysId[4:8] + "." + sysId[8:12]
+		}
+		return fmt.Sprintf("as:%d,area:%s,router:%s.%02x", n.AS, areaId, sysId, n.PSNId)
+	}
+
+	str := fmt.Sprintf("as:%d,area:%s,router:%s", n.AS, areaId, evpnIPStr(n.RouterId))
+	if n.DRIP != nil {
+		str += ",dr:" + n.DRIP.String()
+	}
+	return str
+}
+
+type LinkStateLinkDescriptor struct {
+	LocalId  uint32
+	RemoteId uint32
+	LocalIP  net.IP
+	RemoteIP net.IP
+}
+
+func (l *LinkStateLinkDescriptor) clone() LinkStateLinkDescriptor {
+	x := *l
+	x.LocalIP = linkStateCloneIP(l.LocalIP)
+	x.RemoteIP = linkStateCloneIP(l.RemoteIP)
+	return x
+}
+
+func linkStateAddrTLV(v4Type, v6Type uint16, ip net.IP) LinkStateTLV {
+	if ip4 := ip.To4(); ip4 != nil {
+		return newLinkStateTLV(v4Type, []byte(ip4))
+	}
+	return newLinkStateTLV(v6Type, []byte(ip.To16()))
+}
+
+func (l *LinkStateLinkDescriptor) tlvs() []LinkStateTLV {
+	tlvs := make([]LinkStateTLV, 0, 3)
+	if l.LocalId != 0 || l.RemoteId != 0 {
+		linkId := make([]byte, 8)
+		binary.BigEndian.PutUint32(linkId[0:], l.LocalId)
+		binary.BigEndian.PutUint32(linkId[4:], l.RemoteId)
+		tlvs = append(tlvs, newLinkStateTLV(LinkStateTLVLinkId, linkId))
+	}
+	if l.LocalIP != nil {
+		tlvs = append(tlvs, linkStateAddrTLV(LinkStateTLVIPv4IntfAddr, LinkStateTLVIPv6IntfAddr, l.LocalIP))
+	}
+	if l.RemoteIP != nil {
+		tlvs = append(tlvs, linkStateAddrTLV(LinkStateTLVIPv4NeighborAddr, LinkStateTLVIPv6NeighborAddr,
+			l.RemoteIP))
+	}
+	return tlvs
+}
+
+func (l *LinkStateLinkDescriptor) decodeTLV(tlv LinkStateTLV) error {
+	switch tlv.Type {
+	case LinkStateTLVLinkId:
+		if len(tlv.Value) != 8 {
+			return linkStateTLVError(tlv.Type, len(tlv.Value))
+		}
+		l.LocalId = binary.BigEndian.Uint32(tlv.Value[0:])
+		l.RemoteId = binary.BigEndian.Uint32(tlv.Value[4:])
+
+	case LinkStateTLVIPv4IntfAddr, LinkStateTLVIPv4NeighborAddr:
+		if len(tlv.Value) != net.IPv4len {
+			return linkStateTLVError(tlv.Type, len(tlv.Value))
+		}
+		if tlv.Type == LinkStateTLVIPv4IntfAddr {
+			l.LocalIP = net.IP(tlv.Value).To16()
+		} else {
+			l.RemoteIP = net.IP(tlv.Value).To16()
+		}
+
+	case LinkStateTLVIPv6IntfAddr, LinkStateTLVIPv6NeighborAddr:
+		if len(tlv.Value) != net.IPv6len {
+			return linkStateTLVError(tlv.Type, len(tlv.Value))
+		}
+		if tlv.Type == LinkStateTLVIPv6IntfAddr {
+			l.LocalIP = net.IP(tlv.Value)
+		} else {
+			l.RemoteIP = net.IP(tlv.Value)
+		}
+	}
+	return nil
+}
+
+func (l *LinkStateLinkDescriptor) String() string {
+	if l.LocalIP != nil || l.RemoteIP != nil {
+		return fmt.Sprintf("%s>%s", evpnIPStr(l.LocalIP), evpnIPStr(l.RemoteIP))
+	}
+	return fmt.Sprintf("%d>%d", l.LocalId, l.RemoteId)
+}
+
+type LinkStatePrefixDescriptor struct {
+	RouteType uint8
+	Prefix    net.IP
+	Length    uint8
+}
+
+func (p *LinkStatePrefixDescriptor) clone() LinkStatePrefixDescriptor {
+	x := *p
+	x.Prefix = linkStateCloneIP(p.Prefix)
+	return x
+}
+
+func (p *LinkStatePrefixDescriptor) tlvs(nlriType uint16) []LinkStateTLV {
+	tlvs := make([]LinkStateTLV, 0, 2)
+	if p.RouteType != 0 {
+		tlvs = append(tlvs, newLinkStateTLV(LinkStateTLVOSPFRouteType, []byte{p.RouteType}))
+	}
+
+	prefix := p.Prefix.To16()
+	if nlriType == LinkStateNLRITypeIPv4Prefix {
+		prefix = p.Prefix.To4()
+	}
+	reach := make([]byte, 0, 1+(int(p.Length)+7)/8)
+	reach = append(reach, p.Length)
+	reach = append(reach, prefix[:(int(p.Length)+7)/8]...)
+	tlvs = append(tlvs, newLinkStateTLV(LinkStateTLVIPReachability, reach))
+	return tlvs
+}
+
+func (p *LinkStatePrefixDescriptor) decodeTLV(tlv LinkStateTLV, nlriType uint16) error {
+	switch tlv.Type {
+	case LinkStateTLVOSPFRouteType:
+		if len(tlv.Value) != 1 {
+			return linkStateTLVError(tlv.Type, len(tlv.Value))
+		}
+		p.RouteType = tlv.Value[0]
+
+	case LinkStateTLVIPReachability:
+		ipLen := net.IPv6len
+		if nlriType == LinkStateNLRITypeIPv4Prefix {
+			ipLen = net.IPv4len
+		}
+		if len(tlv.Value) < 1 || int(tlv.Value[0]) > ipLen*8 || len(tlv.Value) != 1+(int(tlv.Value[0])+7)/8 {
+			return linkStateTLVError(tlv.Type, len(tlv.Value))
+		}
+		p.Length = tlv.Value[0]
+		prefix := make(net.IP, ipLen)
+		copy(prefix, tlv.Value[1:])
+		p.Prefix = prefix.Mask(net.CIDRMask(int(p.Length), ipLen*8))
+	}
+	return nil
+}
+
+func (p *LinkStatePrefixDescriptor) String() string {
+	return fmt.Sprintf("%s/%d", evpnIPStr(p.Prefix), p.Length)
+}
+
+type LinkStateNLRI struct {
+	NLRIType   uint16
+	ProtocolId uint8
+	Identifier uint64
+	LocalNode  LinkStateNodeDescriptor
+	RemoteNode LinkStateNodeDescriptor
+	Link       LinkStateLinkDescriptor
+	Prefix     LinkStatePrefixDescriptor
+	Value      []byte
+	Malformed  bool
+}
+
+func (n *LinkStateNLRI) Clone() NLRI {
+	x := *n
+	x.LocalNode = n.LocalNode.clone()
+	x.RemoteNode = n.RemoteNode.clone()
+	x.Link = n.Link.clone()
+	x.Prefix = n.Prefix.clone()
+	if n.Value != nil {
+		x.Value = make([]byte, len(n.Value))
+		copy(x.Value, n.Value)
+	}
+	return &x
+}
+
+func (n *LinkStateNLRI) isKnownType() bool {
+	_, ok := LinkStateNLRITypeToStrMap[n.NLRIType]
+	return ok
+}
+
+// Value holds the NLRI as received so that TLVs which are not decoded are sent unchanged
+func (n *LinkStateNLRI) encodeValue() []byte {
+	if n.Value != nil || !n.isKnownType() {
+		return n.Value
+	}
+
+	pkt := make([]byte, 9)
+	pkt[0] = n.ProtocolId
+	binary.BigEndian.PutUint64(pkt[1:], n.Identifier)
+	tlvs := []LinkStateTLV{newLinkStateTLV(LinkStateTLVLocalNode, n.LocalNode.encode())}
+	switch n.NLRIType {
+	case LinkStateNLRITypeLink:
+		tlvs = append(tlvs, newLinkStateTLV(LinkStateTLVRemoteNode, n.RemoteNode.encode()))
+		tlvs = append(tlvs, n.Link.tlvs()...)
+	case LinkStateNLRITypeIPv4Prefix, LinkStateNLRITypeIPv6Prefix:
+		tlvs = append(tlvs, n.Prefix.tlvs(n.NLRIType)...)
+	}
+	return append(pkt, encodeLinkStateTLVs(tlvs)...)
+}
+
+func (n *LinkStateNLRI) Encode(afi AFI) ([]byte, error) {
+	value := n.encodeValue()
+	pkt := make([]byte, 4, 4+len(value))
+	binary.BigEndian.PutUint16(pkt[0:], n.NLRIType)
+	binary.BigEndian.PutUint16(pkt[2:], uint16(len(value)))
+	return append(pkt, value...), nil
+}
+
+func (n *LinkStateNLRI) Decode(pkt []byte, afi AFI) error {
+	if len(pkt) < 4 {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			"Link-State NLRI does not contain NLRI type and length"}
+	}
+
+	n.NLRIType = binary.BigEndian.Uint16(pkt[0:])
+	length := int(binary.BigEndian.Uint16(pkt[2:]))
+	if len(pkt) < 4+length {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("Link-State NLRI length %d is more than the available data", length)}
+	}
+
+	n.Value = make([]byte, length)
+	copy(n.Value, pkt[4:4+length])
+	if !n.isKnownType() {
+		return nil
+	}
+
+	// The NLRI length is valid, the NLRI is marked as malformed so that it is treated as withdrawn instead of
+	// resetting the session
+	if err := n.decodeValue(); err != nil {
+		n.Malformed = true
+	}
+	return nil
+}
+
+func (n *LinkStateNLRI) decodeValue() error {
+	length := len(n.Value)
+	if length < 9 {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("Link-State NLRI length %d is less than 9", length)}
+	}
+	n.ProtocolId = n.Value[0]
+	n.Identifier = binary.BigEndian.Uint64(n.Value[1:])
+
+	tlvs, err := decodeLinkStateTLVs(n.Value[9:])
+	if err != nil {
+		return err
+	}
+
+	localNode := false
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case LinkStateTLVLocalNode:
+			localNode = true
+			err = n.LocalNode.decode(tlv.Value, n.ProtocolId)
+		case LinkStateTLVRemoteNode:
+			err = n.RemoteNode.decode(tlv.Value, n.ProtocolId)
+		case LinkStateTLVOSPFRouteType, LinkStateTLVIPReachability:
+			err = n.Prefix.decodeTLV(tlv, n.NLRIType)
+		default:
+			err = n.Link.decodeTLV(tlv)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if !localNode {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			"Link-State NLRI does not contain local node descriptors"}
+	}
+	return nil
+}
+
+// RemoveMalformedLinkStateNLRI removes the Link-State NLRIs that could not be decoded from the MP_REACH_NLRI
+// attribute and returns them. They are treated as withdrawn as described in RFC 7752 section 8.2.2.
+func RemoveMalformedLinkStateNLRI(mpReach *BGPPathAttrMPReachNLRI) []NLRI {
+	malformed := make([]NLRI, 0)
+	if mpReach.SAFI != SafiLinkState {
+		return malformed
+	}
+
+	nlris := make([]NLRI, 0, len(mpReach.NLRI))
+	for _, nlri := range mpReach.NLRI {
+		if lsNLRI, ok := nlri.(*LinkStateNLRI); ok && lsNLRI.Malformed {
+			malformed = append(malformed, nlri)
+		} else {
+			nlris = append(nlris, nlri)
+		}
+	}
+	mpReach.NLRI = nlris
+	return malformed
+}
+
+func (n *LinkStateNLRI) Len() uint32 {
+	return 4 + uint32(len(n.encodeValue()))
+}
+
+func (n *LinkStateNLRI) GetIPPrefix() *IPPrefix {
+	return NewIPPrefix(n.GetPrefix(), n.GetLength())
+}
+
+func (n *LinkStateNLRI) GetPrefix() net.IP {
+	if n.NLRIType == LinkStateNLRITypeIPv4Prefix || n.NLRIType == LinkStateNLRITypeIPv6Prefix {
+		return n.Prefix.Prefix
+	}
+	return n.LocalNode.RouterId
+}
+
+func (n *LinkStateNLRI) GetLength() uint8 {
+	if n.NLRIType == LinkStateNLRITypeIPv4Prefix || n.NLRIType == LinkStateNLRITypeIPv6Prefix {
+		return n.Prefix.Length
+	}
+	return 0
+}
+
+func (n *LinkStateNLRI) GetPathId() uint32 {
+	return 0
+}
+
+func (n *LinkStateNLRI) GetCIDR() string {
+	typeStr, ok := LinkStateNLRITypeToStrMap[n.NLRIType]
+	if !ok {
+		return fmt.Sprintf("[%d]:[%s]", n.NLRIType, hex.EncodeToString(n.Value))
+	}
+
+	protoStr, ok := LinkStateProtoToStrMap[n.ProtocolId]
+	if !ok {
+		protoStr = fmt.Sprintf("%d", n.ProtocolId)
+	}
+	strList := []string{typeStr, protoStr, fmt.Sprintf("%d", n.Identifier), n.LocalNode.String()}
+	switch n.NLRIType {
+	case LinkStateNLRITypeLink:
+		strList = append(strList, n.RemoteNode.String(), n.Link.String())
+	case LinkStateNLRITypeIPv4Prefix, LinkStateNLRITypeIPv6Prefix:
+		strList = append(strList, n.Prefix.String())
+	}
+	return "[" + strings.Join(strList, "]:[") + "]"
+}
+
+func (n *LinkStateNLRI) String() string {
+	return "{" + n.GetCIDR() + "}"
+}
+
+func NewLinkStateNodeNLRI(protoId uint8, node *LinkStateNodeDescriptor) *LinkStateNLRI {
+	return &LinkStateNLRI{
+		NLRIType:   LinkStateNLRITypeNode,
+		ProtocolId: protoId,
+		LocalNode:  node.clone(),
+	}
+}
+
+func NewLinkStateLinkNLRI(protoId uint8, local, remote *LinkStateNodeDescriptor,
+	link *LinkStateLinkDescriptor) *LinkStateNLRI {
+	return &LinkStateNLRI{
+		NLRIType:   LinkStateNLRITypeLink,
+		ProtocolId: protoId,
+		LocalNode:  local.clone(),
+		RemoteNode: remote.clone(),
+		Link:       link.clone(),
+	}
+}
+
+func NewLinkStatePrefixNLRI(protoId uint8, node *LinkStateNodeDescriptor,
+	prefix *LinkStatePrefixDescriptor) *LinkStateNLRI {
+	nlriType := LinkStateNLRITypeIPv6Prefix
+	if prefix.Prefix.To4() != nil {
+		nlriType = LinkStateNLRITypeIPv4Prefix
+	}
+	return &LinkStateNLRI{
+		NLRIType:   nlriType,
+		ProtocolId: protoId,
+		LocalNode:  node.clone(),
+		Prefix:     prefix.clone(),
+	}
+}
+
+// BGPPathAttrLinkState is the BGP-LS attribute carrying the node, link and prefix properties
+type BGPPathAttrLinkState struct {
+	BGPPathAttrBase
+	TLVs []LinkStateTLV
+}
+
+func (l *BGPPathAttrLinkState) Clone() BGPPathAttr {
+	x := *l
+	x.BGPPathAttrBase = l.BGPPathAttrBase.Clone()
+	x.TLVs = make([]LinkStateTLV, len(l.TLVs))
+	for idx, tlv := range l.TLVs {
+		x.TLVs[idx] = newLinkStateTLV(tlv.Type, append([]byte(nil), tlv.Value...))
+	}
+	return &x
+}
+
+func (l *BGPPathAttrLinkState) Encode() ([]byte, error) {
+	pkt, err := l.BGPPathAttrBase.Encode()
+	if err != nil {
+		return pkt, err
+	}
+
+	copy(pkt[l.BGPPathAttrLen:], encodeLinkStateTLVs(l.TLVs))
+	return pkt, nil
+}
+
+func (l *BGPPathAttrLinkState) Decode(pkt []byte, data interface{}) error {
+	err := l.BGPPathAttrBase.Decode(pkt, data)
+	if err != nil {
+		return err
+	}
+
+	l.TLVs, err = decodeLinkStateTLVs(pkt[l.BGPPathAttrLen:l.TotalLen()])
+	if err != nil {
+		return BGPMessageError{BGPUpdateMsgError, BGPOptionalAttrError, pkt[:l.TotalLen()],
+			"BGP-LS attribute TLVs are malformed"}
+	}
+	return nil
+}
+
+func (l *BGPPathAttrLinkState) New() BGPPathAttr {
+	return &BGPPathAttrLinkState{}
+}
+
+func (l *BGPPathAttrLinkState) String() string {
+	strList := make([]string, 0, len(l.TLVs))
+	for _, tlv := range l.TLVs {
+		strList = append(strList, tlv.String())
+	}
+	return fmt.Sprintf("{BGP_LS %s}", strings.Join(strList, " "))
+}
+
+func (l *BGPPathAttrLinkState) GetTLV(tlvType uint16) []byte {
+	for _, tlv := range l.TLVs {
+		if tlv.Type == tlvType {
+			return tlv.Value
+		}
+	}
+	return nil
+}
+
+func NewBGPPathAttrLinkState(tlvs []LinkStateTLV) *BGPPathAttrLinkState {
+	attr := &BGPPathAttrLinkState{
+		BGPPathAttrBase: BGPPathAttrBase{
+			Flags:          BGPPathAttrFlagOptional,
+			Code:           BGPPathAttrTypeLinkState,
+			BGPPathAttrLen: 3,
+		},
+		TLVs: tlvs,
+	}
+	length := 0
+	for _, tlv := range tlvs {
+		length += tlv.Len()
+	}
+	attr.setValueLength(uint16(length))
+	return attr
+}
+
+func GetLinkStateAttr(pathAttrs []BGPPathAttr) *BGPPathAttrLinkState {
+	if attr := getTypeFromPathAttrs(pathAttrs, BGPPathAttrTypeLinkState); attr != nil {
+		return attr.(*BGPPathAttrLinkState)
+	}
+	return nil
+}
+
+func NewLinkStateNodeFlagsTLV(flags uint8) LinkStateTLV {
+	return newLinkStateTLV(LinkStateAttrNodeFlags, []byte{flags})
+}
+
+func NewLinkStateNodeNameTLV(name string) LinkStateTLV {
+	return newLinkStateTLV(LinkStateAttrNodeName, []byte(name))
+}
+
+func NewLinkStateRouterIdTLV(tlvType uint16, routerId net.IP) LinkStateTLV {
+	return newLinkStateTLV(tlvType, []byte(routerId.To4()))
+}
+
+// OSPF metrics are 16 bits, IGP metric TLV uses the shortest length that can carry the metric
+func NewLinkStateIGPMetricTLV(metric uint32) LinkStateTLV {
+	if metric > 0xFFFF {
+		return newLinkStateTLV(LinkStateAttrIGPMetric, []byte{uint8(metric >> 16), uint8(metric >> 8),
+			uint8(metric)})
+	}
+	return newLinkStateTLV(LinkStateAttrIGPMetric, []byte{uint8(metric >> 8), uint8(metric)})
+}
+
+func NewLinkStatePrefixMetricTLV(metric uint32) LinkStateTLV {
+	return newLinkStateUint32TLV(LinkStateAttrPrefixMetric, metric)
+}
@@ -0,0 +1,267 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// linkstate_test.go
+package packet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+)
+
+func TestLinkStateNLRIDecode(t *testing.T) {
+	pkts := map[string]string{
+		// OSPFv2 node 10.0.0.1 in area 0
+		"0001001d03" + "0000000000000000" + "01000010" + "0202000400000000" + "020300040a000001": "[node]:[ospfv2]:[0]:[as:0,area:0.0.0.0,router:10.0.0.1]",
+		// OSPFv2 intra area prefix 10.1.1.0/24 advertised by 10.0.0.1
+		"0003002a03" + "0000000000000000" + "01000010" + "0202000400000000" + "020300040a000001" + "0108000101" +
+			"01090004180a0101": "[ipv4-prefix]:[ospfv2]:[0]:[as:0,area:0.0.0.0,router:10.0.0.1]:[10.1.1.0/24]",
+		// IS-IS level 2 node 1921.6800.1001
+		"0001001f02" + "0000000000000000" + "01000012" + "0202000400000000" + "02030006192168001001": "[node]:[isis-l2]:[0]:[as:0,area:0.0.0.0,router:1921.6800.1001.00]",
+		// IS-IS level 1 pseudonode 1921.6800.1001.03
+		"0001002001" + "0000000000000000" + "01000013" + "0202000400000000" + "0203000719216800100103": "[node]:[isis-l1]:[0]:[as:0,area:0.0.0.0,router:1921.6800.1001.03]",
+		// Unknown NLRI type is kept as it is
+		"00090002abcd": "[9]:[abcd]",
+	}
+
+	for strPkt, expected := range pkts {
+		pkt, _ := hex.DecodeString(strPkt)
+		nlri := &LinkStateNLRI{}
+		err := nlri.Decode(pkt, AfiLinkState)
+		if err != nil {
+			t.Fatal("Link-State NLRI decode for", strPkt, "failed with error:", err)
+		}
+
+		if nlri.Len() != uint32(len(pkt)) {
+			t.Fatal("Link-State NLRI length mismatch for", strPkt, "expected:", len(pkt), "got:", nlri.Len())
+		}
+
+		if nlri.GetCIDR() != expected {
+			t.Fatal("Link-State NLRI mismatch, expected:", expected, "got:", nlri.GetCIDR())
+		}
+
+		encoded, err := nlri.Encode(AfiLinkState)
+		if err != nil {
+			t.Fatal("Link-State NLRI encode for", expected, "failed with error:", err)
+		}
+		if !bytes.Equal(encoded, pkt) {
+			t.Fatalf("Link-State NLRI encode mismatch, expected: %x got: %x", pkt, encoded)
+		}
+	}
+}
+
+func TestLinkStateNLRIBadPackets(t *testing.T) {
+	pkts := []string{
+		"00",
+		"0001",
+		"0001001d0300000000",
+	}
+
+	for _, strPkt := range pkts {
+		pkt, _ := hex.DecodeString(strPkt)
+		nlri := &LinkStateNLRI{}
+		err := nlri.Decode(pkt, AfiLinkState)
+		if err == nil {
+			t.Fatal("Link-State NLRI decode called for", strPkt, "expected failure, got NO errors")
+		} else {
+			t.Log("Link-State NLRI decode called for", strPkt, "expected failure, got error:", err)
+		}
+	}
+}
+
+func TestLinkStateNLRIMalformed(t *testing.T) {
+	pkts := []string{
+		// missing local node descriptors
+		"0001000903" + "0000000000000000",
+		// bad IGP router id length
+		"0001001c03" + "0000000000000000" + "0100000f" + "0202000400000000" + "020300030a0000",
+		// OSPF router id length used for IS-IS
+		"0001001d02" + "0000000000000000" + "01000010" + "0202000400000000" + "020300040a000001",
+		// prefix length longer than the address
+		"0003002a03" + "0000000000000000" + "01000010" + "0202000400000000" + "020300040a000001" + "0108000101" +
+			"01090004210a0101",
+	}
+
+	for _, strPkt := range pkts {
+		pkt, _ := hex.DecodeString(strPkt)
+		nlri := &LinkStateNLRI{}
+		err := nlri.Decode(pkt, AfiLinkState)
+		if err != nil {
+			t.Fatal("Link-State NLRI decode for", strPkt, "failed with error:", err)
+		}
+		if !nlri.Malformed {
+			t.Fatal("Link-State NLRI decode for", strPkt, "expected malformed NLRI, got:", nlri)
+		}
+		if nlri.Len() != uint32(len(pkt)) {
+			t.Fatal("Link-State NLRI length mismatch for", strPkt, "expected:", len(pkt), "got:", nlri.Len())
+		}
+	}
+}
+
+func TestLinkStateNLRIEncodeDecode(t *testing.T) {
+	local := &LinkStateNodeDescriptor{AS: 65000, AreaId: 1, RouterId: net.ParseIP("10.0.0.1")}
+	remote := &LinkStateNodeDescriptor{AS: 65000, AreaId: 1, RouterId: net.ParseIP("10.0.0.2")}
+	pseudo := &LinkStateNodeDescriptor{AS: 65000, AreaId: 1, RouterId: net.ParseIP("10.0.0.2"),
+		DRIP: net.ParseIP("10.1.1.2")}
+	isisNode := &LinkStateNodeDescriptor{AS: 65000, SystemId: []byte{0x19, 0x21, 0x68, 0x00, 0x10, 0x01}}
+	isisPseudo := &LinkStateNodeDescriptor{AS: 65000, SystemId: []byte{0x19, 0x21, 0x68, 0x00, 0x10, 0x02},
+		PSNId: 1}
+	nlris := []*LinkStateNLRI{
+		NewLinkStateNodeNLRI(LinkStateProtoOSPFv2, local),
+		NewLinkStateNodeNLRI(LinkStateProtoOSPFv2, pseudo),
+		NewLinkStateLinkNLRI(LinkStateProtoOSPFv2, local, remote, &LinkStateLinkDescriptor{
+			LocalIP: net.ParseIP("10.2.2.1"), RemoteIP: net.ParseIP("10.2.2.2")}),
+		NewLinkStateLinkNLRI(LinkStateProtoOSPFv2, local, pseudo, &LinkStateLinkDescriptor{
+			LocalIP: net.ParseIP("10.1.1.1")}),
+		NewLinkStateLinkNLRI(LinkStateProtoOSPFv2, local, remote, &LinkStateLinkDescriptor{LocalId: 5,
+			RemoteId: 7}),
+		NewLinkStatePrefixNLRI(LinkStateProtoOSPFv2, local, &LinkStatePrefixDescriptor{
+			RouteType: OSPFRouteTypeExternal2, Prefix: net.ParseIP("192.168.0.0"), Length: 16}),
+		NewLinkStatePrefixNLRI(LinkStateProtoOSPFv3, local, &LinkStatePrefixDescriptor{
+			RouteType: OSPFRouteTypeIntraArea, Prefix: net.ParseIP("2001:db8::"), Length: 32}),
+		NewLinkStateLinkNLRI(LinkStateProtoISISL2, isisNode, isisPseudo, &LinkStateLinkDescriptor{
+			LocalIP: net.ParseIP("10.3.3.1")}),
+	}
+
+	for _, nlri := range nlris {
+		pkt, err := nlri.Encode(AfiLinkState)
+		if err != nil {
+			t.Fatal("Link-State NLRI encode for", nlri, "failed with error:", err)
+		}
+		if uint32(len(pkt)) != nlri.Len() {
+			t.Fatal("Link-State NLRI length mismatch for", nlri, "expected:", nlri.Len(), "got:", len(pkt))
+		}
+
+		newNLRI := &LinkStateNLRI{}
+		if err = newNLRI.Decode(pkt, AfiLinkState); err != nil {
+			t.Fatal("Link-State NLRI decode for", nlri, "failed with error:", err)
+		}
+		if newNLRI.GetCIDR() != nlri.GetCIDR() {
+			t.Fatal("Link-State NLRI mismatch, expected:", nlri.GetCIDR(), "got:", newNLRI.GetCIDR())
+		}
+		if newNLRI.Clone().GetCIDR() != nlri.GetCIDR() {
+			t.Fatal("Link-State NLRI clone mismatch, expected:", nlri.GetCIDR(), "got:",
+				newNLRI.Clone().GetCIDR())
+		}
+	}
+
+	if !nlris[1].LocalNode.IsPseudonode() || nlris[0].LocalNode.IsPseudonode() {
+		t.Fatal("Link-State pseudonode mismatch for", nlris[0], nlris[1])
+	}
+	if !nlris[7].RemoteNode.IsPseudonode() || nlris[7].LocalNode.IsPseudonode() {
+		t.Fatal("Link-State IS-IS pseudonode mismatch for", nlris[7])
+	}
+	if nlris[5].NLRIType != LinkStateNLRITypeIPv4Prefix || nlris[6].NLRIType != LinkStateNLRITypeIPv6Prefix {
+		t.Fatal("Link-State prefix NLRI type mismatch for", nlris[5], nlris[6])
+	}
+	if nlris[5].GetIPPrefix().GetCIDR() != "192.168.0.0/16" {
+		t.Fatal("Link-State prefix mismatch, expected 192.168.0.0/16 got:", nlris[5].GetIPPrefix().GetCIDR())
+	}
+}
+
+func TestMPReachNLRILinkStateDecode(t *testing.T) {
+	hexPkt, _ := hex.DecodeString("800e2a40044704" + "0a000001" + "00" + "0001001d03" + "0000000000000000" + "01000010" +
+		"0202000400000000" + "020300040a000001")
+	mpReach := NewBGPPathAttrMPReachNLRI()
+	peerAttrs := BGPPeerAttrs{
+		ASSize:           4,
+		AddPathsRxActual: false,
+	}
+	err := mpReach.Decode(hexPkt, peerAttrs)
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI decode for Link-State failed with error:", err)
+	}
+
+	if !mpReach.NextHop.GetNextHop().Equal(net.ParseIP("10.0.0.1")) || len(mpReach.NLRI) != 1 {
+		t.Fatal("BGP MPReachNLRI decode for Link-State, expected next hop 10.0.0.1 and 1 NLRI, got:",
+			mpReach.NextHop, mpReach.NLRI)
+	}
+	if _, ok := mpReach.NLRI[0].(*LinkStateNLRI); !ok {
+		t.Fatal("BGP MPReachNLRI decode for Link-State, expected Link-State NLRI, got:", mpReach.NLRI[0])
+	}
+
+	pkt, err := mpReach.Encode()
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI encode for Link-State failed with error:", err)
+	}
+	if !bytes.Equal(pkt, hexPkt) {
+		t.Fatalf("BGP MPReachNLRI encode for Link-State mismatch, expected: %x got: %x", hexPkt, pkt)
+	}
+}
+
+func TestRemoveMalformedLinkStateNLRI(t *testing.T) {
+	// The second NLRI has an IGP router id of 3 bytes
+	hexPkt, _ := hex.DecodeString("800e4a4004470400000000" + "00" + "0001001d03" + "0000000000000000" + "01000010" +
+		"0202000400000000" + "020300040a000001" + "0001001c03" + "0000000000000000" + "0100000f" + "0202000400000000" +
+		"020300030a0000")
+	mpReach := NewBGPPathAttrMPReachNLRI()
+	err := mpReach.Decode(hexPkt, BGPPeerAttrs{ASSize: 4})
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI decode with malformed Link-State NLRI failed with error:", err)
+	}
+	if len(mpReach.NLRI) != 2 {
+		t.Fatal("BGP MPReachNLRI decode with malformed Link-State NLRI, expected 2 NLRI, got:", mpReach.NLRI)
+	}
+
+	malformed := RemoveMalformedLinkStateNLRI(mpReach)
+	if len(malformed) != 1 || !malformed[0].(*LinkStateNLRI).Malformed {
+		t.Fatal("Expected 1 malformed Link-State NLRI, got:", malformed)
+	}
+	if len(mpReach.NLRI) != 1 || mpReach.NLRI[0].(*LinkStateNLRI).Malformed {
+		t.Fatal("Expected 1 valid Link-State NLRI in MP_REACH_NLRI, got:", mpReach.NLRI)
+	}
+}
+
+func TestLinkStateAttrEncodeDecode(t *testing.T) {
+	attr := NewBGPPathAttrLinkState([]LinkStateTLV{
+		NewLinkStateNodeFlagsTLV(LinkStateNodeFlagABR),
+		NewLinkStateRouterIdTLV(LinkStateAttrLocalRouterId, net.ParseIP("10.0.0.1")),
+		NewLinkStateIGPMetricTLV(10),
+	})
+	pkt, err := attr.Encode()
+	if err != nil {
+		t.Fatal("BGP-LS attribute encode failed with error:", err)
+	}
+
+	expected, _ := hex.DecodeString("801d13" + "0400000110" + "040400040a000001" + "04470002000a")
+	if !bytes.Equal(pkt, expected) {
+		t.Fatalf("BGP-LS attribute encode mismatch, expected: %x got: %x", expected, pkt)
+	}
+
+	newAttr := BGPGetPathAttr(pkt)
+	if err = newAttr.Decode(pkt, BGPPeerAttrs{ASSize: 4}); err != nil {
+		t.Fatal("BGP-LS attribute decode failed with error:", err)
+	}
+	decoded := GetLinkStateAttr([]BGPPathAttr{newAttr})
+	if decoded == nil || len(decoded.TLVs) != 3 || decoded.GetTLV(LinkStateAttrNodeFlags)[0] != LinkStateNodeFlagABR ||
+		!net.IP(decoded.GetTLV(LinkStateAttrLocalRouterId)).Equal(net.ParseIP("10.0.0.1").To4()) {
+		t.Fatal("BGP-LS attribute decode mismatch, got:", newAttr)
+	}
+
+	bad, _ := hex.DecodeString("801d050400000210")
+	if err = BGPGetPathAttr(bad).Decode(bad, BGPPeerAttrs{ASSize: 4}); err == nil {
+		t.Fatal("BGP-LS attribute decode called for", bad, "expected failure, got NO errors")
+	}
+}
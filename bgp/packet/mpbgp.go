@@ -34,8 +34,10 @@ import (
 )
 
 var BGPAFIToStructMap = map[AFI]MPNextHop{
-	AfiIP:  &MPNextHopIP{},
-	AfiIP6: &MPNextHopIP6{},
+	AfiIP:        &MPNextHopIP{},
+	AfiIP6:       &MPNextHopIP6{},
+	AfiL2VPN:     &MPNextHopIP{},
+	AfiLinkState: &MPNextHopIP{},
 }
 
 type MPNextHop interface {
@@ -180,6 +182,14 @@ func (i *MPNextHopIP6) String() string {
 	return fmt.Sprintf("{NEXTHOP %v}", i.Value)
 }
 
+// Link local next hop is used when the peer doesn't send a global next hop
+func (i *MPNextHopIP6) GetNextHop() net.IP {
+	if (i.Value == nil || i.Value.IsUnspecified()) && i.LinkLocal != nil {
+		return i.LinkLocal
+	}
+	return i.Value
+}
+
 func (i *MPNextHopIP6) SetGlobalNextHop(ip net.IP) error {
 	if len(ip) != 16 {
 		return errors.New(fmt.Sprintf("IPv6 next hop address is not 16 bytes, length =%d", len(ip)))
@@ -347,6 +357,14 @@ func (r *BGPPathAttrMPReachNLRI) Decode(pkt []byte, data interface{}) error {
 	idx += 3
 
 	nextHop := BGPGetMPNextHop(r.AFI)
+	if r.SAFI == SafiFlowSpec {
+		nextHop = NewMPNextHopUnknown()
+	} else if r.SAFI == SafiMPLSVPN {
+		nextHop = NewMPNextHopVPN()
+	} else if r.AFI == AfiIP && pkt[idx] >= net.IPv6len {
+		// IPv4 NLRI with IPv6 next hop, RFC 8950
+		nextHop = NewMPNextHopIP6()
+	}
 	nextHop.Decode(pkt[idx:])
 	r.NextHop = nextHop
 	idx += int(nextHop.Len())
@@ -156,6 +156,33 @@ func TestMPReachNLRIDecode(t *testing.T) {
 	}
 }
 
+func TestMPReachNLRIIPv4WithIPv6NextHop(t *testing.T) {
+	protoFamily := GetProtocolFamily(AfiIP, SafiUnicast)
+	nlri := NewIPPrefix(net.ParseIP("10.1.0.0"), 16)
+	mpReach := ConstructIPv6MPReachNLRI(protoFamily, net.IPv6zero, net.ParseIP("fe80::1"), []NLRI{nlri})
+	pkt, err := mpReach.Encode()
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI encode failed with error:", err)
+	}
+
+	decoded := NewBGPPathAttrMPReachNLRI()
+	err = decoded.Decode(pkt, BGPPeerAttrs{ASSize: 4})
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI decode failed with error:", err)
+	}
+
+	if decoded.AFI != AfiIP || decoded.SAFI != SafiUnicast || len(decoded.NLRI) != 1 {
+		t.Fatalf("BGP MPReachNLRI decode - got %+v", decoded)
+	}
+	nextHop, ok := decoded.NextHop.(*MPNextHopIP6)
+	if !ok {
+		t.Fatalf("BGP MPReachNLRI decode - expected IPv6 next hop, got %+v", decoded.NextHop)
+	}
+	if !nextHop.GetNextHop().Equal(net.ParseIP("fe80::1")) {
+		t.Fatal("BGP MPReachNLRI decode - expected link local next hop fe80::1, got", nextHop.GetNextHop())
+	}
+}
+
 func TestMPUnreachNLRIDecode(t *testing.T) {
 	packets := make([]string, 0)
 	packets = append(packets, "800F13000102000000011814010A000000020A0A01")
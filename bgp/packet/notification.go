@@ -0,0 +1,125 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// notification.go
+package packet
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Cease NOTIFICATION subcodes (RFC 4486)
+const (
+	_ uint8 = iota
+	BGPCeaseMaxPrefixes
+	BGPCeaseAdminShutdown
+	BGPCeasePeerDeconfigured
+	BGPCeaseAdminReset
+	BGPCeaseConnRejected
+	BGPCeaseOtherConfigChange
+	BGPCeaseConnCollision
+	BGPCeaseOutOfResources
+)
+
+const BGPShutdownCommunicationMaxLen = 128
+
+var BGPErrorCodeToStrMap = map[uint8]string{
+	BGPMsgHeaderError:   "Message Header Error",
+	BGPOpenMsgError:     "OPEN Message Error",
+	BGPUpdateMsgError:   "UPDATE Message Error",
+	BGPHoldTimerExpired: "Hold Timer Expired",
+	BGPFSMError:         "Finite State Machine Error",
+	BGPCease:            "Cease",
+}
+
+var BGPCeaseSubcodeToStrMap = map[uint8]string{
+	BGPCeaseMaxPrefixes:       "Maximum Number of Prefixes Reached",
+	BGPCeaseAdminShutdown:     "Administrative Shutdown",
+	BGPCeasePeerDeconfigured:  "Peer De-configured",
+	BGPCeaseAdminReset:        "Administrative Reset",
+	BGPCeaseConnRejected:      "Connection Rejected",
+	BGPCeaseOtherConfigChange: "Other Configuration Change",
+	BGPCeaseConnCollision:     "Connection Collision Resolution",
+	BGPCeaseOutOfResources:    "Out of Resources",
+}
+
+// NewBGPShutdownCommunication encodes the shutdown communication sent in the data of the Cease NOTIFICATION with
+// Administrative Shutdown or Administrative Reset subcode (RFC 8203). Messages longer than the max length are
+// truncated at a UTF-8 character boundary.
+func NewBGPShutdownCommunication(msg string) []byte {
+	if msg == "" {
+		return nil
+	}
+
+	for len(msg) > BGPShutdownCommunicationMaxLen {
+		_, size := utf8.DecodeLastRuneInString(msg)
+		msg = msg[:len(msg)-size]
+	}
+
+	data := make([]byte, 1, len(msg)+1)
+	data[0] = uint8(len(msg))
+	return append(data, msg...)
+}
+
+func (msg *BGPNotification) HasShutdownCommunication() bool {
+	return msg.ErrorCode == BGPCease && (msg.ErrorSubcode == BGPCeaseAdminShutdown ||
+		msg.ErrorSubcode == BGPCeaseAdminReset) && len(msg.Data) > 0
+}
+
+// GetShutdownCommunication decodes the shutdown communication in the Cease NOTIFICATION
+func (msg *BGPNotification) GetShutdownCommunication() (string, error) {
+	if !msg.HasShutdownCommunication() {
+		return "", nil
+	}
+
+	length := int(msg.Data[0])
+	if length > BGPShutdownCommunicationMaxLen || length+1 > len(msg.Data) {
+		return "", errors.New(fmt.Sprintf("Shutdown communication length %d not valid, data length %d", length,
+			len(msg.Data)))
+	}
+
+	communication := msg.Data[1 : length+1]
+	if !utf8.Valid(communication) {
+		return "", errors.New("Shutdown communication is not valid UTF-8")
+	}
+	return string(communication), nil
+}
+
+func (msg *BGPNotification) String() string {
+	codeStr, ok := BGPErrorCodeToStrMap[msg.ErrorCode]
+	if !ok {
+		codeStr = fmt.Sprintf("Error code %d", msg.ErrorCode)
+	}
+
+	if msg.ErrorCode == BGPCease {
+		if subcodeStr, ok := BGPCeaseSubcodeToStrMap[msg.ErrorSubcode]; ok {
+			return codeStr + "/" + subcodeStr
+		}
+	}
+	if msg.ErrorSubcode == 0 {
+		return codeStr
+	}
+	return fmt.Sprintf("%s/subcode %d", codeStr, msg.ErrorSubcode)
+}
@@ -0,0 +1,93 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// notification_test.go
+package packet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBGPShutdownCommunicationEncodeDecode(t *testing.T) {
+	reason := "Planned maintenance, back in 10 minutes"
+	notification := &BGPNotification{BGPCease, BGPCeaseAdminShutdown, NewBGPShutdownCommunication(reason)}
+	pkt, err := notification.Encode()
+	if err != nil {
+		t.Fatal("BGPNotification.Encode failed with error:", err)
+	}
+	if len(pkt) != len(reason)+3 || pkt[2] != uint8(len(reason)) {
+		t.Fatal("BGPNotification.Encode returned unexpected packet:", pkt)
+	}
+
+	decoded := &BGPNotification{}
+	decoded.Decode(nil, pkt, nil)
+	msg, err := decoded.GetShutdownCommunication()
+	if err != nil {
+		t.Fatal("BGPNotification.GetShutdownCommunication failed with error:", err)
+	}
+	if msg != reason {
+		t.Fatal("BGPNotification.GetShutdownCommunication returned", msg, "expected", reason)
+	}
+	if decoded.String() != "Cease/Administrative Shutdown" {
+		t.Fatal("BGPNotification.String returned unexpected string:", decoded.String())
+	}
+
+	decoded.ErrorSubcode = BGPCeaseAdminReset
+	if msg, _ = decoded.GetShutdownCommunication(); msg != reason {
+		t.Fatal("BGPNotification.GetShutdownCommunication failed for Administrative Reset, message:", msg)
+	}
+	decoded.ErrorSubcode = BGPCeasePeerDeconfigured
+	if msg, _ = decoded.GetShutdownCommunication(); msg != "" {
+		t.Fatal("BGPNotification.GetShutdownCommunication returned message for subcode", decoded.ErrorSubcode)
+	}
+}
+
+func TestBGPShutdownCommunicationTruncate(t *testing.T) {
+	data := NewBGPShutdownCommunication(strings.Repeat("é", BGPShutdownCommunicationMaxLen))
+	if len(data) != BGPShutdownCommunicationMaxLen+1 || data[0] != BGPShutdownCommunicationMaxLen {
+		t.Fatal("NewBGPShutdownCommunication did not truncate the message, length:", len(data))
+	}
+
+	notification := &BGPNotification{BGPCease, BGPCeaseAdminShutdown, data}
+	msg, err := notification.GetShutdownCommunication()
+	if err != nil || len(msg) != BGPShutdownCommunicationMaxLen {
+		t.Fatal("BGPNotification.GetShutdownCommunication failed, message:", msg, "error:", err)
+	}
+
+	if NewBGPShutdownCommunication("") != nil {
+		t.Fatal("NewBGPShutdownCommunication returned data for empty message")
+	}
+}
+
+func TestBGPShutdownCommunicationInvalid(t *testing.T) {
+	notification := &BGPNotification{BGPCease, BGPCeaseAdminShutdown, []byte{10, 'a', 'b'}}
+	if _, err := notification.GetShutdownCommunication(); err == nil {
+		t.Fatal("BGPNotification.GetShutdownCommunication did not fail for truncated data")
+	}
+
+	notification.Data = []byte{2, 0xC3, 0x28}
+	if _, err := notification.GetShutdownCommunication(); err == nil {
+		t.Fatal("BGPNotification.GetShutdownCommunication did not fail for invalid UTF-8")
+	}
+}
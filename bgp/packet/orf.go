@@ -0,0 +1,338 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// orf.go
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const (
+	ORFTypeAddressPrefix uint8 = 64
+)
+
+const (
+	ORFModeReceive uint8 = iota + 1
+	ORFModeSend
+	ORFModeBoth
+)
+
+const (
+	ORFWhenToRefreshImmediate uint8 = iota + 1
+	ORFWhenToRefreshDefer
+)
+
+const (
+	ORFActionAdd uint8 = iota
+	ORFActionRemove
+	ORFActionRemoveAll
+)
+
+const (
+	ORFMatchPermit uint8 = iota
+	ORFMatchDeny
+)
+
+const ORFPrefixEntryFixedLen = 7
+
+type ORFTypeMode struct {
+	Type uint8
+	Mode uint8
+}
+
+type ORFCapAFISAFI struct {
+	AFI  AFI
+	SAFI SAFI
+	ORFs []ORFTypeMode
+}
+
+func (o *ORFCapAFISAFI) Encode(pkt []byte) {
+	binary.BigEndian.PutUint16(pkt, uint16(o.AFI))
+	pkt[2] = 0
+	pkt[3] = uint8(o.SAFI)
+	pkt[4] = uint8(len(o.ORFs))
+	offset := 5
+	for _, orf := range o.ORFs {
+		pkt[offset] = orf.Type
+		pkt[offset+1] = orf.Mode
+		offset += 2
+	}
+}
+
+func (o *ORFCapAFISAFI) Decode(pkt []byte) error {
+	if len(pkt) < 5 {
+		return BGPMessageError{BGPOpenMsgError, BGPUnspecific, nil, "Not enough data to decode ORF capability"}
+	}
+
+	o.AFI = AFI(binary.BigEndian.Uint16(pkt))
+	o.SAFI = SAFI(pkt[3])
+	numORFs := int(pkt[4])
+	if len(pkt) < 5+(numORFs*2) {
+		return BGPMessageError{BGPOpenMsgError, BGPUnspecific, nil,
+			fmt.Sprintf("Not enough data to decode %d ORF types", numORFs)}
+	}
+
+	o.ORFs = make([]ORFTypeMode, 0, numORFs)
+	offset := 5
+	for i := 0; i < numORFs; i++ {
+		o.ORFs = append(o.ORFs, ORFTypeMode{pkt[offset], pkt[offset+1]})
+		offset += 2
+	}
+	return nil
+}
+
+func (o *ORFCapAFISAFI) Len() uint8 {
+	return uint8(5 + (len(o.ORFs) * 2))
+}
+
+type BGPCapORF struct {
+	BGPCapabilityBase
+	Value []ORFCapAFISAFI
+}
+
+func (msg *BGPCapORF) New() BGPCapability {
+	return &BGPCapORF{}
+}
+
+func (msg *BGPCapORF) Encode() ([]byte, error) {
+	pkt, err := msg.BGPCapabilityBase.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := uint8(2)
+	for _, val := range msg.Value {
+		val.Encode(pkt[offset:])
+		offset += val.Len()
+	}
+	return pkt, nil
+}
+
+func (msg *BGPCapORF) Decode(pkt []byte) error {
+	err := msg.BGPCapabilityBase.Decode(pkt)
+	if err != nil {
+		return err
+	}
+
+	msg.Value = make([]ORFCapAFISAFI, 0)
+	offset := uint16(2)
+	for offset < msg.TotalLen() {
+		orfAFISAFI := ORFCapAFISAFI{}
+		err := orfAFISAFI.Decode(pkt[offset:msg.TotalLen()])
+		if err != nil {
+			return err
+		}
+		msg.Value = append(msg.Value, orfAFISAFI)
+		offset += uint16(orfAFISAFI.Len())
+	}
+	return nil
+}
+
+func (msg *BGPCapORF) AddORF(afi AFI, safi SAFI, orfType uint8, mode uint8) {
+	for i := 0; i < len(msg.Value); i++ {
+		if msg.Value[i].AFI == afi && msg.Value[i].SAFI == safi {
+			msg.Value[i].ORFs = append(msg.Value[i].ORFs, ORFTypeMode{orfType, mode})
+			msg.Len += 2
+			return
+		}
+	}
+
+	orfAFISAFI := ORFCapAFISAFI{afi, safi, []ORFTypeMode{{orfType, mode}}}
+	msg.Value = append(msg.Value, orfAFISAFI)
+	msg.Len += orfAFISAFI.Len()
+}
+
+func NewBGPCapORF() *BGPCapORF {
+	return &BGPCapORF{
+		BGPCapabilityBase: BGPCapabilityBase{
+			Type: BGPCapTypeORF,
+			Len:  0,
+		},
+		Value: make([]ORFCapAFISAFI, 0),
+	}
+}
+
+type ORFPrefixEntry struct {
+	Action   uint8
+	Match    uint8
+	Sequence uint32
+	MinLen   uint8
+	MaxLen   uint8
+	Prefix   *IPPrefix
+}
+
+func (o *ORFPrefixEntry) Encode(afi AFI) ([]byte, error) {
+	if o.Action == ORFActionRemoveAll {
+		return []byte{o.Action << 6}, nil
+	}
+
+	if o.Prefix == nil {
+		return nil, BGPMessageError{BGPUpdateMsgError, BGPUnspecific, nil, "ORF prefix entry does not have a prefix"}
+	}
+
+	prefixBytes, err := o.Prefix.Encode(afi)
+	if err != nil {
+		return nil, err
+	}
+
+	pkt := make([]byte, ORFPrefixEntryFixedLen, ORFPrefixEntryFixedLen+len(prefixBytes))
+	pkt[0] = (o.Action << 6) | ((o.Match & 0x1) << 5)
+	binary.BigEndian.PutUint32(pkt[1:], o.Sequence)
+	pkt[5] = o.MinLen
+	pkt[6] = o.MaxLen
+	pkt = append(pkt, prefixBytes...)
+	return pkt, nil
+}
+
+func (o *ORFPrefixEntry) Decode(pkt []byte, afi AFI) (int, error) {
+	if len(pkt) < 1 {
+		return 0, BGPMessageError{BGPUpdateMsgError, BGPUnspecific, nil, "Not enough data to decode ORF entry"}
+	}
+
+	o.Action = pkt[0] >> 6
+	o.Match = (pkt[0] >> 5) & 0x1
+	if o.Action == ORFActionRemoveAll {
+		return 1, nil
+	}
+
+	if len(pkt) < ORFPrefixEntryFixedLen+1 {
+		return 0, BGPMessageError{BGPUpdateMsgError, BGPUnspecific, nil,
+			"Not enough data to decode ORF address prefix entry"}
+	}
+
+	o.Sequence = binary.BigEndian.Uint32(pkt[1:])
+	o.MinLen = pkt[5]
+	o.MaxLen = pkt[6]
+	o.Prefix = &IPPrefix{}
+	err := o.Prefix.Decode(pkt[ORFPrefixEntryFixedLen:], afi)
+	if err != nil {
+		return 0, err
+	}
+	return ORFPrefixEntryFixedLen + int(o.Prefix.Len()), nil
+}
+
+func (o *ORFPrefixEntry) Matches(prefix net.IP, length uint8) bool {
+	if o.Prefix == nil {
+		return false
+	}
+
+	maxBits := uint8(len(o.Prefix.Prefix) * 8)
+	if o.Prefix.Prefix.To4() != nil {
+		maxBits = net.IPv4len * 8
+	}
+	ipNet := net.IPNet{IP: o.Prefix.Prefix, Mask: net.CIDRMask(int(o.Prefix.Length), int(maxBits))}
+	if !ipNet.Contains(prefix) || length < o.Prefix.Length {
+		return false
+	}
+
+	if o.MinLen == 0 && o.MaxLen == 0 {
+		return length == o.Prefix.Length
+	}
+
+	minLen, maxLen := o.Prefix.Length, maxBits
+	if o.MinLen != 0 {
+		minLen = o.MinLen
+	}
+	if o.MaxLen != 0 {
+		maxLen = o.MaxLen
+	}
+	return length >= minLen && length <= maxLen
+}
+
+func NewORFPrefixEntry(action uint8, match uint8, seq uint32, minLen uint8, maxLen uint8,
+	prefix *IPPrefix) *ORFPrefixEntry {
+	return &ORFPrefixEntry{
+		Action:   action,
+		Match:    match,
+		Sequence: seq,
+		MinLen:   minLen,
+		MaxLen:   maxLen,
+		Prefix:   prefix,
+	}
+}
+
+type ORFEntries struct {
+	Type    uint8
+	Entries []*ORFPrefixEntry
+}
+
+func (o *ORFEntries) Clone() *ORFEntries {
+	x := *o
+	x.Entries = make([]*ORFPrefixEntry, len(o.Entries))
+	for idx, entry := range o.Entries {
+		e := *entry
+		if entry.Prefix != nil {
+			e.Prefix = entry.Prefix.Clone().(*IPPrefix)
+		}
+		x.Entries[idx] = &e
+	}
+	return &x
+}
+
+func (o *ORFEntries) Encode(afi AFI) ([]byte, error) {
+	pkt := make([]byte, 3)
+	pkt[0] = o.Type
+	for _, entry := range o.Entries {
+		bytes, err := entry.Encode(afi)
+		if err != nil {
+			return nil, err
+		}
+		pkt = append(pkt, bytes...)
+	}
+	binary.BigEndian.PutUint16(pkt[1:], uint16(len(pkt)-3))
+	return pkt, nil
+}
+
+func (o *ORFEntries) Decode(pkt []byte, afi AFI) (int, error) {
+	if len(pkt) < 3 {
+		return 0, BGPMessageError{BGPUpdateMsgError, BGPUnspecific, nil, "Not enough data to decode ORF type and length"}
+	}
+
+	o.Type = pkt[0]
+	orfLen := int(binary.BigEndian.Uint16(pkt[1:]))
+	if len(pkt) < orfLen+3 {
+		return 0, BGPMessageError{BGPUpdateMsgError, BGPUnspecific, nil,
+			fmt.Sprintf("ORF length %d is greater than the remaining data %d", orfLen, len(pkt)-3)}
+	}
+
+	o.Entries = make([]*ORFPrefixEntry, 0)
+	if o.Type != ORFTypeAddressPrefix {
+		// Unknown ORF types are skipped
+		return orfLen + 3, nil
+	}
+
+	offset := 3
+	for offset < orfLen+3 {
+		entry := &ORFPrefixEntry{}
+		entryLen, err := entry.Decode(pkt[offset:orfLen+3], afi)
+		if err != nil {
+			return 0, err
+		}
+		o.Entries = append(o.Entries, entry)
+		offset += entryLen
+	}
+	return orfLen + 3, nil
+}
@@ -0,0 +1,171 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// orf_test.go
+package packet
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestBGPCapORFEncodeDecode(t *testing.T) {
+	orfCap := NewBGPCapORF()
+	orfCap.AddORF(AfiIP, SafiUnicast, ORFTypeAddressPrefix, ORFModeReceive)
+	orfCap.AddORF(AfiIP6, SafiUnicast, ORFTypeAddressPrefix, ORFModeBoth)
+	pkt, err := orfCap.Encode()
+	if err != nil {
+		t.Fatal("BGP ORF capability encode failed with error", err)
+	}
+
+	expected := []byte{0x03, 0x0e, 0x00, 0x01, 0x00, 0x01, 0x01, 0x40, 0x01, 0x00, 0x02, 0x00, 0x01, 0x01, 0x40,
+		0x03}
+	if !bytes.Equal(pkt, expected) {
+		t.Fatalf("BGP ORF capability encode - expected %x, got %x", expected, pkt)
+	}
+
+	decoded := &BGPCapORF{}
+	err = decoded.Decode(pkt)
+	if err != nil {
+		t.Fatal("BGP ORF capability decode failed with error", err)
+	}
+
+	if len(decoded.Value) != 2 || decoded.Value[1].AFI != AfiIP6 || decoded.Value[1].SAFI != SafiUnicast ||
+		len(decoded.Value[1].ORFs) != 1 || decoded.Value[1].ORFs[0].Mode != ORFModeBoth {
+		t.Fatalf("BGP ORF capability decode - got %+v", decoded)
+	}
+
+	err = decoded.Decode([]byte{0x03, 0x05, 0x00, 0x01, 0x00, 0x01, 0x02, 0x40})
+	if err == nil {
+		t.Fatal("BGP ORF capability decode called... expected failure, got NO error")
+	}
+}
+
+func TestBGPOpenORFCapability(t *testing.T) {
+	afiSafiMap := map[uint32]bool{
+		GetProtocolFamily(AfiIP, SafiUnicast):   true,
+		GetProtocolFamily(AfiIP, SafiMulticast): true,
+	}
+	optParams := ConstructOptParams(65000, afiSafiMap, false, 0, nil, false)
+	orfCap := ConstructORFCap(afiSafiMap)
+	if orfCap == nil {
+		t.Fatal("ORF capability not constructed for IPv4 unicast")
+	}
+	orfCap.Value[0].ORFs[0].Mode = ORFModeSend
+	optParams = append(optParams, NewBGPOptParamCapability([]BGPCapability{orfCap}))
+	openMsg := NewBGPOpenMessage(65000, 90, "10.1.1.1", optParams)
+	pkt, err := openMsg.Encode()
+	if err != nil {
+		t.Fatal("BGP open message encode failed with error", err)
+	}
+
+	bgpHeader := NewBGPHeader()
+	bgpHeader.Decode(pkt[:BGPMsgHeaderLen])
+	bgpMessage := NewBGPMessage()
+	err = bgpMessage.Decode(bgpHeader, pkt[BGPMsgHeaderLen:], BGPPeerAttrs{ASSize: 2})
+	if err != nil {
+		t.Fatal("BGP open message decode failed with error", err)
+	}
+
+	orfFamily := GetORFPrefixSendFamily(bgpMessage.Body.(*BGPOpen))
+	if len(orfFamily) != 1 || !orfFamily[GetProtocolFamily(AfiIP, SafiUnicast)] {
+		t.Fatalf("BGP open message ORF send families - got %+v", orfFamily)
+	}
+}
+
+func TestBGPRouteRefreshORFEncodeDecode(t *testing.T) {
+	orfs := []*ORFEntries{
+		{
+			Type: ORFTypeAddressPrefix,
+			Entries: []*ORFPrefixEntry{
+				NewORFPrefixEntry(ORFActionRemoveAll, ORFMatchPermit, 0, 0, 0, nil),
+				NewORFPrefixEntry(ORFActionAdd, ORFMatchDeny, 10, 0, 24, NewIPPrefix(net.IP{10, 1, 0, 0}, 16)),
+			},
+		},
+	}
+	msg := NewBGPRouteRefreshORFMessage(AfiIP, SafiUnicast, ORFWhenToRefreshImmediate, orfs)
+	pkt, err := msg.Encode()
+	if err != nil {
+		t.Fatal("BGP route refresh ORF message encode failed with error", err)
+	}
+
+	expectedBody := []byte{0x00, 0x01, 0x00, 0x01, 0x01, 0x40, 0x00, 0x0b, 0x80, 0x20, 0x00, 0x00, 0x00, 0x0a, 0x00,
+		0x18, 0x10, 0x0a, 0x01}
+	if !bytes.Equal(pkt[BGPMsgHeaderLen:], expectedBody) {
+		t.Fatalf("BGP route refresh ORF message encode - expected %x, got %x", expectedBody, pkt[BGPMsgHeaderLen:])
+	}
+
+	bgpHeader := NewBGPHeader()
+	err = bgpHeader.Decode(pkt[:BGPMsgHeaderLen])
+	if err != nil {
+		t.Fatal("BGP packet header decode failed with error", err)
+	}
+
+	bgpMessage := NewBGPMessage()
+	err = bgpMessage.Decode(bgpHeader, pkt[BGPMsgHeaderLen:], BGPPeerAttrs{ASSize: 4})
+	if err != nil {
+		t.Fatal("BGP route refresh ORF message decode failed with error", err)
+	}
+
+	refresh := bgpMessage.Body.(*BGPRouteRefresh)
+	if refresh.WhenToRefresh != ORFWhenToRefreshImmediate || len(refresh.ORFs) != 1 ||
+		len(refresh.ORFs[0].Entries) != 2 {
+		t.Fatalf("BGP route refresh ORF message decode - got %+v", refresh)
+	}
+
+	entry := refresh.ORFs[0].Entries[1]
+	if refresh.ORFs[0].Entries[0].Action != ORFActionRemoveAll || entry.Action != ORFActionAdd ||
+		entry.Match != ORFMatchDeny || entry.Sequence != 10 || entry.MaxLen != 24 ||
+		entry.Prefix.GetCIDR() != "10.1.0.0/16" {
+		t.Fatalf("BGP route refresh ORF entries decode - got %+v %+v", refresh.ORFs[0].Entries[0], entry)
+	}
+}
+
+func TestORFPrefixEntryMatches(t *testing.T) {
+	prefix := NewIPPrefix(net.IP{10, 1, 0, 0}, 16)
+	tests := []struct {
+		minLen   uint8
+		maxLen   uint8
+		ip       net.IP
+		length   uint8
+		expected bool
+	}{
+		{0, 0, net.ParseIP("10.1.0.0"), 16, true},
+		{0, 0, net.ParseIP("10.1.1.0"), 24, false},
+		{0, 24, net.ParseIP("10.1.1.0"), 24, true},
+		{0, 24, net.ParseIP("10.1.1.128"), 25, false},
+		{20, 0, net.ParseIP("10.1.1.128"), 25, true},
+		{20, 0, net.ParseIP("10.1.0.0"), 18, false},
+		{0, 24, net.ParseIP("10.2.0.0"), 16, false},
+		{0, 24, net.ParseIP("10.0.0.0"), 8, false},
+	}
+
+	for idx, test := range tests {
+		entry := NewORFPrefixEntry(ORFActionAdd, ORFMatchPermit, 5, test.minLen, test.maxLen, prefix)
+		if entry.Matches(test.ip, test.length) != test.expected {
+			t.Fatalf("ORF prefix entry match %d for %s/%d - expected %t", idx, test.ip, test.length,
+				test.expected)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// rd.go
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	RouteDistinguisherTypeAS2 uint16 = iota
+	RouteDistinguisherTypeIPv4
+	RouteDistinguisherTypeAS4
+)
+
+const RouteDistinguisherLen = 8
+
+type RouteDistinguisher struct {
+	Type     uint16
+	Admin    uint32
+	Assigned uint32
+}
+
+func (r *RouteDistinguisher) Encode() []byte {
+	pkt := make([]byte, RouteDistinguisherLen)
+	binary.BigEndian.PutUint16(pkt[0:2], r.Type)
+	switch r.Type {
+	case RouteDistinguisherTypeAS2:
+		binary.BigEndian.PutUint16(pkt[2:4], uint16(r.Admin))
+		binary.BigEndian.PutUint32(pkt[4:8], r.Assigned)
+	default:
+		binary.BigEndian.PutUint32(pkt[2:6], r.Admin)
+		binary.BigEndian.PutUint16(pkt[6:8], uint16(r.Assigned))
+	}
+	return pkt
+}
+
+func (r *RouteDistinguisher) Decode(pkt []byte) error {
+	if len(pkt) < RouteDistinguisherLen {
+		return errors.New(fmt.Sprintf("Route distinguisher needs %d bytes, got %d", RouteDistinguisherLen,
+			len(pkt)))
+	}
+
+	r.Type = binary.BigEndian.Uint16(pkt[0:2])
+	switch r.Type {
+	case RouteDistinguisherTypeAS2:
+		r.Admin = uint32(binary.BigEndian.Uint16(pkt[2:4]))
+		r.Assigned = binary.BigEndian.Uint32(pkt[4:8])
+	case RouteDistinguisherTypeIPv4, RouteDistinguisherTypeAS4:
+		r.Admin = binary.BigEndian.Uint32(pkt[2:6])
+		r.Assigned = uint32(binary.BigEndian.Uint16(pkt[6:8]))
+	default:
+		return errors.New(fmt.Sprintf("Route distinguisher type %d is not supported", r.Type))
+	}
+	return nil
+}
+
+func (r *RouteDistinguisher) String() string {
+	if r.Type == RouteDistinguisherTypeIPv4 {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, r.Admin)
+		return fmt.Sprintf("%s:%d", ip, r.Assigned)
+	}
+	return fmt.Sprintf("%d:%d", r.Admin, r.Assigned)
+}
+
+func NewRouteDistinguisher(rdType uint16, admin, assigned uint32) *RouteDistinguisher {
+	return &RouteDistinguisher{
+		Type:     rdType,
+		Admin:    admin,
+		Assigned: assigned,
+	}
+}
+
+func ParseRouteDistinguisher(str string) (*RouteDistinguisher, error) {
+	parts := strings.Split(strings.TrimSpace(str), ":")
+	if len(parts) != 2 {
+		return nil, errors.New(fmt.Sprintf("Route distinguisher %s is not in ADMIN:VAL format", str))
+	}
+
+	if ip := net.ParseIP(parts[0]); ip != nil {
+		if ip.To4() == nil {
+			return nil, errors.New(fmt.Sprintf("Route distinguisher %s has non IPv4 admin %s", str, parts[0]))
+		}
+		val, err := strconv.ParseUint(parts[1], 10, 16)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Route distinguisher %s has invalid value %s", str, parts[1]))
+		}
+		return NewRouteDistinguisher(RouteDistinguisherTypeIPv4, binary.BigEndian.Uint32(ip.To4()),
+			uint32(val)), nil
+	}
+
+	as, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Route distinguisher %s has invalid AS %s", str, parts[0]))
+	}
+
+	if as > 0xFFFF {
+		val, err := strconv.ParseUint(parts[1], 10, 16)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Route distinguisher %s has invalid value %s", str, parts[1]))
+		}
+		return NewRouteDistinguisher(RouteDistinguisherTypeAS4, uint32(as), uint32(val)), nil
+	}
+
+	val, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Route distinguisher %s has invalid value %s", str, parts[1]))
+	}
+	return NewRouteDistinguisher(RouteDistinguisherTypeAS2, uint32(as), uint32(val)), nil
+}
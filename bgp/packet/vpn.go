@@ -0,0 +1,304 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// vpn.go
+package packet
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+const SafiMPLSVPN SAFI = 128
+
+const (
+	VPNLabelLen           = 3
+	VPNLabelWithdraw      = 0x800000
+	VPNLabelBottomOfStack = 0x1
+	VPNLabelMax           = 0xFFFFF
+)
+
+func encodeVPNLabel(pkt []byte, label uint32, bottom bool) {
+	val := label << 4
+	if bottom {
+		val |= VPNLabelBottomOfStack
+	}
+	pkt[0] = uint8(val >> 16)
+	pkt[1] = uint8(val >> 8)
+	pkt[2] = uint8(val)
+}
+
+type VPNNLRI struct {
+	Labels []uint32
+	RD     RouteDistinguisher
+	Length uint8
+	Prefix net.IP
+}
+
+func (n *VPNNLRI) Clone() NLRI {
+	x := *n
+	x.Labels = make([]uint32, len(n.Labels))
+	copy(x.Labels, n.Labels)
+	x.Prefix = make(net.IP, len(n.Prefix))
+	copy(x.Prefix, n.Prefix)
+	return &x
+}
+
+func (n *VPNNLRI) prefixBytes() int {
+	return int((n.Length + 7) / 8)
+}
+
+func (n *VPNNLRI) Encode(afi AFI) ([]byte, error) {
+	if len(n.Labels) == 0 {
+		return nil, BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("VPN NLRI %s does not have a label", n.GetCIDR())}
+	}
+
+	ipLen := net.IPv4len
+	prefix := n.Prefix.To4()
+	if afi == AfiIP6 {
+		ipLen = net.IPv6len
+		prefix = n.Prefix.To16()
+	}
+	if prefix == nil || int(n.Length) > ipLen*8 {
+		return nil, BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("VPN NLRI %s is not valid for afi %d", n.GetCIDR(), afi)}
+	}
+
+	pkt := make([]byte, n.Len())
+	pkt[0] = uint8(len(n.Labels)*VPNLabelLen*8+RouteDistinguisherLen*8) + n.Length
+	idx := 1
+	for i, label := range n.Labels {
+		if label == VPNLabelWithdraw {
+			pkt[idx] = uint8(label >> 16)
+		} else {
+			encodeVPNLabel(pkt[idx:], label, i == len(n.Labels)-1)
+		}
+		idx += VPNLabelLen
+	}
+	copy(pkt[idx:], n.RD.Encode())
+	idx += RouteDistinguisherLen
+	copy(pkt[idx:], prefix[:n.prefixBytes()])
+	return pkt, nil
+}
+
+func (n *VPNNLRI) Decode(pkt []byte, afi AFI) error {
+	if len(pkt) < 1 {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil, "VPN NLRI does not contain length"}
+	}
+
+	bits := int(pkt[0])
+	idx := 1
+	n.Labels = make([]uint32, 0, 1)
+	for {
+		if bits < VPNLabelLen*8 || len(pkt) < idx+VPNLabelLen {
+			return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+				"VPN NLRI does not contain a complete label stack"}
+		}
+		val := uint32(pkt[idx])<<16 | uint32(pkt[idx+1])<<8 | uint32(pkt[idx+2])
+		idx += VPNLabelLen
+		bits -= VPNLabelLen * 8
+		if val == VPNLabelWithdraw {
+			n.Labels = append(n.Labels, val)
+			break
+		}
+		n.Labels = append(n.Labels, val>>4)
+		if val&VPNLabelBottomOfStack != 0 {
+			break
+		}
+	}
+
+	if bits < RouteDistinguisherLen*8 || len(pkt) < idx+RouteDistinguisherLen {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			"VPN NLRI does not contain route distinguisher"}
+	}
+	if err := n.RD.Decode(pkt[idx:]); err != nil {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil, err.Error()}
+	}
+	idx += RouteDistinguisherLen
+	bits -= RouteDistinguisherLen * 8
+
+	ipLen := net.IPv4len
+	if afi == AfiIP6 {
+		ipLen = net.IPv6len
+	}
+	if bits > ipLen*8 {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("VPN prefix length %d is greater than expected length %d", bits, ipLen*8)}
+	}
+
+	n.Length = uint8(bits)
+	bytes := n.prefixBytes()
+	if len(pkt) < idx+bytes {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil, "VPN prefix length invalid"}
+	}
+	n.Prefix = make(net.IP, ipLen)
+	copy(n.Prefix, pkt[idx:idx+bytes])
+	if bytes > 0 && (n.Length%8) > 0 {
+		n.Prefix[bytes-1] &= (^byte(0xff >> (n.Length % 8)))
+	}
+	return nil
+}
+
+func (n *VPNNLRI) Len() uint32 {
+	return uint32(1 + len(n.Labels)*VPNLabelLen + RouteDistinguisherLen + n.prefixBytes())
+}
+
+func (n *VPNNLRI) GetIPPrefix() *IPPrefix {
+	prefix := make(net.IP, len(n.Prefix))
+	copy(prefix, n.Prefix)
+	return NewIPPrefix(prefix, n.Length)
+}
+
+func (n *VPNNLRI) GetPrefix() net.IP {
+	return n.Prefix
+}
+
+func (n *VPNNLRI) GetLength() uint8 {
+	return n.Length
+}
+
+func (n *VPNNLRI) GetPathId() uint32 {
+	return 0
+}
+
+func (n *VPNNLRI) GetRD() *RouteDistinguisher {
+	return &n.RD
+}
+
+func (n *VPNNLRI) GetLabel() uint32 {
+	if len(n.Labels) == 0 {
+		return VPNLabelWithdraw
+	}
+	return n.Labels[0]
+}
+
+func (n *VPNNLRI) GetCIDR() string {
+	return n.RD.String() + ":" + n.Prefix.String() + "/" + strconv.Itoa(int(n.Length))
+}
+
+func (n *VPNNLRI) String() string {
+	return "{" + n.GetCIDR() + " label " + strconv.Itoa(int(n.GetLabel())) + "}"
+}
+
+func NewVPNNLRI(rd *RouteDistinguisher, label uint32, prefix *IPPrefix) *VPNNLRI {
+	ip := prefix.Prefix.To4()
+	if ip == nil {
+		ip = prefix.Prefix.To16()
+	}
+	vpnPrefix := make(net.IP, len(ip))
+	copy(vpnPrefix, ip)
+	return &VPNNLRI{
+		Labels: []uint32{label},
+		RD:     *rd,
+		Length: prefix.Length,
+		Prefix: vpnPrefix,
+	}
+}
+
+type MPNextHopVPN struct {
+	Length uint8
+	Value  net.IP
+}
+
+func (v *MPNextHopVPN) Clone() MPNextHop {
+	x := *v
+	x.Value = make(net.IP, len(v.Value))
+	copy(x.Value, v.Value)
+	return &x
+}
+
+func (v *MPNextHopVPN) Encode(pkt []byte) error {
+	pkt[0] = v.Length
+	ipLen := int(v.Length) - RouteDistinguisherLen
+	if ipLen != net.IPv4len && ipLen != net.IPv6len {
+		return errors.New(fmt.Sprintf("Wrong VPN next hop len %d", v.Length))
+	}
+	for i := 1; i <= RouteDistinguisherLen; i++ {
+		pkt[i] = 0
+	}
+	ip := v.Value.To4()
+	if ipLen == net.IPv6len {
+		ip = v.Value.To16()
+	}
+	copy(pkt[1+RouteDistinguisherLen:], ip)
+	return nil
+}
+
+func (v *MPNextHopVPN) Decode(pkt []byte) error {
+	v.Length = pkt[0]
+	ipLen := int(v.Length) - RouteDistinguisherLen
+	if v.Length == 2*(RouteDistinguisherLen+net.IPv6len) {
+		ipLen = net.IPv6len
+	}
+	if ipLen != net.IPv4len && ipLen != net.IPv6len {
+		return errors.New(fmt.Sprintf("Wrong VPN next hop len %d", v.Length))
+	}
+
+	start := 1 + RouteDistinguisherLen
+	if ipLen == net.IPv4len {
+		v.Value = net.IPv4(pkt[start], pkt[start+1], pkt[start+2], pkt[start+3])
+	} else {
+		v.Value = make(net.IP, ipLen)
+		copy(v.Value, pkt[start:start+ipLen])
+	}
+	return nil
+}
+
+func (v *MPNextHopVPN) Len() uint8 {
+	return v.Length + 1
+}
+
+func (v *MPNextHopVPN) New() MPNextHop {
+	return &MPNextHopVPN{}
+}
+
+func (v *MPNextHopVPN) String() string {
+	return fmt.Sprintf("{NEXTHOP 0:0:%v}", v.Value)
+}
+
+func (v *MPNextHopVPN) GetNextHop() net.IP {
+	return v.Value
+}
+
+func (v *MPNextHopVPN) SetNextHop(ip net.IP) error {
+	if ip.To4() == nil && ip.To16() == nil {
+		return errors.New(fmt.Sprintf("VPN next hop %s is NOT IPv4 or IPv6 address", ip))
+	}
+
+	v.Value = ip
+	v.Length = uint8(RouteDistinguisherLen + net.IPv6len)
+	if ip.To4() != nil {
+		v.Length = uint8(RouteDistinguisherLen + net.IPv4len)
+	}
+	return nil
+}
+
+func NewMPNextHopVPN() *MPNextHopVPN {
+	return &MPNextHopVPN{
+		Length: 0,
+		Value:  net.IP{},
+	}
+}
@@ -0,0 +1,156 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// vpn_test.go
+package packet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+)
+
+func TestVPNNLRIDecode(t *testing.T) {
+	pkts := []struct {
+		pkt   string
+		afi   AFI
+		cidr  string
+		label uint32
+	}{
+		// label 100, RD 65000:100, 10.1.1.0/24
+		{"700006410000fde8000000640a0101", AfiIP, "65000:100:10.1.1.0/24", 100},
+		// label 200, RD 10.0.0.1:5, 2001:db8::/32
+		{"78000c8100010a000001000520010db8", AfiIP6, "10.0.0.1:5:2001:db8::/32", 200},
+		// withdrawn label, RD 65000:100, 10.1.1.0/24
+		{"708000000000fde8000000640a0101", AfiIP, "65000:100:10.1.1.0/24", VPNLabelWithdraw},
+	}
+
+	for _, test := range pkts {
+		pkt, _ := hex.DecodeString(test.pkt)
+		nlri := &VPNNLRI{}
+		err := nlri.Decode(pkt, test.afi)
+		if err != nil {
+			t.Fatal("VPN NLRI decode for", test.pkt, "failed with error:", err)
+		}
+		if nlri.GetCIDR() != test.cidr || nlri.GetLabel() != test.label {
+			t.Fatal("VPN NLRI decode for", test.pkt, "expected", test.cidr, "label", test.label, "got",
+				nlri.GetCIDR(), "label", nlri.GetLabel())
+		}
+		if nlri.Len() != uint32(len(pkt)) {
+			t.Fatal("VPN NLRI decode for", test.pkt, "expected length", len(pkt), "got", nlri.Len())
+		}
+
+		encPkt, err := nlri.Encode(test.afi)
+		if err != nil {
+			t.Fatal("VPN NLRI encode for", test.pkt, "failed with error:", err)
+		}
+		if !bytes.Equal(encPkt, pkt) {
+			t.Fatalf("VPN NLRI encode mismatch, expected: %x got: %x", pkt, encPkt)
+		}
+	}
+}
+
+func TestVPNNLRIBadPackets(t *testing.T) {
+	pkts := map[string]AFI{
+		// no label stack
+		"70": AfiIP,
+		// label without bottom of stack bit and no more data
+		"70000640": AfiIP,
+		// truncated route distinguisher
+		"700006410000fde8": AfiIP,
+		// prefix length 40 for IPv4
+		"80000006410000fde8000000640a01010101": AfiIP,
+		// truncated prefix
+		"700006410000fde8000000640a01": AfiIP,
+	}
+
+	for strPkt, afi := range pkts {
+		pkt, _ := hex.DecodeString(strPkt)
+		nlri := &VPNNLRI{}
+		if err := nlri.Decode(pkt, afi); err == nil {
+			t.Fatal("VPN NLRI decode for", strPkt, "expected failure, got NO errors")
+		}
+	}
+}
+
+func TestVPNNLRIConstruct(t *testing.T) {
+	rd, _ := ParseRouteDistinguisher("65000:100")
+	nlri := NewVPNNLRI(rd, 100, NewIPPrefix(net.ParseIP("10.1.1.0"), 24))
+	pkt, err := nlri.Encode(AfiIP)
+	if err != nil {
+		t.Fatal("VPN NLRI encode failed with error:", err)
+	}
+
+	expected, _ := hex.DecodeString("700006410000fde8000000640a0101")
+	if !bytes.Equal(pkt, expected) {
+		t.Fatalf("VPN NLRI encode mismatch, expected: %x got: %x", expected, pkt)
+	}
+
+	prefix := nlri.GetIPPrefix()
+	if prefix.GetCIDR() != "10.1.1.0/24" {
+		t.Fatal("VPN NLRI IP prefix, expected 10.1.1.0/24 got", prefix.GetCIDR())
+	}
+	if _, err = prefix.Encode(AfiIP); err != nil {
+		t.Fatal("VPN NLRI IP prefix encode failed with error:", err)
+	}
+}
+
+func TestMPReachNLRIVPNDecode(t *testing.T) {
+	hexPkt, _ := hex.DecodeString("800E200001800c00000000000000000a00000100700006410000fde8000000640a0101")
+	mpReach := NewBGPPathAttrMPReachNLRI()
+	peerAttrs := BGPPeerAttrs{
+		ASSize:           4,
+		AddPathsRxActual: false,
+	}
+	err := mpReach.Decode(hexPkt, peerAttrs)
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI decode for VPN failed with error:", err)
+	}
+
+	if !mpReach.NextHop.GetNextHop().Equal(net.ParseIP("10.0.0.1")) || len(mpReach.NLRI) != 1 {
+		t.Fatal("BGP MPReachNLRI decode for VPN, expected next hop 10.0.0.1 and 1 NLRI, got:", mpReach.NextHop,
+			mpReach.NLRI)
+	}
+	if _, ok := mpReach.NLRI[0].(*VPNNLRI); !ok {
+		t.Fatal("BGP MPReachNLRI decode for VPN, expected VPN NLRI, got:", mpReach.NLRI[0])
+	}
+
+	pkt, err := mpReach.Encode()
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI encode for VPN failed with error:", err)
+	}
+	if !bytes.Equal(pkt, hexPkt) {
+		t.Fatalf("BGP MPReachNLRI encode for VPN mismatch, expected: %x got: %x", hexPkt, pkt)
+	}
+
+	mpReach = ConstructIPv6MPReachNLRI(GetProtocolFamily(AfiIP, SafiMPLSVPN), net.ParseIP("10.0.0.1"), nil,
+		mpReach.NLRI)
+	pkt, err = mpReach.Encode()
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI encode for constructed VPN failed with error:", err)
+	}
+	if !bytes.Equal(pkt[4:], hexPkt[3:]) {
+		t.Fatalf("BGP MPReachNLRI encode for constructed VPN mismatch, expected: %x got: %x", hexPkt, pkt)
+	}
+}
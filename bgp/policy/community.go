@@ -0,0 +1,344 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// community.go
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"l3/bgp/packet"
+	"strings"
+	"sync"
+)
+
+const (
+	CommunityMatchAny = "any"
+	CommunityMatchAll = "all"
+)
+
+const (
+	CommunityActionSet    = "set"
+	CommunityActionAdd    = "add"
+	CommunityActionDelete = "delete"
+)
+
+type CommunityConditionConfig struct {
+	Name        string
+	MatchType   string
+	Communities []string
+}
+
+type CommunityActionConfig struct {
+	Name        string
+	ActionType  string
+	Communities []string
+}
+
+type communitySet struct {
+	std   []uint32
+	ext   []uint64
+	large []packet.LargeCommunity
+}
+
+func (c *communitySet) isEmpty() bool {
+	return len(c.std) == 0 && len(c.ext) == 0 && len(c.large) == 0
+}
+
+func parseCommunitySet(communities []string) (communitySet, error) {
+	var set communitySet
+	for _, str := range communities {
+		str = strings.TrimSpace(str)
+		if strings.HasPrefix(str, "rt:") || strings.HasPrefix(str, "soo:") {
+			community, err := packet.ParseExtCommunity(str)
+			if err != nil {
+				return set, err
+			}
+			set.ext = append(set.ext, community)
+		} else if strings.Count(str, ":") == 2 {
+			community, err := packet.ParseLargeCommunity(str)
+			if err != nil {
+				return set, err
+			}
+			set.large = append(set.large, community)
+		} else {
+			community, err := packet.ParseCommunity(str)
+			if err != nil {
+				return set, err
+			}
+			set.std = append(set.std, community)
+		}
+	}
+	return set, nil
+}
+
+type CommunityCondition struct {
+	Name      string
+	MatchType string
+	set       communitySet
+}
+
+func (c *CommunityCondition) match(pathAttrs []packet.BGPPathAttr) bool {
+	std := packet.GetCommunities(pathAttrs)
+	ext := packet.GetExtCommunities(pathAttrs)
+	large := packet.GetLargeCommunities(pathAttrs)
+	total := len(c.set.std) + len(c.set.ext) + len(c.set.large)
+	found := 0
+
+	for _, community := range c.set.std {
+		for _, val := range std {
+			if val == community {
+				found++
+				break
+			}
+		}
+	}
+	for _, community := range c.set.ext {
+		for _, val := range ext {
+			if val == community {
+				found++
+				break
+			}
+		}
+	}
+	for _, community := range c.set.large {
+		for _, val := range large {
+			if val == community {
+				found++
+				break
+			}
+		}
+	}
+
+	if c.MatchType == CommunityMatchAll {
+		return found == total
+	}
+	return found > 0
+}
+
+type CommunityAction struct {
+	Name       string
+	ActionType string
+	set        communitySet
+}
+
+func (a *CommunityAction) apply(pathAttrs []packet.BGPPathAttr) []packet.BGPPathAttr {
+	switch a.ActionType {
+	case CommunityActionSet:
+		if a.set.isEmpty() || len(a.set.std) > 0 {
+			pathAttrs = packet.SetCommunities(pathAttrs, a.set.std)
+		}
+		if a.set.isEmpty() || len(a.set.ext) > 0 {
+			pathAttrs = packet.SetExtCommunities(pathAttrs, a.set.ext)
+		}
+		if a.set.isEmpty() || len(a.set.large) > 0 {
+			pathAttrs = packet.SetLargeCommunities(pathAttrs, a.set.large)
+		}
+
+	case CommunityActionAdd:
+		if len(a.set.std) > 0 {
+			std := append(append([]uint32(nil), packet.GetCommunities(pathAttrs)...), a.set.std...)
+			pathAttrs = packet.SetCommunities(pathAttrs, std)
+		}
+		if len(a.set.ext) > 0 {
+			ext := append(append([]uint64(nil), packet.GetExtCommunities(pathAttrs)...), a.set.ext...)
+			pathAttrs = packet.SetExtCommunities(pathAttrs, ext)
+		}
+		if len(a.set.large) > 0 {
+			large := append(append([]packet.LargeCommunity(nil), packet.GetLargeCommunities(pathAttrs)...),
+				a.set.large...)
+			pathAttrs = packet.SetLargeCommunities(pathAttrs, large)
+		}
+
+	case CommunityActionDelete:
+		if len(a.set.std) > 0 {
+			std := make([]uint32, 0)
+			for _, val := range packet.GetCommunities(pathAttrs) {
+				if !containsCommunity(a.set.std, val) {
+					std = append(std, val)
+				}
+			}
+			pathAttrs = packet.SetCommunities(pathAttrs, std)
+		}
+		if len(a.set.ext) > 0 {
+			ext := make([]uint64, 0)
+			for _, val := range packet.GetExtCommunities(pathAttrs) {
+				if !containsExtCommunity(a.set.ext, val) {
+					ext = append(ext, val)
+				}
+			}
+			pathAttrs = packet.SetExtCommunities(pathAttrs, ext)
+		}
+		if len(a.set.large) > 0 {
+			large := make([]packet.LargeCommunity, 0)
+			for _, val := range packet.GetLargeCommunities(pathAttrs) {
+				if !containsLargeCommunity(a.set.large, val) {
+					large = append(large, val)
+				}
+			}
+			pathAttrs = packet.SetLargeCommunities(pathAttrs, large)
+		}
+	}
+
+	return pathAttrs
+}
+
+func containsCommunity(communities []uint32, community uint32) bool {
+	for _, val := range communities {
+		if val == community {
+			return true
+		}
+	}
+	return false
+}
+
+func containsExtCommunity(communities []uint64, community uint64) bool {
+	for _, val := range communities {
+		if val == community {
+			return true
+		}
+	}
+	return false
+}
+
+func containsLargeCommunity(communities []packet.LargeCommunity, community packet.LargeCommunity) bool {
+	for _, val := range communities {
+		if val == community {
+			return true
+		}
+	}
+	return false
+}
+
+type CommunityDB struct {
+	sync.RWMutex
+	conditions map[string]*CommunityCondition
+	actions    map[string]*CommunityAction
+}
+
+func NewCommunityDB() *CommunityDB {
+	return &CommunityDB{
+		conditions: make(map[string]*CommunityCondition),
+		actions:    make(map[string]*CommunityAction),
+	}
+}
+
+func (db *CommunityDB) CreateCondition(cfg CommunityConditionConfig) error {
+	if cfg.MatchType == "" {
+		cfg.MatchType = CommunityMatchAny
+	}
+	if cfg.MatchType != CommunityMatchAny && cfg.MatchType != CommunityMatchAll {
+		return errors.New(fmt.Sprintf("Unknown community match type %s", cfg.MatchType))
+	}
+
+	set, err := parseCommunitySet(cfg.Communities)
+	if err != nil {
+		return err
+	}
+	if set.isEmpty() {
+		return errors.New(fmt.Sprintf("Community condition %s has no communities", cfg.Name))
+	}
+
+	db.Lock()
+	defer db.Unlock()
+	db.conditions[cfg.Name] = &CommunityCondition{Name: cfg.Name, MatchType: cfg.MatchType, set: set}
+	return nil
+}
+
+func (db *CommunityDB) DeleteCondition(name string) error {
+	db.Lock()
+	defer db.Unlock()
+	if _, ok := db.conditions[name]; !ok {
+		return errors.New(fmt.Sprintf("Community condition %s not found", name))
+	}
+	delete(db.conditions, name)
+	return nil
+}
+
+func (db *CommunityDB) CreateAction(cfg CommunityActionConfig) error {
+	if cfg.ActionType != CommunityActionSet && cfg.ActionType != CommunityActionAdd &&
+		cfg.ActionType != CommunityActionDelete {
+		return errors.New(fmt.Sprintf("Unknown community action type %s", cfg.ActionType))
+	}
+
+	set, err := parseCommunitySet(cfg.Communities)
+	if err != nil {
+		return err
+	}
+	if set.isEmpty() && cfg.ActionType != CommunityActionSet {
+		return errors.New(fmt.Sprintf("Community action %s has no communities", cfg.Name))
+	}
+
+	db.Lock()
+	defer db.Unlock()
+	db.actions[cfg.Name] = &CommunityAction{Name: cfg.Name, ActionType: cfg.ActionType, set: set}
+	return nil
+}
+
+func (db *CommunityDB) DeleteAction(name string) error {
+	db.Lock()
+	defer db.Unlock()
+	if _, ok := db.actions[name]; !ok {
+		return errors.New(fmt.Sprintf("Community action %s not found", name))
+	}
+	delete(db.actions, name)
+	return nil
+}
+
+func (db *CommunityDB) IsAction(name string) bool {
+	db.RLock()
+	defer db.RUnlock()
+	_, ok := db.actions[name]
+	return ok
+}
+
+// MatchConditions returns false if any of the community conditions in the list
+// does not match the path attrs. Names that are not community conditions are skipped.
+func (db *CommunityDB) MatchConditions(names []string, pathAttrs []packet.BGPPathAttr) bool {
+	db.RLock()
+	defer db.RUnlock()
+	for _, name := range names {
+		if condition, ok := db.conditions[name]; ok && !condition.match(pathAttrs) {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyActions returns a new path attrs list with all the community actions in
+// the list applied. The path attrs passed in are not modified.
+func (db *CommunityDB) ApplyActions(names []string, pathAttrs []packet.BGPPathAttr) ([]packet.BGPPathAttr, bool) {
+	db.RLock()
+	defer db.RUnlock()
+	applied := false
+	for _, name := range names {
+		if action, ok := db.actions[name]; ok {
+			if !applied {
+				pathAttrs = packet.CopyPathAttrs(pathAttrs)
+				applied = true
+			}
+			pathAttrs = action.apply(pathAttrs)
+		}
+	}
+	return pathAttrs, applied
+}
@@ -0,0 +1,124 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// dampening.go
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"l3/bgp/config"
+	"sync"
+)
+
+const (
+	DampeningDefaultHalfLife          = 900
+	DampeningDefaultReuseThreshold    = 750
+	DampeningDefaultSuppressThreshold = 2000
+	DampeningDefaultMaxSuppressTime   = 3600
+)
+
+type DampeningActionConfig struct {
+	Name              string
+	HalfLife          uint32
+	ReuseThreshold    uint32
+	SuppressThreshold uint32
+	MaxSuppressTime   uint32
+}
+
+type DampeningDB struct {
+	sync.RWMutex
+	actions map[string]*config.DampeningConfig
+}
+
+func NewDampeningDB() *DampeningDB {
+	return &DampeningDB{
+		actions: make(map[string]*config.DampeningConfig),
+	}
+}
+
+func (db *DampeningDB) CreateAction(cfg DampeningActionConfig) error {
+	dampConf := &config.DampeningConfig{
+		HalfLife:          cfg.HalfLife,
+		ReuseThreshold:    cfg.ReuseThreshold,
+		SuppressThreshold: cfg.SuppressThreshold,
+		MaxSuppressTime:   cfg.MaxSuppressTime,
+	}
+	if dampConf.HalfLife == 0 {
+		dampConf.HalfLife = DampeningDefaultHalfLife
+	}
+	if dampConf.ReuseThreshold == 0 {
+		dampConf.ReuseThreshold = DampeningDefaultReuseThreshold
+	}
+	if dampConf.SuppressThreshold == 0 {
+		dampConf.SuppressThreshold = DampeningDefaultSuppressThreshold
+	}
+	if dampConf.MaxSuppressTime == 0 {
+		dampConf.MaxSuppressTime = DampeningDefaultMaxSuppressTime
+	}
+
+	if dampConf.ReuseThreshold >= dampConf.SuppressThreshold {
+		return errors.New(fmt.Sprintf("Dampening action %s reuse threshold %d is not less than suppress threshold %d",
+			cfg.Name, dampConf.ReuseThreshold, dampConf.SuppressThreshold))
+	}
+	if dampConf.MaxSuppressTime < dampConf.HalfLife {
+		return errors.New(fmt.Sprintf("Dampening action %s max suppress time %d is less than half life %d",
+			cfg.Name, dampConf.MaxSuppressTime, dampConf.HalfLife))
+	}
+
+	db.Lock()
+	defer db.Unlock()
+	db.actions[cfg.Name] = dampConf
+	return nil
+}
+
+func (db *DampeningDB) DeleteAction(name string) error {
+	db.Lock()
+	defer db.Unlock()
+	if _, ok := db.actions[name]; !ok {
+		return errors.New(fmt.Sprintf("Dampening action %s not found", name))
+	}
+	delete(db.actions, name)
+	return nil
+}
+
+func (db *DampeningDB) IsAction(name string) bool {
+	db.RLock()
+	defer db.RUnlock()
+	_, ok := db.actions[name]
+	return ok
+}
+
+// GetConfig returns the dampening parameters of the last dampening action in the
+// list. Names that are not dampening actions are skipped.
+func (db *DampeningDB) GetConfig(names []string) *config.DampeningConfig {
+	db.RLock()
+	defer db.RUnlock()
+	var dampConf *config.DampeningConfig
+	for _, name := range names {
+		if conf, ok := db.actions[name]; ok {
+			dampConf = conf
+		}
+	}
+	return dampConf
+}
@@ -46,7 +46,22 @@ type BGPPolicyManager struct {
 	ActionDelCh     chan string
 	StmtDelCh       chan string
 	DefinitionDelCh chan string
+	UpdateCh        chan bool
 	policyPlugin    config.PolicyMgrIntf
+
+	CommunityDB             *CommunityDB
+	CommunityConditionCfgCh chan CommunityConditionConfig
+	CommunityActionCfgCh    chan CommunityActionConfig
+	CommunityConditionDelCh chan string
+	CommunityActionDelCh    chan string
+
+	RPKIDB             *RPKIDB
+	RPKIConditionCfgCh chan RPKIConditionConfig
+	RPKIConditionDelCh chan string
+
+	DampeningDB          *DampeningDB
+	DampeningActionCfgCh chan DampeningActionConfig
+	DampeningActionDelCh chan string
 }
 
 func NewPolicyManager(logger *logging.Writer, pMgr config.PolicyMgrIntf) *BGPPolicyManager {
@@ -62,7 +77,19 @@ func NewPolicyManager(logger *logging.Writer, pMgr config.PolicyMgrIntf) *BGPPol
 		policyManager.ActionDelCh = make(chan string)
 		policyManager.StmtDelCh = make(chan string)
 		policyManager.DefinitionDelCh = make(chan string)
+		policyManager.UpdateCh = make(chan bool, 1)
 		policyManager.policyPlugin = pMgr
+		policyManager.CommunityDB = NewCommunityDB()
+		policyManager.CommunityConditionCfgCh = make(chan CommunityConditionConfig)
+		policyManager.CommunityActionCfgCh = make(chan CommunityActionConfig)
+		policyManager.CommunityConditionDelCh = make(chan string)
+		policyManager.CommunityActionDelCh = make(chan string)
+		policyManager.RPKIDB = NewRPKIDB()
+		policyManager.RPKIConditionCfgCh = make(chan RPKIConditionConfig)
+		policyManager.RPKIConditionDelCh = make(chan string)
+		policyManager.DampeningDB = NewDampeningDB()
+		policyManager.DampeningActionCfgCh = make(chan DampeningActionConfig)
+		policyManager.DampeningActionDelCh = make(chan string)
 		PolicyManager = policyManager
 	}
 
@@ -73,6 +100,14 @@ func (eng *BGPPolicyManager) AddPolicyEngine(bgpPE BGPPolicyEngine) {
 	eng.policyEngines = append(eng.policyEngines, bgpPE)
 }
 
+// notifyUpdate tells the BGP server that the policy conditions, statements or definitions changed
+func (eng *BGPPolicyManager) notifyUpdate() {
+	select {
+	case eng.UpdateCh <- true:
+	default:
+	}
+}
+
 func convertModelsToPolicyCondition(cfg objects.PolicyCondition) *utilspolicy.PolicyConditionConfig {
 	destIPMatch := utilspolicy.PolicyDstIpMatchPrefixSetCondition{
 		Prefix: utilspolicy.PolicyPrefix{
@@ -189,6 +224,7 @@ func (eng *BGPPolicyManager) StartPolicyEngine(dbUtil *dbutils.DBUtil, doneCh ch
 			for _, pe := range eng.policyEngines {
 				pe.CreatePolicyCondition(condCfg)
 			}
+			eng.notifyUpdate()
 
 		case actionCfg := <-eng.ActionCfgCh:
 			eng.logger.Info("BGPPolicyEngine - create policy action", actionCfg.Name)
@@ -201,18 +237,21 @@ func (eng *BGPPolicyManager) StartPolicyEngine(dbUtil *dbutils.DBUtil, doneCh ch
 			for _, pe := range eng.policyEngines {
 				pe.CreatePolicyStmt(stmtCfg)
 			}
+			eng.notifyUpdate()
 
 		case defCfg := <-eng.DefinitionCfgCh:
 			eng.logger.Info("BGPPolicyEngine - create policy definition", defCfg.Name)
 			for _, pe := range eng.policyEngines {
 				pe.CreatePolicyDefinition(defCfg)
 			}
+			eng.notifyUpdate()
 
 		case conditionName := <-eng.ConditionDelCh:
 			eng.logger.Info("BGPPolicyEngine - delete policy condition", conditionName)
 			for _, pe := range eng.policyEngines {
 				pe.DeletePolicyCondition(conditionName)
 			}
+			eng.notifyUpdate()
 
 		case actionName := <-eng.ActionDelCh:
 			eng.logger.Info("BGPPolicyEngine - delete policy action", actionName)
@@ -225,12 +264,62 @@ func (eng *BGPPolicyManager) StartPolicyEngine(dbUtil *dbutils.DBUtil, doneCh ch
 			for _, pe := range eng.policyEngines {
 				pe.DeletePolicyStmt(stmtName)
 			}
+			eng.notifyUpdate()
 
 		case policyName := <-eng.DefinitionDelCh:
 			eng.logger.Info("BGPPolicyEngine - delete policy definition", policyName)
 			for _, pe := range eng.policyEngines {
 				pe.DeletePolicyDefinition(policyName)
 			}
+			eng.notifyUpdate()
+
+		case condCfg := <-eng.CommunityConditionCfgCh:
+			eng.logger.Info("BGPPolicyEngine - create community condition", condCfg.Name)
+			if err := eng.CommunityDB.CreateCondition(condCfg); err != nil {
+				eng.logger.Err("BGPPolicyEngine - create community condition", condCfg.Name, "failed with error", err)
+			}
+
+		case actionCfg := <-eng.CommunityActionCfgCh:
+			eng.logger.Info("BGPPolicyEngine - create community action", actionCfg.Name)
+			if err := eng.CommunityDB.CreateAction(actionCfg); err != nil {
+				eng.logger.Err("BGPPolicyEngine - create community action", actionCfg.Name, "failed with error", err)
+			}
+
+		case conditionName := <-eng.CommunityConditionDelCh:
+			eng.logger.Info("BGPPolicyEngine - delete community condition", conditionName)
+			if err := eng.CommunityDB.DeleteCondition(conditionName); err != nil {
+				eng.logger.Err("BGPPolicyEngine - delete community condition failed with error", err)
+			}
+
+		case actionName := <-eng.CommunityActionDelCh:
+			eng.logger.Info("BGPPolicyEngine - delete community action", actionName)
+			if err := eng.CommunityDB.DeleteAction(actionName); err != nil {
+				eng.logger.Err("BGPPolicyEngine - delete community action failed with error", err)
+			}
+
+		case condCfg := <-eng.RPKIConditionCfgCh:
+			eng.logger.Info("BGPPolicyEngine - create RPKI condition", condCfg.Name)
+			if err := eng.RPKIDB.CreateCondition(condCfg); err != nil {
+				eng.logger.Err("BGPPolicyEngine - create RPKI condition", condCfg.Name, "failed with error", err)
+			}
+
+		case conditionName := <-eng.RPKIConditionDelCh:
+			eng.logger.Info("BGPPolicyEngine - delete RPKI condition", conditionName)
+			if err := eng.RPKIDB.DeleteCondition(conditionName); err != nil {
+				eng.logger.Err("BGPPolicyEngine - delete RPKI condition failed with error", err)
+			}
+
+		case actionCfg := <-eng.DampeningActionCfgCh:
+			eng.logger.Info("BGPPolicyEngine - create dampening action", actionCfg.Name)
+			if err := eng.DampeningDB.CreateAction(actionCfg); err != nil {
+				eng.logger.Err("BGPPolicyEngine - create dampening action", actionCfg.Name, "failed with error", err)
+			}
+
+		case actionName := <-eng.DampeningActionDelCh:
+			eng.logger.Info("BGPPolicyEngine - delete dampening action", actionName)
+			if err := eng.DampeningDB.DeleteAction(actionName); err != nil {
+				eng.logger.Err("BGPPolicyEngine - delete dampening action failed with error", err)
+			}
 		}
 	}
 }
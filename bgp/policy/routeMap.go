@@ -0,0 +1,173 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// routeMap.go
+package policy
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"utils/patriciaDB"
+	utilspolicy "utils/policy"
+	"utils/policy/policyCommonDefs"
+)
+
+// getPolicyStmts returns the statements of a policy in precedence order
+func (eng *BasePolicyEngine) getPolicyStmts(policyName string) []utilspolicy.PolicyStmt {
+	stmts := make([]utilspolicy.PolicyStmt, 0)
+	nodeGet := eng.PolicyEngine.PolicyDB.Get(patriciaDB.Prefix(policyName))
+	if nodeGet == nil {
+		eng.logger.Infof("getPolicyStmts - Policy %s not defined", policyName)
+		return stmts
+	}
+	policy := nodeGet.(utilspolicy.Policy)
+
+	precedences := make([]int, 0, len(policy.PolicyStmtPrecedenceMap))
+	for precedence := range policy.PolicyStmtPrecedenceMap {
+		precedences = append(precedences, precedence)
+	}
+	sort.Ints(precedences)
+
+	for _, precedence := range precedences {
+		stmtName := policy.PolicyStmtPrecedenceMap[precedence]
+		stmtGet := eng.PolicyEngine.PolicyStmtDB.Get(patriciaDB.Prefix(stmtName))
+		if stmtGet == nil {
+			eng.logger.Errf("getPolicyStmts - Policy %s statement %s not defined", policyName, stmtName)
+			continue
+		}
+		stmts = append(stmts, stmtGet.(utilspolicy.PolicyStmt))
+	}
+	return stmts
+}
+
+// MatchNextStmt continues the evaluation of a policy after the statement stmtName, whose BGP conditions did not
+// match. It returns the first of the following statements whose prefix conditions match the prefix and whose
+// other conditions are matched by matchFunc.
+func (eng *BasePolicyEngine) MatchNextStmt(policyName, stmtName, cidr string,
+	matchFunc func(utilspolicy.PolicyStmt) bool) (utilspolicy.PolicyStmt, bool) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		eng.logger.Errf("MatchNextStmt - Invalid prefix %s, err %s", cidr, err)
+		return utilspolicy.PolicyStmt{}, false
+	}
+
+	found := false
+	for _, stmt := range eng.getPolicyStmts(policyName) {
+		if !found {
+			found = stmt.Name == stmtName
+			continue
+		}
+
+		if eng.matchStmtPrefix(stmt, ipNet) && matchFunc(stmt) {
+			return stmt, true
+		}
+	}
+	return utilspolicy.PolicyStmt{}, false
+}
+
+// MatchPrefix evaluates the statements of a policy used as a route map in precedence order. The first statement
+// whose prefix conditions match decides whether the prefix is permitted.
+func (eng *BasePolicyEngine) MatchPrefix(policyName string, cidr string) bool {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		eng.logger.Errf("MatchPrefix - Invalid prefix %s, err %s", cidr, err)
+		return false
+	}
+
+	for _, stmt := range eng.getPolicyStmts(policyName) {
+		if !eng.matchStmtPrefix(stmt, ipNet) {
+			continue
+		}
+
+		for _, action := range stmt.Actions {
+			if action == "permit" {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func (eng *BasePolicyEngine) matchStmtPrefix(stmt utilspolicy.PolicyStmt, ipNet *net.IPNet) bool {
+	matchAll := stmt.MatchConditions != "any"
+	numPrefixConds := 0
+	for _, condName := range stmt.Conditions {
+		condGet := eng.PolicyEngine.PolicyConditionsDB.Get(patriciaDB.Prefix(condName))
+		if condGet == nil {
+			continue
+		}
+		cond := condGet.(utilspolicy.PolicyCondition)
+		if cond.ConditionType != policyCommonDefs.PolicyConditionTypeDstIpPrefixMatch {
+			continue
+		}
+
+		numPrefixConds++
+		matchPrefix, ok := cond.ConditionInfo.(utilspolicy.MatchPrefixConditionInfo)
+		if !ok {
+			continue
+		}
+		matched := matchPolicyPrefix(matchPrefix.Prefix, ipNet)
+		if matched && !matchAll {
+			return true
+		} else if !matched && matchAll {
+			return false
+		}
+	}
+
+	// Statements without prefix conditions match all the prefixes
+	return numPrefixConds == 0 || matchAll
+}
+
+func matchPolicyPrefix(prefix utilspolicy.PolicyPrefix, ipNet *net.IPNet) bool {
+	_, condNet, err := net.ParseCIDR(prefix.IpPrefix)
+	if err != nil || len(condNet.IP) != len(ipNet.IP) {
+		return false
+	}
+
+	condLen, _ := condNet.Mask.Size()
+	destLen, _ := ipNet.Mask.Size()
+	if destLen < condLen || !condNet.Contains(ipNet.IP) {
+		return false
+	}
+
+	if prefix.MasklengthRange == "" || prefix.MasklengthRange == "exact" {
+		return destLen == condLen
+	}
+
+	lenRange := strings.Split(prefix.MasklengthRange, "-")
+	if len(lenRange) != 2 {
+		return false
+	}
+	low, err := strconv.Atoi(lenRange[0])
+	if err != nil {
+		return false
+	}
+	high, err := strconv.Atoi(lenRange[1])
+	if err != nil {
+		return false
+	}
+	return destLen >= low && destLen <= high
+}
@@ -0,0 +1,101 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// rpki.go
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"l3/bgp/rpki"
+	"sync"
+)
+
+type RPKIConditionConfig struct {
+	Name            string
+	ValidationState string
+}
+
+type RPKIDB struct {
+	sync.RWMutex
+	conditions map[string]rpki.ValidationState
+}
+
+func NewRPKIDB() *RPKIDB {
+	return &RPKIDB{
+		conditions: make(map[string]rpki.ValidationState),
+	}
+}
+
+func (db *RPKIDB) CreateCondition(cfg RPKIConditionConfig) error {
+	state, err := rpki.ParseValidationState(cfg.ValidationState)
+	if err != nil {
+		return err
+	}
+
+	db.Lock()
+	defer db.Unlock()
+	db.conditions[cfg.Name] = state
+	return nil
+}
+
+func (db *RPKIDB) DeleteCondition(name string) error {
+	db.Lock()
+	defer db.Unlock()
+	if _, ok := db.conditions[name]; !ok {
+		return errors.New(fmt.Sprintf("RPKI condition %s not found", name))
+	}
+	delete(db.conditions, name)
+	return nil
+}
+
+func (db *RPKIDB) HasConditions() bool {
+	db.RLock()
+	defer db.RUnlock()
+	return len(db.conditions) > 0
+}
+
+// IsConditionInList returns true if any of the names in the list is an RPKI condition
+func (db *RPKIDB) IsConditionInList(names []string) bool {
+	db.RLock()
+	defer db.RUnlock()
+	for _, name := range names {
+		if _, ok := db.conditions[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchConditions returns false if any of the RPKI conditions in the list does not match the validation state.
+// Names that are not RPKI conditions are skipped.
+func (db *RPKIDB) MatchConditions(names []string, state rpki.ValidationState) bool {
+	db.RLock()
+	defer db.RUnlock()
+	for _, name := range names {
+		if condState, ok := db.conditions[name]; ok && condState != state {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,286 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// dampening.go
+package rib
+
+import (
+	"l3/bgp/config"
+	"math"
+	"time"
+)
+
+const (
+	DampeningWithdrawPenalty   = 1000
+	DampeningAttrChangePenalty = 500
+)
+
+// dampeningKey identifies a path of a destination by the peer and the path id, so that the paths advertised by a
+// peer with ADD-PATH are dampened independently
+type dampeningKey struct {
+	peerIP string
+	pathId uint32
+}
+
+// DampeningInfo tracks the flap penalty of a path of a destination from one peer (RFC 2439)
+type DampeningInfo struct {
+	Config       *config.DampeningConfig
+	Penalty      float64
+	FlapCount    uint32
+	LastUpdate   time.Time
+	Suppressed   bool
+	SuppressTime time.Time
+}
+
+func NewDampeningInfo(dampConf *config.DampeningConfig) *DampeningInfo {
+	return &DampeningInfo{
+		Config:     dampConf,
+		LastUpdate: time.Now(),
+	}
+}
+
+func (d *DampeningInfo) maxPenalty() float64 {
+	return float64(d.Config.ReuseThreshold) *
+		math.Pow(2, float64(d.Config.MaxSuppressTime)/float64(d.Config.HalfLife))
+}
+
+func (d *DampeningInfo) decay(now time.Time) {
+	elapsed := now.Sub(d.LastUpdate).Seconds()
+	if elapsed > 0 && d.Config.HalfLife > 0 {
+		d.Penalty = d.Penalty * math.Pow(0.5, elapsed/float64(d.Config.HalfLife))
+	}
+	d.LastUpdate = now
+}
+
+// penalize adds the penalty for a flap and returns true if the path got suppressed
+func (d *DampeningInfo) penalize(penalty float64, now time.Time) bool {
+	d.decay(now)
+	d.FlapCount++
+	d.Penalty += penalty
+	if maxPenalty := d.maxPenalty(); d.Penalty > maxPenalty {
+		d.Penalty = maxPenalty
+	}
+
+	if !d.Suppressed && d.Penalty >= float64(d.Config.SuppressThreshold) {
+		d.Suppressed = true
+		d.SuppressTime = now
+		return true
+	}
+	return false
+}
+
+// reuse decays the penalty and returns true if a suppressed path can be used again
+func (d *DampeningInfo) reuse(now time.Time) bool {
+	d.decay(now)
+	if !d.Suppressed {
+		return false
+	}
+
+	if d.Penalty < float64(d.Config.ReuseThreshold) ||
+		now.Sub(d.SuppressTime) >= time.Duration(d.Config.MaxSuppressTime)*time.Second {
+		d.Suppressed = false
+		return true
+	}
+	return false
+}
+
+// The flap history is kept until the penalty falls below half the reuse threshold
+func (d *DampeningInfo) canForget() bool {
+	return !d.Suppressed && d.Penalty < float64(d.Config.ReuseThreshold)/2
+}
+
+// isAttrChange returns true if the update changed the attributes used by the best path selection. Only these
+// changes are penalized, updates that change other attributes are not route flaps.
+func isAttrChange(oldPath, newPath *Path, protoFamily uint32) bool {
+	if oldPath.GetOrigin() != newPath.GetOrigin() || oldPath.MED != newPath.MED ||
+		oldPath.LocalPref != newPath.LocalPref ||
+		!oldPath.GetNextHop(protoFamily).Equal(newPath.GetNextHop(protoFamily)) {
+		return true
+	}
+
+	oldASes := oldPath.GetAS4ByteList()
+	newASes := newPath.GetAS4ByteList()
+	if len(oldASes) != len(newASes) {
+		return true
+	}
+	for idx, as := range oldASes {
+		if newASes[idx] != as {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Destination) penalizePath(peerIP string, pathId uint32, path *Path, penalty float64) {
+	dampConf := path.GetDampeningConfig()
+	if dampConf == nil {
+		return
+	}
+
+	key := dampeningKey{peerIP, pathId}
+	info, ok := d.dampInfo[key]
+	if !ok {
+		info = NewDampeningInfo(dampConf)
+		d.dampInfo[key] = info
+	}
+	d.rib.addDampeningInfo(d)
+	info.Config = dampConf
+	if info.penalize(penalty, time.Now()) {
+		d.logger.Infof("Destination %s path id %d from peer %s is suppressed, penalty %d flaps %d",
+			d.NLRI.GetCIDR(), pathId, peerIP, uint32(info.Penalty), info.FlapCount)
+		d.recalculate = true
+	}
+	d.setDampeningState(peerIP)
+}
+
+func (d *Destination) isSuppressed(peerIP string, pathId uint32) bool {
+	if info, ok := d.dampInfo[dampeningKey{peerIP, pathId}]; ok {
+		return info.Suppressed
+	}
+	return false
+}
+
+func (d *Destination) setDampeningState(peerIP string) {
+	for pathId, path := range d.peerPathMap[peerIP] {
+		if route, ok := d.pathRouteMap[path]; ok {
+			route.SetDampeningState(d.dampInfo[dampeningKey{peerIP, pathId}])
+		}
+	}
+}
+
+func (d *Destination) resetDampening() bool {
+	suppressed := false
+	for key, info := range d.dampInfo {
+		if info.Suppressed {
+			suppressed = true
+		}
+		delete(d.dampInfo, key)
+		d.setDampeningState(key.peerIP)
+	}
+	if suppressed {
+		d.recalculate = true
+	}
+	return suppressed
+}
+
+func (l *LocRib) addDampeningInfo(dest *Destination) {
+	cidr := dest.NLRI.GetCIDR()
+	if _, ok := l.dampHistory[dest.protoFamily]; !ok {
+		l.dampHistory[dest.protoFamily] = make(map[string]map[dampeningKey]*DampeningInfo)
+	}
+	l.dampHistory[dest.protoFamily][cidr] = dest.dampInfo
+}
+
+func (l *LocRib) getDampeningInfo(protoFamily uint32, cidr string) map[dampeningKey]*DampeningInfo {
+	if dampInfo, ok := l.dampHistory[protoFamily][cidr]; ok {
+		return dampInfo
+	}
+	return make(map[dampeningKey]*DampeningInfo)
+}
+
+func (l *LocRib) HasDampeningInfo() bool {
+	return len(l.dampHistory) > 0
+}
+
+// ProcessDampeningReuse decays the flap penalties of all the dampened destinations and runs the best path
+// selection for the destinations with paths that are no longer suppressed.
+func (l *LocRib) ProcessDampeningReuse(addPathCount int) (map[uint32]map[*Path][]*Destination, []*Destination,
+	[]*Destination) {
+	withdrawn := make([]*Destination, 0)
+	updated := make(map[uint32]map[*Path][]*Destination)
+	updatedAddPaths := make([]*Destination, 0)
+	now := time.Now()
+
+	for protoFamily, cidrMap := range l.dampHistory {
+		for cidr, dampInfo := range cidrMap {
+			reused := false
+			for key, info := range dampInfo {
+				if info.reuse(now) {
+					l.logger.Infof("LocRib - destination %s path id %d from peer %s is reused, penalty %d", cidr,
+						key.pathId, key.peerIP, uint32(info.Penalty))
+					reused = true
+				}
+				if info.canForget() {
+					delete(dampInfo, key)
+				}
+			}
+			if len(dampInfo) == 0 {
+				delete(cidrMap, cidr)
+			}
+
+			dest, ok := l.destPathMap[protoFamily][cidr]
+			if !ok {
+				continue
+			}
+			for peerIP, _ := range dest.peerPathMap {
+				dest.setDampeningState(peerIP)
+			}
+			if reused {
+				dest.recalculate = true
+				action, addPathsMod, addRoutes, updRoutes, delRoutes := dest.SelectRouteForLocRib(addPathCount)
+				updated, withdrawn, updatedAddPaths = l.updateRibOutInfo(action, addPathsMod, addRoutes, updRoutes,
+					delRoutes, dest, updated, withdrawn, updatedAddPaths)
+				l.stateDBMgr.UpdateObject(l.GetRouteStateConfigObj(dest.GetBGPRoute()))
+			}
+		}
+		if len(cidrMap) == 0 {
+			delete(l.dampHistory, protoFamily)
+		}
+	}
+
+	return updated, withdrawn, updatedAddPaths
+}
+
+// ClearDampening removes the flap history of the prefix in all the address families and runs the best path
+// selection if any of the paths were suppressed.
+func (l *LocRib) ClearDampening(cidr string, addPathCount int) (map[uint32]map[*Path][]*Destination,
+	[]*Destination, []*Destination, bool) {
+	withdrawn := make([]*Destination, 0)
+	updated := make(map[uint32]map[*Path][]*Destination)
+	updatedAddPaths := make([]*Destination, 0)
+	found := false
+
+	for protoFamily, cidrMap := range l.dampHistory {
+		if _, ok := cidrMap[cidr]; !ok {
+			continue
+		}
+
+		found = true
+		delete(cidrMap, cidr)
+		if len(cidrMap) == 0 {
+			delete(l.dampHistory, protoFamily)
+		}
+
+		dest, ok := l.destPathMap[protoFamily][cidr]
+		if !ok || !dest.resetDampening() {
+			continue
+		}
+		l.logger.Infof("LocRib - cleared dampening for destination %s", cidr)
+		action, addPathsMod, addRoutes, updRoutes, delRoutes := dest.SelectRouteForLocRib(addPathCount)
+		updated, withdrawn, updatedAddPaths = l.updateRibOutInfo(action, addPathsMod, addRoutes, updRoutes,
+			delRoutes, dest, updated, withdrawn, updatedAddPaths)
+		l.stateDBMgr.UpdateObject(l.GetRouteStateConfigObj(dest.GetBGPRoute()))
+	}
+
+	return updated, withdrawn, updatedAddPaths, found
+}
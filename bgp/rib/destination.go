@@ -29,6 +29,7 @@ import (
 	"fmt"
 	"l3/bgp/config"
 	"l3/bgp/packet"
+	"l3/bgp/rpki"
 	"math"
 	"net"
 	"sort"
@@ -39,6 +40,22 @@ import (
 const BGP_INTERNAL_PREF = 100
 const BGP_EXTERNAL_PREF = 100
 
+// Best path selection steps, recorded per destination to show the step that decided the best path
+const (
+	BestPathReasonOnlyPath    = "Only path"
+	BestPathReasonRouteSource = "Route source"
+	BestPathReasonLocalPref   = "Local preference"
+	BestPathReasonValidation  = "Origin validation state"
+	BestPathReasonASPathLen   = "AS path length"
+	BestPathReasonOrigin      = "Origin"
+	BestPathReasonMED         = "MED"
+	BestPathReasonEBGP        = "eBGP over iBGP"
+	BestPathReasonOldestPath  = "Oldest path"
+	BestPathReasonRouterId    = "Router id"
+	BestPathReasonClusterLen  = "Cluster list length"
+	BestPathReasonPeerAddress = "Peer address"
+)
+
 type PathAndRoute struct {
 	Path
 }
@@ -50,11 +67,14 @@ type Destination struct {
 	NLRI              packet.NLRI
 	protoFamily       uint32
 	peerPathMap       map[string]map[uint32]*Path
+	stalePaths        map[*Path]bool
 	LocRibPath        *Path
 	LocRibPathRoute   *Route
 	aggPath           *Path
 	aggregatedDestMap map[string]*Destination
 	ecmpPaths         map[*Path]*Route
+	BackupPath        *Path
+	nextHopGroups     map[*Path]*NextHopGroup
 	pathRouteMap      map[*Path]*Route
 	AddPaths          []*Path
 	maxPathId         uint32
@@ -63,6 +83,8 @@ type Destination struct {
 	BGPRouteState     config.ModelRouteIntf
 	PathInfoRouteMap  map[*bgpd.PathInfo]*Route
 	routeListIdx      int
+	BestPathReason    string
+	dampInfo          map[dampeningKey]*DampeningInfo
 }
 
 func NewDestination(rib *LocRib, nlri packet.NLRI, protoFamily uint32, gConf *config.GlobalConfig) *Destination {
@@ -73,7 +95,9 @@ func NewDestination(rib *LocRib, nlri packet.NLRI, protoFamily uint32, gConf *co
 		NLRI:              nlri,
 		protoFamily:       protoFamily,
 		peerPathMap:       make(map[string]map[uint32]*Path),
+		stalePaths:        make(map[*Path]bool),
 		ecmpPaths:         make(map[*Path]*Route),
+		nextHopGroups:     make(map[*Path]*NextHopGroup),
 		aggregatedDestMap: make(map[string]*Destination),
 		pathRouteMap:      make(map[*Path]*Route),
 		AddPaths:          make([]*Path, 0),
@@ -81,6 +105,7 @@ func NewDestination(rib *LocRib, nlri packet.NLRI, protoFamily uint32, gConf *co
 		pathIds:           make([]uint32, 0),
 		routeListIdx:      -1,
 		PathInfoRouteMap:  make(map[*bgpd.PathInfo]*Route),
+		dampInfo:          make(map[dampeningKey]*DampeningInfo),
 	}
 
 	dest.setBGPRouteState(protoFamily, nlri.GetPrefix().String(), int16(nlri.GetLength()))
@@ -88,8 +113,19 @@ func NewDestination(rib *LocRib, nlri packet.NLRI, protoFamily uint32, gConf *co
 }
 
 func (d *Destination) setBGPRouteState(protoFamily uint32, network string, cidrLen int16) {
-	afi, _ := packet.GetAfiSafi(protoFamily)
-	if afi == packet.AfiIP6 {
+	afi, safi := packet.GetAfiSafi(protoFamily)
+	if safi == packet.SafiFlowSpec {
+		d.BGPRouteState = NewFlowSpecRoute(d.NLRI.GetCIDR(), packet.GetProtocolFamilyStr(protoFamily))
+	} else if nlri, ok := d.NLRI.(*packet.EVPNNLRI); ok {
+		d.BGPRouteState = NewEVPNRoute(d.NLRI.GetCIDR(), packet.EVPNRouteTypeToStrMap[nlri.RouteType],
+			nlri.Route.GetRD().String())
+	} else if nlri, ok := d.NLRI.(*packet.VPNNLRI); ok {
+		d.BGPRouteState = NewVPNRoute(d.NLRI.GetCIDR(), nlri.RD.String())
+	} else if nlri, ok := d.NLRI.(*packet.LinkStateNLRI); ok {
+		d.BGPRouteState = NewLinkStateRoute(d.NLRI.GetCIDR(), packet.LinkStateNLRITypeToStrMap[nlri.NLRIType])
+	} else if d.rib.vrf != "" {
+		d.BGPRouteState = NewVRFRoute(d.rib.vrf, network, cidrLen)
+	} else if afi == packet.AfiIP6 {
 		d.BGPRouteState = NewIPv6Route(network, cidrLen)
 	} else {
 		d.BGPRouteState = NewIPv4Route(network, cidrLen)
@@ -113,10 +149,35 @@ func (d *Destination) GetPathRoute(path *Path) *Route {
 	return nil
 }
 
+// GetECMPPaths returns the paths installed for the destination, in the order of their path ids
+func (d *Destination) GetECMPPaths() []*Path {
+	pathIds := make([]int, 0, len(d.ecmpPaths))
+	pathIdMap := make(map[int]*Path)
+	for path, route := range d.ecmpPaths {
+		pathIds = append(pathIds, int(route.OutPathId))
+		pathIdMap[int(route.OutPathId)] = path
+	}
+	sort.Ints(pathIds)
+
+	paths := make([]*Path, 0, len(pathIds))
+	for _, pathId := range pathIds {
+		paths = append(paths, pathIdMap[pathId])
+	}
+	return paths
+}
+
 func (d *Destination) GetProtocolFamily() uint32 {
 	return d.protoFamily
 }
 
+func (d *Destination) GetLocRib() *LocRib {
+	return d.rib
+}
+
+func (d *Destination) GetPeerPathMap() map[string]map[uint32]*Path {
+	return d.peerPathMap
+}
+
 func (d *Destination) String() string {
 	return d.NLRI.String()
 }
@@ -250,6 +311,7 @@ func (d *Destination) AddOrUpdatePath(peerIp string, pathId uint32, path *Path)
 		if d.LocRibPath == oldPath {
 			d.LocRibPath = nil
 		}
+		delete(d.stalePaths, oldPath)
 	} else {
 		d.logger.Infof("Destination %s New path from %s, id %d", d.NLRI.GetPrefix(), peerIp, pathId)
 		added = true
@@ -272,6 +334,7 @@ func (d *Destination) AddOrUpdatePath(peerIp string, pathId uint32, path *Path)
 	d.PathInfoRouteMap[route.PathInfo] = route
 	route.setIdx(idx)
 	d.peerPathMap[peerIp][pathId] = path
+	d.setDampeningState(peerIp)
 	return added
 }
 
@@ -296,9 +359,14 @@ func (d *Destination) RemovePath(peerIP string, pathId uint32, path *Path) *Path
 			d.LocRibPath = nil
 		}
 
+		if d.BackupPath == oldPath {
+			d.recalculate = true
+		}
+
 		route := d.pathRouteMap[oldPath]
 		d.releasePathId(route.OutPathId)
 		delete(d.pathRouteMap, oldPath)
+		delete(d.stalePaths, oldPath)
 		if route.routeListIdx != -1 {
 			newPath := d.BGPRouteState.GetLastPath()
 			if newRoute, ok := d.PathInfoRouteMap[newPath]; ok {
@@ -336,10 +404,44 @@ func (d *Destination) RemoveAllPaths(peerIP string, path *Path) {
 	}
 }
 
+func (d *Destination) MarkStalePaths(peerIP string) int {
+	count := 0
+	for _, path := range d.peerPathMap[peerIP] {
+		if !d.stalePaths[path] {
+			d.stalePaths[path] = true
+			count++
+		}
+	}
+	return count
+}
+
+func (d *Destination) IsPathStale(path *Path) bool {
+	return d.stalePaths[path]
+}
+
+func (d *Destination) HasStalePaths(peerIP string) bool {
+	for _, path := range d.peerPathMap[peerIP] {
+		if d.stalePaths[path] {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Destination) RemoveStalePaths(peerIP string, path *Path) {
+	for pathId, stalePath := range d.peerPathMap[peerIP] {
+		if d.stalePaths[stalePath] {
+			d.logger.Info("Remove stale path id", pathId, "for", d.NLRI.GetCIDR(), "from peer", peerIP)
+			d.RemovePath(peerIP, pathId, path)
+		}
+	}
+}
+
 func (d *Destination) RemoveAllNeighborPaths() {
 	for peerIP, pathMap := range d.peerPathMap {
 		for pathId, path := range pathMap {
 			if path.NeighborConf != nil {
+				delete(d.stalePaths, path)
 				delete(d.peerPathMap[peerIP], pathId)
 				if len(d.peerPathMap[peerIP]) == 0 {
 					delete(d.peerPathMap, peerIP)
@@ -424,11 +526,133 @@ func (d *Destination) ConstructRouteConfig(path *Path, reachInfo *ReachabilityIn
 		OutgoingInterface: strconv.Itoa(int(reachInfo.NextHopIfIdx)),
 		IsIPv6:            isIPv6,
 		NullRoute:         nullRoute,
+		Vrf:               d.rib.vrf,
+	}
+
+	return &cfg
+}
+
+func (d *Destination) ConstructFlowSpecRule(path *Path) *config.FlowSpecRule {
+	protocol := "IBGP"
+	if path.IsExternal() {
+		protocol = "EBGP"
+	}
+	afi, _ := packet.GetAfiSafi(d.protoFamily)
+
+	cfg := config.FlowSpecRule{
+		Rule:     d.NLRI.GetCIDR(),
+		Protocol: protocol,
+		IsIPv6:   afi == packet.AfiIP6,
+		Matches:  make([]config.FlowSpecMatch, 0),
+	}
+	if nlri, ok := d.NLRI.(*packet.FlowSpecNLRI); ok {
+		cfg.NLRI, _ = nlri.Encode(afi)
+		for _, component := range nlri.Components {
+			switch c := component.(type) {
+			case *packet.FlowSpecPrefix:
+				cidr := c.GetIPPrefix().GetCIDR()
+				if c.Type == packet.FlowSpecTypeDstPrefix {
+					cfg.DestinationNw = cidr
+				} else {
+					cfg.SourceNw = cidr
+				}
+			case *packet.FlowSpecOpComponent:
+				cfg.Matches = append(cfg.Matches, config.FlowSpecMatch{
+					Type:  c.Type,
+					Name:  packet.FlowSpecTypeToStrMap[c.Type],
+					Value: c.ValueString(),
+				})
+			}
+		}
+	}
+
+	for _, action := range packet.GetFlowSpecActions(path.PathAttrs) {
+		switch action.SubType {
+		case packet.BGPExtCommunitySubTypeTrafficRateBytes, packet.BGPExtCommunitySubTypeTrafficRatePackets:
+			cfg.RateLimit = true
+			cfg.Rate = action.Rate
+			cfg.RateInPackets = action.SubType == packet.BGPExtCommunitySubTypeTrafficRatePackets
+		case packet.BGPExtCommunitySubTypeTrafficAction:
+			cfg.Sample = action.Sample
+			cfg.Terminal = action.Terminal
+		case packet.BGPExtCommunitySubTypeRedirect:
+			cfg.Redirect = action.Target
+		case packet.BGPExtCommunitySubTypeTrafficMarking:
+			cfg.MarkDSCP = true
+			cfg.DSCP = action.DSCP
+		}
 	}
 
 	return &cfg
 }
 
+func (d *Destination) ConstructEVPNInfo(path *Path) (*config.EVPNVtepInfo, *config.EVPNMacInfo) {
+	nlri, ok := d.NLRI.(*packet.EVPNNLRI)
+	if !ok {
+		return nil, nil
+	}
+
+	if encap, ok := packet.GetEncapsulation(path.PathAttrs); ok && encap != packet.TunnelTypeVXLAN {
+		d.logger.Infof("EVPN route %s has encapsulation %d, only VXLAN is supported", d.NLRI.GetCIDR(), encap)
+		return nil, nil
+	}
+
+	vtepIP := path.GetNextHop(d.protoFamily)
+	switch route := nlri.Route.(type) {
+	case *packet.EVPNInclusiveMulticast:
+		vni := route.EthTag
+		if pmsi := packet.GetPMSITunnel(path.PathAttrs); pmsi != nil {
+			vni = pmsi.Label
+			if pmsi.TunnelId != nil {
+				vtepIP = pmsi.TunnelId
+			}
+		}
+		return &config.EVPNVtepInfo{
+			Vni:    vni,
+			VtepIP: vtepIP.String(),
+		}, nil
+
+	case *packet.EVPNMacIPAdvertisement:
+		ip := ""
+		if route.IP != nil {
+			ip = route.IP.String()
+		}
+		return nil, &config.EVPNMacInfo{
+			Vni:    route.GetVNI(),
+			MAC:    route.MAC.String(),
+			IP:     ip,
+			VtepIP: vtepIP.String(),
+		}
+	}
+
+	return nil, nil
+}
+
+func getEVPNInfoVni(vtep *config.EVPNVtepInfo, mac *config.EVPNMacInfo) uint32 {
+	if vtep != nil {
+		return vtep.Vni
+	}
+	if mac != nil {
+		return mac.Vni
+	}
+	return 0
+}
+
+// isEVPNRouteImported returns true if the VNI is a local VNI and the path carries the route target of the VNI. Only
+// the VNI of the route target is compared (RFC 8365 section 5.1.2.1), PEs in other ASes derive it with their AS.
+func (d *Destination) isEVPNRouteImported(path *Path, vni uint32) bool {
+	if !d.rib.evpnVnis[vni] {
+		return false
+	}
+	for _, community := range packet.GetExtCommunities(path.PathAttrs) {
+		if uint8(community>>56) == packet.BGPExtCommunityTypeAS2 &&
+			uint8(community>>48) == packet.BGPExtCommunitySubTypeRT && uint32(community)&0xFFFFFF == vni {
+			return true
+		}
+	}
+	return false
+}
+
 func (d *Destination) SelectRouteForLocRib(addPathCount int) (RouteAction, bool, []*Route, []*Route, []*Route) {
 	updatedPaths := make([]*Path, 0)
 	removedPaths := make([]*Path, 0)
@@ -445,6 +669,11 @@ func (d *Destination) SelectRouteForLocRib(addPathCount int) (RouteAction, bool,
 	if !d.recalculate {
 		return locRibAction, addPathsUpdated, addedRoutes, updatedRoutes, deletedRoutes
 	}
+	if d.rib.IsSelectionDeferred() {
+		d.logger.Infof("Destination %s - best path selection is deferred", d.NLRI.GetPrefix())
+		d.rib.addDeferredDest(d)
+		return locRibAction, addPathsUpdated, addedRoutes, updatedRoutes, deletedRoutes
+	}
 	d.recalculate = false
 
 	if d.LocRibPath != nil {
@@ -454,14 +683,20 @@ func (d *Destination) SelectRouteForLocRib(addPathCount int) (RouteAction, bool,
 		} else {
 			peerIP = d.gConf.RouterId.String()
 		}
-		routeSrc = getRouteSource(d.LocRibPath.routeType)
-		updatedPaths = append(updatedPaths, d.LocRibPath)
-		d.logger.Infof("Destination %s Add loc rib path %v from %s to path selection, source=%d",
-			d.NLRI.GetPrefix(), d.LocRibPath, peerIP, routeSrc)
+		if route, ok := d.pathRouteMap[d.LocRibPath]; ok && d.isSuppressed(peerIP, uint32(route.PathInfo.PathId)) {
+			d.logger.Infof("Destination %s loc rib path %v from %s is suppressed", d.NLRI.GetPrefix(),
+				d.LocRibPath, peerIP)
+			d.LocRibPath = nil
+		} else {
+			routeSrc = getRouteSource(d.LocRibPath.routeType)
+			updatedPaths = append(updatedPaths, d.LocRibPath)
+			d.logger.Infof("Destination %s Add loc rib path %v from %s to path selection, source=%d",
+				d.NLRI.GetPrefix(), d.LocRibPath, peerIP, routeSrc)
+		}
 	}
 
 	for peerIP, pathMap := range d.peerPathMap {
-		for _, path := range pathMap {
+		for pathId, path := range pathMap {
 			if d.LocRibPath == nil || d.LocRibPath != path {
 				if !path.IsReachable(d.protoFamily) {
 					d.logger.Infof("Destination %s peer %s, NEXT_HOP %s is not reachable", d.NLRI.GetPrefix(), peerIP,
@@ -469,6 +704,12 @@ func (d *Destination) SelectRouteForLocRib(addPathCount int) (RouteAction, bool,
 					continue
 				}
 
+				if d.isSuppressed(peerIP, pathId) {
+					d.logger.Infof("Destination %s peer %s, path id %d is suppressed by dampening",
+						d.NLRI.GetPrefix(), peerIP, pathId)
+					continue
+				}
+
 				if path.HasASLoop() {
 					d.logger.Infof("Destination %s peer %s, path has AS %d loop", d.NLRI.GetPrefix(),
 						peerIP, path.NeighborConf.RunningConf.LocalAS)
@@ -507,9 +748,18 @@ func (d *Destination) SelectRouteForLocRib(addPathCount int) (RouteAction, bool,
 	if len(updatedPaths) > 0 {
 		var ecmpPaths [][]*Path
 		var addPaths []*Path
+		var backupPaths []*Path
+		if d.gConf.InstallBackupPath && routeSrc == RouteSrcExternal && d.isBackupPathFamily() {
+			backupPaths = append(backupPaths, updatedPaths...)
+		}
+		d.BestPathReason = BestPathReasonOnlyPath
+		if len(removedPaths) > 0 {
+			d.BestPathReason = BestPathReasonRouteSource
+		}
 		if len(updatedPaths) > 1 || (addPathCount > 0) {
 			d.logger.Infof("Found multiple paths with same pref, run path selection algorithm")
-			if d.gConf.UseMultiplePaths {
+			if d.gConf.UseMultiplePaths && !packet.IsFlowSpecFamily(d.protoFamily) &&
+				!packet.IsEVPNFamily(d.protoFamily) && !packet.IsLinkStateFamily(d.protoFamily) {
 				updatedPaths, ecmpPaths, addPaths =
 					d.calculateBestPath(updatedPaths, removedPaths, d.gConf.EBGPMaxPaths > 1, d.gConf.IBGPMaxPaths > 1,
 						addPathCount)
@@ -582,8 +832,12 @@ func (d *Destination) SelectRouteForLocRib(addPathCount int) (RouteAction, bool,
 
 		d.LocRibPath = ecmpPaths[0][0]
 		d.LocRibPathRoute = d.ecmpPaths[d.LocRibPath]
+		if d.LocRibPathRoute != nil {
+			d.LocRibPathRoute.SetBestPathReason(d.BestPathReason)
+		}
 		d.logger.Infof("Destination %s loc rib path %v route %v, d.ecmpPaths %v ecmpPaths %v",
 			d.NLRI.GetPrefix(), d.LocRibPath, d.LocRibPathRoute, d.ecmpPaths, ecmpPaths)
+		d.setBackupPath(d.selectBackupPath(backupPaths, ecmpPaths))
 	} else {
 		// Remove route
 		for _, route := range d.ecmpPaths {
@@ -591,16 +845,34 @@ func (d *Destination) SelectRouteForLocRib(addPathCount int) (RouteAction, bool,
 		}
 		locRibAction = RouteActionDelete
 		d.LocRibPath = nil
+		d.BestPathReason = ""
+		d.setBackupPath(nil)
 	}
 
 	for path, route := range d.ecmpPaths {
 		if route.action == RouteActionNone || route.action == RouteActionDelete {
-			if !path.IsLocal() || path.IsAggregate() {
+			if packet.IsFlowSpecFamily(d.protoFamily) {
+				d.logger.Info("Remove flowspec rule", d.NLRI.GetCIDR(), "from ECMP paths, route =", route)
+				d.rib.deleteFlowSpecRule(d.ConstructFlowSpecRule(path))
+			} else if packet.IsEVPNFamily(d.protoFamily) {
+				if !path.IsLocal() {
+					d.logger.Info("Remove EVPN route", d.NLRI.GetCIDR(), "from ECMP paths, route =", route)
+					if vtep, mac := d.ConstructEVPNInfo(path); d.isEVPNRouteImported(path, getEVPNInfoVni(vtep, mac)) {
+						d.rib.deleteEVPNRoute(vtep, mac)
+					}
+				}
+			} else if packet.IsVPNFamily(d.protoFamily) {
+				d.logger.Info("Remove VPN route", d.NLRI.GetCIDR(), "from ECMP paths, route =", route)
+			} else if packet.IsLinkStateFamily(d.protoFamily) {
+				d.logger.Info("Remove link state route", d.NLRI.GetCIDR(), "from ECMP paths, route =", route)
+			} else if !path.IsLocal() || path.IsAggregate() {
 				reachInfo := path.GetReachability(d.protoFamily)
 				d.logger.Info("Remove route from ECMP paths, route =", route, "ip =",
 					d.NLRI.GetCIDR(), "next hop =", reachInfo.NextHop)
 				cfg := d.ConstructRouteConfig(path, reachInfo, ipLength)
+				cfg.NextHopGroupId = d.getNextHopGroupId(path)
 				d.rib.routeMgr.UpdateRoute(cfg, "remove")
+				d.removeNextHopGroup(path)
 				d.logger.Info("DeleteV4Route from ECMP paths, route =", route, "ip =",
 					d.NLRI.GetCIDR(), "next hop =", reachInfo.NextHop, "DONE")
 			}
@@ -610,20 +882,63 @@ func (d *Destination) SelectRouteForLocRib(addPathCount int) (RouteAction, bool,
 			delete(d.ecmpPaths, path)
 		} else {
 			route.setAction(RouteActionNone)
+			if d.isBackupPathFamily() && !path.IsLocal() && !isPathInList(createRibRoutes, path) {
+				// Move the installed route to the next hop group of the new backup path
+				groupId, oldGroup, updated := d.updateNextHopGroup(path)
+				if updated {
+					cfg := d.ConstructRouteConfig(path, path.GetReachability(d.protoFamily), ipLength)
+					cfg.NextHopGroupId = groupId
+					d.rib.routeMgr.UpdateRoute(cfg, "replace")
+				}
+				if oldGroup != nil {
+					d.rib.releaseNextHopGroup(oldGroup)
+				}
+			}
 		}
 	}
 
 	for _, path := range createRibRoutes {
+		if packet.IsFlowSpecFamily(d.protoFamily) {
+			d.logger.Infof("Add flowspec rule %s", d.NLRI.GetCIDR())
+			d.rib.createFlowSpecRule(d.ConstructFlowSpecRule(path))
+			continue
+		}
+		if packet.IsEVPNFamily(d.protoFamily) {
+			vtep, mac := d.ConstructEVPNInfo(path)
+			if !d.isEVPNRouteImported(path, getEVPNInfoVni(vtep, mac)) {
+				d.logger.Infof("EVPN route %s is not imported, no local VNI with its route target",
+					d.NLRI.GetCIDR())
+				continue
+			}
+			d.logger.Infof("Add EVPN route %s", d.NLRI.GetCIDR())
+			d.rib.createEVPNRoute(vtep, mac)
+			continue
+		}
+		if packet.IsVPNFamily(d.protoFamily) {
+			// VPN routes are installed by importing them into the VRF tables
+			d.logger.Infof("Add VPN route %s", d.NLRI.GetCIDR())
+			continue
+		}
+		if packet.IsLinkStateFamily(d.protoFamily) {
+			// BGP-LS routes describe the IGP topology and are not installed in the dataplane
+			d.logger.Infof("Add link state route %s", d.NLRI.GetCIDR())
+			continue
+		}
 		reachInfo := path.GetReachability(d.protoFamily)
 		d.logger.Infof("Add route for ip=%s, mask=%s, next hop=%s", d.NLRI.GetCIDR(),
 			d.constructNetmaskFromLen(int(d.NLRI.GetLength()), ipLength*8).String(), reachInfo.NextHop)
 		cfg := d.ConstructRouteConfig(path, reachInfo, ipLength)
+		groupId, oldGroup, _ := d.updateNextHopGroup(path)
+		cfg.NextHopGroupId = groupId
 		if firstRoute {
 			d.rib.routeMgr.CreateRoute(cfg)
 			firstRoute = false
 		} else {
 			d.rib.routeMgr.UpdateRoute(cfg, "add")
 		}
+		if oldGroup != nil {
+			d.rib.releaseNextHopGroup(oldGroup)
+		}
 	}
 	return locRibAction, addPathsUpdated, addedRoutes, updatedRoutes, deletedRoutes
 }
@@ -679,6 +994,47 @@ func (d *Destination) getRoutesWithHighestPref(updatedPaths []*Path, prunedPaths
 	return updatedPaths, prunedPaths
 }
 
+func (d *Destination) getRoutesWithBestValidationState(updatedPaths []*Path, prunedPaths []PathSortIface) ([]*Path,
+	[]PathSortIface) {
+	bestState := rpki.ValidationStateInvalid + 1
+	removedPaths := make([]*Path, 0)
+	n := len(updatedPaths)
+	idx := 0
+
+	for i := 0; i < n; i++ {
+		state := updatedPaths[i].GetValidationState(d.NLRI)
+		d.logger.Info("Dest =", d.NLRI.GetPrefix(), "origin validation state =", state)
+		if state > bestState {
+			removedPaths = append(removedPaths, updatedPaths[i])
+		} else if state < bestState {
+			removedPaths = append(removedPaths, updatedPaths[:idx]...)
+			bestState = state
+			updatedPaths[0] = updatedPaths[i]
+			idx = 1
+		} else if state == bestState {
+			updatedPaths[idx] = updatedPaths[i]
+			idx++
+		}
+	}
+
+	if len(removedPaths) > 0 {
+		pathSortIface := PathSortIface{
+			paths: removedPaths,
+			iface: ByValidationState{removedPaths, d.NLRI},
+		}
+		prunedPaths = append(prunedPaths, pathSortIface)
+	}
+
+	if idx > 0 {
+		for i := idx; i < n; i++ {
+			updatedPaths[i] = nil
+		}
+		updatedPaths = updatedPaths[:idx]
+	}
+
+	return updatedPaths, prunedPaths
+}
+
 func (d *Destination) getRoutesWithSmallestAS(updatedPaths []*Path, prunedPaths []PathSortIface) ([]*Path,
 	[]PathSortIface) {
 	minASNums := uint32(4096)
@@ -725,6 +1081,66 @@ func (d *Destination) getRoutesWithSmallestAS(updatedPaths []*Path, prunedPaths
 	return updatedPaths, prunedPaths
 }
 
+func (d *Destination) getMEDCompareAS(path *Path) uint32 {
+	if d.gConf.BestPath.AlwaysCompareMED {
+		return 0
+	}
+
+	return path.GetNeighborAS()
+}
+
+// MEDs are compared only between the paths from the same neighbor AS unless always-compare-med is set. With
+// deterministic-med the paths are grouped by the neighbor AS, otherwise the MEDs are compared only when all the
+// paths are from the same neighbor AS so that the result does not depend on the order of the paths.
+func (d *Destination) getRoutesWithLowestMED(updatedPaths []*Path, prunedPaths []PathSortIface) ([]*Path,
+	[]PathSortIface) {
+	bestPathConf := d.gConf.BestPath
+	lowestMED := make(map[uint32]uint32)
+	removedPaths := make([]*Path, 0)
+	n := len(updatedPaths)
+	idx := 0
+
+	if !bestPathConf.DeterministicMED {
+		firstAS := d.getMEDCompareAS(updatedPaths[0])
+		for i := 1; i < n; i++ {
+			if d.getMEDCompareAS(updatedPaths[i]) != firstAS {
+				return updatedPaths, prunedPaths
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		as := d.getMEDCompareAS(updatedPaths[i])
+		med := updatedPaths[i].GetMED(bestPathConf.MEDMissingAsWorst)
+		if currMED, ok := lowestMED[as]; !ok || med < currMED {
+			lowestMED[as] = med
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		minMED, ok := lowestMED[d.getMEDCompareAS(updatedPaths[i])]
+		if ok && updatedPaths[i].GetMED(bestPathConf.MEDMissingAsWorst) > minMED {
+			removedPaths = append(removedPaths, updatedPaths[i])
+			continue
+		}
+		updatedPaths[idx] = updatedPaths[i]
+		idx++
+	}
+
+	if len(removedPaths) > 0 {
+		pathSortIface := PathSortIface{
+			paths: removedPaths,
+			iface: ByLowestMED{removedPaths, bestPathConf.MEDMissingAsWorst},
+		}
+		prunedPaths = append(prunedPaths, pathSortIface)
+	}
+
+	for i := idx; i < n; i++ {
+		updatedPaths[i] = nil
+	}
+	return updatedPaths[:idx], prunedPaths
+}
+
 func (d *Destination) getRoutesWithLowestOrigin(updatedPaths []*Path, prunedPaths []PathSortIface) ([]*Path,
 	[]PathSortIface) {
 	minOrigin := uint8(packet.BGPPathAttrOriginMax)
@@ -819,6 +1235,40 @@ func (d *Destination) isIBGPRoute(path *Path) bool {
 	return false
 }
 
+// getOldestEBGPRoute keeps the current best path, the oldest path, if all the paths are from external peers.
+// It avoids the route flaps caused by comparing the router ids of the eBGP paths.
+func (d *Destination) getOldestEBGPRoute(updatedPaths []*Path, prunedPaths []PathSortIface) ([]*Path,
+	[]PathSortIface) {
+	oldestIdx := -1
+	for i, path := range updatedPaths {
+		if !d.isEBGPRoute(path) {
+			return updatedPaths, prunedPaths
+		}
+		if path == d.LocRibPath {
+			oldestIdx = i
+		}
+	}
+
+	if oldestIdx == -1 {
+		return updatedPaths, prunedPaths
+	}
+
+	removedPaths := make([]*Path, 0, len(updatedPaths)-1)
+	removedPaths = append(removedPaths, updatedPaths[:oldestIdx]...)
+	removedPaths = append(removedPaths, updatedPaths[oldestIdx+1:]...)
+	pathSortIface := PathSortIface{
+		paths: removedPaths,
+		iface: ByLowestBGPId{removedPaths},
+	}
+	prunedPaths = append(prunedPaths, pathSortIface)
+
+	updatedPaths[0] = updatedPaths[oldestIdx]
+	for i := 1; i < len(updatedPaths); i++ {
+		updatedPaths[i] = nil
+	}
+	return updatedPaths[:1], prunedPaths
+}
+
 func (d *Destination) getRoutesWithLowestBGPId(updatedPaths []*Path, prunedPaths []PathSortIface) ([]*Path,
 	[]PathSortIface) {
 	removedPaths := make([]*Path, 0)
@@ -981,6 +1431,55 @@ func (d *Destination) getECMPPaths(updatedPaths []*Path) [][]*Path {
 	return ecmpPaths
 }
 
+func isSameASPath(path1, path2 *Path) bool {
+	asList1 := path1.GetAS4ByteList()
+	asList2 := path2.GetAS4ByteList()
+	if len(asList1) != len(asList2) {
+		return false
+	}
+
+	for i := 0; i < len(asList1); i++ {
+		if asList1[i] != asList2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// removeECMPPathsWithDiffASPath removes the multi paths whose AS path is not the same as the best path's when
+// multipath-same-as-path is set. It is not needed when multipath-relax is set, the paths only need the same AS path
+// length.
+func (d *Destination) removeECMPPathsWithDiffASPath(ecmpPaths [][]*Path, bestPath *Path) [][]*Path {
+	if !d.gConf.BestPath.MultipathSameASPath || d.gConf.BestPath.ASPathMultipathRelax ||
+		d.gConf.EBGPAllowMultipleAS {
+		return ecmpPaths
+	}
+
+	idx := 0
+	for _, paths := range ecmpPaths {
+		pathIdx := 0
+		for _, path := range paths {
+			if path == bestPath || isSameASPath(path, bestPath) {
+				paths[pathIdx] = path
+				pathIdx++
+			}
+		}
+		if pathIdx > 0 {
+			ecmpPaths[idx] = paths[:pathIdx]
+			idx++
+		}
+	}
+
+	d.logger.Info("removeECMPPathsWithDiffASPath: best path =", bestPath, "ecmpPaths =", ecmpPaths[:idx])
+	return ecmpPaths[:idx]
+}
+
+func (d *Destination) setBestPathReason(updatedPaths []*Path, reason string) {
+	if len(updatedPaths) == 1 {
+		d.BestPathReason = reason
+	}
+}
+
 func (d *Destination) addAddPaths(addPaths, currPaths []*Path, pathMap map[string]*Path) ([]*Path, map[string]*Path) {
 	currPathMap := make(map[string]*Path)
 	for _, path := range currPaths {
@@ -1011,16 +1510,31 @@ func (d *Destination) calculateBestPath(updatedPaths, removedPaths []*Path, ebgp
 	if len(updatedPaths) > 1 {
 		d.logger.Info("calling getRoutesWithHighestPref, update paths =", updatedPaths)
 		updatedPaths, prunedPaths = d.getRoutesWithHighestPref(updatedPaths, prunedPaths)
+		d.setBestPathReason(updatedPaths, BestPathReasonLocalPref)
 	}
 
-	if len(updatedPaths) > 1 {
+	if len(updatedPaths) > 1 && d.gConf.RPKI.PreferValid {
+		d.logger.Info("calling getRoutesWithBestValidationState, update paths =", updatedPaths)
+		updatedPaths, prunedPaths = d.getRoutesWithBestValidationState(updatedPaths, prunedPaths)
+		d.setBestPathReason(updatedPaths, BestPathReasonValidation)
+	}
+
+	if len(updatedPaths) > 1 && !d.gConf.BestPath.ASPathIgnore {
 		d.logger.Info("calling getRoutesWithSmallestAS, update paths =", updatedPaths)
 		updatedPaths, prunedPaths = d.getRoutesWithSmallestAS(updatedPaths, prunedPaths)
+		d.setBestPathReason(updatedPaths, BestPathReasonASPathLen)
 	}
 
 	if len(updatedPaths) > 1 {
 		d.logger.Info("calling getRoutesWithLowestOrigin, update paths =", updatedPaths)
 		updatedPaths, prunedPaths = d.getRoutesWithLowestOrigin(updatedPaths, prunedPaths)
+		d.setBestPathReason(updatedPaths, BestPathReasonOrigin)
+	}
+
+	if len(updatedPaths) > 1 && d.gConf.BestPath.CompareMED {
+		d.logger.Info("calling getRoutesWithLowestMED, update paths =", updatedPaths)
+		updatedPaths, prunedPaths = d.getRoutesWithLowestMED(updatedPaths, prunedPaths)
+		d.setBestPathReason(updatedPaths, BestPathReasonMED)
 	}
 
 	if (len(updatedPaths) > 1) && ebgpMultiPath && ibgpMultiPath {
@@ -1031,6 +1545,7 @@ func (d *Destination) calculateBestPath(updatedPaths, removedPaths []*Path, ebgp
 	if len(updatedPaths) > 1 {
 		d.logger.Info("calling removeIBGPRoutesIfEBGPExist, update paths =", updatedPaths)
 		updatedPaths, prunedPaths = d.removeIBGPRoutesIfEBGPExist(updatedPaths, prunedPaths)
+		d.setBestPathReason(updatedPaths, BestPathReasonEBGP)
 	}
 
 	if len(updatedPaths) > 1 && ibgpMultiPath != ebgpMultiPath {
@@ -1043,19 +1558,32 @@ func (d *Destination) calculateBestPath(updatedPaths, removedPaths []*Path, ebgp
 		}
 	}
 
+	if len(updatedPaths) > 1 && d.gConf.BestPath.PreferOldestEBGP && !d.gConf.BestPath.CompareRouterId {
+		d.logger.Info("calling getOldestEBGPRoute, update paths =", updatedPaths)
+		updatedPaths, prunedPaths = d.getOldestEBGPRoute(updatedPaths, prunedPaths)
+		d.setBestPathReason(updatedPaths, BestPathReasonOldestPath)
+	}
+
 	if len(updatedPaths) > 1 {
 		d.logger.Info("calling getRoutesWithLowestBGPId, update paths =", updatedPaths)
 		updatedPaths, prunedPaths = d.getRoutesWithLowestBGPId(updatedPaths, prunedPaths)
+		d.setBestPathReason(updatedPaths, BestPathReasonRouterId)
 	}
 
 	if len(updatedPaths) > 1 {
 		d.logger.Info("calling getRoutesWithShorterClusterLen")
 		updatedPaths, prunedPaths = d.getRoutesWithShorterClusterLen(updatedPaths, prunedPaths)
+		d.setBestPathReason(updatedPaths, BestPathReasonClusterLen)
 	}
 
 	if len(updatedPaths) > 1 {
 		d.logger.Info("calling getRoutesWithLowestPeerAddress")
 		updatedPaths, prunedPaths = d.getRoutesWithLowestPeerAddress(updatedPaths, prunedPaths)
+		d.setBestPathReason(updatedPaths, BestPathReasonPeerAddress)
+	}
+
+	if len(ecmpPaths) > 0 {
+		ecmpPaths = d.removeECMPPathsWithDiffASPath(ecmpPaths, updatedPaths[0])
 	}
 
 	pathMap := make(map[string]*Path)
@@ -28,8 +28,10 @@ import (
 	"l3/bgp/baseobjects"
 	"l3/bgp/config"
 	"l3/bgp/packet"
+	"l3/bgp/rpki"
 	"net"
 	"testing"
+	"time"
 	"utils/logging"
 )
 
@@ -60,6 +62,12 @@ func (r *RouteMgr) DeleteRoute(route *config.RouteConfig) {
 func (r *RouteMgr) UpdateRoute(cfg *config.RouteConfig, op string) {
 	r.t.Log("RouteMgr:UpdateRoute:", cfg, "operation:", op)
 }
+func (r *RouteMgr) CreateNextHopGroup(cfg *config.NextHopGroupConfig) {
+	r.t.Log("RouteMgr:CreateNextHopGroup:", cfg)
+}
+func (r *RouteMgr) DeleteNextHopGroup(cfg *config.NextHopGroupConfig) {
+	r.t.Log("RouteMgr:DeleteNextHopGroup:", cfg)
+}
 
 func (r *RouteMgr) ApplyPolicy(policy, conditions []*config.ApplyPolicyInfo) {
 	r.t.Log("RouteMgr:ApplyPolicy")
@@ -68,6 +76,10 @@ func (r *RouteMgr) GetRoutes() (ri1 []*config.RouteInfo, ri2 []*config.RouteInfo
 	r.t.Log("RouteMgr:GetRoutes")
 	return ri1, ri2
 }
+func (r *RouteMgr) GetInstalledRoutes() []*config.RouteConfig {
+	r.t.Log("RouteMgr:GetInstalledRoutes")
+	return nil
+}
 
 func constructRibAndDest(t *testing.T, logger *logging.Writer, gConf *config.GlobalConfig) (*LocRib, *Destination) {
 	routeMgr := &RouteMgr{t}
@@ -216,6 +228,56 @@ func TestRemoveAllPaths(t *testing.T) {
 	dest.RemoveAllPaths(peerIP2, path2)
 }
 
+func TestRemoveStalePaths(t *testing.T) {
+	logger := getLogger(t)
+	peerIP := "192.168.0.100"
+	gConf, pConf := getConfObjects(peerIP, uint32(1234), uint32(4321))
+	locRib, dest := constructRibAndDest(t, logger, gConf)
+
+	// Add paths with id 1 and 2 from neighbor1
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	pathAttrs := constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+1)
+	path := NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	dest.AddOrUpdatePath(peerIP, 1, path)
+	pathAttrs = constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+2)
+	path1 := NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	dest.AddOrUpdatePath(peerIP, 2, path1)
+
+	// Add path with id 1 from neighbor2
+	peerIP2 := "172.16.0.1"
+	pConf2 := getNeighborConf(peerIP2, 0, 5432)
+	nConf2 := base.NewNeighborConf(logger, gConf, nil, *pConf2)
+	pathAttrs2 := constructPathAttrs(pConf2.NeighborAddress, pConf2.PeerAS, pConf2.PeerAS+2)
+	path2 := NewPath(locRib, nConf2, pathAttrs2, nil, RouteTypeEGP)
+	dest.AddOrUpdatePath(peerIP2, 1, path2)
+
+	if count := dest.MarkStalePaths(peerIP); count != 2 {
+		t.Fatal("Destination:MarkStalePaths marked", count, "paths, expected 2")
+	}
+	if !dest.HasStalePaths(peerIP) || dest.HasStalePaths(peerIP2) {
+		t.Fatal("Destination:HasStalePaths failed after marking paths from", peerIP, "as stale")
+	}
+
+	// Refresh path with id 2 from neighbor1
+	pathAttrs = constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+3)
+	path3 := NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	dest.AddOrUpdatePath(peerIP, 2, path3)
+	if dest.IsPathStale(path3) || !dest.IsPathStale(path) {
+		t.Fatal("Destination:IsPathStale failed after refreshing path id 2 from", peerIP)
+	}
+
+	dest.RemoveStalePaths(peerIP, path)
+	if dest.HasStalePaths(peerIP) {
+		t.Fatal("Destination:RemoveStalePaths did not remove stale paths from", peerIP)
+	}
+	if len(dest.peerPathMap[peerIP]) != 1 || dest.peerPathMap[peerIP][2] != path3 {
+		t.Fatal("Destination:RemoveStalePaths removed the refreshed path from", peerIP)
+	}
+	if len(dest.peerPathMap[peerIP2]) != 1 {
+		t.Fatal("Destination:RemoveStalePaths removed paths from", peerIP2)
+	}
+}
+
 func TestSelectRouteForLocRib(t *testing.T) {
 	logger := getLogger(t)
 	peerIP := "192.168.0.100"
@@ -262,3 +324,408 @@ func TestSelectRouteForLocRib(t *testing.T) {
 	action, addPathsMod, _, _, _ = dest.SelectRouteForLocRib(2)
 	t.Log("SelectRouteForLocRib returned action:", action, "addPaths updated:", addPathsMod)
 }
+
+func TestGetRoutesWithBestValidationState(t *testing.T) {
+	logger := getLogger(t)
+	peerIP := "192.168.0.100"
+	gConf, pConf := getConfObjects(peerIP, uint32(1234), uint32(4321))
+	gConf.RPKI.PreferValid = true
+	locRib, dest := constructRibAndDest(t, logger, gConf)
+	table := rpki.NewROATable()
+	table.Update("cache", true, []rpki.ROA{rpki.NewROA(net.ParseIP("20.1.0.0"), 16, 24, 5434)}, nil)
+	locRib.SetROATable(table)
+
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	pathAttrs := constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+1)
+	path := NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+
+	peerIP2 := "172.16.0.1"
+	pConf2 := getNeighborConf(peerIP2, 0, 5432)
+	nConf2 := base.NewNeighborConf(logger, gConf, nil, *pConf2)
+	pathAttrs2 := constructPathAttrs(pConf2.NeighborAddress, pConf2.PeerAS, pConf2.PeerAS+1, pConf2.PeerAS+2)
+	path2 := NewPath(locRib, nConf2, pathAttrs2, nil, RouteTypeEGP)
+
+	updatedPaths, prunedPaths := dest.getRoutesWithBestValidationState([]*Path{path, path2}, nil)
+	if len(updatedPaths) != 1 || updatedPaths[0] != path2 {
+		t.Fatal("getRoutesWithBestValidationState returned", updatedPaths, "expected the valid path", path2)
+	}
+	if len(prunedPaths) != 1 || len(prunedPaths[0].paths) != 1 || prunedPaths[0].paths[0] != path {
+		t.Fatal("getRoutesWithBestValidationState pruned", prunedPaths, "expected the invalid path", path)
+	}
+}
+
+func constructMEDPath(locRib *LocRib, nConf *base.NeighborConf, med uint32, hasMED bool, asList ...uint32) *Path {
+	pathAttrs := constructPathAttrs(nConf.Neighbor.NeighborAddress, asList...)
+	if hasMED {
+		medAttr := packet.NewBGPPathAttrMultiExitDisc()
+		medAttr.Value = med
+		pathAttrs = append(pathAttrs, medAttr)
+	}
+	return NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+}
+
+func TestGetRoutesWithLowestMED(t *testing.T) {
+	logger := getLogger(t)
+	peerIP := "192.168.0.100"
+	gConf, pConf := getConfObjects(peerIP, uint32(1234), uint32(4321))
+	locRib, dest := constructRibAndDest(t, logger, gConf)
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	pConf2 := getNeighborConf("172.16.0.1", 0, 5432)
+	nConf2 := base.NewNeighborConf(logger, gConf, nil, *pConf2)
+
+	path1 := constructMEDPath(locRib, nConf, 20, true, 4321, 100)
+	path2 := constructMEDPath(locRib, nConf, 10, true, 4321, 200)
+	path3 := constructMEDPath(locRib, nConf2, 5, true, 5432, 100)
+	path4 := constructMEDPath(locRib, nConf2, 0, false, 5432, 200)
+
+	tests := []struct {
+		bestPath config.BestPathConfig
+		paths    []*Path
+		expected []*Path
+	}{
+		// Without deterministic-med the MEDs are compared only if all the paths are from the same AS, in any order
+		{config.BestPathConfig{CompareMED: true}, []*Path{path1, path2, path3, path4},
+			[]*Path{path1, path2, path3, path4}},
+		{config.BestPathConfig{CompareMED: true}, []*Path{path3, path1, path2, path4},
+			[]*Path{path3, path1, path2, path4}},
+		{config.BestPathConfig{CompareMED: true}, []*Path{path1, path2}, []*Path{path2}},
+		{config.BestPathConfig{CompareMED: true}, []*Path{path2, path1}, []*Path{path2}},
+		{config.BestPathConfig{CompareMED: true, DeterministicMED: true}, []*Path{path1, path2, path3, path4},
+			[]*Path{path2, path4}},
+		{config.BestPathConfig{CompareMED: true, DeterministicMED: true, MEDMissingAsWorst: true},
+			[]*Path{path1, path2, path3, path4}, []*Path{path2, path3}},
+		{config.BestPathConfig{CompareMED: true, AlwaysCompareMED: true}, []*Path{path1, path2, path3, path4},
+			[]*Path{path4}},
+		{config.BestPathConfig{CompareMED: true, AlwaysCompareMED: true, MEDMissingAsWorst: true},
+			[]*Path{path1, path2, path3, path4}, []*Path{path3}},
+	}
+
+	for idx, test := range tests {
+		gConf.BestPath = test.bestPath
+		numPaths := len(test.paths)
+		updatedPaths, prunedPaths := dest.getRoutesWithLowestMED(append([]*Path(nil), test.paths...), nil)
+		if len(updatedPaths) != len(test.expected) {
+			t.Fatal("Test", idx, "getRoutesWithLowestMED returned", updatedPaths, "expected", test.expected)
+		}
+		for i, path := range test.expected {
+			if updatedPaths[i] != path {
+				t.Fatal("Test", idx, "getRoutesWithLowestMED returned", updatedPaths, "expected", test.expected)
+			}
+		}
+		if numPaths == len(test.expected) {
+			if len(prunedPaths) != 0 {
+				t.Fatal("Test", idx, "getRoutesWithLowestMED pruned", prunedPaths, "expected no pruned paths")
+			}
+		} else if len(prunedPaths) != 1 || len(prunedPaths[0].paths) != numPaths-len(test.expected) {
+			t.Fatal("Test", idx, "getRoutesWithLowestMED pruned", prunedPaths)
+		}
+	}
+}
+
+func TestCalculateBestPathReason(t *testing.T) {
+	logger := getLogger(t)
+	peerIP := "192.168.0.100"
+	gConf, pConf := getConfObjects(peerIP, uint32(1234), uint32(4321))
+	locRib, dest := constructRibAndDest(t, logger, gConf)
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	nConf.SetPeerAttrs(net.ParseIP("10.0.0.2"), 4, 3, 1, nil)
+	pConf2 := getNeighborConf("172.16.0.1", 0, 5432)
+	nConf2 := base.NewNeighborConf(logger, gConf, nil, *pConf2)
+	nConf2.SetPeerAttrs(net.ParseIP("10.0.0.1"), 4, 3, 1, nil)
+
+	path1 := constructMEDPath(locRib, nConf, 0, false, 4321, 100)
+	path2 := constructMEDPath(locRib, nConf2, 0, false, 5432, 200, 300)
+
+	updatedPaths, _, _ := dest.calculateBestPath([]*Path{path1, path2}, nil, false, false, 0)
+	if len(updatedPaths) != 1 || updatedPaths[0] != path1 || dest.BestPathReason != BestPathReasonASPathLen {
+		t.Fatal("calculateBestPath returned", updatedPaths, "reason", dest.BestPathReason, "expected", path1,
+			BestPathReasonASPathLen)
+	}
+
+	// The router ids decide by default, the oldest eBGP path is kept only with prefer-oldest-ebgp
+	gConf.BestPath.ASPathIgnore = true
+	dest.LocRibPath = path1
+	updatedPaths, _, _ = dest.calculateBestPath([]*Path{path1, path2}, nil, false, false, 0)
+	if len(updatedPaths) != 1 || updatedPaths[0] != path2 || dest.BestPathReason != BestPathReasonRouterId {
+		t.Fatal("calculateBestPath returned", updatedPaths, "reason", dest.BestPathReason, "expected", path2,
+			BestPathReasonRouterId)
+	}
+
+	gConf.BestPath.PreferOldestEBGP = true
+	updatedPaths, _, _ = dest.calculateBestPath([]*Path{path1, path2}, nil, false, false, 0)
+	if len(updatedPaths) != 1 || updatedPaths[0] != path1 || dest.BestPathReason != BestPathReasonOldestPath {
+		t.Fatal("calculateBestPath returned", updatedPaths, "reason", dest.BestPathReason, "expected", path1,
+			BestPathReasonOldestPath)
+	}
+
+	gConf.BestPath.CompareRouterId = true
+	updatedPaths, _, _ = dest.calculateBestPath([]*Path{path1, path2}, nil, false, false, 0)
+	if len(updatedPaths) != 1 || updatedPaths[0] != path2 || dest.BestPathReason != BestPathReasonRouterId {
+		t.Fatal("calculateBestPath returned", updatedPaths, "reason", dest.BestPathReason, "expected", path2,
+			BestPathReasonRouterId)
+	}
+}
+
+func TestRemoveECMPPathsWithDiffASPath(t *testing.T) {
+	logger := getLogger(t)
+	peerIP := "192.168.0.100"
+	gConf, pConf := getConfObjects(peerIP, uint32(1234), uint32(4321))
+	locRib, dest := constructRibAndDest(t, logger, gConf)
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	pConf2 := getNeighborConf("172.16.0.1", 0, 5432)
+	nConf2 := base.NewNeighborConf(logger, gConf, nil, *pConf2)
+
+	path1 := constructMEDPath(locRib, nConf, 0, false, 4321, 100)
+	path2 := constructMEDPath(locRib, nConf2, 0, false, 5432, 100)
+
+	// ECMP across the paths with the same AS path length by default
+	ecmpPaths := dest.removeECMPPathsWithDiffASPath([][]*Path{{path1}, {path2}}, path1)
+	if len(ecmpPaths) != 2 {
+		t.Fatal("removeECMPPathsWithDiffASPath returned", ecmpPaths, "expected both the paths")
+	}
+
+	gConf.BestPath.MultipathSameASPath = true
+	ecmpPaths = dest.removeECMPPathsWithDiffASPath([][]*Path{{path1}, {path2}}, path1)
+	if len(ecmpPaths) != 1 || len(ecmpPaths[0]) != 1 || ecmpPaths[0][0] != path1 {
+		t.Fatal("removeECMPPathsWithDiffASPath returned", ecmpPaths, "expected only the best path", path1)
+	}
+
+	gConf.BestPath.ASPathMultipathRelax = true
+	ecmpPaths = dest.removeECMPPathsWithDiffASPath([][]*Path{{path1}, {path2}}, path1)
+	if len(ecmpPaths) != 2 {
+		t.Fatal("removeECMPPathsWithDiffASPath returned", ecmpPaths, "expected both the paths with multipath-relax")
+	}
+}
+
+func TestDampeningInfo(t *testing.T) {
+	dampConf := &config.DampeningConfig{
+		HalfLife:          900,
+		ReuseThreshold:    750,
+		SuppressThreshold: 2000,
+		MaxSuppressTime:   3600,
+	}
+	now := time.Now()
+	info := NewDampeningInfo(dampConf)
+	info.LastUpdate = now
+
+	if info.penalize(DampeningWithdrawPenalty, now) {
+		t.Fatal("Path suppressed after one flap, penalty", info.Penalty)
+	}
+	if !info.penalize(DampeningWithdrawPenalty, now) {
+		t.Fatal("Path not suppressed after two flaps, penalty", info.Penalty)
+	}
+	if info.FlapCount != 2 {
+		t.Fatal("Expected flap count 2, found", info.FlapCount)
+	}
+
+	// Penalty is capped at reuse * 2^(max suppress time/half life)
+	for i := 0; i < 20; i++ {
+		info.penalize(DampeningWithdrawPenalty, now)
+	}
+	if info.Penalty != 750*16 {
+		t.Fatal("Expected max penalty", 750*16, "found", info.Penalty)
+	}
+
+	// Penalty halves every half life
+	now = now.Add(900 * time.Second)
+	if info.reuse(now) {
+		t.Fatal("Path reused after one half life, penalty", info.Penalty)
+	}
+	if info.Penalty < 5999 || info.Penalty > 6001 {
+		t.Fatal("Expected penalty 6000 after one half life, found", info.Penalty)
+	}
+
+	now = now.Add(3 * 900 * time.Second)
+	if !info.reuse(now) {
+		t.Fatal("Path not reused after max suppress time, penalty", info.Penalty)
+	}
+	if info.canForget() {
+		t.Fatal("Flap history removed with penalty", info.Penalty)
+	}
+
+	now = now.Add(2 * 900 * time.Second)
+	info.reuse(now)
+	if !info.canForget() {
+		t.Fatal("Flap history not removed with penalty", info.Penalty)
+	}
+}
+
+func TestSelectRouteForLocRibDampening(t *testing.T) {
+	logger := getLogger(t)
+	peerIP := "192.168.0.100"
+	gConf, pConf := getConfObjects(peerIP, uint32(1234), uint32(4321))
+	locRib, dest := constructRibAndDest(t, logger, gConf)
+	dampConf := &config.DampeningConfig{
+		HalfLife:          900,
+		ReuseThreshold:    750,
+		SuppressThreshold: 2000,
+		MaxSuppressTime:   3600,
+	}
+
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	nConf.SetPeerAttrs(net.ParseIP(peerIP), 4, 3, 1, nil)
+	pathAttrs := constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+1)
+	path := NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	path.SetDampeningConfig(dampConf)
+	reachInfo := NewReachabilityInfo("192.168.0.101", 0, 0, 0)
+	path.SetReachabilityForNextHop(pConf.NeighborAddress.String(), reachInfo)
+	dest.AddOrUpdatePath(peerIP, 1, path)
+	action, _, _, _, _ := dest.SelectRouteForLocRib(0)
+	if action != RouteActionAdd || dest.LocRibPath != path {
+		t.Fatal("Path not selected as best path, action", action)
+	}
+
+	dest.penalizePath(peerIP, 1, path, DampeningWithdrawPenalty)
+	dest.penalizePath(peerIP, 1, path, DampeningWithdrawPenalty)
+	if !dest.isSuppressed(peerIP, 1) {
+		t.Fatal("Path not suppressed after two flaps")
+	}
+	if route := dest.GetPathRoute(path); route == nil || !route.PathInfo.Suppressed {
+		t.Fatal("Route state does not show the path as suppressed")
+	}
+
+	action, _, _, _, _ = dest.SelectRouteForLocRib(0)
+	if action != RouteActionDelete || dest.LocRibPath != nil {
+		t.Fatal("Suppressed path not removed from loc rib, action", action)
+	}
+
+	if !dest.resetDampening() {
+		t.Fatal("Dampening reset did not find the suppressed path")
+	}
+	dest.SelectRouteForLocRib(0)
+	if dest.isSuppressed(peerIP, 1) || dest.LocRibPath != path {
+		t.Fatal("Path not selected as best path after clearing dampening")
+	}
+}
+
+func TestSelectRouteForLocRibDampeningPathId(t *testing.T) {
+	logger := getLogger(t)
+	peerIP := "192.168.0.100"
+	gConf, pConf := getConfObjects(peerIP, uint32(1234), uint32(4321))
+	locRib, dest := constructRibAndDest(t, logger, gConf)
+	dampConf := &config.DampeningConfig{
+		HalfLife:          900,
+		ReuseThreshold:    750,
+		SuppressThreshold: 2000,
+		MaxSuppressTime:   3600,
+	}
+
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	nConf.SetPeerAttrs(net.ParseIP(peerIP), 4, 3, 1, nil)
+	pathAttrs := constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+1)
+	path := NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	path.SetDampeningConfig(dampConf)
+	path.SetReachabilityForNextHop(pConf.NeighborAddress.String(), NewReachabilityInfo("192.168.0.101", 0, 0, 0))
+	dest.AddOrUpdatePath(peerIP, 1, path)
+	pathAttrs2 := constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+2, pConf.PeerAS+3)
+	path2 := NewPath(locRib, nConf, pathAttrs2, nil, RouteTypeEGP)
+	path2.SetDampeningConfig(dampConf)
+	path2.SetReachabilityForNextHop(pConf.NeighborAddress.String(), NewReachabilityInfo("192.168.0.101", 0, 0, 0))
+	dest.AddOrUpdatePath(peerIP, 2, path2)
+	dest.SelectRouteForLocRib(0)
+	if dest.LocRibPath != path {
+		t.Fatal("Path with the shorter AS path not selected as best path")
+	}
+
+	dest.penalizePath(peerIP, 1, path, DampeningWithdrawPenalty)
+	dest.penalizePath(peerIP, 1, path, DampeningWithdrawPenalty)
+	if !dest.isSuppressed(peerIP, 1) || dest.isSuppressed(peerIP, 2) {
+		t.Fatal("Flaps of path id 1 did not suppress only path id 1")
+	}
+	dest.SelectRouteForLocRib(0)
+	if dest.LocRibPath != path2 {
+		t.Fatal("Path id 2 from the same peer not selected when path id 1 is suppressed, best path",
+			dest.LocRibPath)
+	}
+}
+
+func TestIsAttrChange(t *testing.T) {
+	logger := getLogger(t)
+	peerIP := "192.168.0.100"
+	gConf, pConf := getConfObjects(peerIP, uint32(1234), uint32(4321))
+	locRib, _ := constructRibAndDest(t, logger, gConf)
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+
+	path := NewPath(locRib, nConf, constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+1), nil,
+		RouteTypeEGP)
+	samePath := NewPath(locRib, nConf, constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+1),
+		nil, RouteTypeEGP)
+	if isAttrChange(path, samePath, protoFamily) {
+		t.Error("Path with the same attributes is an attribute change")
+	}
+
+	asPathChange := NewPath(locRib, nConf, constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS,
+		pConf.PeerAS+2), nil, RouteTypeEGP)
+	if !isAttrChange(path, asPathChange, protoFamily) {
+		t.Error("Path with a different AS path is not an attribute change")
+	}
+
+	medChange := samePath.Clone()
+	medChange.MED = path.MED + 10
+	if !isAttrChange(path, medChange, protoFamily) {
+		t.Error("Path with a different MED is not an attribute change")
+	}
+}
+
+func TestSelectRouteForLocRibBackupPath(t *testing.T) {
+	logger := getLogger(t)
+	peerIP := "192.168.0.100"
+	gConf, pConf := getConfObjects(peerIP, uint32(1234), uint32(4321))
+	gConf.InstallBackupPath = true
+	locRib, dest := constructRibAndDest(t, logger, gConf)
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
+	dest2 := NewDestination(locRib, packet.NewIPPrefix(net.ParseIP("20.1.20.0"), 24), protoFamily, gConf)
+
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	nConf.SetPeerAttrs(net.ParseIP(peerIP), 4, 3, 1, nil)
+	pathAttrs := constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+1)
+	path := NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	path.SetReachabilityForNextHop(pConf.NeighborAddress.String(), NewReachabilityInfo("192.168.0.101", 0, 0, 0))
+
+	peerIP2 := "172.16.0.1"
+	pConf2 := getNeighborConf(peerIP2, 0, 5432)
+	nConf2 := base.NewNeighborConf(logger, gConf, nil, *pConf2)
+	nConf2.SetPeerAttrs(net.ParseIP(peerIP2), 4, 3, 1, nil)
+	pathAttrs2 := constructPathAttrs(pConf2.NeighborAddress, pConf2.PeerAS, pConf2.PeerAS+1, pConf2.PeerAS+2)
+	path2 := NewPath(locRib, nConf2, pathAttrs2, nil, RouteTypeEGP)
+	path2.SetReachabilityForNextHop(pConf2.NeighborAddress.String(), NewReachabilityInfo("172.16.0.2", 0, 0, 0))
+
+	for _, d := range []*Destination{dest, dest2} {
+		d.AddOrUpdatePath(peerIP, 1, path)
+		d.AddOrUpdatePath(peerIP2, 1, path2)
+		d.SelectRouteForLocRib(0)
+		if d.LocRibPath != path || d.BackupPath != path2 {
+			t.Fatal("Destination", d.NLRI.GetCIDR(), "best path", d.LocRibPath, "backup path", d.BackupPath)
+		}
+		if route := d.GetPathRoute(path2); route == nil || !route.PathInfo.BackupPath {
+			t.Fatal("Route state does not show the backup path")
+		}
+	}
+
+	// Both destinations resolve over the same next hop group
+	groups := locRib.GetNextHopGroups()
+	if len(groups) != 1 {
+		t.Fatal("Expected one next hop group, found", groups)
+	}
+	for _, group := range groups {
+		if group.NextHop.NextHop != "192.168.0.101" || group.Backup.NextHop != "172.16.0.2" || group.refCount != 2 {
+			t.Fatalf("Next hop group %+v", group)
+		}
+	}
+
+	dest.RemovePath(peerIP2, 1, path2)
+	dest.SelectRouteForLocRib(0)
+	if dest.LocRibPath != path || dest.BackupPath != nil {
+		t.Fatal("Backup path not removed, backup path", dest.BackupPath)
+	}
+	if len(groups) != 1 {
+		t.Fatal("Next hop group removed while in use, groups", groups)
+	}
+
+	dest2.RemovePath(peerIP, 1, path)
+	dest2.SelectRouteForLocRib(0)
+	if dest2.LocRibPath != path2 || dest2.BackupPath != nil || len(groups) != 0 {
+		t.Fatal("Next hop group not removed after the last route stopped using it, groups", groups)
+	}
+}
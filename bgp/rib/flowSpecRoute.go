@@ -0,0 +1,105 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// flowSpecRoute.go
+package rib
+
+import (
+	"bgpd"
+	bgputils "l3/bgp/utils"
+	"models/objects"
+	"strconv"
+)
+
+type FlowSpecRoute struct {
+	*bgpd.BGPFlowSpecRouteState
+}
+
+func NewFlowSpecRoute(rule string, addressFamily string) *FlowSpecRoute {
+	return &FlowSpecRoute{
+		&bgpd.BGPFlowSpecRouteState{
+			Rule:          rule,
+			AddressFamily: addressFamily,
+		},
+	}
+}
+
+func (i *FlowSpecRoute) SetNetwork(rule string) {
+	i.Rule = rule
+}
+
+func (i *FlowSpecRoute) GetNetwork() string {
+	return i.Rule
+}
+
+func (i *FlowSpecRoute) SetCIDRLen(cidrLen int16) {
+}
+
+func (i *FlowSpecRoute) GetCIDRLen() int16 {
+	return 0
+}
+
+func (i *FlowSpecRoute) GetPaths() []*bgpd.PathInfo {
+	return i.Paths
+}
+
+func (i *FlowSpecRoute) AppendPath(path *bgpd.PathInfo) {
+	i.Paths = append(i.Paths, path)
+}
+
+func (i *FlowSpecRoute) SetPath(path *bgpd.PathInfo, idx int) {
+	i.Paths[idx] = path
+}
+
+func (i *FlowSpecRoute) GetPath(idx int) *bgpd.PathInfo {
+	return i.Paths[idx]
+}
+
+func (i *FlowSpecRoute) GetLastPath() *bgpd.PathInfo {
+	return i.Paths[len(i.Paths)-1]
+}
+
+func (i *FlowSpecRoute) RemovePathAndSetLast(idx int) {
+	if idx < len(i.Paths) {
+		i.Paths[idx] = i.Paths[len(i.Paths)-1]
+		i.Paths[len(i.Paths)-1] = nil
+		i.Paths = i.Paths[:len(i.Paths)-1]
+	}
+}
+
+func (i *FlowSpecRoute) GetModelObject() objects.ConfigObj {
+	var dbObj objects.BGPFlowSpecRouteState
+	objects.ConvertThriftTobgpdBGPFlowSpecRouteStateObj(i.BGPFlowSpecRouteState, &dbObj)
+	for idx1 := 0; idx1 < len(dbObj.Paths); idx1++ {
+		for idx2 := 0; idx2 < len(dbObj.Paths[idx1].Path); idx2++ {
+			asdoPlain, _ := strconv.Atoi(dbObj.Paths[idx1].Path[idx2])
+			asdotPath, _ := bgputils.GetAsDot(asdoPlain)
+			dbObj.Paths[idx1].Path[idx2] = asdotPath
+		}
+	}
+	return &dbObj
+}
+
+func (i *FlowSpecRoute) GetThriftObject() interface{} {
+	return i.BGPFlowSpecRouteState
+}
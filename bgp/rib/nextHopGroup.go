@@ -0,0 +1,184 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// nextHopGroup.go
+package rib
+
+import (
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	"strconv"
+)
+
+// NextHopGroup is a next hop and the backup next hop pre-installed for it in RIBd (BGP PIC). It is shared by all the
+// destinations whose installed path and backup path resolve over the same next hops.
+type NextHopGroup struct {
+	Id       int32
+	NextHop  *ReachabilityInfo
+	Backup   *ReachabilityInfo
+	key      string
+	refCount int
+}
+
+func getNextHopGroupKey(nextHop, backup *ReachabilityInfo) string {
+	return nextHop.NextHop + "%" + strconv.Itoa(int(nextHop.NextHopIfIdx)) + "," + backup.NextHop + "%" +
+		strconv.Itoa(int(backup.NextHopIfIdx))
+}
+
+func (l *LocRib) constructNextHopGroupConfig(group *NextHopGroup) *config.NextHopGroupConfig {
+	return &config.NextHopGroupConfig{
+		Id:                      group.Id,
+		Vrf:                     l.vrf,
+		NextHopIp:               group.NextHop.NextHop,
+		OutgoingInterface:       strconv.Itoa(int(group.NextHop.NextHopIfIdx)),
+		BackupNextHopIp:         group.Backup.NextHop,
+		BackupOutgoingInterface: strconv.Itoa(int(group.Backup.NextHopIfIdx)),
+	}
+}
+
+// acquireNextHopGroup returns the group for the next hop and backup next hop. The group is created in RIBd when
+// the first route starts using it.
+func (l *LocRib) acquireNextHopGroup(nextHop, backup *ReachabilityInfo) *NextHopGroup {
+	key := getNextHopGroupKey(nextHop, backup)
+	group, ok := l.nextHopGroups[key]
+	if !ok {
+		l.nextHopGroupId++
+		group = &NextHopGroup{
+			Id:      l.nextHopGroupId,
+			NextHop: nextHop,
+			Backup:  backup,
+			key:     key,
+		}
+		l.logger.Infof("Create next hop group %d, next hop %s backup %s", group.Id, nextHop.NextHop,
+			backup.NextHop)
+		l.nextHopGroups[key] = group
+		l.routeMgr.CreateNextHopGroup(l.constructNextHopGroupConfig(group))
+	}
+	group.refCount++
+	return group
+}
+
+// releaseNextHopGroup deletes the group from RIBd when the last route using it moved away
+func (l *LocRib) releaseNextHopGroup(group *NextHopGroup) {
+	group.refCount--
+	if group.refCount > 0 {
+		return
+	}
+
+	l.logger.Infof("Delete next hop group %d, next hop %s backup %s", group.Id, group.NextHop.NextHop,
+		group.Backup.NextHop)
+	delete(l.nextHopGroups, group.key)
+	l.routeMgr.DeleteNextHopGroup(l.constructNextHopGroupConfig(group))
+}
+
+func (l *LocRib) GetNextHopGroups() map[string]*NextHopGroup {
+	return l.nextHopGroups
+}
+
+func (d *Destination) isBackupPathFamily() bool {
+	return d.protoFamily == packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast) ||
+		d.protoFamily == packet.GetProtocolFamily(packet.AfiIP6, packet.SafiUnicast)
+}
+
+// selectBackupPath runs the best path selection on the paths that don't share a next hop with the installed paths
+func (d *Destination) selectBackupPath(paths []*Path, ecmpPaths [][]*Path) *Path {
+	nextHops := make(map[string]bool)
+	for _, ecmp := range ecmpPaths {
+		if reachInfo := ecmp[0].GetReachability(d.protoFamily); reachInfo != nil {
+			nextHops[reachInfo.NextHop] = true
+		}
+	}
+
+	backupPaths := make([]*Path, 0)
+	for _, path := range paths {
+		reachInfo := path.GetReachability(d.protoFamily)
+		if path.IsLocal() || reachInfo == nil || nextHops[reachInfo.NextHop] {
+			continue
+		}
+		backupPaths = append(backupPaths, path)
+	}
+
+	if len(backupPaths) > 1 {
+		bestPathReason := d.BestPathReason
+		backupPaths, _, _ = d.calculateBestPath(backupPaths, make([]*Path, 0), false, false, 0)
+		d.BestPathReason = bestPathReason
+	}
+
+	if len(backupPaths) == 0 {
+		return nil
+	}
+	return backupPaths[0]
+}
+
+func (d *Destination) setBackupPath(path *Path) {
+	if d.BackupPath == path {
+		return
+	}
+
+	if route, ok := d.pathRouteMap[d.BackupPath]; ok {
+		route.ResetBackupPath()
+	}
+	if route, ok := d.pathRouteMap[path]; ok {
+		route.SetBackupPath()
+	}
+	d.logger.Infof("Destination %s backup path %v", d.NLRI.GetCIDR(), path)
+	d.BackupPath = path
+}
+
+// updateNextHopGroup moves the route for the path to the group of its next hop and the next hop of the backup path.
+// It returns the id of the group (0 if there is no backup path), and the group previously used by the route, to be
+// released after the route is updated in RIBd.
+func (d *Destination) updateNextHopGroup(path *Path) (int32, *NextHopGroup, bool) {
+	oldGroup := d.nextHopGroups[path]
+	if d.BackupPath == nil {
+		if oldGroup == nil {
+			return 0, nil, false
+		}
+		delete(d.nextHopGroups, path)
+		return 0, oldGroup, true
+	}
+
+	nextHop := path.GetReachability(d.protoFamily)
+	backup := d.BackupPath.GetReachability(d.protoFamily)
+	if oldGroup != nil && oldGroup.key == getNextHopGroupKey(nextHop, backup) {
+		return oldGroup.Id, nil, false
+	}
+
+	group := d.rib.acquireNextHopGroup(nextHop, backup)
+	d.nextHopGroups[path] = group
+	return group.Id, oldGroup, true
+}
+
+func (d *Destination) getNextHopGroupId(path *Path) int32 {
+	if group, ok := d.nextHopGroups[path]; ok {
+		return group.Id
+	}
+	return 0
+}
+
+func (d *Destination) removeNextHopGroup(path *Path) {
+	if group, ok := d.nextHopGroups[path]; ok {
+		delete(d.nextHopGroups, path)
+		d.rib.releaseNextHopGroup(group)
+	}
+}
@@ -28,7 +28,10 @@ import (
 	"encoding/binary"
 	_ "fmt"
 	"l3/bgp/baseobjects"
+	"l3/bgp/config"
 	"l3/bgp/packet"
+	"l3/bgp/rpki"
+	"math"
 	"net"
 	_ "ribd"
 	"strconv"
@@ -83,6 +86,10 @@ type Path struct {
 	MED                uint32
 	LocalPref          uint32
 	AggregatedPaths    map[string]*Path
+	validationStates   map[string]rpki.ValidationState
+	validationGen      uint64
+	leaked             bool
+	dampConf           *config.DampeningConfig
 }
 
 func NewPath(locRib *LocRib, peer *base.NeighborConf, pa []packet.BGPPathAttr,
@@ -114,6 +121,8 @@ func (p *Path) Clone() *Path {
 		routeType:          p.routeType,
 		MED:                p.MED,
 		LocalPref:          p.LocalPref,
+		leaked:             p.leaked,
+		dampConf:           p.dampConf,
 	}
 
 	return path
@@ -182,6 +191,24 @@ func (p *Path) IsValid() bool {
 	return true
 }
 
+// Leaked paths are copied between the VRF and the VPN tables and are not counted against the neighbor
+func (p *Path) SetLeaked() {
+	p.leaked = true
+}
+
+func (p *Path) IsLeaked() bool {
+	return p.leaked
+}
+
+// Paths with dampening parameters set are penalized when they flap
+func (p *Path) SetDampeningConfig(dampConf *config.DampeningConfig) {
+	p.dampConf = dampConf
+}
+
+func (p *Path) GetDampeningConfig() *config.DampeningConfig {
+	return p.dampConf
+}
+
 func (p *Path) GetNeighborConf() *base.NeighborConf {
 	return p.NeighborConf
 }
@@ -194,6 +221,11 @@ func (p *Path) GetPeerIP() string {
 }
 
 func (p *Path) GetPreference() uint32 {
+	// Paths from a neighbor in graceful shutdown get the lowest preference so that the traffic moves to the
+	// alternate paths before the session is torn down (RFC 8326).
+	if p.NeighborConf != nil && p.NeighborConf.IsGracefulShutdown() {
+		return 0
+	}
 	return p.Pref
 }
 
@@ -300,6 +332,85 @@ func (p *Path) GetNumClusters() uint16 {
 	return packet.GetNumClusters(p.PathAttrs)
 }
 
+func (p *Path) GetCommunities() []uint32 {
+	return packet.GetCommunities(p.PathAttrs)
+}
+
+func (p *Path) GetExtCommunities() []uint64 {
+	return packet.GetExtCommunities(p.PathAttrs)
+}
+
+func (p *Path) GetLargeCommunities() []packet.LargeCommunity {
+	return packet.GetLargeCommunities(p.PathAttrs)
+}
+
+func (p *Path) HasCommunity(community uint32) bool {
+	return packet.HasCommunity(p.PathAttrs, community)
+}
+
+func (p *Path) GetOriginAS() uint32 {
+	if originAS, ok := packet.GetOriginAS(p.PathAttrs); ok {
+		return originAS
+	}
+	if p.NeighborConf != nil {
+		return p.NeighborConf.RunningConf.LocalAS
+	}
+	return p.rib.gConf.AS
+}
+
+// GetNeighborAS returns the AS the path was received from. Paths with an empty AS path are from the local AS.
+func (p *Path) GetNeighborAS() uint32 {
+	if neighborAS, ok := packet.GetNeighborAS(p.PathAttrs); ok {
+		return neighborAS
+	}
+	if p.NeighborConf != nil {
+		return p.NeighborConf.RunningConf.LocalAS
+	}
+	return p.rib.gConf.AS
+}
+
+// GetMED returns the MED of the path. A missing MED is treated as 0, or as the worst value if missingAsWorst
+// is set.
+func (p *Path) GetMED(missingAsWorst bool) uint32 {
+	if med, ok := packet.GetMED(p.PathAttrs); ok {
+		return med
+	}
+	if missingAsWorst {
+		return math.MaxUint32
+	}
+	return 0
+}
+
+// GetValidationState returns the origin validation state of the path for the prefix. A path is shared by all
+// the prefixes in an update, the states are cached per prefix till the ROA table changes.
+func (p *Path) GetValidationState(nlri packet.NLRI) rpki.ValidationState {
+	if p.rib.roaTable == nil {
+		return rpki.ValidationStateNotFound
+	}
+
+	generation := p.rib.roaTable.GetGeneration()
+	if p.validationStates == nil || p.validationGen != generation {
+		p.validationStates = make(map[string]rpki.ValidationState)
+		p.validationGen = generation
+	}
+
+	key := nlri.GetCIDR()
+	if state, ok := p.validationStates[key]; ok {
+		return state
+	}
+
+	state := p.rib.roaTable.Validate(nlri.GetPrefix(), nlri.GetLength(), p.GetOriginAS())
+	p.validationStates[key] = state
+	return state
+}
+
+func (p *Path) CloneWithPathAttrs(pathAttrs []packet.BGPPathAttr) *Path {
+	path := p.Clone()
+	path.PathAttrs = pathAttrs
+	path.AggregatedPaths = make(map[string]*Path)
+	return path
+}
+
 func (p *Path) SetReachabilityForFamily(protoFamily uint32, reachabilityInfo *ReachabilityInfo) {
 	if nhReachInfo, ok := p.nhReachabilityInfo[protoFamily]; ok {
 		nhReachInfo.reachabilityInfo = reachabilityInfo
@@ -28,6 +28,7 @@ import (
 	"l3/bgp/baseobjects"
 	"l3/bgp/config"
 	"l3/bgp/packet"
+	"l3/bgp/rpki"
 	"l3/bgp/utils"
 	"net"
 	"testing"
@@ -87,3 +88,28 @@ func TestPath(t *testing.T) {
 		t.Log("Path successfully created")
 	}
 }
+
+func TestPathValidationState(t *testing.T) {
+	logger := getLogger(t)
+	gConf, pConf := getConfObjects("192.168.0.100", uint32(1234), uint32(4321))
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	pathAttrs := constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+1)
+	locRib := NewLocRib(logger, nil, nil, gConf)
+	path := NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	nlri := packet.NewIPPrefix(net.ParseIP("20.1.10.0"), 24)
+	if state := path.GetValidationState(nlri); state != rpki.ValidationStateNotFound {
+		t.Fatal("Path validation state without ROA table is", state, "expected NotFound")
+	}
+
+	table := rpki.NewROATable()
+	locRib.SetROATable(table)
+	table.Update("cache", true, []rpki.ROA{rpki.NewROA(net.ParseIP("20.1.0.0"), 16, 24, pConf.PeerAS+1)}, nil)
+	if state := path.GetValidationState(nlri); state != rpki.ValidationStateValid {
+		t.Fatal("Path validation state is", state, "expected Valid")
+	}
+
+	table.Update("cache", true, []rpki.ROA{rpki.NewROA(net.ParseIP("20.1.0.0"), 16, 16, pConf.PeerAS+1)}, nil)
+	if state := path.GetValidationState(nlri); state != rpki.ValidationStateInvalid {
+		t.Fatal("Path validation state after ROA table update is", state, "expected Invalid")
+	}
+}
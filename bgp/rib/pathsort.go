@@ -25,6 +25,7 @@
 package rib
 
 import (
+	"l3/bgp/packet"
 	"sort"
 )
 
@@ -69,7 +70,16 @@ type ByPref struct {
 }
 
 func (b ByPref) Less(i, j int) bool {
-	return b.Paths[i].Pref > b.Paths[j].Pref
+	return b.Paths[i].GetPreference() > b.Paths[j].GetPreference()
+}
+
+type ByValidationState struct {
+	Paths
+	nlri packet.NLRI
+}
+
+func (b ByValidationState) Less(i, j int) bool {
+	return b.Paths[i].GetValidationState(b.nlri) < b.Paths[j].GetValidationState(b.nlri)
 }
 
 type BySmallestAS struct {
@@ -88,6 +98,15 @@ func (b ByLowestOrigin) Less(i, j int) bool {
 	return b.Paths[i].GetOrigin() < b.Paths[j].GetOrigin()
 }
 
+type ByLowestMED struct {
+	Paths
+	missingAsWorst bool
+}
+
+func (b ByLowestMED) Less(i, j int) bool {
+	return b.Paths[i].GetMED(b.missingAsWorst) < b.Paths[j].GetMED(b.missingAsWorst)
+}
+
 type ByIBGPOrEBGPRoutes struct {
 	Paths
 }
@@ -30,8 +30,10 @@ import (
 	"l3/bgp/baseobjects"
 	"l3/bgp/config"
 	"l3/bgp/packet"
+	"l3/bgp/rpki"
 	"models/objects"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 	"utils/logging"
@@ -63,6 +65,8 @@ type LocRib struct {
 	logger           *logging.Writer
 	gConf            *config.GlobalConfig
 	routeMgr         config.RouteMgrIntf
+	flowSpecMgr      config.FlowSpecMgrIntf
+	evpnMgr          config.EVPNMgrIntf
 	stateDBMgr       statedbclient.StateDBClient
 	destPathMap      map[uint32]map[string]*Destination
 	reachabilityMap  map[string]*ReachabilityInfo
@@ -73,6 +77,14 @@ type LocRib struct {
 	routeListDirty   map[uint32]bool
 	activeGet        map[uint32]bool
 	timer            map[uint32]*time.Timer
+	deferSelection   bool
+	deferredDests    map[*Destination]bool
+	roaTable         *rpki.ROATable
+	vrf              string
+	dampHistory      map[uint32]map[string]map[dampeningKey]*DampeningInfo
+	nextHopGroups    map[string]*NextHopGroup
+	nextHopGroupId   int32
+	evpnVnis         map[uint32]bool
 }
 
 func NewLocRib(logger *logging.Writer, rMgr config.RouteMgrIntf, sDBMgr statedbclient.StateDBClient,
@@ -91,13 +103,33 @@ func NewLocRib(logger *logging.Writer, rMgr config.RouteMgrIntf, sDBMgr statedbc
 		activeGet:        make(map[uint32]bool),
 		routeMutex:       sync.RWMutex{},
 		timer:            make(map[uint32]*time.Timer),
+		deferredDests:    make(map[*Destination]bool),
+		dampHistory:      make(map[uint32]map[string]map[dampeningKey]*DampeningInfo),
+		nextHopGroups:    make(map[string]*NextHopGroup),
+		evpnVnis:         make(map[uint32]bool),
 	}
 
 	return rib
 }
 
+func isPathInList(paths []*Path, path *Path) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
 func isIpInList(prefixes []packet.NLRI, ip packet.NLRI) bool {
 	for _, nlri := range prefixes {
+		switch ip.(type) {
+		case *packet.FlowSpecNLRI, *packet.EVPNNLRI, *packet.VPNNLRI, *packet.LinkStateNLRI:
+			if nlri.GetCIDR() == ip.GetCIDR() {
+				return true
+			}
+			continue
+		}
 		if nlri.GetPathId() == ip.GetPathId() &&
 			nlri.GetPrefix().Equal(ip.GetPrefix()) {
 			return true
@@ -111,12 +143,21 @@ func (l *LocRib) GetRoutesCount() map[uint32]uint32 {
 }
 
 func (l *LocRib) GetReachabilityInfo(ipStr string) *ReachabilityInfo {
+	return l.getReachabilityInfo(ipStr, -1)
+}
+
+// Link local next hops are only reachable on the interface the neighbor was discovered on
+func (l *LocRib) GetLinkLocalReachabilityInfo(ipStr string, ifIndex int32) *ReachabilityInfo {
+	return l.getReachabilityInfo(ipStr, ifIndex)
+}
+
+func (l *LocRib) getReachabilityInfo(ipStr string, ifIndex int32) *ReachabilityInfo {
 	if reachabilityInfo, ok := l.reachabilityMap[ipStr]; ok {
 		return reachabilityInfo
 	}
 
 	l.logger.Infof("GetReachabilityInfo: Reachability info not cached for Next hop %s", ipStr)
-	ribdReachabilityInfo, err := l.routeMgr.GetNextHopInfo(ipStr, -1)
+	ribdReachabilityInfo, err := l.routeMgr.GetNextHopInfo(ipStr, ifIndex)
 	if err != nil {
 		l.logger.Infof("NEXT_HOP[%s] is not reachable", ipStr)
 		return nil
@@ -133,6 +174,175 @@ func (l *LocRib) GetReachabilityInfo(ipStr string) *ReachabilityInfo {
 	return reachabilityInfo
 }
 
+func (l *LocRib) SetVrf(vrf string) {
+	l.vrf = vrf
+}
+
+func (l *LocRib) GetVrf() string {
+	return l.vrf
+}
+
+func (l *LocRib) SetROATable(table *rpki.ROATable) {
+	l.roaTable = table
+}
+
+func (l *LocRib) SetFlowSpecMgr(mgr config.FlowSpecMgrIntf) {
+	l.flowSpecMgr = mgr
+}
+
+func (l *LocRib) createFlowSpecRule(rule *config.FlowSpecRule) {
+	if l.flowSpecMgr == nil {
+		l.logger.Infof("FlowSpec manager not found, can't install rule %s", rule.Rule)
+		return
+	}
+	l.flowSpecMgr.CreateFlowSpecRule(rule)
+}
+
+func (l *LocRib) deleteFlowSpecRule(rule *config.FlowSpecRule) {
+	if l.flowSpecMgr == nil {
+		return
+	}
+	l.flowSpecMgr.DeleteFlowSpecRule(rule)
+}
+
+// getBestMatchDest returns the destination with the longest prefix that covers prefix and has a best path
+func (l *LocRib) getBestMatchDest(protoFamily uint32, prefix *packet.IPPrefix) *Destination {
+	bits := packet.GetAddressLengthForFamily(protoFamily) * 8
+	for length := int(prefix.Length); length >= 0; length-- {
+		ip := prefix.Prefix.Mask(net.CIDRMask(length, bits))
+		if ip == nil {
+			return nil
+		}
+		cidr := ip.String() + "/" + strconv.Itoa(length)
+		if dest, ok := l.destPathMap[protoFamily][cidr]; ok && dest.LocRibPath != nil {
+			return dest
+		}
+	}
+	return nil
+}
+
+// isFlowSpecFeasible validates a FlowSpec route received from an eBGP neighbor (RFC 8955 section 6). The flow must
+// have a destination prefix, the best match unicast route for the prefix must be from the same neighbor and no more
+// specific unicast route may be from a different neighbor AS.
+func (l *LocRib) isFlowSpecFeasible(nlri packet.NLRI, path *Path, protoFamily uint32) bool {
+	if path.NeighborConf == nil || !path.NeighborConf.IsExternal() {
+		return true
+	}
+
+	flowSpecNLRI, ok := nlri.(*packet.FlowSpecNLRI)
+	if !ok || flowSpecNLRI.GetComponent(packet.FlowSpecTypeDstPrefix) == nil {
+		return false
+	}
+
+	afi, _ := packet.GetAfiSafi(protoFamily)
+	unicastFamily := packet.GetProtocolFamily(afi, packet.SafiUnicast)
+	prefix := flowSpecNLRI.GetIPPrefix()
+	bestMatch := l.getBestMatchDest(unicastFamily, prefix)
+	if bestMatch == nil || bestMatch.LocRibPath.GetPeerIP() != path.GetPeerIP() {
+		return false
+	}
+
+	neighborAS := bestMatch.LocRibPath.GetNeighborAS()
+	bits := packet.GetAddressLengthForFamily(unicastFamily) * 8
+	ipNet := &net.IPNet{IP: prefix.Prefix, Mask: net.CIDRMask(int(prefix.Length), bits)}
+	for _, dest := range l.destPathMap[unicastFamily] {
+		destPrefix := dest.NLRI.GetIPPrefix()
+		if dest.LocRibPath != nil && destPrefix.Length > prefix.Length && ipNet.Contains(destPrefix.Prefix) &&
+			dest.LocRibPath.GetNeighborAS() != neighborAS {
+			return false
+		}
+	}
+	return true
+}
+
+// validateFlowSpecRoutes removes the FlowSpec routes that are not feasible from the added routes. The ones that
+// were accepted before are withdrawn.
+func (l *LocRib) validateFlowSpecRoutes(peerIP string, add, remove []packet.NLRI, path *Path,
+	protoFamily uint32) ([]packet.NLRI, []packet.NLRI) {
+	feasible := make([]packet.NLRI, 0, len(add))
+	withdrawn := make([]packet.NLRI, 0, len(remove))
+	withdrawn = append(withdrawn, remove...)
+	for _, nlri := range add {
+		if l.isFlowSpecFeasible(nlri, path, protoFamily) {
+			feasible = append(feasible, nlri)
+			continue
+		}
+
+		l.logger.Infof("FlowSpec route %s from peer %s is not feasible, it is not validated by the unicast routes",
+			nlri.GetCIDR(), peerIP)
+		if dest, ok := l.GetDest(nlri, protoFamily, false); ok && dest.getPathForIP(peerIP, nlri.GetPathId()) != nil {
+			withdrawn = append(withdrawn, nlri)
+		}
+	}
+	return feasible, withdrawn
+}
+
+func (l *LocRib) SetEVPNMgr(mgr config.EVPNMgrIntf) {
+	l.evpnMgr = mgr
+}
+
+func (l *LocRib) createEVPNRoute(vtep *config.EVPNVtepInfo, mac *config.EVPNMacInfo) {
+	if l.evpnMgr == nil {
+		l.logger.Info("EVPN manager not found, can't program remote vtep", vtep, "mac", mac)
+		return
+	}
+	if vtep != nil {
+		l.evpnMgr.CreateRemoteVtep(vtep)
+	}
+	if mac != nil {
+		l.evpnMgr.CreateRemoteMac(mac)
+	}
+}
+
+// SetEVPNVni adds or removes a local VNI. Remote EVPN routes are only programmed for the local VNIs, the routes
+// already received for the VNI are programmed when it is added and removed with it.
+func (l *LocRib) SetEVPNVni(vni uint32, add bool) {
+	if add == l.evpnVnis[vni] {
+		return
+	}
+	if add {
+		l.evpnVnis[vni] = true
+	}
+
+	protoFamily := packet.GetProtocolFamily(packet.AfiL2VPN, packet.SafiEVPN)
+	for _, dest := range l.destPathMap[protoFamily] {
+		for path := range dest.ecmpPaths {
+			if path.IsLocal() {
+				continue
+			}
+			vtep, mac := dest.ConstructEVPNInfo(path)
+			if getEVPNInfoVni(vtep, mac) != vni || !dest.isEVPNRouteImported(path, vni) {
+				continue
+			}
+			if add {
+				l.createEVPNRoute(vtep, mac)
+			} else {
+				l.deleteEVPNRoute(vtep, mac)
+			}
+		}
+	}
+
+	if !add {
+		delete(l.evpnVnis, vni)
+	}
+}
+
+func (l *LocRib) deleteEVPNRoute(vtep *config.EVPNVtepInfo, mac *config.EVPNMacInfo) {
+	if l.evpnMgr == nil {
+		return
+	}
+	if mac != nil {
+		l.evpnMgr.DeleteRemoteMac(mac)
+	}
+	if vtep != nil {
+		l.evpnMgr.DeleteRemoteVtep(vtep)
+	}
+}
+
+func (l *LocRib) GetDestinations(protoFamily uint32) map[string]*Destination {
+	return l.destPathMap[protoFamily]
+}
+
 func (l *LocRib) GetDestFromIPAndLen(protoFamily uint32, ip string, cidrLen uint32) *Destination {
 	if nlriDestMap, ok := l.destPathMap[protoFamily]; ok {
 		if dest, ok := nlriDestMap[ip]; ok {
@@ -153,6 +363,7 @@ func (l *LocRib) GetDest(nlri packet.NLRI, protoFamily uint32, createIfNotExist
 		dest, ok = nlriDestMap[nlri.GetCIDR()]
 		if !ok && createIfNotExist {
 			dest = NewDestination(l, nlri, protoFamily, l.gConf)
+			dest.dampInfo = l.getDampeningInfo(protoFamily, nlri.GetCIDR())
 			l.destPathMap[protoFamily][nlri.GetCIDR()] = dest
 			l.addRoutesToRouteList(dest, protoFamily)
 			if _, found := l.routesCount[protoFamily]; !found {
@@ -206,6 +417,9 @@ func (l *LocRib) ProcessRoutes(peerIP string, add, rem []packet.NLRI, addPath, r
 			}
 			op := l.stateDBMgr.UpdateObject
 			oldPath := dest.RemovePath(peerIP, nlri.GetPathId(), remPath)
+			if oldPath != nil {
+				dest.penalizePath(peerIP, nlri.GetPathId(), oldPath, DampeningWithdrawPenalty)
+			}
 			if oldPath != nil && !oldPath.IsReachable(dest.protoFamily) {
 				nextHop := oldPath.GetNextHop(dest.protoFamily)
 				if nextHop != nil {
@@ -239,7 +453,7 @@ func (l *LocRib) ProcessRoutes(peerIP string, add, rem []packet.NLRI, addPath, r
 			updated, withdrawn, updatedAddPaths = l.updateRibOutInfo(action, addPathsMod, addRoutes, updRoutes,
 				delRoutes, dest, updated, withdrawn, updatedAddPaths)
 
-			if oldPath != nil && remPath != nil {
+			if oldPath != nil && remPath != nil && !remPath.IsLeaked() {
 				if neighborConf := remPath.GetNeighborConf(); neighborConf != nil {
 					l.logger.Infof("Decrement prefix count for destination %s from Peer %s",
 						nlri.GetCIDR(), peerIP)
@@ -263,7 +477,8 @@ func (l *LocRib) ProcessRoutes(peerIP string, add, rem []packet.NLRI, addPath, r
 
 	nextHopStr := addPath.GetNextHop(protoFamily).String()
 	for _, nlri := range add {
-		if nlri.GetPrefix().String() == "0.0.0.0" {
+		if nlri.GetPrefix().String() == "0.0.0.0" && !packet.IsFlowSpecFamily(protoFamily) &&
+			!packet.IsEVPNFamily(protoFamily) && !packet.IsLinkStateFamily(protoFamily) {
 			l.logger.Infof("Can't process NLRI 0.0.0.0")
 			continue
 		}
@@ -274,7 +489,8 @@ func (l *LocRib) ProcessRoutes(peerIP string, add, rem []packet.NLRI, addPath, r
 		if !alreadyCreated {
 			op = l.stateDBMgr.AddObject
 		}
-		if oldPath := dest.getPathForIP(peerIP, nlri.GetPathId()); oldPath == nil && addPath.NeighborConf != nil {
+		oldPath := dest.getPathForIP(peerIP, nlri.GetPathId())
+		if oldPath == nil && addPath.NeighborConf != nil && !addPath.IsLeaked() {
 			if !addPath.NeighborConf.CanAcceptNewPrefix() {
 				l.logger.Infof("Max prefixes limit reached for peer %s, can't process %s", peerIP,
 					nlri.GetCIDR())
@@ -283,6 +499,8 @@ func (l *LocRib) ProcessRoutes(peerIP string, add, rem []packet.NLRI, addPath, r
 			}
 			l.logger.Infof("Increment prefix count for destination %s from Peer %s", nlri.GetCIDR(), peerIP)
 			addPath.NeighborConf.IncrPrefixCount()
+		} else if oldPath != nil && oldPath != addPath && isAttrChange(oldPath, addPath, protoFamily) {
+			dest.penalizePath(peerIP, nlri.GetPathId(), addPath, DampeningAttrChangePenalty)
 		}
 
 		dest.AddOrUpdatePath(peerIP, nlri.GetPathId(), addPath)
@@ -339,14 +557,28 @@ func (l *LocRib) TestNHAndProcessRoutes(peerIP string, add, remove []packet.NLRI
 	updatedAddPaths []*Destination) (map[uint32]map[*Path][]*Destination, []*Destination, []*Destination, bool) {
 	var reachabilityInfo *ReachabilityInfo
 	nextHopStr := ""
-	if len(add) > 0 {
+	if len(add) > 0 && packet.IsFlowSpecFamily(protoFamily) {
+		// FlowSpec rules don't have a next hop to resolve
+		addPath.SetReachabilityForFamily(protoFamily, NewReachabilityInfo("", 0, 0, 0))
+		if !addPath.IsValid() {
+			l.logger.Infof("Received a update with our cluster id %d, Discarding the update.",
+				addPath.NeighborConf.RunningConf.RouteReflectorClusterId)
+			return updated, withdrawn, updatedAddPaths, true
+		}
+		add, remove = l.validateFlowSpecRoutes(peerIP, add, remove, addPath, protoFamily)
+	} else if len(add) > 0 {
 		nextHop := addPath.GetNextHop(protoFamily)
 		if nextHop == nil {
 			l.logger.Errf("RIB - Next hop not found for protocol family %d", protoFamily)
 			return updated, withdrawn, updatedAddPaths, true
 		}
 		nextHopStr = nextHop.String()
-		reachabilityInfo = l.GetReachabilityInfo(nextHopStr)
+		if nextHop.IsLinkLocalUnicast() && addPath.NeighborConf != nil &&
+			addPath.NeighborConf.RunningConf.IfIndex != -1 {
+			reachabilityInfo = l.GetLinkLocalReachabilityInfo(nextHopStr, addPath.NeighborConf.RunningConf.IfIndex)
+		} else {
+			reachabilityInfo = l.GetReachabilityInfo(nextHopStr)
+		}
 		addPath.SetReachabilityForFamily(protoFamily, reachabilityInfo)
 
 		//addPath.GetReachabilityInfo()
@@ -509,6 +741,173 @@ func (l *LocRib) RemoveUpdatesFromNeighbor(peerIP string, neighborConf *base.Nei
 	return updated, withdrawn, updatedAddPaths
 }
 
+func (l *LocRib) MarkStaleUpdatesFromNeighbor(peerIP string, protoFamily uint32) int {
+	count := 0
+	for _, dest := range l.destPathMap[protoFamily] {
+		count += dest.MarkStalePaths(peerIP)
+	}
+	l.logger.Infof("MarkStaleUpdatesFromNeighbor - Neighbor %s family %d, marked %d paths as stale", peerIP,
+		protoFamily, count)
+	return count
+}
+
+func (l *LocRib) RemoveStaleUpdatesFromNeighbor(peerIP string, neighborConf *base.NeighborConf, protoFamily uint32,
+	addPathCount int) (map[uint32]map[*Path][]*Destination, []*Destination, []*Destination) {
+	remPath := NewPath(l, neighborConf, nil, nil, RouteTypeEGP)
+	withdrawn := make([]*Destination, 0)
+	updated := make(map[uint32]map[*Path][]*Destination)
+	updatedAddPaths := make([]*Destination, 0)
+
+	for destIP, dest := range l.destPathMap[protoFamily] {
+		if !dest.HasStalePaths(peerIP) {
+			continue
+		}
+
+		op := l.stateDBMgr.UpdateObject
+		dest.RemoveStalePaths(peerIP, remPath)
+		action, addPathsMod, addRoutes, updRoutes, delRoutes := dest.SelectRouteForLocRib(addPathCount)
+		l.logger.Info("RemoveStaleUpdatesFromNeighbor - dest", dest.NLRI.GetCIDR(),
+			"SelectRouteForLocRib returned action", action, "addRoutes", addRoutes, "updRoutes", updRoutes,
+			"delRoutes", delRoutes)
+		updated, withdrawn, updatedAddPaths = l.updateRibOutInfo(action, addPathsMod, addRoutes, updRoutes,
+			delRoutes, dest, updated, withdrawn, updatedAddPaths)
+		if action == RouteActionDelete && dest.IsEmpty() {
+			l.logger.Info("All routes removed for dest", dest.NLRI.GetCIDR())
+			l.removeRoutesFromRouteList(dest, protoFamily)
+			delete(l.destPathMap[protoFamily], destIP)
+			l.routesCount[protoFamily]--
+			op = l.stateDBMgr.DeleteObject
+		}
+		op(l.GetRouteStateConfigObj(dest.GetBGPRoute()))
+	}
+
+	return updated, withdrawn, updatedAddPaths
+}
+
+func (l *LocRib) DeferSelection() {
+	l.logger.Info("LocRib - defer best path selection")
+	l.deferSelection = true
+}
+
+func (l *LocRib) IsSelectionDeferred() bool {
+	return l.deferSelection
+}
+
+// isRouteInstalled returns true if the route in RIBd is the next hop of one of the ECMP paths of the destination
+func (l *LocRib) isRouteInstalled(route *config.RouteConfig) bool {
+	afi, ipLength := packet.AfiIP, net.IPv4len
+	if route.IsIPv6 {
+		afi, ipLength = packet.AfiIP6, net.IPv6len
+	}
+	mask := net.ParseIP(route.NetworkMask)
+	if mask == nil {
+		return false
+	}
+	ones, _ := net.IPMask(mask[len(mask)-ipLength:]).Size()
+	protoFamily := packet.GetProtocolFamily(afi, packet.SafiUnicast)
+	dest, ok := l.destPathMap[protoFamily][route.DestinationNw+"/"+strconv.Itoa(ones)]
+	if !ok {
+		return false
+	}
+
+	for path, _ := range dest.ecmpPaths {
+		if path.IsAggregate() {
+			if route.NullRoute {
+				return true
+			}
+			continue
+		}
+		if reachInfo := path.GetReachability(protoFamily); reachInfo != nil && reachInfo.NextHop == route.NextHopIp {
+			return true
+		}
+	}
+	return false
+}
+
+// SweepInstalledRoutes deletes the routes that bgpd installed in RIBd before it restarted and didn't install again
+// after the restart.
+func (l *LocRib) SweepInstalledRoutes(routes []*config.RouteConfig) {
+	for _, route := range routes {
+		if l.isRouteInstalled(route) {
+			continue
+		}
+		l.logger.Info("LocRib - remove stale route", route.DestinationNw, "mask", route.NetworkMask, "next hop",
+			route.NextHopIp, "installed before the restart")
+		l.routeMgr.DeleteRoute(route)
+	}
+}
+
+func (l *LocRib) addDeferredDest(dest *Destination) {
+	l.deferredDests[dest] = true
+}
+
+func (l *LocRib) ProcessDeferredDests(addPathCount int) (map[uint32]map[*Path][]*Destination, []*Destination,
+	[]*Destination) {
+	withdrawn := make([]*Destination, 0)
+	updated := make(map[uint32]map[*Path][]*Destination)
+	updatedAddPaths := make([]*Destination, 0)
+
+	l.logger.Info("LocRib - run deferred best path selection for", len(l.deferredDests), "destinations")
+	l.deferSelection = false
+	for dest, _ := range l.deferredDests {
+		protoFamily := dest.GetProtocolFamily()
+		destIP := dest.NLRI.GetCIDR()
+		if l.destPathMap[protoFamily][destIP] != dest {
+			continue
+		}
+
+		op := l.stateDBMgr.UpdateObject
+		action, addPathsMod, addRoutes, updRoutes, delRoutes := dest.SelectRouteForLocRib(addPathCount)
+		updated, withdrawn, updatedAddPaths = l.updateRibOutInfo(action, addPathsMod, addRoutes, updRoutes,
+			delRoutes, dest, updated, withdrawn, updatedAddPaths)
+		if action == RouteActionDelete && dest.IsEmpty() {
+			l.removeRoutesFromRouteList(dest, protoFamily)
+			delete(l.destPathMap[protoFamily], destIP)
+			l.routesCount[protoFamily]--
+			op = l.stateDBMgr.DeleteObject
+		}
+		op(l.GetRouteStateConfigObj(dest.GetBGPRoute()))
+	}
+	l.deferredDests = make(map[*Destination]bool)
+
+	return updated, withdrawn, updatedAddPaths
+}
+
+// ProcessValidationStateChange runs the best path selection for all the destinations after the ROA table
+// changed. It is needed only when valid paths are preferred.
+func (l *LocRib) ProcessValidationStateChange(addPathCount int) (map[uint32]map[*Path][]*Destination,
+	[]*Destination, []*Destination) {
+	l.logger.Info("LocRib - run best path selection after origin validation state change")
+	return l.RecalculateBestPaths(addPathCount)
+}
+
+// RecalculateBestPaths runs the best path selection for all the destinations.
+func (l *LocRib) RecalculateBestPaths(addPathCount int) (map[uint32]map[*Path][]*Destination,
+	[]*Destination, []*Destination) {
+	withdrawn := make([]*Destination, 0)
+	updated := make(map[uint32]map[*Path][]*Destination)
+	updatedAddPaths := make([]*Destination, 0)
+
+	for protoFamily, ipDestMap := range l.destPathMap {
+		for destIP, dest := range ipDestMap {
+			op := l.stateDBMgr.UpdateObject
+			dest.recalculate = true
+			action, addPathsMod, addRoutes, updRoutes, delRoutes := dest.SelectRouteForLocRib(addPathCount)
+			updated, withdrawn, updatedAddPaths = l.updateRibOutInfo(action, addPathsMod, addRoutes, updRoutes,
+				delRoutes, dest, updated, withdrawn, updatedAddPaths)
+			if action == RouteActionDelete && dest.IsEmpty() {
+				l.removeRoutesFromRouteList(dest, protoFamily)
+				delete(l.destPathMap[protoFamily], destIP)
+				l.routesCount[protoFamily]--
+				op = l.stateDBMgr.DeleteObject
+			}
+			op(l.GetRouteStateConfigObj(dest.GetBGPRoute()))
+		}
+	}
+
+	return updated, withdrawn, updatedAddPaths
+}
+
 func (l *LocRib) RemoveUpdatesFromAllNeighbors(addPathCount int) {
 	withdrawn := make([]*Destination, 0)
 	updated := make(map[uint32]map[*Path][]*Destination)
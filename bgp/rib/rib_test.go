@@ -226,3 +226,412 @@ func TestProcessUpdate(t *testing.T) {
 		t.Fatal("LocRib:ProcessUpdate - Did not add all prefixes to RIB")
 	}
 }
+
+func TestRouteServerProcessUpdate(t *testing.T) {
+	logger := getLogger(t)
+	neighbor := "192.168.0.100"
+	peerAS := uint32(4321)
+	gConf, pConf := getConfObjects(neighbor, uint32(1234), peerAS)
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	routeServer := NewRouteServer(logger, &RouteMgr{t}, gConf)
+	rejectAll := false
+	routeServer.SetAcceptFunc(func(client string, protoFamily uint32, nlri packet.NLRI, path *Path) bool {
+		return !rejectAll && !(client == "192.168.0.102" && nlri.GetCIDR() == "60.1.0.0/16")
+	})
+	for _, client := range []string{neighbor, "192.168.0.101", "192.168.0.102"} {
+		routeServer.AddClient(client)
+	}
+
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
+	view, _ := routeServer.GetView(neighbor)
+	path := NewPath(view, nConf, constructPathAttrs(net.ParseIP(neighbor), peerAS, peerAS+3), nil, RouteTypeEGP)
+	nlri := constructIPPrefix(t, "30.1.10.0/24", "60.1.0.0/16")
+	updated, _, _, addedAllPrefixes := routeServer.ProcessUpdate(nConf, path, nlri, nil, protoFamily, 0,
+		make(map[uint32]map[*Path][]*Destination), make([]*Destination, 0), make([]*Destination, 0))
+	if !addedAllPrefixes || nConf.Neighbor.State.TotalPrefixes != 2 {
+		t.Fatal("RouteServer:ProcessUpdate - Prefix count", nConf.Neighbor.State.TotalPrefixes)
+	}
+	for _, destinations := range updated[protoFamily] {
+		if len(destinations) != 3 {
+			t.Fatal("RouteServer:ProcessUpdate - Expected 3 destinations in the views, found", destinations)
+		}
+	}
+
+	expected := map[string]int{neighbor: 0, "192.168.0.101": 2, "192.168.0.102": 1}
+	for client, count := range expected {
+		if view, _ := routeServer.GetView(client); len(view.GetDestinations(protoFamily)) != count {
+			t.Fatal("RouteServer:ProcessUpdate - View of", client, "has destinations",
+				view.GetDestinations(protoFamily), "expected", count)
+		}
+	}
+
+	// A new client gets the routes from the existing clients
+	routeServer.AddClient("192.168.0.103")
+	routeServer.SyncClient("192.168.0.103", 0)
+	if view, _ := routeServer.GetView("192.168.0.103"); len(view.GetDestinations(protoFamily)) != 2 {
+		t.Fatal("RouteServer:SyncClient - View of new client has destinations", view.GetDestinations(protoFamily))
+	}
+
+	rejectAll = true
+	_, withdrawn, _ := routeServer.SyncClient("192.168.0.101", 0)
+	if len(withdrawn) != 2 {
+		t.Fatal("RouteServer:SyncClient - Routes rejected by the client policy not withdrawn, withdrawn", withdrawn)
+	}
+
+	routeServer.RemoveUpdatesFromNeighbor(neighbor, nConf, 0)
+	for client := range expected {
+		if view, _ := routeServer.GetView(client); len(view.GetDestinations(protoFamily)) != 0 {
+			t.Fatal("RouteServer:RemoveUpdatesFromNeighbor - View of", client, "has destinations",
+				view.GetDestinations(protoFamily))
+		}
+	}
+	if nConf.Neighbor.State.TotalPrefixes != 0 {
+		t.Fatal("RouteServer:RemoveUpdatesFromNeighbor - Prefix count", nConf.Neighbor.State.TotalPrefixes)
+	}
+}
+
+func TestProcessDeferredDests(t *testing.T) {
+	logger := getLogger(t)
+	neighbor := "192.168.0.100"
+	localAS := uint32(1234)
+	peerAS := uint32(4321)
+	gConf, pConf := getConfObjects(neighbor, localAS, peerAS)
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	locRib := constructRib(t, logger, gConf)
+	locRib.DeferSelection()
+
+	pathAttrs := constructPathAttrs(net.ParseIP(neighbor), peerAS, peerAS+3, peerAS+6)
+	nlri := constructIPPrefix(t, "30.1.10.0/24", "40.1.0.0/16")
+	path := NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	updated := make(map[uint32]map[*Path][]*Destination)
+	withdrawn := make([]*Destination, 0)
+	updatedAddPaths := make([]*Destination, 0)
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
+
+	updated, withdrawn, updatedAddPaths, _ = locRib.ProcessUpdate(nConf, path, nlri, nil, protoFamily, 0, updated,
+		withdrawn, updatedAddPaths)
+	if len(updated[protoFamily]) != 0 || len(withdrawn) != 0 {
+		t.Fatal("LocRib:ProcessUpdate - Found updated or withdrawn paths while selection is deferred, updated=",
+			updated, "withdrawn=", withdrawn)
+	}
+
+	updated, withdrawn, updatedAddPaths = locRib.ProcessDeferredDests(0)
+	if locRib.IsSelectionDeferred() {
+		t.Fatal("LocRib:ProcessDeferredDests - Best path selection is still deferred")
+	}
+	if len(updated[protoFamily]) != 1 {
+		t.Fatal("LocRib:ProcessDeferredDests - Found more path in protocol family", protoFamily)
+	}
+	for path, destinations := range updated[protoFamily] {
+		if len(destinations) != 2 {
+			t.Fatalf("LocRib:ProcessDeferredDests - Did not find 2 destinations %+v for path %+v", destinations,
+				path)
+		}
+	}
+	if len(withdrawn) != 0 {
+		t.Fatal("LocRib:ProcessDeferredDests - Found withdrawn paths, withdrawn=", withdrawn)
+	}
+}
+
+type sweepRouteMgr struct {
+	RouteMgr
+	deleted []*config.RouteConfig
+}
+
+func (r *sweepRouteMgr) DeleteRoute(route *config.RouteConfig) {
+	r.t.Log("sweepRouteMgr:DeleteRoute:", route)
+	r.deleted = append(r.deleted, route)
+}
+
+func TestSweepInstalledRoutes(t *testing.T) {
+	logger := getLogger(t)
+	peerIP := "192.168.0.100"
+	gConf, pConf := getConfObjects(peerIP, uint32(1234), uint32(4321))
+	routeMgr := &sweepRouteMgr{RouteMgr: RouteMgr{t}}
+	locRib := NewLocRib(logger, routeMgr, &DBClient{t}, gConf)
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
+	dest, _ := locRib.GetDest(packet.NewIPPrefix(net.ParseIP("20.1.10.0"), 24), protoFamily, true)
+
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	nConf.SetPeerAttrs(net.ParseIP(peerIP), 4, 3, 1, nil)
+	pathAttrs := constructPathAttrs(pConf.NeighborAddress, pConf.PeerAS, pConf.PeerAS+1)
+	path := NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	path.SetReachabilityForNextHop(peerIP, NewReachabilityInfo("192.168.0.101", 0, 0, 0))
+	dest.AddOrUpdatePath(peerIP, 0, path)
+	dest.SelectRouteForLocRib(0)
+
+	installed := &config.RouteConfig{DestinationNw: "20.1.10.0", NetworkMask: "255.255.255.0",
+		NextHopIp: "192.168.0.101", Protocol: "EBGP"}
+	staleNextHop := &config.RouteConfig{DestinationNw: "20.1.10.0", NetworkMask: "255.255.255.0",
+		NextHopIp: "192.168.0.102", Protocol: "EBGP"}
+	staleDest := &config.RouteConfig{DestinationNw: "30.1.10.0", NetworkMask: "255.255.255.0",
+		NextHopIp: "192.168.0.101", Protocol: "EBGP"}
+	locRib.SweepInstalledRoutes([]*config.RouteConfig{installed, staleNextHop, staleDest})
+
+	if len(routeMgr.deleted) != 2 || routeMgr.deleted[0] != staleNextHop || routeMgr.deleted[1] != staleDest {
+		t.Fatal("LocRib:SweepInstalledRoutes - Expected the stale routes to be deleted, deleted=",
+			routeMgr.deleted)
+	}
+}
+
+type FlowSpecMgr struct {
+	t       *testing.T
+	created []*config.FlowSpecRule
+	deleted []*config.FlowSpecRule
+}
+
+func (f *FlowSpecMgr) Start() {
+}
+
+func (f *FlowSpecMgr) CreateFlowSpecRule(rule *config.FlowSpecRule) {
+	f.t.Log("FlowSpecMgr:CreateFlowSpecRule", rule.Rule)
+	f.created = append(f.created, rule)
+}
+
+func (f *FlowSpecMgr) DeleteFlowSpecRule(rule *config.FlowSpecRule) {
+	f.t.Log("FlowSpecMgr:DeleteFlowSpecRule", rule.Rule)
+	f.deleted = append(f.deleted, rule)
+}
+
+// addUnicastRoutes adds the IPv4 unicast prefixes from the neighbor to the Loc-RIB
+func addUnicastRoutes(t *testing.T, locRib *LocRib, nConf *base.NeighborConf, prefixes ...string) {
+	neighbor := nConf.RunningConf.NeighborAddress
+	peerAS := nConf.RunningConf.PeerAS
+	pathAttrs := constructPathAttrs(neighbor, peerAS, peerAS+3)
+	path := NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
+	locRib.ProcessUpdate(nConf, path, constructIPPrefix(t, prefixes...), nil, protoFamily, 0,
+		make(map[uint32]map[*Path][]*Destination), make([]*Destination, 0), make([]*Destination, 0))
+}
+
+// processFlowSpecRoutes adds the FlowSpec routes from the neighbor and returns the number of routes updated
+func processFlowSpecRoutes(locRib *LocRib, nConf *base.NeighborConf, nlri ...packet.NLRI) int {
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiFlowSpec)
+	neighbor := nConf.RunningConf.NeighborAddress
+	pathAttrs := constructPathAttrs(neighbor, nConf.RunningConf.PeerAS)
+	mpReach := packet.ConstructIPv6MPReachNLRI(protoFamily, nil, nil, nlri)
+	path := NewPath(locRib, nConf, pathAttrs, mpReach, RouteTypeEGP)
+	updated, _, _, _ := locRib.ProcessUpdate(nConf, path, nlri, nil, protoFamily, 0,
+		make(map[uint32]map[*Path][]*Destination), make([]*Destination, 0), make([]*Destination, 0))
+
+	count := 0
+	for _, destinations := range updated[protoFamily] {
+		count += len(destinations)
+	}
+	return count
+}
+
+func newFlowSpecDstNLRI(prefix string, length uint8) packet.NLRI {
+	return packet.NewFlowSpecNLRI(
+		packet.NewFlowSpecPrefix(packet.FlowSpecTypeDstPrefix, net.ParseIP(prefix), length),
+		packet.NewFlowSpecOpComponent(packet.FlowSpecTypeIPProto,
+			packet.FlowSpecOpValue{Op: packet.FlowSpecOpEq, Value: 17}))
+}
+
+func TestProcessFlowSpecUpdate(t *testing.T) {
+	logger := getLogger(t)
+	neighbor := "192.168.0.100"
+	localAS := uint32(1234)
+	peerAS := uint32(4321)
+	gConf, pConf := getConfObjects(neighbor, localAS, peerAS)
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	locRib := constructRib(t, logger, gConf)
+	flowSpecMgr := &FlowSpecMgr{t: t}
+	locRib.SetFlowSpecMgr(flowSpecMgr)
+	addUnicastRoutes(t, locRib, nConf, "30.1.10.0/24")
+
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiFlowSpec)
+	nlri := []packet.NLRI{
+		packet.NewFlowSpecNLRI(
+			packet.NewFlowSpecPrefix(packet.FlowSpecTypeDstPrefix, net.ParseIP("30.1.10.0"), 24),
+			packet.NewFlowSpecOpComponent(packet.FlowSpecTypeIPProto,
+				packet.FlowSpecOpValue{Op: packet.FlowSpecOpEq, Value: 17})),
+		packet.NewFlowSpecNLRI(
+			packet.NewFlowSpecPrefix(packet.FlowSpecTypeDstPrefix, net.ParseIP("30.1.10.0"), 25),
+			packet.NewFlowSpecOpComponent(packet.FlowSpecTypeDstPort,
+				packet.FlowSpecOpValue{Op: packet.FlowSpecOpEq, Value: 53})),
+	}
+	pathAttrs := constructPathAttrs(net.ParseIP(neighbor), peerAS, peerAS+3, peerAS+6)
+	pathAttrs = packet.SetExtCommunities(pathAttrs,
+		[]uint64{packet.NewFlowSpecTrafficRateCommunity(uint16(peerAS), 0)})
+	mpReach := packet.ConstructIPv6MPReachNLRI(protoFamily, nil, nil, nlri)
+	path := NewPath(locRib, nConf, pathAttrs, mpReach, RouteTypeEGP)
+	updated := make(map[uint32]map[*Path][]*Destination)
+	withdrawn := make([]*Destination, 0)
+	updatedAddPaths := make([]*Destination, 0)
+
+	updated, withdrawn, updatedAddPaths, _ = locRib.ProcessUpdate(nConf, path, nlri, nil, protoFamily, 0, updated,
+		withdrawn, updatedAddPaths)
+	if len(updated[protoFamily]) != 1 {
+		t.Fatal("LocRib:ProcessUpdate - Expected 1 path in protocol family", protoFamily, "updated=", updated)
+	}
+	for path, destinations := range updated[protoFamily] {
+		if len(destinations) != 2 {
+			t.Fatalf("LocRib:ProcessUpdate - Did not find 2 flowspec destinations %+v for path %+v", destinations,
+				path)
+		}
+	}
+	if len(flowSpecMgr.created) != 2 {
+		t.Fatal("LocRib:ProcessUpdate - Expected 2 flowspec rules to be installed, got:", flowSpecMgr.created)
+	}
+	for _, rule := range flowSpecMgr.created {
+		if !rule.RateLimit || rule.Rate != 0 || rule.Protocol != "EBGP" {
+			t.Fatalf("LocRib:ProcessUpdate - flowspec rule %+v does not have the discard action", rule)
+		}
+		if rule.Rule == nlri[0].GetCIDR() && (rule.DestinationNw != "30.1.10.0/24" || len(rule.Matches) != 1 ||
+			rule.Matches[0].Value != "=17") {
+			t.Fatalf("LocRib:ProcessUpdate - flowspec rule %+v does not match NLRI %s", rule, nlri[0])
+		}
+	}
+
+	updated = make(map[uint32]map[*Path][]*Destination)
+	withdrawn = make([]*Destination, 0)
+	updatedAddPaths = make([]*Destination, 0)
+	path = NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	updated, withdrawn, updatedAddPaths, _ = locRib.ProcessUpdate(nConf, path, nil, nlri[1:], protoFamily, 0,
+		updated, withdrawn, updatedAddPaths)
+	if len(withdrawn) != 1 {
+		t.Fatal("LocRib:ProcessUpdate - Expected 1 withdrawn flowspec destination, withdrawn=", withdrawn)
+	}
+	if len(flowSpecMgr.deleted) != 1 || flowSpecMgr.deleted[0].Rule != nlri[1].GetCIDR() {
+		t.Fatal("LocRib:ProcessUpdate - Expected flowspec rule", nlri[1].GetCIDR(), "to be removed, got:",
+			flowSpecMgr.deleted)
+	}
+}
+
+func TestProcessFlowSpecValidation(t *testing.T) {
+	logger := getLogger(t)
+	neighbor := "192.168.0.100"
+	gConf, pConf := getConfObjects(neighbor, uint32(1234), uint32(4321))
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	nConf2 := base.NewNeighborConf(logger, gConf, nil, *getNeighborConf("172.16.0.1", 0, 5432))
+	locRib := constructRib(t, logger, gConf)
+	locRib.SetFlowSpecMgr(&FlowSpecMgr{t: t})
+
+	if count := processFlowSpecRoutes(locRib, nConf, newFlowSpecDstNLRI("30.1.10.0", 24)); count != 0 {
+		t.Fatal("FlowSpec route without a unicast route for the destination prefix is accepted")
+	}
+
+	addUnicastRoutes(t, locRib, nConf2, "30.1.0.0/16")
+	if count := processFlowSpecRoutes(locRib, nConf, newFlowSpecDstNLRI("30.1.10.0", 24)); count != 0 {
+		t.Fatal("FlowSpec route is accepted when the best match unicast route is from another neighbor")
+	}
+
+	addUnicastRoutes(t, locRib, nConf, "30.1.10.0/24")
+	if count := processFlowSpecRoutes(locRib, nConf, newFlowSpecDstNLRI("30.1.10.0", 24)); count != 1 {
+		t.Fatal("FlowSpec route is not accepted when the best match unicast route is from the same neighbor")
+	}
+
+	noDstPrefix := packet.NewFlowSpecNLRI(packet.NewFlowSpecOpComponent(packet.FlowSpecTypeDstPort,
+		packet.FlowSpecOpValue{Op: packet.FlowSpecOpEq, Value: 53}))
+	if count := processFlowSpecRoutes(locRib, nConf, noDstPrefix); count != 0 {
+		t.Fatal("FlowSpec route without a destination prefix is accepted")
+	}
+
+	addUnicastRoutes(t, locRib, nConf2, "30.1.10.128/25")
+	if count := processFlowSpecRoutes(locRib, nConf, newFlowSpecDstNLRI("30.1.10.0", 24)); count != 0 {
+		t.Fatal("FlowSpec route is accepted with a more specific unicast route from another neighbor AS")
+	}
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiFlowSpec)
+	if dest, ok := locRib.GetDest(newFlowSpecDstNLRI("30.1.10.0", 24), protoFamily, false); ok &&
+		dest.LocRibPath != nil {
+		t.Fatal("Accepted FlowSpec route is not withdrawn after it is received again and is not feasible")
+	}
+}
+
+type EVPNMgr struct {
+	t           *testing.T
+	vteps       []*config.EVPNVtepInfo
+	macs        []*config.EVPNMacInfo
+	deletedMacs []*config.EVPNMacInfo
+}
+
+func (e *EVPNMgr) Start() {
+}
+
+func (e *EVPNMgr) CreateRemoteVtep(vtep *config.EVPNVtepInfo) {
+	e.t.Log("EVPNMgr:CreateRemoteVtep", vtep.Vni, vtep.VtepIP)
+	e.vteps = append(e.vteps, vtep)
+}
+
+func (e *EVPNMgr) DeleteRemoteVtep(vtep *config.EVPNVtepInfo) {
+	e.t.Log("EVPNMgr:DeleteRemoteVtep", vtep.Vni, vtep.VtepIP)
+}
+
+func (e *EVPNMgr) CreateRemoteMac(mac *config.EVPNMacInfo) {
+	e.t.Log("EVPNMgr:CreateRemoteMac", mac.Vni, mac.MAC, mac.VtepIP)
+	e.macs = append(e.macs, mac)
+}
+
+func (e *EVPNMgr) DeleteRemoteMac(mac *config.EVPNMacInfo) {
+	e.t.Log("EVPNMgr:DeleteRemoteMac", mac.Vni, mac.MAC, mac.VtepIP)
+	e.deletedMacs = append(e.deletedMacs, mac)
+}
+
+func TestProcessEVPNUpdate(t *testing.T) {
+	logger := getLogger(t)
+	neighbor := "192.168.0.100"
+	localAS := uint32(1234)
+	peerAS := uint32(4321)
+	gConf, pConf := getConfObjects(neighbor, localAS, peerAS)
+	nConf := base.NewNeighborConf(logger, gConf, nil, *pConf)
+	locRib := constructRib(t, logger, gConf)
+	evpnMgr := &EVPNMgr{t: t}
+	locRib.SetEVPNMgr(evpnMgr)
+
+	protoFamily := packet.GetProtocolFamily(packet.AfiL2VPN, packet.SafiEVPN)
+	vtepIP := net.ParseIP("10.1.1.1")
+	rd := packet.NewRouteDistinguisher(packet.RouteDistinguisherTypeAS2, peerAS, 100)
+	mac, _ := net.ParseMAC("00:aa:bb:cc:dd:ee")
+	nlri := []packet.NLRI{
+		packet.NewEVPNInclusiveMulticastNLRI(rd, 0, vtepIP),
+		packet.NewEVPNMacIPNLRI(rd, 0, mac, net.ParseIP("20.1.1.10"), 100),
+	}
+	pathAttrs := constructPathAttrs(net.ParseIP(neighbor), peerAS)
+	pathAttrs = packet.SetExtCommunities(pathAttrs, []uint64{packet.NewEncapsulationCommunity(packet.TunnelTypeVXLAN),
+		packet.NewEVPNRouteTargetCommunity(peerAS, 100)})
+	pathAttrs = append(pathAttrs, packet.NewBGPPathAttrPMSITunnel(packet.PMSITunnelTypeIngressReplication, 100,
+		vtepIP))
+	mpReach := packet.ConstructIPv6MPReachNLRI(protoFamily, vtepIP, nil, nlri)
+	path := NewPath(locRib, nConf, pathAttrs, mpReach, RouteTypeEGP)
+	updated := make(map[uint32]map[*Path][]*Destination)
+	withdrawn := make([]*Destination, 0)
+	updatedAddPaths := make([]*Destination, 0)
+
+	updated, withdrawn, updatedAddPaths, _ = locRib.ProcessUpdate(nConf, path, nlri, nil, protoFamily, 0, updated,
+		withdrawn, updatedAddPaths)
+	if len(updated[protoFamily]) != 1 {
+		t.Fatal("LocRib:ProcessUpdate - Expected 1 path in protocol family", protoFamily, "updated=", updated)
+	}
+	if len(evpnMgr.vteps) != 0 || len(evpnMgr.macs) != 0 {
+		t.Fatal("LocRib:ProcessUpdate - Expected no EVPN routes without the local VNI, got:", evpnMgr.vteps,
+			evpnMgr.macs)
+	}
+
+	locRib.SetEVPNVni(200, true)
+	if len(evpnMgr.vteps) != 0 || len(evpnMgr.macs) != 0 {
+		t.Fatal("LocRib:SetEVPNVni - Expected no EVPN routes for VNI 200, got:", evpnMgr.vteps, evpnMgr.macs)
+	}
+
+	locRib.SetEVPNVni(100, true)
+	if len(evpnMgr.vteps) != 1 || evpnMgr.vteps[0].Vni != 100 || evpnMgr.vteps[0].VtepIP != vtepIP.String() {
+		t.Fatal("LocRib:ProcessUpdate - Expected remote vtep 100 via", vtepIP, "got:", evpnMgr.vteps)
+	}
+	if len(evpnMgr.macs) != 1 || evpnMgr.macs[0].Vni != 100 || evpnMgr.macs[0].MAC != mac.String() ||
+		evpnMgr.macs[0].IP != "20.1.1.10" || evpnMgr.macs[0].VtepIP != vtepIP.String() {
+		t.Fatal("LocRib:ProcessUpdate - Expected remote mac", mac, "via", vtepIP, "got:", evpnMgr.macs)
+	}
+
+	updated = make(map[uint32]map[*Path][]*Destination)
+	withdrawn = make([]*Destination, 0)
+	updatedAddPaths = make([]*Destination, 0)
+	path = NewPath(locRib, nConf, pathAttrs, nil, RouteTypeEGP)
+	updated, withdrawn, updatedAddPaths, _ = locRib.ProcessUpdate(nConf, path, nil, nlri[1:], protoFamily, 0,
+		updated, withdrawn, updatedAddPaths)
+	if len(withdrawn) != 1 {
+		t.Fatal("LocRib:ProcessUpdate - Expected 1 withdrawn EVPN destination, withdrawn=", withdrawn)
+	}
+	if len(evpnMgr.deletedMacs) != 1 || evpnMgr.deletedMacs[0].MAC != mac.String() {
+		t.Fatal("LocRib:ProcessUpdate - Expected remote mac", mac, "to be removed, got:", evpnMgr.deletedMacs)
+	}
+}
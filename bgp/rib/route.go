@@ -93,6 +93,23 @@ func (r *Route) SetBestPath() {
 
 func (r *Route) ResetBestPath() {
 	r.PathInfo.BestPath = false
+	r.PathInfo.BestPathReason = ""
+}
+
+func (r *Route) SetBestPathReason(reason string) {
+	r.PathInfo.BestPathReason = reason
+}
+
+func (r *Route) SetDampeningState(info *DampeningInfo) {
+	if info == nil {
+		r.PathInfo.Suppressed = false
+		r.PathInfo.DampeningPenalty = 0
+		r.PathInfo.FlapCount = 0
+		return
+	}
+	r.PathInfo.Suppressed = info.Suppressed
+	r.PathInfo.DampeningPenalty = int32(info.Penalty)
+	r.PathInfo.FlapCount = int32(info.FlapCount)
 }
 
 func (r *Route) SetMultiPath() {
@@ -103,6 +120,14 @@ func (r *Route) ResetMultiPath() {
 	r.PathInfo.MultiPath = false
 }
 
+func (r *Route) SetBackupPath() {
+	r.PathInfo.BackupPath = true
+}
+
+func (r *Route) ResetBackupPath() {
+	r.PathInfo.BackupPath = false
+}
+
 func (r *Route) SetAdditionalPath() {
 	r.PathInfo.AdditionalPath = true
 }
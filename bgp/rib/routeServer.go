@@ -0,0 +1,338 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// routeServer.go
+package rib
+
+import (
+	"fmt"
+	"l3/bgp/baseobjects"
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	"l3/bgp/rpki"
+	"models/objects"
+	"utils/logging"
+)
+
+// RouteServerAcceptFunc returns true if the path received from a route server client can be added to the view of
+// the client
+type RouteServerAcceptFunc func(client string, protoFamily uint32, nlri packet.NLRI, path *Path) bool
+
+// viewRouteMgr resolves the next hops for the route server views. The routes selected in a view are only advertised
+// to the route server client and are not installed in RIBd.
+type viewRouteMgr struct {
+	config.RouteMgrIntf
+}
+
+func (v *viewRouteMgr) CreateRoute(*config.RouteConfig)                {}
+func (v *viewRouteMgr) DeleteRoute(*config.RouteConfig)                {}
+func (v *viewRouteMgr) UpdateRoute(cfg *config.RouteConfig, op string) {}
+func (v *viewRouteMgr) CreateNextHopGroup(*config.NextHopGroupConfig)  {}
+func (v *viewRouteMgr) DeleteNextHopGroup(*config.NextHopGroupConfig)  {}
+
+// viewStateDB keeps the routes of the route server views out of the state DB
+type viewStateDB struct{}
+
+func (v *viewStateDB) Init() error                                  { return nil }
+func (v *viewStateDB) AddObject(obj objects.ConfigObj) error        { return nil }
+func (v *viewStateDB) DeleteObject(obj objects.ConfigObj) error     { return nil }
+func (v *viewStateDB) UpdateObject(obj objects.ConfigObj) error     { return nil }
+func (v *viewStateDB) DeleteAllObjects(obj objects.ConfigObj) error { return nil }
+
+type routeServerRoute struct {
+	nlri  packet.NLRI
+	path  *Path
+	stale bool
+}
+
+// RouteServer keeps a Loc-RIB view per route server client (RFC 7947). The routes received from a client are
+// processed into the views of all the other clients that accept them, so that the best path selection in a view
+// only considers the paths the client can receive and the export policy of one client doesn't affect the others.
+type RouteServer struct {
+	logger     *logging.Writer
+	gConf      *config.GlobalConfig
+	routeMgr   config.RouteMgrIntf
+	stateDBMgr *viewStateDB
+	roaTable   *rpki.ROATable
+	accept     RouteServerAcceptFunc
+	views      map[string]*LocRib
+	routes     map[string]map[uint32]map[string]*routeServerRoute
+}
+
+func NewRouteServer(logger *logging.Writer, rMgr config.RouteMgrIntf, gConf *config.GlobalConfig) *RouteServer {
+	return &RouteServer{
+		logger:     logger,
+		gConf:      gConf,
+		routeMgr:   &viewRouteMgr{rMgr},
+		stateDBMgr: &viewStateDB{},
+		views:      make(map[string]*LocRib),
+		routes:     make(map[string]map[uint32]map[string]*routeServerRoute),
+	}
+}
+
+func getRouteServerKey(nlri packet.NLRI) string {
+	return fmt.Sprintf("%s:%d", nlri.GetCIDR(), nlri.GetPathId())
+}
+
+func (r *RouteServer) SetROATable(table *rpki.ROATable) {
+	r.roaTable = table
+	for _, view := range r.views {
+		view.SetROATable(table)
+	}
+}
+
+func (r *RouteServer) SetAcceptFunc(accept RouteServerAcceptFunc) {
+	r.accept = accept
+}
+
+func (r *RouteServer) AddClient(client string) *LocRib {
+	if view, ok := r.views[client]; ok {
+		return view
+	}
+
+	r.logger.Infof("RouteServer - add view for client %s", client)
+	view := NewLocRib(r.logger, r.routeMgr, r.stateDBMgr, r.gConf)
+	view.SetROATable(r.roaTable)
+	r.views[client] = view
+	return view
+}
+
+// RemoveClient removes the view of the client. The routes received from the client must be removed from the other
+// views before the client is removed.
+func (r *RouteServer) RemoveClient(client string) {
+	r.logger.Infof("RouteServer - remove view for client %s", client)
+	delete(r.views, client)
+	delete(r.routes, client)
+}
+
+func (r *RouteServer) GetView(client string) (*LocRib, bool) {
+	view, ok := r.views[client]
+	return view, ok
+}
+
+func (r *RouteServer) GetViews() map[string]*LocRib {
+	return r.views
+}
+
+func (r *RouteServer) getClientRoutes(client string, protoFamily uint32) map[string]*routeServerRoute {
+	if _, ok := r.routes[client]; !ok {
+		r.routes[client] = make(map[uint32]map[string]*routeServerRoute)
+	}
+	if _, ok := r.routes[client][protoFamily]; !ok {
+		r.routes[client][protoFamily] = make(map[string]*routeServerRoute)
+	}
+	return r.routes[client][protoFamily]
+}
+
+func (r *RouteServer) filterNLRIs(client string, protoFamily uint32, nlris []packet.NLRI, path *Path) (
+	[]packet.NLRI, []packet.NLRI) {
+	accepted := make([]packet.NLRI, 0, len(nlris))
+	rejected := make([]packet.NLRI, 0)
+	for _, nlri := range nlris {
+		if r.accept == nil || r.accept(client, protoFamily, nlri, path) {
+			accepted = append(accepted, nlri)
+		} else {
+			rejected = append(rejected, nlri)
+		}
+	}
+	return accepted, rejected
+}
+
+func mergeUpdates(updated map[uint32]map[*Path][]*Destination, withdrawn, updatedAddPaths []*Destination,
+	viewUpdated map[uint32]map[*Path][]*Destination, viewWithdrawn, viewUpdatedAddPaths []*Destination) (
+	map[uint32]map[*Path][]*Destination, []*Destination, []*Destination) {
+	for protoFamily, pathDestMap := range viewUpdated {
+		if _, ok := updated[protoFamily]; !ok {
+			updated[protoFamily] = make(map[*Path][]*Destination)
+		}
+		for path, destinations := range pathDestMap {
+			updated[protoFamily][path] = append(updated[protoFamily][path], destinations...)
+		}
+	}
+	return updated, append(withdrawn, viewWithdrawn...), append(updatedAddPaths, viewUpdatedAddPaths...)
+}
+
+// ProcessUpdate stores the routes received from the client and processes them into the views of the other clients.
+// Routes rejected by the export policy of a client are removed from the client's view.
+func (r *RouteServer) ProcessUpdate(neighborConf *base.NeighborConf, path *Path, add, rem []packet.NLRI,
+	protoFamily uint32, addPathCount int, updated map[uint32]map[*Path][]*Destination, withdrawn []*Destination,
+	updatedAddPaths []*Destination) (map[uint32]map[*Path][]*Destination, []*Destination, []*Destination, bool) {
+	src := neighborConf.Neighbor.NeighborAddress.String()
+	addedAllPrefixes := true
+	viewPath := path.Clone()
+	viewPath.SetLeaked()
+
+	routes := r.getClientRoutes(src, protoFamily)
+	for _, nlri := range rem {
+		key := getRouteServerKey(nlri)
+		if _, ok := routes[key]; ok && !isIpInList(add, nlri) {
+			delete(routes, key)
+			neighborConf.DecrPrefixCount()
+		}
+	}
+
+	added := make([]packet.NLRI, 0, len(add))
+	for _, nlri := range add {
+		key := getRouteServerKey(nlri)
+		if _, ok := routes[key]; !ok {
+			if !neighborConf.CanAcceptNewPrefix() {
+				r.logger.Infof("Max prefixes limit reached for route server client %s, can't process %s", src,
+					nlri.GetCIDR())
+				addedAllPrefixes = false
+				continue
+			}
+			neighborConf.IncrPrefixCount()
+		}
+		routes[key] = &routeServerRoute{nlri: nlri, path: viewPath}
+		added = append(added, nlri)
+	}
+
+	for client, view := range r.views {
+		if client == src {
+			continue
+		}
+
+		accepted, rejected := r.filterNLRIs(client, protoFamily, added, viewPath)
+		updated, withdrawn, updatedAddPaths, _ = view.TestNHAndProcessRoutes(src, accepted,
+			append(rejected, rem...), viewPath, viewPath.Clone(), addPathCount, protoFamily, updated, withdrawn,
+			updatedAddPaths)
+	}
+	return updated, withdrawn, updatedAddPaths, addedAllPrefixes
+}
+
+func (r *RouteServer) ProcessFilteredRoutes(neighborConf *base.NeighborConf,
+	filteredRoutes map[*Path]map[uint32]*FilteredRoutes, addPathCount int) (map[uint32]map[*Path][]*Destination,
+	[]*Destination, []*Destination, bool) {
+	updated := make(map[uint32]map[*Path][]*Destination)
+	withdrawn := make([]*Destination, 0)
+	updatedAddPaths := make([]*Destination, 0)
+	addedAllPrefixes := true
+
+	for path, pfNLRIs := range filteredRoutes {
+		for protoFamily, routes := range pfNLRIs {
+			updated, withdrawn, updatedAddPaths, addedAllPrefixes = r.ProcessUpdate(neighborConf, path, routes.Add,
+				routes.Remove, protoFamily, addPathCount, updated, withdrawn, updatedAddPaths)
+			if !addedAllPrefixes {
+				return updated, withdrawn, updatedAddPaths, addedAllPrefixes
+			}
+		}
+	}
+	return updated, withdrawn, updatedAddPaths, addedAllPrefixes
+}
+
+// SyncClient applies the export policy of the client to the routes from all the other clients again and updates
+// the view of the client
+func (r *RouteServer) SyncClient(client string, addPathCount int) (map[uint32]map[*Path][]*Destination,
+	[]*Destination, []*Destination) {
+	updated := make(map[uint32]map[*Path][]*Destination)
+	withdrawn := make([]*Destination, 0)
+	updatedAddPaths := make([]*Destination, 0)
+	view, ok := r.views[client]
+	if !ok {
+		return updated, withdrawn, updatedAddPaths
+	}
+
+	for src, pfRoutes := range r.routes {
+		if src == client {
+			continue
+		}
+		for protoFamily, routes := range pfRoutes {
+			for _, route := range routes {
+				nlris := []packet.NLRI{route.nlri}
+				accepted, rejected := r.filterNLRIs(client, protoFamily, nlris, route.path)
+				updated, withdrawn, updatedAddPaths, _ = view.TestNHAndProcessRoutes(src, accepted, rejected,
+					route.path, route.path.Clone(), addPathCount, protoFamily, updated, withdrawn, updatedAddPaths)
+			}
+		}
+	}
+	return updated, withdrawn, updatedAddPaths
+}
+
+func (r *RouteServer) MarkStaleUpdatesFromNeighbor(peerIP string, protoFamily uint32) int {
+	for client, view := range r.views {
+		if client != peerIP {
+			view.MarkStaleUpdatesFromNeighbor(peerIP, protoFamily)
+		}
+	}
+
+	routes := r.routes[peerIP][protoFamily]
+	for _, route := range routes {
+		route.stale = true
+	}
+	return len(routes)
+}
+
+func (r *RouteServer) RemoveStaleUpdatesFromNeighbor(peerIP string, neighborConf *base.NeighborConf,
+	protoFamily uint32, addPathCount int) (map[uint32]map[*Path][]*Destination, []*Destination, []*Destination) {
+	updated := make(map[uint32]map[*Path][]*Destination)
+	withdrawn := make([]*Destination, 0)
+	updatedAddPaths := make([]*Destination, 0)
+
+	for key, route := range r.routes[peerIP][protoFamily] {
+		if route.stale {
+			delete(r.routes[peerIP][protoFamily], key)
+			neighborConf.DecrPrefixCount()
+		}
+	}
+
+	for client, view := range r.views {
+		if client == peerIP {
+			continue
+		}
+		viewUpdated, viewWithdrawn, viewUpdatedAddPaths := view.RemoveStaleUpdatesFromNeighbor(peerIP, neighborConf,
+			protoFamily, addPathCount)
+		updated, withdrawn, updatedAddPaths = mergeUpdates(updated, withdrawn, updatedAddPaths, viewUpdated,
+			viewWithdrawn, viewUpdatedAddPaths)
+	}
+	return updated, withdrawn, updatedAddPaths
+}
+
+func (r *RouteServer) RemoveUpdatesFromNeighbor(peerIP string, neighborConf *base.NeighborConf, addPathCount int) (
+	map[uint32]map[*Path][]*Destination, []*Destination, []*Destination) {
+	updated := make(map[uint32]map[*Path][]*Destination)
+	withdrawn := make([]*Destination, 0)
+	updatedAddPaths := make([]*Destination, 0)
+
+	delete(r.routes, peerIP)
+	for client, view := range r.views {
+		if client == peerIP {
+			continue
+		}
+		viewUpdated, viewWithdrawn, viewUpdatedAddPaths := view.RemoveUpdatesFromNeighbor(peerIP, nil,
+			addPathCount)
+		updated, withdrawn, updatedAddPaths = mergeUpdates(updated, withdrawn, updatedAddPaths, viewUpdated,
+			viewWithdrawn, viewUpdatedAddPaths)
+	}
+
+	if neighborConf != nil {
+		neighborConf.SetPrefixCount(0)
+	}
+	return updated, withdrawn, updatedAddPaths
+}
+
+func (r *RouteServer) RemoveUpdatesFromAllNeighbors(addPathCount int) {
+	r.routes = make(map[string]map[uint32]map[string]*routeServerRoute)
+	for _, view := range r.views {
+		view.RemoveUpdatesFromAllNeighbors(addPathCount)
+	}
+}
@@ -44,6 +44,8 @@ type AdjRIBRoute struct {
 	PolicyList       []string
 	PolicyHitCounter int
 	Accept           bool
+	ActionList       []string
+	FilterPath       *Path // path the Adj-RIB filter was evaluated for
 }
 
 func NewAdjRIBRoute(neighbor net.IP, protoFamily uint32, nlri packet.NLRI) *AdjRIBRoute {
@@ -58,6 +60,17 @@ func NewAdjRIBRoute(neighbor net.IP, protoFamily uint32, nlri packet.NLRI) *AdjR
 	}
 }
 
+func (a *AdjRIBRoute) Clone() *AdjRIBRoute {
+	x := *a
+	x.PathMap = make(map[uint32]*Path, len(a.PathMap))
+	for pathId, path := range a.PathMap {
+		x.PathMap[pathId] = path
+	}
+	x.PolicyList = append([]string(nil), a.PolicyList...)
+	x.ActionList = append([]string(nil), a.ActionList...)
+	return &x
+}
+
 func (a *AdjRIBRoute) AddPath(pathId uint32, path *Path) {
 	a.PathMap[pathId] = path
 }
@@ -0,0 +1,184 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// vpnRoute.go
+package rib
+
+import (
+	"bgpd"
+	bgputils "l3/bgp/utils"
+	"models/objects"
+	"strconv"
+)
+
+type VPNRoute struct {
+	*bgpd.BGPVPNRouteState
+}
+
+func NewVPNRoute(route string, rd string) *VPNRoute {
+	return &VPNRoute{
+		&bgpd.BGPVPNRouteState{
+			Route: route,
+			RD:    rd,
+		},
+	}
+}
+
+func (i *VPNRoute) SetNetwork(route string) {
+	i.Route = route
+}
+
+func (i *VPNRoute) GetNetwork() string {
+	return i.Route
+}
+
+func (i *VPNRoute) SetCIDRLen(cidrLen int16) {
+}
+
+func (i *VPNRoute) GetCIDRLen() int16 {
+	return 0
+}
+
+func (i *VPNRoute) GetPaths() []*bgpd.PathInfo {
+	return i.Paths
+}
+
+func (i *VPNRoute) AppendPath(path *bgpd.PathInfo) {
+	i.Paths = append(i.Paths, path)
+}
+
+func (i *VPNRoute) SetPath(path *bgpd.PathInfo, idx int) {
+	i.Paths[idx] = path
+}
+
+func (i *VPNRoute) GetPath(idx int) *bgpd.PathInfo {
+	return i.Paths[idx]
+}
+
+func (i *VPNRoute) GetLastPath() *bgpd.PathInfo {
+	return i.Paths[len(i.Paths)-1]
+}
+
+func (i *VPNRoute) RemovePathAndSetLast(idx int) {
+	if idx < len(i.Paths) {
+		i.Paths[idx] = i.Paths[len(i.Paths)-1]
+		i.Paths[len(i.Paths)-1] = nil
+		i.Paths = i.Paths[:len(i.Paths)-1]
+	}
+}
+
+func (i *VPNRoute) GetModelObject() objects.ConfigObj {
+	var dbObj objects.BGPVPNRouteState
+	objects.ConvertThriftTobgpdBGPVPNRouteStateObj(i.BGPVPNRouteState, &dbObj)
+	for idx1 := 0; idx1 < len(dbObj.Paths); idx1++ {
+		for idx2 := 0; idx2 < len(dbObj.Paths[idx1].Path); idx2++ {
+			asdoPlain, _ := strconv.Atoi(dbObj.Paths[idx1].Path[idx2])
+			asdotPath, _ := bgputils.GetAsDot(asdoPlain)
+			dbObj.Paths[idx1].Path[idx2] = asdotPath
+		}
+	}
+	return &dbObj
+}
+
+func (i *VPNRoute) GetThriftObject() interface{} {
+	return i.BGPVPNRouteState
+}
+
+type VRFRoute struct {
+	*bgpd.BGPVrfRouteState
+}
+
+func NewVRFRoute(vrf string, network string, cidrLen int16) *VRFRoute {
+	return &VRFRoute{
+		&bgpd.BGPVrfRouteState{
+			Vrf:     vrf,
+			Network: network,
+			CIDRLen: cidrLen,
+		},
+	}
+}
+
+func (i *VRFRoute) SetNetwork(network string) {
+	i.Network = network
+}
+
+func (i *VRFRoute) GetNetwork() string {
+	return i.Network
+}
+
+func (i *VRFRoute) SetCIDRLen(cidrLen int16) {
+	i.CIDRLen = cidrLen
+}
+
+func (i *VRFRoute) GetCIDRLen() int16 {
+	return i.CIDRLen
+}
+
+func (i *VRFRoute) GetPaths() []*bgpd.PathInfo {
+	return i.Paths
+}
+
+func (i *VRFRoute) AppendPath(pathInfo *bgpd.PathInfo) {
+	i.Paths = append(i.Paths, pathInfo)
+}
+
+func (i *VRFRoute) SetPath(pathInfo *bgpd.PathInfo, idx int) {
+	i.Paths[idx] = pathInfo
+}
+
+func (i *VRFRoute) GetPath(idx int) *bgpd.PathInfo {
+	return i.Paths[idx]
+}
+
+func (i *VRFRoute) GetLastPath() *bgpd.PathInfo {
+	return i.Paths[len(i.Paths)-1]
+}
+
+func (i *VRFRoute) RemovePathAndSetLast(idx int) {
+	if idx < len(i.Paths) {
+		i.Paths[idx] = i.Paths[len(i.Paths)-1]
+		i.Paths[len(i.Paths)-1] = nil
+		i.Paths = i.Paths[:len(i.Paths)-1]
+	}
+}
+
+func (i *VRFRoute) GetNumPaths() int {
+	return len(i.Paths)
+}
+
+func (i *VRFRoute) GetModelObject() objects.ConfigObj {
+	var dbObj objects.BGPVrfRouteState
+	objects.ConvertThriftTobgpdBGPVrfRouteStateObj(i.BGPVrfRouteState, &dbObj)
+	for idx1 := 0; idx1 < len(dbObj.Paths); idx1++ {
+		for idx2 := 0; idx2 < len(dbObj.Paths[idx1].Path); idx2++ {
+			asdoPlain, _ := strconv.Atoi(dbObj.Paths[idx1].Path[idx2])
+			asdotPath, _ := bgputils.GetAsDot(asdoPlain)
+			dbObj.Paths[idx1].Path[idx2] = asdotPath
+		}
+	}
+	return &dbObj
+}
+
+func (i *VRFRoute) GetThriftObject() interface{} {
+	return i.BGPVrfRouteState
+}
@@ -31,6 +31,7 @@ import (
 	"fmt"
 	"l3/bgp/config"
 	"l3/bgp/fsm"
+	"l3/bgp/keychain"
 	"l3/bgp/packet"
 	bgppolicy "l3/bgp/policy"
 	"l3/bgp/server"
@@ -39,6 +40,7 @@ import (
 	"models/objects"
 	"net"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 	"utils/dbutils"
@@ -83,14 +85,19 @@ func (h *BGPHandler) convertModelToBGPGlobalConfig(obj objects.BGPGlobal) (gConf
 
 	gConf = config.GlobalConfig{
 		GlobalBase: config.GlobalBase{
-			Vrf:                 obj.Vrf,
-			AS:                  uint32(asnum),
-			RouterId:            h.convertStrIPToNetIP(obj.RouterId),
-			Disabled:            obj.Disabled,
-			UseMultiplePaths:    obj.UseMultiplePaths,
-			EBGPMaxPaths:        obj.EBGPMaxPaths,
-			EBGPAllowMultipleAS: obj.EBGPAllowMultipleAS,
-			IBGPMaxPaths:        obj.IBGPMaxPaths,
+			Vrf:                     obj.Vrf,
+			AS:                      uint32(asnum),
+			RouterId:                h.convertStrIPToNetIP(obj.RouterId),
+			Disabled:                obj.Disabled,
+			UseMultiplePaths:        obj.UseMultiplePaths,
+			EBGPMaxPaths:            obj.EBGPMaxPaths,
+			EBGPAllowMultipleAS:     obj.EBGPAllowMultipleAS,
+			IBGPMaxPaths:            obj.IBGPMaxPaths,
+			GracefulRestart:         obj.GracefulRestart,
+			RestartTime:             uint32(obj.RestartTime),
+			StalePathTime:           uint32(obj.StalePathTime),
+			PreserveForwardingState: obj.PreserveForwardingState,
+			InstallBackupPath:       obj.InstallBackupPath,
 		},
 	}
 
@@ -102,6 +109,38 @@ func (h *BGPHandler) convertModelToBGPGlobalConfig(obj objects.BGPGlobal) (gConf
 		}
 	}
 
+	if obj.BMPStations != nil {
+		gConf.BMPStations = make([]config.BMPStation, 0)
+		for i := 0; i < len(obj.BMPStations); i++ {
+			station := config.BMPStation{obj.BMPStations[i].Address, uint16(obj.BMPStations[i].Port)}
+			gConf.BMPStations = append(gConf.BMPStations, station)
+		}
+	}
+
+	gConf.MRT = config.MRTConfig{obj.MRTDumpDir, uint32(obj.MRTDumpInterval), obj.MRTLogUpdates,
+		uint32(obj.MRTRotateInterval)}
+
+	gConf.RPKI.PreferValid = obj.RPKIPreferValid
+	gConf.BestPath = config.BestPathConfig{
+		CompareMED:           obj.BestPathCompareMED,
+		AlwaysCompareMED:     obj.BestPathAlwaysCompareMED,
+		DeterministicMED:     obj.BestPathDeterministicMED,
+		MEDMissingAsWorst:    obj.BestPathMEDMissingAsWorst,
+		ASPathIgnore:         obj.BestPathASPathIgnore,
+		MultipathSameASPath:  obj.BestPathMultipathSameASPath,
+		ASPathMultipathRelax: obj.BestPathASPathMultipathRelax,
+		PreferOldestEBGP:     obj.BestPathPreferOldestEBGP,
+		CompareRouterId:      obj.BestPathCompareRouterId,
+	}
+	if obj.RPKICacheServers != nil {
+		gConf.RPKI.CacheServers = make([]config.RPKICacheServer, 0)
+		for i := 0; i < len(obj.RPKICacheServers); i++ {
+			cache := config.RPKICacheServer{obj.RPKICacheServers[i].Address,
+				uint16(obj.RPKICacheServers[i].Port)}
+			gConf.RPKI.CacheServers = append(gConf.RPKI.CacheServers, cache)
+		}
+	}
+
 	if gConf.RouterId == nil {
 		h.logger.Err("convertModelToBGPGlobalConfig - IP is not valid:", obj.RouterId)
 		err = config.IPError{obj.RouterId}
@@ -132,6 +171,77 @@ func (h *BGPHandler) handleGlobalConfig() error {
 	return nil
 }
 
+func (h *BGPHandler) convertModelToAddPathsFamilies(families []objects.BGPAddPathsFamily) []config.AddPathsConfig {
+	if families == nil {
+		return nil
+	}
+
+	addPathsFamilies := make([]config.AddPathsConfig, 0, len(families))
+	for i := 0; i < len(families); i++ {
+		addPathsFamilies = append(addPathsFamilies, config.AddPathsConfig{families[i].AfiSafiName,
+			families[i].Rx, uint8(families[i].MaxTx), families[i].TxMode})
+	}
+	return addPathsFamilies
+}
+
+func (h *BGPHandler) convertThriftToAddPathsFamilies(families []*bgpd.BGPAddPathsFamily) []config.AddPathsConfig {
+	if families == nil {
+		return nil
+	}
+
+	addPathsFamilies := make([]config.AddPathsConfig, 0, len(families))
+	for i := 0; i < len(families); i++ {
+		addPathsFamilies = append(addPathsFamilies, config.AddPathsConfig{families[i].AfiSafiName,
+			families[i].Rx, uint8(families[i].MaxTx), families[i].TxMode})
+	}
+	return addPathsFamilies
+}
+
+func (h *BGPHandler) convertAddPathsFamiliesToThrift(families []config.AddPathsConfig) []*bgpd.BGPAddPathsFamily {
+	addPathsFamilies := make([]*bgpd.BGPAddPathsFamily, 0, len(families))
+	for _, family := range families {
+		addPathsFamily := bgpd.NewBGPAddPathsFamily()
+		addPathsFamily.AfiSafiName = family.AfiSafiName
+		addPathsFamily.Rx = family.Rx
+		addPathsFamily.MaxTx = int16(family.MaxTx)
+		addPathsFamily.TxMode = family.TxMode
+		addPathsFamilies = append(addPathsFamilies, addPathsFamily)
+	}
+	return addPathsFamilies
+}
+
+func (h *BGPHandler) isValidAddPathsTxMode(txMode string) bool {
+	switch txMode {
+	case "", config.AddPathsTxModeBestN, config.AddPathsTxModeAll, config.AddPathsTxModeECMP,
+		config.AddPathsTxModeBestExternal:
+		return true
+	}
+	return false
+}
+
+func (h *BGPHandler) validateAddPathsConfig(conf config.BaseConfig) error {
+	if !h.isValidAddPathsTxMode(conf.AddPathsTxMode) {
+		return errors.New(fmt.Sprintf("Add paths transmit mode %s not valid", conf.AddPathsTxMode))
+	}
+
+	families := make(map[string]bool)
+	for _, family := range conf.AddPathsFamilies {
+		if _, ok := packet.ProtocolFamilyMap[family.AfiSafiName]; !ok {
+			return errors.New(fmt.Sprintf("Add paths family %s not valid", family.AfiSafiName))
+		}
+		if families[family.AfiSafiName] {
+			return errors.New(fmt.Sprintf("Add paths family %s configured more than once", family.AfiSafiName))
+		}
+		families[family.AfiSafiName] = true
+
+		if !h.isValidAddPathsTxMode(family.TxMode) {
+			return errors.New(fmt.Sprintf("Add paths transmit mode %s for family %s not valid", family.TxMode,
+				family.AfiSafiName))
+		}
+	}
+	return nil
+}
+
 func (h *BGPHandler) convertModelToBGPv4PeerGroup(obj objects.BGPv4PeerGroup) (group config.PeerGroupConfig,
 	err error) {
 	peerAS, err := bgputils.GetAsNum(obj.PeerAS)
@@ -162,12 +272,26 @@ func (h *BGPHandler) convertModelToBGPv4PeerGroup(obj objects.BGPv4PeerGroup) (g
 			KeepaliveTime:           uint32(obj.KeepaliveTime),
 			AddPathsRx:              obj.AddPathsRx,
 			AddPathsMaxTx:           uint8(obj.AddPathsMaxTx),
+			AddPathsTxMode:          obj.AddPathsTxMode,
+			AddPathsFamilies:        h.convertModelToAddPathsFamilies(obj.AddPathsFamilies),
 			MaxPrefixes:             uint32(obj.MaxPrefixes),
 			MaxPrefixesThresholdPct: uint8(obj.MaxPrefixesThresholdPct),
 			MaxPrefixesDisconnect:   obj.MaxPrefixesDisconnect,
 			MaxPrefixesRestartTimer: uint8(obj.MaxPrefixesRestartTimer),
 			AdjRIBInFilter:          obj.AdjRIBInFilter,
 			AdjRIBOutFilter:         obj.AdjRIBOutFilter,
+			DefaultOriginate:        obj.DefaultOriginate,
+			DefaultOriginateMap:     obj.DefaultOriginateMap,
+			AdvertiseMap:            obj.AdvertiseMap,
+			ExistMap:                obj.ExistMap,
+			NonExistMap:             obj.NonExistMap,
+			ORFPrefixReceive:        obj.ORFPrefixReceive,
+			Dampening:               obj.Dampening,
+			KeyChain:                obj.KeyChain,
+			TCPAO:                   obj.TCPAO,
+			TTLSecurity:             obj.TTLSecurity,
+			TTLSecurityHops:         uint8(obj.TTLSecurityHops),
+			RouteServerClient:       obj.RouteServerClient,
 		},
 		Name: obj.Name,
 	}
@@ -226,12 +350,27 @@ func (h *BGPHandler) convertModelToBGPv6PeerGroup(obj objects.BGPv6PeerGroup) (g
 			KeepaliveTime:           uint32(obj.KeepaliveTime),
 			AddPathsRx:              obj.AddPathsRx,
 			AddPathsMaxTx:           uint8(obj.AddPathsMaxTx),
+			AddPathsTxMode:          obj.AddPathsTxMode,
+			AddPathsFamilies:        h.convertModelToAddPathsFamilies(obj.AddPathsFamilies),
 			MaxPrefixes:             uint32(obj.MaxPrefixes),
 			MaxPrefixesThresholdPct: uint8(obj.MaxPrefixesThresholdPct),
 			MaxPrefixesDisconnect:   obj.MaxPrefixesDisconnect,
 			MaxPrefixesRestartTimer: uint8(obj.MaxPrefixesRestartTimer),
 			AdjRIBInFilter:          obj.AdjRIBInFilter,
 			AdjRIBOutFilter:         obj.AdjRIBOutFilter,
+			ExtendedNextHop:         obj.ExtendedNextHop,
+			DefaultOriginate:        obj.DefaultOriginate,
+			DefaultOriginateMap:     obj.DefaultOriginateMap,
+			AdvertiseMap:            obj.AdvertiseMap,
+			ExistMap:                obj.ExistMap,
+			NonExistMap:             obj.NonExistMap,
+			ORFPrefixReceive:        obj.ORFPrefixReceive,
+			Dampening:               obj.Dampening,
+			KeyChain:                obj.KeyChain,
+			TCPAO:                   obj.TCPAO,
+			TTLSecurity:             obj.TTLSecurity,
+			TTLSecurityHops:         uint8(obj.TTLSecurityHops),
+			RouteServerClient:       obj.RouteServerClient,
 		},
 		Name: obj.Name,
 	}
@@ -303,17 +442,32 @@ func (h *BGPHandler) convertModelToBGPv4Neighbor(obj objects.BGPv4Neighbor) (nei
 			BfdSessionParam:         obj.BfdSessionParam,
 			AddPathsRx:              obj.AddPathsRx,
 			AddPathsMaxTx:           uint8(obj.AddPathsMaxTx),
+			AddPathsTxMode:          obj.AddPathsTxMode,
+			AddPathsFamilies:        h.convertModelToAddPathsFamilies(obj.AddPathsFamilies),
 			MaxPrefixes:             uint32(obj.MaxPrefixes),
 			MaxPrefixesThresholdPct: uint8(obj.MaxPrefixesThresholdPct),
 			MaxPrefixesDisconnect:   obj.MaxPrefixesDisconnect,
 			MaxPrefixesRestartTimer: uint8(obj.MaxPrefixesRestartTimer),
 			AdjRIBInFilter:          obj.AdjRIBInFilter,
 			AdjRIBOutFilter:         obj.AdjRIBOutFilter,
+			DefaultOriginate:        obj.DefaultOriginate,
+			DefaultOriginateMap:     obj.DefaultOriginateMap,
+			AdvertiseMap:            obj.AdvertiseMap,
+			ExistMap:                obj.ExistMap,
+			NonExistMap:             obj.NonExistMap,
+			ORFPrefixReceive:        obj.ORFPrefixReceive,
+			Dampening:               obj.Dampening,
+			KeyChain:                obj.KeyChain,
+			TCPAO:                   obj.TCPAO,
+			TTLSecurity:             obj.TTLSecurity,
+			TTLSecurityHops:         uint8(obj.TTLSecurityHops),
+			RouteServerClient:       obj.RouteServerClient,
 		},
 		NeighborAddress: ip,
 		IfIndex:         ifIndex,
 		PeerGroup:       obj.PeerGroup,
 		Disabled:        obj.Disabled,
+		Vrf:             obj.Vrf,
 	}
 	return neighbor, err
 }
@@ -383,18 +537,34 @@ func (h *BGPHandler) convertModelToBGPv6Neighbor(obj objects.BGPv6Neighbor) (nei
 			BfdSessionParam:         obj.BfdSessionParam,
 			AddPathsRx:              obj.AddPathsRx,
 			AddPathsMaxTx:           uint8(obj.AddPathsMaxTx),
+			AddPathsTxMode:          obj.AddPathsTxMode,
+			AddPathsFamilies:        h.convertModelToAddPathsFamilies(obj.AddPathsFamilies),
 			MaxPrefixes:             uint32(obj.MaxPrefixes),
 			MaxPrefixesThresholdPct: uint8(obj.MaxPrefixesThresholdPct),
 			MaxPrefixesDisconnect:   obj.MaxPrefixesDisconnect,
 			MaxPrefixesRestartTimer: uint8(obj.MaxPrefixesRestartTimer),
 			AdjRIBInFilter:          obj.AdjRIBInFilter,
 			AdjRIBOutFilter:         obj.AdjRIBOutFilter,
+			ExtendedNextHop:         obj.ExtendedNextHop,
+			DefaultOriginate:        obj.DefaultOriginate,
+			DefaultOriginateMap:     obj.DefaultOriginateMap,
+			AdvertiseMap:            obj.AdvertiseMap,
+			ExistMap:                obj.ExistMap,
+			NonExistMap:             obj.NonExistMap,
+			ORFPrefixReceive:        obj.ORFPrefixReceive,
+			Dampening:               obj.Dampening,
+			KeyChain:                obj.KeyChain,
+			TCPAO:                   obj.TCPAO,
+			TTLSecurity:             obj.TTLSecurity,
+			TTLSecurityHops:         uint8(obj.TTLSecurityHops),
+			RouteServerClient:       obj.RouteServerClient,
 		},
 		NeighborAddress: ip,
 		IfIndex:         ifIndex,
 		IfName:          ifName,
 		PeerGroup:       obj.PeerGroup,
 		Disabled:        obj.Disabled,
+		Vrf:             obj.Vrf,
 	}
 	return neighbor, err
 }
@@ -486,12 +656,128 @@ func (h *BGPHandler) handleBGPv6Aggregate() error {
 	return nil
 }
 
+func (h *BGPHandler) convertModelToBGPVrf(obj objects.BGPVrf) (config.VrfConfig, error) {
+	vrfConf := config.VrfConfig{
+		Name:     obj.Name,
+		RD:       obj.RD,
+		Label:    uint32(obj.Label),
+		ImportRT: obj.ImportRT,
+		ExportRT: obj.ExportRT,
+	}
+
+	return vrfConf, nil
+}
+
+func (h *BGPHandler) handleBGPVrf() error {
+	var obj objects.BGPVrf
+	objList, err := h.dbUtil.GetAllObjFromDb(obj)
+	if err != nil {
+		h.logger.Errf("GetAllObjFromDb failed for BGPVrf with error %s", err)
+		return err
+	}
+
+	for _, confObj := range objList {
+		obj = confObj.(objects.BGPVrf)
+
+		vrfConf, err := h.convertModelToBGPVrf(obj)
+		if err != nil {
+			h.logger.Err("handleBGPVrf - Failed to convert Model object BGPVrf, error:", err)
+			return err
+		}
+		h.server.AddVrfCh <- server.VrfUpdate{config.VrfConfig{}, vrfConf, make([]bool, 0)}
+	}
+	return nil
+}
+
+func (h *BGPHandler) convertModelToBGPListenRange(obj objects.BGPListenRange) (config.ListenRangeConfig, error) {
+	rangeConf := config.ListenRangeConfig{
+		Prefix:    obj.Prefix,
+		PeerGroup: obj.PeerGroup,
+		Limit:     uint32(obj.Limit),
+	}
+
+	ip, _, err := net.ParseCIDR(obj.Prefix)
+	if err != nil {
+		return rangeConf, err
+	}
+
+	if ip.To4() == nil {
+		rangeConf.PeerAddressType = config.PeerAddressV6
+	}
+	return rangeConf, nil
+}
+
+func (h *BGPHandler) handleBGPListenRange() error {
+	var obj objects.BGPListenRange
+	objList, err := h.dbUtil.GetAllObjFromDb(obj)
+	if err != nil {
+		h.logger.Errf("GetAllObjFromDb failed for BGPListenRange with error %s", err)
+		return err
+	}
+
+	for _, confObj := range objList {
+		obj = confObj.(objects.BGPListenRange)
+
+		rangeConf, err := h.convertModelToBGPListenRange(obj)
+		if err != nil {
+			h.logger.Err("handleBGPListenRange - Failed to convert Model object BGPListenRange, error:", err)
+			return err
+		}
+		h.server.AddListenRangeCh <- server.ListenRangeUpdate{config.ListenRangeConfig{}, rangeConf,
+			make([]bool, 0)}
+	}
+	return nil
+}
+
+func (h *BGPHandler) convertModelToBGPKeyChain(obj objects.BGPKeyChain) (config.KeyChainConfig, error) {
+	keyChainConf := config.KeyChainConfig{
+		Name: obj.Name,
+		Keys: make([]config.KeyChainKey, 0, len(obj.Keys)),
+	}
+
+	for i := 0; i < len(obj.Keys); i++ {
+		key, err := h.convertToKeyChainKey(obj.Keys[i].KeyId, obj.Keys[i].Secret, obj.Keys[i].Algorithm,
+			obj.Keys[i].SendLifetimeStart, obj.Keys[i].SendLifetimeEnd, obj.Keys[i].AcceptLifetimeStart,
+			obj.Keys[i].AcceptLifetimeEnd)
+		if err != nil {
+			return keyChainConf, err
+		}
+		keyChainConf.Keys = append(keyChainConf.Keys, key)
+	}
+	return keyChainConf, nil
+}
+
+func (h *BGPHandler) handleBGPKeyChain() error {
+	var obj objects.BGPKeyChain
+	objList, err := h.dbUtil.GetAllObjFromDb(obj)
+	if err != nil {
+		h.logger.Errf("GetAllObjFromDb failed for BGPKeyChain with error %s", err)
+		return err
+	}
+
+	for _, confObj := range objList {
+		obj = confObj.(objects.BGPKeyChain)
+
+		keyChainConf, err := h.convertModelToBGPKeyChain(obj)
+		if err != nil {
+			h.logger.Err("handleBGPKeyChain - Failed to convert Model object BGPKeyChain, error:", err)
+			return err
+		}
+		h.server.AddKeyChainCh <- keyChainConf
+	}
+	return nil
+}
+
 func (h *BGPHandler) ReadBGPConfigFromDB() error {
 	var err error
 	if err = h.handleGlobalConfig(); err != nil {
 		return err
 	}
 
+	if err = h.handleBGPVrf(); err != nil {
+		return err
+	}
+
 	if err = h.handleBGPv4Aggregate(); err != nil {
 		return err
 	}
@@ -500,6 +786,10 @@ func (h *BGPHandler) ReadBGPConfigFromDB() error {
 		return err
 	}
 
+	if err = h.handleBGPKeyChain(); err != nil {
+		return err
+	}
+
 	if err = h.handleV4PeerGroup(); err != nil {
 		return err
 	}
@@ -515,6 +805,10 @@ func (h *BGPHandler) ReadBGPConfigFromDB() error {
 		return err
 	}
 
+	if err = h.handleBGPListenRange(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -527,6 +821,15 @@ func (h *BGPHandler) convertStrIPToNetIP(ip string) net.IP {
 	return netIP
 }
 
+func (h *BGPHandler) convertProtoFamilyMapToList(afiSafiMap map[uint32]bool) []string {
+	afiSafis := make([]string, 0)
+	for protoFamily, _ := range afiSafiMap {
+		afiSafis = append(afiSafis, packet.GetProtocolFamilyStr(protoFamily))
+	}
+	sort.Strings(afiSafis)
+	return afiSafis
+}
+
 func (h *BGPHandler) validateBGPGlobal(bgpGlobal *bgpd.BGPGlobal) (gConf config.GlobalConfig, err error) {
 	if bgpGlobal == nil {
 		return gConf, err
@@ -552,14 +855,19 @@ func (h *BGPHandler) validateBGPGlobal(bgpGlobal *bgpd.BGPGlobal) (gConf config.
 
 	gConf = config.GlobalConfig{
 		GlobalBase: config.GlobalBase{
-			Vrf:                 bgpGlobal.Vrf,
-			AS:                  uint32(asNum),
-			RouterId:            ip,
-			Disabled:            bgpGlobal.Disabled,
-			UseMultiplePaths:    bgpGlobal.UseMultiplePaths,
-			EBGPMaxPaths:        uint32(bgpGlobal.EBGPMaxPaths),
-			EBGPAllowMultipleAS: bgpGlobal.EBGPAllowMultipleAS,
-			IBGPMaxPaths:        uint32(bgpGlobal.IBGPMaxPaths),
+			Vrf:                     bgpGlobal.Vrf,
+			AS:                      uint32(asNum),
+			RouterId:                ip,
+			Disabled:                bgpGlobal.Disabled,
+			UseMultiplePaths:        bgpGlobal.UseMultiplePaths,
+			EBGPMaxPaths:            uint32(bgpGlobal.EBGPMaxPaths),
+			EBGPAllowMultipleAS:     bgpGlobal.EBGPAllowMultipleAS,
+			IBGPMaxPaths:            uint32(bgpGlobal.IBGPMaxPaths),
+			GracefulRestart:         bgpGlobal.GracefulRestart,
+			RestartTime:             uint32(bgpGlobal.RestartTime),
+			StalePathTime:           uint32(bgpGlobal.StalePathTime),
+			PreserveForwardingState: bgpGlobal.PreserveForwardingState,
+			InstallBackupPath:       bgpGlobal.InstallBackupPath,
 		},
 	}
 
@@ -571,6 +879,38 @@ func (h *BGPHandler) validateBGPGlobal(bgpGlobal *bgpd.BGPGlobal) (gConf config.
 		}
 	}
 
+	if bgpGlobal.BMPStations != nil {
+		gConf.BMPStations = make([]config.BMPStation, 0)
+		for i := 0; i < len(bgpGlobal.BMPStations); i++ {
+			station := config.BMPStation{bgpGlobal.BMPStations[i].Address, uint16(bgpGlobal.BMPStations[i].Port)}
+			gConf.BMPStations = append(gConf.BMPStations, station)
+		}
+	}
+
+	gConf.MRT = config.MRTConfig{bgpGlobal.MRTDumpDir, uint32(bgpGlobal.MRTDumpInterval), bgpGlobal.MRTLogUpdates,
+		uint32(bgpGlobal.MRTRotateInterval)}
+
+	gConf.RPKI.PreferValid = bgpGlobal.RPKIPreferValid
+	gConf.BestPath = config.BestPathConfig{
+		CompareMED:           bgpGlobal.BestPathCompareMED,
+		AlwaysCompareMED:     bgpGlobal.BestPathAlwaysCompareMED,
+		DeterministicMED:     bgpGlobal.BestPathDeterministicMED,
+		MEDMissingAsWorst:    bgpGlobal.BestPathMEDMissingAsWorst,
+		ASPathIgnore:         bgpGlobal.BestPathASPathIgnore,
+		MultipathSameASPath:  bgpGlobal.BestPathMultipathSameASPath,
+		ASPathMultipathRelax: bgpGlobal.BestPathASPathMultipathRelax,
+		PreferOldestEBGP:     bgpGlobal.BestPathPreferOldestEBGP,
+		CompareRouterId:      bgpGlobal.BestPathCompareRouterId,
+	}
+	if bgpGlobal.RPKICacheServers != nil {
+		gConf.RPKI.CacheServers = make([]config.RPKICacheServer, 0)
+		for i := 0; i < len(bgpGlobal.RPKICacheServers); i++ {
+			cache := config.RPKICacheServer{bgpGlobal.RPKICacheServers[i].Address,
+				uint16(bgpGlobal.RPKICacheServers[i].Port)}
+			gConf.RPKI.CacheServers = append(gConf.RPKI.CacheServers, cache)
+		}
+	}
+
 	return gConf, nil
 }
 
@@ -595,14 +935,19 @@ func (h *BGPHandler) validateBGPGlobalForPatchUpdate(oldConfig *bgpd.BGPGlobal,
 
 	gConf = config.GlobalConfig{
 		GlobalBase: config.GlobalBase{
-			Vrf:                 oldConfig.Vrf,
-			AS:                  uint32(oldAsnum),
-			RouterId:            ip,
-			Disabled:            oldConfig.Disabled,
-			UseMultiplePaths:    oldConfig.UseMultiplePaths,
-			EBGPMaxPaths:        uint32(oldConfig.EBGPMaxPaths),
-			EBGPAllowMultipleAS: oldConfig.EBGPAllowMultipleAS,
-			IBGPMaxPaths:        uint32(oldConfig.IBGPMaxPaths),
+			Vrf:                     oldConfig.Vrf,
+			AS:                      uint32(oldAsnum),
+			RouterId:                ip,
+			Disabled:                oldConfig.Disabled,
+			UseMultiplePaths:        oldConfig.UseMultiplePaths,
+			EBGPMaxPaths:            uint32(oldConfig.EBGPMaxPaths),
+			EBGPAllowMultipleAS:     oldConfig.EBGPAllowMultipleAS,
+			IBGPMaxPaths:            uint32(oldConfig.IBGPMaxPaths),
+			GracefulRestart:         oldConfig.GracefulRestart,
+			RestartTime:             uint32(oldConfig.RestartTime),
+			StalePathTime:           uint32(oldConfig.StalePathTime),
+			PreserveForwardingState: oldConfig.PreserveForwardingState,
+			InstallBackupPath:       oldConfig.InstallBackupPath,
 		},
 	}
 
@@ -666,14 +1011,19 @@ func (h *BGPHandler) validateBGPGlobalForUpdate(oldConfig *bgpd.BGPGlobal, newCo
 
 	gConf = config.GlobalConfig{
 		GlobalBase: config.GlobalBase{
-			Vrf:                 newConfig.Vrf,
-			AS:                  uint32(newASNum),
-			RouterId:            ip,
-			Disabled:            newConfig.Disabled,
-			UseMultiplePaths:    newConfig.UseMultiplePaths,
-			EBGPMaxPaths:        uint32(newConfig.EBGPMaxPaths),
-			EBGPAllowMultipleAS: newConfig.EBGPAllowMultipleAS,
-			IBGPMaxPaths:        uint32(newConfig.IBGPMaxPaths),
+			Vrf:                     newConfig.Vrf,
+			AS:                      uint32(newASNum),
+			RouterId:                ip,
+			Disabled:                newConfig.Disabled,
+			UseMultiplePaths:        newConfig.UseMultiplePaths,
+			EBGPMaxPaths:            uint32(newConfig.EBGPMaxPaths),
+			EBGPAllowMultipleAS:     newConfig.EBGPAllowMultipleAS,
+			IBGPMaxPaths:            uint32(newConfig.IBGPMaxPaths),
+			GracefulRestart:         newConfig.GracefulRestart,
+			RestartTime:             uint32(newConfig.RestartTime),
+			StalePathTime:           uint32(newConfig.StalePathTime),
+			PreserveForwardingState: newConfig.PreserveForwardingState,
+			InstallBackupPath:       newConfig.InstallBackupPath,
 		},
 	}
 
@@ -685,6 +1035,38 @@ func (h *BGPHandler) validateBGPGlobalForUpdate(oldConfig *bgpd.BGPGlobal, newCo
 		}
 	}
 
+	if newConfig.BMPStations != nil {
+		gConf.BMPStations = make([]config.BMPStation, 0)
+		for i := 0; i < len(newConfig.BMPStations); i++ {
+			station := config.BMPStation{newConfig.BMPStations[i].Address, uint16(newConfig.BMPStations[i].Port)}
+			gConf.BMPStations = append(gConf.BMPStations, station)
+		}
+	}
+
+	gConf.MRT = config.MRTConfig{newConfig.MRTDumpDir, uint32(newConfig.MRTDumpInterval), newConfig.MRTLogUpdates,
+		uint32(newConfig.MRTRotateInterval)}
+
+	gConf.RPKI.PreferValid = newConfig.RPKIPreferValid
+	gConf.BestPath = config.BestPathConfig{
+		CompareMED:           newConfig.BestPathCompareMED,
+		AlwaysCompareMED:     newConfig.BestPathAlwaysCompareMED,
+		DeterministicMED:     newConfig.BestPathDeterministicMED,
+		MEDMissingAsWorst:    newConfig.BestPathMEDMissingAsWorst,
+		ASPathIgnore:         newConfig.BestPathASPathIgnore,
+		MultipathSameASPath:  newConfig.BestPathMultipathSameASPath,
+		ASPathMultipathRelax: newConfig.BestPathASPathMultipathRelax,
+		PreferOldestEBGP:     newConfig.BestPathPreferOldestEBGP,
+		CompareRouterId:      newConfig.BestPathCompareRouterId,
+	}
+	if newConfig.RPKICacheServers != nil {
+		gConf.RPKI.CacheServers = make([]config.RPKICacheServer, 0)
+		for i := 0; i < len(newConfig.RPKICacheServers); i++ {
+			cache := config.RPKICacheServer{newConfig.RPKICacheServers[i].Address,
+				uint16(newConfig.RPKICacheServers[i].Port)}
+			gConf.RPKI.CacheServers = append(gConf.RPKI.CacheServers, cache)
+		}
+	}
+
 	if attrSet != nil {
 		objTyp := reflect.TypeOf(*newConfig)
 		for i := 0; i < objTyp.NumField(); i++ {
@@ -754,6 +1136,11 @@ func (h *BGPHandler) GetBGPGlobalState(vrfId string) (*bgpd.BGPGlobalState, erro
 	bgpGlobalResponse.EBGPMaxPaths = int32(bgpGlobal.EBGPMaxPaths)
 	bgpGlobalResponse.EBGPAllowMultipleAS = bgpGlobal.EBGPAllowMultipleAS
 	bgpGlobalResponse.IBGPMaxPaths = int32(bgpGlobal.IBGPMaxPaths)
+	bgpGlobalResponse.GracefulRestart = bgpGlobal.GracefulRestart
+	bgpGlobalResponse.RestartTime = int32(bgpGlobal.RestartTime)
+	bgpGlobalResponse.StalePathTime = int32(bgpGlobal.StalePathTime)
+	bgpGlobalResponse.PreserveForwardingState = bgpGlobal.PreserveForwardingState
+	bgpGlobalResponse.InstallBackupPath = bgpGlobal.InstallBackupPath
 	bgpGlobalResponse.TotalPaths = int32(bgpGlobal.TotalPaths)
 	bgpGlobalResponse.Totalv4Prefixes = int32(bgpGlobal.Totalv4Prefixes)
 	bgpGlobalResponse.Totalv6Prefixes = int32(bgpGlobal.Totalv6Prefixes)
@@ -877,17 +1264,32 @@ func (h *BGPHandler) ConvertV4NeighborFromThrift(bgpNeighbor *bgpd.BGPv4Neighbor
 			BfdSessionParam:         bgpNeighbor.BfdSessionParam,
 			AddPathsRx:              bgpNeighbor.AddPathsRx,
 			AddPathsMaxTx:           uint8(bgpNeighbor.AddPathsMaxTx),
+			AddPathsTxMode:          bgpNeighbor.AddPathsTxMode,
+			AddPathsFamilies:        h.convertThriftToAddPathsFamilies(bgpNeighbor.AddPathsFamilies),
 			MaxPrefixes:             uint32(bgpNeighbor.MaxPrefixes),
 			MaxPrefixesThresholdPct: uint8(bgpNeighbor.MaxPrefixesThresholdPct),
 			MaxPrefixesDisconnect:   bgpNeighbor.MaxPrefixesDisconnect,
 			MaxPrefixesRestartTimer: uint8(bgpNeighbor.MaxPrefixesRestartTimer),
 			AdjRIBInFilter:          bgpNeighbor.AdjRIBInFilter,
 			AdjRIBOutFilter:         bgpNeighbor.AdjRIBOutFilter,
+			DefaultOriginate:        bgpNeighbor.DefaultOriginate,
+			DefaultOriginateMap:     bgpNeighbor.DefaultOriginateMap,
+			AdvertiseMap:            bgpNeighbor.AdvertiseMap,
+			ExistMap:                bgpNeighbor.ExistMap,
+			NonExistMap:             bgpNeighbor.NonExistMap,
+			ORFPrefixReceive:        bgpNeighbor.ORFPrefixReceive,
+			Dampening:               bgpNeighbor.Dampening,
+			KeyChain:                bgpNeighbor.KeyChain,
+			TCPAO:                   bgpNeighbor.TCPAO,
+			TTLSecurity:             bgpNeighbor.TTLSecurity,
+			TTLSecurityHops:         uint8(bgpNeighbor.TTLSecurityHops),
+			RouteServerClient:       bgpNeighbor.RouteServerClient,
 		},
 		NeighborAddress: ip,
 		IfIndex:         ifIndex,
 		PeerGroup:       bgpNeighbor.PeerGroup,
 		Disabled:        bgpNeighbor.Disabled,
+		Vrf:             bgpNeighbor.Vrf,
 	}
 	return pConf, err
 }
@@ -911,7 +1313,18 @@ func (h *BGPHandler) ValidateV4Neighbor(bgpNeighbor *bgpd.BGPv4Neighbor) (pConf
 		err = errors.New(fmt.Sprintf("Update source %s not a valid IP", bgpNeighbor.UpdateSource))
 		return pConf, err
 	}
+
+	if bgpNeighbor.TTLSecurity && bgpNeighbor.MultiHopEnable {
+		err = errors.New("TTL security and multihop can't be enabled together, use TTL security hops instead")
+		return pConf, err
+	}
+
+	if bgpNeighbor.TTLSecurityHops < 0 {
+		err = errors.New(fmt.Sprintf("TTL security hops %d not valid", bgpNeighbor.TTLSecurityHops))
+		return pConf, err
+	}
 	pConf, _ = h.ConvertV4NeighborFromThrift(bgpNeighbor, ip, ifIndex)
+	err = h.validateAddPathsConfig(pConf.BaseConfig)
 	return pConf, err
 }
 
@@ -1019,8 +1432,11 @@ func (h *BGPHandler) convertToThriftV4Neighbor(neighborState *config.NeighborSta
 	bgpNeighborResponse.KeepaliveTime = int32(neighborState.KeepaliveTime)
 	bgpNeighborResponse.BfdNeighborState = neighborState.BfdNeighborState
 	bgpNeighborResponse.PeerGroup = neighborState.PeerGroup
+	bgpNeighborResponse.Dynamic = neighborState.Dynamic
 	bgpNeighborResponse.AddPathsRx = neighborState.AddPathsRx
 	bgpNeighborResponse.AddPathsMaxTx = int8(neighborState.AddPathsMaxTx)
+	bgpNeighborResponse.AddPathsTxMode = neighborState.AddPathsTxMode
+	bgpNeighborResponse.AddPathsFamilies = h.convertAddPathsFamiliesToThrift(neighborState.AddPathsFamilies)
 
 	bgpNeighborResponse.MaxPrefixes = int32(neighborState.MaxPrefixes)
 	bgpNeighborResponse.MaxPrefixesThresholdPct = int8(neighborState.MaxPrefixesThresholdPct)
@@ -1029,6 +1445,29 @@ func (h *BGPHandler) convertToThriftV4Neighbor(neighborState *config.NeighborSta
 	bgpNeighborResponse.TotalPrefixes = int32(neighborState.TotalPrefixes)
 	bgpNeighborResponse.AdjRIBInFilter = neighborState.AdjRIBInFilter
 	bgpNeighborResponse.AdjRIBOutFilter = neighborState.AdjRIBOutFilter
+	bgpNeighborResponse.EndOfRIBReceived = h.convertProtoFamilyMapToList(neighborState.EndOfRIBReceived)
+	bgpNeighborResponse.EndOfRIBSent = h.convertProtoFamilyMapToList(neighborState.EndOfRIBSent)
+	bgpNeighborResponse.DefaultOriginate = neighborState.DefaultOriginate
+	bgpNeighborResponse.DefaultOriginateMap = neighborState.DefaultOriginateMap
+	bgpNeighborResponse.AdvertiseMap = neighborState.AdvertiseMap
+	bgpNeighborResponse.ExistMap = neighborState.ExistMap
+	bgpNeighborResponse.NonExistMap = neighborState.NonExistMap
+	bgpNeighborResponse.ORFPrefixReceive = neighborState.ORFPrefixReceive
+	bgpNeighborResponse.Dampening = neighborState.Dampening
+	bgpNeighborResponse.KeyChain = neighborState.KeyChain
+	bgpNeighborResponse.TCPAO = neighborState.TCPAO
+	bgpNeighborResponse.AuthType = neighborState.AuthType
+	bgpNeighborResponse.ActiveKeyId = neighborState.ActiveKeyId
+	bgpNeighborResponse.TTLSecurity = neighborState.TTLSecurity
+	bgpNeighborResponse.TTLSecurityHops = int8(neighborState.TTLSecurityHops)
+	bgpNeighborResponse.TTLSecurityDrops = int32(neighborState.TTLSecurityDrops)
+	bgpNeighborResponse.RouteServerClient = neighborState.RouteServerClient
+	bgpNeighborResponse.LastErrorSent = neighborState.LastErrorSent
+	bgpNeighborResponse.LastErrorRcvd = neighborState.LastErrorRcvd
+	bgpNeighborResponse.ShutdownMsgSent = neighborState.ShutdownMsgSent
+	bgpNeighborResponse.ShutdownMsgRcvd = neighborState.ShutdownMsgRcvd
+	bgpNeighborResponse.GracefulShutdown = neighborState.GracefulShutdown
+	bgpNeighborResponse.AdminShutdown = neighborState.AdminShutdown
 
 	received := bgpd.NewBGPCounters()
 	received.Notification = int64(neighborState.Messages.Received.Notification)
@@ -1164,18 +1603,34 @@ func (h *BGPHandler) ConvertV6NeighborFromThrift(bgpNeighbor *bgpd.BGPv6Neighbor
 			BfdSessionParam:         bgpNeighbor.BfdSessionParam,
 			AddPathsRx:              bgpNeighbor.AddPathsRx,
 			AddPathsMaxTx:           uint8(bgpNeighbor.AddPathsMaxTx),
+			AddPathsTxMode:          bgpNeighbor.AddPathsTxMode,
+			AddPathsFamilies:        h.convertThriftToAddPathsFamilies(bgpNeighbor.AddPathsFamilies),
 			MaxPrefixes:             uint32(bgpNeighbor.MaxPrefixes),
 			MaxPrefixesThresholdPct: uint8(bgpNeighbor.MaxPrefixesThresholdPct),
 			MaxPrefixesDisconnect:   bgpNeighbor.MaxPrefixesDisconnect,
 			MaxPrefixesRestartTimer: uint8(bgpNeighbor.MaxPrefixesRestartTimer),
 			AdjRIBInFilter:          bgpNeighbor.AdjRIBInFilter,
 			AdjRIBOutFilter:         bgpNeighbor.AdjRIBOutFilter,
+			ExtendedNextHop:         bgpNeighbor.ExtendedNextHop,
+			DefaultOriginate:        bgpNeighbor.DefaultOriginate,
+			DefaultOriginateMap:     bgpNeighbor.DefaultOriginateMap,
+			AdvertiseMap:            bgpNeighbor.AdvertiseMap,
+			ExistMap:                bgpNeighbor.ExistMap,
+			NonExistMap:             bgpNeighbor.NonExistMap,
+			ORFPrefixReceive:        bgpNeighbor.ORFPrefixReceive,
+			Dampening:               bgpNeighbor.Dampening,
+			KeyChain:                bgpNeighbor.KeyChain,
+			TCPAO:                   bgpNeighbor.TCPAO,
+			TTLSecurity:             bgpNeighbor.TTLSecurity,
+			TTLSecurityHops:         uint8(bgpNeighbor.TTLSecurityHops),
+			RouteServerClient:       bgpNeighbor.RouteServerClient,
 		},
 		NeighborAddress: ip,
 		IfIndex:         ifIndex,
 		IfName:          ifName,
 		PeerGroup:       bgpNeighbor.PeerGroup,
 		Disabled:        bgpNeighbor.Disabled,
+		Vrf:             bgpNeighbor.Vrf,
 	}
 	return pConf, err
 }
@@ -1202,7 +1657,18 @@ func (h *BGPHandler) ValidateV6Neighbor(bgpNeighbor *bgpd.BGPv6Neighbor) (pConf
 		return pConf, err
 	}
 
+	if bgpNeighbor.TTLSecurity && bgpNeighbor.MultiHopEnable {
+		err = errors.New("TTL security and multihop can't be enabled together, use TTL security hops instead")
+		return pConf, err
+	}
+
+	if bgpNeighbor.TTLSecurityHops < 0 {
+		err = errors.New(fmt.Sprintf("TTL security hops %d not valid", bgpNeighbor.TTLSecurityHops))
+		return pConf, err
+	}
+
 	pConf, _ = h.ConvertV6NeighborFromThrift(bgpNeighbor, ip, ifIndex, ifName)
+	err = h.validateAddPathsConfig(pConf.BaseConfig)
 	return pConf, err
 }
 
@@ -1299,8 +1765,11 @@ func (h *BGPHandler) convertToThriftV6Neighbor(neighborState *config.NeighborSta
 	bgpNeighborResponse.KeepaliveTime = int32(neighborState.KeepaliveTime)
 	bgpNeighborResponse.BfdNeighborState = neighborState.BfdNeighborState
 	bgpNeighborResponse.PeerGroup = neighborState.PeerGroup
+	bgpNeighborResponse.Dynamic = neighborState.Dynamic
 	bgpNeighborResponse.AddPathsRx = neighborState.AddPathsRx
 	bgpNeighborResponse.AddPathsMaxTx = int8(neighborState.AddPathsMaxTx)
+	bgpNeighborResponse.AddPathsTxMode = neighborState.AddPathsTxMode
+	bgpNeighborResponse.AddPathsFamilies = h.convertAddPathsFamiliesToThrift(neighborState.AddPathsFamilies)
 
 	bgpNeighborResponse.MaxPrefixes = int32(neighborState.MaxPrefixes)
 	bgpNeighborResponse.MaxPrefixesThresholdPct = int8(neighborState.MaxPrefixesThresholdPct)
@@ -1309,6 +1778,30 @@ func (h *BGPHandler) convertToThriftV6Neighbor(neighborState *config.NeighborSta
 	bgpNeighborResponse.TotalPrefixes = int32(neighborState.TotalPrefixes)
 	bgpNeighborResponse.AdjRIBInFilter = neighborState.AdjRIBInFilter
 	bgpNeighborResponse.AdjRIBOutFilter = neighborState.AdjRIBOutFilter
+	bgpNeighborResponse.EndOfRIBReceived = h.convertProtoFamilyMapToList(neighborState.EndOfRIBReceived)
+	bgpNeighborResponse.EndOfRIBSent = h.convertProtoFamilyMapToList(neighborState.EndOfRIBSent)
+	bgpNeighborResponse.DefaultOriginate = neighborState.DefaultOriginate
+	bgpNeighborResponse.DefaultOriginateMap = neighborState.DefaultOriginateMap
+	bgpNeighborResponse.AdvertiseMap = neighborState.AdvertiseMap
+	bgpNeighborResponse.ExistMap = neighborState.ExistMap
+	bgpNeighborResponse.NonExistMap = neighborState.NonExistMap
+	bgpNeighborResponse.ORFPrefixReceive = neighborState.ORFPrefixReceive
+	bgpNeighborResponse.Dampening = neighborState.Dampening
+	bgpNeighborResponse.KeyChain = neighborState.KeyChain
+	bgpNeighborResponse.TCPAO = neighborState.TCPAO
+	bgpNeighborResponse.AuthType = neighborState.AuthType
+	bgpNeighborResponse.ActiveKeyId = neighborState.ActiveKeyId
+	bgpNeighborResponse.TTLSecurity = neighborState.TTLSecurity
+	bgpNeighborResponse.TTLSecurityHops = int8(neighborState.TTLSecurityHops)
+	bgpNeighborResponse.TTLSecurityDrops = int32(neighborState.TTLSecurityDrops)
+	bgpNeighborResponse.RouteServerClient = neighborState.RouteServerClient
+	bgpNeighborResponse.LastErrorSent = neighborState.LastErrorSent
+	bgpNeighborResponse.LastErrorRcvd = neighborState.LastErrorRcvd
+	bgpNeighborResponse.ShutdownMsgSent = neighborState.ShutdownMsgSent
+	bgpNeighborResponse.ShutdownMsgRcvd = neighborState.ShutdownMsgRcvd
+	bgpNeighborResponse.GracefulShutdown = neighborState.GracefulShutdown
+	bgpNeighborResponse.AdminShutdown = neighborState.AdminShutdown
+	bgpNeighborResponse.ExtendedNextHop = neighborState.ExtendedNextHop
 
 	received := bgpd.NewBGPCounters()
 	received.Notification = int64(neighborState.Messages.Received.Notification)
@@ -1429,16 +1922,31 @@ func (h *BGPHandler) ValidateBGPv4PeerGroup(peerGroup *bgpd.BGPv4PeerGroup) (gro
 			KeepaliveTime:           uint32(peerGroup.KeepaliveTime),
 			AddPathsRx:              peerGroup.AddPathsRx,
 			AddPathsMaxTx:           uint8(peerGroup.AddPathsMaxTx),
+			AddPathsTxMode:          peerGroup.AddPathsTxMode,
+			AddPathsFamilies:        h.convertThriftToAddPathsFamilies(peerGroup.AddPathsFamilies),
 			MaxPrefixes:             uint32(peerGroup.MaxPrefixes),
 			MaxPrefixesThresholdPct: uint8(peerGroup.MaxPrefixesThresholdPct),
 			MaxPrefixesDisconnect:   peerGroup.MaxPrefixesDisconnect,
 			MaxPrefixesRestartTimer: uint8(peerGroup.MaxPrefixesRestartTimer),
 			AdjRIBInFilter:          peerGroup.AdjRIBInFilter,
 			AdjRIBOutFilter:         peerGroup.AdjRIBOutFilter,
+			DefaultOriginate:        peerGroup.DefaultOriginate,
+			DefaultOriginateMap:     peerGroup.DefaultOriginateMap,
+			AdvertiseMap:            peerGroup.AdvertiseMap,
+			ExistMap:                peerGroup.ExistMap,
+			NonExistMap:             peerGroup.NonExistMap,
+			ORFPrefixReceive:        peerGroup.ORFPrefixReceive,
+			Dampening:               peerGroup.Dampening,
+			KeyChain:                peerGroup.KeyChain,
+			TCPAO:                   peerGroup.TCPAO,
+			TTLSecurity:             peerGroup.TTLSecurity,
+			TTLSecurityHops:         uint8(peerGroup.TTLSecurityHops),
+			RouteServerClient:       peerGroup.RouteServerClient,
 		},
 		Name: peerGroup.Name,
 	}
 
+	err = h.validateAddPathsConfig(group.BaseConfig)
 	return group, err
 }
 
@@ -1525,16 +2033,32 @@ func (h *BGPHandler) ValidateBGPv6PeerGroup(peerGroup *bgpd.BGPv6PeerGroup) (gro
 			KeepaliveTime:           uint32(peerGroup.KeepaliveTime),
 			AddPathsRx:              peerGroup.AddPathsRx,
 			AddPathsMaxTx:           uint8(peerGroup.AddPathsMaxTx),
+			AddPathsTxMode:          peerGroup.AddPathsTxMode,
+			AddPathsFamilies:        h.convertThriftToAddPathsFamilies(peerGroup.AddPathsFamilies),
 			MaxPrefixes:             uint32(peerGroup.MaxPrefixes),
 			MaxPrefixesThresholdPct: uint8(peerGroup.MaxPrefixesThresholdPct),
 			MaxPrefixesDisconnect:   peerGroup.MaxPrefixesDisconnect,
 			MaxPrefixesRestartTimer: uint8(peerGroup.MaxPrefixesRestartTimer),
 			AdjRIBInFilter:          peerGroup.AdjRIBInFilter,
 			AdjRIBOutFilter:         peerGroup.AdjRIBOutFilter,
+			ExtendedNextHop:         peerGroup.ExtendedNextHop,
+			DefaultOriginate:        peerGroup.DefaultOriginate,
+			DefaultOriginateMap:     peerGroup.DefaultOriginateMap,
+			AdvertiseMap:            peerGroup.AdvertiseMap,
+			ExistMap:                peerGroup.ExistMap,
+			NonExistMap:             peerGroup.NonExistMap,
+			ORFPrefixReceive:        peerGroup.ORFPrefixReceive,
+			Dampening:               peerGroup.Dampening,
+			KeyChain:                peerGroup.KeyChain,
+			TCPAO:                   peerGroup.TCPAO,
+			TTLSecurity:             peerGroup.TTLSecurity,
+			TTLSecurityHops:         uint8(peerGroup.TTLSecurityHops),
+			RouteServerClient:       peerGroup.RouteServerClient,
 		},
 		Name: peerGroup.Name,
 	}
 
+	err = h.validateAddPathsConfig(group.BaseConfig)
 	return group, err
 }
 
@@ -1951,6 +2475,267 @@ func (h *BGPHandler) DeleteBGPv6Aggregate(bgpAgg *bgpd.BGPv6Aggregate) (bool, er
 	return true, nil
 }
 
+func (h *BGPHandler) validateBGPListenRange(bgpRange *bgpd.BGPListenRange) (rangeConf config.ListenRangeConfig,
+	err error) {
+	if bgpRange == nil {
+		return rangeConf, err
+	}
+
+	ip, _, err := net.ParseCIDR(bgpRange.Prefix)
+	if err != nil {
+		err = errors.New(fmt.Sprintf("BGPListenRange: Prefix %s is not valid", bgpRange.Prefix))
+		h.logger.Info("SendBGPListenRange: Prefix", bgpRange.Prefix, "is not valid")
+		return rangeConf, err
+	}
+
+	if bgpRange.PeerGroup == "" {
+		err = errors.New(fmt.Sprintf("BGPListenRange: Peer group is not set for prefix %s", bgpRange.Prefix))
+		h.logger.Info("SendBGPListenRange: Peer group is not set for prefix", bgpRange.Prefix)
+		return rangeConf, err
+	}
+
+	if bgpRange.Limit < 0 {
+		err = errors.New(fmt.Sprintf("BGPListenRange: Limit %d is not valid", bgpRange.Limit))
+		h.logger.Info("SendBGPListenRange: Limit", bgpRange.Limit, "is not valid")
+		return rangeConf, err
+	}
+
+	rangeConf = config.ListenRangeConfig{
+		Prefix:          bgpRange.Prefix,
+		PeerGroup:       bgpRange.PeerGroup,
+		PeerAddressType: config.PeerAddressV4,
+		Limit:           uint32(bgpRange.Limit),
+	}
+	if ip.To4() == nil {
+		rangeConf.PeerAddressType = config.PeerAddressV6
+	}
+	return rangeConf, nil
+}
+
+func (h *BGPHandler) SendBGPListenRange(oldConfig *bgpd.BGPListenRange, newConfig *bgpd.BGPListenRange,
+	attrSet []bool) (bool, error) {
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	oldRange, err := h.validateBGPListenRange(oldConfig)
+	if err != nil {
+		return false, err
+	}
+
+	newRange, err := h.validateBGPListenRange(newConfig)
+	if err != nil {
+		return false, err
+	}
+
+	h.server.AddListenRangeCh <- server.ListenRangeUpdate{oldRange, newRange, attrSet}
+	return true, err
+}
+
+func (h *BGPHandler) CreateBGPListenRange(bgpRange *bgpd.BGPListenRange) (bool, error) {
+	h.logger.Info("Create BGP listen range:", bgpRange)
+	return h.SendBGPListenRange(nil, bgpRange, make([]bool, 0))
+}
+
+func (h *BGPHandler) UpdateBGPListenRange(origR *bgpd.BGPListenRange, updatedR *bgpd.BGPListenRange, attrSet []bool,
+	op []*bgpd.PatchOpInfo) (bool, error) {
+	h.logger.Info("Update BGP listen range:", updatedR, "old:", origR)
+	return h.SendBGPListenRange(origR, updatedR, attrSet)
+}
+
+func (h *BGPHandler) DeleteBGPListenRange(bgpRange *bgpd.BGPListenRange) (bool, error) {
+	h.logger.Info("Delete BGP listen range:", bgpRange)
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	h.server.RemListenRangeCh <- config.ListenRangeConfig{Prefix: bgpRange.Prefix}
+	return true, nil
+}
+
+func (h *BGPHandler) convertStrToKeyLifetime(lifetime string) (time.Time, error) {
+	if lifetime == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, lifetime)
+}
+
+func (h *BGPHandler) convertToKeyChainKey(keyId int32, secret, algorithm, sendStart, sendEnd, acceptStart,
+	acceptEnd string) (key config.KeyChainKey, err error) {
+	if keyId < 0 || keyId > math.MaxUint8 {
+		return key, errors.New(fmt.Sprintf("BGPKeyChain: Key id %d is not valid", keyId))
+	}
+
+	key = config.KeyChainKey{
+		KeyId:     uint8(keyId),
+		Secret:    secret,
+		Algorithm: algorithm,
+	}
+
+	lifetimes := []string{sendStart, sendEnd, acceptStart, acceptEnd}
+	times := []*time.Time{&key.SendLifetimeStart, &key.SendLifetimeEnd, &key.AcceptLifetimeStart,
+		&key.AcceptLifetimeEnd}
+	for idx, lifetime := range lifetimes {
+		if *times[idx], err = h.convertStrToKeyLifetime(lifetime); err != nil {
+			return key, errors.New(fmt.Sprintf("BGPKeyChain: Key %d lifetime %s is not valid", keyId, lifetime))
+		}
+	}
+	return key, nil
+}
+
+func (h *BGPHandler) validateBGPKeyChain(bgpKeyChain *bgpd.BGPKeyChain) (keyChainConf config.KeyChainConfig,
+	err error) {
+	if bgpKeyChain == nil {
+		return keyChainConf, err
+	}
+
+	if bgpKeyChain.Name == "" {
+		err = errors.New("BGPKeyChain: Key chain name is not set")
+		h.logger.Info("SendBGPKeyChain: Key chain name is not set")
+		return keyChainConf, err
+	}
+
+	keyChainConf = config.KeyChainConfig{
+		Name: bgpKeyChain.Name,
+		Keys: make([]config.KeyChainKey, 0, len(bgpKeyChain.Keys)),
+	}
+	for _, bgpKey := range bgpKeyChain.Keys {
+		key, err := h.convertToKeyChainKey(bgpKey.KeyId, bgpKey.Secret, bgpKey.Algorithm, bgpKey.SendLifetimeStart,
+			bgpKey.SendLifetimeEnd, bgpKey.AcceptLifetimeStart, bgpKey.AcceptLifetimeEnd)
+		if err != nil {
+			h.logger.Info("SendBGPKeyChain:", err)
+			return keyChainConf, err
+		}
+		keyChainConf.Keys = append(keyChainConf.Keys, key)
+	}
+
+	if _, err = keychain.NewKeyChain(keyChainConf); err != nil {
+		h.logger.Info("SendBGPKeyChain:", err)
+		return keyChainConf, err
+	}
+	return keyChainConf, nil
+}
+
+func (h *BGPHandler) SendBGPKeyChain(oldConfig *bgpd.BGPKeyChain, newConfig *bgpd.BGPKeyChain) (bool, error) {
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	if _, err := h.validateBGPKeyChain(oldConfig); err != nil {
+		return false, err
+	}
+
+	newKeyChain, err := h.validateBGPKeyChain(newConfig)
+	if err != nil {
+		return false, err
+	}
+
+	h.server.AddKeyChainCh <- newKeyChain
+	return true, err
+}
+
+func (h *BGPHandler) CreateBGPKeyChain(bgpKeyChain *bgpd.BGPKeyChain) (bool, error) {
+	h.logger.Info("Create BGP key chain:", bgpKeyChain.Name)
+	return h.SendBGPKeyChain(nil, bgpKeyChain)
+}
+
+func (h *BGPHandler) UpdateBGPKeyChain(origK *bgpd.BGPKeyChain, updatedK *bgpd.BGPKeyChain, attrSet []bool,
+	op []*bgpd.PatchOpInfo) (bool, error) {
+	h.logger.Info("Update BGP key chain:", updatedK.Name)
+	return h.SendBGPKeyChain(origK, updatedK)
+}
+
+func (h *BGPHandler) DeleteBGPKeyChain(bgpKeyChain *bgpd.BGPKeyChain) (bool, error) {
+	h.logger.Info("Delete BGP key chain:", bgpKeyChain.Name)
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	h.server.RemKeyChainCh <- bgpKeyChain.Name
+	return true, nil
+}
+
+func (h *BGPHandler) validateBGPVrf(bgpVrf *bgpd.BGPVrf) (vrfConf config.VrfConfig, err error) {
+	if bgpVrf == nil {
+		return vrfConf, err
+	}
+
+	if bgpVrf.Name == "" {
+		err = errors.New("BGPVrf: VRF name is not set")
+		h.logger.Info("SendBGPVrf: VRF name is not set")
+		return vrfConf, err
+	}
+
+	if _, err = packet.ParseRouteDistinguisher(bgpVrf.RD); err != nil {
+		err = errors.New(fmt.Sprintf("BGPVrf: RD %s is not valid", bgpVrf.RD))
+		h.logger.Info("SendBGPVrf: RD", bgpVrf.RD, "is not valid")
+		return vrfConf, err
+	}
+
+	if bgpVrf.Label < 0 || uint32(bgpVrf.Label) > packet.VPNLabelMax {
+		err = errors.New(fmt.Sprintf("BGPVrf: Label %d is not valid", bgpVrf.Label))
+		h.logger.Info("SendBGPVrf: Label", bgpVrf.Label, "is not valid")
+		return vrfConf, err
+	}
+
+	for _, rt := range append(bgpVrf.ImportRT, bgpVrf.ExportRT...) {
+		if _, err = packet.ParseRouteTarget(rt); err != nil {
+			err = errors.New(fmt.Sprintf("BGPVrf: Route target %s is not valid", rt))
+			h.logger.Info("SendBGPVrf: Route target", rt, "is not valid")
+			return vrfConf, err
+		}
+	}
+
+	vrfConf = config.VrfConfig{
+		Name:     bgpVrf.Name,
+		RD:       bgpVrf.RD,
+		Label:    uint32(bgpVrf.Label),
+		ImportRT: bgpVrf.ImportRT,
+		ExportRT: bgpVrf.ExportRT,
+	}
+	return vrfConf, nil
+}
+
+func (h *BGPHandler) SendBGPVrf(oldConfig *bgpd.BGPVrf, newConfig *bgpd.BGPVrf, attrSet []bool) (bool, error) {
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	oldVrf, err := h.validateBGPVrf(oldConfig)
+	if err != nil {
+		return false, err
+	}
+
+	newVrf, err := h.validateBGPVrf(newConfig)
+	if err != nil {
+		return false, err
+	}
+
+	h.server.AddVrfCh <- server.VrfUpdate{oldVrf, newVrf, attrSet}
+	return true, err
+}
+
+func (h *BGPHandler) CreateBGPVrf(bgpVrf *bgpd.BGPVrf) (bool, error) {
+	h.logger.Info("Create BGP VRF:", bgpVrf)
+	return h.SendBGPVrf(nil, bgpVrf, make([]bool, 0))
+}
+
+func (h *BGPHandler) UpdateBGPVrf(origV *bgpd.BGPVrf, updatedV *bgpd.BGPVrf, attrSet []bool,
+	op []*bgpd.PatchOpInfo) (bool, error) {
+	h.logger.Info("Update BGP VRF:", updatedV, "old:", origV)
+	return h.SendBGPVrf(origV, updatedV, attrSet)
+}
+
+func (h *BGPHandler) DeleteBGPVrf(bgpVrf *bgpd.BGPVrf) (bool, error) {
+	h.logger.Info("Delete BGP VRF:", bgpVrf)
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	h.server.RemVrfCh <- config.VrfConfig{Name: bgpVrf.Name}
+	return true, nil
+}
+
 func (h *BGPHandler) ExecuteActionResetBGPv4NeighborByIPAddr(resetIP *bgpd.ResetBGPv4NeighborByIPAddr) (bool, error) {
 	h.logger.Info("Reset BGP v4 neighbor by IP address", resetIP.IPAddr)
 	if err := h.checkBGPGlobal(); err != nil {
@@ -1961,7 +2746,76 @@ func (h *BGPHandler) ExecuteActionResetBGPv4NeighborByIPAddr(resetIP *bgpd.Reset
 	if ip == nil {
 		return false, errors.New(fmt.Sprintf("IPv4 Neighbor address %s is not a valid IP", resetIP.IPAddr))
 	}
-	h.server.PeerCommandCh <- config.PeerCommand{IP: ip, Command: int(fsm.BGPEventManualStop)}
+	h.server.PeerCommandCh <- config.PeerCommand{IP: ip, Command: int(fsm.BGPEventManualStop),
+		Reason: resetIP.Reason}
+	return true, nil
+}
+
+func (h *BGPHandler) ExecuteActionGracefulShutdownBGPv4NeighborByIPAddr(
+	shutdownIP *bgpd.GracefulShutdownBGPv4NeighborByIPAddr) (bool, error) {
+	h.logger.Info("Graceful shutdown BGP v4 neighbor by IP address", shutdownIP.IPAddr)
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(shutdownIP.IPAddr))
+	if ip == nil {
+		return false, errors.New(fmt.Sprintf("IPv4 Neighbor address %s is not a valid IP", shutdownIP.IPAddr))
+	}
+	h.server.PeerShutdownCh <- config.PeerShutdown{IP: ip, Reason: shutdownIP.Reason, Time: uint32(shutdownIP.Time)}
+	return true, nil
+}
+
+func (h *BGPHandler) ExecuteActionStartBGPv4NeighborByIPAddr(startIP *bgpd.StartBGPv4NeighborByIPAddr) (bool, error) {
+	h.logger.Info("Start BGP v4 neighbor by IP address", startIP.IPAddr)
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(startIP.IPAddr))
+	if ip == nil {
+		return false, errors.New(fmt.Sprintf("IPv4 Neighbor address %s is not a valid IP", startIP.IPAddr))
+	}
+	h.server.PeerCommandCh <- config.PeerCommand{IP: ip, Command: int(fsm.BGPEventManualStart)}
+	return true, nil
+}
+
+func (h *BGPHandler) ExecuteActionSoftResetInBGPv4NeighborByIPAddr(
+	resetIP *bgpd.SoftResetInBGPv4NeighborByIPAddr) (bool, error) {
+	h.logger.Info("Soft reset inbound BGP v4 neighbor by IP address", resetIP.IPAddr)
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(resetIP.IPAddr))
+	if ip == nil {
+		return false, errors.New(fmt.Sprintf("IPv4 Neighbor address %s is not a valid IP", resetIP.IPAddr))
+	}
+	h.server.SoftResetInCh <- ip.String()
+	return true, nil
+}
+
+func (h *BGPHandler) ExecuteActionDumpBGPMRTTable(dump *bgpd.DumpBGPMRTTable) (bool, error) {
+	h.logger.Info("Dump BGP table in MRT format")
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	h.server.MRTDumpCh <- false
+	return true, nil
+}
+
+func (h *BGPHandler) ExecuteActionClearBGPDampening(clear *bgpd.ClearBGPDampening) (bool, error) {
+	h.logger.Info("Clear BGP dampening for prefix", clear.Prefix)
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	_, ipNet, err := net.ParseCIDR(strings.TrimSpace(clear.Prefix))
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Prefix %s is not a valid CIDR", clear.Prefix))
+	}
+	h.server.ClearDampeningCh <- ipNet.String()
 	return true, nil
 }
 
@@ -2002,7 +2856,8 @@ func (h *BGPHandler) ExecuteActionResetBGPv4NeighborByInterface(resetIf *bgpd.Re
 	h.logger.Info("IPv4Addr of the v4Neighbor remote interface is", ifIP)
 	ip := ifIP
 
-	h.server.PeerCommandCh <- config.PeerCommand{IP: ip, Command: int(fsm.BGPEventManualStop)}
+	h.server.PeerCommandCh <- config.PeerCommand{IP: ip, Command: int(fsm.BGPEventManualStop),
+		Reason: resetIf.Reason}
 	return true, nil
 }
 
@@ -2016,7 +2871,52 @@ func (h *BGPHandler) ExecuteActionResetBGPv6NeighborByIPAddr(resetIP *bgpd.Reset
 	if ip == nil {
 		return false, errors.New(fmt.Sprintf("IPv6 Neighbor address %s is not a valid IP", resetIP.IPAddr))
 	}
-	h.server.PeerCommandCh <- config.PeerCommand{IP: ip, Command: int(fsm.BGPEventManualStop)}
+	h.server.PeerCommandCh <- config.PeerCommand{IP: ip, Command: int(fsm.BGPEventManualStop),
+		Reason: resetIP.Reason}
+	return true, nil
+}
+
+func (h *BGPHandler) ExecuteActionGracefulShutdownBGPv6NeighborByIPAddr(
+	shutdownIP *bgpd.GracefulShutdownBGPv6NeighborByIPAddr) (bool, error) {
+	h.logger.Info("Graceful shutdown BGP v6 neighbor by IP address", shutdownIP.IPAddr)
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(shutdownIP.IPAddr))
+	if ip == nil {
+		return false, errors.New(fmt.Sprintf("IPv6 Neighbor address %s is not a valid IP", shutdownIP.IPAddr))
+	}
+	h.server.PeerShutdownCh <- config.PeerShutdown{IP: ip, Reason: shutdownIP.Reason, Time: uint32(shutdownIP.Time)}
+	return true, nil
+}
+
+func (h *BGPHandler) ExecuteActionStartBGPv6NeighborByIPAddr(startIP *bgpd.StartBGPv6NeighborByIPAddr) (bool, error) {
+	h.logger.Info("Start BGP v6 neighbor by IP address", startIP.IPAddr)
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(startIP.IPAddr))
+	if ip == nil {
+		return false, errors.New(fmt.Sprintf("IPv6 Neighbor address %s is not a valid IP", startIP.IPAddr))
+	}
+	h.server.PeerCommandCh <- config.PeerCommand{IP: ip, Command: int(fsm.BGPEventManualStart)}
+	return true, nil
+}
+
+func (h *BGPHandler) ExecuteActionSoftResetInBGPv6NeighborByIPAddr(
+	resetIP *bgpd.SoftResetInBGPv6NeighborByIPAddr) (bool, error) {
+	h.logger.Info("Soft reset inbound BGP v6 neighbor by IP address", resetIP.IPAddr)
+	if err := h.checkBGPGlobal(); err != nil {
+		return false, err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(resetIP.IPAddr))
+	if ip == nil {
+		return false, errors.New(fmt.Sprintf("IPv6 Neighbor address %s is not a valid IP", resetIP.IPAddr))
+	}
+	h.server.SoftResetInCh <- ip.String()
 	return true, nil
 }
 
@@ -2049,6 +2949,7 @@ func (h *BGPHandler) ExecuteActionResetBGPv6NeighborByInterface(resetIf *bgpd.Re
 		return false, errors.New(fmt.Sprintf("IPv6 Neighbor address %s for interface %s is not a valid IP",
 			ipInfo.LinklocalIpAddr, resetIf.IntfRef))
 	}
-	h.server.PeerCommandCh <- config.PeerCommand{IP: ip, Command: int(fsm.BGPEventManualStop)}
+	h.server.PeerCommandCh <- config.PeerCommand{IP: ip, Command: int(fsm.BGPEventManualStop),
+		Reason: resetIf.Reason}
 	return true, nil
 }
@@ -0,0 +1,352 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// client.go
+package rpki
+
+import (
+	"io"
+	"net"
+	"time"
+	"utils/logging"
+)
+
+const (
+	RTRConnectTimeout  = 10   // seconds
+	RTRReconnectTime   = 30   // seconds
+	RTRDefaultRefresh  = 3600 // seconds
+	RTRDefaultExpire   = 7200 // seconds
+	RTRResponseTimeout = 60   // seconds
+)
+
+func ReadRTRMessage(reader io.Reader) (*RTRMessage, error) {
+	buf := make([]byte, RTRHeaderLen)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return nil, err
+	}
+
+	header := &RTRHeader{}
+	if err := header.Decode(buf); err != nil {
+		return nil, err
+	}
+
+	buf = make([]byte, header.Length-RTRHeaderLen)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	msg := &RTRMessage{}
+	if err := msg.Decode(header, buf); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeRTRMessage(conn net.Conn, msg *RTRMessage) error {
+	pkt, err := msg.Encode()
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(pkt)
+	return err
+}
+
+// RTRClient keeps the ROAs of one RPKI cache in the ROA table in sync using the RPKI-RTR protocol (RFC 8210).
+// The ROAs are kept when the connection to the cache goes down till the expire interval elapses.
+type RTRClient struct {
+	logger    *logging.Writer
+	Address   string
+	table     *ROATable
+	updateCh  chan bool
+	stopCh    chan bool
+	version   uint8
+	sessionId uint16
+	serial    uint32
+	hasData   bool
+	refresh   uint32
+	expire    uint32
+	announced []ROA
+	withdrawn []ROA
+	reset     bool
+}
+
+func NewRTRClient(logger *logging.Writer, address string, table *ROATable, updateCh chan bool) *RTRClient {
+	return &RTRClient{
+		logger:   logger,
+		Address:  address,
+		table:    table,
+		updateCh: updateCh,
+		stopCh:   make(chan bool),
+		version:  RTRVersion1,
+		refresh:  RTRDefaultRefresh,
+		expire:   RTRDefaultExpire,
+	}
+}
+
+func (c *RTRClient) Start() {
+	go c.run()
+}
+
+func (c *RTRClient) Stop() {
+	close(c.stopCh)
+}
+
+func (c *RTRClient) notifyUpdate() {
+	select {
+	case c.updateCh <- true:
+	default:
+	}
+}
+
+func (c *RTRClient) expireData() {
+	c.logger.Infof("RPKI cache %s: data expired, remove ROAs", c.Address)
+	c.hasData = false
+	if c.table.RemoveSource(c.Address) {
+		c.notifyUpdate()
+	}
+}
+
+func (c *RTRClient) run() {
+	reconnectTimer := time.NewTimer(0)
+	expireTimer := time.NewTimer(time.Duration(c.expire) * time.Second)
+	expireTimer.Stop()
+	defer func() {
+		if c.table.RemoveSource(c.Address) {
+			c.notifyUpdate()
+		}
+	}()
+
+	for {
+		select {
+		case <-reconnectTimer.C:
+			conn, err := net.DialTimeout("tcp", c.Address, RTRConnectTimeout*time.Second)
+			if err != nil {
+				c.logger.Infof("RPKI cache %s: connect failed with error %s, retry in %d seconds", c.Address, err,
+					RTRReconnectTime)
+				reconnectTimer.Reset(RTRReconnectTime * time.Second)
+				continue
+			}
+
+			c.logger.Infof("RPKI cache %s: connected, version %d", c.Address, c.version)
+			expireTimer.Stop()
+			if stop := c.serve(conn); stop {
+				return
+			}
+			if c.hasData {
+				expireTimer.Reset(time.Duration(c.expire) * time.Second)
+			}
+			reconnectTimer.Reset(RTRReconnectTime * time.Second)
+
+		case <-expireTimer.C:
+			c.expireData()
+
+		case <-c.stopCh:
+			reconnectTimer.Stop()
+			expireTimer.Stop()
+			return
+		}
+	}
+}
+
+func (c *RTRClient) sendQuery(conn net.Conn) error {
+	c.announced = make([]ROA, 0)
+	c.withdrawn = make([]ROA, 0)
+	if c.hasData {
+		c.reset = false
+		return writeRTRMessage(conn, NewRTRSerialQueryMessage(c.version, c.sessionId, c.serial))
+	}
+
+	c.reset = true
+	return writeRTRMessage(conn, NewRTRResetQueryMessage(c.version))
+}
+
+func (c *RTRClient) serve(conn net.Conn) bool {
+	defer conn.Close()
+
+	msgCh := make(chan *RTRMessage)
+	errCh := make(chan error, 1)
+	doneCh := make(chan bool)
+	defer close(doneCh)
+	go func() {
+		for {
+			msg, err := ReadRTRMessage(conn)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			select {
+			case msgCh <- msg:
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+
+	if err := c.sendQuery(conn); err != nil {
+		c.logger.Errf("RPKI cache %s: failed to send query with error %s", c.Address, err)
+		return false
+	}
+
+	inResponse := false
+	queryPending := true
+	refreshTimer := time.NewTimer(time.Duration(c.refresh) * time.Second)
+	defer refreshTimer.Stop()
+	responseTimer := time.NewTimer(RTRResponseTimeout * time.Second)
+	defer responseTimer.Stop()
+
+	for {
+		select {
+		case msg := <-msgCh:
+			if msg.Header.Version != c.version {
+				c.logger.Errf("RPKI cache %s: received PDU version %d, expected version %d", c.Address,
+					msg.Header.Version, c.version)
+				if c.version == RTRVersion1 && msg.Header.Version == RTRVersion0 && !c.hasData {
+					c.logger.Infof("RPKI cache %s: downgrade to version %d", c.Address, RTRVersion0)
+					c.version = RTRVersion0
+				} else {
+					writeRTRMessage(conn, NewRTRErrorReportMessage(c.version, RTRErrUnexpectedVersion, nil,
+						"Unexpected protocol version"))
+				}
+				return false
+			}
+
+			switch msg.Header.Type {
+			case RTRPDUSerialNotify:
+				if !queryPending {
+					if err := c.sendQuery(conn); err != nil {
+						c.logger.Errf("RPKI cache %s: failed to send query with error %s", c.Address, err)
+						return false
+					}
+					queryPending = true
+					responseTimer.Reset(RTRResponseTimeout * time.Second)
+				}
+
+			case RTRPDUCacheResponse:
+				if c.hasData && !c.reset && msg.Header.SessionId != c.sessionId {
+					c.logger.Errf("RPKI cache %s: session id changed from %d to %d", c.Address, c.sessionId,
+						msg.Header.SessionId)
+					c.hasData = false
+					return false
+				}
+				c.sessionId = msg.Header.SessionId
+				inResponse = true
+
+			case RTRPDUIPv4Prefix, RTRPDUIPv6Prefix:
+				if !inResponse {
+					c.logger.Errf("RPKI cache %s: received prefix PDU outside of a cache response", c.Address)
+					writeRTRMessage(conn, NewRTRErrorReportMessage(c.version, RTRErrInvalidRequest, nil,
+						"Prefix PDU outside of a cache response"))
+					return false
+				}
+				prefix := msg.Body.(*RTRPrefix)
+				if prefix.IsAnnounce() {
+					c.announced = append(c.announced, prefix.GetROA())
+				} else {
+					c.withdrawn = append(c.withdrawn, prefix.GetROA())
+				}
+
+			case RTRPDUEndOfData:
+				if !inResponse {
+					c.logger.Errf("RPKI cache %s: received End of Data PDU outside of a cache response", c.Address)
+					return false
+				}
+				eod := msg.Body.(*RTREndOfData)
+				changed := c.table.Update(c.Address, c.reset, c.announced, c.withdrawn)
+				c.logger.Infof("RPKI cache %s: serial %d, announced %d, withdrawn %d, total ROAs %d", c.Address,
+					eod.Serial, len(c.announced), len(c.withdrawn), c.table.GetCount())
+				c.serial = eod.Serial
+				c.hasData = true
+				if eod.Refresh != 0 {
+					c.refresh = eod.Refresh
+				}
+				if eod.Expire != 0 {
+					c.expire = eod.Expire
+				}
+				c.announced = nil
+				c.withdrawn = nil
+				inResponse = false
+				queryPending = false
+				responseTimer.Stop()
+				refreshTimer.Reset(time.Duration(c.refresh) * time.Second)
+				if changed {
+					c.notifyUpdate()
+				}
+
+			case RTRPDUCacheReset:
+				c.logger.Infof("RPKI cache %s: received Cache Reset", c.Address)
+				c.hasData = false
+				if err := c.sendQuery(conn); err != nil {
+					c.logger.Errf("RPKI cache %s: failed to send query with error %s", c.Address, err)
+					return false
+				}
+				queryPending = true
+				responseTimer.Reset(RTRResponseTimeout * time.Second)
+
+			case RTRPDURouterKey:
+				// Router keys are used by BGPsec which is not supported
+
+			case RTRPDUErrorReport:
+				errReport := msg.Body.(*RTRErrorReport)
+				c.logger.Errf("RPKI cache %s: received Error Report code %d, %s", c.Address, msg.Header.SessionId,
+					errReport.Text)
+				if msg.Header.SessionId == RTRErrUnsupportedVersion && c.version == RTRVersion1 && !c.hasData {
+					c.logger.Infof("RPKI cache %s: downgrade to version %d", c.Address, RTRVersion0)
+					c.version = RTRVersion0
+				}
+				return false
+
+			default:
+				c.logger.Errf("RPKI cache %s: received unsupported PDU type %d", c.Address, msg.Header.Type)
+				writeRTRMessage(conn, NewRTRErrorReportMessage(c.version, RTRErrUnsupportedPDUType, nil,
+					"Unsupported PDU type"))
+				return false
+			}
+
+		case err := <-errCh:
+			c.logger.Infof("RPKI cache %s: connection closed with error %s", c.Address, err)
+			return false
+
+		case <-refreshTimer.C:
+			if !queryPending {
+				if err := c.sendQuery(conn); err != nil {
+					c.logger.Errf("RPKI cache %s: failed to send query with error %s", c.Address, err)
+					return false
+				}
+				queryPending = true
+				responseTimer.Reset(RTRResponseTimeout * time.Second)
+			}
+
+		case <-responseTimer.C:
+			if queryPending {
+				c.logger.Errf("RPKI cache %s: no response received in %d seconds", c.Address, RTRResponseTimeout)
+				return false
+			}
+
+		case <-c.stopCh:
+			return true
+		}
+	}
+}
@@ -0,0 +1,86 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// manager.go
+package rpki
+
+import (
+	"l3/bgp/config"
+	"net"
+	"strconv"
+	"utils/logging"
+)
+
+type RPKIManager struct {
+	logger   *logging.Writer
+	Table    *ROATable
+	clients  map[string]*RTRClient
+	UpdateCh chan bool
+}
+
+func NewRPKIManager(logger *logging.Writer) *RPKIManager {
+	return &RPKIManager{
+		logger:   logger,
+		Table:    NewROATable(),
+		clients:  make(map[string]*RTRClient),
+		UpdateCh: make(chan bool, 1),
+	}
+}
+
+func (m *RPKIManager) SetCacheServers(servers []config.RPKICacheServer) {
+	addrMap := make(map[string]bool)
+	for _, server := range servers {
+		if net.ParseIP(server.Address) == nil || server.Port == 0 {
+			m.logger.Errf("RPKI cache %s port %d is not valid", server.Address, server.Port)
+			continue
+		}
+		addrMap[net.JoinHostPort(server.Address, strconv.Itoa(int(server.Port)))] = true
+	}
+
+	for addr, client := range m.clients {
+		if !addrMap[addr] {
+			m.logger.Infof("Stop RPKI cache client %s", addr)
+			client.Stop()
+			delete(m.clients, addr)
+		}
+	}
+
+	for addr, _ := range addrMap {
+		if _, ok := m.clients[addr]; !ok {
+			m.logger.Infof("Start RPKI cache client %s", addr)
+			m.clients[addr] = NewRTRClient(m.logger, addr, m.Table, m.UpdateCh)
+			m.clients[addr].Start()
+		}
+	}
+}
+
+func (m *RPKIManager) IsEnabled() bool {
+	return len(m.clients) > 0
+}
+
+func (m *RPKIManager) Stop() {
+	for addr, client := range m.clients {
+		client.Stop()
+		delete(m.clients, addr)
+	}
+}
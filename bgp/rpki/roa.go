@@ -0,0 +1,246 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// roa.go
+package rpki
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ValidationState values match the RFC 8097 origin validation state extended community, lower is preferred
+type ValidationState uint8
+
+const (
+	ValidationStateValid ValidationState = iota
+	ValidationStateNotFound
+	ValidationStateInvalid
+)
+
+var ValidationStateToStrMap = map[ValidationState]string{
+	ValidationStateValid:    "Valid",
+	ValidationStateNotFound: "NotFound",
+	ValidationStateInvalid:  "Invalid",
+}
+
+func (s ValidationState) String() string {
+	if str, ok := ValidationStateToStrMap[s]; ok {
+		return str
+	}
+	return "Unknown"
+}
+
+func ParseValidationState(str string) (ValidationState, error) {
+	for state, stateStr := range ValidationStateToStrMap {
+		if strings.EqualFold(str, stateStr) {
+			return state, nil
+		}
+	}
+	return ValidationStateNotFound, errors.New(fmt.Sprintf("Unknown RPKI validation state %s", str))
+}
+
+type ROA struct {
+	Prefix    net.IP
+	Length    uint8
+	MaxLength uint8
+	AS        uint32
+}
+
+func NewROA(prefix net.IP, length, maxLength uint8, as uint32) ROA {
+	return ROA{maskIP(prefix, length), length, maxLength, as}
+}
+
+func (r ROA) String() string {
+	return fmt.Sprintf("%s/%d-%d AS%d", r.Prefix, r.Length, r.MaxLength, r.AS)
+}
+
+func maskIP(ip net.IP, length uint8) net.IP {
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		bits = 8 * net.IPv4len
+	}
+	return ip.Mask(net.CIDRMask(int(length), bits))
+}
+
+func prefixKey(ip net.IP, length uint8) string {
+	return fmt.Sprintf("%s/%d", maskIP(ip, length), length)
+}
+
+// ROATable is the union of the ROAs received from all the RPKI caches. Generation is incremented every time
+// the table changes so that cached validation states can be checked for staleness.
+type ROATable struct {
+	sync.RWMutex
+	sources    map[string]map[string]ROA
+	prefixROAs map[string]map[string]int
+	roas       map[string]ROA
+	generation uint64
+}
+
+func NewROATable() *ROATable {
+	return &ROATable{
+		sources:    make(map[string]map[string]ROA),
+		prefixROAs: make(map[string]map[string]int),
+		roas:       make(map[string]ROA),
+		generation: 1,
+	}
+}
+
+func (t *ROATable) addROA(roa ROA) {
+	roaKey := roa.String()
+	key := prefixKey(roa.Prefix, roa.Length)
+	if _, ok := t.prefixROAs[key]; !ok {
+		t.prefixROAs[key] = make(map[string]int)
+	}
+	t.prefixROAs[key][roaKey]++
+	t.roas[roaKey] = roa
+}
+
+func (t *ROATable) removeROA(roa ROA) {
+	roaKey := roa.String()
+	key := prefixKey(roa.Prefix, roa.Length)
+	if _, ok := t.prefixROAs[key][roaKey]; !ok {
+		return
+	}
+
+	t.prefixROAs[key][roaKey]--
+	if t.prefixROAs[key][roaKey] == 0 {
+		delete(t.prefixROAs[key], roaKey)
+		delete(t.roas, roaKey)
+		if len(t.prefixROAs[key]) == 0 {
+			delete(t.prefixROAs, key)
+		}
+	}
+}
+
+// Update applies the ROAs announced and withdrawn by a cache. When reset is set all the ROAs previously
+// received from the cache are replaced by the announced ROAs.
+func (t *ROATable) Update(source string, reset bool, announced, withdrawn []ROA) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	changed := false
+	sourceROAs, ok := t.sources[source]
+	if !ok || reset {
+		for _, roa := range sourceROAs {
+			t.removeROA(roa)
+			changed = true
+		}
+		sourceROAs = make(map[string]ROA)
+		t.sources[source] = sourceROAs
+	}
+
+	for _, roa := range withdrawn {
+		roaKey := roa.String()
+		if _, ok := sourceROAs[roaKey]; ok {
+			t.removeROA(roa)
+			delete(sourceROAs, roaKey)
+			changed = true
+		}
+	}
+
+	for _, roa := range announced {
+		roaKey := roa.String()
+		if _, ok := sourceROAs[roaKey]; !ok {
+			t.addROA(roa)
+			sourceROAs[roaKey] = roa
+			changed = true
+		}
+	}
+
+	if changed {
+		t.generation++
+	}
+	return changed
+}
+
+func (t *ROATable) RemoveSource(source string) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	sourceROAs, ok := t.sources[source]
+	if !ok {
+		return false
+	}
+
+	for _, roa := range sourceROAs {
+		t.removeROA(roa)
+	}
+	delete(t.sources, source)
+	t.generation++
+	return len(sourceROAs) > 0
+}
+
+func (t *ROATable) GetGeneration() uint64 {
+	t.RLock()
+	defer t.RUnlock()
+	return t.generation
+}
+
+func (t *ROATable) GetCount() int {
+	t.RLock()
+	defer t.RUnlock()
+	return len(t.roas)
+}
+
+func (t *ROATable) GetROAs() []ROA {
+	t.RLock()
+	defer t.RUnlock()
+	roas := make([]ROA, 0, len(t.roas))
+	for _, roa := range t.roas {
+		roas = append(roas, roa)
+	}
+	return roas
+}
+
+// Validate implements the route origin validation procedure in RFC 6811. Origin AS 0 is used when the origin
+// can not be determined, for example when the AS path ends with an AS_SET, it never matches any ROA.
+func (t *ROATable) Validate(prefix net.IP, length uint8, originAS uint32) ValidationState {
+	t.RLock()
+	defer t.RUnlock()
+
+	covered := false
+	for l := int(length); l >= 0; l-- {
+		roaKeys, ok := t.prefixROAs[prefixKey(prefix, uint8(l))]
+		if !ok {
+			continue
+		}
+
+		covered = true
+		for roaKey, _ := range roaKeys {
+			roa := t.roas[roaKey]
+			if originAS != 0 && roa.AS == originAS && length <= roa.MaxLength {
+				return ValidationStateValid
+			}
+		}
+	}
+
+	if covered {
+		return ValidationStateInvalid
+	}
+	return ValidationStateNotFound
+}
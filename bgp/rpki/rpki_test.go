@@ -0,0 +1,260 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// rpki_test.go
+package rpki
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+	"utils/logging"
+)
+
+func getLogger(t *testing.T) *logging.Writer {
+	logger, err := logging.NewLogger("bgpd", "BGP", true)
+	if err != nil {
+		t.Fatal("Failed to start the logger. Exiting!!")
+	}
+	return logger
+}
+
+func TestRTRMessages(t *testing.T) {
+	msgs := []*RTRMessage{
+		NewRTRSerialNotifyMessage(RTRVersion1, 10, 100),
+		NewRTRSerialQueryMessage(RTRVersion1, 10, 100),
+		NewRTRResetQueryMessage(RTRVersion1),
+		NewRTRCacheResponseMessage(RTRVersion1, 10),
+		NewRTRPrefixMessage(RTRVersion1, true, NewROA(net.ParseIP("10.1.0.0"), 16, 24, 65001)),
+		NewRTRPrefixMessage(RTRVersion1, false, NewROA(net.ParseIP("2001:db8::"), 32, 48, 4200000000)),
+		NewRTREndOfDataMessage(RTRVersion1, 10, 101, 300, 60, 600),
+		NewRTREndOfDataMessage(RTRVersion0, 10, 101, 0, 0, 0),
+		NewRTRCacheResetMessage(RTRVersion1),
+		NewRTRErrorReportMessage(RTRVersion1, RTRErrNoDataAvailable, []byte{1, 2, 3, 4}, "No data"),
+	}
+	lengths := []uint32{12, 12, 8, 8, 20, 32, 24, 12, 8, 27}
+
+	for i, msg := range msgs {
+		pkt, err := msg.Encode()
+		if err != nil {
+			t.Fatal("RTR message type", msg.Header.Type, "encode failed with error:", err)
+		}
+		if uint32(len(pkt)) != lengths[i] || msg.Header.Length != lengths[i] {
+			t.Fatal("RTR message type", msg.Header.Type, "length", len(pkt), "expected", lengths[i])
+		}
+
+		decoded, err := ReadRTRMessage(bytes.NewReader(pkt))
+		if err != nil {
+			t.Fatal("RTR message type", msg.Header.Type, "decode failed with error:", err)
+		}
+		if decoded.Header != msg.Header {
+			t.Fatal("RTR message header", decoded.Header, "expected", msg.Header)
+		}
+
+		decodedPkt, _ := decoded.Encode()
+		if !bytes.Equal(pkt, decodedPkt) {
+			t.Fatal("RTR message type", msg.Header.Type, "encoded", decodedPkt, "expected", pkt)
+		}
+	}
+
+	prefix := NewRTRPrefixMessage(RTRVersion1, true, NewROA(net.ParseIP("10.1.0.0"), 24, 16, 65001))
+	pkt, _ := prefix.Encode()
+	if _, err := ReadRTRMessage(bytes.NewReader(pkt)); err == nil {
+		t.Fatal("RTR prefix with length greater than max length decoded without error")
+	}
+}
+
+func TestROATableValidate(t *testing.T) {
+	table := NewROATable()
+	roas := []ROA{
+		NewROA(net.ParseIP("10.1.0.0"), 16, 24, 65001),
+		NewROA(net.ParseIP("10.1.2.0"), 24, 24, 65002),
+		NewROA(net.ParseIP("2001:db8::"), 32, 48, 65003),
+		NewROA(net.ParseIP("192.168.0.0"), 16, 16, 0),
+	}
+	if !table.Update("cache1", true, roas, nil) {
+		t.Fatal("ROA table not changed after adding ROAs")
+	}
+
+	tests := []struct {
+		prefix   string
+		length   uint8
+		originAS uint32
+		state    ValidationState
+	}{
+		{"10.1.1.0", 24, 65001, ValidationStateValid},
+		{"10.1.0.0", 16, 65001, ValidationStateValid},
+		{"10.1.1.128", 25, 65001, ValidationStateInvalid},
+		{"10.1.1.0", 24, 65009, ValidationStateInvalid},
+		{"10.1.2.0", 24, 65002, ValidationStateValid},
+		{"10.1.2.0", 24, 65001, ValidationStateValid},
+		{"10.0.0.0", 8, 65001, ValidationStateNotFound},
+		{"20.1.1.0", 24, 65001, ValidationStateNotFound},
+		{"2001:db8:1::", 48, 65003, ValidationStateValid},
+		{"2001:db8:1::", 64, 65003, ValidationStateInvalid},
+		{"2001:db9::", 32, 65003, ValidationStateNotFound},
+		{"192.168.0.0", 16, 0, ValidationStateInvalid},
+		{"10.1.1.0", 24, 0, ValidationStateInvalid},
+	}
+	for _, test := range tests {
+		state := table.Validate(net.ParseIP(test.prefix), test.length, test.originAS)
+		if state != test.state {
+			t.Error("Validate", test.prefix, test.length, "AS", test.originAS, "returned", state, "expected",
+				test.state)
+		}
+	}
+
+	// The same ROA from two caches is removed only when both caches withdraw it
+	generation := table.GetGeneration()
+	table.Update("cache2", true, roas[:1], nil)
+	table.Update("cache1", false, nil, roas[:1])
+	if table.GetGeneration() == generation {
+		t.Fatal("ROA table generation not changed after update")
+	}
+	if state := table.Validate(net.ParseIP("10.1.1.0"), 24, 65001); state != ValidationStateValid {
+		t.Fatal("Validate returned", state, "after one of the caches withdrew the ROA")
+	}
+	table.RemoveSource("cache2")
+	if state := table.Validate(net.ParseIP("10.1.1.0"), 24, 65001); state != ValidationStateNotFound {
+		t.Fatal("Validate returned", state, "after all the caches withdrew the ROA")
+	}
+	if table.GetCount() != len(roas)-1 {
+		t.Fatal("ROA table has", table.GetCount(), "ROAs, expected", len(roas)-1)
+	}
+}
+
+type testCache struct {
+	t        *testing.T
+	listener net.Listener
+	conn     net.Conn
+}
+
+func newTestCache(t *testing.T) *testCache {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen, error:", err)
+	}
+	return &testCache{t: t, listener: listener}
+}
+
+func (c *testCache) accept() {
+	conn, err := c.listener.Accept()
+	if err != nil {
+		c.t.Fatal("Failed to accept connection, error:", err)
+	}
+	c.conn = conn
+}
+
+func (c *testCache) expect(pduType uint8) *RTRMessage {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg, err := ReadRTRMessage(c.conn)
+	if err != nil {
+		c.t.Fatal("Failed to read RTR message, error:", err)
+	}
+	if msg.Header.Type != pduType {
+		c.t.Fatal("Received RTR PDU type", msg.Header.Type, "expected", pduType)
+	}
+	return msg
+}
+
+func (c *testCache) send(msgs ...*RTRMessage) {
+	for _, msg := range msgs {
+		if err := writeRTRMessage(c.conn, msg); err != nil {
+			c.t.Fatal("Failed to send RTR message, error:", err)
+		}
+	}
+}
+
+func (c *testCache) close() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.listener.Close()
+}
+
+func waitForUpdate(t *testing.T, updateCh chan bool) {
+	select {
+	case <-updateCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for ROA table update")
+	}
+}
+
+func TestRTRClient(t *testing.T) {
+	logger := getLogger(t)
+	cache := newTestCache(t)
+	defer cache.close()
+
+	table := NewROATable()
+	updateCh := make(chan bool, 1)
+	client := NewRTRClient(logger, cache.listener.Addr().String(), table, updateCh)
+	client.Start()
+	defer client.Stop()
+
+	roa1 := NewROA(net.ParseIP("10.1.0.0"), 16, 24, 65001)
+	roa2 := NewROA(net.ParseIP("2001:db8::"), 32, 48, 65002)
+	cache.accept()
+	cache.expect(RTRPDUResetQuery)
+	cache.send(NewRTRCacheResponseMessage(RTRVersion1, 7),
+		NewRTRPrefixMessage(RTRVersion1, true, roa1),
+		NewRTRPrefixMessage(RTRVersion1, true, roa2),
+		NewRTREndOfDataMessage(RTRVersion1, 7, 1, 3600, 600, 7200))
+	waitForUpdate(t, updateCh)
+	if state := table.Validate(net.ParseIP("10.1.1.0"), 24, 65001); state != ValidationStateValid {
+		t.Fatal("Validate returned", state, "after reset query, expected Valid")
+	}
+	if table.GetCount() != 2 {
+		t.Fatal("ROA table has", table.GetCount(), "ROAs, expected 2")
+	}
+
+	cache.send(NewRTRSerialNotifyMessage(RTRVersion1, 7, 2))
+	query := cache.expect(RTRPDUSerialQuery)
+	if query.Header.SessionId != 7 || query.Body.(*RTRSerial).Serial != 1 {
+		t.Fatal("Serial query session id", query.Header.SessionId, "serial", query.Body.(*RTRSerial).Serial,
+			"expected session id 7 serial 1")
+	}
+	cache.send(NewRTRCacheResponseMessage(RTRVersion1, 7),
+		NewRTRPrefixMessage(RTRVersion1, false, roa1),
+		NewRTREndOfDataMessage(RTRVersion1, 7, 2, 3600, 600, 7200))
+	waitForUpdate(t, updateCh)
+	if state := table.Validate(net.ParseIP("10.1.1.0"), 24, 65001); state != ValidationStateNotFound {
+		t.Fatal("Validate returned", state, "after withdraw, expected NotFound")
+	}
+	if state := table.Validate(net.ParseIP("2001:db8::"), 64, 65002); state != ValidationStateInvalid {
+		t.Fatal("Validate returned", state, "for IPv6 prefix longer than max length, expected Invalid")
+	}
+
+	cache.send(NewRTRCacheResetMessage(RTRVersion1))
+	cache.expect(RTRPDUResetQuery)
+	cache.send(NewRTRCacheResponseMessage(RTRVersion1, 8),
+		NewRTRPrefixMessage(RTRVersion1, true, roa1),
+		NewRTREndOfDataMessage(RTRVersion1, 8, 1, 3600, 600, 7200))
+	waitForUpdate(t, updateCh)
+	if table.GetCount() != 1 {
+		t.Fatal("ROA table has", table.GetCount(), "ROAs after cache reset, expected 1")
+	}
+	if state := table.Validate(net.ParseIP("10.1.1.0"), 24, 65001); state != ValidationStateValid {
+		t.Fatal("Validate returned", state, "after cache reset, expected Valid")
+	}
+}
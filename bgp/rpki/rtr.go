@@ -0,0 +1,391 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// rtr.go
+package rpki
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	RTRVersion0 uint8 = 0
+	RTRVersion1 uint8 = 1
+)
+
+const RTRHeaderLen = 8
+
+const (
+	RTRPDUSerialNotify  uint8 = 0
+	RTRPDUSerialQuery   uint8 = 1
+	RTRPDUResetQuery    uint8 = 2
+	RTRPDUCacheResponse uint8 = 3
+	RTRPDUIPv4Prefix    uint8 = 4
+	RTRPDUIPv6Prefix    uint8 = 6
+	RTRPDUEndOfData     uint8 = 7
+	RTRPDUCacheReset    uint8 = 8
+	RTRPDURouterKey     uint8 = 9
+	RTRPDUErrorReport   uint8 = 10
+)
+
+const (
+	RTRErrCorruptData uint16 = iota
+	RTRErrInternalError
+	RTRErrNoDataAvailable
+	RTRErrInvalidRequest
+	RTRErrUnsupportedVersion
+	RTRErrUnsupportedPDUType
+	RTRErrWithdrawUnknown
+	RTRErrDuplicateAnnounce
+	RTRErrUnexpectedVersion
+)
+
+const (
+	RTRIPv4PrefixLen      = 20
+	RTRIPv6PrefixLen      = 32
+	RTREndOfDataV0Len     = 12
+	RTREndOfDataV1Len     = 24
+	RTRMaxPDULen          = 65536
+	RTRPrefixFlagAnnounce = 0x01
+)
+
+type RTRHeader struct {
+	Version   uint8
+	Type      uint8
+	SessionId uint16
+	Length    uint32
+}
+
+func (header *RTRHeader) Encode(pkt []byte) {
+	pkt[0] = header.Version
+	pkt[1] = header.Type
+	binary.BigEndian.PutUint16(pkt[2:4], header.SessionId)
+	binary.BigEndian.PutUint32(pkt[4:8], header.Length)
+}
+
+func (header *RTRHeader) Decode(pkt []byte) error {
+	if len(pkt) < RTRHeaderLen {
+		return errors.New(fmt.Sprintf("RTR header is %d bytes long", len(pkt)))
+	}
+	header.Version = pkt[0]
+	header.Type = pkt[1]
+	header.SessionId = binary.BigEndian.Uint16(pkt[2:4])
+	header.Length = binary.BigEndian.Uint32(pkt[4:8])
+	if header.Length < RTRHeaderLen || header.Length > RTRMaxPDULen {
+		return errors.New(fmt.Sprintf("RTR PDU length %d is not valid", header.Length))
+	}
+	return nil
+}
+
+type RTRBody interface {
+	Encode(*RTRHeader) ([]byte, error)
+	Decode(*RTRHeader, []byte) error
+}
+
+// RTRSerial is the body of the Serial Notify, Serial Query PDUs
+type RTRSerial struct {
+	Serial uint32
+}
+
+func (s *RTRSerial) Encode(header *RTRHeader) ([]byte, error) {
+	pkt := make([]byte, 4)
+	binary.BigEndian.PutUint32(pkt, s.Serial)
+	return pkt, nil
+}
+
+func (s *RTRSerial) Decode(header *RTRHeader, pkt []byte) error {
+	if len(pkt) != 4 {
+		return errors.New(fmt.Sprintf("RTR PDU type %d body is %d bytes long", header.Type, len(pkt)))
+	}
+	s.Serial = binary.BigEndian.Uint32(pkt)
+	return nil
+}
+
+// RTREmpty is the body of the Reset Query, Cache Response and Cache Reset PDUs
+type RTREmpty struct {
+}
+
+func (e *RTREmpty) Encode(header *RTRHeader) ([]byte, error) {
+	return make([]byte, 0), nil
+}
+
+func (e *RTREmpty) Decode(header *RTRHeader, pkt []byte) error {
+	if len(pkt) != 0 {
+		return errors.New(fmt.Sprintf("RTR PDU type %d body is %d bytes long", header.Type, len(pkt)))
+	}
+	return nil
+}
+
+type RTRPrefix struct {
+	Flags     uint8
+	Length    uint8
+	MaxLength uint8
+	Prefix    net.IP
+	AS        uint32
+}
+
+func (p *RTRPrefix) IsAnnounce() bool {
+	return p.Flags&RTRPrefixFlagAnnounce != 0
+}
+
+func (p *RTRPrefix) GetROA() ROA {
+	return NewROA(p.Prefix, p.Length, p.MaxLength, p.AS)
+}
+
+func (p *RTRPrefix) Encode(header *RTRHeader) ([]byte, error) {
+	ip := p.Prefix.To4()
+	if header.Type == RTRPDUIPv6Prefix {
+		ip = p.Prefix.To16()
+	}
+	if ip == nil {
+		return nil, errors.New(fmt.Sprintf("RTR prefix %s does not match PDU type %d", p.Prefix, header.Type))
+	}
+
+	pkt := make([]byte, 8+len(ip))
+	pkt[0] = p.Flags
+	pkt[1] = p.Length
+	pkt[2] = p.MaxLength
+	copy(pkt[4:], ip)
+	binary.BigEndian.PutUint32(pkt[4+len(ip):], p.AS)
+	return pkt, nil
+}
+
+func (p *RTRPrefix) Decode(header *RTRHeader, pkt []byte) error {
+	ipLen := net.IPv4len
+	maxLen := uint8(32)
+	if header.Type == RTRPDUIPv6Prefix {
+		ipLen = net.IPv6len
+		maxLen = 128
+	}
+	if len(pkt) != 8+ipLen {
+		return errors.New(fmt.Sprintf("RTR prefix PDU body is %d bytes long", len(pkt)))
+	}
+
+	p.Flags = pkt[0]
+	p.Length = pkt[1]
+	p.MaxLength = pkt[2]
+	p.Prefix = make(net.IP, ipLen)
+	copy(p.Prefix, pkt[4:4+ipLen])
+	p.AS = binary.BigEndian.Uint32(pkt[4+ipLen:])
+	if p.Length > p.MaxLength || p.MaxLength > maxLen {
+		return errors.New(fmt.Sprintf("RTR prefix %s length %d max length %d is not valid", p.Prefix, p.Length,
+			p.MaxLength))
+	}
+	return nil
+}
+
+type RTREndOfData struct {
+	Serial  uint32
+	Refresh uint32
+	Retry   uint32
+	Expire  uint32
+}
+
+func (e *RTREndOfData) Encode(header *RTRHeader) ([]byte, error) {
+	if header.Version == RTRVersion0 {
+		pkt := make([]byte, 4)
+		binary.BigEndian.PutUint32(pkt, e.Serial)
+		return pkt, nil
+	}
+
+	pkt := make([]byte, 16)
+	binary.BigEndian.PutUint32(pkt[0:4], e.Serial)
+	binary.BigEndian.PutUint32(pkt[4:8], e.Refresh)
+	binary.BigEndian.PutUint32(pkt[8:12], e.Retry)
+	binary.BigEndian.PutUint32(pkt[12:16], e.Expire)
+	return pkt, nil
+}
+
+func (e *RTREndOfData) Decode(header *RTRHeader, pkt []byte) error {
+	if header.Version == RTRVersion0 && len(pkt) == RTREndOfDataV0Len-RTRHeaderLen {
+		e.Serial = binary.BigEndian.Uint32(pkt[0:4])
+		return nil
+	}
+	if header.Version == RTRVersion0 || len(pkt) != RTREndOfDataV1Len-RTRHeaderLen {
+		return errors.New(fmt.Sprintf("RTR End of Data PDU version %d body is %d bytes long", header.Version,
+			len(pkt)))
+	}
+
+	e.Serial = binary.BigEndian.Uint32(pkt[0:4])
+	e.Refresh = binary.BigEndian.Uint32(pkt[4:8])
+	e.Retry = binary.BigEndian.Uint32(pkt[8:12])
+	e.Expire = binary.BigEndian.Uint32(pkt[12:16])
+	return nil
+}
+
+// RTRErrorReport carries the error code in the session id field of the header
+type RTRErrorReport struct {
+	PDU  []byte
+	Text string
+}
+
+func (e *RTRErrorReport) Encode(header *RTRHeader) ([]byte, error) {
+	pkt := make([]byte, 8+len(e.PDU)+len(e.Text))
+	binary.BigEndian.PutUint32(pkt[0:4], uint32(len(e.PDU)))
+	copy(pkt[4:], e.PDU)
+	binary.BigEndian.PutUint32(pkt[4+len(e.PDU):], uint32(len(e.Text)))
+	copy(pkt[8+len(e.PDU):], e.Text)
+	return pkt, nil
+}
+
+func (e *RTRErrorReport) Decode(header *RTRHeader, pkt []byte) error {
+	if len(pkt) < 8 {
+		return errors.New(fmt.Sprintf("RTR Error Report PDU body is %d bytes long", len(pkt)))
+	}
+
+	pduLen := binary.BigEndian.Uint32(pkt[0:4])
+	if uint32(len(pkt)) < 8+pduLen {
+		return errors.New(fmt.Sprintf("RTR Error Report encapsulated PDU length %d is not valid", pduLen))
+	}
+	e.PDU = pkt[4 : 4+pduLen]
+
+	textLen := binary.BigEndian.Uint32(pkt[4+pduLen : 8+pduLen])
+	if uint32(len(pkt)) != 8+pduLen+textLen {
+		return errors.New(fmt.Sprintf("RTR Error Report text length %d is not valid", textLen))
+	}
+	e.Text = string(pkt[8+pduLen:])
+	return nil
+}
+
+type RTRUnknown struct {
+	Data []byte
+}
+
+func (u *RTRUnknown) Encode(header *RTRHeader) ([]byte, error) {
+	return u.Data, nil
+}
+
+func (u *RTRUnknown) Decode(header *RTRHeader, pkt []byte) error {
+	u.Data = pkt
+	return nil
+}
+
+type RTRMessage struct {
+	Header RTRHeader
+	Body   RTRBody
+}
+
+func (msg *RTRMessage) Encode() ([]byte, error) {
+	body, err := msg.Body.Encode(&msg.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Header.Length = uint32(RTRHeaderLen + len(body))
+	pkt := make([]byte, RTRHeaderLen)
+	msg.Header.Encode(pkt)
+	return append(pkt, body...), nil
+}
+
+func (msg *RTRMessage) Decode(header *RTRHeader, pkt []byte) error {
+	msg.Header = *header
+	switch header.Type {
+	case RTRPDUSerialNotify, RTRPDUSerialQuery:
+		msg.Body = &RTRSerial{}
+
+	case RTRPDUResetQuery, RTRPDUCacheResponse, RTRPDUCacheReset:
+		msg.Body = &RTREmpty{}
+
+	case RTRPDUIPv4Prefix, RTRPDUIPv6Prefix:
+		msg.Body = &RTRPrefix{}
+
+	case RTRPDUEndOfData:
+		msg.Body = &RTREndOfData{}
+
+	case RTRPDUErrorReport:
+		msg.Body = &RTRErrorReport{}
+
+	default:
+		msg.Body = &RTRUnknown{}
+	}
+
+	return msg.Body.Decode(header, pkt)
+}
+
+func NewRTRSerialNotifyMessage(version uint8, sessionId uint16, serial uint32) *RTRMessage {
+	return &RTRMessage{
+		Header: RTRHeader{Version: version, Type: RTRPDUSerialNotify, SessionId: sessionId},
+		Body:   &RTRSerial{serial},
+	}
+}
+
+func NewRTRSerialQueryMessage(version uint8, sessionId uint16, serial uint32) *RTRMessage {
+	return &RTRMessage{
+		Header: RTRHeader{Version: version, Type: RTRPDUSerialQuery, SessionId: sessionId},
+		Body:   &RTRSerial{serial},
+	}
+}
+
+func NewRTRResetQueryMessage(version uint8) *RTRMessage {
+	return &RTRMessage{
+		Header: RTRHeader{Version: version, Type: RTRPDUResetQuery},
+		Body:   &RTREmpty{},
+	}
+}
+
+func NewRTRCacheResponseMessage(version uint8, sessionId uint16) *RTRMessage {
+	return &RTRMessage{
+		Header: RTRHeader{Version: version, Type: RTRPDUCacheResponse, SessionId: sessionId},
+		Body:   &RTREmpty{},
+	}
+}
+
+func NewRTRCacheResetMessage(version uint8) *RTRMessage {
+	return &RTRMessage{
+		Header: RTRHeader{Version: version, Type: RTRPDUCacheReset},
+		Body:   &RTREmpty{},
+	}
+}
+
+func NewRTRPrefixMessage(version uint8, announce bool, roa ROA) *RTRMessage {
+	pduType := RTRPDUIPv4Prefix
+	if roa.Prefix.To4() == nil {
+		pduType = RTRPDUIPv6Prefix
+	}
+
+	var flags uint8
+	if announce {
+		flags = RTRPrefixFlagAnnounce
+	}
+	return &RTRMessage{
+		Header: RTRHeader{Version: version, Type: pduType},
+		Body:   &RTRPrefix{flags, roa.Length, roa.MaxLength, roa.Prefix, roa.AS},
+	}
+}
+
+func NewRTREndOfDataMessage(version uint8, sessionId uint16, serial, refresh, retry, expire uint32) *RTRMessage {
+	return &RTRMessage{
+		Header: RTRHeader{Version: version, Type: RTRPDUEndOfData, SessionId: sessionId},
+		Body:   &RTREndOfData{serial, refresh, retry, expire},
+	}
+}
+
+func NewRTRErrorReportMessage(version uint8, code uint16, pdu []byte, text string) *RTRMessage {
+	return &RTRMessage{
+		Header: RTRHeader{Version: version, Type: RTRPDUErrorReport, SessionId: code},
+		Body:   &RTRErrorReport{pdu, text},
+	}
+}
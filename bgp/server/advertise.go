@@ -0,0 +1,263 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// advertise.go
+package server
+
+import (
+	"l3/bgp/packet"
+	bgprib "l3/bgp/rib"
+	"net"
+)
+
+func (p *Peer) matchRouteMap(routeMap string, dest *bgprib.Destination) bool {
+	return p.server.ribOutPE.MatchPrefix(routeMap, dest.NLRI.GetCIDR())
+}
+
+// getConditionRouteMaps returns the route maps that are matched against the Loc-RIB for the conditional
+// advertisement and the default originate
+func (p *Peer) getConditionRouteMaps() []string {
+	routeMaps := make([]string, 0)
+	conf := &p.NeighborConf.RunningConf
+	if p.isAdvertiseMapConfigured() {
+		if conf.ExistMap != "" {
+			routeMaps = append(routeMaps, conf.ExistMap)
+		} else {
+			routeMaps = append(routeMaps, conf.NonExistMap)
+		}
+	}
+	if conf.DefaultOriginate && conf.DefaultOriginateMap != "" {
+		routeMaps = append(routeMaps, conf.DefaultOriginateMap)
+	}
+	return routeMaps
+}
+
+// updateLocRibMatches tracks the Loc-RIB prefixes that match the condition route maps. The Loc-RIB is walked only
+// when a route map is first used, after that the matches are updated from the updated and withdrawn destinations.
+func (p *Peer) updateLocRibMatches(updated map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+	withdrawn []*bgprib.Destination) {
+	routeMaps := p.getConditionRouteMaps()
+	for routeMap, _ := range p.locRibMatches {
+		found := false
+		for _, name := range routeMaps {
+			if name == routeMap {
+				found = true
+				break
+			}
+		}
+		if !found {
+			delete(p.locRibMatches, routeMap)
+		}
+	}
+
+	for _, routeMap := range routeMaps {
+		matches, ok := p.locRibMatches[routeMap]
+		if !ok {
+			matches = make(map[string]bool)
+			for _, pathDestMap := range p.locRib.GetLocRib() {
+				for _, destinations := range pathDestMap {
+					for _, dest := range destinations {
+						if dest != nil && p.matchRouteMap(routeMap, dest) {
+							matches[dest.NLRI.GetCIDR()] = true
+						}
+					}
+				}
+			}
+			p.locRibMatches[routeMap] = matches
+			continue
+		}
+
+		for _, dest := range withdrawn {
+			if dest != nil {
+				delete(matches, dest.NLRI.GetCIDR())
+			}
+		}
+		for _, pathDestMap := range updated {
+			for _, destinations := range pathDestMap {
+				for _, dest := range destinations {
+					if dest == nil {
+						continue
+					}
+					if dest.LocRibPath == nil {
+						delete(matches, dest.NLRI.GetCIDR())
+					} else if p.matchRouteMap(routeMap, dest) {
+						matches[dest.NLRI.GetCIDR()] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// ProcessPolicyUpdate clears the Loc-RIB matches of the condition route maps when a policy changes. The matches are
+// built again with the changed route maps and the conditional advertisement and the default route are evaluated.
+func (s *BGPServer) ProcessPolicyUpdate() {
+	for _, peer := range s.PeerMap {
+		peer.locRibMatches = make(map[string]map[string]bool)
+		if len(peer.getConditionRouteMaps()) > 0 && peer.NeighborConf.Neighbor.Transport.Config.LocalAddress != nil {
+			peer.SendUpdate(make(map[uint32]map[*bgprib.Path][]*bgprib.Destination), make([]*bgprib.Destination, 0),
+				make([]*bgprib.Destination, 0))
+		}
+	}
+}
+
+func (p *Peer) locRibHasMatch(routeMap string) bool {
+	return len(p.locRibMatches[routeMap]) > 0
+}
+
+func (p *Peer) isAdvertiseMapConfigured() bool {
+	return p.NeighborConf.RunningConf.AdvertiseMap != "" &&
+		(p.NeighborConf.RunningConf.ExistMap != "" || p.NeighborConf.RunningConf.NonExistMap != "")
+}
+
+// Prefixes matching the advertise map are withheld unless the exist map matches a Loc-RIB route, or the
+// non-exist map matches none
+func (p *Peer) isConditionallyWithheld(dest *bgprib.Destination) bool {
+	return p.advertiseWithheld && p.matchRouteMap(p.NeighborConf.RunningConf.AdvertiseMap, dest)
+}
+
+func (p *Peer) evaluateConditionalAdvertisement(
+	updated map[uint32]map[*bgprib.Path][]*bgprib.Destination) map[uint32]map[*bgprib.Path][]*bgprib.Destination {
+	if !p.isAdvertiseMapConfigured() {
+		p.advertiseWithheld = false
+		return updated
+	}
+
+	withheld := false
+	if p.NeighborConf.RunningConf.ExistMap != "" {
+		withheld = !p.locRibHasMatch(p.NeighborConf.RunningConf.ExistMap)
+	} else {
+		withheld = p.locRibHasMatch(p.NeighborConf.RunningConf.NonExistMap)
+	}
+
+	if withheld == p.advertiseWithheld {
+		return updated
+	}
+
+	p.logger.Infof("Neighbor %s: Conditional advertisement changed, advertise map %s withheld %t",
+		p.NeighborConf.Neighbor.NeighborAddress, p.NeighborConf.RunningConf.AdvertiseMap, withheld)
+	p.advertiseWithheld = withheld
+
+	// Re-evaluate the advertise map prefixes so that they are advertised or withdrawn. The updated map is shared
+	// with the other peers and is not modified.
+	condUpdated := make(map[uint32]map[*bgprib.Path][]*bgprib.Destination)
+	for protoFamily, pathDestMap := range updated {
+		condUpdated[protoFamily] = make(map[*bgprib.Path][]*bgprib.Destination)
+		for path, destinations := range pathDestMap {
+			condUpdated[protoFamily][path] = append(condUpdated[protoFamily][path], destinations...)
+		}
+	}
+
+	for protoFamily, pathDestMap := range p.locRib.GetLocRib() {
+		for path, destinations := range pathDestMap {
+			for _, dest := range destinations {
+				if !p.matchRouteMap(p.NeighborConf.RunningConf.AdvertiseMap, dest) {
+					continue
+				}
+				if _, ok := condUpdated[protoFamily]; !ok {
+					condUpdated[protoFamily] = make(map[*bgprib.Path][]*bgprib.Destination)
+				}
+				condUpdated[protoFamily][path] = append(condUpdated[protoFamily][path], dest)
+			}
+		}
+	}
+	return condUpdated
+}
+
+func (p *Peer) constructDefaultRoutePath(protoFamily uint32) *bgprib.Path {
+	pathAttrs := make([]packet.BGPPathAttr, 0)
+	pathAttrs = append(pathAttrs, packet.NewBGPPathAttrOrigin(packet.BGPPathAttrOriginIGP))
+	pathAttrs = append(pathAttrs, packet.NewBGPPathAttrASPath())
+	if protoFamily == packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast) &&
+		!p.NeighborConf.IsExtendedNextHop(protoFamily) {
+		nextHop := packet.NewBGPPathAttrNextHop()
+		nextHop.Value = p.NeighborConf.Neighbor.Transport.Config.LocalAddress
+		pathAttrs = append(pathAttrs, nextHop)
+		return bgprib.NewPath(p.locRib, nil, pathAttrs, nil, bgprib.RouteTypeConnected)
+	}
+
+	mpReach := packet.ConstructIPv6MPReachNLRI(protoFamily, p.NeighborConf.Neighbor.Transport.Config.LocalAddress,
+		nil, nil)
+	return bgprib.NewPath(p.locRib, nil, pathAttrs, mpReach, bgprib.RouteTypeConnected)
+}
+
+func (p *Peer) sendDefaultRoute(protoFamily uint32, originate bool) {
+	afi, _ := packet.GetAfiSafi(protoFamily)
+	var nlri packet.NLRI
+	if afi == packet.AfiIP {
+		nlri = packet.NewIPPrefix(net.IPv4zero, 0)
+	} else {
+		nlri = packet.NewIPPrefix(net.IPv6zero, 0)
+	}
+
+	var updateMsg *packet.BGPMessage
+	classicNLRI := protoFamily == packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast) &&
+		!p.NeighborConf.IsExtendedNextHop(protoFamily)
+	if !originate {
+		p.logger.Infof("Neighbor %s: Withdraw default route for protocol family %d",
+			p.NeighborConf.Neighbor.NeighborAddress, protoFamily)
+		if classicNLRI {
+			updateMsg = packet.NewBGPUpdateMessage([]packet.NLRI{nlri}, make([]packet.BGPPathAttr, 0), nil)
+		} else {
+			mpUnreachNLRI := packet.ConstructMPUnreachNLRIFromProtoFamily(protoFamily, []packet.NLRI{nlri})
+			updateMsg = packet.NewBGPUpdateMessage(nil, []packet.BGPPathAttr{mpUnreachNLRI}, nil)
+		}
+		p.sendUpdateMsg(updateMsg, nil)
+		return
+	}
+
+	p.logger.Infof("Neighbor %s: Originate default route for protocol family %d",
+		p.NeighborConf.Neighbor.NeighborAddress, protoFamily)
+	path := p.constructDefaultRoutePath(protoFamily)
+	if classicNLRI {
+		updateMsg = packet.NewBGPUpdateMessage(nil, packet.CopyPathAttrs(path.PathAttrs), []packet.NLRI{nlri})
+	} else {
+		mpReachNLRI := packet.ConstructIPv6MPReachNLRI(protoFamily,
+			p.NeighborConf.Neighbor.Transport.Config.LocalAddress, nil, []packet.NLRI{nlri})
+		pa := packet.AddMPReachNLRIToPathAttrs(packet.CopyPathAttrs(path.PathAttrs), mpReachNLRI)
+		updateMsg = packet.NewBGPUpdateMessage(nil, pa, nil)
+	}
+	p.sendUpdateMsg(updateMsg, path)
+}
+
+// Default route is originated to the peer regardless of Loc-RIB. With a route map it is originated only when a
+// Loc-RIB route matches the route map.
+func (p *Peer) isDefaultOriginated() bool {
+	originate := p.NeighborConf.RunningConf.DefaultOriginate
+	if originate && p.NeighborConf.RunningConf.DefaultOriginateMap != "" {
+		originate = p.locRibHasMatch(p.NeighborConf.RunningConf.DefaultOriginateMap)
+	}
+	return originate
+}
+
+func (p *Peer) evaluateDefaultOriginate() {
+	originate := p.isDefaultOriginated()
+	for _, afi := range []packet.AFI{packet.AfiIP, packet.AfiIP6} {
+		protoFamily := packet.GetProtocolFamily(afi, packet.SafiUnicast)
+		if !p.NeighborConf.CanSendFamily(protoFamily) || p.defaultOriginated[protoFamily] == originate {
+			continue
+		}
+		p.sendDefaultRoute(protoFamily, originate)
+		p.defaultOriginated[protoFamily] = originate
+	}
+}
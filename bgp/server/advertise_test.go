@@ -0,0 +1,242 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// advertise_test.go
+package server
+
+import (
+	"l3/bgp/baseobjects"
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	bgppolicy "l3/bgp/policy"
+	bgprib "l3/bgp/rib"
+	"net"
+	"testing"
+	"utils/logging"
+	utilspolicy "utils/policy"
+)
+
+var ipv4Unicast = packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
+
+func getAdvertisePeer(t *testing.T, peerConf config.NeighborConfig) *Peer {
+	logger, err := logging.NewLogger("bgpd", "BGP", true)
+	if err != nil {
+		t.Fatal("Failed to start the logger. Exiting!!")
+	}
+
+	s := &BGPServer{
+		logger:   logger,
+		ribOutPE: bgppolicy.NewAdjRibPolicyEngine(logger),
+	}
+	gConf := &config.GlobalConfig{}
+	locRib := bgprib.NewLocRib(logger, nil, nil, gConf)
+	peerConf.NeighborAddress = net.ParseIP("192.168.0.1")
+	return &Peer{
+		server:            s,
+		logger:            logger,
+		locRib:            locRib,
+		importRib:         locRib,
+		NeighborConf:      base.NewNeighborConf(logger, gConf, nil, peerConf),
+		ribOut:            make(map[uint32]map[string]*bgprib.AdjRIBRoute),
+		defaultOriginated: make(map[uint32]bool),
+		locRibMatches:     make(map[string]map[string]bool),
+	}
+}
+
+func addRouteMap(t *testing.T, p *Peer, name, prefix string) {
+	pe := p.server.ribOutPE
+	cond := utilspolicy.PolicyConditionConfig{
+		Name:          name,
+		ConditionType: "MatchDstIpPrefix",
+		MatchDstIpPrefixConditionInfo: utilspolicy.PolicyDstIpMatchPrefixSetCondition{
+			Prefix: utilspolicy.PolicyPrefix{
+				IpPrefix:        prefix,
+				MasklengthRange: "exact",
+			},
+		},
+	}
+	if _, err := pe.CreatePolicyCondition(cond); err != nil {
+		t.Fatal("Failed to create policy condition", name, "error:", err)
+	}
+
+	stmt := utilspolicy.PolicyStmtConfig{Name: name, MatchConditions: "all", Conditions: []string{name},
+		Actions: []string{"permit"}}
+	if err := pe.CreatePolicyStmt(stmt); err != nil {
+		t.Fatal("Failed to create policy statement", name, "error:", err)
+	}
+
+	def := utilspolicy.PolicyDefinitionConfig{Name: name, Precedence: 1, MatchType: "any", PolicyType: "BGP"}
+	def.PolicyDefinitionStatements = []utilspolicy.PolicyDefinitionStmtPrecedence{{Precedence: 1, Statement: name}}
+	if err := pe.CreatePolicyDefinition(def); err != nil {
+		t.Fatal("Failed to create policy definition", name, "error:", err)
+	}
+}
+
+// addLocRibDest adds a destination with a best path to the Loc-RIB and returns the update for the peer
+func addLocRibDest(p *Peer, ip string, length uint8) (map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+	*bgprib.Destination) {
+	dest, _ := p.locRib.GetDest(packet.NewIPPrefix(net.ParseIP(ip), length), ipv4Unicast, true)
+	pathAttrs := []packet.BGPPathAttr{packet.NewBGPPathAttrOrigin(packet.BGPPathAttrOriginIGP)}
+	path := bgprib.NewPath(p.locRib, nil, pathAttrs, nil, bgprib.RouteTypeConnected)
+	dest.LocRibPath = path
+	updated := map[uint32]map[*bgprib.Path][]*bgprib.Destination{ipv4Unicast: {path: {dest}}}
+	return updated, dest
+}
+
+func removeLocRibDest(dest *bgprib.Destination) []*bgprib.Destination {
+	dest.LocRibPath = nil
+	return []*bgprib.Destination{dest}
+}
+
+func evaluateAdvertisement(p *Peer, updated map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+	withdrawn []*bgprib.Destination) map[uint32]map[*bgprib.Path][]*bgprib.Destination {
+	if updated == nil {
+		updated = make(map[uint32]map[*bgprib.Path][]*bgprib.Destination)
+	}
+	p.updateLocRibMatches(updated, withdrawn)
+	return p.evaluateConditionalAdvertisement(updated)
+}
+
+func countDests(updated map[uint32]map[*bgprib.Path][]*bgprib.Destination) int {
+	count := 0
+	for _, pathDestMap := range updated {
+		for _, destinations := range pathDestMap {
+			count += len(destinations)
+		}
+	}
+	return count
+}
+
+func TestConditionalAdvertisementExistMap(t *testing.T) {
+	p := getAdvertisePeer(t, config.NeighborConfig{AdvertiseMap: "advertise", ExistMap: "exist"})
+	addRouteMap(t, p, "advertise", "20.1.1.0/24")
+	addRouteMap(t, p, "exist", "10.1.1.0/24")
+	addLocRibDest(p, "20.1.1.0", 24)
+
+	evaluateAdvertisement(p, nil, nil)
+	if !p.advertiseWithheld {
+		t.Fatal("Advertise map is not withheld when the exist map has no match in the Loc-RIB")
+	}
+
+	// Unrelated prefix does not change the state
+	updated, _ := addLocRibDest(p, "30.1.1.0", 24)
+	if condUpdated := evaluateAdvertisement(p, updated, nil); !p.advertiseWithheld || countDests(condUpdated) != 1 {
+		t.Fatal("Advertise map state changed by a prefix that does not match the exist map")
+	}
+
+	updated, existDest := addLocRibDest(p, "10.1.1.0", 24)
+	condUpdated := evaluateAdvertisement(p, updated, nil)
+	if p.advertiseWithheld {
+		t.Fatal("Advertise map is withheld after the exist map prefix is added to the Loc-RIB")
+	}
+	if countDests(condUpdated) != 2 {
+		t.Error("Expected the exist map prefix and the advertise map prefix in the update, found",
+			countDests(condUpdated))
+	}
+	if p.isConditionallyWithheld(existDest) {
+		t.Error("Exist map prefix is withheld")
+	}
+
+	condUpdated = evaluateAdvertisement(p, nil, removeLocRibDest(existDest))
+	if !p.advertiseWithheld {
+		t.Fatal("Advertise map is not withheld after the exist map prefix is withdrawn from the Loc-RIB")
+	}
+	if countDests(condUpdated) != 1 {
+		t.Error("Expected the advertise map prefix in the update to withdraw it, found", countDests(condUpdated))
+	}
+}
+
+func TestConditionalAdvertisementNonExistMap(t *testing.T) {
+	p := getAdvertisePeer(t, config.NeighborConfig{AdvertiseMap: "advertise", NonExistMap: "nonexist"})
+	addRouteMap(t, p, "advertise", "20.1.1.0/24")
+	addRouteMap(t, p, "nonexist", "10.1.1.0/24")
+	_, advertiseDest := addLocRibDest(p, "20.1.1.0", 24)
+
+	evaluateAdvertisement(p, nil, nil)
+	if p.advertiseWithheld || p.isConditionallyWithheld(advertiseDest) {
+		t.Fatal("Advertise map is withheld when the non-exist map has no match in the Loc-RIB")
+	}
+
+	updated, nonExistDest := addLocRibDest(p, "10.1.1.0", 24)
+	evaluateAdvertisement(p, updated, nil)
+	if !p.advertiseWithheld || !p.isConditionallyWithheld(advertiseDest) {
+		t.Fatal("Advertise map is not withheld after the non-exist map prefix is added to the Loc-RIB")
+	}
+
+	evaluateAdvertisement(p, nil, removeLocRibDest(nonExistDest))
+	if p.advertiseWithheld {
+		t.Fatal("Advertise map is withheld after the non-exist map prefix is withdrawn from the Loc-RIB")
+	}
+}
+
+func TestConditionalAdvertisementPolicyUpdate(t *testing.T) {
+	p := getAdvertisePeer(t, config.NeighborConfig{AdvertiseMap: "advertise", ExistMap: "exist"})
+	p.server.PeerMap = map[string]*Peer{p.NeighborConf.RunningConf.NeighborAddress.String(): p}
+	addRouteMap(t, p, "advertise", "20.1.1.0/24")
+	addLocRibDest(p, "10.1.1.0", 24)
+
+	evaluateAdvertisement(p, nil, nil)
+	if !p.advertiseWithheld {
+		t.Fatal("Advertise map is not withheld before the exist map is configured")
+	}
+
+	// The Loc-RIB matches of the exist map are built again after the policy update
+	addRouteMap(t, p, "exist", "10.1.1.0/24")
+	p.server.ProcessPolicyUpdate()
+	evaluateAdvertisement(p, nil, nil)
+	if p.advertiseWithheld {
+		t.Fatal("Advertise map is withheld after the exist map is configured with a prefix in the Loc-RIB")
+	}
+}
+
+func TestDefaultOriginate(t *testing.T) {
+	p := getAdvertisePeer(t, config.NeighborConfig{})
+	p.updateLocRibMatches(nil, nil)
+	if p.isDefaultOriginated() {
+		t.Error("Default route originated when default originate is not configured")
+	}
+
+	p = getAdvertisePeer(t, config.NeighborConfig{DefaultOriginate: true})
+	p.updateLocRibMatches(nil, nil)
+	if !p.isDefaultOriginated() {
+		t.Error("Default route not originated when default originate is configured without a route map")
+	}
+
+	p = getAdvertisePeer(t, config.NeighborConfig{DefaultOriginate: true, DefaultOriginateMap: "default"})
+	addRouteMap(t, p, "default", "10.1.1.0/24")
+	p.updateLocRibMatches(nil, nil)
+	if p.isDefaultOriginated() {
+		t.Error("Default route originated when the route map has no match in the Loc-RIB")
+	}
+
+	updated, dest := addLocRibDest(p, "10.1.1.0", 24)
+	p.updateLocRibMatches(updated, nil)
+	if !p.isDefaultOriginated() {
+		t.Error("Default route not originated after the route map prefix is added to the Loc-RIB")
+	}
+
+	p.updateLocRibMatches(nil, removeLocRibDest(dest))
+	if p.isDefaultOriginated() {
+		t.Error("Default route originated after the route map prefix is withdrawn from the Loc-RIB")
+	}
+}
@@ -0,0 +1,188 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// bmp.go
+package server
+
+import (
+	"l3/bgp/bmp"
+	"l3/bgp/packet"
+	bgprib "l3/bgp/rib"
+	"net"
+	"strconv"
+)
+
+func (s *BGPServer) bmpPeerHeader(peer *Peer, postPolicy bool) *bmp.BMPPerPeerHeader {
+	// AS paths are normalized to 4 byte ASes when the update is decoded, irrespective of the peer's AS size
+	return bmp.NewBMPPerPeerHeader(peer.NeighborConf.RunningConf.NeighborAddress, peer.NeighborConf.RunningConf.PeerAS,
+		peer.NeighborConf.BGPId, postPolicy, 4)
+}
+
+func getConnPort(addr net.Addr) uint16 {
+	_, portStr, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return uint16(port)
+}
+
+func (s *BGPServer) bmpPeerUpMessage(peer *Peer) *bmp.BMPMessage {
+	if peer.conn == nil || peer.NeighborConf.SentOpen == nil || peer.NeighborConf.ReceivedOpen == nil {
+		s.logger.Errf("BMP: Peer %s OPEN messages are not available", peer.NeighborConf.RunningConf.NeighborAddress)
+		return nil
+	}
+
+	return bmp.NewBMPPeerUpMessage(s.bmpPeerHeader(peer, false),
+		peer.NeighborConf.Neighbor.Transport.Config.LocalAddress, getConnPort((*peer.conn).LocalAddr()),
+		getConnPort((*peer.conn).RemoteAddr()), peer.NeighborConf.SentOpen, peer.NeighborConf.ReceivedOpen)
+}
+
+func (s *BGPServer) SendBMPPeerUp(peer *Peer) {
+	if !s.bmpManager.IsEnabled() {
+		return
+	}
+
+	if msg := s.bmpPeerUpMessage(peer); msg != nil {
+		s.bmpManager.Send(msg)
+	}
+}
+
+func (s *BGPServer) SendBMPPeerDown(peer *Peer, deconfigured, tcpConnFailed bool) {
+	if !s.bmpManager.IsEnabled() || peer.NeighborConf.Neighbor.Transport.Config.LocalAddress == nil {
+		return
+	}
+
+	var msg *bmp.BMPMessage
+	peerHeader := s.bmpPeerHeader(peer, false)
+	notification := peer.NeighborConf.LastNotification
+	if deconfigured {
+		msg = bmp.NewBMPPeerDownMessage(peerHeader, bmp.BMPPeerDownDeconfigured, nil)
+	} else if notification != nil {
+		reason := bmp.BMPPeerDownRemoteNotification
+		if peer.NeighborConf.LastNotificationSent {
+			reason = bmp.BMPPeerDownLocalNotification
+		}
+		data, err := notification.Encode()
+		if err != nil {
+			s.logger.Errf("BMP: Failed to encode notification for peer %s with error %s",
+				peer.NeighborConf.RunningConf.NeighborAddress, err)
+			return
+		}
+		msg = bmp.NewBMPPeerDownMessage(peerHeader, reason, data)
+	} else if tcpConnFailed {
+		msg = bmp.NewBMPPeerDownMessage(peerHeader, bmp.BMPPeerDownRemoteNoNotification, nil)
+	} else {
+		msg = bmp.NewBMPPeerDownFSMEventMessage(peerHeader, 0)
+	}
+	s.bmpManager.Send(msg)
+}
+
+func (s *BGPServer) SendBMPRouteMonitoring(peer *Peer, updateMsg *packet.BGPMessage, postPolicy bool) {
+	if !s.bmpManager.IsEnabled() {
+		return
+	}
+
+	s.bmpManager.Send(bmp.NewBMPRouteMonitoringMessage(s.bmpPeerHeader(peer, postPolicy), updateMsg))
+}
+
+func newBMPUpdateMessage(protoFamily uint32, pathAttrs []packet.BGPPathAttr, nextHop net.IP,
+	nlris []packet.NLRI) *packet.BGPMessage {
+	if protoFamily == packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast) {
+		return packet.NewBGPUpdateMessage(make([]packet.NLRI, 0), packet.CopyPathAttrs(pathAttrs), nlris)
+	}
+
+	if nextHop == nil {
+		nextHop = net.IPv6zero
+	}
+	mpReach := packet.ConstructIPv6MPReachNLRI(protoFamily, nextHop, nil, nlris)
+	pathAttrs = packet.AddMPReachNLRIToPathAttrs(packet.CopyPathAttrs(pathAttrs), mpReach)
+	return packet.NewBGPUpdateMessage(make([]packet.NLRI, 0), pathAttrs, make([]packet.NLRI, 0))
+}
+
+// ProcessBMPStationUp sends the peer up messages and dumps the pre and post policy RIB-In of all the
+// established peers to a station that just connected. The dump of each peer is queued as one batch so that a
+// slow collector never blocks the server.
+func (s *BGPServer) ProcessBMPStationUp(addr string) {
+	s.logger.Infof("BMP station %s is up", addr)
+	for _, peer := range s.PeerMap {
+		if peer.NeighborConf.Neighbor.Transport.Config.LocalAddress == nil {
+			continue
+		}
+
+		msgs := make([]*bmp.BMPMessage, 0)
+		if msg := s.bmpPeerUpMessage(peer); msg != nil {
+			msgs = append(msgs, msg)
+		}
+
+		actionPaths := make(map[*bgprib.Path]map[string]*bgprib.Path)
+		for _, postPolicy := range []bool{false, true} {
+			for protoFamily, ribIn := range peer.ribIn {
+				for _, route := range ribIn {
+					if postPolicy && !route.Accept {
+						continue
+					}
+					for _, path := range route.GetPathMap() {
+						if postPolicy {
+							path = peer.getActionPath(route, path, actionPaths)
+						}
+						updateMsg := newBMPUpdateMessage(protoFamily, path.PathAttrs, path.GetNextHop(protoFamily),
+							[]packet.NLRI{route.NLRI})
+						msgs = append(msgs, bmp.NewBMPRouteMonitoringMessage(s.bmpPeerHeader(peer, postPolicy),
+							updateMsg))
+					}
+				}
+				msgs = append(msgs, bmp.NewBMPRouteMonitoringMessage(s.bmpPeerHeader(peer, postPolicy),
+					packet.NewBGPEndOfRIBMessage(protoFamily)))
+			}
+		}
+		s.bmpManager.SendToStation(addr, msgs)
+	}
+}
+
+func (p *Peer) sendBMPPostPolicyUpdate(updateMsg *packet.BGPUpdate, mpReach *packet.BGPPathAttrMPReachNLRI,
+	mpUnreach *packet.BGPPathAttrMPUnreachNLRI) {
+	pathAttrs := make([]packet.BGPPathAttr, 0)
+	if len(updateMsg.NLRI) > 0 || (mpReach != nil && len(mpReach.NLRI) > 0) {
+		pathAttrs = packet.CopyPathAttrs(updateMsg.PathAttributes)
+		if mpReach != nil && len(mpReach.NLRI) > 0 {
+			pathAttrs = packet.AddMPReachNLRIToPathAttrs(pathAttrs, mpReach)
+		}
+	}
+	if mpUnreach != nil && len(mpUnreach.NLRI) > 0 {
+		pathAttrs = packet.AddMPUnreachNLRIToPathAttrs(pathAttrs, mpUnreach)
+	}
+
+	if len(pathAttrs) == 0 && len(updateMsg.WithdrawnRoutes) == 0 {
+		return
+	}
+	bgpMsg := packet.NewBGPUpdateMessage(updateMsg.WithdrawnRoutes, pathAttrs, updateMsg.NLRI)
+	p.server.SendBMPRouteMonitoring(p, bgpMsg, true)
+}
+
+func (p *Peer) sendBMPActionPathUpdates(protoFamily uint32, actionNLRIs map[*bgprib.Path][]packet.NLRI) {
+	for actionPath, nlris := range actionNLRIs {
+		updateMsg := newBMPUpdateMessage(protoFamily, actionPath.PathAttrs, actionPath.GetNextHop(protoFamily), nlris)
+		p.server.SendBMPRouteMonitoring(p, updateMsg, true)
+	}
+}
@@ -0,0 +1,70 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// dampening.go
+package server
+
+import (
+	"time"
+)
+
+const DampeningReuseInterval = 10
+
+func (s *BGPServer) startDampeningTimer() {
+	s.dampeningTimer = time.AfterFunc(time.Duration(DampeningReuseInterval)*time.Second, func() {
+		s.DampeningReuseCh <- true
+	})
+}
+
+// ProcessDampeningReuse advertises the destinations with paths that are no longer suppressed
+func (s *BGPServer) ProcessDampeningReuse() {
+	for _, locRib := range s.getLocRibs() {
+		if !locRib.HasDampeningInfo() {
+			continue
+		}
+		updated, withdrawn, updatedAddPaths := locRib.ProcessDampeningReuse(s.AddPathCount)
+		updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+		s.SendUpdate(updated, withdrawn, updatedAddPaths)
+	}
+	s.dampeningTimer.Reset(time.Duration(DampeningReuseInterval) * time.Second)
+}
+
+// ClearDampening removes the flap history of the prefix in all the Loc-RIBs and advertises the paths that were
+// suppressed
+func (s *BGPServer) ClearDampening(prefix string) {
+	cleared := false
+	for _, locRib := range s.getLocRibs() {
+		updated, withdrawn, updatedAddPaths, found := locRib.ClearDampening(prefix, s.AddPathCount)
+		if !found {
+			continue
+		}
+
+		cleared = true
+		updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+		s.SendUpdate(updated, withdrawn, updatedAddPaths)
+	}
+
+	if !cleared {
+		s.logger.Infof("Dampening info not found for prefix %s", prefix)
+	}
+}
@@ -0,0 +1,121 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// evpn.go
+package server
+
+import (
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	bgprib "l3/bgp/rib"
+	"net"
+)
+
+// RD and RT are auto derived from the router id, AS and VNI as per RFC 7432 and RFC 8365
+func (s *BGPServer) getEVPNRouteDistinguisher(vni uint32) *packet.RouteDistinguisher {
+	return packet.NewEVPNRouteDistinguisher(s.BgpConfig.Global.Config.RouterId, s.BgpConfig.Global.Config.AS, vni)
+}
+
+func (s *BGPServer) getEVPNVtepIP(vni uint32, vtepIP string) net.IP {
+	if ip := net.ParseIP(vtepIP); ip != nil && !ip.IsUnspecified() {
+		return ip
+	}
+	if ip, ok := s.evpnVnis[vni]; ok && ip != nil {
+		return ip
+	}
+	return s.BgpConfig.Global.Config.RouterId
+}
+
+func (s *BGPServer) constructEVPNPath(vni uint32, vtepIP net.IP, nlri packet.NLRI) *bgprib.Path {
+	protoFamily := packet.GetProtocolFamily(packet.AfiL2VPN, packet.SafiEVPN)
+	pathAttrs := packet.ConstructPathAttrForConnRoutes(s.BgpConfig.Global.Config.AS)
+	pathAttrs = packet.SetExtCommunities(pathAttrs, []uint64{
+		packet.NewEVPNRouteTargetCommunity(s.BgpConfig.Global.Config.AS, vni),
+		packet.NewEncapsulationCommunity(packet.TunnelTypeVXLAN),
+	})
+	if _, ok := nlri.(*packet.EVPNNLRI).Route.(*packet.EVPNInclusiveMulticast); ok {
+		pathAttrs = append(pathAttrs, packet.NewBGPPathAttrPMSITunnel(packet.PMSITunnelTypeIngressReplication, vni,
+			vtepIP))
+	}
+	mpReach := packet.ConstructIPv6MPReachNLRI(protoFamily, vtepIP, nil, nil)
+	return bgprib.NewPath(s.LocRib, nil, pathAttrs, mpReach, bgprib.RouteTypeConnected)
+}
+
+func (s *BGPServer) processEVPNRoute(vni uint32, vtepIP net.IP, nlri packet.NLRI, valid bool) {
+	protoFamily := packet.GetProtocolFamily(packet.AfiL2VPN, packet.SafiEVPN)
+	add := make(map[uint32][]packet.NLRI)
+	remove := make(map[uint32][]packet.NLRI)
+	if valid {
+		add[protoFamily] = []packet.NLRI{nlri}
+	} else {
+		remove[protoFamily] = []packet.NLRI{nlri}
+	}
+	routerId := s.BgpConfig.Global.Config.RouterId.String()
+	path := s.constructEVPNPath(vni, vtepIP, nlri)
+	updated, withdrawn, updatedAddPaths := s.LocRib.ProcessConnectedRoutes(routerId, path, add, remove,
+		s.AddPathCount)
+	updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+	s.SendUpdate(updated, withdrawn, updatedAddPaths)
+}
+
+func (s *BGPServer) handleEVPNNotification(evpnInfo config.EVPNInfo) {
+	if !s.GlobalCfgDone {
+		s.logger.Info("BGP global config not done, ignore EVPN notification", evpnInfo.Oper)
+		return
+	}
+
+	switch evpnInfo.Oper {
+	case config.EVPN_VNI_CREATED, config.EVPN_VNI_DELETED:
+		if evpnInfo.Vtep == nil {
+			return
+		}
+		vni := evpnInfo.Vtep.Vni
+		vtepIP := s.getEVPNVtepIP(vni, evpnInfo.Vtep.VtepIP)
+		s.logger.Infof("EVPN local VNI %d vtep %s oper %d", vni, vtepIP, evpnInfo.Oper)
+		nlri := packet.NewEVPNInclusiveMulticastNLRI(s.getEVPNRouteDistinguisher(vni), 0, vtepIP)
+		if evpnInfo.Oper == config.EVPN_VNI_CREATED {
+			s.evpnVnis[vni] = vtepIP
+			s.LocRib.SetEVPNVni(vni, true)
+			s.processEVPNRoute(vni, vtepIP, nlri, true)
+		} else {
+			delete(s.evpnVnis, vni)
+			s.LocRib.SetEVPNVni(vni, false)
+			s.processEVPNRoute(vni, vtepIP, nlri, false)
+		}
+
+	case config.EVPN_MAC_CREATED, config.EVPN_MAC_DELETED:
+		if evpnInfo.Mac == nil {
+			return
+		}
+		vni := evpnInfo.Mac.Vni
+		mac, err := net.ParseMAC(evpnInfo.Mac.MAC)
+		if err != nil {
+			s.logger.Errf("EVPN local MAC %s for VNI %d is not valid, error: %s", evpnInfo.Mac.MAC, vni, err)
+			return
+		}
+		vtepIP := s.getEVPNVtepIP(vni, evpnInfo.Mac.VtepIP)
+		s.logger.Infof("EVPN local MAC %s ip %s VNI %d oper %d", mac, evpnInfo.Mac.IP, vni, evpnInfo.Oper)
+		nlri := packet.NewEVPNMacIPNLRI(s.getEVPNRouteDistinguisher(vni), 0, mac, net.ParseIP(evpnInfo.Mac.IP), vni)
+		s.processEVPNRoute(vni, vtepIP, nlri, evpnInfo.Oper == config.EVPN_MAC_CREATED)
+	}
+}
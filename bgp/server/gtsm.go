@@ -0,0 +1,95 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// gtsm.go
+package server
+
+import (
+	"l3/bgp/config"
+	"l3/bgp/fsm"
+	"net"
+)
+
+// getListenerTTLSecurity returns whether TTL security is needed on the listener and the number of hops that
+// allows the most distant neighbor. The minimum TTL is not enforced when a neighbor without TTL security can
+// connect on the listener.
+// The kernel silently drops the segments with a TTL less than the minimum TTL, these drops are only counted by
+// the TCPMinTTLDrop counter of the system in /proc/net/netstat. TTLSecurityDrops of a neighbor counts the
+// connection requests that passed the listener and were rejected after checking the TTL of the saved SYN.
+func (s *BGPServer) getListenerTTLSecurity(ipv6 bool) (bool, uint8) {
+	enable := false
+	var hops uint8
+	checkTTLSecurity := func(baseConf *config.BaseConfig) {
+		if !baseConf.TTLSecurity {
+			hops = fsm.GTSMMaxTTL - 1
+			return
+		}
+
+		enable = true
+		ttlHops := baseConf.TTLSecurityHops
+		if ttlHops == 0 {
+			ttlHops = config.BGPTTLSecurityHopsDefault
+		}
+		if ttlHops > hops {
+			hops = ttlHops
+		}
+	}
+
+	for _, peer := range s.PeerMap {
+		peerIP := peer.NeighborConf.RunningConf.NeighborAddress
+		if peer.IsDynamic() || peerIP == nil || (peerIP.To4() == nil) != ipv6 {
+			continue
+		}
+		checkTTLSecurity(&peer.NeighborConf.RunningConf.BaseConfig)
+	}
+
+	for _, listenRange := range s.listenRanges {
+		if (listenRange.Config.PeerAddressType == config.PeerAddressV6) != ipv6 {
+			continue
+		}
+		groupConf := s.getPeerGroupConfig(listenRange.Config.PeerGroup, listenRange.Config.PeerAddressType)
+		if groupConf == nil {
+			continue
+		}
+		checkTTLSecurity(&groupConf.BaseConfig)
+	}
+	return enable, hops
+}
+
+func (s *BGPServer) setListenerTTLSecurity(listener *net.TCPListener, ipv6 bool) {
+	if listener == nil {
+		return
+	}
+
+	enable, hops := s.getListenerTTLSecurity(ipv6)
+	if err := fsm.SetListenerTTLSecurity(listener, ipv6, enable, hops); err != nil {
+		s.logger.Errf("Failed to set TTL security on the listener, ipv6 %t, error %s", ipv6, err)
+	}
+}
+
+// updateListenerTTLSecurity sets TTL security on the listeners when neighbors are configured with TTL security,
+// so that the SYN-ACKs reach the neighbors and the kernel drops the connection requests that are too far away
+func (s *BGPServer) updateListenerTTLSecurity() {
+	s.setListenerTTLSecurity(s.listener, false)
+	s.setListenerTTLSecurity(s.listenerIPv6, true)
+}
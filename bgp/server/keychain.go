@@ -0,0 +1,216 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// keychain.go
+package server
+
+import (
+	"l3/bgp/config"
+	"l3/bgp/keychain"
+	"net"
+	"time"
+)
+
+func (s *BGPServer) getListener(ip net.IP) *net.TCPListener {
+	if ip.To4() != nil {
+		return s.listener
+	}
+	return s.listenerIPv6
+}
+
+// setPeerAuthKeys installs the keys of the peer's key chain that are valid now on the listener and on the
+// established connection to the peer. Only TCP-AO moves an established connection to a new key without a reset.
+// The MD5 key of an established connection is kept until the session restarts, the new MD5 key is only
+// installed on the listener.
+func (s *BGPServer) setPeerAuthKeys(peer *Peer) {
+	nConf := peer.NeighborConf
+	if nConf.RunningConf.KeyChain == "" {
+		nConf.SetKeyChain(nil)
+		if nConf.RunningConf.AuthPassword != "" && peer.conn != nil {
+			nConf.Neighbor.State.AuthType = keychain.AuthTypeMD5
+		}
+		return
+	}
+
+	keyChain, ok := s.keyChains[nConf.RunningConf.KeyChain]
+	if !ok {
+		s.logger.Infof("Neighbor %s: Key chain %s not configured yet, connections are refused",
+			nConf.RunningConf.NeighborAddress, nConf.RunningConf.KeyChain)
+		nConf.SetKeyChain(nil)
+		return
+	}
+
+	nConf.SetKeyChain(keyChain)
+	if nConf.RunningConf.NeighborAddress == nil {
+		return
+	}
+
+	remote := nConf.RunningConf.NeighborAddress
+	tcpAO := nConf.RunningConf.TCPAO
+	now := time.Now()
+	if listener := s.getListener(remote); listener != nil {
+		err := keychain.ControlFd(listener, func(fd int) error {
+			_, err := keyChain.SetKeys(fd, remote, tcpAO, true, now)
+			return err
+		})
+		if err != nil {
+			s.logger.Errf("Neighbor %s: Failed to set keys from key chain %s on the listener, error %s", remote,
+				keyChain.Name, err)
+		}
+	}
+
+	if peer.conn == nil {
+		return
+	}
+
+	if !tcpAO {
+		sendKeyId := int32(-1)
+		if sendKey := keyChain.GetSendKey(now, false); sendKey != nil {
+			sendKeyId = int32(sendKey.KeyId)
+		}
+		if nConf.Neighbor.State.AuthType != keychain.AuthTypeMD5 {
+			nConf.Neighbor.State.ActiveKeyId = sendKeyId
+			if sendKeyId != -1 {
+				nConf.Neighbor.State.AuthType = keychain.AuthTypeMD5
+			}
+		} else if sendKeyId != nConf.Neighbor.State.ActiveKeyId {
+			s.logger.Infof("Neighbor %s: MD5 key %d from key chain %s is used after the session restarts, "+
+				"the session keeps key %d", remote, sendKeyId, keyChain.Name, nConf.Neighbor.State.ActiveKeyId)
+		}
+		return
+	}
+
+	tcpConn, ok := (*peer.conn).(*net.TCPConn)
+	if !ok {
+		return
+	}
+
+	var keyId int32
+	err := keychain.ControlFd(tcpConn, func(fd int) (err error) {
+		keyId, err = keyChain.SetKeys(fd, remote, tcpAO, false, now)
+		return err
+	})
+	if err != nil {
+		s.logger.Errf("Neighbor %s: Failed to set keys from key chain %s on the connection, error %s", remote,
+			keyChain.Name, err)
+		return
+	}
+
+	nConf.Neighbor.State.ActiveKeyId = keyId
+	nConf.Neighbor.State.AuthType = ""
+	if keyId != -1 {
+		nConf.Neighbor.State.AuthType = keychain.AuthTypeTCPAO
+	}
+}
+
+// removePeerAuthKeys removes the keys of the peer's key chain from the listener
+func (s *BGPServer) removePeerAuthKeys(peer *Peer) {
+	nConf := peer.NeighborConf
+	keyChain := nConf.GetKeyChain()
+	nConf.SetKeyChain(nil)
+	if keyChain == nil || nConf.RunningConf.NeighborAddress == nil {
+		return
+	}
+
+	remote := nConf.RunningConf.NeighborAddress
+	if listener := s.getListener(remote); listener != nil {
+		err := keychain.ControlFd(listener, func(fd int) error {
+			return keyChain.RemoveKeys(fd, remote, nConf.RunningConf.TCPAO)
+		})
+		if err != nil {
+			s.logger.Errf("Neighbor %s: Failed to remove keys from key chain %s on the listener, error %s", remote,
+				keyChain.Name, err)
+		}
+	}
+}
+
+func (s *BGPServer) AddOrUpdateKeyChain(keyChainConf config.KeyChainConfig) {
+	keyChain, err := keychain.NewKeyChain(keyChainConf)
+	if err != nil {
+		s.logger.Errf("Failed to add key chain %s, error %s", keyChainConf.Name, err)
+		return
+	}
+
+	s.logger.Infof("Add key chain %s with %d keys", keyChain.Name, len(keyChain.Keys))
+	if keyChain.HasMD5AcceptLifetimes() {
+		s.logger.Warningf("Key chain %s: MD5 keys use the send lifetime for both directions, the accept lifetimes "+
+			"are ignored", keyChain.Name)
+	}
+	s.keyChains[keyChain.Name] = keyChain
+	for _, peer := range s.PeerMap {
+		if peer.NeighborConf.RunningConf.KeyChain == keyChain.Name {
+			s.removePeerAuthKeys(peer)
+			s.setPeerAuthKeys(peer)
+		}
+	}
+	s.scheduleKeyChainTimer()
+}
+
+func (s *BGPServer) DeleteKeyChain(name string) {
+	if _, ok := s.keyChains[name]; !ok {
+		s.logger.Infof("Key chain %s not found", name)
+		return
+	}
+
+	s.logger.Infof("Delete key chain %s", name)
+	for _, peer := range s.PeerMap {
+		if peer.NeighborConf.RunningConf.KeyChain == name {
+			s.removePeerAuthKeys(peer)
+		}
+	}
+	delete(s.keyChains, name)
+	s.scheduleKeyChainTimer()
+}
+
+// ProcessKeyChainTimer moves the peers to the keys that became valid or expired since the last key change
+func (s *BGPServer) ProcessKeyChainTimer() {
+	for _, peer := range s.PeerMap {
+		if peer.NeighborConf.GetKeyChain() != nil {
+			s.setPeerAuthKeys(peer)
+		}
+	}
+	s.scheduleKeyChainTimer()
+}
+
+func (s *BGPServer) scheduleKeyChainTimer() {
+	if s.keyChainTimer != nil {
+		s.keyChainTimer.Stop()
+		s.keyChainTimer = nil
+	}
+
+	now := time.Now()
+	var next time.Time
+	for _, keyChain := range s.keyChains {
+		if change, ok := keyChain.GetNextChange(now); ok && (next.IsZero() || change.Before(next)) {
+			next = change
+		}
+	}
+
+	if next.IsZero() {
+		return
+	}
+
+	s.keyChainTimer = time.AfterFunc(next.Sub(now), func() {
+		s.KeyChainTimerCh <- true
+	})
+}
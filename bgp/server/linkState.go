@@ -0,0 +1,199 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// linkState.go
+package server
+
+import (
+	"encoding/binary"
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	bgprib "l3/bgp/rib"
+	"net"
+)
+
+var linkStateRouteTypeMap = map[uint8]uint8{
+	config.LinkStateRouteIntraArea: packet.OSPFRouteTypeIntraArea,
+	config.LinkStateRouteInterArea: packet.OSPFRouteTypeInterArea,
+	config.LinkStateRouteExternal1: packet.OSPFRouteTypeExternal1,
+	config.LinkStateRouteExternal2: packet.OSPFRouteTypeExternal2,
+	config.LinkStateRouteNSSA1:     packet.OSPFRouteTypeNSSA1,
+	config.LinkStateRouteNSSA2:     packet.OSPFRouteTypeNSSA2,
+}
+
+type linkStateRoute struct {
+	nlri    *packet.LinkStateNLRI
+	tlvs    []packet.LinkStateTLV
+	attrStr string
+}
+
+func newLinkStateRoute(nlri *packet.LinkStateNLRI, tlvs []packet.LinkStateTLV) *linkStateRoute {
+	return &linkStateRoute{
+		nlri:    nlri,
+		tlvs:    tlvs,
+		attrStr: packet.NewBGPPathAttrLinkState(tlvs).String(),
+	}
+}
+
+func (s *BGPServer) getLinkStateNode(areaId uint32, routerId, drIP string) *packet.LinkStateNodeDescriptor {
+	ip := net.ParseIP(routerId)
+	if ip == nil || ip.To4() == nil {
+		return nil
+	}
+	return &packet.LinkStateNodeDescriptor{
+		AS:       s.BgpConfig.Global.Config.AS,
+		AreaId:   areaId,
+		RouterId: ip,
+		DRIP:     net.ParseIP(drIP),
+	}
+}
+
+// The OSPF LSDB is mapped to the BGP-LS NLRIs as per RFC 7752, the transit networks are advertised as pseudonodes
+func (s *BGPServer) buildLinkStateRoutes(area *config.LinkStateArea, routes map[string]*linkStateRoute) {
+	areaIP := net.ParseIP(area.AreaId).To4()
+	if areaIP == nil {
+		s.logger.Err("Link state area id", area.AreaId, "is not valid")
+		return
+	}
+	areaId := binary.BigEndian.Uint32(areaIP)
+	protoId := packet.LinkStateProtoOSPFv2
+
+	for _, node := range area.Nodes {
+		desc := s.getLinkStateNode(areaId, node.RouterId, node.DRIP)
+		if desc == nil {
+			continue
+		}
+		var flags uint8
+		if node.ABR {
+			flags |= packet.LinkStateNodeFlagABR
+		}
+		if node.ASBR {
+			flags |= packet.LinkStateNodeFlagExternal
+		}
+		tlvs := []packet.LinkStateTLV{packet.NewLinkStateNodeFlagsTLV(flags)}
+		if !desc.IsPseudonode() {
+			tlvs = append(tlvs, packet.NewLinkStateRouterIdTLV(packet.LinkStateAttrLocalRouterId, desc.RouterId))
+		}
+		nlri := packet.NewLinkStateNodeNLRI(protoId, desc)
+		routes[nlri.GetCIDR()] = newLinkStateRoute(nlri, tlvs)
+	}
+
+	for _, link := range area.Links {
+		local := s.getLinkStateNode(areaId, link.LocalRouterId, link.LocalDRIP)
+		remote := s.getLinkStateNode(areaId, link.RemoteRouterId, link.RemoteDRIP)
+		if local == nil || remote == nil {
+			continue
+		}
+		linkDesc := &packet.LinkStateLinkDescriptor{
+			LocalId:  link.LocalIfIndex,
+			LocalIP:  net.ParseIP(link.LocalIP),
+			RemoteIP: net.ParseIP(link.RemoteIP),
+		}
+		tlvs := []packet.LinkStateTLV{packet.NewLinkStateIGPMetricTLV(link.Metric)}
+		if !local.IsPseudonode() {
+			tlvs = append(tlvs, packet.NewLinkStateRouterIdTLV(packet.LinkStateAttrLocalRouterId, local.RouterId))
+		}
+		if !remote.IsPseudonode() {
+			tlvs = append(tlvs, packet.NewLinkStateRouterIdTLV(packet.LinkStateAttrRemoteRouterId, remote.RouterId))
+		}
+		nlri := packet.NewLinkStateLinkNLRI(protoId, local, remote, linkDesc)
+		routes[nlri.GetCIDR()] = newLinkStateRoute(nlri, tlvs)
+	}
+
+	for _, prefix := range area.Prefixes {
+		desc := s.getLinkStateNode(areaId, prefix.RouterId, prefix.DRIP)
+		_, ipNet, err := net.ParseCIDR(prefix.Prefix)
+		if desc == nil || err != nil {
+			continue
+		}
+		ones, _ := ipNet.Mask.Size()
+		prefixDesc := &packet.LinkStatePrefixDescriptor{
+			RouteType: linkStateRouteTypeMap[prefix.RouteType],
+			Prefix:    ipNet.IP,
+			Length:    uint8(ones),
+		}
+		tlvs := []packet.LinkStateTLV{packet.NewLinkStatePrefixMetricTLV(prefix.Metric)}
+		nlri := packet.NewLinkStatePrefixNLRI(protoId, desc, prefixDesc)
+		routes[nlri.GetCIDR()] = newLinkStateRoute(nlri, tlvs)
+	}
+}
+
+func (s *BGPServer) constructLinkStatePath(tlvs []packet.LinkStateTLV) *bgprib.Path {
+	protoFamily := packet.GetProtocolFamily(packet.AfiLinkState, packet.SafiLinkState)
+	pathAttrs := packet.ConstructPathAttrForConnRoutes(s.BgpConfig.Global.Config.AS)
+	if len(tlvs) > 0 {
+		pathAttrs = append(pathAttrs, packet.NewBGPPathAttrLinkState(tlvs))
+	}
+	mpReach := packet.ConstructIPv6MPReachNLRI(protoFamily, s.BgpConfig.Global.Config.RouterId, nil, nil)
+	return bgprib.NewPath(s.LocRib, nil, pathAttrs, mpReach, bgprib.RouteTypeConnected)
+}
+
+func (s *BGPServer) processLinkStateRoutes(tlvs []packet.LinkStateTLV, nlris []packet.NLRI, valid bool) {
+	protoFamily := packet.GetProtocolFamily(packet.AfiLinkState, packet.SafiLinkState)
+	add := make(map[uint32][]packet.NLRI)
+	remove := make(map[uint32][]packet.NLRI)
+	if valid {
+		add[protoFamily] = nlris
+	} else {
+		remove[protoFamily] = nlris
+	}
+	routerId := s.BgpConfig.Global.Config.RouterId.String()
+	path := s.constructLinkStatePath(tlvs)
+	updated, withdrawn, updatedAddPaths := s.LocRib.ProcessConnectedRoutes(routerId, path, add, remove,
+		s.AddPathCount)
+	updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+	s.SendUpdate(updated, withdrawn, updatedAddPaths)
+}
+
+// Every notification carries the whole topology, only the NLRIs that changed are advertised or withdrawn
+func (s *BGPServer) handleLinkStateNotification(linkStateInfo config.LinkStateInfo) {
+	if !s.GlobalCfgDone {
+		s.logger.Info("BGP global config not done, ignore link state notification from", linkStateInfo.RouterId)
+		return
+	}
+
+	routes := make(map[string]*linkStateRoute)
+	for idx := range linkStateInfo.Areas {
+		s.buildLinkStateRoutes(&linkStateInfo.Areas[idx], routes)
+	}
+
+	withdrawn := make([]packet.NLRI, 0)
+	for key, route := range s.linkStateRoutes {
+		if _, ok := routes[key]; !ok {
+			withdrawn = append(withdrawn, route.nlri)
+		}
+	}
+	s.logger.Infof("Link state topology from %s has %d NLRIs, %d withdrawn", linkStateInfo.RouterId, len(routes),
+		len(withdrawn))
+	if len(withdrawn) > 0 {
+		s.processLinkStateRoutes(nil, withdrawn, false)
+	}
+
+	for key, route := range routes {
+		if oldRoute, ok := s.linkStateRoutes[key]; ok && oldRoute.attrStr == route.attrStr {
+			continue
+		}
+		s.processLinkStateRoutes(route.tlvs, []packet.NLRI{route.nlri}, true)
+	}
+	s.linkStateRoutes = routes
+}
@@ -0,0 +1,122 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// linkState_test.go
+package server
+
+import (
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	"net"
+	"testing"
+	"utils/logging"
+)
+
+func getLinkStateServer(t *testing.T) *BGPServer {
+	logger, err := logging.NewLogger("bgpd", "BGP", true)
+	if err != nil {
+		t.Fatal("Failed to start the logger. Exiting!!")
+	}
+
+	s := &BGPServer{logger: logger}
+	s.BgpConfig.Global.Config.AS = 100
+	return s
+}
+
+func TestBuildLinkStateRoutes(t *testing.T) {
+	s := getLinkStateServer(t)
+	area := &config.LinkStateArea{
+		AreaId: "0.0.0.1",
+		Nodes: []config.LinkStateNode{
+			{RouterId: "1.1.1.1", ABR: true},
+			{RouterId: "2.2.2.2", DRIP: "20.0.0.2"},
+			{RouterId: "2001:db8::1"},
+		},
+		Links: []config.LinkStateLink{
+			{LocalRouterId: "1.1.1.1", RemoteRouterId: "2.2.2.2", RemoteDRIP: "20.0.0.2", LocalIP: "20.0.0.1",
+				Metric: 5},
+			{LocalRouterId: "1.1.1.1", RemoteRouterId: "invalid"},
+		},
+		Prefixes: []config.LinkStatePrefix{
+			{RouterId: "1.1.1.1", Prefix: "30.0.0.0/24", RouteType: config.LinkStateRouteInterArea, Metric: 20},
+			{RouterId: "1.1.1.1", Prefix: "invalid"},
+		},
+	}
+	routes := make(map[string]*linkStateRoute)
+	s.buildLinkStateRoutes(area, routes)
+
+	local := &packet.LinkStateNodeDescriptor{AS: 100, AreaId: 1, RouterId: net.ParseIP("1.1.1.1")}
+	pseudo := &packet.LinkStateNodeDescriptor{AS: 100, AreaId: 1, RouterId: net.ParseIP("2.2.2.2"),
+		DRIP: net.ParseIP("20.0.0.2")}
+	node := packet.NewLinkStateNodeNLRI(packet.LinkStateProtoOSPFv2, local)
+	pseudoNode := packet.NewLinkStateNodeNLRI(packet.LinkStateProtoOSPFv2, pseudo)
+	link := packet.NewLinkStateLinkNLRI(packet.LinkStateProtoOSPFv2, local, pseudo,
+		&packet.LinkStateLinkDescriptor{LocalIP: net.ParseIP("20.0.0.1")})
+	prefix := packet.NewLinkStatePrefixNLRI(packet.LinkStateProtoOSPFv2, local, &packet.LinkStatePrefixDescriptor{
+		RouteType: packet.OSPFRouteTypeInterArea, Prefix: net.ParseIP("30.0.0.0"), Length: 24})
+
+	if len(routes) != 4 {
+		t.Fatal("Expected 4 link state routes, found", len(routes))
+	}
+	for _, nlri := range []*packet.LinkStateNLRI{node, pseudoNode, link, prefix} {
+		if _, ok := routes[nlri.GetCIDR()]; !ok {
+			t.Fatal("Link state route", nlri.GetCIDR(), "not found")
+		}
+	}
+
+	attr := packet.NewBGPPathAttrLinkState(routes[node.GetCIDR()].tlvs)
+	if flags := attr.GetTLV(packet.LinkStateAttrNodeFlags); len(flags) != 1 ||
+		flags[0] != packet.LinkStateNodeFlagABR {
+		t.Error("Expected the ABR node flag for", node.GetCIDR(), "found", flags)
+	}
+	if routerId := attr.GetTLV(packet.LinkStateAttrLocalRouterId); !net.IP(routerId).Equal(local.RouterId) {
+		t.Error("Expected the local router id", local.RouterId, "for", node.GetCIDR(), "found", routerId)
+	}
+
+	// The pseudonode does not have a router id
+	attr = packet.NewBGPPathAttrLinkState(routes[pseudoNode.GetCIDR()].tlvs)
+	if routerId := attr.GetTLV(packet.LinkStateAttrLocalRouterId); routerId != nil {
+		t.Error("Expected no router id for the pseudonode", pseudoNode.GetCIDR(), "found", routerId)
+	}
+
+	attr = packet.NewBGPPathAttrLinkState(routes[link.GetCIDR()].tlvs)
+	if attr.GetTLV(packet.LinkStateAttrLocalRouterId) == nil || attr.GetTLV(packet.LinkStateAttrRemoteRouterId) != nil {
+		t.Error("Expected only the local router id for the link to the pseudonode", attr)
+	}
+	if metric := attr.GetTLV(packet.LinkStateAttrIGPMetric); metric == nil || metric[len(metric)-1] != 5 {
+		t.Error("Expected the IGP metric 5 for", link.GetCIDR(), "found", metric)
+	}
+}
+
+func TestBuildLinkStateRoutesBadArea(t *testing.T) {
+	s := getLinkStateServer(t)
+	area := &config.LinkStateArea{
+		AreaId: "invalid",
+		Nodes:  []config.LinkStateNode{{RouterId: "1.1.1.1"}},
+	}
+	routes := make(map[string]*linkStateRoute)
+	s.buildLinkStateRoutes(area, routes)
+	if len(routes) != 0 {
+		t.Error("Expected no link state routes for an invalid area, found", routes)
+	}
+}
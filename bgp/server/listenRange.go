@@ -0,0 +1,182 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// listenRange.go
+package server
+
+import (
+	"errors"
+	"fmt"
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	"net"
+)
+
+const listenRangeLimitDefault uint32 = 100
+
+type ListenRange struct {
+	Config config.ListenRangeConfig
+	IPNet  *net.IPNet
+	Peers  map[string]*Peer
+}
+
+func NewListenRange(conf config.ListenRangeConfig) (*ListenRange, error) {
+	_, ipNet, err := net.ParseCIDR(conf.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Limit == 0 {
+		conf.Limit = listenRangeLimitDefault
+	}
+
+	listenRange := ListenRange{
+		Config: conf,
+		IPNet:  ipNet,
+		Peers:  make(map[string]*Peer),
+	}
+	return &listenRange, nil
+}
+
+func (s *BGPServer) getListenRange(ip net.IP) *ListenRange {
+	var match *ListenRange
+	matchLen := -1
+	for _, listenRange := range s.listenRanges {
+		if !listenRange.IPNet.Contains(ip) {
+			continue
+		}
+
+		if ones, _ := listenRange.IPNet.Mask.Size(); ones > matchLen {
+			match = listenRange
+			matchLen = ones
+		}
+	}
+	return match
+}
+
+func (s *BGPServer) getPeerGroupConfig(groupName string, peerAddrType config.PeerAddressType) *config.PeerGroupConfig {
+	protoFamily, _ := packet.GetProtocolFamilyFromPeerAddrType(peerAddrType)
+	if _, ok := s.BgpConfig.PeerGroups[protoFamily]; !ok {
+		return nil
+	}
+
+	if group, ok := s.BgpConfig.PeerGroups[protoFamily][groupName]; ok {
+		return &group.Config
+	}
+	return nil
+}
+
+func (s *BGPServer) createDynamicPeer(ip net.IP) *Peer {
+	listenRange := s.getListenRange(ip)
+	if listenRange == nil {
+		s.logger.Info("No listen range found for", ip)
+		return nil
+	}
+
+	if uint32(len(listenRange.Peers)) >= listenRange.Config.Limit {
+		s.logger.Info("Can't create dynamic neighbor", ip, "listen range", listenRange.Config.Prefix,
+			"reached the limit", listenRange.Config.Limit)
+		return nil
+	}
+
+	if s.getPeerGroupConfig(listenRange.Config.PeerGroup, listenRange.Config.PeerAddressType) == nil {
+		s.logger.Info("Can't create dynamic neighbor", ip, "peer group", listenRange.Config.PeerGroup,
+			"not found")
+		return nil
+	}
+
+	peerConf := config.NeighborConfig{
+		BaseConfig: config.BaseConfig{
+			PeerAddressType: listenRange.Config.PeerAddressType,
+		},
+		NeighborAddress: ip,
+		IfIndex:         -1,
+		PeerGroup:       listenRange.Config.PeerGroup,
+		Dynamic:         true,
+	}
+
+	s.logger.Info("Create dynamic neighbor", ip, "for listen range", listenRange.Config.Prefix)
+	s.CreatePeer(peerConf)
+	peer, ok := s.PeerMap[ip.String()]
+	if !ok {
+		return nil
+	}
+
+	listenRange.Peers[ip.String()] = peer
+	return peer
+}
+
+func (s *BGPServer) removeDynamicPeer(peer *Peer) {
+	peerIP := peer.NeighborConf.Neighbor.NeighborAddress.String()
+	s.logger.Info("Remove dynamic neighbor", peerIP)
+	for _, listenRange := range s.listenRanges {
+		delete(listenRange.Peers, peerIP)
+	}
+
+	s.NeighborMutex.Lock()
+	s.removePeerFromList(peer)
+	s.NeighborMutex.Unlock()
+	delete(s.PeerMap, peerIP)
+	peer.Cleanup()
+}
+
+func (s *BGPServer) removeListenRangePeers(listenRange *ListenRange) {
+	for peerIP, peer := range listenRange.Peers {
+		delete(listenRange.Peers, peerIP)
+		s.removePeer(peer.NeighborConf.Neighbor.Config)
+	}
+}
+
+func (s *BGPServer) AddOrUpdateListenRange(oldConf, newConf config.ListenRangeConfig) error {
+	s.logger.Info("AddOrUpdateListenRange old:", oldConf, "new:", newConf)
+	newRange, err := NewListenRange(newConf)
+	if err != nil {
+		s.logger.Errf("Failed to add listen range %s, error: %s", newConf.Prefix, err)
+		return err
+	}
+
+	if oldConf.Prefix != "" {
+		if listenRange, ok := s.listenRanges[oldConf.Prefix]; ok {
+			if oldConf.Prefix != newConf.Prefix || oldConf.PeerGroup != newConf.PeerGroup {
+				s.removeListenRangePeers(listenRange)
+			} else {
+				newRange.Peers = listenRange.Peers
+			}
+			delete(s.listenRanges, oldConf.Prefix)
+		}
+	}
+
+	s.listenRanges[newConf.Prefix] = newRange
+	return nil
+}
+
+func (s *BGPServer) DeleteListenRange(conf config.ListenRangeConfig) error {
+	listenRange, ok := s.listenRanges[conf.Prefix]
+	if !ok {
+		return errors.New(fmt.Sprintf("Listen range %s not found", conf.Prefix))
+	}
+
+	s.removeListenRangePeers(listenRange)
+	delete(s.listenRanges, conf.Prefix)
+	return nil
+}
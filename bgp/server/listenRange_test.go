@@ -0,0 +1,153 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// listenRange_test.go
+package server
+
+import (
+	"l3/bgp/baseobjects"
+	"l3/bgp/config"
+	"net"
+	"testing"
+	"utils/logging"
+)
+
+func getListenRangeServer(t *testing.T) *BGPServer {
+	logger, err := logging.NewLogger("bgpd", "BGP", true)
+	if err != nil {
+		t.Fatal("Failed to start the logger. Exiting!!")
+	}
+
+	s := &BGPServer{
+		logger:       logger,
+		PeerMap:      make(map[string]*Peer),
+		Neighbors:    make([]*Peer, 0),
+		listenRanges: make(map[string]*ListenRange),
+	}
+	return s
+}
+
+func addListenRange(t *testing.T, s *BGPServer, prefix string, limit uint32) *ListenRange {
+	conf := config.ListenRangeConfig{
+		Prefix:          prefix,
+		PeerGroup:       "group",
+		PeerAddressType: config.PeerAddressV4,
+		Limit:           limit,
+	}
+	if err := s.AddOrUpdateListenRange(config.ListenRangeConfig{}, conf); err != nil {
+		t.Fatal("Failed to add listen range", prefix, "error:", err)
+	}
+	return s.listenRanges[prefix]
+}
+
+func addDynamicPeer(s *BGPServer, listenRange *ListenRange, ip string) *Peer {
+	peerConf := config.NeighborConfig{
+		NeighborAddress: net.ParseIP(ip),
+		IfIndex:         -1,
+		PeerGroup:       listenRange.Config.PeerGroup,
+		Dynamic:         true,
+	}
+	peer := &Peer{
+		server:       s,
+		logger:       s.logger,
+		NeighborConf: base.NewNeighborConf(s.logger, &config.GlobalConfig{}, nil, peerConf),
+	}
+	s.PeerMap[ip] = peer
+	s.addPeerToList(peer)
+	listenRange.Peers[ip] = peer
+	return peer
+}
+
+func TestGetListenRange(t *testing.T) {
+	s := getListenRangeServer(t)
+	addListenRange(t, s, "10.0.0.0/8", 0)
+	addListenRange(t, s, "10.1.0.0/16", 0)
+	addListenRange(t, s, "10.1.1.0/24", 0)
+
+	tests := map[string]string{
+		"10.1.1.1":    "10.1.1.0/24",
+		"10.1.2.1":    "10.1.0.0/16",
+		"10.2.1.1":    "10.0.0.0/8",
+		"192.168.1.1": "",
+	}
+	for ip, prefix := range tests {
+		listenRange := s.getListenRange(net.ParseIP(ip))
+		if prefix == "" {
+			if listenRange != nil {
+				t.Error("Expected no listen range for", ip, "found", listenRange.Config.Prefix)
+			}
+			continue
+		}
+
+		if listenRange == nil || listenRange.Config.Prefix != prefix {
+			t.Error("Expected listen range", prefix, "for", ip, "found", listenRange)
+		}
+	}
+}
+
+func TestListenRangeLimit(t *testing.T) {
+	s := getListenRangeServer(t)
+	listenRange := addListenRange(t, s, "10.1.1.0/24", 2)
+	if listenRange.Config.Limit != 2 {
+		t.Error("Expected listen range limit 2, found", listenRange.Config.Limit)
+	}
+
+	addDynamicPeer(s, listenRange, "10.1.1.1")
+	addDynamicPeer(s, listenRange, "10.1.1.2")
+	if peer := s.createDynamicPeer(net.ParseIP("10.1.1.3")); peer != nil {
+		t.Error("Dynamic neighbor 10.1.1.3 created after reaching the limit of the listen range")
+	}
+	if _, ok := s.PeerMap["10.1.1.3"]; ok {
+		t.Error("Dynamic neighbor 10.1.1.3 added to the peer map after reaching the limit of the listen range")
+	}
+
+	defaultRange := addListenRange(t, s, "10.2.0.0/16", 0)
+	if defaultRange.Config.Limit != listenRangeLimitDefault {
+		t.Error("Expected default listen range limit", listenRangeLimitDefault, "found", defaultRange.Config.Limit)
+	}
+}
+
+func TestRemoveDynamicPeer(t *testing.T) {
+	s := getListenRangeServer(t)
+	listenRange := addListenRange(t, s, "10.1.1.0/24", 1)
+	peer := addDynamicPeer(s, listenRange, "10.1.1.1")
+	if !peer.IsDynamic() || !peer.NeighborConf.Neighbor.Transport.Config.PassiveMode {
+		t.Error("Dynamic neighbor 10.1.1.1 is not in passive mode")
+	}
+
+	s.removeDynamicPeer(peer)
+	if _, ok := s.PeerMap["10.1.1.1"]; ok {
+		t.Error("Dynamic neighbor 10.1.1.1 not removed from the peer map")
+	}
+	if _, ok := listenRange.Peers["10.1.1.1"]; ok {
+		t.Error("Dynamic neighbor 10.1.1.1 not removed from the listen range")
+	}
+	if len(s.Neighbors) != 0 {
+		t.Error("Dynamic neighbor 10.1.1.1 not removed from the neighbor list")
+	}
+
+	addDynamicPeer(s, listenRange, "10.1.1.2")
+	if len(listenRange.Peers) != 1 {
+		t.Error("Expected 1 dynamic neighbor in the listen range after removal, found", len(listenRange.Peers))
+	}
+}
@@ -0,0 +1,140 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// mrt.go
+package server
+
+import (
+	"l3/bgp/config"
+	"l3/bgp/mrt"
+	"l3/bgp/packet"
+	"net"
+	"time"
+)
+
+const (
+	MRTRIBFilePrefix    = "bgp-rib"
+	MRTUpdateFilePrefix = "bgp-updates"
+)
+
+func (s *BGPServer) SetupMRT(mrtConf config.MRTConfig) {
+	s.logger.Info("SetupMRT - MRT config:", mrtConf)
+	if s.mrtDumpTimer != nil {
+		s.mrtDumpTimer.Stop()
+		s.mrtDumpTimer = nil
+	}
+	s.mrtUpdates.Close()
+
+	if mrtConf.DumpDir == "" {
+		return
+	}
+
+	if mrtConf.LogUpdates {
+		if err := s.mrtUpdates.Open(mrtConf.DumpDir, MRTUpdateFilePrefix, mrtConf.RotateInterval); err != nil {
+			s.logger.Errf("Failed to start MRT update logging in %s with error %s", mrtConf.DumpDir, err)
+		}
+	}
+
+	if mrtConf.DumpInterval != 0 {
+		s.mrtDumpTimer = time.AfterFunc(time.Duration(mrtConf.DumpInterval)*time.Second, func() {
+			s.MRTDumpCh <- true
+		})
+	}
+}
+
+func (s *BGPServer) ProcessMRTDump(periodic bool) {
+	s.DumpMRTTable()
+	if periodic && s.mrtDumpTimer != nil {
+		s.mrtDumpTimer.Reset(time.Duration(s.BgpConfig.Global.Config.MRT.DumpInterval) * time.Second)
+	}
+}
+
+// DumpMRTTable writes the paths of all the LocRib destinations to a new TABLE_DUMP_V2 file
+func (s *BGPServer) DumpMRTTable() {
+	gConf := &s.BgpConfig.Global.Config
+	if gConf.MRT.DumpDir == "" {
+		s.logger.Err("Can't dump BGP table, MRT dump directory is not configured")
+		return
+	}
+
+	writer := mrt.NewMRTWriter(s.logger)
+	if err := writer.Open(gConf.MRT.DumpDir, MRTRIBFilePrefix, 0); err != nil {
+		s.logger.Errf("Failed to open MRT dump file in %s with error %s", gConf.MRT.DumpDir, err)
+		return
+	}
+	defer writer.Close()
+
+	now := time.Now()
+	// Locally originated paths are dumped with the first peer entry
+	peers := []mrt.MRTPeerEntry{mrt.MRTPeerEntry{gConf.RouterId, net.IPv4zero, gConf.AS}}
+	peerIndex := make(map[string]uint16)
+	for peerIP, peer := range s.PeerMap {
+		peerIndex[peerIP] = uint16(len(peers))
+		peers = append(peers, mrt.MRTPeerEntry{peer.NeighborConf.BGPId, peer.NeighborConf.RunningConf.NeighborAddress,
+			peer.NeighborConf.RunningConf.PeerAS})
+	}
+	if err := writer.Write(mrt.NewMRTPeerIndexTableMessage(now, gConf.RouterId, gConf.Vrf, peers)); err != nil {
+		s.logger.Errf("Failed to write MRT peer index table with error %s", err)
+		return
+	}
+
+	seqNum := uint32(0)
+	for _, protoFamily := range packet.ProtocolFamilyMap {
+		if _, ok := mrt.GetRIBSubType(protoFamily); !ok {
+			continue
+		}
+		afi, _ := packet.GetAfiSafi(protoFamily)
+		for _, dest := range s.LocRib.GetDestinations(protoFamily) {
+			entries := make([]mrt.MRTRIBEntry, 0)
+			for peerIP, pathMap := range dest.GetPeerPathMap() {
+				for _, path := range pathMap {
+					pathAttrs := path.PathAttrs
+					if afi != packet.AfiIP {
+						nextHop := path.GetNextHop(protoFamily)
+						if nextHop == nil {
+							nextHop = net.IPv6zero
+						}
+						mpReach := packet.ConstructIPv6MPReachNLRI(protoFamily, nextHop, nil, nil)
+						pathAttrs = packet.AddMPReachNLRIToPathAttrs(packet.CopyPathAttrs(pathAttrs), mpReach)
+					}
+					entries = append(entries, mrt.MRTRIBEntry{peerIndex[peerIP], uint32(now.Unix()), pathAttrs})
+				}
+			}
+			if len(entries) == 0 {
+				continue
+			}
+
+			msg, err := mrt.NewMRTRIBMessage(now, protoFamily, seqNum, dest.NLRI.GetIPPrefix(), entries)
+			if err != nil {
+				s.logger.Err("Failed to construct MRT RIB entry for", dest.NLRI.GetCIDR(), "error:", err)
+				break
+			}
+			if err = writer.Write(msg); err != nil {
+				s.logger.Err("Failed to write MRT RIB entry for", dest.NLRI.GetCIDR(), "error:", err)
+				return
+			}
+			seqNum++
+		}
+	}
+	s.logger.Infof("Dumped %d BGP destinations to MRT file %s", seqNum, writer.GetFileName())
+}
@@ -0,0 +1,146 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// orf.go
+package server
+
+import (
+	"l3/bgp/packet"
+	bgprib "l3/bgp/rib"
+	"sort"
+)
+
+type orfPrefixEntries []*packet.ORFPrefixEntry
+
+func (o orfPrefixEntries) Len() int {
+	return len(o)
+}
+
+func (o orfPrefixEntries) Swap(i, j int) {
+	o[i], o[j] = o[j], o[i]
+}
+
+func (o orfPrefixEntries) Less(i, j int) bool {
+	return o[i].Sequence < o[j].Sequence
+}
+
+func (p *Peer) hasORFEntries() bool {
+	for _, entries := range p.orfPrefix {
+		if len(entries) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Prefixes are permitted when no ORF entries are received. Otherwise the first matching entry in sequence order
+// decides, and prefixes that match no entry are denied.
+func (p *Peer) isPermittedByORF(dest *bgprib.Destination) bool {
+	entries := p.orfPrefix[dest.GetProtocolFamily()]
+	if len(entries) == 0 {
+		return true
+	}
+
+	prefix := dest.NLRI.GetIPPrefix()
+	for _, entry := range entries {
+		if entry.Matches(prefix.Prefix, prefix.Length) {
+			return entry.Match == packet.ORFMatchPermit
+		}
+	}
+	return false
+}
+
+func (p *Peer) applyORFEntries(protoFamily uint32, entries []*packet.ORFPrefixEntry) {
+	pending, ok := p.orfPending[protoFamily]
+	if !ok {
+		pending = append(orfPrefixEntries(nil), p.orfPrefix[protoFamily]...)
+	}
+
+	for _, entry := range entries {
+		switch entry.Action {
+		case packet.ORFActionRemoveAll:
+			pending = nil
+
+		case packet.ORFActionAdd, packet.ORFActionRemove:
+			for idx, pendingEntry := range pending {
+				if pendingEntry.Sequence == entry.Sequence {
+					pending = append(pending[:idx], pending[idx+1:]...)
+					break
+				}
+			}
+			if entry.Action == packet.ORFActionAdd {
+				pending = append(pending, entry)
+			}
+		}
+	}
+
+	sort.Sort(pending)
+	p.orfPending[protoFamily] = pending
+}
+
+func (p *Peer) activateORFEntries(protoFamily uint32) bool {
+	pending, ok := p.orfPending[protoFamily]
+	if !ok {
+		return false
+	}
+
+	p.logger.Infof("Neighbor %s: Activate %d ORF entries for protocol family %d",
+		p.NeighborConf.Neighbor.NeighborAddress, len(pending), protoFamily)
+	p.orfPrefix[protoFamily] = pending
+	delete(p.orfPending, protoFamily)
+	return true
+}
+
+func (p *Peer) ProcessORF(protoFamily uint32, refreshMsg *packet.BGPRouteRefresh,
+	updated map[uint32]map[*bgprib.Path][]*bgprib.Destination) {
+	if !p.NeighborConf.IsORFPrefixReceive(protoFamily) {
+		p.logger.Errf("Neighbor %s: ORF for protocol family %d is not negotiated",
+			p.NeighborConf.Neighbor.NeighborAddress, protoFamily)
+		return
+	}
+
+	for _, orf := range refreshMsg.ORFs {
+		if orf.Type != packet.ORFTypeAddressPrefix {
+			p.logger.Infof("Neighbor %s: Ignore ORF type %d", p.NeighborConf.Neighbor.NeighborAddress, orf.Type)
+			continue
+		}
+		p.applyORFEntries(protoFamily, orf.Entries)
+	}
+
+	if refreshMsg.WhenToRefresh == packet.ORFWhenToRefreshDefer {
+		return
+	}
+	p.refreshORF(protoFamily, updated)
+}
+
+// Routes that are denied by the new ORF entries are withdrawn and the routes that are now permitted are
+// advertised. The Adj-RIB-Out is updated outside the update group so that the other members are not affected.
+func (p *Peer) refreshORF(protoFamily uint32, updated map[uint32]map[*bgprib.Path][]*bgprib.Destination) {
+	if !p.activateORFEntries(protoFamily) {
+		return
+	}
+
+	p.splitFromUpdateGroup()
+	p.SendUpdate(updated, make([]*bgprib.Destination, 0), make([]*bgprib.Destination, 0))
+	p.joinUpdateGroup()
+}
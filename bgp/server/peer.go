@@ -32,10 +32,12 @@ import (
 	"l3/bgp/packet"
 	bgppolicy "l3/bgp/policy"
 	bgprib "l3/bgp/rib"
+	"l3/bgp/rpki"
 	"net"
 	"runtime"
 	"strings"
 	"sync/atomic"
+	"time"
 	"utils/logging"
 	"utils/patriciaDB"
 	utilspolicy "utils/policy"
@@ -51,6 +53,7 @@ type AdjRIBPolicyParams struct {
 	CreateType      int
 	DeleteType      int
 	Route           *bgprib.AdjRIBRoute
+	Path            *bgprib.Path
 	Peer            *Peer
 	Accept          int
 	PolicyEngine    *bgppolicy.AdjRibPPolicyEngine
@@ -59,16 +62,59 @@ type AdjRIBPolicyParams struct {
 	updatedAddPaths *([]*bgprib.Destination)
 }
 
+func (params *AdjRIBPolicyParams) getPathAttrs() []packet.BGPPathAttr {
+	if params.Path != nil {
+		return params.Path.PathAttrs
+	}
+
+	if params.Route != nil {
+		for _, path := range params.Route.GetPathMap() {
+			return path.PathAttrs
+		}
+	}
+	return nil
+}
+
+func (params *AdjRIBPolicyParams) getValidationState() rpki.ValidationState {
+	if params.Route == nil {
+		return rpki.ValidationStateNotFound
+	}
+
+	path := params.Path
+	if path == nil {
+		for _, path = range params.Route.GetPathMap() {
+			break
+		}
+	}
+	if path == nil {
+		return rpki.ValidationStateNotFound
+	}
+	return path.GetValidationState(params.Route.NLRI)
+}
+
 type Peer struct {
-	server       *BGPServer
-	logger       *logging.Writer
-	locRib       *bgprib.LocRib
-	NeighborConf *base.NeighborConf
-	fsmManager   *fsm.FSMManager
-	active       bool
-	ifIdx        int32
-	ribIn        map[uint32]map[string]*bgprib.AdjRIBRoute
-	ribOut       map[uint32]map[string]*bgprib.AdjRIBRoute
+	server            *BGPServer
+	logger            *logging.Writer
+	locRib            *bgprib.LocRib
+	importRib         peerRib
+	NeighborConf      *base.NeighborConf
+	fsmManager        *fsm.FSMManager
+	active            bool
+	ifIdx             int32
+	conn              *net.Conn
+	ribIn             map[uint32]map[string]*bgprib.AdjRIBRoute
+	ribOut            map[uint32]map[string]*bgprib.AdjRIBRoute
+	staleFamily       map[uint32]bool
+	grTimer           *time.Timer
+	gshutTimer        *time.Timer
+	gshutReason       string
+	advertiseWithheld bool
+	defaultOriginated map[uint32]bool
+	locRibMatches     map[string]map[string]bool
+	updateGroup       *UpdateGroup
+	orfPrefix         map[uint32]orfPrefixEntries
+	orfPending        map[uint32]orfPrefixEntries
+	sourceExcluded    map[uint32]map[string]bool
 }
 
 func NewPeer(server *BGPServer, locRib *bgprib.LocRib, globalConf *config.GlobalConfig,
@@ -76,16 +122,24 @@ func NewPeer(server *BGPServer, locRib *bgprib.LocRib, globalConf *config.Global
 	server.logger.Info("NewPeer - ip:", peerConf.NeighborAddress, "ifIndex:", peerConf.IfIndex)
 
 	peer := Peer{
-		server: server,
-		logger: server.logger,
-		locRib: locRib,
-		active: false,
-		ifIdx:  -1,
-		ribIn:  make(map[uint32]map[string]*bgprib.AdjRIBRoute),
-		ribOut: make(map[uint32]map[string]*bgprib.AdjRIBRoute),
+		server:            server,
+		logger:            server.logger,
+		locRib:            locRib,
+		importRib:         locRib,
+		active:            false,
+		ifIdx:             -1,
+		ribIn:             make(map[uint32]map[string]*bgprib.AdjRIBRoute),
+		ribOut:            make(map[uint32]map[string]*bgprib.AdjRIBRoute),
+		staleFamily:       make(map[uint32]bool),
+		defaultOriginated: make(map[uint32]bool),
+		locRibMatches:     make(map[string]map[string]bool),
+		sourceExcluded:    make(map[uint32]map[string]bool),
+		orfPrefix:         make(map[uint32]orfPrefixEntries),
+		orfPending:        make(map[uint32]orfPrefixEntries),
 	}
 
 	peer.NeighborConf = base.NewNeighborConf(peer.logger, globalConf, peerGroup, peerConf)
+	peer.NeighborConf.RestartDeadline = server.restartDeadline
 
 	if !peer.IsConfigured() {
 		peer.logger.Infof("NewPeer - Neighbor is not ready to be started, ip:",
@@ -100,7 +154,7 @@ func NewPeer(server *BGPServer, locRib *bgprib.LocRib, globalConf *config.Global
 	}
 
 	peer.fsmManager = fsm.NewFSMManager(peer.logger, peer.NeighborConf, server.BGPPktSrcCh,
-		server.PeerFSMConnCh, server.ReachabilityCh)
+		server.PeerFSMConnCh, server.ReachabilityCh, server.mrtUpdates)
 	return &peer
 }
 
@@ -254,6 +308,10 @@ func (p *Peer) IsDisabled() bool {
 	return p.NeighborConf.RunningConf.Disabled
 }
 
+func (p *Peer) IsDynamic() bool {
+	return p.NeighborConf.RunningConf.Dynamic
+}
+
 func (p *Peer) IsActive() bool {
 	return p.active
 }
@@ -287,6 +345,12 @@ func (p *Peer) Init() {
 		return
 	}
 
+	if p.NeighborConf.IsAdminShutdown() {
+		p.logger.Info("Init - Neighbor is administratively shut down, ip:", p.NeighborConf.Neighbor.NeighborAddress,
+			"ifIndex:", p.NeighborConf.Neighbor.Config.IfIndex)
+		return
+	}
+
 	p.logger.Debug("Init - adjribinfilter:", p.NeighborConf.RunningConf.AdjRIBInFilter, "adjriboutfilter:",
 		p.NeighborConf.RunningConf.AdjRIBOutFilter)
 	if p.NeighborConf.RunningConf.AdjRIBInFilter != "" {
@@ -300,7 +364,7 @@ func (p *Peer) Init() {
 	if p.fsmManager == nil {
 		p.logger.Infof("Init - Instantiating new FSM Manager for neighbor %s", p.NeighborConf.Neighbor.NeighborAddress)
 		fsmMgr = fsm.NewFSMManager(p.logger, p.NeighborConf, p.server.BGPPktSrcCh,
-			p.server.PeerFSMConnCh, p.server.ReachabilityCh)
+			p.server.PeerFSMConnCh, p.server.ReachabilityCh, p.server.mrtUpdates)
 	} else {
 		fsmMgr = p.fsmManager
 	}
@@ -334,6 +398,8 @@ func (p *Peer) Cleanup() {
 	}
 
 	p.active = false
+	p.ClearStaleRoutes()
+	p.leaveUpdateGroup()
 
 	if p.NeighborConf.RunningConf.AdjRIBInFilter != "" {
 		p.RemoveAdjRIBFilter(p.server.ribInPE, p.NeighborConf.RunningConf.AdjRIBInFilter, bgprib.AdjRIBDirIn)
@@ -368,6 +434,7 @@ func (p *Peer) StopFSM(msg string) {
 
 func (p *Peer) MaxPrefixesExceeded() {
 	if p.NeighborConf.RunningConf.MaxPrefixesDisconnect {
+		p.NeighborConf.SetShutdownCommunication(packet.BGPCeaseMaxPrefixes, "")
 		p.Command(int(fsm.BGPEventAutoStop), fsm.BGPCmdReasonMaxPrefixExceeded)
 	}
 }
@@ -387,6 +454,13 @@ func (p *Peer) AcceptConn(conn *net.TCPConn) {
 		conn.Close()
 		return
 	}
+
+	if !p.NeighborConf.IsAuthReady() {
+		p.logger.Errf("Neighbor %s: Key chain %s is not configured, refuse the connection without authentication",
+			p.NeighborConf.Neighbor.NeighborAddress, p.NeighborConf.RunningConf.KeyChain)
+		conn.Close()
+		return
+	}
 	p.fsmManager.AcceptCh <- conn
 }
 
@@ -409,16 +483,34 @@ func (p *Peer) BfdFaultCleared() {
 	p.fsmManager.BfdStatusCh <- true
 }
 
+// getAddPathsMaxTx returns the number of additional paths the Loc-RIB needs to select for the peer
 func (p *Peer) getAddPathsMaxTx() int {
-	return int(p.NeighborConf.Neighbor.State.AddPathsMaxTx)
+	maxTx := 0
+	for _, addPathsConf := range p.NeighborConf.AddPathsTxFamily {
+		count := int(addPathsConf.MaxTx)
+		if addPathsConf.TxMode == config.AddPathsTxModeBestExternal {
+			count = int(config.AddPathsMaxTxAll)
+		}
+		if count > maxTx {
+			maxTx = count
+		}
+	}
+	return maxTx
 }
 
 func (p *Peer) clearRibOut() {
+	p.leaveUpdateGroup()
 	p.ribIn = nil
 	p.ribOut = nil
 	p.ribIn = make(map[uint32]map[string]*bgprib.AdjRIBRoute)
 	p.ribOut = make(map[uint32]map[string]*bgprib.AdjRIBRoute)
 	p.initAdjRIBTables()
+	p.advertiseWithheld = false
+	p.defaultOriginated = make(map[uint32]bool)
+	p.locRibMatches = make(map[string]map[string]bool)
+	p.sourceExcluded = make(map[uint32]map[string]bool)
+	p.orfPrefix = make(map[uint32]orfPrefixEntries)
+	p.orfPending = make(map[uint32]orfPrefixEntries)
 }
 
 func (p *Peer) ProcessBfd(add bool) {
@@ -460,6 +552,7 @@ func (p *Peer) PeerConnEstablished(conn *net.Conn) {
 	hostSplit := strings.Split(host, "%")
 	host = hostSplit[0]
 	p.NeighborConf.Neighbor.Transport.Config.LocalAddress = net.ParseIP(host)
+	p.conn = conn
 	p.NeighborConf.PeerConnEstablished()
 	p.clearRibOut()
 	//p.Server.PeerConnEstCh <- p.Neighbor.NeighborAddress.String()
@@ -470,6 +563,7 @@ func (p *Peer) PeerConnBroken(fsmCleanup bool) {
 		p.NeighborConf.Neighbor.Transport.Config.LocalAddress = nil
 		//p.Server.PeerConnBrokenCh <- p.Neighbor.NeighborAddress.String()
 	}
+	p.conn = nil
 	p.NeighborConf.PeerConnBroken()
 	p.clearRibOut()
 }
@@ -530,7 +624,7 @@ func (p *Peer) processWithdraws(protoFamily uint32, nlris *[]packet.NLRI) {
 		if !route.DoesPathsExist() {
 			p.logger.Infof("Neighbor %s: remove nlri %s protocol family %s from RIB-In",
 				p.NeighborConf.RunningConf.NeighborAddress, ip, protoFamily)
-			p.checkRIBInFilter(nlri, route, false)
+			p.checkRIBInFilter(nlri, route, nil, false)
 			delete(p.ribIn[protoFamily], ip)
 		}
 
@@ -548,21 +642,28 @@ func (p *Peer) processWithdraws(protoFamily uint32, nlris *[]packet.NLRI) {
 	(*nlris) = (*nlris)[:idx]
 }
 
-func (p *Peer) checkAdjRIBFilter(nlri packet.NLRI, route *bgprib.AdjRIBRoute, pe *bgppolicy.AdjRibPPolicyEngine,
-	policyDir int, create bool) bool {
+func (p *Peer) checkAdjRIBFilter(nlri packet.NLRI, route *bgprib.AdjRIBRoute, path *bgprib.Path,
+	pe *bgppolicy.AdjRibPPolicyEngine, policyDir int, create bool) bool {
 	if route != nil {
-		if len(route.PolicyList) > 0 {
+		// The community and RPKI conditions depend on the path, the filter is evaluated again when the path changes
+		if len(route.PolicyList) > 0 && (path == nil || path == route.FilterPath) {
 			return true
 		}
 
+		bgppolicy.UpdateAdjRIBRoutePolicyState(route, bgppolicy.DelAll, "", "")
+		route.ActionList = nil
+		route.FilterPath = path
+
 		peEntity := utilspolicy.PolicyEngineFilterEntityParams{
 			DestNetIp: route.NLRI.GetCIDR(),
 			Neighbor:  p.NeighborConf.RunningConf.NeighborAddress.String(),
 		}
 
 		callbackInfo := &AdjRIBPolicyParams{
-			Peer:  p,
-			Route: route,
+			Peer:         p,
+			Route:        route,
+			Path:         path,
+			PolicyEngine: pe,
 		}
 
 		if create {
@@ -583,27 +684,108 @@ func (p *Peer) checkAdjRIBFilter(nlri packet.NLRI, route *bgprib.AdjRIBRoute, pe
 	return false
 }
 
-func (p *Peer) checkRIBInFilter(nlri packet.NLRI, route *bgprib.AdjRIBRoute, create bool) bool {
+func (p *Peer) checkRIBInFilter(nlri packet.NLRI, route *bgprib.AdjRIBRoute, path *bgprib.Path,
+	create bool) bool {
 	if p.NeighborConf.Neighbor.Config.AdjRIBInFilter == "" {
 		p.logger.Debugf("Peer %s - RIB In filter is not set", p.NeighborConf.Neighbor.NeighborAddress)
 		return true
 	}
 
-	return p.checkAdjRIBFilter(nlri, route, p.server.ribInPE, policyCommonDefs.PolicyPath_Import, create)
+	return p.checkAdjRIBFilter(nlri, route, path, p.server.ribInPE, policyCommonDefs.PolicyPath_Import, create)
 }
 
-func (p *Peer) checkRIBOutFilter(nlri packet.NLRI, route *bgprib.AdjRIBRoute, create bool) bool {
+func (p *Peer) checkRIBOutFilter(nlri packet.NLRI, route *bgprib.AdjRIBRoute, path *bgprib.Path,
+	create bool) bool {
 	if p.NeighborConf.Neighbor.Config.AdjRIBOutFilter == "" {
 		p.logger.Debugf("Peer %s - RIB Out filter is not set", p.NeighborConf.Neighbor.NeighborAddress)
 		return true
 	}
 
-	return p.checkAdjRIBFilter(nlri, route, p.server.ribOutPE, policyCommonDefs.PolicyPath_Export, create)
+	return p.checkAdjRIBFilter(nlri, route, path, p.server.ribOutPE, policyCommonDefs.PolicyPath_Export, create)
+}
+
+func (p *Peer) applyCommunityActions(route *bgprib.AdjRIBRoute, path *bgprib.Path,
+	actionPaths map[*bgprib.Path]map[string]*bgprib.Path) *bgprib.Path {
+	if path == nil || len(route.ActionList) == 0 {
+		return path
+	}
+
+	key := strings.Join(route.ActionList, ",")
+	if _, ok := actionPaths[path]; !ok {
+		actionPaths[path] = make(map[string]*bgprib.Path)
+	}
+	if actionPath, ok := actionPaths[path][key]; ok {
+		return actionPath
+	}
+
+	pathAttrs, applied := p.server.policyManager.CommunityDB.ApplyActions(route.ActionList, path.PathAttrs)
+	if !applied {
+		return path
+	}
+
+	actionPath := path.CloneWithPathAttrs(pathAttrs)
+	actionPaths[path][key] = actionPath
+	return actionPath
 }
 
-func (p *Peer) processUpdates(protoFamily uint32, nlris *[]packet.NLRI, path *bgprib.Path) {
+// applyDampeningActions sets the dampening parameters from the policy actions on the path received from the peer
+func (p *Peer) applyDampeningActions(route *bgprib.AdjRIBRoute, path, actionPath *bgprib.Path,
+	actionPaths map[*bgprib.Path]map[string]*bgprib.Path) *bgprib.Path {
+	if path == nil || len(route.ActionList) == 0 {
+		return actionPath
+	}
+
+	dampConf := p.server.policyManager.DampeningDB.GetConfig(route.ActionList)
+	if dampConf == nil {
+		return actionPath
+	}
+
+	if actionPath != path {
+		actionPath.SetDampeningConfig(dampConf)
+		return actionPath
+	}
+
+	key := strings.Join(route.ActionList, ",")
+	if _, ok := actionPaths[path]; !ok {
+		actionPaths[path] = make(map[string]*bgprib.Path)
+	}
+	if dampPath, ok := actionPaths[path][key]; ok {
+		return dampPath
+	}
+
+	dampPath := path.CloneWithPathAttrs(path.PathAttrs)
+	dampPath.SetDampeningConfig(dampConf)
+	actionPaths[path][key] = dampPath
+	return dampPath
+}
+
+// getActionPath applies the policy actions of the route to the path received from the peer. RIB-In keeps the
+// received path and the path with the actions applied is installed in Loc-RIB.
+func (p *Peer) getActionPath(route *bgprib.AdjRIBRoute, path *bgprib.Path,
+	actionPaths map[*bgprib.Path]map[string]*bgprib.Path) *bgprib.Path {
+	actionPath := p.applyCommunityActions(route, path, actionPaths)
+	return p.applyDampeningActions(route, path, actionPath, actionPaths)
+}
+
+func (p *Peer) addActionPathNLRIs(route *bgprib.AdjRIBRoute, actionNLRIs map[*bgprib.Path][]packet.NLRI,
+	actionPaths map[*bgprib.Path]map[string]*bgprib.Path) map[*bgprib.Path][]packet.NLRI {
+	if actionNLRIs == nil {
+		actionNLRIs = make(map[*bgprib.Path][]packet.NLRI)
+	}
+	for pathId, path := range route.GetPathMap() {
+		actionPath := p.getActionPath(route, path, actionPaths)
+		actionNLRIs[actionPath] = append(actionNLRIs[actionPath],
+			packet.ConstructNLRIFromPathIdAndNLRI(route.NLRI, pathId))
+	}
+	return actionNLRIs
+}
+
+func (p *Peer) processUpdates(protoFamily uint32, nlris *[]packet.NLRI,
+	path *bgprib.Path) map[*bgprib.Path][]packet.NLRI {
 	var ok bool
 	var route *bgprib.AdjRIBRoute
+	actionPaths := make(map[*bgprib.Path]map[string]*bgprib.Path)
+	actionNLRIs := make(map[*bgprib.Path][]packet.NLRI)
 	total := len(*nlris)
 	last := total - 1
 	idx := 0
@@ -638,7 +820,7 @@ func (p *Peer) processUpdates(protoFamily uint32, nlris *[]packet.NLRI, path *bg
 			continue
 		}
 
-		accept := p.checkRIBInFilter(nlri, route, true)
+		accept := p.checkRIBInFilter(nlri, route, path, true)
 		route.Accept = accept
 		if !accept {
 			p.logger.Infof("Neighbor %s: filter nlri %s", p.NeighborConf.RunningConf.NeighborAddress, ip)
@@ -647,9 +829,37 @@ func (p *Peer) processUpdates(protoFamily uint32, nlris *[]packet.NLRI, path *bg
 			last--
 			continue
 		}
+
+		actionPath := p.getActionPath(route, path, actionPaths)
+		if actionPath != path {
+			p.logger.Infof("Neighbor %s: nlri %s path changed by policy actions %v",
+				p.NeighborConf.RunningConf.NeighborAddress, ip, route.ActionList)
+			actionNLRIs[actionPath] = append(actionNLRIs[actionPath], nlri)
+			(*nlris)[idx] = (*nlris)[last]
+			(*nlris)[last] = nil
+			last--
+			continue
+		}
 		idx++
 	}
 	(*nlris) = (*nlris)[:idx]
+	return actionNLRIs
+}
+
+func (p *Peer) processActionPathUpdates(protoFamily uint32, actionNLRIs map[*bgprib.Path][]packet.NLRI,
+	updated map[uint32]map[*bgprib.Path][]*bgprib.Destination, withdrawn []*bgprib.Destination,
+	updatedAddPaths []*bgprib.Destination) (map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+	[]*bgprib.Destination, []*bgprib.Destination) {
+	var addedAllPrefixes bool
+	for actionPath, nlris := range actionNLRIs {
+		updated, withdrawn, updatedAddPaths, addedAllPrefixes = p.importRib.ProcessUpdate(p.NeighborConf, actionPath,
+			nlris, make([]packet.NLRI, 0), protoFamily, p.server.AddPathCount, updated, withdrawn, updatedAddPaths)
+		if !addedAllPrefixes {
+			p.MaxPrefixesExceeded()
+			break
+		}
+	}
+	return updated, withdrawn, updatedAddPaths
 }
 
 func (p *Peer) AddRouteNLRIs(route *bgprib.AdjRIBRoute, pathNLRIs map[*bgprib.Path]map[uint32]*bgprib.FilteredRoutes,
@@ -681,7 +891,13 @@ func (p *Peer) AdjRIBInPolicyUpdated(adjRibDir bgprib.AdjRIBDir, data interface{
 	updateFunc utilspolicy.PolicyApplyfunc) (map[uint32]map[*bgprib.Path][]*bgprib.Destination, []*bgprib.Destination,
 	[]*bgprib.Destination) {
 	filteredRoutes := make(map[*bgprib.Path]map[uint32]*bgprib.FilteredRoutes)
+	actionPaths := make(map[*bgprib.Path]map[string]*bgprib.Path)
+	actionNLRIs := make(map[uint32]map[*bgprib.Path][]packet.NLRI)
 	adjRIB := p.GetAdjRIB(adjRibDir)
+	pe := p.server.ribInPE
+	if adjRibDir == bgprib.AdjRIBDirOut {
+		pe = p.server.ribOutPE
+	}
 	for _, prefixRouteMap := range adjRIB {
 		for _, adjRoute := range prefixRouteMap {
 			if adjRoute == nil {
@@ -696,28 +912,278 @@ func (p *Peer) AdjRIBInPolicyUpdated(adjRibDir bgprib.AdjRIBDir, data interface{
 				PolicyList: adjRoute.PolicyList,
 			}
 			callbackInfo := &AdjRIBPolicyParams{
-				CreateType: utilspolicy.Invalid,
-				DeleteType: utilspolicy.Invalid,
-				Peer:       p,
-				Route:      adjRoute,
+				CreateType:   utilspolicy.Invalid,
+				DeleteType:   utilspolicy.Invalid,
+				Peer:         p,
+				Route:        adjRoute,
+				PolicyEngine: pe,
 			}
 
 			updateFunc(peEntity, data, callbackInfo)
 
 			if !adjRoute.Accept && callbackInfo.Accept == Accept {
-				filteredRoutes = p.AddRouteNLRIs(adjRoute, filteredRoutes, true)
+				if adjRibDir == bgprib.AdjRIBDirIn {
+					actionNLRIs[adjRoute.ProtocolFamily] = p.addActionPathNLRIs(adjRoute,
+						actionNLRIs[adjRoute.ProtocolFamily], actionPaths)
+				} else {
+					filteredRoutes = p.AddRouteNLRIs(adjRoute, filteredRoutes, true)
+				}
 			} else if adjRoute.Accept && callbackInfo.Accept == Reject {
 				filteredRoutes = p.AddRouteNLRIs(adjRoute, filteredRoutes, false)
 			}
 		}
 	}
 
-	updated, withdrawn, updatedAddPaths, addedAllPrefixes := p.locRib.ProcessFilteredRoutes(p.NeighborConf,
+	return p.processFilteredRoutes(filteredRoutes, actionNLRIs)
+}
+
+// processFilteredRoutes removes the rejected routes from Loc-RIB and adds the accepted routes with the policy
+// actions applied
+func (p *Peer) processFilteredRoutes(filteredRoutes map[*bgprib.Path]map[uint32]*bgprib.FilteredRoutes,
+	actionNLRIs map[uint32]map[*bgprib.Path][]packet.NLRI) (map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+	[]*bgprib.Destination, []*bgprib.Destination) {
+	updated, withdrawn, updatedAddPaths, addedAllPrefixes := p.importRib.ProcessFilteredRoutes(p.NeighborConf,
 		filteredRoutes, p.server.AddPathCount)
 	if !addedAllPrefixes {
 		p.MaxPrefixesExceeded()
+		return updated, withdrawn, updatedAddPaths
 	}
 
+	for protoFamily, pathNLRIs := range actionNLRIs {
+		updated, withdrawn, updatedAddPaths = p.processActionPathUpdates(protoFamily, pathNLRIs, updated, withdrawn,
+			updatedAddPaths)
+	}
+	return updated, withdrawn, updatedAddPaths
+}
+
+// ApplyRIBInPolicy applies the inbound policy again to all the routes in RIB-In. Routes whose policy actions
+// changed are installed again in Loc-RIB with the new actions applied to the received paths.
+func (p *Peer) ApplyRIBInPolicy() (map[uint32]map[*bgprib.Path][]*bgprib.Destination, []*bgprib.Destination,
+	[]*bgprib.Destination) {
+	filteredRoutes := make(map[*bgprib.Path]map[uint32]*bgprib.FilteredRoutes)
+	actionPaths := make(map[*bgprib.Path]map[string]*bgprib.Path)
+	actionNLRIs := make(map[uint32]map[*bgprib.Path][]packet.NLRI)
+	for protoFamily, prefixRouteMap := range p.ribIn {
+		for _, route := range prefixRouteMap {
+			if route == nil {
+				continue
+			}
+
+			var path *bgprib.Path
+			for _, path = range route.GetPathMap() {
+				break
+			}
+
+			accepted := route.Accept
+			actions := strings.Join(route.ActionList, ",")
+			bgppolicy.UpdateAdjRIBRoutePolicyState(route, bgppolicy.DelAll, "", "")
+			route.PolicyHitCounter = 0
+			route.ActionList = nil
+			route.Accept = p.checkRIBInFilter(route.NLRI, route, path, true)
+			if accepted && !route.Accept {
+				filteredRoutes = p.AddRouteNLRIs(route, filteredRoutes, false)
+			} else if route.Accept && (!accepted || actions != strings.Join(route.ActionList, ",")) {
+				actionNLRIs[protoFamily] = p.addActionPathNLRIs(route, actionNLRIs[protoFamily], actionPaths)
+			}
+		}
+	}
+
+	return p.processFilteredRoutes(filteredRoutes, actionNLRIs)
+}
+
+func (p *Peer) SoftResetIn() (map[uint32]map[*bgprib.Path][]*bgprib.Destination, []*bgprib.Destination,
+	[]*bgprib.Destination) {
+	p.logger.Infof("Neighbor %s: Soft reset inbound", p.NeighborConf.Neighbor.NeighborAddress)
+
+	// RIB-In keeps the paths as received from the peer, the new policy is applied to them right away. The peer is
+	// also asked to resend its Adj-RIB-Out, the paths it resends replace the ones in RIB-In.
+	p.requestRouteRefresh()
+	return p.ApplyRIBInPolicy()
+}
+
+// requestRouteRefresh sends a ROUTE-REFRESH for the protocol families negotiated with the peer if the peer
+// advertised the route refresh capability
+func (p *Peer) requestRouteRefresh() {
+	if !p.NeighborConf.RouteRefresh || p.fsmManager == nil {
+		p.logger.Infof("Neighbor %s: Route refresh capability not negotiated, don't send route refresh",
+			p.NeighborConf.Neighbor.NeighborAddress)
+		return
+	}
+
+	for protoFamily := range p.NeighborConf.AfiSafiMap {
+		if !p.NeighborConf.PeerAfiSafiMap[protoFamily] {
+			continue
+		}
+		afi, safi := packet.GetAfiSafi(protoFamily)
+		p.fsmManager.SendRouteRefreshMsg(afi, safi)
+	}
+}
+
+func (p *Peer) ProcessRouteRefresh(protoFamily uint32, updated map[uint32]map[*bgprib.Path][]*bgprib.Destination) {
+	if !p.NeighborConf.AfiSafiMap[protoFamily] {
+		p.logger.Errf("Neighbor %s: Route refresh for protocol family %d is not configured",
+			p.NeighborConf.Neighbor.NeighborAddress, protoFamily)
+		return
+	}
+
+	p.logger.Infof("Neighbor %s: Route refresh for protocol family %d, resend RIB-Out",
+		p.NeighborConf.Neighbor.NeighborAddress, protoFamily)
+	p.splitFromUpdateGroup()
+	p.activateORFEntries(protoFamily)
+	p.ribOut[protoFamily] = make(map[string]*bgprib.AdjRIBRoute)
+	delete(p.sourceExcluded, protoFamily)
+	p.SendUpdate(updated, make([]*bgprib.Destination, 0), make([]*bgprib.Destination, 0))
+	p.joinUpdateGroup()
+}
+
+func (p *Peer) IsGracefulRestartHelper() bool {
+	return p.NeighborConf.Global.GracefulRestart && p.NeighborConf.GracefulRestartCap != nil
+}
+
+func (p *Peer) startGracefulRestartTimer(seconds uint32) {
+	p.stopGracefulRestartTimer()
+	peerIP := p.NeighborConf.Neighbor.NeighborAddress.String()
+	p.grTimer = time.AfterFunc(time.Duration(seconds)*time.Second, func() {
+		p.server.StaleTimerCh <- peerIP
+	})
+}
+
+func (p *Peer) stopGracefulRestartTimer() {
+	if p.grTimer != nil {
+		p.grTimer.Stop()
+		p.grTimer = nil
+	}
+}
+
+func (p *Peer) ClearStaleRoutes() {
+	p.stopGracefulRestartTimer()
+	p.staleFamily = make(map[uint32]bool)
+}
+
+func (p *Peer) MarkStaleRoutes() (map[uint32]map[*bgprib.Path][]*bgprib.Destination, []*bgprib.Destination,
+	[]*bgprib.Destination) {
+	peerIP := p.NeighborConf.Neighbor.NeighborAddress.String()
+	grCap := p.NeighborConf.GracefulRestartCap
+	retain := make(map[uint32]bool)
+	for _, afiSafi := range grCap.Value {
+		retain[packet.GetProtocolFamily(afiSafi.AFI, afiSafi.SAFI)] = true
+	}
+
+	sweep := make([]uint32, 0)
+	for protoFamily, _ := range p.NeighborConf.AfiSafiMap {
+		if p.importRib.MarkStaleUpdatesFromNeighbor(peerIP, protoFamily) == 0 && !p.staleFamily[protoFamily] {
+			continue
+		}
+		p.staleFamily[protoFamily] = true
+		if !retain[protoFamily] {
+			sweep = append(sweep, protoFamily)
+		}
+	}
+
+	updated, withdrawn, updatedAddPaths := p.RemoveStaleRoutes(sweep)
+	if len(p.staleFamily) > 0 {
+		p.logger.Infof("Neighbor %s: Retain stale routes for families %v, restart time %d", peerIP,
+			p.staleFamily, grCap.RestartTime)
+		p.startGracefulRestartTimer(uint32(grCap.RestartTime))
+	}
+	return updated, withdrawn, updatedAddPaths
+}
+
+func (p *Peer) ProcessGracefulRestart() (map[uint32]map[*bgprib.Path][]*bgprib.Destination, []*bgprib.Destination,
+	[]*bgprib.Destination) {
+	if len(p.staleFamily) == 0 {
+		return make(map[uint32]map[*bgprib.Path][]*bgprib.Destination), make([]*bgprib.Destination, 0),
+			make([]*bgprib.Destination, 0)
+	}
+
+	p.stopGracefulRestartTimer()
+	preserved := make(map[uint32]bool)
+	if grCap := p.NeighborConf.GracefulRestartCap; grCap != nil {
+		for _, afiSafi := range grCap.Value {
+			if afiSafi.IsForwardingStatePreserved() {
+				preserved[packet.GetProtocolFamily(afiSafi.AFI, afiSafi.SAFI)] = true
+			}
+		}
+	}
+
+	sweep := make([]uint32, 0)
+	for protoFamily, _ := range p.staleFamily {
+		if !preserved[protoFamily] {
+			sweep = append(sweep, protoFamily)
+		}
+	}
+
+	updated, withdrawn, updatedAddPaths := p.RemoveStaleRoutes(sweep)
+	if len(p.staleFamily) > 0 {
+		p.logger.Infof("Neighbor %s: Wait for End-of-RIB for families %v, stale path time %d",
+			p.NeighborConf.Neighbor.NeighborAddress, p.staleFamily, p.NeighborConf.Global.StalePathTime)
+		p.startGracefulRestartTimer(p.NeighborConf.Global.StalePathTime)
+	}
+	return updated, withdrawn, updatedAddPaths
+}
+
+func (p *Peer) SendEndOfRIB() {
+	if p.fsmManager == nil || p.NeighborConf.Neighbor.Transport.Config.LocalAddress == nil {
+		p.logger.Errf("Neighbor %s: Can't send End-of-RIB, FSM is not in Established state",
+			p.NeighborConf.Neighbor.NeighborAddress)
+		return
+	}
+
+	for protoFamily, _ := range p.NeighborConf.GetNegotiatedAfiSafiMap() {
+		if p.NeighborConf.Neighbor.State.EndOfRIBSent[protoFamily] {
+			continue
+		}
+		p.logger.Infof("Neighbor %s: Send End-of-RIB for protocol family %d", p.NeighborConf.Neighbor.NeighborAddress,
+			protoFamily)
+		atomic.AddUint32(&p.NeighborConf.Neighbor.State.Queues.Output, 1)
+		p.fsmManager.SendUpdateMsg(packet.NewBGPEndOfRIBMessage(protoFamily))
+		p.NeighborConf.SetEndOfRIBSent(protoFamily)
+	}
+}
+
+func (p *Peer) ProcessEndOfRIB(protoFamily uint32) (map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+	[]*bgprib.Destination, []*bgprib.Destination) {
+	p.logger.Infof("Neighbor %s: Received End-of-RIB for protocol family %d", p.NeighborConf.Neighbor.NeighborAddress,
+		protoFamily)
+	p.NeighborConf.SetEndOfRIBReceived(protoFamily)
+	if !p.staleFamily[protoFamily] {
+		return make(map[uint32]map[*bgprib.Path][]*bgprib.Destination), make([]*bgprib.Destination, 0),
+			make([]*bgprib.Destination, 0)
+	}
+	return p.RemoveStaleRoutes([]uint32{protoFamily})
+}
+
+func (p *Peer) RemoveAllStaleRoutes() (map[uint32]map[*bgprib.Path][]*bgprib.Destination, []*bgprib.Destination,
+	[]*bgprib.Destination) {
+	families := make([]uint32, 0)
+	for protoFamily, _ := range p.staleFamily {
+		families = append(families, protoFamily)
+	}
+	return p.RemoveStaleRoutes(families)
+}
+
+func (p *Peer) RemoveStaleRoutes(families []uint32) (map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+	[]*bgprib.Destination, []*bgprib.Destination) {
+	peerIP := p.NeighborConf.Neighbor.NeighborAddress.String()
+	updated := make(map[uint32]map[*bgprib.Path][]*bgprib.Destination)
+	withdrawn := make([]*bgprib.Destination, 0)
+	updatedAddPaths := make([]*bgprib.Destination, 0)
+
+	for _, protoFamily := range families {
+		p.logger.Infof("Neighbor %s: Remove stale routes for protocol family %d", peerIP, protoFamily)
+		famUpdated, famWithdrawn, famUpdatedAddPaths := p.importRib.RemoveStaleUpdatesFromNeighbor(peerIP,
+			p.NeighborConf, protoFamily, p.server.AddPathCount)
+		for family, pathDestMap := range famUpdated {
+			updated[family] = pathDestMap
+		}
+		withdrawn = append(withdrawn, famWithdrawn...)
+		updatedAddPaths = append(updatedAddPaths, famUpdatedAddPaths...)
+		delete(p.staleFamily, protoFamily)
+	}
+
+	if len(p.staleFamily) == 0 {
+		p.stopGracefulRestartTimer()
+	}
 	return updated, withdrawn, updatedAddPaths
 }
 
@@ -747,16 +1213,21 @@ func (p *Peer) ReceiveUpdate(pktInfo *packet.BGPPktSrc) (map[uint32]map[*bgprib.
 	mpReach, mpUnreach := packet.RemoveMPAttrs(&updateMsg.PathAttributes)
 	//remPath := bgprib.NewPath(p.locRib, p.neighborConf, updateMsg.PathAttributes, mpReach, RouteTypeEGP)
 	path := bgprib.NewPath(p.locRib, p.NeighborConf, updateMsg.PathAttributes, mpReach, bgprib.RouteTypeEGP)
+	if p.NeighborConf.RunningConf.Dampening != "" {
+		path.SetDampeningConfig(p.server.policyManager.DampeningDB.GetConfig(
+			[]string{p.NeighborConf.RunningConf.Dampening}))
+	}
 
+	var actionNLRIs map[*bgprib.Path][]packet.NLRI
 	p.processWithdraws(protoFamily, &updateMsg.WithdrawnRoutes)
 	if asLoop {
 		updateMsg.NLRI = make([]packet.NLRI, 0)
 	} else {
-		p.processUpdates(protoFamily, &updateMsg.NLRI, path)
+		actionNLRIs = p.processUpdates(protoFamily, &updateMsg.NLRI, path)
 	}
 
 	if len(updateMsg.WithdrawnRoutes) > 0 || len(updateMsg.NLRI) > 0 {
-		updated, withdrawn, updatedAddPaths, addedAllPrefixes = p.locRib.ProcessUpdate(p.NeighborConf, path,
+		updated, withdrawn, updatedAddPaths, addedAllPrefixes = p.importRib.ProcessUpdate(p.NeighborConf, path,
 			updateMsg.NLRI, updateMsg.WithdrawnRoutes, protoFamily, p.server.AddPathCount, updated, withdrawn,
 			updatedAddPaths)
 		if !addedAllPrefixes {
@@ -764,18 +1235,40 @@ func (p *Peer) ReceiveUpdate(pktInfo *packet.BGPPktSrc) (map[uint32]map[*bgprib.
 		}
 	}
 
+	if len(actionNLRIs) > 0 {
+		updated, withdrawn, updatedAddPaths = p.processActionPathUpdates(protoFamily, actionNLRIs, updated,
+			withdrawn, updatedAddPaths)
+		if p.server.bmpManager.IsEnabled() {
+			p.sendBMPActionPathUpdates(protoFamily, actionNLRIs)
+		}
+	}
+
 	if mpUnreach != nil {
 		mpUnreachProtoFamily := packet.GetProtocolFamily(mpUnreach.AFI, mpUnreach.SAFI)
 		p.processWithdraws(mpUnreachProtoFamily, &(mpUnreach.NLRI))
 	}
 
+	if mpReach != nil {
+		if malformed := packet.RemoveMalformedLinkStateNLRI(mpReach); len(malformed) > 0 {
+			p.logger.Errf("Neighbor %s: Treat %d malformed Link-State NLRI as withdrawn",
+				p.NeighborConf.Neighbor.NeighborAddress, len(malformed))
+			malformedProtoFamily := packet.GetProtocolFamily(mpReach.AFI, mpReach.SAFI)
+			p.processWithdraws(malformedProtoFamily, &malformed)
+			if len(malformed) > 0 {
+				updated, withdrawn, updatedAddPaths, _ = p.importRib.ProcessUpdate(p.NeighborConf, path,
+					make([]packet.NLRI, 0), malformed, malformedProtoFamily, p.server.AddPathCount, updated,
+					withdrawn, updatedAddPaths)
+			}
+		}
+	}
+
 	mpProtoFamilySame := false
 	if mpReach != nil {
 		if asLoop {
 			mpReach.NLRI = make([]packet.NLRI, 0)
 		} else {
 			mpReachProtoFamily = packet.GetProtocolFamily(mpReach.AFI, mpReach.SAFI)
-			p.processUpdates(mpReachProtoFamily, &(mpReach.NLRI), path)
+			actionNLRIs = p.processUpdates(mpReachProtoFamily, &(mpReach.NLRI), path)
 			if mpReachProtoFamily == mpUnreachProtoFamily {
 				mpProtoFamilySame = true
 				mpReachNLRI = mpReach.NLRI
@@ -784,7 +1277,7 @@ func (p *Peer) ReceiveUpdate(pktInfo *packet.BGPPktSrc) (map[uint32]map[*bgprib.
 	}
 
 	if mpUnreach != nil && len(mpUnreach.NLRI) > 0 {
-		updated, withdrawn, updatedAddPaths, addedAllPrefixes = p.locRib.ProcessUpdate(p.NeighborConf, path,
+		updated, withdrawn, updatedAddPaths, addedAllPrefixes = p.importRib.ProcessUpdate(p.NeighborConf, path,
 			mpReachNLRI, mpUnreach.NLRI, mpUnreachProtoFamily, p.server.AddPathCount, updated, withdrawn,
 			updatedAddPaths)
 		if !addedAllPrefixes {
@@ -793,7 +1286,7 @@ func (p *Peer) ReceiveUpdate(pktInfo *packet.BGPPktSrc) (map[uint32]map[*bgprib.
 	}
 
 	if mpReach != nil && !mpProtoFamilySame && len(mpReach.NLRI) > 0 {
-		updated, withdrawn, updatedAddPaths, addedAllPrefixes = p.locRib.ProcessUpdate(p.NeighborConf, path,
+		updated, withdrawn, updatedAddPaths, addedAllPrefixes = p.importRib.ProcessUpdate(p.NeighborConf, path,
 			mpReach.NLRI, make([]packet.NLRI, 0), mpReachProtoFamily, p.server.AddPathCount, updated, withdrawn,
 			updatedAddPaths)
 		if !addedAllPrefixes {
@@ -801,6 +1294,17 @@ func (p *Peer) ReceiveUpdate(pktInfo *packet.BGPPktSrc) (map[uint32]map[*bgprib.
 		}
 	}
 
+	if mpReach != nil && len(actionNLRIs) > 0 {
+		updated, withdrawn, updatedAddPaths = p.processActionPathUpdates(mpReachProtoFamily, actionNLRIs, updated,
+			withdrawn, updatedAddPaths)
+		if p.server.bmpManager.IsEnabled() {
+			p.sendBMPActionPathUpdates(mpReachProtoFamily, actionNLRIs)
+		}
+	}
+
+	if p.server.bmpManager.IsEnabled() {
+		p.sendBMPPostPolicyUpdate(updateMsg, mpReach, mpUnreach)
+	}
 	return updated, withdrawn, updatedAddPaths
 }
 
@@ -839,6 +1343,9 @@ func (p *Peer) updatePathAttrs(bgpMsg *packet.BGPMessage, path *bgprib.Path) boo
 		if p.NeighborConf.RunningConf.NextHopSelf {
 			packet.SetNextHop(bgpMsg, p.NeighborConf.Neighbor.Transport.Config.LocalAddress)
 		}
+	} else if p.isRouteServerClient() && path.NeighborConf != nil {
+		// Route server is transparent, AS path, next hop and MED of the client routes are not changed
+		packet.RemoveLocalPref(bgpMsg)
 	} else {
 		// Do change these path attrs for local routes
 		if path.NeighborConf != nil {
@@ -853,6 +1360,16 @@ func (p *Peer) updatePathAttrs(bgpMsg *packet.BGPMessage, path *bgprib.Path) boo
 		packet.RemoveLocalPref(bgpMsg)
 	}
 
+	if p.NeighborConf.IsGracefulShutdown() {
+		// Ask the peer to lower the preference of the paths before the session is torn down (RFC 8326)
+		communities := append([]uint32{}, packet.GetCommunities(updateMsg.PathAttributes)...)
+		communities = append(communities, packet.BGPCommunityGracefulShutdown)
+		updateMsg.PathAttributes = packet.SetCommunities(updateMsg.PathAttributes, communities)
+		if p.NeighborConf.IsInternal() {
+			packet.SetLocalPref(bgpMsg, 0)
+		}
+	}
+
 	if removeRRPathAttrs {
 		packet.RemoveOriginatorId(bgpMsg)
 		packet.RemoveClusterList(bgpMsg)
@@ -861,34 +1378,53 @@ func (p *Peer) updatePathAttrs(bgpMsg *packet.BGPMessage, path *bgprib.Path) boo
 	return true
 }
 
-func (p *Peer) sendUpdateMsg(msg *packet.BGPMessage, path *bgprib.Path) {
-	if p.fsmManager == nil {
-		p.logger.Errf("Can't send update, FSM Manager is not instantiated for neighbor %s",
-			p.NeighborConf.Neighbor.NeighborAddress)
-		return
-	}
+func (p *Peer) isSourcePeer(path *bgprib.Path) bool {
+	return path != nil && path.NeighborConf != nil &&
+		p.NeighborConf.RunningConf.NeighborAddress.String() == path.NeighborConf.RunningConf.NeighborAddress.String()
+}
 
+func (p *Peer) formatUpdateMsg(msg *packet.BGPMessage, path *bgprib.Path) bool {
 	if path != nil && path.NeighborConf != nil {
 		if path.NeighborConf.IsInternal() {
 
 			if p.NeighborConf.IsInternal() && !path.NeighborConf.IsRouteReflectorClient() &&
 				!p.NeighborConf.IsRouteReflectorClient() {
-				return
+				return false
 			}
 		}
+	}
 
-		// Don't send the update to the peer that sent the update.
-		if p.NeighborConf.RunningConf.NeighborAddress.String() ==
-			path.NeighborConf.RunningConf.NeighborAddress.String() {
-			return
-		}
+	return p.updatePathAttrs(msg, path)
+}
+
+func (p *Peer) sendFormattedUpdateMsg(msg *packet.BGPMessage) {
+	if p.fsmManager == nil {
+		p.logger.Errf("Can't send update, FSM Manager is not instantiated for neighbor %s",
+			p.NeighborConf.Neighbor.NeighborAddress)
+		return
 	}
 
-	if p.updatePathAttrs(msg, path) {
-		atomic.AddUint32(&p.NeighborConf.Neighbor.State.Queues.Output, 1)
-		p.fsmManager.SendUpdateMsg(msg)
+	atomic.AddUint32(&p.NeighborConf.Neighbor.State.Queues.Output, 1)
+	p.fsmManager.SendUpdateMsg(msg)
+}
+
+func (p *Peer) sendUpdateMsg(msg *packet.BGPMessage, path *bgprib.Path) {
+	// Don't send the update to the peer that sent the update.
+	if p.isSourcePeer(path) {
+		return
 	}
 
+	if p.formatUpdateMsg(msg, path) {
+		p.sendFormattedUpdateMsg(msg)
+	}
+}
+
+func (p *Peer) appendUpdateMsg(msgs []*updateMsgInfo, msg *packet.BGPMessage,
+	path *bgprib.Path) []*updateMsgInfo {
+	if p.formatUpdateMsg(msg, path) {
+		msgs = append(msgs, &updateMsgInfo{msg, path})
+	}
+	return msgs
 }
 
 func (p *Peer) isAdvertisable(path *bgprib.Path) bool {
@@ -900,36 +1436,66 @@ func (p *Peer) isAdvertisable(path *bgprib.Path) bool {
 			}
 		}
 
-		// Don't send the update to the peer that sent the update.
-		if p.NeighborConf.RunningConf.NeighborAddress.String() ==
-			path.NeighborConf.RunningConf.NeighborAddress.String() {
+		if packet.HasASLoop(path.PathAttrs, p.NeighborConf.RunningConf.PeerAS) {
 			return false
 		}
 
-		if packet.HasASLoop(path.PathAttrs, p.NeighborConf.RunningConf.PeerAS) {
+	}
+
+	if path != nil {
+		if path.HasCommunity(packet.BGPCommunityNoAdvertise) {
 			return false
 		}
 
+		if p.NeighborConf.IsExternal() && (path.HasCommunity(packet.BGPCommunityNoExport) ||
+			path.HasCommunity(packet.BGPCommunityNoExportSubconfed)) {
+			return false
+		}
 	}
 
 	return true
 }
 
-func (p *Peer) addPathFamilyToUpdated(pathAdded, protoFamilyAdded bool, path *bgprib.Path, protoFamily uint32,
-	updated map[*bgprib.Path]map[uint32][]packet.NLRI) (bool, bool, map[*bgprib.Path]map[uint32][]packet.NLRI) {
-	if !pathAdded {
+func (p *Peer) addNLRIToUpdated(path *bgprib.Path, protoFamily uint32, nlri packet.NLRI,
+	updated map[*bgprib.Path]map[uint32][]packet.NLRI) map[*bgprib.Path]map[uint32][]packet.NLRI {
+	if _, ok := updated[path]; !ok {
 		updated[path] = make(map[uint32][]packet.NLRI)
-		pathAdded = true
 	}
-	if !protoFamilyAdded {
+	if _, ok := updated[path][protoFamily]; !ok {
 		updated[path][protoFamily] = make([]packet.NLRI, 0)
-		protoFamilyAdded = true
 	}
-	return pathAdded, protoFamilyAdded, updated
+	updated[path][protoFamily] = append(updated[path][protoFamily], nlri)
+	return updated
+}
+
+func isExternalPath(path *bgprib.Path) bool {
+	return path != nil && path.NeighborConf != nil && path.NeighborConf.IsExternal()
+}
+
+// getAddPathsForTx returns the paths to advertise in addition to the best path for the Add-Path transmit mode
+func (p *Peer) getAddPathsForTx(dest *bgprib.Destination, addPathsConf config.AddPathsConfig) []*bgprib.Path {
+	switch addPathsConf.TxMode {
+	case config.AddPathsTxModeECMP:
+		return dest.GetECMPPaths()
+
+	case config.AddPathsTxModeBestExternal:
+		if isExternalPath(dest.LocRibPath) {
+			return nil
+		}
+		for _, path := range dest.AddPaths {
+			if isExternalPath(path) {
+				return []*bgprib.Path{path}
+			}
+		}
+		return nil
+	}
+
+	return dest.AddPaths
 }
 
 func (p *Peer) calculateAddPathsAdvertisements(dest *bgprib.Destination, path *bgprib.Path,
-	newUpdated map[*bgprib.Path]map[uint32][]packet.NLRI, withdrawList map[uint32][]packet.NLRI, addPathsTx int) (
+	newUpdated map[*bgprib.Path]map[uint32][]packet.NLRI, withdrawList map[uint32][]packet.NLRI,
+	addPathsConf config.AddPathsConfig, actionPaths map[*bgprib.Path]map[string]*bgprib.Path) (
 	map[*bgprib.Path]map[uint32][]packet.NLRI, map[uint32][]packet.NLRI) {
 	pathIdMap := make(map[uint32]*bgprib.Path)
 	ip := dest.NLRI.GetCIDR()
@@ -943,26 +1509,21 @@ func (p *Peer) calculateAddPathsAdvertisements(dest *bgprib.Destination, path *b
 	}
 
 	ribOutRoute := p.ribOut[protoFamily][ip]
-	canAdvertise := p.checkRIBOutFilter(dest.NLRI, ribOutRoute, true)
-	canWithdraw := p.checkRIBOutWithdraw(ribOutRoute)
-
-	pathAdded := false
-	protoFamilyAdded := false
-	if _, ok := newUpdated[path]; ok {
-		pathAdded = true
-		if _, ok := newUpdated[path][protoFamily]; ok {
-			protoFamilyAdded = true
-		}
+	filterPath := path
+	if filterPath == nil {
+		filterPath = dest.LocRibPath
 	}
+	canAdvertise := p.checkRIBOutFilter(dest.NLRI, ribOutRoute, filterPath, true)
+	canWithdraw := p.checkRIBOutWithdraw(ribOutRoute)
+	withheld := p.isConditionallyWithheld(dest) || !p.isPermittedByORF(dest)
 
-	if p.isAdvertisable(path) {
+	if p.isAdvertisable(path) && !withheld {
 		route := dest.LocRibPathRoute
 		if path != nil { // Loc-RIB path changed
 			if canAdvertise {
-				pathAdded, protoFamilyAdded, newUpdated = p.addPathFamilyToUpdated(pathAdded, protoFamilyAdded, path,
-					protoFamily, newUpdated)
-				nlri := packet.NewExtNLRI(route.OutPathId, dest.NLRI.GetIPPrefix())
-				newUpdated[path][protoFamily] = append(newUpdated[path][protoFamily], nlri)
+				outPath := p.applyCommunityActions(ribOutRoute, path, actionPaths)
+				newUpdated = p.addNLRIToUpdated(outPath, protoFamily,
+					packet.NewExtNLRI(route.OutPathId, dest.NLRI.GetIPPrefix()), newUpdated)
 			}
 		} else {
 			path = dest.LocRibPath
@@ -970,10 +1531,17 @@ func (p *Peer) calculateAddPathsAdvertisements(dest *bgprib.Destination, path *b
 		pathIdMap[route.OutPathId] = path
 	}
 
-	for i := 0; i < len(dest.AddPaths) && len(pathIdMap) < (addPathsTx-1); i++ {
-		route := dest.GetPathRoute(dest.AddPaths[i])
-		if route != nil && p.isAdvertisable(dest.AddPaths[i]) {
-			pathIdMap[route.OutPathId] = dest.AddPaths[i]
+	// MaxTx is the total number of paths sent for the prefix, the best path included. pathIdMap already holds the
+	// best path when it is advertised, so it is compared against MaxTx. The neighbor's AddPathsMaxTx is converted to
+	// MaxTx-1 in GetAddPathsConfig, so it sends the same number of paths as before.
+	addPaths := p.getAddPathsForTx(dest, addPathsConf)
+	for i := 0; i < len(addPaths) && len(pathIdMap) < int(addPathsConf.MaxTx) && !withheld; i++ {
+		if addPaths[i] == dest.LocRibPath {
+			continue
+		}
+		route := dest.GetPathRoute(addPaths[i])
+		if route != nil && p.isAdvertisable(addPaths[i]) {
+			pathIdMap[route.OutPathId] = addPaths[i]
 		}
 	}
 
@@ -988,10 +1556,9 @@ func (p *Peer) calculateAddPathsAdvertisements(dest *bgprib.Destination, path *b
 			delete(pathIdMap, ribOutPathId)
 		} else if ribOutPath != path {
 			if canAdvertise {
-				pathAdded, protoFamilyAdded, newUpdated = p.addPathFamilyToUpdated(pathAdded, protoFamilyAdded, path,
-					protoFamily, newUpdated)
-				nlri := packet.NewExtNLRI(ribOutPathId, dest.NLRI.GetIPPrefix())
-				newUpdated[path][protoFamily] = append(newUpdated[path][protoFamily], nlri)
+				outPath := p.applyCommunityActions(ribOutRoute, path, actionPaths)
+				newUpdated = p.addNLRIToUpdated(outPath, protoFamily,
+					packet.NewExtNLRI(ribOutPathId, dest.NLRI.GetIPPrefix()), newUpdated)
 			}
 			ribOutRoute.AddPath(ribOutPathId, path)
 			delete(pathIdMap, ribOutPathId)
@@ -1000,11 +1567,11 @@ func (p *Peer) calculateAddPathsAdvertisements(dest *bgprib.Destination, path *b
 
 	for pathId, path := range pathIdMap {
 		if canAdvertise {
-			pathAdded, protoFamilyAdded, newUpdated = p.addPathFamilyToUpdated(pathAdded, protoFamilyAdded, path,
-				protoFamily, newUpdated)
-			nlri := packet.NewExtNLRI(pathId, dest.NLRI.GetIPPrefix())
-			newUpdated[path][protoFamily] = append(newUpdated[path][protoFamily], nlri)
+			outPath := p.applyCommunityActions(ribOutRoute, path, actionPaths)
+			newUpdated = p.addNLRIToUpdated(outPath, protoFamily, packet.NewExtNLRI(pathId, dest.NLRI.GetIPPrefix()),
+				newUpdated)
 		}
+		p.excludeSourceMembers(protoFamily, packet.NewExtNLRI(pathId, dest.NLRI.GetIPPrefix()), path)
 		ribOutRoute.AddPath(pathId, path)
 		delete(pathIdMap, pathId)
 	}
@@ -1027,6 +1594,16 @@ func (p *Peer) checkRIBOutWithdraw(route *bgprib.AdjRIBRoute) bool {
 	return false
 }
 
+// EVPN routes carry the VTEP address as the next hop instead of the session address
+func (p *Peer) getMPNextHop(path *bgprib.Path, protoFamily uint32, localAddress net.IP) net.IP {
+	if packet.IsEVPNFamily(protoFamily) || (p.isRouteServerClient() && path.NeighborConf != nil) {
+		if nextHop := path.GetNextHop(protoFamily); nextHop != nil && !nextHop.IsUnspecified() {
+			return nextHop
+		}
+	}
+	return localAddress
+}
+
 func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destination, withdrawn,
 	updatedAddPaths []*bgprib.Destination) {
 	p.logger.Infof("Neighbor %s: Send update message valid routes:%v, withdraw routes:%v",
@@ -1037,9 +1614,14 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 		return
 	}
 
-	addPathsTx := p.getAddPathsMaxTx()
+	p.updateLocRibMatches(updated, withdrawn)
+	updated = p.evaluateConditionalAdvertisement(updated)
+	p.evaluateDefaultOriginate()
+
+	addPathsTxFamily := p.NeighborConf.AddPathsTxFamily
 	withdrawList := make(map[uint32][]packet.NLRI)
 	newUpdated := make(map[*bgprib.Path]map[uint32][]packet.NLRI)
+	actionPaths := make(map[*bgprib.Path]map[string]*bgprib.Path)
 	if len(withdrawn) > 0 {
 		for _, dest := range withdrawn {
 			if dest != nil {
@@ -1064,7 +1646,7 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 						continue
 					}
 
-					if addPathsTx > 0 {
+					if _, ok := addPathsTxFamily[protoFamily]; ok {
 						for pathId, _ := range route.GetPathMap() {
 							nlri := packet.NewExtNLRI(pathId, dest.NLRI.GetIPPrefix())
 							withdrawList[protoFamily] = append(withdrawList[protoFamily], nlri)
@@ -1079,7 +1661,7 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 	}
 
 	for protoFamily, pathDestMap := range updated {
-		if !p.NeighborConf.AfiSafiMap[protoFamily] {
+		if !p.NeighborConf.CanSendFamily(protoFamily) {
 			continue
 		}
 		if _, ok := p.ribOut[protoFamily]; !ok {
@@ -1088,17 +1670,18 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 		if _, ok := withdrawList[protoFamily]; !ok {
 			withdrawList[protoFamily] = make([]packet.NLRI, 0)
 		}
+		addPathsConf, addPathsTx := addPathsTxFamily[protoFamily]
 		for path, destinations := range pathDestMap {
 			for _, dest := range destinations {
 				if dest == nil {
 					continue
 				}
 				ip := dest.NLRI.GetCIDR()
-				if addPathsTx > 0 {
+				if addPathsTx {
 					newUpdated, withdrawList = p.calculateAddPathsAdvertisements(dest, path, newUpdated,
-						withdrawList, addPathsTx)
+						withdrawList, addPathsConf, actionPaths)
 				} else {
-					if !p.isAdvertisable(path) {
+					if !p.isAdvertisable(path) || p.isConditionallyWithheld(dest) || !p.isPermittedByORF(dest) {
 						if ribOutRoute := p.ribOut[protoFamily][ip]; ribOutRoute != nil &&
 							p.checkRIBOutWithdraw(ribOutRoute) {
 							withdrawList[protoFamily] = append(withdrawList[protoFamily], dest.NLRI)
@@ -1113,23 +1696,22 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 								protoFamily, dest.NLRI)
 						}
 						ribOutRoute := p.ribOut[protoFamily][ip]
+						advertised := len(ribOutRoute.GetPathMap()) > 0
 						for ribPathId, _ := range ribOutRoute.GetPathMap() {
 							if pathId != ribPathId {
 								ribOutRoute.RemovePath(ribPathId)
 							}
 						}
 						if ribOutPath := ribOutRoute.GetPath(pathId); ribOutPath == nil || ribOutPath != path {
-							if p.checkRIBOutFilter(dest.NLRI, ribOutRoute, true) {
-								if _, ok := newUpdated[path]; !ok {
-									newUpdated[path] = make(map[uint32][]packet.NLRI)
-								}
-								if _, ok := newUpdated[path][protoFamily]; !ok {
-									newUpdated[path][protoFamily] = make([]packet.NLRI, 0)
-								}
-								newUpdated[path][protoFamily] = append(newUpdated[path][protoFamily],
-									dest.NLRI.GetIPPrefix())
+							if p.checkRIBOutFilter(dest.NLRI, ribOutRoute, path, true) {
+								outPath := p.applyCommunityActions(ribOutRoute, path, actionPaths)
+								newUpdated = p.addNLRIToUpdated(outPath, protoFamily,
+									packet.StripPathId(dest.NLRI), newUpdated)
 							}
 						}
+						if !advertised {
+							p.excludeSourceMembers(protoFamily, packet.StripPathId(dest.NLRI), path)
+						}
 						ribOutRoute.AddPath(pathId, path)
 					}
 				}
@@ -1137,13 +1719,14 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 		}
 	}
 
-	if addPathsTx > 0 {
-		for _, dest := range updatedAddPaths {
+	for _, dest := range updatedAddPaths {
+		if addPathsConf, ok := addPathsTxFamily[dest.GetProtocolFamily()]; ok {
 			newUpdated, withdrawList = p.calculateAddPathsAdvertisements(dest, nil, newUpdated, withdrawList,
-				addPathsTx)
+				addPathsConf, actionPaths)
 		}
 	}
 
+	msgs := make([]*updateMsgInfo, 0)
 	if withdrawList != nil {
 		p.logger.Infof("Neighbor %s: Send update message withdraw routes:%+v",
 			p.NeighborConf.Neighbor.NeighborAddress, withdrawList)
@@ -1160,13 +1743,13 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 				pathAtts := make([]packet.BGPPathAttr, 0)
 				pathAtts = append(pathAtts, mpUnreachNLRI)
 				updateMsg = packet.NewBGPUpdateMessage(ipv4List, pathAtts, nil)
-				p.sendUpdateMsg(updateMsg.Clone(), nil)
+				msgs = p.appendUpdateMsg(msgs, updateMsg.Clone(), nil)
 				ipv4List = nil
 			}
 		}
 		if ipv4List != nil {
 			updateMsg = packet.NewBGPUpdateMessage(ipv4List, nil, nil)
-			p.sendUpdateMsg(updateMsg.Clone(), nil)
+			msgs = p.appendUpdateMsg(msgs, updateMsg.Clone(), nil)
 		}
 	}
 
@@ -1176,7 +1759,7 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 		var updateMsg *packet.BGPMessage
 		var ipv4List []packet.NLRI
 		protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
-		if nlriList, ok := pfNLRIMap[protoFamily]; ok {
+		if nlriList, ok := pfNLRIMap[protoFamily]; ok && !p.NeighborConf.IsExtendedNextHop(protoFamily) {
 			if len(nlriList) > 0 {
 				ipv4List = nlriList
 				delete(pfNLRIMap, protoFamily)
@@ -1185,13 +1768,14 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 
 		for protoFamily, nlriList := range pfNLRIMap {
 			if len(nlriList) > 0 {
-				mpReachNLRI := packet.ConstructIPv6MPReachNLRI(protoFamily, localAddress, nil, nlriList)
+				mpReachNLRI := packet.ConstructIPv6MPReachNLRI(protoFamily,
+					p.getMPNextHop(path, protoFamily, localAddress), nil, nlriList)
 				pa := packet.CopyPathAttrs(path.PathAttrs)
 				pa = packet.AddMPReachNLRIToPathAttrs(pa, mpReachNLRI)
 				updateMsg = packet.NewBGPUpdateMessage(nil, pa, ipv4List)
 				p.logger.Infof("Neighbor %s: Send update message valid routes:%+v, path attrs:%+v",
 					p.NeighborConf.Neighbor.NeighborAddress, nlriList, path.PathAttrs)
-				p.sendUpdateMsg(updateMsg.Clone(), path)
+				msgs = p.appendUpdateMsg(msgs, updateMsg.Clone(), path)
 				ipv4List = nil
 			}
 		}
@@ -1200,14 +1784,17 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 			p.logger.Infof("Neighbor %s: Send update message valid routes:%+v, path attrs:%+v",
 				p.NeighborConf.Neighbor.NeighborAddress, ipv4List, path.PathAttrs)
 			updateMsg := packet.NewBGPUpdateMessage(make([]packet.NLRI, 0), path.PathAttrs, ipv4List)
-			p.sendUpdateMsg(updateMsg.Clone(), path)
+			msgs = p.appendUpdateMsg(msgs, updateMsg.Clone(), path)
 		}
 	}
+
+	p.sendUpdateMsgsToGroup(msgs)
 }
 
 func (p *Peer) AdjRIBOutPolicyUpdated(data interface{}, updateFunc utilspolicy.PolicyApplyfunc) {
 	filteredRoutes := make(map[*bgprib.Path]map[uint32]*bgprib.FilteredRoutes)
 	adjRIB := p.GetAdjRIB(bgprib.AdjRIBDirOut)
+	pe := p.server.ribOutPE
 	for _, prefixRouteMap := range adjRIB {
 		for _, adjRoute := range prefixRouteMap {
 			if adjRoute == nil {
@@ -1222,10 +1809,11 @@ func (p *Peer) AdjRIBOutPolicyUpdated(data interface{}, updateFunc utilspolicy.P
 				PolicyList: adjRoute.PolicyList,
 			}
 			callbackInfo := &AdjRIBPolicyParams{
-				CreateType: utilspolicy.Invalid,
-				DeleteType: utilspolicy.Invalid,
-				Peer:       p,
-				Route:      adjRoute,
+				CreateType:   utilspolicy.Invalid,
+				DeleteType:   utilspolicy.Invalid,
+				Peer:         p,
+				Route:        adjRoute,
+				PolicyEngine: pe,
 			}
 
 			updateFunc(peEntity, data, callbackInfo)
@@ -1244,7 +1832,7 @@ func (p *Peer) AdjRIBOutPolicyUpdated(data interface{}, updateFunc utilspolicy.P
 		var updateMsg *packet.BGPMessage
 		var updateList, withdrawList []packet.NLRI
 		protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
-		if routesMap, ok := pfNLRIMap[protoFamily]; ok {
+		if routesMap, ok := pfNLRIMap[protoFamily]; ok && !p.NeighborConf.IsExtendedNextHop(protoFamily) {
 			if len(routesMap.Add) > 0 {
 				updateList = routesMap.Add
 			}
@@ -1259,7 +1847,8 @@ func (p *Peer) AdjRIBOutPolicyUpdated(data interface{}, updateFunc utilspolicy.P
 				var pa []packet.BGPPathAttr
 				if len(routesMap.Add) > 0 {
 					pa = packet.CopyPathAttrs(path.PathAttrs)
-					mpReachNLRI := packet.ConstructIPv6MPReachNLRI(protoFamily, localAddress, nil, routesMap.Add)
+					mpReachNLRI := packet.ConstructIPv6MPReachNLRI(protoFamily,
+						p.getMPNextHop(path, protoFamily, localAddress), nil, routesMap.Add)
 					pa = packet.AddMPReachNLRIToPathAttrs(pa, mpReachNLRI)
 				}
 
@@ -0,0 +1,298 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// peer_test.go
+package server
+
+import (
+	"l3/bgp/baseobjects"
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	bgppolicy "l3/bgp/policy"
+	bgprib "l3/bgp/rib"
+	"net"
+	"testing"
+	"utils/logging"
+)
+
+type testRouteMgr struct{}
+
+func (r *testRouteMgr) Start() {}
+func (r *testRouteMgr) GetNextHopInfo(ipAddr string, ifIndex int32) (*config.NextHopInfo, error) {
+	return &config.NextHopInfo{NextHopIp: ipAddr, IsReachable: true}, nil
+}
+func (r *testRouteMgr) CreateRoute(cfg *config.RouteConfig)                       {}
+func (r *testRouteMgr) DeleteRoute(cfg *config.RouteConfig)                       {}
+func (r *testRouteMgr) UpdateRoute(cfg *config.RouteConfig, op string)            {}
+func (r *testRouteMgr) CreateNextHopGroup(cfg *config.NextHopGroupConfig)         {}
+func (r *testRouteMgr) DeleteNextHopGroup(cfg *config.NextHopGroupConfig)         {}
+func (r *testRouteMgr) ApplyPolicy(applyList, undoList []*config.ApplyPolicyInfo) {}
+func (r *testRouteMgr) GetRoutes() (ri1 []*config.RouteInfo, ri2 []*config.RouteInfo) {
+	return ri1, ri2
+}
+func (r *testRouteMgr) GetInstalledRoutes() []*config.RouteConfig { return nil }
+
+// getAddPathsPeer returns an eBGP peer in AS 600 with a Loc-RIB that runs ECMP across two eBGP paths
+func getAddPathsPeer(t *testing.T) (*Peer, *config.GlobalConfig) {
+	logger, err := logging.NewLogger("bgpd", "BGP", true)
+	if err != nil {
+		t.Fatal("Failed to start the logger. Exiting!!")
+	}
+
+	gConf := &config.GlobalConfig{}
+	gConf.AS = 100
+	gConf.RouterId = net.ParseIP("10.1.10.100")
+	gConf.UseMultiplePaths = true
+	gConf.EBGPMaxPaths = 2
+	gConf.BestPath.ASPathMultipathRelax = true
+
+	s := &BGPServer{
+		logger:   logger,
+		ribOutPE: bgppolicy.NewAdjRibPolicyEngine(logger),
+	}
+	locRib := bgprib.NewLocRib(logger, &testRouteMgr{}, nil, gConf)
+	peerConf := config.NeighborConfig{NeighborAddress: net.ParseIP("10.0.9.1")}
+	peerConf.PeerAS = 600
+	return &Peer{
+		server:            s,
+		logger:            logger,
+		locRib:            locRib,
+		importRib:         locRib,
+		NeighborConf:      base.NewNeighborConf(logger, gConf, nil, peerConf),
+		ribOut:            make(map[uint32]map[string]*bgprib.AdjRIBRoute),
+		defaultOriginated: make(map[uint32]bool),
+		locRibMatches:     make(map[string]map[string]bool),
+	}, gConf
+}
+
+// addPeerPath adds a path from the neighbor ip in peerAS to the destination. The neighbor address is the next hop
+// and the BGP identifier of the path.
+func addPeerPath(p *Peer, gConf *config.GlobalConfig, dest *bgprib.Destination, ip string, peerAS uint32,
+	localPref uint32) *bgprib.Path {
+	peerConf := config.NeighborConfig{NeighborAddress: net.ParseIP(ip)}
+	peerConf.PeerAS = peerAS
+	nConf := base.NewNeighborConf(p.logger, gConf, nil, peerConf)
+	nConf.SetPeerAttrs(net.ParseIP(ip), 4, 3, 1, nil)
+
+	pathAttrs := make([]packet.BGPPathAttr, 0)
+	pathAttrs = append(pathAttrs, packet.NewBGPPathAttrOrigin(packet.BGPPathAttrOriginIGP))
+	// iBGP paths get a different first AS so that all the paths have the same AS path length
+	firstAS := peerAS
+	if peerAS == gConf.AS {
+		firstAS = 400
+	}
+	asSeg := packet.NewBGPAS4PathSegmentSeq()
+	asSeg.AppendAS(firstAS)
+	asSeg.AppendAS(1000)
+	asPath := packet.NewBGPPathAttrASPath()
+	asPath.ASSize = 4
+	asPath.AppendASPathSegment(asSeg)
+	pathAttrs = append(pathAttrs, asPath)
+	nextHop := packet.NewBGPPathAttrNextHop()
+	nextHop.Value = net.ParseIP(ip)
+	pathAttrs = append(pathAttrs, nextHop)
+	if localPref != 0 {
+		pref := packet.NewBGPPathAttrLocalPref()
+		pref.Value = localPref
+		pathAttrs = append(pathAttrs, pref)
+	}
+
+	path := bgprib.NewPath(p.locRib, nConf, pathAttrs, nil, bgprib.RouteTypeEGP)
+	path.SetReachabilityForNextHop(ip, bgprib.NewReachabilityInfo(ip, 0, 0, 0))
+	dest.AddOrUpdatePath(ip, 0, path)
+	return path
+}
+
+// getAddPathsDest returns a destination with the best path from 10.0.1.1, an ECMP path from 10.0.2.1 and two iBGP
+// paths. All the other paths are selected as additional paths.
+func getAddPathsDest(t *testing.T, p *Peer, gConf *config.GlobalConfig) *bgprib.Destination {
+	dest, _ := p.locRib.GetDest(packet.NewIPPrefix(net.ParseIP("20.1.1.0"), 24), ipv4Unicast, true)
+	addPeerPath(p, gConf, dest, "10.0.1.1", 200, 0)
+	addPeerPath(p, gConf, dest, "10.0.2.1", 300, 0)
+	addPeerPath(p, gConf, dest, "10.0.3.1", 100, 0)
+	addPeerPath(p, gConf, dest, "10.0.4.1", 100, 0)
+	dest.SelectRouteForLocRib(int(config.AddPathsMaxTxAll))
+
+	if dest.LocRibPath == nil || !isExternalPath(dest.LocRibPath) {
+		t.Fatal("Expected an eBGP best path, found", dest.LocRibPath)
+	}
+	if len(dest.AddPaths) != 3 {
+		t.Fatal("Expected 3 additional paths, found", len(dest.AddPaths))
+	}
+	if len(dest.GetECMPPaths()) != 2 {
+		t.Fatal("Expected 2 ECMP paths, found", len(dest.GetECMPPaths()))
+	}
+	return dest
+}
+
+// advertiseAddPaths returns the number of paths in the Adj-RIB-Out of the peer for the destination
+func advertiseAddPaths(p *Peer, dest *bgprib.Destination, addPathsConf config.AddPathsConfig) int {
+	p.ribOut = map[uint32]map[string]*bgprib.AdjRIBRoute{ipv4Unicast: make(map[string]*bgprib.AdjRIBRoute)}
+	p.calculateAddPathsAdvertisements(dest, nil, make(map[*bgprib.Path]map[uint32][]packet.NLRI),
+		make(map[uint32][]packet.NLRI), addPathsConf, make(map[*bgprib.Path]map[string]*bgprib.Path))
+	return len(p.ribOut[ipv4Unicast][dest.NLRI.GetCIDR()].GetPathMap())
+}
+
+func TestAddPathsTxAll(t *testing.T) {
+	p, gConf := getAddPathsPeer(t)
+	dest := getAddPathsDest(t, p, gConf)
+	addPathsConf := config.AddPathsConfig{MaxTx: config.AddPathsMaxTxAll, TxMode: config.AddPathsTxModeAll}
+
+	if paths := p.getAddPathsForTx(dest, addPathsConf); len(paths) != len(dest.AddPaths) {
+		t.Error("Expected all the", len(dest.AddPaths), "additional paths, found", len(paths))
+	}
+	if count := advertiseAddPaths(p, dest, addPathsConf); count != 4 {
+		t.Error("Expected the best path and 3 additional paths to be advertised, found", count)
+	}
+}
+
+func TestAddPathsTxBestN(t *testing.T) {
+	p, gConf := getAddPathsPeer(t)
+	dest := getAddPathsDest(t, p, gConf)
+
+	for maxTx := uint8(1); maxTx <= 4; maxTx++ {
+		addPathsConf := config.AddPathsConfig{MaxTx: maxTx, TxMode: config.AddPathsTxModeBestN}
+		if count := advertiseAddPaths(p, dest, addPathsConf); count != int(maxTx) {
+			t.Error("Expected", maxTx, "paths including the best path to be advertised, found", count)
+		}
+	}
+}
+
+func TestAddPathsTxECMP(t *testing.T) {
+	p, gConf := getAddPathsPeer(t)
+	dest := getAddPathsDest(t, p, gConf)
+	addPathsConf := config.AddPathsConfig{MaxTx: config.AddPathsMaxTxAll, TxMode: config.AddPathsTxModeECMP}
+
+	paths := p.getAddPathsForTx(dest, addPathsConf)
+	if len(paths) != 2 {
+		t.Fatal("Expected the 2 ECMP paths, found", len(paths))
+	}
+	for _, path := range paths {
+		if !isExternalPath(path) {
+			t.Error("iBGP path", path, "returned as an ECMP path")
+		}
+	}
+	if count := advertiseAddPaths(p, dest, addPathsConf); count != 2 {
+		t.Error("Expected the 2 ECMP paths to be advertised, found", count)
+	}
+}
+
+func TestAddPathsTxBestExternal(t *testing.T) {
+	p, gConf := getAddPathsPeer(t)
+	dest := getAddPathsDest(t, p, gConf)
+	addPathsConf := config.AddPathsConfig{MaxTx: config.AddPathsMaxTxAll, TxMode: config.AddPathsTxModeBestExternal}
+
+	// The best path is external, nothing is advertised in addition to it
+	if paths := p.getAddPathsForTx(dest, addPathsConf); len(paths) != 0 {
+		t.Error("Expected no additional paths when the best path is external, found", paths)
+	}
+	if count := advertiseAddPaths(p, dest, addPathsConf); count != 1 {
+		t.Error("Expected only the best path to be advertised, found", count)
+	}
+
+	// An iBGP path with a higher local preference becomes the best path, the best external path is advertised too
+	ibgpPath := addPeerPath(p, gConf, dest, "10.0.5.1", 100, 200)
+	dest.SelectRouteForLocRib(int(config.AddPathsMaxTxAll))
+	if dest.LocRibPath != ibgpPath {
+		t.Fatal("Expected the iBGP path with the higher local preference to be the best path, found",
+			dest.LocRibPath)
+	}
+
+	paths := p.getAddPathsForTx(dest, addPathsConf)
+	if len(paths) != 1 || !isExternalPath(paths[0]) {
+		t.Fatal("Expected the best external path, found", paths)
+	}
+	if count := advertiseAddPaths(p, dest, addPathsConf); count != 2 {
+		t.Error("Expected the best path and the best external path to be advertised, found", count)
+	}
+}
+
+func TestAddPathsLegacyMaxTx(t *testing.T) {
+	p, gConf := getAddPathsPeer(t)
+	dest := getAddPathsDest(t, p, gConf)
+
+	// The neighbor's AddPathsMaxTx sends one path less than configured
+	peerConf := p.NeighborConf.RunningConf
+	peerConf.AddPathsMaxTx = 3
+	nConf := base.NewNeighborConf(p.logger, gConf, nil, peerConf)
+	addPathsConf := nConf.GetAddPathsConfig(ipv4Unicast)
+	if addPathsConf.MaxTx != 2 || addPathsConf.TxMode != config.AddPathsTxModeBestN {
+		t.Fatal("Expected MaxTx 2 in best-n mode for the neighbor AddPathsMaxTx 3, found", addPathsConf)
+	}
+	if count := advertiseAddPaths(p, dest, addPathsConf); count != 2 {
+		t.Error("Expected 2 paths to be advertised for the neighbor AddPathsMaxTx 3, found", count)
+	}
+
+	// The family MaxTx is the total number of paths
+	peerConf.AddPathsFamilies = []config.AddPathsConfig{{AfiSafiName: "ipv4-unicast", MaxTx: 3}}
+	nConf = base.NewNeighborConf(p.logger, gConf, nil, peerConf)
+	if addPathsConf = nConf.GetAddPathsConfig(ipv4Unicast); addPathsConf.MaxTx != 3 {
+		t.Error("Expected MaxTx 3 for the family MaxTx 3, found", addPathsConf)
+	}
+}
+
+func TestUpdateGroupSourceExclusion(t *testing.T) {
+	p, gConf := getAddPathsPeer(t)
+	dest, _ := p.locRib.GetDest(packet.NewIPPrefix(net.ParseIP("30.1.1.0"), 24), ipv4Unicast, true)
+	sourcePath := addPeerPath(p, gConf, dest, "10.0.9.1", 600, 0)
+	otherPath := addPeerPath(p, gConf, dest, "10.0.1.1", 200, 0)
+	nlri := packet.NewIPPrefix(net.ParseIP("30.1.1.0"), 24)
+	sourceUpdate := &updateMsgInfo{packet.NewBGPUpdateMessage(nil, sourcePath.PathAttrs, []packet.NLRI{nlri}),
+		sourcePath}
+	otherUpdate := &updateMsgInfo{packet.NewBGPUpdateMessage(nil, otherPath.PathAttrs, []packet.NLRI{nlri}),
+		otherPath}
+	withdraw := &updateMsgInfo{packet.NewBGPUpdateMessage([]packet.NLRI{nlri}, nil, nil), nil}
+
+	// The route is added to the Adj-RIB-Out with a path learned from the peer, nothing is sent to the peer
+	p.excludeSourceMembers(ipv4Unicast, nlri, sourcePath)
+	if msg := p.getMemberUpdateMsg(sourceUpdate); msg != nil {
+		t.Error("Expected no update for a new route learned from the peer, found", msg)
+	}
+	if msg := p.getMemberUpdateMsg(withdraw); msg != nil {
+		t.Error("Expected no withdraw for a route that was not advertised to the peer, found", msg)
+	}
+
+	// The route is advertised with a path from another peer and then replaced by a path learned from the peer
+	if msg := p.getMemberUpdateMsg(otherUpdate); msg == nil || len(msg.Body.(*packet.BGPUpdate).NLRI) != 1 {
+		t.Fatal("Expected the route learned from another peer to be advertised, found", msg)
+	}
+	msg := p.getMemberUpdateMsg(sourceUpdate)
+	if msg == nil || len(msg.Body.(*packet.BGPUpdate).WithdrawnRoutes) != 1 {
+		t.Fatal("Expected the advertised route to be withdrawn from the peer, found", msg)
+	}
+	if msg = p.getMemberUpdateMsg(sourceUpdate); msg != nil {
+		t.Error("Expected no update when the path learned from the peer is updated, found", msg)
+	}
+
+	// Only the routes that were advertised to the peer are withdrawn
+	nlri2 := packet.NewIPPrefix(net.ParseIP("30.1.2.0"), 24)
+	withdraw = &updateMsgInfo{packet.NewBGPUpdateMessage([]packet.NLRI{nlri, nlri2}, nil, nil), nil}
+	msg = p.getMemberUpdateMsg(withdraw)
+	if msg == nil {
+		t.Fatal("Expected a withdraw for the route advertised to the peer")
+	}
+	withdrawn := msg.Body.(*packet.BGPUpdate).WithdrawnRoutes
+	if len(withdrawn) != 1 || withdrawn[0].GetCIDR() != nlri2.GetCIDR() {
+		t.Error("Expected only", nlri2.GetCIDR(), "to be withdrawn, found", withdrawn)
+	}
+}
@@ -0,0 +1,158 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// routeServer.go
+package server
+
+import (
+	"l3/bgp/baseobjects"
+	"l3/bgp/packet"
+	bgprib "l3/bgp/rib"
+)
+
+// peerRib processes the routes received from a neighbor. It's the Loc-RIB of the neighbor's VRF or the route server
+// for the route server clients.
+type peerRib interface {
+	ProcessUpdate(neighborConf *base.NeighborConf, path *bgprib.Path, add, rem []packet.NLRI, protoFamily uint32,
+		addPathCount int, updated map[uint32]map[*bgprib.Path][]*bgprib.Destination, withdrawn []*bgprib.Destination,
+		updatedAddPaths []*bgprib.Destination) (map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+		[]*bgprib.Destination, []*bgprib.Destination, bool)
+	ProcessFilteredRoutes(neighborConf *base.NeighborConf,
+		filteredRoutes map[*bgprib.Path]map[uint32]*bgprib.FilteredRoutes, addPathCount int) (
+		map[uint32]map[*bgprib.Path][]*bgprib.Destination, []*bgprib.Destination, []*bgprib.Destination, bool)
+	MarkStaleUpdatesFromNeighbor(peerIP string, protoFamily uint32) int
+	RemoveStaleUpdatesFromNeighbor(peerIP string, neighborConf *base.NeighborConf, protoFamily uint32,
+		addPathCount int) (map[uint32]map[*bgprib.Path][]*bgprib.Destination, []*bgprib.Destination,
+		[]*bgprib.Destination)
+	RemoveUpdatesFromNeighbor(peerIP string, neighborConf *base.NeighborConf, addPathCount int) (
+		map[uint32]map[*bgprib.Path][]*bgprib.Destination, []*bgprib.Destination, []*bgprib.Destination)
+}
+
+type locRibUpdate struct {
+	updated         map[uint32]map[*bgprib.Path][]*bgprib.Destination
+	withdrawn       []*bgprib.Destination
+	updatedAddPaths []*bgprib.Destination
+}
+
+func (p *Peer) isRouteServerClient() bool {
+	return p.NeighborConf.RunningConf.RouteServerClient
+}
+
+// acceptRouteServerPath applies the export policy of the route server client to a path before it's added to the
+// view of the client, so that a path filtered for the client doesn't hide the other paths for the destination
+func (s *BGPServer) acceptRouteServerPath(client string, protoFamily uint32, nlri packet.NLRI,
+	path *bgprib.Path) bool {
+	peer, ok := s.PeerMap[client]
+	if !ok || !peer.isAdvertisable(path) {
+		return false
+	}
+
+	route := bgprib.NewAdjRIBRoute(peer.NeighborConf.Neighbor.NeighborAddress, protoFamily, nlri)
+	return peer.checkRIBOutFilter(nlri, route, path, true)
+}
+
+// setRouteServerClient moves the neighbor to its own view when it's configured as a route server client and back
+// to the Loc-RIB of its VRF when it's not. The routes received from the neighbor are removed from the Loc-RIB it
+// leaves, the session is reset by the caller to receive them again.
+func (s *BGPServer) setRouteServerClient(peer *Peer) {
+	if peer.NeighborConf.RunningConf.NeighborAddress == nil {
+		return
+	}
+
+	client := peer.NeighborConf.RunningConf.NeighborAddress.String()
+	_, hasView := s.routeServer.GetView(client)
+	if hasView == peer.isRouteServerClient() && hasView == (peer.importRib == s.routeServer) {
+		return
+	}
+
+	s.ProcessRemoveNeighbor(client, peer)
+	if peer.isRouteServerClient() {
+		peer.locRib = s.routeServer.AddClient(client)
+		peer.importRib = s.routeServer
+		updated, withdrawn, updatedAddPaths := s.routeServer.SyncClient(client, s.AddPathCount)
+		s.SendUpdate(updated, withdrawn, updatedAddPaths)
+		return
+	}
+
+	s.routeServer.RemoveClient(client)
+	if locRib, ok := s.getLocRibForVrf(peer.NeighborConf.RunningConf.Vrf); ok {
+		peer.locRib = locRib
+		peer.importRib = locRib
+	}
+}
+
+func (s *BGPServer) removeRouteServerClient(peerIP string, peer *Peer) {
+	if peer.isRouteServerClient() {
+		s.routeServer.RemoveClient(peerIP)
+	}
+}
+
+// splitUpdateByLocRib groups the destinations by their Loc-RIB. The routes from a route server client update the
+// views of all the other clients.
+func (s *BGPServer) splitUpdateByLocRib(updated map[uint32]map[*bgprib.Path][]*bgprib.Destination, withdrawn,
+	updatedAddPaths []*bgprib.Destination) map[*bgprib.LocRib]*locRibUpdate {
+	updates := make(map[*bgprib.LocRib]*locRibUpdate)
+	getUpdate := func(dest *bgprib.Destination) *locRibUpdate {
+		locRib := dest.GetLocRib()
+		if _, ok := updates[locRib]; !ok {
+			updates[locRib] = &locRibUpdate{
+				updated:         make(map[uint32]map[*bgprib.Path][]*bgprib.Destination),
+				withdrawn:       make([]*bgprib.Destination, 0),
+				updatedAddPaths: make([]*bgprib.Destination, 0),
+			}
+		}
+		return updates[locRib]
+	}
+
+	for protoFamily, pathDestMap := range updated {
+		for path, destinations := range pathDestMap {
+			for _, dest := range destinations {
+				if dest == nil {
+					continue
+				}
+				update := getUpdate(dest)
+				if _, ok := update.updated[protoFamily]; !ok {
+					update.updated[protoFamily] = make(map[*bgprib.Path][]*bgprib.Destination)
+				}
+				update.updated[protoFamily][path] = append(update.updated[protoFamily][path], dest)
+			}
+		}
+	}
+
+	for _, dest := range withdrawn {
+		if dest == nil {
+			continue
+		}
+		update := getUpdate(dest)
+		update.withdrawn = append(update.withdrawn, dest)
+	}
+
+	for _, dest := range updatedAddPaths {
+		if dest == nil {
+			continue
+		}
+		update := getUpdate(dest)
+		update.updatedAddPaths = append(update.updatedAddPaths, dest)
+	}
+	return updates
+}
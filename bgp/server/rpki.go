@@ -0,0 +1,57 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// rpki.go
+package server
+
+import (
+	"l3/bgp/config"
+)
+
+func (s *BGPServer) SetupRPKI(rpkiConf config.RPKIConfig) {
+	s.logger.Info("SetupRPKI - RPKI config:", rpkiConf)
+	s.rpkiManager.SetCacheServers(rpkiConf.CacheServers)
+}
+
+func (s *BGPServer) processValidationStateChange() {
+	updated, withdrawn, updatedAddPaths := s.LocRib.ProcessValidationStateChange(s.AddPathCount)
+	updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+	s.SendUpdate(updated, withdrawn, updatedAddPaths)
+}
+
+// ProcessROAUpdate is called when the ROAs received from the RPKI caches change. Inbound policies are applied
+// again if they have RPKI conditions and the best paths are selected again if valid paths are preferred.
+func (s *BGPServer) ProcessROAUpdate() {
+	s.logger.Info("ROA table updated, total ROAs", s.rpkiManager.Table.GetCount())
+	if s.policyManager.RPKIDB.HasConditions() {
+		for _, peer := range s.PeerMap {
+			updated, withdrawn, updatedAddPaths := peer.ApplyRIBInPolicy()
+			updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+			s.SendUpdate(updated, withdrawn, updatedAddPaths)
+		}
+	}
+
+	if s.BgpConfig.Global.Config.RPKI.PreferValid {
+		s.processValidationStateChange()
+	}
+}
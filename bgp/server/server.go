@@ -29,13 +29,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"l3/bgp/bmp"
 	"l3/bgp/config"
 	"l3/bgp/fsm"
+	"l3/bgp/keychain"
+	"l3/bgp/mrt"
 	"l3/bgp/packet"
 	bgppolicy "l3/bgp/policy"
 	bgprib "l3/bgp/rib"
+	"l3/bgp/rpki"
 	"l3/bgp/utils"
 	"net"
+	"os"
 	"reflect"
 	"runtime"
 	"strconv"
@@ -83,6 +88,18 @@ type AggUpdate struct {
 	AttrSet []bool
 }
 
+type ListenRangeUpdate struct {
+	OldRange config.ListenRangeConfig
+	NewRange config.ListenRangeConfig
+	AttrSet  []bool
+}
+
+type VrfUpdate struct {
+	OldVrf  config.VrfConfig
+	NewVrf  config.VrfConfig
+	AttrSet []bool
+}
+
 type PolicyParams struct {
 	CreateType      int
 	DeleteType      int
@@ -114,13 +131,30 @@ type BGPServer struct {
 	RemPeerGroupCh   chan config.PeerGroupConfig
 	AddAggCh         chan AggUpdate
 	RemAggCh         chan config.BGPAggregate
+	AddVrfCh         chan VrfUpdate
+	RemVrfCh         chan config.VrfConfig
+	AddListenRangeCh chan ListenRangeUpdate
+	RemListenRangeCh chan config.ListenRangeConfig
 	PeerFSMConnCh    chan fsm.PeerFSMConn
 	PeerConnEstCh    chan string
 	PeerConnBrokenCh chan string
 	PeerCommandCh    chan config.PeerCommand
+	PeerShutdownCh   chan config.PeerShutdown
+	ShutdownTimerCh  chan string
+	SoftResetInCh    chan string
+	StaleTimerCh     chan string
+	DeferralExpCh    chan bool
+	MRTDumpCh        chan bool
+	DampeningReuseCh chan bool
+	ClearDampeningCh chan string
+	AddKeyChainCh    chan config.KeyChainConfig
+	RemKeyChainCh    chan string
+	KeyChainTimerCh  chan bool
 	ReachabilityCh   chan config.ReachabilityInfo
 	BGPPktSrcCh      chan *packet.BGPPktSrc
 	BfdCh            chan config.BfdInfo
+	EVPNCh           chan config.EVPNInfo
+	LinkStateCh      chan config.LinkStateInfo
 	IntfCh           chan config.IntfStateInfo
 	IntfMapCh        chan config.IntfMapInfo
 	RoutesCh         chan *config.RouteCh
@@ -138,18 +172,38 @@ type BGPServer struct {
 	IntfIdNameMap     map[int32]IntfEntry
 	IfNameToIfIndex   map[string]int32
 	RedistributionMap map[string]string
+	evpnVnis          map[uint32]net.IP
+	linkStateRoutes   map[string]*linkStateRoute
+	vrfs              map[string]*VRF
+	routeServer       *bgprib.RouteServer
+	listenRanges      map[string]*ListenRange
+	updateGroups      map[updateGroupKey]*UpdateGroup
 	ifaceIP           net.IP
 	AddPathCount      int
+	restartDeadline   time.Time
+	deferralTimer     *time.Timer
+	installedRoutes   []*config.RouteConfig
+	bmpManager        *bmp.BMPManager
+	mrtUpdates        *mrt.MRTWriter
+	mrtDumpTimer      *time.Timer
+	dampeningTimer    *time.Timer
+	keyChains         map[string]*keychain.KeyChain
+	keyChainTimer     *time.Timer
+	rpkiManager       *rpki.RPKIManager
 	// all managers
 	IntfMgr    config.IntfStateMgrIntf
 	routeMgr   config.RouteMgrIntf
 	bfdMgr     config.BfdMgrIntf
+	fsMgr      config.FlowSpecMgrIntf
+	evpnMgr    config.EVPNMgrIntf
+	lsMgr      config.LinkStateMgrIntf
 	stateDBMgr statedbclient.StateDBClient
 	eventDbHdl *dbutils.DBUtil
 }
 
 func NewBGPServer(logger *logging.Writer, policyManager *bgppolicy.BGPPolicyManager, iMgr config.IntfStateMgrIntf,
-	rMgr config.RouteMgrIntf, bMgr config.BfdMgrIntf, sDBMgr statedbclient.StateDBClient) *BGPServer {
+	rMgr config.RouteMgrIntf, bMgr config.BfdMgrIntf, fsMgr config.FlowSpecMgrIntf, evpnMgr config.EVPNMgrIntf,
+	lsMgr config.LinkStateMgrIntf, sDBMgr statedbclient.StateDBClient) *BGPServer {
 	bgpServer := &BGPServer{}
 	bgpServer.logger = logger
 	bgpServer.policyManager = policyManager
@@ -162,13 +216,31 @@ func NewBGPServer(logger *logging.Writer, policyManager *bgppolicy.BGPPolicyMana
 	bgpServer.RemPeerGroupCh = make(chan config.PeerGroupConfig)
 	bgpServer.AddAggCh = make(chan AggUpdate)
 	bgpServer.RemAggCh = make(chan config.BGPAggregate)
+	bgpServer.AddVrfCh = make(chan VrfUpdate)
+	bgpServer.RemVrfCh = make(chan config.VrfConfig)
+	bgpServer.AddListenRangeCh = make(chan ListenRangeUpdate)
+	bgpServer.RemListenRangeCh = make(chan config.ListenRangeConfig)
 	bgpServer.PeerFSMConnCh = make(chan fsm.PeerFSMConn, 50)
 	bgpServer.PeerConnEstCh = make(chan string)
 	bgpServer.PeerConnBrokenCh = make(chan string)
 	bgpServer.PeerCommandCh = make(chan config.PeerCommand)
+	bgpServer.PeerShutdownCh = make(chan config.PeerShutdown)
+	bgpServer.ShutdownTimerCh = make(chan string)
+	bgpServer.SoftResetInCh = make(chan string)
+	bgpServer.StaleTimerCh = make(chan string)
+	bgpServer.DeferralExpCh = make(chan bool)
+	bgpServer.MRTDumpCh = make(chan bool)
+	bgpServer.DampeningReuseCh = make(chan bool)
+	bgpServer.ClearDampeningCh = make(chan string)
+	bgpServer.AddKeyChainCh = make(chan config.KeyChainConfig)
+	bgpServer.RemKeyChainCh = make(chan string)
+	bgpServer.KeyChainTimerCh = make(chan bool)
+	bgpServer.keyChains = make(map[string]*keychain.KeyChain)
 	bgpServer.ReachabilityCh = make(chan config.ReachabilityInfo)
 	bgpServer.BGPPktSrcCh = make(chan *packet.BGPPktSrc)
 	bgpServer.BfdCh = make(chan config.BfdInfo)
+	bgpServer.EVPNCh = make(chan config.EVPNInfo)
+	bgpServer.LinkStateCh = make(chan config.LinkStateInfo)
 	bgpServer.IntfCh = make(chan config.IntfStateInfo)
 	bgpServer.IntfMapCh = make(chan config.IntfMapInfo)
 	bgpServer.RoutesCh = make(chan *config.RouteCh)
@@ -184,14 +256,32 @@ func NewBGPServer(logger *logging.Writer, policyManager *bgppolicy.BGPPolicyMana
 	bgpServer.IntfMgr = iMgr
 	bgpServer.routeMgr = rMgr
 	bgpServer.bfdMgr = bMgr
+	bgpServer.fsMgr = fsMgr
+	bgpServer.evpnMgr = evpnMgr
+	bgpServer.lsMgr = lsMgr
 	bgpServer.stateDBMgr = sDBMgr
 	bgpServer.LocRib = bgprib.NewLocRib(logger, rMgr, sDBMgr, &bgpServer.BgpConfig.Global.Config)
+	bgpServer.LocRib.SetFlowSpecMgr(fsMgr)
+	bgpServer.LocRib.SetEVPNMgr(evpnMgr)
 	bgpServer.IfNameToIfIndex = make(map[string]int32)
 	bgpServer.IntfIdNameMap = make(map[int32]IntfEntry)
 	bgpServer.IfIndexPeerMap = make(map[int32][]string)
 	bgpServer.RedistributionMap = make(map[string]string)
+	bgpServer.evpnVnis = make(map[uint32]net.IP)
+	bgpServer.linkStateRoutes = make(map[string]*linkStateRoute)
+	bgpServer.vrfs = make(map[string]*VRF)
+	bgpServer.routeServer = bgprib.NewRouteServer(logger, rMgr, &bgpServer.BgpConfig.Global.Config)
+	bgpServer.routeServer.SetAcceptFunc(bgpServer.acceptRouteServerPath)
+	bgpServer.listenRanges = make(map[string]*ListenRange)
+	bgpServer.updateGroups = make(map[updateGroupKey]*UpdateGroup)
 	bgpServer.ifaceIP = nil
 	bgpServer.AddPathCount = 0
+	hostname, _ := os.Hostname()
+	bgpServer.bmpManager = bmp.NewBMPManager(logger, hostname, "FlexSwitch bgpd")
+	bgpServer.mrtUpdates = mrt.NewMRTWriter(logger)
+	bgpServer.rpkiManager = rpki.NewRPKIManager(logger)
+	bgpServer.LocRib.SetROATable(bgpServer.rpkiManager.Table)
+	bgpServer.routeServer.SetROATable(bgpServer.rpkiManager.Table)
 	bgpServer.initGlobalConfig()
 	bgpServer.initPolicyEngines()
 	return bgpServer
@@ -276,6 +366,10 @@ func (s *BGPServer) createListener(proto string) (*net.TCPListener, error) {
 		return nil, err
 	}
 
+	if err = fsm.EnableSavedSyn(listener); err != nil {
+		s.logger.Err("Failed to save SYN on the listener for TTL security, error", err)
+	}
+
 	return listener, nil
 }
 
@@ -322,9 +416,38 @@ func (s *BGPServer) listenForPeers(listener *net.TCPListener, proto string, acce
 
 func (s *BGPServer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destination, withdrawn,
 	updatedAddPaths []*bgprib.Destination) {
+	if len(s.routeServer.GetViews()) > 0 {
+		for locRib, update := range s.splitUpdateByLocRib(updated, withdrawn, updatedAddPaths) {
+			s.sendLocRibUpdate(locRib, update.updated, update.withdrawn, update.updatedAddPaths)
+		}
+		return
+	}
+
+	locRib := s.getUpdateLocRib(updated, withdrawn, updatedAddPaths)
+	if locRib == nil {
+		locRib = s.LocRib
+	}
+	s.sendLocRibUpdate(locRib, updated, withdrawn, updatedAddPaths)
+}
+
+func (s *BGPServer) sendLocRibUpdate(locRib *bgprib.LocRib, updated map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+	withdrawn, updatedAddPaths []*bgprib.Destination) {
+	// UPDATE messages are constructed once per update group and sent to all the members
+	groups := make(map[*UpdateGroup]bool)
 	for _, peer := range s.PeerMap {
+		if peer.locRib != locRib {
+			continue
+		}
+
+		if peer.updateGroup != nil {
+			if groups[peer.updateGroup] {
+				continue
+			}
+			groups[peer.updateGroup] = true
+		}
 		peer.SendUpdate(updated, withdrawn, updatedAddPaths)
 	}
+	s.leakVrfRoutes(locRib, updated, withdrawn)
 }
 
 func (s *BGPServer) DoesRouteExist(params interface{}) bool {
@@ -597,6 +720,10 @@ func (s *BGPServer) CheckForAggregation(updated map[uint32]map[*bgprib.Path][]*b
 	updatedAddPaths []*bgprib.Destination) (map[uint32]map[*bgprib.Path][]*bgprib.Destination, []*bgprib.Destination,
 	[]*bgprib.Destination) {
 	s.logger.Infof("BGPServer:checkForAggregate - start, updated %v withdrawn %v", updated, withdrawn)
+	if locRib := s.getUpdateLocRib(updated, withdrawn, updatedAddPaths); locRib != nil && locRib != s.LocRib {
+		// Aggregates are configured only for the global table
+		return updated, withdrawn, updatedAddPaths
+	}
 
 	for _, dest := range withdrawn {
 		if dest == nil || dest.LocRibPath == nil || dest.LocRibPath.IsAggregate() {
@@ -850,26 +977,92 @@ func (s *BGPServer) DoesAdjRIBOutRouteExist(params interface{}) bool {
 	return s.DoesAdjRIBRouteExist(params, bgprib.AdjRIBDirOut)
 }
 
+// matchAdjRIBConditions checks the community and RPKI conditions of the statement that are not evaluated by the
+// policy engine
+func (s *BGPServer) matchAdjRIBConditions(policyStmt utilspolicy.PolicyStmt, policyParams *AdjRIBPolicyParams) bool {
+	if !s.policyManager.CommunityDB.MatchConditions(policyStmt.Conditions, policyParams.getPathAttrs()) {
+		return false
+	}
+
+	if s.policyManager.RPKIDB.IsConditionInList(policyStmt.Conditions) &&
+		!s.policyManager.RPKIDB.MatchConditions(policyStmt.Conditions, policyParams.getValidationState()) {
+		return false
+	}
+	return true
+}
+
+// matchNextAdjRIBStmt falls through to the next statements of the neighbor's filter when the community or RPKI
+// conditions of the statement matched by the policy engine are not met
+func (s *BGPServer) matchNextAdjRIBStmt(policyStmt utilspolicy.PolicyStmt, policyParams *AdjRIBPolicyParams) (
+	utilspolicy.PolicyStmt, bool) {
+	peer := policyParams.Peer
+	if peer == nil || policyParams.Route == nil {
+		return policyStmt, false
+	}
+
+	filter := peer.NeighborConf.RunningConf.AdjRIBInFilter
+	if policyParams.PolicyEngine == s.ribOutPE {
+		filter = peer.NeighborConf.RunningConf.AdjRIBOutFilter
+	}
+	if filter == "" || policyParams.PolicyEngine == nil {
+		return policyStmt, false
+	}
+
+	matchFunc := func(stmt utilspolicy.PolicyStmt) bool {
+		return s.matchAdjRIBConditions(stmt, policyParams)
+	}
+	return policyParams.PolicyEngine.MatchNextStmt(filter, policyStmt.Name, policyParams.Route.NLRI.GetCIDR(),
+		matchFunc)
+}
+
 func (s *BGPServer) ApplyAdjRIBAction(actionInfo interface{}, conditionInfo []interface{}, params interface{},
 	policyStmt utilspolicy.PolicyStmt) {
 	policyParams := params.(*AdjRIBPolicyParams)
 	s.logger.Infof("BGPServer:ApplyAdjRIBAction - policyParams=%+v, policyStmt=%+v\n", policyParams, policyStmt)
+	if !s.matchAdjRIBConditions(policyStmt, policyParams) {
+		nextStmt, ok := s.matchNextAdjRIBStmt(policyStmt, policyParams)
+		if !ok {
+			s.logger.Infof("BGPServer:ApplyAdjRIBAction - policyParams=%+v, policyStmt=%+v, BGP conditions not met\n",
+				policyParams, policyStmt)
+			return
+		}
+		s.logger.Infof("BGPServer:ApplyAdjRIBAction - policyParams=%+v, policyStmt=%+v, BGP conditions not met, "+
+			"matched statement %s\n", policyParams, policyStmt, nextStmt.Name)
+		policyStmt = nextStmt
+	}
+
 	if len(policyStmt.Actions) > 0 {
+		permitted := false
+		actionList := make([]string, 0)
 		for _, action := range policyStmt.Actions {
 			if action == "permit" {
 				s.logger.Info("BGPServer:ApplyAdjRIBAction - policyParams=%+v, policyStmt=%+v, action permit\n",
 					policyParams, policyStmt, action)
 				policyParams.Accept = Accept
-				break
+				permitted = true
 			} else if action == "deny" {
+				if permitted {
+					continue
+				}
 				s.logger.Info("BGPServer:ApplyAdjRIBAction - policyParams=%+v, policyStmt=%+v, action deny\n",
 					policyParams, policyStmt, action)
 				policyParams.Accept = Reject
+			} else if s.policyManager.CommunityDB.IsAction(action) {
+				s.logger.Info("BGPServer:ApplyAdjRIBAction - policyParams=%+v, policyStmt=%+v, community action %s\n",
+					policyParams, policyStmt, action)
+				actionList = append(actionList, action)
+			} else if s.policyManager.DampeningDB.IsAction(action) {
+				s.logger.Info("BGPServer:ApplyAdjRIBAction - policyParams=%+v, policyStmt=%+v, dampening action %s\n",
+					policyParams, policyStmt, action)
+				actionList = append(actionList, action)
 			} else {
 				s.logger.Err("BGPServer:ApplyAdjRIBAction - policyParams=%+v, policyStmt=%+v, unknown action=%s\n",
 					policyParams, policyStmt, action)
 			}
 		}
+		if policyParams.Route != nil {
+			policyParams.Route.ActionList = append(policyParams.Route.ActionList, actionList...)
+		}
 	}
 }
 
@@ -877,6 +1070,9 @@ func (s *BGPServer) UndoAdjRIBAction(actionInfo interface{}, conditionInfo []int
 	policyStmt utilspolicy.PolicyStmt) {
 	policyParams := params.(*AdjRIBPolicyParams)
 	s.logger.Info("BGPServer:UndoAdjRIBAction - policyParams=%+v policyStmt=%+v\n", policyParams, policyStmt)
+	if policyParams.Route != nil {
+		policyParams.Route.ActionList = nil
+	}
 	if len(policyStmt.Actions) > 0 {
 		for _, action := range policyStmt.Actions {
 			if action == "permit" {
@@ -1061,9 +1257,150 @@ func (s *BGPServer) ProcessUpdate(pktInfo *packet.BGPPktSrc) {
 		return
 	}
 
+	// Pre-policy route monitoring has to be sent before the update is filtered in place
+	s.SendBMPRouteMonitoring(peer, pktInfo.Msg, false)
 	updated, withdrawn, updatedAddPaths := peer.ReceiveUpdate(pktInfo)
 	updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
 	s.SendUpdate(updated, withdrawn, updatedAddPaths)
+
+	if protoFamily, ok := packet.GetEndOfRIBFamily(pktInfo.Msg); ok {
+		s.SendBMPRouteMonitoring(peer, packet.NewBGPEndOfRIBMessage(protoFamily), true)
+		updated, withdrawn, updatedAddPaths = peer.ProcessEndOfRIB(protoFamily)
+		updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+		s.SendUpdate(updated, withdrawn, updatedAddPaths)
+		s.CheckSelectionDeferral()
+	}
+}
+
+// startGracefulRestart checks whether bgpd restarted. RIBd keeps the routes installed by bgpd when it goes down, so
+// bgpd restarted if there are BGP routes in RIBd at startup. The restart state is advertised and the best path
+// selection is deferred only after a restart. The routes in RIBd keep forwarding till the deferral ends when the
+// forwarding state is preserved, otherwise they are removed right away.
+func (s *BGPServer) startGracefulRestart() {
+	gConf := &s.BgpConfig.Global.Config
+	s.installedRoutes = s.routeMgr.GetInstalledRoutes()
+	if len(s.installedRoutes) == 0 {
+		s.installedRoutes = nil
+		return
+	}
+
+	s.logger.Info("Found", len(s.installedRoutes), "routes installed before bgpd restarted")
+	if !gConf.GracefulRestart || !gConf.PreserveForwardingState {
+		s.LocRib.SweepInstalledRoutes(s.installedRoutes)
+		s.installedRoutes = nil
+	}
+	if !gConf.GracefulRestart {
+		return
+	}
+
+	restartTime := time.Duration(gConf.RestartTime) * time.Second
+	s.restartDeadline = time.Now().Add(restartTime)
+	s.logger.Info("Graceful restart enabled, restarting till", s.restartDeadline)
+	s.LocRib.DeferSelection()
+	s.deferralTimer = time.AfterFunc(restartTime, func() {
+		s.DeferralExpCh <- true
+	})
+}
+
+func (s *BGPServer) CheckSelectionDeferral() {
+	if !s.LocRib.IsSelectionDeferred() {
+		return
+	}
+
+	established := 0
+	for peerIP, peer := range s.PeerMap {
+		if peer.NeighborConf.Neighbor.Transport.Config.LocalAddress == nil {
+			continue
+		}
+		if !peer.NeighborConf.IsEndOfRIBReceived() {
+			s.logger.Infof("Best path selection is deferred, waiting for End-of-RIB from peer %s", peerIP)
+			return
+		}
+		established++
+	}
+
+	if established > 0 {
+		s.EndSelectionDeferral()
+	}
+}
+
+func (s *BGPServer) EndSelectionDeferral() {
+	if !s.LocRib.IsSelectionDeferred() {
+		return
+	}
+
+	if s.deferralTimer != nil {
+		s.deferralTimer.Stop()
+		s.deferralTimer = nil
+	}
+
+	updated, withdrawn, updatedAddPaths := s.LocRib.ProcessDeferredDests(s.AddPathCount)
+	updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+	s.SendUpdate(updated, withdrawn, updatedAddPaths)
+	s.LocRib.SweepInstalledRoutes(s.installedRoutes)
+	s.installedRoutes = nil
+	for _, peer := range s.PeerMap {
+		if peer.NeighborConf.Neighbor.Transport.Config.LocalAddress != nil {
+			peer.SendEndOfRIB()
+		}
+	}
+}
+
+func (s *BGPServer) ProcessMarkStaleNeighbor(peer *Peer) {
+	updated, withdrawn, updatedAddPaths := peer.MarkStaleRoutes()
+	updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+	s.SendUpdate(updated, withdrawn, updatedAddPaths)
+}
+
+func (s *BGPServer) ProcessGracefulRestart(peer *Peer) {
+	updated, withdrawn, updatedAddPaths := peer.ProcessGracefulRestart()
+	updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+	s.SendUpdate(updated, withdrawn, updatedAddPaths)
+}
+
+func (s *BGPServer) ProcessStaleTimerExpiry(peerIP string) {
+	peer, ok := s.PeerMap[peerIP]
+	if !ok {
+		s.logger.Errf("Failed to remove stale routes, Peer %s does not exist", peerIP)
+		return
+	}
+
+	updated, withdrawn, updatedAddPaths := peer.RemoveAllStaleRoutes()
+	updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+	s.SendUpdate(updated, withdrawn, updatedAddPaths)
+}
+
+func (s *BGPServer) ProcessRouteRefresh(pktInfo *packet.BGPPktSrc) {
+	peer, ok := s.PeerMap[pktInfo.Src]
+	if !ok {
+		s.logger.Err("BgpServer:ProcessRouteRefresh - Peer not found, address:", pktInfo.Src)
+		return
+	}
+
+	refreshMsg := pktInfo.Msg.Body.(*packet.BGPRouteRefresh)
+	protoFamily := packet.GetProtocolFamily(refreshMsg.AFI, refreshMsg.SAFI)
+	updated := make(map[uint32]map[*bgprib.Path][]*bgprib.Destination)
+	if pathDestMap, ok := peer.locRib.GetLocRib()[protoFamily]; ok {
+		updated[protoFamily] = pathDestMap
+	}
+
+	if len(refreshMsg.ORFs) > 0 {
+		peer.ProcessORF(protoFamily, refreshMsg, updated)
+		return
+	}
+	peer.ProcessRouteRefresh(protoFamily, updated)
+}
+
+func (s *BGPServer) SoftResetPeerIn(peerIP string) {
+	peer, ok := s.PeerMap[peerIP]
+	if !ok {
+		s.logger.Errf("Failed to soft reset inbound, Peer %s does not exist", peerIP)
+		return
+	}
+
+	updated, withdrawn, updatedAddPaths := peer.SoftResetIn()
+	updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+	s.SendUpdate(updated, withdrawn, updatedAddPaths)
 }
 
 func (s *BGPServer) convertDestIPToIPPrefix(routes []*config.RouteInfo) map[uint32][]packet.NLRI {
@@ -1131,7 +1468,7 @@ func (s *BGPServer) GetIfaceIP(ifIndex int32) (*utils.IPInfo, error) {
 }
 
 func (s *BGPServer) ProcessRemoveNeighbor(peerIp string, peer *Peer) {
-	updated, withdrawn, updatedAddPaths := s.LocRib.RemoveUpdatesFromNeighbor(peerIp, peer.NeighborConf,
+	updated, withdrawn, updatedAddPaths := peer.importRib.RemoveUpdatesFromNeighbor(peerIp, peer.NeighborConf,
 		s.AddPathCount)
 	s.logger.Infof("ProcessRemoveNeighbor - Neighbor %s, send updated paths %v, withdrawn paths %v",
 		peerIp, updated, withdrawn)
@@ -1142,12 +1479,15 @@ func (s *BGPServer) ProcessRemoveNeighbor(peerIp string, peer *Peer) {
 func (s *BGPServer) SendAllRoutesToPeer(peer *Peer) {
 	withdrawn := make([]*bgprib.Destination, 0)
 	updatedAddPaths := make([]*bgprib.Destination, 0)
-	updated := s.LocRib.GetLocRib()
-	s.SendUpdate(updated, withdrawn, updatedAddPaths)
+	updated := peer.locRib.GetLocRib()
+	peer.splitFromUpdateGroup()
+	peer.SendUpdate(updated, withdrawn, updatedAddPaths)
+	peer.joinUpdateGroup()
 }
 
 func (s *BGPServer) RemoveRoutesFromAllNeighbor() {
 	s.LocRib.RemoveUpdatesFromAllNeighbors(s.AddPathCount)
+	s.routeServer.RemoveUpdatesFromAllNeighbors(s.AddPathCount)
 }
 
 func (s *BGPServer) addPeerToList(peer *Peer) {
@@ -1349,6 +1689,25 @@ func (s *BGPServer) copyGlobalConf(gConf config.GlobalConfig) {
 	s.BgpConfig.Global.Config.EBGPMaxPaths = gConf.EBGPMaxPaths
 	s.BgpConfig.Global.Config.EBGPAllowMultipleAS = gConf.EBGPAllowMultipleAS
 	s.BgpConfig.Global.Config.IBGPMaxPaths = gConf.IBGPMaxPaths
+	s.BgpConfig.Global.Config.GracefulRestart = gConf.GracefulRestart
+	s.BgpConfig.Global.Config.RestartTime = gConf.RestartTime
+	s.BgpConfig.Global.Config.StalePathTime = gConf.StalePathTime
+	s.BgpConfig.Global.Config.PreserveForwardingState = gConf.PreserveForwardingState
+	s.BgpConfig.Global.Config.InstallBackupPath = gConf.InstallBackupPath
+	s.BgpConfig.Global.Config.BMPStations = gConf.BMPStations
+	s.BgpConfig.Global.Config.MRT = gConf.MRT
+	s.BgpConfig.Global.Config.RPKI = gConf.RPKI
+	s.BgpConfig.Global.Config.BestPath = gConf.BestPath
+	s.setGracefulRestartDefaults()
+}
+
+func (s *BGPServer) setGracefulRestartDefaults() {
+	if s.BgpConfig.Global.Config.RestartTime == 0 {
+		s.BgpConfig.Global.Config.RestartTime = config.BGPRestartTimeDefault
+	}
+	if s.BgpConfig.Global.Config.StalePathTime == 0 {
+		s.BgpConfig.Global.Config.StalePathTime = config.BGPStalePathTimeDefault
+	}
 }
 
 func (s *BGPServer) handleBfdNotifications(oper config.Operation, DestIp string,
@@ -1412,6 +1771,11 @@ func (s *BGPServer) constructBGPGlobalState(gConf *config.GlobalConfig) {
 	s.BgpConfig.Global.State.EBGPMaxPaths = gConf.EBGPMaxPaths
 	s.BgpConfig.Global.State.EBGPAllowMultipleAS = gConf.EBGPAllowMultipleAS
 	s.BgpConfig.Global.State.IBGPMaxPaths = gConf.IBGPMaxPaths
+	s.BgpConfig.Global.State.GracefulRestart = gConf.GracefulRestart
+	s.BgpConfig.Global.State.RestartTime = gConf.RestartTime
+	s.BgpConfig.Global.State.StalePathTime = gConf.StalePathTime
+	s.BgpConfig.Global.State.PreserveForwardingState = gConf.PreserveForwardingState
+	s.BgpConfig.Global.State.InstallBackupPath = gConf.InstallBackupPath
 }
 
 func (s *BGPServer) SetupRedistribution(gConf config.GlobalConfig) {
@@ -1607,6 +1971,9 @@ func (s *BGPServer) UpdateGlobal(bgpGlobal *bgpd.BGPGlobal, oldConfig, newConfig
 	if attrSet != nil {
 		objTyp := reflect.TypeOf(*bgpGlobal)
 		restart := false
+		mrtUpdated := false
+		rpkiUpdated := false
+		bestPathUpdated := false
 		for i := 0; i < objTyp.NumField(); i++ {
 			objName := objTyp.Field(i).Name
 			if attrSet[i] {
@@ -1617,6 +1984,15 @@ func (s *BGPServer) UpdateGlobal(bgpGlobal *bgpd.BGPGlobal, oldConfig, newConfig
 						return
 					}
 					s.SetupRedistribution(newConfig)
+				} else if objName == "BMPStations" {
+					s.BgpConfig.Global.Config.BMPStations = newConfig.BMPStations
+					s.bmpManager.SetStations(newConfig.BMPStations)
+				} else if strings.HasPrefix(objName, "MRT") {
+					mrtUpdated = true
+				} else if strings.HasPrefix(objName, "RPKI") {
+					rpkiUpdated = true
+				} else if strings.HasPrefix(objName, "BestPath") || objName == "InstallBackupPath" {
+					bestPathUpdated = true
 				} else {
 					restart = true
 				}
@@ -1625,7 +2001,50 @@ func (s *BGPServer) UpdateGlobal(bgpGlobal *bgpd.BGPGlobal, oldConfig, newConfig
 
 		if restart {
 			s.Restart(newConfig)
+			return
 		}
+
+		if mrtUpdated {
+			s.BgpConfig.Global.Config.MRT = newConfig.MRT
+			s.SetupMRT(newConfig.MRT)
+		}
+		if rpkiUpdated {
+			preferValidUpdated := s.BgpConfig.Global.Config.RPKI.PreferValid != newConfig.RPKI.PreferValid
+			s.BgpConfig.Global.Config.RPKI = newConfig.RPKI
+			s.SetupRPKI(newConfig.RPKI)
+			if preferValidUpdated {
+				s.processValidationStateChange()
+			}
+		}
+		if bestPathUpdated {
+			s.BgpConfig.Global.Config.BestPath = newConfig.BestPath
+			s.BgpConfig.Global.Config.InstallBackupPath = newConfig.InstallBackupPath
+			s.BgpConfig.Global.State.InstallBackupPath = newConfig.InstallBackupPath
+			s.processBestPathConfigChange()
+		}
+	}
+}
+
+// processBestPathConfigChange runs the best path selection for all the destinations after the best path
+// options changed.
+// getLocRibs returns the global Loc-RIB, the VRF Loc-RIBs and the route server views
+func (s *BGPServer) getLocRibs() []*bgprib.LocRib {
+	locRibs := []*bgprib.LocRib{s.LocRib}
+	for _, vrf := range s.vrfs {
+		locRibs = append(locRibs, vrf.LocRib)
+	}
+	for _, view := range s.routeServer.GetViews() {
+		locRibs = append(locRibs, view)
+	}
+	return locRibs
+}
+
+func (s *BGPServer) processBestPathConfigChange() {
+	s.logger.Info("Best path options updated, run best path selection")
+	for _, locRib := range s.getLocRibs() {
+		updated, withdrawn, updatedAddPaths := locRib.RecalculateBestPaths(s.AddPathCount)
+		updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
+		s.SendUpdate(updated, withdrawn, updatedAddPaths)
 	}
 }
 
@@ -1648,6 +2067,9 @@ func (s *BGPServer) Restart(cfg config.GlobalConfig) {
 	packet.SetNextHopPathAttrs(s.ConnRoutesPath.PathAttrs, gConf.RouterId)
 	s.copyGlobalConf(gConf)
 	s.constructBGPGlobalState(&gConf)
+	s.bmpManager.SetStations(gConf.BMPStations)
+	s.SetupMRT(gConf.MRT)
+	s.SetupRPKI(gConf.RPKI)
 
 	for _, peer := range s.PeerMap {
 		peer.UpdateGlobal(&s.BgpConfig.Global.Config)
@@ -1756,9 +2178,16 @@ func (s *BGPServer) CreatePeer(newPeer config.NeighborConfig) {
 	var peer *Peer
 
 	if newPeer.NeighborAddress != nil {
-		if _, ok = s.PeerMap[newPeer.NeighborAddress.String()]; ok {
-			s.logger.Infof("Failed to add neighbor. Neighbor at address %s already exists", newPeer.NeighborAddress)
-			return
+		if peer, ok = s.PeerMap[newPeer.NeighborAddress.String()]; ok {
+			if !peer.IsDynamic() || newPeer.Dynamic {
+				s.logger.Infof("Failed to add neighbor. Neighbor at address %s already exists",
+					newPeer.NeighborAddress)
+				return
+			}
+			s.logger.Info("Replace dynamic neighbor", newPeer.NeighborAddress, "with configured neighbor")
+			s.SendBMPPeerDown(peer, true, false)
+			s.removeDynamicPeer(peer)
+			s.ProcessRemoveNeighbor(newPeer.NeighborAddress.String(), peer)
 		}
 	}
 
@@ -1793,9 +2222,18 @@ func (s *BGPServer) CreatePeer(newPeer config.NeighborConfig) {
 		}
 	}
 
-	s.logger.Info("Add neighbor, ip:", newPeer.NeighborAddress.String(), "ifIndex:", newPeer.IfIndex)
-	peer = NewPeer(s, s.LocRib, &s.BgpConfig.Global.Config, groupConfig, newPeer)
-	if peer.NeighborConf.RunningConf.NeighborAddress.To4() != nil &&
+	locRib, ok := s.getLocRibForVrf(newPeer.Vrf)
+	if !ok {
+		s.logger.Info("Failed to add neighbor", newPeer.NeighborAddress, "VRF", newPeer.Vrf, "not configured")
+		return
+	}
+
+	s.logger.Info("Add neighbor, ip:", newPeer.NeighborAddress.String(), "ifIndex:", newPeer.IfIndex, "vrf:",
+		newPeer.Vrf)
+	peer = NewPeer(s, locRib, &s.BgpConfig.Global.Config, groupConfig, newPeer)
+	if peer.NeighborConf.RunningConf.KeyChain != "" {
+		s.setPeerAuthKeys(peer)
+	} else if peer.NeighborConf.RunningConf.NeighborAddress.To4() != nil &&
 		peer.NeighborConf.RunningConf.AuthPassword != "" {
 		err := netUtils.SetTCPListenerMD5(s.listener, newPeer.NeighborAddress.String(),
 			peer.NeighborConf.RunningConf.AuthPassword)
@@ -1813,6 +2251,10 @@ func (s *BGPServer) CreatePeer(newPeer config.NeighborConfig) {
 		s.ifaceNeighbors[newPeer.PeerAddressType][newPeer.IfIndex] = peer
 	}
 
+	if peer.isRouteServerClient() {
+		s.setRouteServerClient(peer)
+	}
+
 	s.NeighborMutex.Lock()
 	s.addPeerToList(peer)
 	s.NeighborMutex.Unlock()
@@ -1844,9 +2286,18 @@ func (s *BGPServer) getPeer(neighbor config.NeighborConfig) *Peer {
 
 func (s *BGPServer) updatePeerConf(oldPeer, newPeer config.NeighborConfig, peer *Peer) {
 	s.logger.Info("Clean up peer, ip:", oldPeer.NeighborAddress.String(), "ifIndex:", oldPeer.IfIndex)
+	if newPeer.Disabled {
+		s.setPeerShutdownReason(peer, packet.BGPCeaseAdminShutdown, "")
+	} else {
+		s.setPeerShutdownReason(peer, packet.BGPCeaseOtherConfigChange, "")
+	}
 	peer.Cleanup()
+	s.removePeerAuthKeys(peer)
 	if peer.NeighborConf.RunningConf.NeighborAddress != nil {
 		s.ProcessRemoveNeighbor(peer.NeighborConf.RunningConf.NeighborAddress.String(), peer)
+		if !peer.NeighborConf.RunningConf.NeighborAddress.Equal(newPeer.NeighborAddress) {
+			s.removeRouteServerClient(peer.NeighborConf.RunningConf.NeighborAddress.String(), peer)
+		}
 		if peer.NeighborConf.RunningConf.NeighborAddress.To4() != nil &&
 			peer.NeighborConf.RunningConf.AuthPassword != "" {
 			err := netUtils.SetTCPListenerMD5(s.listener, peer.NeighborConf.RunningConf.NeighborAddress.String(), "")
@@ -1873,8 +2324,11 @@ func (s *BGPServer) updatePeerConf(oldPeer, newPeer config.NeighborConfig, peer
 			}
 		}
 	}
+	s.setRouteServerClient(peer)
 
-	if peer.NeighborConf.RunningConf.NeighborAddress.To4() != nil &&
+	if peer.NeighborConf.RunningConf.KeyChain != "" {
+		s.setPeerAuthKeys(peer)
+	} else if peer.NeighborConf.RunningConf.NeighborAddress.To4() != nil &&
 		peer.NeighborConf.RunningConf.AuthPassword != "" {
 		err := netUtils.SetTCPListenerMD5(s.listener, newPeer.NeighborAddress.String(),
 			peer.NeighborConf.RunningConf.AuthPassword)
@@ -2014,8 +2468,12 @@ func (s *BGPServer) removePeer(neighbor config.NeighborConfig) {
 		s.removePeerFromList(peer)
 		s.NeighborMutex.Unlock()
 		delete(s.PeerMap, peerIP)
+		s.SendBMPPeerDown(peer, true, false)
+		s.setPeerShutdownReason(peer, packet.BGPCeasePeerDeconfigured, "")
 		peer.Cleanup()
+		s.removePeerAuthKeys(peer)
 		s.ProcessRemoveNeighbor(peerIP, peer)
+		s.removeRouteServerClient(peerIP, peer)
 	} else if ifacePeer != nil {
 		s.NeighborMutex.Lock()
 		s.removePeerFromList(ifacePeer)
@@ -2050,6 +2508,7 @@ func (s *BGPServer) listenChannelUpdates() {
 					s.Updatev6Peer(peer, oldPeer, newPeer, peerUpdate.AttrSet)
 				}
 			}
+			s.updateListenerTTLSecurity()
 			/*
 				var peer *Peer
 				var ok bool
@@ -2117,6 +2576,7 @@ func (s *BGPServer) listenChannelUpdates() {
 
 		case remPeer := <-s.RemPeerCh:
 			s.removePeer(remPeer)
+			s.updateListenerTTLSecurity()
 
 		case groupUpdate := <-s.AddPeerGroupCh:
 			oldGroupConf := groupUpdate.OldGroup
@@ -2148,6 +2608,7 @@ func (s *BGPServer) listenChannelUpdates() {
 				s.BgpConfig.PeerGroups[protoFamily][newGroupConf.Name].Config = newGroupConf
 			}
 			s.UpdatePeerGroupInPeers(newGroupConf.Name, newGroupConf.PeerAddressType, &newGroupConf)
+			s.updateListenerTTLSecurity()
 
 		case group := <-s.RemPeerGroupCh:
 			s.logger.Info("Remove Peer group:", group.Name)
@@ -2163,6 +2624,7 @@ func (s *BGPServer) listenChannelUpdates() {
 			}
 			delete(s.BgpConfig.PeerGroups[protoFamily], group.Name)
 			s.UpdatePeerGroupInPeers(group.Name, group.PeerAddressType, nil)
+			s.updateListenerTTLSecurity()
 
 		case aggUpdate := <-s.AddAggCh:
 			oldAgg := aggUpdate.OldAgg
@@ -2174,6 +2636,24 @@ func (s *BGPServer) listenChannelUpdates() {
 		case aggConf := <-s.RemAggCh:
 			s.DeleteAgg(aggConf)
 
+		case vrfUpdate := <-s.AddVrfCh:
+			if vrfUpdate.NewVrf.Name != "" {
+				s.AddOrUpdateVrf(vrfUpdate.OldVrf, vrfUpdate.NewVrf, vrfUpdate.AttrSet)
+			}
+
+		case vrfConf := <-s.RemVrfCh:
+			s.DeleteVrf(vrfConf)
+
+		case rangeUpdate := <-s.AddListenRangeCh:
+			if rangeUpdate.NewRange.Prefix != "" {
+				s.AddOrUpdateListenRange(rangeUpdate.OldRange, rangeUpdate.NewRange)
+				s.updateListenerTTLSecurity()
+			}
+
+		case rangeConf := <-s.RemListenRangeCh:
+			s.DeleteListenRange(rangeConf)
+			s.updateListenerTTLSecurity()
+
 		case tcpConn := <-s.acceptCh:
 			s.logger.Info("Connected to", tcpConn.RemoteAddr().String())
 			host, _, _ := net.SplitHostPort(tcpConn.RemoteAddr().String())
@@ -2181,21 +2661,66 @@ func (s *BGPServer) listenChannelUpdates() {
 			host = hostSplit[0]
 			peer, ok := s.PeerMap[host]
 			if !ok {
-				s.logger.Info("Can't accept connection. Peer is not configured yet", host)
-				tcpConn.Close()
-				s.logger.Info("Closed connection from", host)
-				break
+				if peer = s.createDynamicPeer(net.ParseIP(host)); peer == nil {
+					s.logger.Info("Can't accept connection. Peer is not configured yet", host)
+					tcpConn.Close()
+					s.logger.Info("Closed connection from", host)
+					break
+				}
 			}
 			peer.AcceptConn(tcpConn)
 
 		case peerCommand := <-s.PeerCommandCh:
 			s.logger.Info("Peer Command received", peerCommand)
-			peer, ok := s.PeerMap[peerCommand.IP.String()]
-			if !ok {
-				s.logger.Infof("Failed to apply command %s. Peer at that address does not exist, %v",
-					peerCommand.Command, peerCommand.IP)
-			}
-			peer.Command(peerCommand.Command, fsm.BGPCmdReasonNone)
+			s.ProcessPeerCommand(peerCommand)
+
+		case peerShutdown := <-s.PeerShutdownCh:
+			s.logger.Info("Peer graceful shutdown received", peerShutdown)
+			s.GracefulShutdownPeer(peerShutdown)
+
+		case peerIP := <-s.ShutdownTimerCh:
+			s.logger.Info("Graceful shutdown timer expired for peer", peerIP)
+			s.ProcessGracefulShutdownExpiry(peerIP)
+
+		case peerIP := <-s.SoftResetInCh:
+			s.logger.Info("Soft reset inbound received for peer", peerIP)
+			s.SoftResetPeerIn(peerIP)
+
+		case stationAddr := <-s.bmpManager.StationUpCh:
+			s.ProcessBMPStationUp(stationAddr)
+
+		case peerIP := <-s.StaleTimerCh:
+			s.logger.Info("Graceful restart timer expired for peer", peerIP)
+			s.ProcessStaleTimerExpiry(peerIP)
+
+		case periodic := <-s.MRTDumpCh:
+			s.ProcessMRTDump(periodic)
+
+		case <-s.DampeningReuseCh:
+			s.ProcessDampeningReuse()
+
+		case prefix := <-s.ClearDampeningCh:
+			s.logger.Info("Clear dampening received for prefix", prefix)
+			s.ClearDampening(prefix)
+
+		case keyChainConf := <-s.AddKeyChainCh:
+			s.AddOrUpdateKeyChain(keyChainConf)
+
+		case name := <-s.RemKeyChainCh:
+			s.DeleteKeyChain(name)
+
+		case <-s.KeyChainTimerCh:
+			s.ProcessKeyChainTimer()
+
+		case <-s.rpkiManager.UpdateCh:
+			s.ProcessROAUpdate()
+
+		case <-s.policyManager.UpdateCh:
+			s.ProcessPolicyUpdate()
+
+		case <-s.DeferralExpCh:
+			s.logger.Info("Best path selection deferral timer expired")
+			s.EndSelectionDeferral()
 
 		case peerFSMConn := <-s.PeerFSMConnCh:
 			s.logger.Infof("Server: Peer %s FSM established/broken channel", peerFSMConn.PeerIP)
@@ -2206,15 +2731,30 @@ func (s *BGPServer) listenChannelUpdates() {
 				break
 			}
 
+			if peerFSMConn.Idle {
+				if peer.IsDynamic() {
+					s.removeDynamicPeer(peer)
+				}
+				break
+			}
+
 			if peerFSMConn.Established {
 				peer.PeerConnEstablished(peerFSMConn.Conn)
+				s.setPeerAuthKeys(peer)
+				s.SendBMPPeerUp(peer)
 				addPathsMaxTx := peer.getAddPathsMaxTx()
 				if addPathsMaxTx > s.AddPathCount {
 					s.AddPathCount = addPathsMaxTx
 				}
 				s.setInterfaceMapForPeer(peerFSMConn.PeerIP, peer)
 				s.SendAllRoutesToPeer(peer)
+				s.ProcessGracefulRestart(peer)
+				if !s.LocRib.IsSelectionDeferred() {
+					peer.SendEndOfRIB()
+				}
 			} else {
+				gracefulRestart := peerFSMConn.GracefulRestart && peer.IsGracefulRestartHelper() && !peer.IsDynamic()
+				s.SendBMPPeerDown(peer, false, peerFSMConn.GracefulRestart)
 				peer.PeerConnBroken(true)
 				addPathsMaxTx := peer.getAddPathsMaxTx()
 				if addPathsMaxTx < s.AddPathCount {
@@ -2227,7 +2767,16 @@ func (s *BGPServer) listenChannelUpdates() {
 					}
 				}
 				s.clearInterfaceMapForPeer(peerFSMConn.PeerIP, peer)
-				s.ProcessRemoveNeighbor(peerFSMConn.PeerIP, peer)
+				if gracefulRestart {
+					s.ProcessMarkStaleNeighbor(peer)
+				} else {
+					peer.ClearStaleRoutes()
+					s.ProcessRemoveNeighbor(peerFSMConn.PeerIP, peer)
+				}
+				if peer.IsDynamic() {
+					s.removeDynamicPeer(peer)
+				}
+				s.CheckSelectionDeferral()
 			}
 
 		case peerIP := <-s.PeerConnEstCh:
@@ -2284,7 +2833,11 @@ func (s *BGPServer) listenChannelUpdates() {
 
 		case pktInfo := <-s.BGPPktSrcCh:
 			s.logger.Info("Received BGP message from peer %s", pktInfo.Src)
-			s.ProcessUpdate(pktInfo)
+			if pktInfo.Msg.Header.Type == packet.BGPMsgTypeRouteRefresh {
+				s.ProcessRouteRefresh(pktInfo)
+			} else {
+				s.ProcessUpdate(pktInfo)
+			}
 
 		case reachabilityInfo := <-s.ReachabilityCh:
 			s.logger.Info("Server: Get reachability info for ip", reachabilityInfo.IP)
@@ -2296,6 +2849,12 @@ func (s *BGPServer) listenChannelUpdates() {
 		case bfdNotify := <-s.BfdCh:
 			s.handleBfdNotifications(bfdNotify.Oper, bfdNotify.DestIp, bfdNotify.State)
 
+		case evpnInfo := <-s.EVPNCh:
+			s.handleEVPNNotification(evpnInfo)
+
+		case linkStateInfo := <-s.LinkStateCh:
+			s.handleLinkStateNotification(linkStateInfo)
+
 		case ifState := <-s.IntfCh:
 			s.logger.Info("Received message on ItfCh")
 			if ifState.State == config.INTF_STATE_DOWN {
@@ -2406,8 +2965,11 @@ func (s *BGPServer) StartServer() {
 	s.GlobalCfgDone = true
 	s.logger.Info("Recieved global conf:", gConf)
 	s.BgpConfig.Global.Config = gConf
+	s.setGracefulRestartDefaults()
+	s.startGracefulRestart()
 	s.constructBGPGlobalState(&gConf)
 	s.BgpConfig.PeerGroups = make(map[uint32]map[string]*config.PeerGroup)
+	s.startDampeningTimer()
 
 	pathAttrs := packet.ConstructPathAttrForConnRoutes(gConf.AS)
 	protoFamily := packet.GetProtocolFamily(packet.AfiIP6, packet.SafiUnicast)
@@ -2428,7 +2990,13 @@ func (s *BGPServer) StartServer() {
 	s.IntfMgr.Start()
 	s.routeMgr.Start()
 	s.bfdMgr.Start()
+	s.fsMgr.Start()
+	s.evpnMgr.Start()
+	s.lsMgr.Start()
 	s.SetupRedistribution(gConf)
+	s.bmpManager.SetStations(gConf.BMPStations)
+	s.SetupMRT(gConf.MRT)
+	s.SetupRPKI(gConf.RPKI)
 
 	/*  ALERT: StartServer is a go routine and hence do not have any other go routine where
 	 *	   you are making calls to other client. FlexSwitch uses thrift for rpc and hence
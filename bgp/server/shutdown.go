@@ -0,0 +1,155 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// shutdown.go
+package server
+
+import (
+	"l3/bgp/config"
+	"l3/bgp/fsm"
+	"l3/bgp/packet"
+	bgprib "l3/bgp/rib"
+	"time"
+)
+
+// setPeerShutdownReason sets the cease subcode and the shutdown communication sent to the peer when the session is
+// stopped and cancels the graceful shutdown in progress.
+func (s *BGPServer) setPeerShutdownReason(peer *Peer, subcode uint8, reason string) {
+	peer.stopGracefulShutdownTimer()
+	peer.NeighborConf.SetGracefulShutdown(false)
+	if peer.IsActive() {
+		peer.NeighborConf.SetShutdownCommunication(subcode, reason)
+	}
+}
+
+func (s *BGPServer) ProcessPeerCommand(peerCommand config.PeerCommand) {
+	peer, ok := s.PeerMap[peerCommand.IP.String()]
+	if !ok {
+		s.logger.Infof("Failed to apply command %d. Peer at that address does not exist, %v",
+			peerCommand.Command, peerCommand.IP)
+		return
+	}
+
+	switch peerCommand.Command {
+	case int(fsm.BGPEventManualStart):
+		if peer.IsActive() {
+			s.logger.Infof("Neighbor %s is already active", peerCommand.IP)
+			return
+		}
+		// Clear the admin shutdown set by the graceful shutdown action and start the peer again
+		s.logger.Infof("Neighbor %s: Clear admin shutdown and start the peer", peerCommand.IP)
+		peer.NeighborConf.SetAdminShutdown(false)
+		peer.Init()
+		return
+
+	case int(fsm.BGPEventManualStop):
+		if !peer.IsActive() {
+			s.logger.Infof("Failed to reset, Neighbor %s is not active", peerCommand.IP)
+			return
+		}
+		s.setPeerShutdownReason(peer, packet.BGPCeaseAdminReset, peerCommand.Reason)
+	}
+	peer.Command(peerCommand.Command, fsm.BGPCmdReasonNone)
+}
+
+// GracefulShutdownPeer lowers the preference of the paths to and from the peer and tears down the session after
+// the shutdown time, giving the network time to move the traffic to the alternate paths (RFC 8326).
+func (s *BGPServer) GracefulShutdownPeer(shutdown config.PeerShutdown) {
+	peerIP := shutdown.IP.String()
+	peer, ok := s.PeerMap[peerIP]
+	if !ok {
+		s.logger.Errf("Failed to gracefully shut down, Peer %s does not exist", peerIP)
+		return
+	}
+
+	if !peer.IsActive() {
+		s.logger.Errf("Failed to gracefully shut down, Peer %s is not active", peerIP)
+		return
+	}
+
+	if peer.NeighborConf.IsGracefulShutdown() {
+		s.logger.Infof("Graceful shutdown of peer %s is already in progress", peerIP)
+		return
+	}
+
+	seconds := shutdown.Time
+	if seconds == 0 {
+		seconds = config.BGPGracefulShutdownTimeDefault
+	}
+	s.logger.Infof("Neighbor %s: Graceful shutdown, tear down the session in %d seconds", peerIP, seconds)
+	peer.NeighborConf.SetGracefulShutdown(true)
+	peer.resendRIBOut()
+	s.processBestPathConfigChange()
+	peer.startGracefulShutdownTimer(seconds, shutdown.Reason)
+}
+
+func (s *BGPServer) ProcessGracefulShutdownExpiry(peerIP string) {
+	peer, ok := s.PeerMap[peerIP]
+	if !ok {
+		s.logger.Errf("Failed to shut down, Peer %s does not exist", peerIP)
+		return
+	}
+
+	if !peer.NeighborConf.IsGracefulShutdown() {
+		s.logger.Infof("Graceful shutdown of peer %s is not in progress", peerIP)
+		return
+	}
+
+	peer.gshutTimer = nil
+	s.setPeerShutdownReason(peer, packet.BGPCeaseAdminShutdown, peer.gshutReason)
+	peer.Cleanup()
+	peer.NeighborConf.SetAdminShutdown(true)
+	s.ProcessRemoveNeighbor(peerIP, peer)
+}
+
+func (p *Peer) startGracefulShutdownTimer(seconds uint32, reason string) {
+	p.stopGracefulShutdownTimer()
+	peerIP := p.NeighborConf.Neighbor.NeighborAddress.String()
+	p.gshutReason = reason
+	p.gshutTimer = time.AfterFunc(time.Duration(seconds)*time.Second, func() {
+		p.server.ShutdownTimerCh <- peerIP
+	})
+}
+
+func (p *Peer) stopGracefulShutdownTimer() {
+	if p.gshutTimer != nil {
+		p.gshutTimer.Stop()
+		p.gshutTimer = nil
+	}
+}
+
+// resendRIBOut sends the Loc-RIB to the peer again so that the path attributes are updated
+func (p *Peer) resendRIBOut() {
+	p.splitFromUpdateGroup()
+	updated := make(map[uint32]map[*bgprib.Path][]*bgprib.Destination)
+	for protoFamily, pathDestMap := range p.locRib.GetLocRib() {
+		if !p.NeighborConf.AfiSafiMap[protoFamily] {
+			continue
+		}
+		updated[protoFamily] = pathDestMap
+		p.ribOut[protoFamily] = make(map[string]*bgprib.AdjRIBRoute)
+		delete(p.sourceExcluded, protoFamily)
+	}
+	p.SendUpdate(updated, make([]*bgprib.Destination, 0), make([]*bgprib.Destination, 0))
+	p.joinUpdateGroup()
+}
@@ -0,0 +1,332 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// updateGroup.go
+package server
+
+import (
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	bgprib "l3/bgp/rib"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type updateGroupKey struct {
+	locRib       *bgprib.LocRib
+	neighbor     string
+	localAddress string
+	localAS      uint32
+	peerAS       uint32
+	asSize       uint8
+	internal     bool
+	rrClient     bool
+	nextHopSelf  bool
+	addPathsTx   string
+	families     string
+	extNextHop   string
+}
+
+// Peers with the same outbound policy and negotiated capabilities share the Adj-RIB-Out and the formatted
+// UPDATE messages
+type UpdateGroup struct {
+	key     updateGroupKey
+	ribOut  map[uint32]map[string]*bgprib.AdjRIBRoute
+	members map[string]*Peer
+}
+
+func newUpdateGroup(key updateGroupKey, ribOut map[uint32]map[string]*bgprib.AdjRIBRoute) *UpdateGroup {
+	return &UpdateGroup{
+		key:     key,
+		ribOut:  ribOut,
+		members: make(map[string]*Peer),
+	}
+}
+
+type updateMsgInfo struct {
+	msg  *packet.BGPMessage
+	path *bgprib.Path
+}
+
+func getProtoFamilyKey(afiSafiMap map[uint32]bool) string {
+	protoFamilies := make([]int, 0, len(afiSafiMap))
+	for protoFamily, ok := range afiSafiMap {
+		if ok {
+			protoFamilies = append(protoFamilies, int(protoFamily))
+		}
+	}
+	sort.Ints(protoFamilies)
+
+	keys := make([]string, len(protoFamilies))
+	for idx, protoFamily := range protoFamilies {
+		keys[idx] = strconv.Itoa(protoFamily)
+	}
+	return strings.Join(keys, ",")
+}
+
+func getAddPathsTxKey(addPathsTxFamily map[uint32]config.AddPathsConfig) string {
+	protoFamilies := make([]int, 0, len(addPathsTxFamily))
+	for protoFamily, _ := range addPathsTxFamily {
+		protoFamilies = append(protoFamilies, int(protoFamily))
+	}
+	sort.Ints(protoFamilies)
+
+	keys := make([]string, len(protoFamilies))
+	for idx, protoFamily := range protoFamilies {
+		addPathsConf := addPathsTxFamily[uint32(protoFamily)]
+		keys[idx] = strconv.Itoa(protoFamily) + ":" + strconv.Itoa(int(addPathsConf.MaxTx)) + ":" + addPathsConf.TxMode
+	}
+	return strings.Join(keys, ",")
+}
+
+func copyRIBOut(ribOut map[uint32]map[string]*bgprib.AdjRIBRoute) map[uint32]map[string]*bgprib.AdjRIBRoute {
+	ribOutCopy := make(map[uint32]map[string]*bgprib.AdjRIBRoute, len(ribOut))
+	for protoFamily, routes := range ribOut {
+		ribOutCopy[protoFamily] = make(map[string]*bgprib.AdjRIBRoute, len(routes))
+		for ip, route := range routes {
+			ribOutCopy[protoFamily][ip] = route.Clone()
+		}
+	}
+	return ribOutCopy
+}
+
+func (p *Peer) getUpdateGroupKey() updateGroupKey {
+	conf := p.NeighborConf
+	key := updateGroupKey{
+		locRib:       p.locRib,
+		localAddress: conf.Neighbor.Transport.Config.LocalAddress.String(),
+		localAS:      conf.RunningConf.LocalAS,
+		peerAS:       conf.RunningConf.PeerAS,
+		asSize:       conf.ASSize,
+		internal:     conf.IsInternal(),
+		rrClient:     conf.IsRouteReflectorClient(),
+		nextHopSelf:  conf.RunningConf.NextHopSelf,
+		addPathsTx:   getAddPathsTxKey(conf.AddPathsTxFamily),
+		families:     getProtoFamilyKey(conf.AfiSafiMap),
+		extNextHop:   getProtoFamilyKey(conf.ExtNextHopFamily),
+	}
+
+	// Adj-RIB-Out policy state, conditional advertisement, default route origination, ORF entries and graceful
+	// shutdown are kept per neighbor. These peers get an update group of their own.
+	if conf.RunningConf.AdjRIBOutFilter != "" || conf.RunningConf.DefaultOriginate || p.isAdvertiseMapConfigured() ||
+		p.hasORFEntries() || conf.IsGracefulShutdown() {
+		key.neighbor = conf.Neighbor.NeighborAddress.String()
+	}
+	return key
+}
+
+func (p *Peer) joinUpdateGroup() {
+	if p.updateGroup != nil || p.NeighborConf.Neighbor.Transport.Config.LocalAddress == nil {
+		return
+	}
+
+	key := p.getUpdateGroupKey()
+	group, ok := p.server.updateGroups[key]
+	if !ok {
+		group = newUpdateGroup(key, p.ribOut)
+		p.server.updateGroups[key] = group
+	}
+
+	p.logger.Infof("Neighbor %s: Join update group with %d members", p.NeighborConf.Neighbor.NeighborAddress,
+		len(group.members))
+	p.ribOut = group.ribOut
+	group.members[p.NeighborConf.Neighbor.NeighborAddress.String()] = p
+	p.updateGroup = group
+}
+
+func (p *Peer) leaveUpdateGroup() {
+	group := p.updateGroup
+	if group == nil {
+		return
+	}
+
+	p.logger.Infof("Neighbor %s: Leave update group with %d members", p.NeighborConf.Neighbor.NeighborAddress,
+		len(group.members))
+	delete(group.members, p.NeighborConf.Neighbor.NeighborAddress.String())
+	p.updateGroup = nil
+	if len(group.members) == 0 {
+		delete(p.server.updateGroups, group.key)
+	}
+}
+
+// Peer leaves the update group with a copy of the Adj-RIB-Out so that it can be updated on its own
+func (p *Peer) splitFromUpdateGroup() {
+	group := p.updateGroup
+	p.leaveUpdateGroup()
+	if group != nil && len(group.members) > 0 {
+		p.ribOut = copyRIBOut(group.ribOut)
+	}
+}
+
+func (p *Peer) getUpdateGroupMembers() []*Peer {
+	if p.updateGroup == nil {
+		return []*Peer{p}
+	}
+
+	members := make([]*Peer, 0, len(p.updateGroup.members))
+	for _, member := range p.updateGroup.members {
+		members = append(members, member)
+	}
+	return members
+}
+
+// getUpdateMsgNLRIs returns the NLRIs advertised and withdrawn by the update message per protocol family
+func getUpdateMsgNLRIs(msg *packet.BGPMessage) (map[uint32][]packet.NLRI, map[uint32][]packet.NLRI) {
+	reach := make(map[uint32][]packet.NLRI)
+	unreach := make(map[uint32][]packet.NLRI)
+	updateMsg := msg.Body.(*packet.BGPUpdate)
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast)
+	if len(updateMsg.NLRI) > 0 {
+		reach[protoFamily] = updateMsg.NLRI
+	}
+	if len(updateMsg.WithdrawnRoutes) > 0 {
+		unreach[protoFamily] = updateMsg.WithdrawnRoutes
+	}
+
+	mpReach, mpUnreach := packet.GetMPAttrs(updateMsg.PathAttributes)
+	if mpReach != nil && len(mpReach.NLRI) > 0 {
+		protoFamily = packet.GetProtocolFamily(mpReach.AFI, mpReach.SAFI)
+		reach[protoFamily] = append(reach[protoFamily], mpReach.NLRI...)
+	}
+	if mpUnreach != nil && len(mpUnreach.NLRI) > 0 {
+		protoFamily = packet.GetProtocolFamily(mpUnreach.AFI, mpUnreach.SAFI)
+		unreach[protoFamily] = append(unreach[protoFamily], mpUnreach.NLRI...)
+	}
+	return reach, unreach
+}
+
+func constructWithdrawMsg(withdrawList map[uint32][]packet.NLRI) *packet.BGPMessage {
+	var ipv4List []packet.NLRI
+	pathAttrs := make([]packet.BGPPathAttr, 0)
+	for protoFamily, nlriList := range withdrawList {
+		if len(nlriList) == 0 {
+			continue
+		}
+		if protoFamily == packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast) {
+			ipv4List = nlriList
+			continue
+		}
+		pathAttrs = append(pathAttrs, packet.ConstructMPUnreachNLRIFromProtoFamily(protoFamily, nlriList))
+	}
+
+	if len(ipv4List) == 0 && len(pathAttrs) == 0 {
+		return nil
+	}
+	return packet.NewBGPUpdateMessage(ipv4List, pathAttrs, nil)
+}
+
+func (p *Peer) getSourceExcludedKey(protoFamily uint32, nlri packet.NLRI) string {
+	if _, ok := p.NeighborConf.AddPathsTxFamily[protoFamily]; ok {
+		return nlri.GetCIDR() + ":" + strconv.Itoa(int(nlri.GetPathId()))
+	}
+	return nlri.GetCIDR()
+}
+
+func (p *Peer) isSourceExcluded(protoFamily uint32, nlri packet.NLRI) bool {
+	return p.sourceExcluded[protoFamily][p.getSourceExcludedKey(protoFamily, nlri)]
+}
+
+func (p *Peer) setSourceExcluded(protoFamily uint32, nlri packet.NLRI, excluded bool) {
+	key := p.getSourceExcludedKey(protoFamily, nlri)
+	if !excluded {
+		delete(p.sourceExcluded[protoFamily], key)
+		return
+	}
+
+	if p.sourceExcluded == nil {
+		p.sourceExcluded = make(map[uint32]map[string]bool)
+	}
+	if _, ok := p.sourceExcluded[protoFamily]; !ok {
+		p.sourceExcluded[protoFamily] = make(map[string]bool)
+	}
+	p.sourceExcluded[protoFamily][key] = true
+}
+
+// A route that is added to the Adj-RIB-Out with a path learned from a member was never advertised to that member.
+// The member is excluded from the route without sending it a withdraw.
+func (p *Peer) excludeSourceMembers(protoFamily uint32, nlri packet.NLRI, path *bgprib.Path) {
+	for _, member := range p.getUpdateGroupMembers() {
+		if member.isSourcePeer(path) {
+			member.setSourceExcluded(protoFamily, nlri, true)
+		}
+	}
+}
+
+// getMemberUpdateMsg returns the update message to send to the member, nil if there is nothing to send. A route
+// learned from the member is withdrawn from it only if it was advertised to the member before, and withdraws of the
+// routes the member is excluded from are not sent to it.
+func (p *Peer) getMemberUpdateMsg(info *updateMsgInfo) *packet.BGPMessage {
+	reach, unreach := getUpdateMsgNLRIs(info.msg)
+	if p.isSourcePeer(info.path) {
+		withdrawList := make(map[uint32][]packet.NLRI)
+		for protoFamily, nlriList := range reach {
+			for _, nlri := range nlriList {
+				if !p.isSourceExcluded(protoFamily, nlri) {
+					withdrawList[protoFamily] = append(withdrawList[protoFamily], nlri)
+					p.setSourceExcluded(protoFamily, nlri, true)
+				}
+			}
+		}
+		return constructWithdrawMsg(withdrawList)
+	}
+
+	for protoFamily, nlriList := range reach {
+		for _, nlri := range nlriList {
+			p.setSourceExcluded(protoFamily, nlri, false)
+		}
+	}
+	if info.path != nil {
+		return info.msg.Clone()
+	}
+
+	filtered := false
+	withdrawList := make(map[uint32][]packet.NLRI)
+	for protoFamily, nlriList := range unreach {
+		for _, nlri := range nlriList {
+			if p.isSourceExcluded(protoFamily, nlri) {
+				p.setSourceExcluded(protoFamily, nlri, false)
+				filtered = true
+				continue
+			}
+			withdrawList[protoFamily] = append(withdrawList[protoFamily], nlri)
+		}
+	}
+	if !filtered {
+		return info.msg.Clone()
+	}
+	return constructWithdrawMsg(withdrawList)
+}
+
+// Members share the Adj-RIB-Out, so a route that was learned from a member is withdrawn from it instead of
+// being advertised back. The routes each member is excluded from are tracked per member.
+func (p *Peer) sendUpdateMsgsToGroup(msgs []*updateMsgInfo) {
+	members := p.getUpdateGroupMembers()
+	for _, info := range msgs {
+		for _, member := range members {
+			if msg := member.getMemberUpdateMsg(info); msg != nil {
+				member.sendFormattedUpdateMsg(msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,430 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// vrf.go
+package server
+
+import (
+	"errors"
+	"fmt"
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	bgprib "l3/bgp/rib"
+)
+
+const (
+	vrfExportSrcPrefix = "vrf:"
+	vpnImportSrcPrefix = "vpn:"
+)
+
+type VRF struct {
+	Name     string
+	RD       *packet.RouteDistinguisher
+	Label    uint32
+	ImportRT map[uint64]bool
+	ExportRT []uint64
+	LocRib   *bgprib.LocRib
+}
+
+func (v *VRF) setConfig(vrfConf config.VrfConfig) error {
+	rd, err := packet.ParseRouteDistinguisher(vrfConf.RD)
+	if err != nil {
+		return err
+	}
+
+	importRT := make(map[uint64]bool)
+	for _, rtStr := range vrfConf.ImportRT {
+		rt, err := packet.ParseRouteTarget(rtStr)
+		if err != nil {
+			return err
+		}
+		importRT[rt] = true
+	}
+
+	exportRT := make([]uint64, 0, len(vrfConf.ExportRT))
+	for _, rtStr := range vrfConf.ExportRT {
+		rt, err := packet.ParseRouteTarget(rtStr)
+		if err != nil {
+			return err
+		}
+		exportRT = append(exportRT, rt)
+	}
+
+	v.RD = rd
+	v.Label = vrfConf.Label
+	v.ImportRT = importRT
+	v.ExportRT = exportRT
+	return nil
+}
+
+func (v *VRF) isImported(path *bgprib.Path) bool {
+	for _, community := range packet.GetExtCommunities(path.PathAttrs) {
+		if v.ImportRT[community] {
+			return true
+		}
+	}
+	return false
+}
+
+func NewVRF(s *BGPServer, vrfConf config.VrfConfig) (*VRF, error) {
+	vrf := &VRF{
+		Name: vrfConf.Name,
+	}
+	if err := vrf.setConfig(vrfConf); err != nil {
+		return nil, err
+	}
+
+	vrf.LocRib = bgprib.NewLocRib(s.logger, s.routeMgr, s.stateDBMgr, &s.BgpConfig.Global.Config)
+	vrf.LocRib.SetVrf(vrfConf.Name)
+	vrf.LocRib.SetROATable(s.rpkiManager.Table)
+	return vrf, nil
+}
+
+func (s *BGPServer) getVrfForLocRib(locRib *bgprib.LocRib) *VRF {
+	for _, vrf := range s.vrfs {
+		if vrf.LocRib == locRib {
+			return vrf
+		}
+	}
+	return nil
+}
+
+func (s *BGPServer) getLocRibForVrf(vrfName string) (*bgprib.LocRib, bool) {
+	if vrfName == "" {
+		return s.LocRib, true
+	}
+	if vrf, ok := s.vrfs[vrfName]; ok {
+		return vrf.LocRib, true
+	}
+	return nil, false
+}
+
+// All the destinations in one set of updates belong to the same LocRib
+func (s *BGPServer) getUpdateLocRib(updated map[uint32]map[*bgprib.Path][]*bgprib.Destination, withdrawn,
+	updatedAddPaths []*bgprib.Destination) *bgprib.LocRib {
+	for _, pathDestMap := range updated {
+		for _, destinations := range pathDestMap {
+			for _, dest := range destinations {
+				if dest != nil {
+					return dest.GetLocRib()
+				}
+			}
+		}
+	}
+
+	for _, destinations := range [][]*bgprib.Destination{withdrawn, updatedAddPaths} {
+		for _, dest := range destinations {
+			if dest != nil {
+				return dest.GetLocRib()
+			}
+		}
+	}
+	return nil
+}
+
+func isUpdateEmpty(updated map[uint32]map[*bgprib.Path][]*bgprib.Destination, withdrawn,
+	updatedAddPaths []*bgprib.Destination) bool {
+	return len(updated) == 0 && len(withdrawn) == 0 && len(updatedAddPaths) == 0
+}
+
+func (s *BGPServer) leakVrfRoutes(locRib *bgprib.LocRib, updated map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+	withdrawn []*bgprib.Destination) {
+	if len(s.vrfs) == 0 {
+		return
+	}
+
+	if locRib == s.LocRib {
+		for _, vrf := range s.vrfs {
+			s.importVPNRoutes(vrf, updated, withdrawn)
+		}
+	} else if vrf := s.getVrfForLocRib(locRib); vrf != nil {
+		s.exportVrfRoutes(vrf, updated, withdrawn)
+	}
+}
+
+func (s *BGPServer) constructVrfExportPath(vrf *VRF, path *bgprib.Path, vpnFamily uint32) *bgprib.Path {
+	pathAttrs := packet.CopyPathAttrs(path.PathAttrs)
+	packet.RemoveNextHop(&pathAttrs)
+	extCommunities := make([]uint64, 0)
+	for _, community := range packet.GetExtCommunities(pathAttrs) {
+		if uint8(community>>48) != packet.BGPExtCommunitySubTypeRT {
+			extCommunities = append(extCommunities, community)
+		}
+	}
+	extCommunities = append(extCommunities, vrf.ExportRT...)
+	pathAttrs = packet.SetExtCommunities(pathAttrs, extCommunities)
+
+	mpReach := packet.ConstructIPv6MPReachNLRI(vpnFamily, packet.GetZeroNextHopForFamily(vpnFamily), nil, nil)
+	exportPath := bgprib.NewPath(s.LocRib, nil, pathAttrs, mpReach, bgprib.RouteTypeConnected)
+	exportPath.SetLeaked()
+	return exportPath
+}
+
+// exportVrfRoutes adds the best paths of the VRF to the VPN table with the RD, label and the export RTs of the VRF
+func (s *BGPServer) exportVrfRoutes(vrf *VRF, updated map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+	withdrawn []*bgprib.Destination) {
+	src := vrfExportSrcPrefix + vrf.Name
+	for protoFamily, pathDestMap := range updated {
+		afi, safi := packet.GetAfiSafi(protoFamily)
+		if safi != packet.SafiUnicast {
+			continue
+		}
+
+		vpnFamily := packet.GetProtocolFamily(afi, packet.SafiMPLSVPN)
+		for path, destinations := range pathDestMap {
+			add := make(map[uint32][]packet.NLRI)
+			for _, dest := range destinations {
+				nlri := packet.NewVPNNLRI(vrf.RD, vrf.Label, dest.NLRI.GetIPPrefix())
+				if path.IsLeaked() {
+					// Routes imported from the VPN table are not exported again
+					s.removeLeakedRoute(s.LocRib, src, vpnFamily, nlri)
+				} else {
+					add[vpnFamily] = append(add[vpnFamily], nlri)
+				}
+			}
+			if len(add) > 0 {
+				s.logger.Infof("VRF %s export routes %v", vrf.Name, add[vpnFamily])
+				s.processLeakedRoutes(s.LocRib, src, s.constructVrfExportPath(vrf, path, vpnFamily), add, nil)
+			}
+		}
+	}
+
+	for _, dest := range withdrawn {
+		afi, safi := packet.GetAfiSafi(dest.GetProtocolFamily())
+		if safi != packet.SafiUnicast {
+			continue
+		}
+		s.removeLeakedRoute(s.LocRib, src, packet.GetProtocolFamily(afi, packet.SafiMPLSVPN),
+			packet.NewVPNNLRI(vrf.RD, vrf.Label, dest.NLRI.GetIPPrefix()))
+	}
+}
+
+func (s *BGPServer) constructVrfImportPath(vrf *VRF, path *bgprib.Path, vpnFamily uint32) *bgprib.Path {
+	afi, _ := packet.GetAfiSafi(vpnFamily)
+	protoFamily := packet.GetProtocolFamily(afi, packet.SafiUnicast)
+	pathAttrs := packet.CopyPathAttrs(path.PathAttrs)
+	nextHop := path.GetNextHop(vpnFamily)
+	var mpReach *packet.BGPPathAttrMPReachNLRI
+	if afi == packet.AfiIP6 {
+		mpReach = packet.ConstructIPv6MPReachNLRI(protoFamily, nextHop, nil, nil)
+	} else {
+		packet.RemoveNextHop(&pathAttrs)
+		nextHopAttr := packet.NewBGPPathAttrNextHop()
+		nextHopAttr.Value = nextHop.To4()
+		pathAttrs = packet.AddPathAttrToPathAttrsByCode(pathAttrs, packet.BGPPathAttrTypeNextHop, nextHopAttr)
+	}
+
+	routeType := bgprib.RouteTypeEGP
+	if path.IsLocal() {
+		routeType = bgprib.RouteTypeConnected
+	}
+	importPath := bgprib.NewPath(vrf.LocRib, path.GetNeighborConf(), pathAttrs, mpReach, routeType)
+	importPath.SetLeaked()
+	return importPath
+}
+
+// isVrfExportPath returns true if the path was exported to the VPN table by the VRF. Other VRFs and remote PEs
+// may use the same RD, the source of the path is checked instead of the RD.
+func (v *VRF) isVrfExportPath(dest *bgprib.Destination, path *bgprib.Path) bool {
+	if !path.IsLeaked() {
+		return false
+	}
+	for _, exportPath := range dest.GetPeerPathMap()[vrfExportSrcPrefix+v.Name] {
+		if exportPath == path {
+			return true
+		}
+	}
+	return false
+}
+
+// importVPNRoutes adds the VPN routes with any of the import RTs of the VRF to the VRF table. Routes that no longer
+// match the import RTs and the routes exported by the VRF itself are removed from the VRF table.
+func (s *BGPServer) importVPNRoutes(vrf *VRF, updated map[uint32]map[*bgprib.Path][]*bgprib.Destination,
+	withdrawn []*bgprib.Destination) {
+	for vpnFamily, pathDestMap := range updated {
+		if !packet.IsVPNFamily(vpnFamily) {
+			continue
+		}
+
+		afi, _ := packet.GetAfiSafi(vpnFamily)
+		protoFamily := packet.GetProtocolFamily(afi, packet.SafiUnicast)
+		for path, destinations := range pathDestMap {
+			for _, dest := range destinations {
+				nlri, ok := dest.NLRI.(*packet.VPNNLRI)
+				if !ok {
+					continue
+				}
+
+				src := vpnImportSrcPrefix + nlri.RD.String()
+				if !vrf.isVrfExportPath(dest, path) && vrf.isImported(path) {
+					add := map[uint32][]packet.NLRI{protoFamily: {nlri.GetIPPrefix()}}
+					s.processLeakedRoutes(vrf.LocRib, src, s.constructVrfImportPath(vrf, path, vpnFamily), add, nil)
+				} else {
+					s.removeLeakedRoute(vrf.LocRib, src, protoFamily, nlri.GetIPPrefix())
+				}
+			}
+		}
+	}
+
+	for _, dest := range withdrawn {
+		nlri, ok := dest.NLRI.(*packet.VPNNLRI)
+		if !ok {
+			continue
+		}
+		afi, _ := packet.GetAfiSafi(dest.GetProtocolFamily())
+		s.removeLeakedRoute(vrf.LocRib, vpnImportSrcPrefix+nlri.RD.String(),
+			packet.GetProtocolFamily(afi, packet.SafiUnicast), nlri.GetIPPrefix())
+	}
+}
+
+func (s *BGPServer) removeLeakedRoute(locRib *bgprib.LocRib, src string, protoFamily uint32, nlri packet.NLRI) {
+	dest, ok := locRib.GetDest(nlri, protoFamily, false)
+	if !ok {
+		return
+	}
+	if _, ok = dest.GetPeerPathMap()[src]; !ok {
+		return
+	}
+
+	path := bgprib.NewPath(locRib, nil, nil, nil, bgprib.RouteTypeEGP)
+	path.SetLeaked()
+	s.processLeakedRoutes(locRib, src, path, nil, map[uint32][]packet.NLRI{protoFamily: {nlri}})
+}
+
+func (s *BGPServer) processLeakedRoutes(locRib *bgprib.LocRib, src string, path *bgprib.Path, add,
+	remove map[uint32][]packet.NLRI) {
+	updated := make(map[uint32]map[*bgprib.Path][]*bgprib.Destination)
+	withdrawn := make([]*bgprib.Destination, 0)
+	updatedAddPaths := make([]*bgprib.Destination, 0)
+
+	for protoFamily, nlris := range remove {
+		updated, withdrawn, updatedAddPaths, _ = locRib.ProcessRoutes(src, nil, nlris, path, path.Clone(),
+			s.AddPathCount, protoFamily, updated, withdrawn, updatedAddPaths)
+	}
+
+	for protoFamily, nlris := range add {
+		if path.IsLocal() {
+			updated, withdrawn, updatedAddPaths, _ = locRib.ProcessRoutes(src, nlris, nil, path, path.Clone(),
+				s.AddPathCount, protoFamily, updated, withdrawn, updatedAddPaths)
+		} else {
+			updated, withdrawn, updatedAddPaths, _ = locRib.TestNHAndProcessRoutes(src, nlris, nil, path,
+				path.Clone(), s.AddPathCount, protoFamily, updated, withdrawn, updatedAddPaths)
+		}
+	}
+
+	if !isUpdateEmpty(updated, withdrawn, updatedAddPaths) {
+		s.SendUpdate(updated, withdrawn, updatedAddPaths)
+	}
+}
+
+// removeVrfRoutes withdraws the routes exported by the VRF and removes the routes imported into the VRF
+func (s *BGPServer) removeVrfRoutes(vrf *VRF) {
+	s.exportVrfRoutes(vrf, nil, s.getVrfDestinations(vrf))
+
+	for protoFamily, destMap := range s.getVrfDestMap(vrf) {
+		for _, dest := range destMap {
+			for src, pathMap := range dest.GetPeerPathMap() {
+				if path, ok := pathMap[0]; ok && path.IsLeaked() {
+					s.removeLeakedRoute(vrf.LocRib, src, protoFamily, dest.NLRI)
+				}
+			}
+		}
+	}
+}
+
+// syncVrfRoutes exports all the VRF routes and imports all the matching VPN routes
+func (s *BGPServer) syncVrfRoutes(vrf *VRF) {
+	s.exportVrfRoutes(vrf, vrf.LocRib.GetLocRib(), nil)
+	s.importVPNRoutes(vrf, s.LocRib.GetLocRib(), nil)
+}
+
+func (s *BGPServer) getVrfDestMap(vrf *VRF) map[uint32]map[string]*bgprib.Destination {
+	destMap := make(map[uint32]map[string]*bgprib.Destination)
+	for _, protoFamily := range packet.ProtocolFamilyMap {
+		if _, safi := packet.GetAfiSafi(protoFamily); safi != packet.SafiUnicast {
+			continue
+		}
+		destMap[protoFamily] = make(map[string]*bgprib.Destination)
+		for key, dest := range vrf.LocRib.GetDestinations(protoFamily) {
+			destMap[protoFamily][key] = dest
+		}
+	}
+	return destMap
+}
+
+func (s *BGPServer) getVrfDestinations(vrf *VRF) []*bgprib.Destination {
+	destinations := make([]*bgprib.Destination, 0)
+	for _, destMap := range s.getVrfDestMap(vrf) {
+		for _, dest := range destMap {
+			destinations = append(destinations, dest)
+		}
+	}
+	return destinations
+}
+
+func (s *BGPServer) AddOrUpdateVrf(oldConf, newConf config.VrfConfig, attrSet []bool) error {
+	s.logger.Info("AddOrUpdateVrf old:", oldConf, "new:", newConf)
+	if vrf, ok := s.vrfs[newConf.Name]; ok {
+		newVrf := &VRF{Name: newConf.Name}
+		if err := newVrf.setConfig(newConf); err != nil {
+			s.logger.Errf("Failed to update VRF %s, error: %s", newConf.Name, err)
+			return err
+		}
+
+		s.removeVrfRoutes(vrf)
+		vrf.RD = newVrf.RD
+		vrf.Label = newVrf.Label
+		vrf.ImportRT = newVrf.ImportRT
+		vrf.ExportRT = newVrf.ExportRT
+		s.syncVrfRoutes(vrf)
+		return nil
+	}
+
+	vrf, err := NewVRF(s, newConf)
+	if err != nil {
+		s.logger.Errf("Failed to create VRF %s, error: %s", newConf.Name, err)
+		return err
+	}
+	s.vrfs[newConf.Name] = vrf
+	s.syncVrfRoutes(vrf)
+	return nil
+}
+
+func (s *BGPServer) DeleteVrf(vrfConf config.VrfConfig) error {
+	vrf, ok := s.vrfs[vrfConf.Name]
+	if !ok {
+		return errors.New(fmt.Sprintf("VRF %s not found", vrfConf.Name))
+	}
+
+	for _, peer := range s.PeerMap {
+		if peer.locRib == vrf.LocRib {
+			s.logger.Errf("Can't delete VRF %s, neighbor %s is in the VRF", vrfConf.Name,
+				peer.NeighborConf.Neighbor.NeighborAddress)
+			return errors.New(fmt.Sprintf("VRF %s has neighbors", vrfConf.Name))
+		}
+	}
+
+	s.removeVrfRoutes(vrf)
+	delete(s.vrfs, vrfConf.Name)
+	return nil
+}
@@ -0,0 +1,75 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+// vrf_test.go
+package server
+
+import (
+	"l3/bgp/baseobjects"
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	bgprib "l3/bgp/rib"
+	"net"
+	"testing"
+	"utils/logging"
+)
+
+func TestVrfExportPath(t *testing.T) {
+	logger, err := logging.NewLogger("bgpd", "BGP", true)
+	if err != nil {
+		t.Fatal("Failed to start the logger. Exiting!!")
+	}
+
+	gConf := &config.GlobalConfig{}
+	gConf.AS = 100
+	gConf.RouterId = net.ParseIP("10.1.10.100")
+	locRib := bgprib.NewLocRib(logger, &testRouteMgr{}, nil, gConf)
+
+	// A remote PE uses the same RD as the VRF
+	rd := packet.NewRouteDistinguisher(packet.RouteDistinguisherTypeAS2, 100, 1)
+	vrf := &VRF{Name: "red", RD: rd, Label: 16}
+	vpnFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiMPLSVPN)
+	nlri := packet.NewVPNNLRI(rd, 16, packet.NewIPPrefix(net.ParseIP("20.1.1.0"), 24))
+	dest, _ := locRib.GetDest(nlri, vpnFamily, true)
+
+	exportPath := bgprib.NewPath(locRib, nil, nil, nil, bgprib.RouteTypeConnected)
+	exportPath.SetLeaked()
+	dest.AddOrUpdatePath(vrfExportSrcPrefix+vrf.Name, 0, exportPath)
+
+	peerConf := config.NeighborConfig{NeighborAddress: net.ParseIP("10.0.1.1")}
+	peerConf.PeerAS = 100
+	remotePath := bgprib.NewPath(locRib, base.NewNeighborConf(logger, gConf, nil, peerConf), nil, nil,
+		bgprib.RouteTypeEGP)
+	dest.AddOrUpdatePath("10.0.1.1", 0, remotePath)
+
+	if !vrf.isVrfExportPath(dest, exportPath) {
+		t.Error("Expected the path exported by VRF", vrf.Name, "to be detected")
+	}
+	if vrf.isVrfExportPath(dest, remotePath) {
+		t.Error("Expected the path from the remote PE with the same RD to be imported")
+	}
+
+	otherVrf := &VRF{Name: "blue", RD: rd, Label: 17}
+	if otherVrf.isVrfExportPath(dest, exportPath) {
+		t.Error("Expected the path exported by VRF", vrf.Name, "to be imported by VRF", otherVrf.Name)
+	}
+}
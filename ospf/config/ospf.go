@@ -134,6 +134,7 @@ const (
 	UnnumberedP2P     IfType = 4
 	PointToMultipoint IfType = 5
 	Stub              IfType = 6
+	VirtualLink       IfType = 7
 )
 
 var IfTypeList = []string{
@@ -143,7 +144,8 @@ var IfTypeList = []string{
 	"NumberedP2P",
 	"UnnumberedP2P",
 	"PointToMultipoint",
-	"Stub"}
+	"Stub",
+	"VirtualLink"}
 
 type MulticastForwarding int
 
@@ -573,8 +575,7 @@ type OspfIPv4Route struct {
 }
 
 type OspfEventState struct {
-	TimeStamp      string
-	EventType      string
-	EventInfo      string
+	TimeStamp string
+	EventType string
+	EventInfo string
 }
-	
@@ -0,0 +1,88 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package ospfdCommonDefs
+
+const (
+	// ospfd publishes the link state topology for bgpd to advertise with BGP-LS
+	PUB_SOCKET_LINK_STATE_ADDR = "ipc:///tmp/ospfd_linkstate.ipc"
+)
+
+const (
+	NOTIFY_LINK_STATE_TOPOLOGY uint16 = iota + 1
+)
+
+type OspfdNotifyMsg struct {
+	MsgType uint16
+	MsgBuf  []byte
+}
+
+// Pseudonodes for the transit networks carry the interface address of the DR in DRIp
+type LinkStateNode struct {
+	RouterId string
+	DRIp     string
+	ABR      bool
+	ASBR     bool
+}
+
+type LinkStateLink struct {
+	LocalRouterId  string
+	LocalDRIp      string
+	RemoteRouterId string
+	RemoteDRIp     string
+	LocalIp        string
+	RemoteIp       string
+	// Unnumbered point to point links are identified by the local ifIndex
+	LocalIfIndex uint32
+	Metric       uint32
+}
+
+const (
+	LINK_STATE_ROUTE_INTRA_AREA uint8 = iota + 1
+	LINK_STATE_ROUTE_INTER_AREA
+	LINK_STATE_ROUTE_EXTERNAL1
+	LINK_STATE_ROUTE_EXTERNAL2
+	LINK_STATE_ROUTE_NSSA1
+	LINK_STATE_ROUTE_NSSA2
+)
+
+type LinkStatePrefix struct {
+	RouterId  string
+	DRIp      string
+	Prefix    string
+	RouteType uint8
+	Metric    uint32
+}
+
+type LinkStateArea struct {
+	AreaId   string
+	Nodes    []LinkStateNode
+	Links    []LinkStateLink
+	Prefixes []LinkStatePrefix
+}
+
+// LinkStateTopology is published as a whole after every SPF run that changed the LSDB
+type LinkStateTopology struct {
+	RouterId string
+	Areas    []LinkStateArea
+}
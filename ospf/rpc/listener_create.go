@@ -106,6 +106,24 @@ func (h *OSPFHandler) SendOspfIfMetricConf(ospfIfMetricConf *ospfd.OspfIfMetricE
 	return nil
 }
 
+func newOspfVirtIfConf(ospfVirtIfConf *ospfd.OspfVirtIfEntry) config.VirtIfConf {
+	return config.VirtIfConf{
+		VirtIfAreaId:          config.AreaId(ospfVirtIfConf.VirtIfAreaId),
+		VirtIfNeighbor:        config.RouterId(ospfVirtIfConf.VirtIfNeighbor),
+		VirtIfTransitDelay:    config.UpToMaxAge(ospfVirtIfConf.VirtIfTransitDelay),
+		VirtIfRetransInterval: config.UpToMaxAge(ospfVirtIfConf.VirtIfRetransInterval),
+		VirtIfHelloInterval:   config.HelloRange(ospfVirtIfConf.VirtIfHelloInterval),
+		VirtIfRtrDeadInterval: config.PositiveInteger(ospfVirtIfConf.VirtIfRtrDeadInterval),
+		VirtIfAuthKey:         ospfVirtIfConf.VirtIfAuthKey,
+		VirtIfAuthType:        config.AuthType(ospfVirtIfConf.VirtIfAuthType),
+	}
+}
+
+func (h *OSPFHandler) SendOspfVirtIfConf(ospfVirtIfConf *ospfd.OspfVirtIfEntry) error {
+	h.server.VirtIfConfigCh <- newOspfVirtIfConf(ospfVirtIfConf)
+	return nil
+}
+
 func (h *OSPFHandler) CreateOspfGlobal(ospfGlobalConf *ospfd.OspfGlobal) (bool, error) {
 	if ospfGlobalConf == nil {
 		err := errors.New("Invalid Global Configuration")
@@ -159,6 +177,14 @@ func (h *OSPFHandler) CreateOspfIfMetricEntry(ospfIfMetricConf *ospfd.OspfIfMetr
 }
 
 func (h *OSPFHandler) CreateOspfVirtIfEntry(ospfVirtIfConf *ospfd.OspfVirtIfEntry) (bool, error) {
+	if ospfVirtIfConf == nil {
+		err := errors.New("Invalid Virtual Interface Configuration")
+		return false, err
+	}
 	h.logger.Info(fmt.Sprintln("Create virtual interface config attrs:", ospfVirtIfConf))
+	err := h.SendOspfVirtIfConf(ospfVirtIfConf)
+	if err != nil {
+		return false, err
+	}
 	return true, nil
 }
@@ -24,6 +24,7 @@
 package rpc
 
 import (
+	"errors"
 	"fmt"
 	"ospfd"
 	//    "l3/ospf/config"
@@ -54,6 +55,11 @@ func (h *OSPFHandler) DeleteOspfIfMetricEntry(ospfIfMetricConf *ospfd.OspfIfMetr
 }
 
 func (h *OSPFHandler) DeleteOspfVirtIfEntry(ospfVirtIfConf *ospfd.OspfVirtIfEntry) (bool, error) {
+	if ospfVirtIfConf == nil {
+		err := errors.New("Invalid Virtual Interface Configuration")
+		return false, err
+	}
 	h.logger.Info(fmt.Sprintln("Delete virtual interface config attrs:", ospfVirtIfConf))
+	h.server.VirtIfDeleteCh <- newOspfVirtIfConf(ospfVirtIfConf)
 	return true, nil
 }
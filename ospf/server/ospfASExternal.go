@@ -47,140 +47,141 @@ func (server *OSPFServer) HandleASExternalLsa(areaId uint32) {
 
 	for lsaKey, lsaEnt := range lsDbEnt.ASExternalLsaMap {
 		server.logger.Info(fmt.Sprintln("AS External LSAKey:", lsaKey, "lsaENt:", lsaEnt))
-		if lsaEnt.Metric == LSInfinity ||
-			lsaEnt.LsaMd.LSAge == config.MaxAge {
-			server.logger.Info("Ignoring AS External LSA...")
-			continue
-		}
-		rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
-		if lsaKey.AdvRouter == rtrId {
-			server.logger.Info("Self originated AS External LSA, so no need to process for routing table calc")
-			continue
-		}
+		server.calcASExternalRoute(areaId, lsaKey, lsaEnt)
+	}
+}
 
-		areaIdKey := AreaIdKey{
-			AreaId: areaId,
-		}
+/*
+RFC 3101 2.5: NSSA LSAs are used for the route calculation
+of the NSSA area the same way as AS external LSAs.
+*/
+func (server *OSPFServer) HandleNSSALsa(areaId uint32) {
+	lsdbKey := LsdbKey{
+		AreaId: areaId,
+	}
+	lsDbEnt, exist := server.AreaLsdb[lsdbKey]
+	if !exist {
+		server.logger.Err(fmt.Sprintln("Unable to find Area Lsdb entry"))
+		return
+	}
 
-		var rKey RoutingTblEntryKey
-		var rEnt RoutingTblEntry
-		var exist bool
-		tempAreaRoutingTbl := server.TempAreaRoutingTbl[areaIdKey]
-		if lsaEnt.FwdAddr == 0 {
-			//Packet should be sent to ASBr
+	for lsaKey, lsaEnt := range lsDbEnt.NSSALsaMap {
+		server.logger.Info(fmt.Sprintln("NSSA LSAKey:", lsaKey, "lsaENt:", lsaEnt))
+		server.calcASExternalRoute(areaId, lsaKey, lsaEnt)
+	}
+}
+
+func (server *OSPFServer) calcASExternalRoute(areaId uint32, lsaKey LsaKey, lsaEnt ASExternalLsa) {
+	if lsaEnt.Metric == LSInfinity ||
+		lsaEnt.LsaMd.LSAge == config.MaxAge {
+		server.logger.Info("Ignoring AS External LSA...")
+		return
+	}
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	if lsaKey.AdvRouter == rtrId {
+		server.logger.Info("Self originated AS External LSA, so no need to process for routing table calc")
+		return
+	}
+
+	areaIdKey := AreaIdKey{
+		AreaId: areaId,
+	}
+
+	var rKey RoutingTblEntryKey
+	var rEnt RoutingTblEntry
+	var exist bool
+	tempAreaRoutingTbl := server.TempAreaRoutingTbl[areaIdKey]
+	if lsaEnt.FwdAddr == 0 {
+		//Packet should be sent to ASBr
+		rKey = RoutingTblEntryKey{
+			DestId:   lsaKey.AdvRouter,
+			AddrMask: 0,
+			DestType: ASBdrRouter,
+		}
+		rEnt, exist = tempAreaRoutingTbl.RoutingTblMap[rKey]
+		if !exist {
+			server.logger.Info("AS Border Router routing table entry doesnot exists for AS External Lsa Advertising Router")
 			rKey = RoutingTblEntryKey{
 				DestId:   lsaKey.AdvRouter,
 				AddrMask: 0,
-				DestType: ASBdrRouter,
+				DestType: ASAreaBdrRouter,
 			}
 			rEnt, exist = tempAreaRoutingTbl.RoutingTblMap[rKey]
 			if !exist {
-				server.logger.Info("AS Border Router routing table entry doesnot exists for AS External Lsa Advertising Router")
-				rKey = RoutingTblEntryKey{
-					DestId:   lsaKey.AdvRouter,
-					AddrMask: 0,
-					DestType: ASAreaBdrRouter,
-				}
-				rEnt, exist = tempAreaRoutingTbl.RoutingTblMap[rKey]
-				if !exist {
-					server.logger.Info("AS Area Border Router routing table entry doesnot exists for AS External Lsa Advertising Router")
-					continue
-				}
+				server.logger.Info("AS Area Border Router routing table entry doesnot exists for AS External Lsa Advertising Router")
+				return
 			}
-		} else {
-			// Packet should be sent to forwarding address
+		}
+	} else {
+		// Packet should be sent to forwarding address
+		rKey = RoutingTblEntryKey{
+			DestId:   lsaEnt.FwdAddr,
+			AddrMask: 0,
+			DestType: ASBdrRouter,
+		}
+		rEnt, exist = tempAreaRoutingTbl.RoutingTblMap[rKey]
+		if !exist {
+			server.logger.Info("AS Border Router routing table entry doesnot exists for AS External Lsa Advertising Router")
 			rKey = RoutingTblEntryKey{
 				DestId:   lsaEnt.FwdAddr,
 				AddrMask: 0,
-				DestType: ASBdrRouter,
+				DestType: ASAreaBdrRouter,
 			}
 			rEnt, exist = tempAreaRoutingTbl.RoutingTblMap[rKey]
 			if !exist {
-				server.logger.Info("AS Border Router routing table entry doesnot exists for AS External Lsa Advertising Router")
-				rKey = RoutingTblEntryKey{
-					DestId:   lsaEnt.FwdAddr,
-					AddrMask: 0,
-					DestType: ASAreaBdrRouter,
-				}
-				rEnt, exist = tempAreaRoutingTbl.RoutingTblMap[rKey]
-				if !exist {
-					server.logger.Info("AS Area Border Router routing table entry doesnot exists for AS External Lsa Advertising Router")
-					continue
-				}
+				// Forwarding address is reachable through a network route
+				rEnt, exist = findFwdAddrRoute(tempAreaRoutingTbl, lsaEnt.FwdAddr)
+			}
+			if !exist {
+				server.logger.Info("AS Area Border Router routing table entry doesnot exists for AS External Lsa Advertising Router")
+				return
 			}
 		}
-		if rEnt.NumOfPaths == 0 {
-			continue
-		}
+	}
+	if rEnt.NumOfPaths == 0 {
+		return
+	}
 
-		cost := rEnt.Cost + uint16(lsaEnt.Metric)
-		nextHopMap := rEnt.NextHops
-		numOfNextHops := rEnt.NumOfPaths
-		rKey = RoutingTblEntryKey{
-			DestId:   lsaKey.LSId & lsaEnt.Netmask,
-			AddrMask: lsaEnt.Netmask,
-			DestType: Network, // TODO: Need to be revisited
-		}
+	cost := rEnt.Cost + uint16(lsaEnt.Metric)
+	nextHopMap := rEnt.NextHops
+	numOfNextHops := rEnt.NumOfPaths
+	rKey = RoutingTblEntryKey{
+		DestId:   lsaKey.LSId & lsaEnt.Netmask,
+		AddrMask: lsaEnt.Netmask,
+		DestType: Network, // TODO: Need to be revisited
+	}
 
-		tempAreaRoutingTbl = server.TempAreaRoutingTbl[areaIdKey]
-		rEnt, exist = tempAreaRoutingTbl.RoutingTblMap[rKey]
-		if exist {
-			if rEnt.PathType == IntraArea ||
-				rEnt.PathType == InterArea {
-				//IntraArea or InterArea Paths are always preferred
-				continue
-			}
-			if rEnt.PathType == Type1Ext &&
-				lsaEnt.BitE == true {
-				//Type1Ext path is always preferred over Type2Ext
-				continue
-			}
-			var pathType PathType
-			if lsaEnt.BitE == true {
-				pathType = Type2Ext
-			} else {
-				pathType = Type1Ext
-			}
-			if rEnt.Cost < cost &&
-				rEnt.PathType == pathType {
-				//Routing table entry cost is less and path type is same
-				server.logger.Info("Route already exists with lesser cost")
-				continue
-			} else if (rEnt.Cost > cost &&
-				rEnt.PathType == pathType) ||
-				(rEnt.Cost < cost &&
-					rEnt.PathType == Type2Ext) {
-				rEnt.OptCapabilities = 0 //TODO
-				//rEnt.PathType = InterArea
-				rEnt.PathType = pathType
-				rEnt.Cost = cost
-				rEnt.Type2Cost = uint16(lsaEnt.Metric)
-				//rEnt.LSOrigin = lsaKey
-				rEnt.NumOfPaths = numOfNextHops
-				rEnt.NextHops = make(map[NextHop]bool)
-				for key, _ := range nextHopMap {
-					key.AdvRtr = lsaKey.AdvRouter
-					rEnt.NextHops[key] = true
-				}
-			} else {
-				cnt := 0
-				for key, _ := range nextHopMap {
-					_, exist = rEnt.NextHops[key]
-					if !exist {
-						key.AdvRtr = lsaKey.AdvRouter
-						rEnt.NextHops[key] = true
-						cnt++
-					}
-				}
-				rEnt.NumOfPaths = numOfNextHops + cnt
-			}
+	tempAreaRoutingTbl = server.TempAreaRoutingTbl[areaIdKey]
+	rEnt, exist = tempAreaRoutingTbl.RoutingTblMap[rKey]
+	if exist {
+		if rEnt.PathType == IntraArea ||
+			rEnt.PathType == InterArea {
+			//IntraArea or InterArea Paths are always preferred
+			return
+		}
+		if rEnt.PathType == Type1Ext &&
+			lsaEnt.BitE == true {
+			//Type1Ext path is always preferred over Type2Ext
+			return
+		}
+		var pathType PathType
+		if lsaEnt.BitE == true {
+			pathType = Type2Ext
 		} else {
+			pathType = Type1Ext
+		}
+		if rEnt.Cost < cost &&
+			rEnt.PathType == pathType {
+			//Routing table entry cost is less and path type is same
+			server.logger.Info("Route already exists with lesser cost")
+			return
+		} else if (rEnt.Cost > cost &&
+			rEnt.PathType == pathType) ||
+			(rEnt.Cost < cost &&
+				rEnt.PathType == Type2Ext) {
 			rEnt.OptCapabilities = 0 //TODO
-			if lsaEnt.BitE == true {
-				rEnt.PathType = Type2Ext
-			} else {
-				rEnt.PathType = Type1Ext
-			}
+			//rEnt.PathType = InterArea
+			rEnt.PathType = pathType
 			rEnt.Cost = cost
 			rEnt.Type2Cost = uint16(lsaEnt.Metric)
 			//rEnt.LSOrigin = lsaKey
@@ -190,10 +191,56 @@ func (server *OSPFServer) HandleASExternalLsa(areaId uint32) {
 				key.AdvRtr = lsaKey.AdvRouter
 				rEnt.NextHops[key] = true
 			}
+		} else {
+			cnt := 0
+			for key, _ := range nextHopMap {
+				_, exist = rEnt.NextHops[key]
+				if !exist {
+					key.AdvRtr = lsaKey.AdvRouter
+					rEnt.NextHops[key] = true
+					cnt++
+				}
+			}
+			rEnt.NumOfPaths = numOfNextHops + cnt
+		}
+	} else {
+		rEnt.OptCapabilities = 0 //TODO
+		if lsaEnt.BitE == true {
+			rEnt.PathType = Type2Ext
+		} else {
+			rEnt.PathType = Type1Ext
+		}
+		rEnt.Cost = cost
+		rEnt.Type2Cost = uint16(lsaEnt.Metric)
+		//rEnt.LSOrigin = lsaKey
+		rEnt.NumOfPaths = numOfNextHops
+		rEnt.NextHops = make(map[NextHop]bool)
+		for key, _ := range nextHopMap {
+			key.AdvRtr = lsaKey.AdvRouter
+			rEnt.NextHops[key] = true
+		}
+	}
+	tempAreaRoutingTbl.RoutingTblMap[rKey] = rEnt
+	server.TempAreaRoutingTbl[areaIdKey] = tempAreaRoutingTbl
+}
+
+/* Longest match network entry for the forwarding address */
+func findFwdAddrRoute(tbl AreaRoutingTbl, fwdAddr uint32) (RoutingTblEntry, bool) {
+	var rEnt RoutingTblEntry
+	var mask uint32
+	found := false
+	for key, ent := range tbl.RoutingTblMap {
+		if key.DestType != Network ||
+			fwdAddr&key.AddrMask != key.DestId {
+			continue
+		}
+		if !found || key.AddrMask > mask {
+			rEnt = ent
+			mask = key.AddrMask
+			found = true
 		}
-		tempAreaRoutingTbl.RoutingTblMap[rKey] = rEnt
-		server.TempAreaRoutingTbl[areaIdKey] = tempAreaRoutingTbl
 	}
+	return rEnt, found
 }
 
 func (server *OSPFServer) CalcASBorderRoutes(areaId uint32) {
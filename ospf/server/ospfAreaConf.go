@@ -40,7 +40,6 @@ type AreaConf struct {
 	AreaSummary            config.AreaSummary
 	StubDefaultCost        int32
 	AreaNssaTranslatorRole config.NssaTranslatorRole
-	TransitCapability      bool
 	IntfListMap            map[IntfConfKey]bool
 }
 
@@ -221,3 +220,19 @@ func (server *OSPFServer) isStubArea(areaid config.AreaId) bool {
 	}
 	return false
 }
+
+func (server *OSPFServer) isNssaArea(areaid config.AreaId) bool {
+
+	areaConfKey := AreaConfKey{
+		AreaId: areaid,
+	}
+
+	conf, exist := server.AreaConfMap[areaConfKey]
+	if !exist {
+		return false
+	}
+	if conf.ImportAsExtern == config.ImportNssa {
+		return true
+	}
+	return false
+}
@@ -30,7 +30,7 @@ package server
 
 import (
 	"fmt"
-	// "l3/ospf/config"
+	"l3/ospf/config"
 	"testing"
 )
 
@@ -52,7 +52,7 @@ func TestOspfArea(t *testing.T) {
 	for index := 1; index < 21; index++ {
 		err := areaTestLogic(index)
 		if err != SUCCESS {
-			fmt.Println("Failed test case for Hello protocol ")
+			t.Error("Failed area conf test case ", index)
 		}
 	}
 
@@ -120,6 +120,30 @@ func areaTestLogic(tNum int) int {
 		conf := ospf.GetOspfGlobalState()
 		fmt.Println("Global conf ", conf)
 		checkAsicdAPIs()
+
+	case 11:
+		fmt.Println(tNum, ": Running isNssaArea ")
+		if err := ospf.processAreaConfig(areaConf); err != nil {
+			fmt.Println("Failed to configure area ", err)
+			return FAIL
+		}
+		if ospf.isNssaArea(areaConfKey.AreaId) {
+			fmt.Println("Area importing external routes is a NSSA area")
+			return FAIL
+		}
+		nssaConf := areaConf
+		nssaConf.ImportAsExtern = config.ImportNssa
+		if err := ospf.processAreaConfig(nssaConf); err != nil {
+			fmt.Println("Failed to configure NSSA area ", err)
+			return FAIL
+		}
+		nssa := ospf.isNssaArea(areaConfKey.AreaId)
+		stub := ospf.isStubArea(areaConfKey.AreaId)
+		ospf.processAreaConfig(areaConf)
+		if !nssa || stub {
+			fmt.Println("Wrong area type for NSSA area, nssa ", nssa, " stub ", stub)
+			return FAIL
+		}
 	}
 
 	return SUCCESS
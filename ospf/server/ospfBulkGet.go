@@ -144,6 +144,13 @@ func (server *OSPFServer) GetBulkOspfLsdbEntryState(idx int, cnt int) (int, int,
 			}
 			lsaEnc = encodeASExternalLsa(lsa, lsaKey)
 			lsaMd = lsa.LsaMd
+		} else if lsdbSliceEnt.LSType == NSSALSA {
+			lsa, exist := lsDbEnt.NSSALsaMap[lsaKey]
+			if !exist {
+				continue
+			}
+			lsaEnc = encodeASExternalLsa(lsa, lsaKey)
+			lsaMd = lsa.LsaMd
 		}
 
 		server.logger.Info(fmt.Sprintln(lsaEnc))
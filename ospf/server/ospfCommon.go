@@ -97,6 +97,7 @@ const (
 	OSPF_PROTO_ID        = 89
 	OSPF_VERSION_2       = 2
 	OSPF_NO_OF_LSA_FIELD = 4
+	VIRTUAL_LINK_TTL     = 64
 )
 
 type OspfType uint8
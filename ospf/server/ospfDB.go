@@ -387,6 +387,13 @@ func (server *OSPFServer) AddLsdbEntry(entry LsdbSliceEnt) error {
 		}
 		lsaEnc = encodeASExternalLsa(lsa, lsaKey)
 		lsaMd = lsa.LsaMd
+	} else if entry.LSType == NSSALSA {
+		lsa, exist := lsDbEnt.NSSALsaMap[lsaKey]
+		if !exist {
+			return nil
+		}
+		lsaEnc = encodeASExternalLsa(lsa, lsaKey)
+		lsaMd = lsa.LsaMd
 	}
 	adv := convertByteToOctetString(lsaEnc[OSPF_LSA_HEADER_SIZE:])
 
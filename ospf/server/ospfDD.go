@@ -303,13 +303,14 @@ func (server *OSPFServer) BuildDBDPkt(intfKey IntfConfKey, ent IntfConf,
 		DstIP = nbrConf.OspfNbrIPAddr
 		DstMAC = dstMAC
 	}
+	DstMAC, DstIP, ttl := ospfPktDst(ent, DstMAC, DstIP)
 
 	ipLayer := layers.IPv4{
 		Version:  uint8(4),
 		IHL:      uint8(IP_HEADER_MIN_LEN),
 		TOS:      uint8(0xc0),
 		Length:   uint16(ipPktlen),
-		TTL:      uint8(1),
+		TTL:      ttl,
 		Protocol: layers.IPProtocol(OSPF_PROTO_ID),
 		SrcIP:    SrcIP,
 		DstIP:    DstIP,
@@ -126,6 +126,23 @@ func (server *OSPFServer) SendSelfOrigLSA(areaId uint32, intfKey IntfConfKey) []
 				ospfLsaPkt.no_lsas++
 				total_len += pktLen
 
+			case NSSALSA:
+				entry, ret := server.getNSSALsaFromLsdb(areaId, key)
+				if ret == LsdbEntryNotFound {
+					continue
+				}
+				LsaEnc = encodeASExternalLsa(entry, key)
+				checksumOffset := uint16(14)
+				checkSum := computeFletcherChecksum(LsaEnc[2:], checksumOffset)
+				binary.BigEndian.PutUint16(LsaEnc[16:18], checkSum)
+				pktLen = len(LsaEnc)
+				binary.BigEndian.PutUint16(LsaEnc[18:20], uint16(pktLen))
+				lsaid := convertUint32ToIPv4(key.LSId)
+				server.logger.Info(fmt.Sprintln("Flood: NSSA  LSA = ", lsaid))
+				ospfLsaPkt.lsa = append(ospfLsaPkt.lsa, LsaEnc...)
+				ospfLsaPkt.no_lsas++
+				total_len += pktLen
+
 			} // end of case
 		}
 	}
@@ -262,6 +279,10 @@ func (server *OSPFServer) processFloodMsg(lsa_data ospfFloodMsg) {
 		server.logger.Info(fmt.Sprintln("LSAEXTFLOOD: Flood external routes for lsa key ", lsa_data.lsaKey))
 		server.processAsExternalLSAFlood(lsa_data.lsaKey)
 
+	case LSANSSAFLOOD: //flood NSSA LSA
+		server.logger.Info(fmt.Sprintln("LSANSSAFLOOD: Flood NSSA route for lsa key ", lsa_data.lsaKey, " area ", lsa_data.areaId))
+		server.processNSSALSAFlood(lsa_data.areaId, lsa_data.lsaKey)
+
 	case LSAAGE: // Flood aged LSAs
 		server.constructAndSendLsaAgeFlood()
 
@@ -368,6 +389,10 @@ func (server *OSPFServer) nbrFloodCheck(nbrKey NeighborConfKey, key IntfConfKey,
 	/* Check neighbor state */
 	flood_check := true
 	nbrConf := server.NeighborConfigMap[nbrKey]
+	/* RFC 2328 13.3: AS external LSAs are not flooded over virtual links */
+	if intf.IfType == config.VirtualLink && lsType == ASExternalLSA {
+		return false
+	}
 	//rtrid := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
 	if nbrConf.intfConfKey == key && nbrConf.isDRBDR && lsType != Summary3LSA && lsType != Summary4LSA {
 		server.logger.Info(fmt.Sprintln("IF FLOOD: Nbr is DR/BDR.   flood on this interface . nbr - ", nbrKey.IPAddr, nbrConf.OspfNbrIPAddr))
@@ -476,6 +501,9 @@ func (server *OSPFServer) floodSummaryLsa(pkt []byte, areaid uint32) {
 func (server *OSPFServer) processAsExternalLSAFlood(lsakey LsaKey) {
 	areaId := convertAreaOrRouterIdUint32("0.0.0.0")
 	for ent, _ := range server.AreaConfMap {
+		if server.isStubArea(ent.AreaId) || server.isNssaArea(ent.AreaId) {
+			continue // AS external LSA is not present in stub and NSSA areas
+		}
 		areaId = convertAreaOrRouterIdUint32(string(ent.AreaId))
 	}
 	var lsaEncPkt []byte
@@ -520,6 +548,13 @@ func (server *OSPFServer) floodASExternalLsa(pkt []byte) {
 			server.logger.Info(fmt.Sprintln("ASBR: Dont flood AS external as area is stub ", areaId))
 			continue
 		}
+		if server.isNssaArea(areaId) {
+			server.logger.Info(fmt.Sprintln("ASBR: Dont flood AS external as area is NSSA ", areaId))
+			continue
+		}
+		if intf.IfType == config.VirtualLink {
+			continue
+		}
 		nbrMdata, ok := ospfIntfToNbrMap[key]
 		if ok && len(nbrMdata.nbrList) > 0 {
 			send_pkt := server.BuildLsaUpdPkt(key, intf, dstMac, dstIp, len(pkt), pkt)
@@ -528,3 +563,53 @@ func (server *OSPFServer) floodASExternalLsa(pkt []byte) {
 		}
 	}
 }
+
+/*
+@fn processNSSALSAFlood
+	This API takes care of flooding NSSA LSA (Type 7).
+	NSSA LSAs are flooded only in the NSSA area.
+*/
+func (server *OSPFServer) processNSSALSAFlood(areaId uint32, lsakey LsaKey) {
+	var lsaEncPkt []byte
+	LsaEnc := []byte{}
+
+	entry, ret := server.getNSSALsaFromLsdb(areaId, lsakey)
+	if ret == LsdbEntryNotFound {
+		server.logger.Info(fmt.Sprintln("NSSA: Lsa not found . Area",
+			areaId, " LSA key ", lsakey))
+		return
+	}
+	LsaEnc = encodeASExternalLsa(entry, lsakey)
+	pktLen := len(LsaEnc)
+	checksumOffset := uint16(14)
+	checkSum := computeFletcherChecksum(LsaEnc[2:], checksumOffset)
+	binary.BigEndian.PutUint16(LsaEnc[16:18], checkSum)
+	binary.BigEndian.PutUint16(LsaEnc[18:20], uint16(pktLen))
+
+	no_lsas := uint32(1)
+	lsas_enc := make([]byte, 4)
+	binary.BigEndian.PutUint32(lsas_enc, no_lsas)
+	lsaEncPkt = append(lsaEncPkt, lsas_enc...)
+	lsaEncPkt = append(lsaEncPkt, LsaEnc...)
+	lsid := convertUint32ToIPv4(lsakey.LSId)
+	adv_router := convertUint32ToIPv4(lsakey.AdvRouter)
+	server.logger.Info(fmt.Sprintln("NSSA: flood lsid ", lsid, " adv_router ", adv_router, " area ", areaId))
+	server.floodNSSALsa(lsaEncPkt, areaId)
+}
+
+func (server *OSPFServer) floodNSSALsa(pkt []byte, areaid uint32) {
+	dstMac := net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x05}
+	dstIp := net.IP{224, 0, 0, 5}
+	for key, intf := range server.IntfConfMap {
+		ifArea := convertIPv4ToUint32(intf.IfAreaId)
+		if ifArea != areaid {
+			continue
+		}
+		nbrMdata, ok := ospfIntfToNbrMap[key]
+		if ok && len(nbrMdata.nbrList) > 0 {
+			send_pkt := server.BuildLsaUpdPkt(key, intf, dstMac, dstIp, len(pkt), pkt)
+			server.logger.Info(fmt.Sprintln("NSSA: Send  LSA to interface ", intf.IfIpAddr, " area ", intf.IfAreaId))
+			server.SendOspfPkt(key, send_pkt)
+		}
+	}
+}
@@ -107,6 +107,10 @@ func (server *OSPFServer) BuildHelloPkt(ent IntfConf) []byte {
 	if isStub {
 		option = uint8(0)
 	}
+	/* RFC 3101 2.1: NSSA interfaces clear the E bit and set the N bit */
+	if server.isNssaArea(areaId) {
+		option = uint8(NPOption)
+	}
 	helloData := OSPFHelloData{
 		netmask:             ent.IfNetmask,
 		helloInterval:       ent.IfHelloInterval,
@@ -149,20 +153,22 @@ func (server *OSPFServer) BuildHelloPkt(ent IntfConf) []byte {
 	copy(ospf[16:24], ent.IfAuthKey)
 
 	ipPktlen := IP_HEADER_MIN_LEN + ospfHdr.pktlen
+	dstMAC, dstIp, ttl := ospfPktDst(ent, net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x05},
+		net.IP{224, 0, 0, 5})
 	ipLayer := layers.IPv4{
 		Version:  uint8(4),
 		IHL:      uint8(IP_HEADER_MIN_LEN),
 		TOS:      uint8(0xc0),
 		Length:   uint16(ipPktlen),
-		TTL:      uint8(1),
+		TTL:      ttl,
 		Protocol: layers.IPProtocol(OSPF_PROTO_ID),
 		SrcIP:    ent.IfIpAddr,
-		DstIP:    net.IP{224, 0, 0, 5},
+		DstIP:    dstIp,
 	}
 
 	ethLayer := layers.Ethernet{
 		SrcMAC:       ent.IfMacAddr,
-		DstMAC:       net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x05},
+		DstMAC:       dstMAC,
 		EthernetType: layers.EthernetTypeIPv4,
 	}
 
@@ -188,8 +194,10 @@ func (server *OSPFServer) processRxHelloPkt(data []byte, ospfHdrMd *OspfHdrMetad
 	}
 	decodeOspfHelloData(data, ospfHelloData)
 
-	//  Todo: Sec 10.5 RFC2328 Need to add check for Virtual links
-	if ent.IfType != config.NumberedP2P || ent.IfType != config.UnnumberedP2P {
+	/* RFC 2328 10.5: Network mask is not checked on point-to-point
+	   networks and virtual links */
+	if ent.IfType != config.NumberedP2P && ent.IfType != config.UnnumberedP2P &&
+		ent.IfType != config.VirtualLink {
 		if bytesEqual(ent.IfNetmask, ospfHelloData.netmask) == false {
 			server.logger.Debug(fmt.Sprintln("HELLO: Netmask mismatch. Int mask", ent.IfNetmask, " Hello mask ", ospfHelloData.netmask, " ip ", ipHdrMd.srcIP))
 			err := errors.New("Netmask mismatch")
@@ -349,10 +357,11 @@ func (server *OSPFServer) CreateAndSendHelloRecvdMsg(routerId uint32,
 	if ifType == config.Broadcast ||
 		ifType == config.Nbma ||
 		ifType == config.PointToMultipoint ||
-		ifType == config.NumberedP2P {
+		ifType == config.NumberedP2P ||
+		ifType == config.VirtualLink {
 		msg.NeighborIP = net.IPv4(ipHdrMd.srcIP[0], ipHdrMd.srcIP[1], ipHdrMd.srcIP[2], ipHdrMd.srcIP[3])
 		//copy(msg.NeighborIP, ipHdrMd.srcIP)
-	} else { //Check for Virtual Links and unnumbered p2p
+	} else { //Check for unnumbered p2p
 		msg.NeighborIP = net.IPv4(ospfHdrMd.routerId[0], ospfHdrMd.routerId[1], ospfHdrMd.routerId[2], ospfHdrMd.routerId[3])
 		//copy(msg.NeighborIP, ospfHdrMd.routerId)
 	}
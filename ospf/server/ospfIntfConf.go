@@ -120,6 +120,9 @@ type IntfConf struct {
 	IfMtu          int32
 	IfCost         uint32
 	IfMetricTOSMap map[uint8]uint32 // Key: TOS Value, Value: TOS Metric
+	/* Virtual link only */
+	IfVirtNbrIpAddr  net.IP
+	IfVirtNextHopMac net.HardwareAddr
 }
 
 func (server *OSPFServer) initDefaultIntfConf(key IntfConfKey, ipIntfProp IPIntfProperty, ifType int) {
@@ -424,6 +427,11 @@ func (server *OSPFServer) processIntfConfig(ifConf config.InterfaceConf) error {
 		err := errors.New("No such L3 interface exists")
 		return err
 	}
+	if ifConf.IfType == config.VirtualLink || ent.IfType == config.VirtualLink {
+		server.logger.Err("Virtual links are configured through the virtual interface config")
+		err := errors.New("Invalid Configuration")
+		return err
+	}
 	if intfConfKey.IPAddr == "0.0.0.0" &&
 		ifConf.IfType == config.NumberedP2P || ifConf.IfType == config.UnnumberedP2P {
 		flag := false
@@ -461,9 +469,13 @@ func (server *OSPFServer) processIntfConfig(ifConf config.InterfaceConf) error {
 func (server *OSPFServer) StopSendRecvPkts(intfConfKey IntfConfKey) {
 	server.logger.Info("Stop Sending Hello Pkt")
 	server.StopOspfIntfFSM(intfConfKey)
-	server.logger.Info("Stop Receiving Hello Pkt")
-	server.StopOspfRecvPkts(intfConfKey)
 	ent, _ := server.IntfConfMap[intfConfKey]
+	/* Virtual link packets are received on the transit interface */
+	if ent.IfType != config.VirtualLink {
+		server.logger.Info("Stop Receiving Hello Pkt")
+		server.StopOspfRecvPkts(intfConfKey)
+	}
+	ent, _ = server.IntfConfMap[intfConfKey]
 	ent.NeighborMap = nil
 	ent.IfEvents = ent.IfEvents + 1
 	ent.IfFSMState = config.Down
@@ -483,12 +495,16 @@ func (server *OSPFServer) StartSendRecvPkts(intfConfKey IntfConfKey) {
 	ent.IfEvents = ent.IfEvents + 1
 	if ent.IfType == config.Broadcast {
 		ent.IfFSMState = config.Waiting
-	} else if ent.IfType == config.NumberedP2P || ent.IfType == config.UnnumberedP2P {
+	} else if ent.IfType == config.NumberedP2P || ent.IfType == config.UnnumberedP2P ||
+		ent.IfType == config.VirtualLink {
 		ent.IfFSMState = config.P2P
 	}
 	server.IntfConfMap[intfConfKey] = ent
 	server.logger.Info("Start Sending Hello Pkt")
 	go server.StartOspfIntfFSM(intfConfKey)
+	if ent.IfType == config.VirtualLink {
+		return
+	}
 	server.logger.Info("Start Receiving Hello Pkt")
 	go server.StartOspfRecvPkts(intfConfKey)
 }
@@ -200,12 +200,13 @@ func (server *OSPFServer) EncodeLSAReqPkt(intfKey IntfConfKey, ent IntfConf,
 	} else {
 		dstIp = nbrConf.OspfNbrIPAddr
 	}
+	dstMAC, dstIp, ttl := ospfPktDst(ent, dstMAC, dstIp)
 	ipLayer := layers.IPv4{
 		Version:  uint8(4),
 		IHL:      uint8(IP_HEADER_MIN_LEN),
 		TOS:      uint8(0xc0),
 		Length:   uint16(ipPktlen),
-		TTL:      uint8(1),
+		TTL:      ttl,
 		Protocol: layers.IPProtocol(OSPF_PROTO_ID),
 		SrcIP:    ent.IfIpAddr,
 		DstIP:    dstIp,
@@ -348,6 +349,7 @@ func (server *OSPFServer) BuildLsaUpdPkt(intfKey IntfConfKey, ent IntfConf,
 		dstIp = net.ParseIP(config.AllSPFRouters)
 		dstMAC, _ = net.ParseMAC(config.McastMAC)
 	}
+	dstMAC, dstIp, ttl := ospfPktDst(ent, dstMAC, dstIp)
 
 	ipPktlen := IP_HEADER_MIN_LEN + ospfHdr.pktlen
 	ipLayer := layers.IPv4{
@@ -355,7 +357,7 @@ func (server *OSPFServer) BuildLsaUpdPkt(intfKey IntfConfKey, ent IntfConf,
 		IHL:      uint8(IP_HEADER_MIN_LEN),
 		TOS:      uint8(0xc0),
 		Length:   uint16(ipPktlen),
-		TTL:      uint8(1),
+		TTL:      ttl,
 		Protocol: layers.IPProtocol(OSPF_PROTO_ID),
 		SrcIP:    ent.IfIpAddr,
 		DstIP:    dstIp,
@@ -451,6 +453,12 @@ func (server *OSPFServer) DecodeLSAUpd(msg ospfNeighborLSAUpdMsg) {
 			" LSTYPE ", lsa_header.LSType,
 			" len ", lsa_header.length))
 		end_index = int(lsa_header.length) + index /* length includes data + header */
+		if !server.lsaAreaCheck(lsa_header.LSType, intf.IfAreaId) {
+			server.logger.Info(fmt.Sprintln("LSAUPD: Discard. LSA type not allowed in area lstype ",
+				lsa_header.LSType, " area ", intf.IfAreaId))
+			index = end_index
+			continue
+		}
 		if lsa_header.LSAge == LSA_MAX_AGE {
 			lsa_max_age = true
 		}
@@ -495,6 +503,12 @@ func (server *OSPFServer) DecodeLSAUpd(msg ospfNeighborLSAUpdMsg) {
 			dalsa, ret := server.getASExternalLsaFromLsdb(msg.areaId, *lsa_key)
 			discard, op = server.sanityCheckASExternalLsa(*alsa, dalsa, nbr, intf, intf.IfAreaId, ret, lsa_max_age)
 
+		case NSSALSA:
+			nlsa := NewASExternalLsa()
+			decodeASExternalLsa(lsdb_msg.Data, nlsa, lsa_key)
+			dnlsa, ret := server.getNSSALsaFromLsdb(msg.areaId, *lsa_key)
+			discard, op = server.sanityCheckNSSALsa(*nlsa, dnlsa, nbr, intf, intf.IfAreaId, ret, lsa_max_age)
+
 		}
 		lsid := convertUint32ToIPv4(lsa_header.LinkId)
 		router_id := convertUint32ToIPv4(lsa_header.Adv_router)
@@ -665,7 +679,10 @@ func (server *OSPFServer) sanityCheckSummaryLsa(slsa SummaryLsa, dslsa SummaryLs
 func (server *OSPFServer) sanityCheckASExternalLsa(alsa ASExternalLsa, dalsa ASExternalLsa, nbr OspfNeighborEntry, intf IntfConf, areaid []byte, exist int, lsa_max_age bool) (discard bool, op uint8) {
 	discard = false
 	op = LsdbAdd
-	// TODO Reject this lsa if area is configured as stub area.
+	if !server.lsaAreaCheck(ASExternalLSA, areaid) {
+		server.logger.Info(fmt.Sprintln("LSAUPD: As external LSA Discard. Area is stub/NSSA ", areaid))
+		return true, LsdbNoAction
+	}
 	send_ack := server.lsAgeCheck(nbr.intfConfKey, lsa_max_age, exist)
 	if send_ack {
 		op = LsdbNoAction
@@ -685,6 +702,47 @@ func (server *OSPFServer) sanityCheckASExternalLsa(alsa ASExternalLsa, dalsa ASE
 	return discard, op
 }
 
+func (server *OSPFServer) sanityCheckNSSALsa(nlsa ASExternalLsa, dnlsa ASExternalLsa, nbr OspfNeighborEntry, intf IntfConf, areaid []byte, exist int, lsa_max_age bool) (discard bool, op uint8) {
+	discard = false
+	op = LsdbAdd
+	if !server.lsaAreaCheck(NSSALSA, areaid) {
+		server.logger.Info(fmt.Sprintln("LSAUPD: NSSA LSA Discard. Area is not NSSA ", areaid))
+		return true, LsdbNoAction
+	}
+	send_ack := server.lsAgeCheck(nbr.intfConfKey, lsa_max_age, exist)
+	if send_ack {
+		op = LsdbNoAction
+		discard = true
+		server.logger.Info(fmt.Sprintln("LSAUPD: NSSA LSA Discard.", " nbr ", nbr))
+		return discard, op
+	} else {
+		isNew := server.validateLsaIsNew(nlsa.LsaMd, dnlsa.LsaMd)
+		if isNew {
+			op = FloodLsa
+			discard = false
+		} else {
+			discard = true
+			op = LsdbNoAction
+		}
+	}
+	return discard, op
+}
+
+/*@fn lsaAreaCheck
+AS external LSAs are not allowed in stub and NSSA areas.
+NSSA LSAs are allowed only in NSSA areas.
+*/
+func (server *OSPFServer) lsaAreaCheck(lsType uint8, areaid []byte) bool {
+	areaId := config.AreaId(convertIPInByteToString(areaid))
+	switch lsType {
+	case ASExternalLSA:
+		return !server.isStubArea(areaId) && !server.isNssaArea(areaId)
+	case NSSALSA:
+		return server.isNssaArea(areaId)
+	}
+	return true
+}
+
 func validateChecksum(data []byte) bool {
 
 	csum := computeFletcherChecksum(data[2:], FLETCHER_CHECKSUM_VALIDATE)
@@ -779,12 +837,13 @@ func (server *OSPFServer) BuildLSAAckPkt(intfKey IntfConfKey, ent IntfConf,
 		dstIp = net.ParseIP(config.AllSPFRouters)
 		dstMAC, _ = net.ParseMAC(config.McastMAC)
 	}
+	dstMAC, dstIp, ttl := ospfPktDst(ent, dstMAC, dstIp)
 	ipLayer := layers.IPv4{
 		Version:  uint8(4),
 		IHL:      uint8(IP_HEADER_MIN_LEN),
 		TOS:      uint8(0xc0),
 		Length:   uint16(ipPktlen),
-		TTL:      uint8(1),
+		TTL:      ttl,
 		Protocol: layers.IPProtocol(OSPF_PROTO_ID),
 		SrcIP:    ent.IfIpAddr,
 		DstIP:    dstIp,
@@ -999,6 +1058,15 @@ func (server *OSPFServer) generateLsaUpdUnicast(req ospfLSAReq, nbrKey NeighborC
 			server.logger.Info(fmt.Sprintln("LSAREQ: AS external lsa not fount. lsaid ",
 				req.link_state_id, " lstype ", lsa_key.LSType, " adv_router ", lsa_key.AdvRouter, " areaid ", areaid))
 		}
+	case NSSALSA:
+		dnlsa, ret := server.getNSSALsaFromLsdb(areaid, *lsa_key)
+		if ret == LsdbEntryFound {
+			lsa_pkt = encodeASExternalLsa(dnlsa, *lsa_key)
+			flood = true
+		} else {
+			server.logger.Info(fmt.Sprintln("LSAREQ: NSSA lsa not found. lsaid ",
+				req.link_state_id, " lstype ", lsa_key.LSType, " adv_router ", lsa_key.AdvRouter, " areaid ", areaid))
+		}
 	}
 	lsid := convertUint32ToIPv4(req.link_state_id)
 	router_id := convertUint32ToIPv4(req.adv_router_id)
@@ -1096,6 +1164,11 @@ func (server *OSPFServer) lsaAddCheck(lsaheader ospfLSAHeader,
 		dalsa, ret := server.getASExternalLsaFromLsdb(areaId, *lsa_key)
 		discard, op = server.sanityCheckASExternalLsa(*alsa, dalsa, nbr, intf, intf.IfAreaId, ret, lsa_max_age)
 
+	case NSSALSA:
+		nlsa := NewASExternalLsa()
+		dnlsa, ret := server.getNSSALsaFromLsdb(areaId, *lsa_key)
+		discard, op = server.sanityCheckNSSALsa(*nlsa, dnlsa, nbr, intf, intf.IfAreaId, ret, lsa_max_age)
+
 	}
 	if discard {
 		server.logger.Info(fmt.Sprintln("DBD: LSA is not added in the request list. Adv router ", adv_router,
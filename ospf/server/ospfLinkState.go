@@ -0,0 +1,264 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	nanomsg "github.com/op/go-nanomsg"
+	"l3/ospf/ospfdCommonDefs"
+	"net"
+)
+
+func (server *OSPFServer) initLinkStatePublisher(address string) error {
+	var err error
+	if server.linkStatePubSocket, err = nanomsg.NewPubSocket(); err != nil {
+		server.logger.Err(fmt.Sprintln("Failed to create link state publish socket, error:", err))
+		return err
+	}
+
+	if _, err = server.linkStatePubSocket.Bind(address); err != nil {
+		server.logger.Err(fmt.Sprintln("Failed to bind link state publish socket, address:", address, "error:", err))
+		server.linkStatePubSocket = nil
+		return err
+	}
+
+	server.logger.Info(fmt.Sprintln("Publishing link state topology at address:", address))
+	return nil
+}
+
+func linkStatePrefixStr(addr, mask uint32) string {
+	ipMask := make(net.IPMask, net.IPv4len)
+	binary.BigEndian.PutUint32(ipMask, mask)
+	ones, _ := ipMask.Size()
+	return fmt.Sprintf("%s/%d", convertUint32ToIPv4(addr&mask), ones)
+}
+
+type linkStateP2PLink struct {
+	idx     int
+	address uint32
+	mask    uint32
+}
+
+func (server *OSPFServer) buildLinkStateArea(areaId uint32, lsDbEnt LSDatabase) ospfdCommonDefs.LinkStateArea {
+	area := ospfdCommonDefs.LinkStateArea{
+		AreaId:   convertUint32ToIPv4(areaId),
+		Nodes:    make([]ospfdCommonDefs.LinkStateNode, 0),
+		Links:    make([]ospfdCommonDefs.LinkStateLink, 0),
+		Prefixes: make([]ospfdCommonDefs.LinkStatePrefix, 0),
+	}
+
+	drIpToRtrId := make(map[uint32]uint32)
+	for lsaKey, ent := range lsDbEnt.NetworkLsaMap {
+		if ent.LsaMd.LSAge == LSA_MAX_AGE {
+			continue
+		}
+		drIpToRtrId[lsaKey.LSId] = lsaKey.AdvRouter
+		routerId := convertUint32ToIPv4(lsaKey.AdvRouter)
+		drIp := convertUint32ToIPv4(lsaKey.LSId)
+		area.Nodes = append(area.Nodes, ospfdCommonDefs.LinkStateNode{RouterId: routerId, DRIp: drIp})
+		for _, attachedRtr := range ent.AttachedRtr {
+			area.Links = append(area.Links, ospfdCommonDefs.LinkStateLink{
+				LocalRouterId:  routerId,
+				LocalDRIp:      drIp,
+				RemoteRouterId: convertUint32ToIPv4(attachedRtr),
+			})
+		}
+		area.Prefixes = append(area.Prefixes, ospfdCommonDefs.LinkStatePrefix{
+			RouterId:  routerId,
+			DRIp:      drIp,
+			Prefix:    linkStatePrefixStr(lsaKey.LSId, ent.Netmask),
+			RouteType: ospfdCommonDefs.LINK_STATE_ROUTE_INTRA_AREA,
+		})
+	}
+
+	p2pLinks := make([]linkStateP2PLink, 0)
+	for lsaKey, ent := range lsDbEnt.RouterLsaMap {
+		if ent.LsaMd.LSAge == LSA_MAX_AGE {
+			continue
+		}
+		routerId := convertUint32ToIPv4(lsaKey.AdvRouter)
+		area.Nodes = append(area.Nodes, ospfdCommonDefs.LinkStateNode{RouterId: routerId, ABR: ent.BitB,
+			ASBR: ent.BitE})
+
+		stubMasks := make(map[uint32]uint32)
+		for _, linkDetail := range ent.LinkDetails {
+			if linkDetail.LinkType == StubLink {
+				stubMasks[linkDetail.LinkId] = linkDetail.LinkData
+			}
+		}
+
+		for _, linkDetail := range ent.LinkDetails {
+			switch linkDetail.LinkType {
+			case P2PLink:
+				link := ospfdCommonDefs.LinkStateLink{
+					LocalRouterId:  routerId,
+					RemoteRouterId: convertUint32ToIPv4(linkDetail.LinkId),
+					Metric:         uint32(linkDetail.LinkMetric),
+				}
+				// Numbered links carry the interface address in the link data, unnumbered links the ifIndex
+				p2pLink := linkStateP2PLink{idx: -1}
+				for network, mask := range stubMasks {
+					if linkDetail.LinkData&mask == network {
+						link.LocalIp = convertUint32ToIPv4(linkDetail.LinkData)
+						p2pLink = linkStateP2PLink{len(area.Links), linkDetail.LinkData, mask}
+						break
+					}
+				}
+				if link.LocalIp == "" {
+					link.LocalIfIndex = linkDetail.LinkData
+				}
+				area.Links = append(area.Links, link)
+				if p2pLink.idx != -1 {
+					p2pLinks = append(p2pLinks, p2pLink)
+				}
+
+			case TransitLink:
+				drRtrId, ok := drIpToRtrId[linkDetail.LinkId]
+				if !ok {
+					continue
+				}
+				area.Links = append(area.Links, ospfdCommonDefs.LinkStateLink{
+					LocalRouterId:  routerId,
+					RemoteRouterId: convertUint32ToIPv4(drRtrId),
+					RemoteDRIp:     convertUint32ToIPv4(linkDetail.LinkId),
+					LocalIp:        convertUint32ToIPv4(linkDetail.LinkData),
+					Metric:         uint32(linkDetail.LinkMetric),
+				})
+
+			case StubLink:
+				area.Prefixes = append(area.Prefixes, ospfdCommonDefs.LinkStatePrefix{
+					RouterId:  routerId,
+					Prefix:    linkStatePrefixStr(linkDetail.LinkId, linkDetail.LinkData),
+					RouteType: ospfdCommonDefs.LINK_STATE_ROUTE_INTRA_AREA,
+					Metric:    uint32(linkDetail.LinkMetric),
+				})
+			}
+		}
+	}
+
+	// The remote address of a numbered point to point link is the local address of the reverse link
+	for _, p2pLink := range p2pLinks {
+		link := &area.Links[p2pLink.idx]
+		for _, revLink := range p2pLinks {
+			rev := area.Links[revLink.idx]
+			if rev.LocalRouterId == link.RemoteRouterId && rev.RemoteRouterId == link.LocalRouterId &&
+				revLink.address&p2pLink.mask == p2pLink.address&p2pLink.mask {
+				link.RemoteIp = rev.LocalIp
+				break
+			}
+		}
+	}
+
+	for lsaKey, ent := range lsDbEnt.Summary3LsaMap {
+		if ent.LsaMd.LSAge == LSA_MAX_AGE {
+			continue
+		}
+		area.Prefixes = append(area.Prefixes, ospfdCommonDefs.LinkStatePrefix{
+			RouterId:  convertUint32ToIPv4(lsaKey.AdvRouter),
+			Prefix:    linkStatePrefixStr(lsaKey.LSId, ent.Netmask),
+			RouteType: ospfdCommonDefs.LINK_STATE_ROUTE_INTER_AREA,
+			Metric:    ent.Metric,
+		})
+	}
+
+	for lsaKey, ent := range lsDbEnt.ASExternalLsaMap {
+		if ent.LsaMd.LSAge == LSA_MAX_AGE {
+			continue
+		}
+		routeType := ospfdCommonDefs.LINK_STATE_ROUTE_EXTERNAL1
+		if ent.BitE {
+			routeType = ospfdCommonDefs.LINK_STATE_ROUTE_EXTERNAL2
+		}
+		area.Prefixes = append(area.Prefixes, ospfdCommonDefs.LinkStatePrefix{
+			RouterId:  convertUint32ToIPv4(lsaKey.AdvRouter),
+			Prefix:    linkStatePrefixStr(lsaKey.LSId, ent.Netmask),
+			RouteType: routeType,
+			Metric:    ent.Metric,
+		})
+	}
+
+	for lsaKey, ent := range lsDbEnt.NSSALsaMap {
+		if ent.LsaMd.LSAge == LSA_MAX_AGE {
+			continue
+		}
+		routeType := ospfdCommonDefs.LINK_STATE_ROUTE_NSSA1
+		if ent.BitE {
+			routeType = ospfdCommonDefs.LINK_STATE_ROUTE_NSSA2
+		}
+		area.Prefixes = append(area.Prefixes, ospfdCommonDefs.LinkStatePrefix{
+			RouterId:  convertUint32ToIPv4(lsaKey.AdvRouter),
+			Prefix:    linkStatePrefixStr(lsaKey.LSId, ent.Netmask),
+			RouteType: routeType,
+			Metric:    ent.Metric,
+		})
+	}
+	return area
+}
+
+func (server *OSPFServer) buildLinkStateTopology() ospfdCommonDefs.LinkStateTopology {
+	topology := ospfdCommonDefs.LinkStateTopology{
+		RouterId: convertIPInByteToString(server.ospfGlobalConf.RouterId),
+		Areas:    make([]ospfdCommonDefs.LinkStateArea, 0, len(server.AreaConfMap)),
+	}
+
+	for key, _ := range server.AreaConfMap {
+		areaId := convertAreaOrRouterIdUint32(string(key.AreaId))
+		lsDbEnt, exist := server.AreaLsdb[LsdbKey{AreaId: areaId}]
+		if !exist {
+			continue
+		}
+		topology.Areas = append(topology.Areas, server.buildLinkStateArea(areaId, lsDbEnt))
+	}
+	return topology
+}
+
+// publishLinkState sends the LSDB topology of all the areas to bgpd after every SPF run
+func (server *OSPFServer) publishLinkState() {
+	if server.linkStatePubSocket == nil {
+		return
+	}
+
+	topology := server.buildLinkStateTopology()
+	msgBuf, err := json.Marshal(topology)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("Failed to marshal link state topology, error:", err))
+		return
+	}
+
+	msg := ospfdCommonDefs.OspfdNotifyMsg{
+		MsgType: ospfdCommonDefs.NOTIFY_LINK_STATE_TOPOLOGY,
+		MsgBuf:  msgBuf,
+	}
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("Failed to marshal link state notification, error:", err))
+		return
+	}
+
+	if _, err = server.linkStatePubSocket.Send(buf, nanomsg.DontWait); err != nil {
+		server.logger.Err(fmt.Sprintln("Failed to publish link state topology, error:", err))
+	}
+}
@@ -0,0 +1,158 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"l3/ospf/ospfdCommonDefs"
+	"testing"
+)
+
+func linkStateTestLsdb() LSDatabase {
+	lsdb := LSDatabase{
+		RouterLsaMap:     make(map[LsaKey]RouterLsa),
+		NetworkLsaMap:    make(map[LsaKey]NetworkLsa),
+		Summary3LsaMap:   make(map[LsaKey]SummaryLsa),
+		Summary4LsaMap:   make(map[LsaKey]SummaryLsa),
+		ASExternalLsaMap: make(map[LsaKey]ASExternalLsa),
+		NSSALsaMap:       make(map[LsaKey]ASExternalLsa),
+	}
+
+	rtr1 := convertAreaOrRouterIdUint32("1.1.1.1")
+	rtr2 := convertAreaOrRouterIdUint32("2.2.2.2")
+	rtr3 := convertAreaOrRouterIdUint32("3.3.3.3")
+	p2pMask := convertAreaOrRouterIdUint32("255.255.255.252")
+	drIp := convertAreaOrRouterIdUint32("20.0.0.2")
+
+	// 1.1.1.1 and 2.2.2.2 are connected by the numbered point to point link 10.0.0.0/30 and by the
+	// transit network 20.0.0.0/24 where 2.2.2.2 is the DR
+	lsdb.RouterLsaMap[LsaKey{RouterLSA, rtr1, rtr1}] = RouterLsa{
+		BitB: true,
+		LinkDetails: []LinkDetail{
+			{LinkId: rtr2, LinkData: convertAreaOrRouterIdUint32("10.0.0.1"), LinkType: P2PLink, LinkMetric: 10},
+			{LinkId: convertAreaOrRouterIdUint32("10.0.0.0"), LinkData: p2pMask, LinkType: StubLink, LinkMetric: 10},
+			{LinkId: drIp, LinkData: convertAreaOrRouterIdUint32("20.0.0.1"), LinkType: TransitLink, LinkMetric: 5},
+		},
+	}
+	lsdb.RouterLsaMap[LsaKey{RouterLSA, rtr2, rtr2}] = RouterLsa{
+		BitE: true,
+		LinkDetails: []LinkDetail{
+			{LinkId: rtr1, LinkData: convertAreaOrRouterIdUint32("10.0.0.2"), LinkType: P2PLink, LinkMetric: 10},
+			{LinkId: convertAreaOrRouterIdUint32("10.0.0.0"), LinkData: p2pMask, LinkType: StubLink, LinkMetric: 10},
+			{LinkId: drIp, LinkData: drIp, LinkType: TransitLink, LinkMetric: 5},
+		},
+	}
+	// LSAs at MaxAge are not part of the topology
+	lsdb.RouterLsaMap[LsaKey{RouterLSA, rtr3, rtr3}] = RouterLsa{LsaMd: LsaMetadata{LSAge: LSA_MAX_AGE}}
+
+	lsdb.NetworkLsaMap[LsaKey{NetworkLSA, drIp, rtr2}] = NetworkLsa{
+		Netmask:     convertAreaOrRouterIdUint32("255.255.255.0"),
+		AttachedRtr: []uint32{rtr1, rtr2},
+	}
+	lsdb.Summary3LsaMap[LsaKey{Summary3LSA, convertAreaOrRouterIdUint32("30.0.0.0"), rtr1}] = SummaryLsa{
+		Netmask: convertAreaOrRouterIdUint32("255.255.255.0"),
+		Metric:  20,
+	}
+	lsdb.ASExternalLsaMap[LsaKey{ASExternalLSA, convertAreaOrRouterIdUint32("40.0.0.0"), rtr2}] = ASExternalLsa{
+		Netmask: convertAreaOrRouterIdUint32("255.255.0.0"),
+		BitE:    true,
+		Metric:  30,
+	}
+	return lsdb
+}
+
+func TestOspfLinkStateArea(t *testing.T) {
+	server := getServerObject()
+	area := server.buildLinkStateArea(convertAreaOrRouterIdUint32("0.0.0.1"), linkStateTestLsdb())
+	if area.AreaId != "0.0.0.1" {
+		t.Error("Expected area 0.0.0.1, found", area.AreaId)
+	}
+
+	nodes := make(map[ospfdCommonDefs.LinkStateNode]bool)
+	for _, node := range area.Nodes {
+		nodes[node] = true
+	}
+	expectedNodes := []ospfdCommonDefs.LinkStateNode{
+		{RouterId: "1.1.1.1", ABR: true},
+		{RouterId: "2.2.2.2", ASBR: true},
+		{RouterId: "2.2.2.2", DRIp: "20.0.0.2"},
+	}
+	if len(nodes) != len(expectedNodes) {
+		t.Error("Expected", len(expectedNodes), "nodes, found", area.Nodes)
+	}
+	for _, node := range expectedNodes {
+		if !nodes[node] {
+			t.Error("Node", node, "not found in", area.Nodes)
+		}
+	}
+
+	links := make(map[ospfdCommonDefs.LinkStateLink]bool)
+	for _, link := range area.Links {
+		links[link] = true
+	}
+	expectedLinks := []ospfdCommonDefs.LinkStateLink{
+		{LocalRouterId: "1.1.1.1", RemoteRouterId: "2.2.2.2", LocalIp: "10.0.0.1", RemoteIp: "10.0.0.2",
+			Metric: 10},
+		{LocalRouterId: "2.2.2.2", RemoteRouterId: "1.1.1.1", LocalIp: "10.0.0.2", RemoteIp: "10.0.0.1",
+			Metric: 10},
+		{LocalRouterId: "1.1.1.1", RemoteRouterId: "2.2.2.2", RemoteDRIp: "20.0.0.2", LocalIp: "20.0.0.1",
+			Metric: 5},
+		{LocalRouterId: "2.2.2.2", RemoteRouterId: "2.2.2.2", RemoteDRIp: "20.0.0.2", LocalIp: "20.0.0.2",
+			Metric: 5},
+		{LocalRouterId: "2.2.2.2", LocalDRIp: "20.0.0.2", RemoteRouterId: "1.1.1.1"},
+		{LocalRouterId: "2.2.2.2", LocalDRIp: "20.0.0.2", RemoteRouterId: "2.2.2.2"},
+	}
+	if len(links) != len(expectedLinks) {
+		t.Error("Expected", len(expectedLinks), "links, found", area.Links)
+	}
+	for _, link := range expectedLinks {
+		if !links[link] {
+			t.Error("Link", link, "not found in", area.Links)
+		}
+	}
+
+	prefixes := make(map[ospfdCommonDefs.LinkStatePrefix]bool)
+	for _, prefix := range area.Prefixes {
+		prefixes[prefix] = true
+	}
+	expectedPrefixes := []ospfdCommonDefs.LinkStatePrefix{
+		{RouterId: "1.1.1.1", Prefix: "10.0.0.0/30", RouteType: ospfdCommonDefs.LINK_STATE_ROUTE_INTRA_AREA,
+			Metric: 10},
+		{RouterId: "2.2.2.2", Prefix: "10.0.0.0/30", RouteType: ospfdCommonDefs.LINK_STATE_ROUTE_INTRA_AREA,
+			Metric: 10},
+		{RouterId: "2.2.2.2", DRIp: "20.0.0.2", Prefix: "20.0.0.0/24",
+			RouteType: ospfdCommonDefs.LINK_STATE_ROUTE_INTRA_AREA},
+		{RouterId: "1.1.1.1", Prefix: "30.0.0.0/24", RouteType: ospfdCommonDefs.LINK_STATE_ROUTE_INTER_AREA,
+			Metric: 20},
+		{RouterId: "2.2.2.2", Prefix: "40.0.0.0/16", RouteType: ospfdCommonDefs.LINK_STATE_ROUTE_EXTERNAL2,
+			Metric: 30},
+	}
+	if len(prefixes) != len(expectedPrefixes) {
+		t.Error("Expected", len(expectedPrefixes), "prefixes, found", area.Prefixes)
+	}
+	for _, prefix := range expectedPrefixes {
+		if !prefixes[prefix] {
+			t.Error("Prefix", prefix, "not found in", area.Prefixes)
+		}
+	}
+}
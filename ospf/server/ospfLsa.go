@@ -35,6 +35,7 @@ const (
 	Summary3LSA   uint8 = 3
 	Summary4LSA   uint8 = 4
 	ASExternalLSA uint8 = 5
+	NSSALSA       uint8 = 7
 )
 
 type LsaKey struct {
@@ -90,6 +91,7 @@ type RouterLsa struct {
 	BitV        bool         /* V Bit */
 	BitE        bool         /* Bit E */
 	BitB        bool         /* Bit B */
+	BitNt       bool         /* Bit Nt (RFC 3101) */
 	NumOfLinks  uint16       /* NumOfLinks */
 	LinkDetails []LinkDetail /* List of LinkDetails */
 }
@@ -134,7 +136,7 @@ type ASExtTOSDetail struct {
 	TOSExtRouteTag uint32
 }
 
-/* LS Type 5 */
+/* LS Type 5 and LS Type 7 (NSSA) */
 type ASExternalLsa struct {
 	LsaMd           LsaMetadata
 	Netmask         uint32 /* Network Mask */
@@ -155,6 +157,7 @@ type LSDatabase struct {
 	Summary3LsaMap   map[LsaKey]SummaryLsa
 	Summary4LsaMap   map[LsaKey]SummaryLsa
 	ASExternalLsaMap map[LsaKey]ASExternalLsa
+	NSSALsaMap       map[LsaKey]ASExternalLsa
 }
 
 type maxAgeLsaMsg struct {
@@ -260,6 +263,11 @@ func decodeRouterLsa(data []byte, lsa *RouterLsa, lsakey *LsaKey) {
 	lsa.LsaMd.LSSequenceNum = int(binary.BigEndian.Uint32(data[12:16]))
 	lsa.LsaMd.LSChecksum = binary.BigEndian.Uint16(data[16:18])
 	lsa.LsaMd.LSLen = binary.BigEndian.Uint16(data[18:20])
+	if data[20]&0x10 != 0 {
+		lsa.BitNt = true
+	} else {
+		lsa.BitNt = false
+	}
 	if data[20]&0x04 != 0 {
 		lsa.BitV = true
 	} else {
@@ -332,6 +340,9 @@ func encodeRouterLsa(lsa RouterLsa, lsakey LsaKey) []byte {
 	lsaHdr := encodeLsaHeader(lsa.LsaMd, lsakey)
 	copy(rtrLsa[0:20], lsaHdr)
 	var val uint8 = 0
+	if lsa.BitNt == true {
+		val = val | 1<<4
+	}
 	if lsa.BitV == true {
 		val = val | 1<<2
 	}
@@ -668,6 +679,18 @@ func (server *OSPFServer) getASExternalLsaFromLsdb(areaId uint32, lsaKey LsaKey)
 	return lsa, LsdbEntryFound
 }
 
+func (server *OSPFServer) getNSSALsaFromLsdb(areaId uint32, lsaKey LsaKey) (lsa ASExternalLsa, retVal int) {
+	lsdbKey := LsdbKey{
+		AreaId: areaId,
+	}
+	lsDbEnt, _ := server.AreaLsdb[lsdbKey]
+	lsa, exist := lsDbEnt.NSSALsaMap[lsaKey]
+	if !exist {
+		return lsa, LsdbEntryNotFound
+	}
+	return lsa, LsdbEntryFound
+}
+
 func (server *OSPFServer) processMaxAgeLSA(lsdbKey LsdbKey, lsdbEnt LSDatabase) {
 	flood_lsa := false
 	/* Router LSA */
@@ -727,6 +750,25 @@ func (server *OSPFServer) processMaxAgeLSA(lsdbKey LsdbKey, lsdbEnt LSDatabase)
 			lsdbEnt.ASExternalLsaMap[lsakey] = lsa_ex
 		}
 	}
+	/* NSSA LSA */
+	for lsakey, lsa_nssa := range lsdbEnt.NSSALsaMap {
+		if lsa_nssa.LsaMd.LSAge == config.MaxAge {
+			// add to flood list
+			lsa_pkt := encodeASExternalLsa(lsa_nssa, lsakey)
+			maxAgeLsaMap[lsakey] = lsa_pkt
+			// delete LSA
+			delete(lsdbEnt.NSSALsaMap, lsakey)
+			advRouter := convertUint32ToIPv4(lsakey.AdvRouter)
+			lsid := convertUint32ToIPv4(lsakey.LSId)
+			server.logger.Info(fmt.Sprintln("DELETE: Max age reached. adv_router ",
+				advRouter, " lstype ", lsakey.LSType, " lsid ", lsid))
+			flood_lsa = true
+
+		} else {
+			lsa_nssa.LsaMd.LSAge++
+			lsdbEnt.NSSALsaMap[lsakey] = lsa_nssa
+		}
+	}
 	/* Summary 3 */
 	for lsakey, lsa_sum := range lsdbEnt.Summary3LsaMap {
 		if lsa_sum.LsaMd.LSAge == config.MaxAge {
@@ -85,6 +85,7 @@ func (server *OSPFServer) initLSDatabase(areaId uint32) {
 		lsDbEnt.Summary3LsaMap = make(map[LsaKey]SummaryLsa)
 		lsDbEnt.Summary4LsaMap = make(map[LsaKey]SummaryLsa)
 		lsDbEnt.ASExternalLsaMap = make(map[LsaKey]ASExternalLsa)
+		lsDbEnt.NSSALsaMap = make(map[LsaKey]ASExternalLsa)
 		server.AreaLsdb[lsdbKey] = lsDbEnt
 	}
 	selfOrigLsaEnt, exist := server.AreaSelfOrigLsa[lsdbKey]
@@ -553,6 +554,22 @@ func (server *OSPFServer) generateRouterLSA(areaId uint32) {
 			linkDetail.LinkType = P2PLink
 			linkDetail.NumOfTOS = 0
 			linkDetail.LinkMetric = uint16(ent.IfCost)
+
+		case config.VirtualLink:
+			/* RFC 2328 12.4.1.3: Type 4 link once the virtual
+			   neighbor is adjacent. Cost is the cost of the
+			   intra area path through the transit area. */
+			nbrData, exist := ospfIntfToNbrMap[key]
+			if !exist || len(nbrData.nbrList) == 0 {
+				server.logger.Info(fmt.Sprintln("LSDB: No neighbor detected for virtual link ", key))
+				continue
+			}
+			nbr := server.NeighborConfigMap[nbrData.nbrList[0]]
+			linkDetail.LinkId = nbr.OspfNbrRtrId
+			linkDetail.LinkData = convertAreaOrRouterIdUint32(ent.IfIpAddr.String())
+			linkDetail.LinkType = VirtualLink
+			linkDetail.NumOfTOS = 0
+			linkDetail.LinkMetric = uint16(ent.IfCost)
 		}
 		linkDetails = append(linkDetails, linkDetail)
 	}
@@ -566,8 +583,19 @@ func (server *OSPFServer) generateRouterLSA(areaId uint32) {
 	AdvRouter := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
 	BitE := false //not an AS boundary router (Todo)
 	BitB := false
+	BitNt := false
+	BitV := server.isVirtualLinkTransitArea(areaId)
 	if server.ospfGlobalConf.AreaBdrRtrStatus == true {
 		BitB = true
+		/* RFC 3101 3.1: Nt bit for an unconditional NSSA translator */
+		areaConfKey := AreaConfKey{
+			AreaId: config.AreaId(convertUint32ToIPv4(areaId)),
+		}
+		areaConf, _ := server.AreaConfMap[areaConfKey]
+		if areaConf.ImportAsExtern == config.ImportNssa &&
+			areaConf.AreaNssaTranslatorRole == config.Always {
+			BitNt = true
+		}
 	}
 	lsaKey := LsaKey{
 		LSType:    LSType,
@@ -606,6 +634,8 @@ func (server *OSPFServer) generateRouterLSA(areaId uint32) {
 	ent.LsaMd.LSLen = uint16(OSPF_LSA_HEADER_SIZE + 4 + (12 * numOfLinks))
 	ent.BitE = BitE
 	ent.BitB = BitB
+	ent.BitNt = BitNt
+	ent.BitV = BitV
 	ent.NumOfLinks = uint16(numOfLinks)
 	ent.LinkDetails = make([]LinkDetail, numOfLinks)
 	copy(ent.LinkDetails, linkDetails[0:])
@@ -651,6 +681,11 @@ func (server *OSPFServer) generateASExternalLsa(route RouteMdata) LsaKey {
 
 	BitE := true
 	for lsdbKey, _ := range server.AreaLsdb {
+		areaId := config.AreaId(convertUint32ToIPv4(lsdbKey.AreaId))
+		if server.isStubArea(areaId) || server.isNssaArea(areaId) {
+			// AS external LSAs are not originated into stub and NSSA areas
+			continue
+		}
 		lsDbEnt, _ := server.AreaLsdb[lsdbKey]
 		ent, exist := lsDbEnt.ASExternalLsaMap[lsaKey]
 		LSAge := 0
@@ -737,6 +772,122 @@ func (server *OSPFServer) updateAsExternalLSA(lsdbKey LsdbKey, lsaKey LsaKey) er
 	return nil
 }
 
+/*@fn generateNSSALsa
+Generate / delete NSSA LSA (Type 7) for the external route in
+every NSSA area. Returns the areas in which the LSA is originated.
+*/
+func (server *OSPFServer) generateNSSALsa(route RouteMdata) (LsaKey, []uint32) {
+	server.logger.Info(fmt.Sprintln("LSDB: Generating NSSA LSA routemdata ", route))
+
+	lsaKey := LsaKey{
+		LSType:    NSSALSA,
+		LSId:      route.ipaddr & route.mask,
+		AdvRouter: convertIPv4ToUint32(server.ospfGlobalConf.RouterId),
+	}
+
+	var areaList []uint32
+	for lsdbKey, lsDbEnt := range server.AreaLsdb {
+		areaId := config.AreaId(convertUint32ToIPv4(lsdbKey.AreaId))
+		if !server.isNssaArea(areaId) {
+			continue
+		}
+		ent, exist := lsDbEnt.NSSALsaMap[lsaKey]
+		if !exist && route.isDel {
+			continue
+		}
+		if !exist {
+			ent.LsaMd.LSSequenceNum = InitialSequenceNumber
+		} else {
+			ent.LsaMd.LSSequenceNum = ent.LsaMd.LSSequenceNum + 1
+		}
+		ent.LsaMd.LSAge = 0
+		ent.LsaMd.LSChecksum = 0
+		ent.LsaMd.LSLen = uint16(OSPF_LSA_HEADER_SIZE + 16)
+		ent.BitE = true
+		ent.Metric = route.metric
+		ent.Netmask = route.mask
+		ent.ExtRouteTag = 0
+		/* RFC 3101 2.3: P bit requires a non zero forwarding address.
+		An ABR originates the AS external LSA itself so P bit is clear. */
+		ent.FwdAddr = server.nssaForwardingAddr(lsdbKey.AreaId)
+		ent.LsaMd.Options = 0
+		if ent.FwdAddr != 0 && !server.ospfGlobalConf.AreaBdrRtrStatus {
+			ent.LsaMd.Options = NPOption
+		}
+
+		LsaEnc := encodeASExternalLsa(ent, lsaKey)
+		checksumOffset := uint16(14)
+		ent.LsaMd.LSChecksum = computeFletcherChecksum(LsaEnc[2:], checksumOffset)
+
+		selfOrigLsaEnt, _ := server.AreaSelfOrigLsa[lsdbKey]
+		if !route.isDel {
+			selfOrigLsaEnt[lsaKey] = true
+		} else {
+			// LSA is flushed by the LSDB aging ticker
+			ent.LsaMd.LSAge = config.MaxAge
+			delete(selfOrigLsaEnt, lsaKey)
+		}
+		server.AreaSelfOrigLsa[lsdbKey] = selfOrigLsaEnt
+		lsDbEnt.NSSALsaMap[lsaKey] = ent
+		server.AreaLsdb[lsdbKey] = lsDbEnt
+		server.logger.Info(fmt.Sprintln("NSSA: Added LSA to area ", lsdbKey, " lsaKey ", lsaKey))
+		if !exist {
+			var val LsdbSliceEnt
+			val.AreaId = lsdbKey.AreaId
+			val.LSType = lsaKey.LSType
+			val.LSId = lsaKey.LSId
+			val.AdvRtr = lsaKey.AdvRouter
+			server.LsdbSlice = append(server.LsdbSlice, val)
+			msg := DbLsdbMsg{
+				entry: val,
+				op:    true,
+			}
+			server.DbLsdbOp <- msg
+		}
+		areaList = append(areaList, lsdbKey.AreaId)
+	}
+	return lsaKey, areaList
+}
+
+/*@fn nssaForwardingAddr
+Forwarding address for NSSA LSA. Lowest interface address
+of the operational interfaces in the NSSA area.
+*/
+func (server *OSPFServer) nssaForwardingAddr(areaId uint32) uint32 {
+	var fwdAddr uint32
+	for _, ent := range server.IntfConfMap {
+		if convertIPv4ToUint32(ent.IfAreaId) != areaId ||
+			ent.IfFSMState <= config.Down {
+			continue
+		}
+		ipAddr := convertAreaOrRouterIdUint32(ent.IfIpAddr.String())
+		if fwdAddr == 0 || ipAddr < fwdAddr {
+			fwdAddr = ipAddr
+		}
+	}
+	return fwdAddr
+}
+
+func (server *OSPFServer) updateNSSALsa(lsdbKey LsdbKey, lsaKey LsaKey) error {
+	lsDbEnt, exist := server.AreaLsdb[lsdbKey]
+	if exist {
+		ent, valid := lsDbEnt.NSSALsaMap[lsaKey]
+		if !valid {
+			server.logger.Warning(fmt.Sprintln("LSDB: NSSA LSA doesnt exist lsdb ", lsdbKey, lsaKey))
+			return nil
+		}
+		ent.LsaMd.LSSequenceNum = ent.LsaMd.LSSequenceNum + 1
+		ent.LsaMd.LSAge = 0
+		ent.LsaMd.LSChecksum = 0
+		LsaEnc := encodeASExternalLsa(ent, lsaKey)
+		checksumOffset := uint16(14)
+		ent.LsaMd.LSChecksum = computeFletcherChecksum(LsaEnc[2:], checksumOffset)
+		lsDbEnt.NSSALsaMap[lsaKey] = ent
+		server.AreaLsdb[lsdbKey] = lsDbEnt
+	}
+	return nil
+}
+
 func (server *OSPFServer) processDeleteRouterLsa(data []byte, areaId uint32) bool {
 	lsakey := NewLsaKey()
 	routerLsa := NewRouterLsa()
@@ -1027,6 +1178,81 @@ func (server *OSPFServer) processRecvdASExternalLsa(data []byte, areaId uint32)
 	return true
 }
 
+func (server *OSPFServer) processDeleteNSSALsa(data []byte, areaId uint32) bool {
+	lsakey := NewLsaKey()
+	var val LsdbSliceEnt
+	nssaLsa := NewASExternalLsa()
+	lsdbKey := LsdbKey{
+		AreaId: areaId,
+	}
+	decodeASExternalLsa(data, nssaLsa, lsakey)
+	lsDbEnt, _ := server.AreaLsdb[lsdbKey]
+	delete(lsDbEnt.NSSALsaMap, *lsakey)
+	server.AreaLsdb[lsdbKey] = lsDbEnt
+
+	val.AreaId = lsdbKey.AreaId
+	val.LSType = lsakey.LSType
+	val.LSId = lsakey.LSId
+	val.AdvRtr = lsakey.AdvRouter
+	err := server.DelLsdbEntry(val)
+	if err != nil {
+		server.logger.Info(fmt.Sprintln("DB: Failed to delete entry from db ", lsakey))
+	}
+	return true
+}
+
+func (server *OSPFServer) processRecvdNSSALsa(data []byte, areaId uint32) bool {
+	lsakey := NewLsaKey()
+	nssaLsa := NewASExternalLsa()
+	lsdbKey := LsdbKey{
+		AreaId: areaId,
+	}
+	if !server.isNssaArea(config.AreaId(convertUint32ToIPv4(areaId))) {
+		server.logger.Err("Recvd NSSA LSA in a non NSSA area")
+		return false
+	}
+	decodeASExternalLsa(data, nssaLsa, lsakey)
+	selfOrigLsaEnt, _ := server.AreaSelfOrigLsa[lsdbKey]
+	_, exist := selfOrigLsaEnt[*lsakey]
+	if exist {
+		server.logger.Info("Recvd a self generated NSSA LSA")
+		return false
+	}
+
+	//Check Checksum
+	csum := computeFletcherChecksum(data[2:], FLETCHER_CHECKSUM_VALIDATE)
+	if csum != 0 {
+		server.logger.Err("Invalid NSSA LSA Checksum")
+		return false
+	}
+	lsDbEnt, _ := server.AreaLsdb[lsdbKey]
+	ent, exist := lsDbEnt.NSSALsaMap[*lsakey]
+	if exist {
+		if ent.LsaMd.LSSequenceNum >= nssaLsa.LsaMd.LSSequenceNum {
+			server.logger.Err("Old instance of NSSA LSA Recvd")
+			return false
+		}
+	}
+	//Add entry in LSADatabase
+	lsDbEnt.NSSALsaMap[*lsakey] = *nssaLsa
+	server.AreaLsdb[lsdbKey] = lsDbEnt
+	if !exist {
+		var val LsdbSliceEnt
+		val.AreaId = lsdbKey.AreaId
+		val.LSType = lsakey.LSType
+		val.LSId = lsakey.LSId
+		val.AdvRtr = lsakey.AdvRouter
+		server.LsdbSlice = append(server.LsdbSlice, val)
+		msg := DbLsdbMsg{
+			entry: val,
+			op:    true,
+		}
+		server.DbLsdbOp <- msg
+	}
+
+	return true
+}
+
 func (server *OSPFServer) processRecvdLsa(data []byte, areaId uint32) bool {
 	LSType := uint8(data[3])
 	if LSType == RouterLSA {
@@ -1043,6 +1269,9 @@ func (server *OSPFServer) processRecvdLsa(data []byte, areaId uint32) bool {
 		return server.processRecvdSummaryLsa(data, areaId, LSType)
 	} else if LSType == ASExternalLSA {
 		return server.processRecvdASExternalLsa(data, areaId)
+	} else if LSType == NSSALSA {
+		server.logger.Info("LSDB: Received NSSA lsa")
+		return server.processRecvdNSSALsa(data, areaId)
 	} else {
 		server.logger.Info("LSDB: Invalid LSA packet from nbr")
 		return false
@@ -1062,6 +1291,8 @@ func (server *OSPFServer) processDeleteLsa(data []byte, areaId uint32) bool {
 		return server.processDeleteSummaryLsa(data, areaId, LSType)
 	} else if LSType == ASExternalLSA {
 		return server.processDeleteASExternalLsa(data, areaId)
+	} else if LSType == NSSALSA {
+		return server.processDeleteNSSALsa(data, areaId)
 	} else {
 		return false
 	}
@@ -1083,6 +1314,7 @@ func (server *OSPFServer) processLSDatabaseUpdates() {
 				if server.ospfGlobalConf.AreaBdrRtrStatus == true {
 					server.installSummaryLsa()
 				}
+				server.installNssaTranslatedLsa()
 			} else if msg.MsgType == LsdbDel {
 				server.logger.Info("Deleting LS in the Lsdb")
 				ret := server.processDeleteLsa(msg.Data, msg.AreaId)
@@ -1094,6 +1326,7 @@ func (server *OSPFServer) processLSDatabaseUpdates() {
 				if server.ospfGlobalConf.AreaBdrRtrStatus == true {
 					server.installSummaryLsa()
 				}
+				server.installNssaTranslatedLsa()
 			} else if msg.MsgType == LsdbUpdate {
 				server.logger.Info("Deleting LS in the Lsdb")
 				ret := server.processRecvdLsa(msg.Data, msg.AreaId)
@@ -1105,6 +1338,7 @@ func (server *OSPFServer) processLSDatabaseUpdates() {
 				if server.ospfGlobalConf.AreaBdrRtrStatus == true {
 					server.installSummaryLsa()
 				}
+				server.installNssaTranslatedLsa()
 			}
 		case msg := <-server.IntfStateChangeCh:
 			server.logger.Info(fmt.Sprintf("Interface State change msg", msg))
@@ -1117,6 +1351,7 @@ func (server *OSPFServer) processLSDatabaseUpdates() {
 			if server.ospfGlobalConf.AreaBdrRtrStatus == true {
 				server.installSummaryLsa()
 			}
+			server.installNssaTranslatedLsa()
 		case msg := <-server.NetworkDRChangeCh:
 			server.logger.Info(fmt.Sprintf("Network DR change msg", msg))
 			// Create a new router LSA
@@ -1128,6 +1363,7 @@ func (server *OSPFServer) processLSDatabaseUpdates() {
 			if server.ospfGlobalConf.AreaBdrRtrStatus == true {
 				server.installSummaryLsa()
 			}
+			server.installNssaTranslatedLsa()
 		case msg := <-server.CreateNetworkLSACh:
 			server.logger.Info(fmt.Sprintf("Create Network LSA msg", msg))
 			server.processNeighborFullEvent(msg)
@@ -1143,6 +1379,7 @@ func (server *OSPFServer) processLSDatabaseUpdates() {
 			if server.ospfGlobalConf.AreaBdrRtrStatus == true {
 				server.installSummaryLsa()
 			}
+			server.installNssaTranslatedLsa()
 
 		case msg := <-server.ExternalRouteNotif: //Generate external LSA
 			server.processExtRouteUpd(msg)
@@ -1164,7 +1401,7 @@ func (server *OSPFServer) processLSDatabaseUpdates() {
 }
 
 /*@fn processExtRouteUpd
-Generate / delete As external LSA.
+Generate / delete As external LSA and NSSA LSA.
 Send flood message if new route is added.
 */
 func (server *OSPFServer) processExtRouteUpd(msg RouteMdata) {
@@ -1174,6 +1411,12 @@ func (server *OSPFServer) processExtRouteUpd(msg RouteMdata) {
 	if !msg.isDel {
 		server.sendLsdbToNeighborEvent(ifkey, nbr, 0, 0, 0, lsaKey, LSAEXTFLOOD)
 	}
+	nssaKey, areaList := server.generateNSSALsa(msg)
+	if !msg.isDel {
+		for _, areaId := range areaList {
+			server.sendLsdbToNeighborEvent(ifkey, nbr, areaId, 0, 0, nssaKey, LSANSSAFLOOD)
+		}
+	}
 }
 
 /*
@@ -1217,6 +1460,12 @@ func (server *OSPFServer) processNeighborFullEvent(msg ospfNbrMdata) {
 	}
 	server.generateRouterLSA(msg.areaId)
 	server.sendLsdbToNeighborEvent(msg.intf, nbr, msg.areaId, 0, 0, lsaKey, LSAFLOOD)
+	if intConf.IfType == config.VirtualLink {
+		/* V bit in the router LSA of the transit area */
+		transitAreaId := virtualLinkTransitAreaId(msg.intf)
+		server.generateRouterLSA(transitAreaId)
+		server.sendLsdbToNeighborEvent(msg.intf, nbr, transitAreaId, 0, 0, lsaKey, LSAFLOOD)
+	}
 }
 
 /* @fn processDrBdrChangeMsg
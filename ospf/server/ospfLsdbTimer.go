@@ -81,6 +81,14 @@ func (server *OSPFServer) lsdbStateRefresh() {
 				val.AdvRtr = lsakey.AdvRouter
 				server.LsdbSlice = append(server.LsdbSlice, val)
 			}
+			for lsakey, _ := range lsdbEnt.NSSALsaMap {
+				var val LsdbSliceEnt
+				val.AreaId = lsdbkey.AreaId
+				val.LSType = lsakey.LSType
+				val.LSId = lsakey.LSId
+				val.AdvRtr = lsakey.AdvRouter
+				server.LsdbSlice = append(server.LsdbSlice, val)
+			}
 		}
 		server.logger.Info(fmt.Sprintln("The new Lsdb Slice after refresh", server.LsdbSlice))
 		server.LsdbStateTimer.Reset(server.RefreshDuration)
@@ -113,6 +121,9 @@ func (server *OSPFServer) lsdbSelfLsaRefresh() {
 				if floodAsExt == 0 && lsaKey.LSType == ASExternalLSA {
 					server.sendLsdbToNeighborEvent(ifkey, nbr, 0, 0, 0, lsaKey, LSAEXTFLOOD)
 				}
+				if lsaKey.LSType == NSSALSA {
+					server.sendLsdbToNeighborEvent(ifkey, nbr, lsdbKey.AreaId, 0, 0, lsaKey, LSANSSAFLOOD)
+				}
 				if err != nil {
 					server.logger.Warning(fmt.Sprintln("LSDB: Failed to regenerate LSA ", lsaKey, " Area ", lsdbKey))
 				}
@@ -158,6 +169,9 @@ func (server *OSPFServer) regenerateLsa(lsdbKey LsdbKey, lsaKey LsaKey) error {
 	case ASExternalLSA:
 		server.updateAsExternalLSA(lsdbKey, lsaKey)
 
+	case NSSALSA:
+		server.updateNSSALsa(lsdbKey, lsaKey)
+
 	}
 	return nil
 }
@@ -33,6 +33,7 @@
 package server
 
 import (
+	"encoding/binary"
 	"fmt"
 	"l3/ospf/config"
 	"net"
@@ -57,7 +58,7 @@ func TestOspfLsdb(t *testing.T) {
 	for index := 1; index < 21; index++ {
 		err := lsdbTestLogic(index)
 		if err != SUCCESS {
-			fmt.Println("Failed test case for interface FSM")
+			t.Error("Failed LSDB test case ", index)
 		}
 	}
 }
@@ -110,6 +111,12 @@ func lsdbTestLogic(tNum int) int {
 	case 9:
 		fmt.Println(tNum, ": Running LSA decode tests ")
 		checkFloodAPIs()
+	case 10:
+		fmt.Println(tNum, ": Running NSSA tests ")
+		return checkNssaAPIs()
+	case 11:
+		fmt.Println(tNum, ": Running virtual link tests ")
+		return checkVirtualLinkAPIs()
 	}
 
 	return SUCCESS
@@ -377,6 +384,233 @@ func checkFloodAPIs() {
 	//	ospf.GenerateSummaryLsa()
 }
 
+func checkNssaAPIs() int {
+	areaConfKey := AreaConfKey{
+		AreaId: config.AreaId(convertUint32ToIPv4(lsdbKey.AreaId)),
+	}
+	areaEnt := ospf.AreaConfMap[areaConfKey]
+	areaEnt.ImportAsExtern = config.ImportNssa
+	areaEnt.AreaNssaTranslatorRole = config.Always
+	if len(areaEnt.IntfListMap) == 0 {
+		areaEnt.IntfListMap = map[IntfConfKey]bool{key: true}
+	}
+	ospf.AreaConfMap[areaConfKey] = areaEnt
+	ospf.initAreaStateSlice(areaConfKey)
+	ospf.initLSDatabase(lsdbKey.AreaId)
+
+	/* Type-7 LSA with the P bit and a forwarding address */
+	lsa_nssa := make([]byte, len(lsa_asExt))
+	copy(lsa_nssa, lsa_asExt)
+	lsa_nssa[2] = NPOption
+	lsa_nssa[3] = NSSALSA
+	binary.BigEndian.PutUint32(lsa_nssa[28:32], convertAreaOrRouterIdUint32("10.1.1.1"))
+	lsa_nssa[16] = 0
+	lsa_nssa[17] = 0
+	checksumOffset := uint16(14)
+	checkSum := computeFletcherChecksum(lsa_nssa[2:], checksumOffset)
+	binary.BigEndian.PutUint16(lsa_nssa[16:18], checkSum)
+
+	areaIdBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(areaIdBytes, lsdbKey.AreaId)
+	if ospf.lsaAreaCheck(ASExternalLSA, areaIdBytes) {
+		fmt.Println("AS external LSA is accepted in NSSA area")
+		return FAIL
+	}
+	if !ospf.lsaAreaCheck(NSSALSA, areaIdBytes) {
+		fmt.Println("NSSA LSA is rejected in NSSA area")
+		return FAIL
+	}
+
+	if !ospf.processRecvdLsa(lsa_nssa, lsdbKey.AreaId) {
+		fmt.Println("NSSA LSA is not installed")
+		return FAIL
+	}
+	nssaLsa := NewASExternalLsa()
+	nssaKey := NewLsaKey()
+	decodeASExternalLsa(lsa_nssa, nssaLsa, nssaKey)
+	if _, ret := ospf.getNSSALsaFromLsdb(lsdbKey.AreaId, *nssaKey); ret != LsdbEntryFound {
+		fmt.Println("NSSA LSA not found in lsdb ", nssaKey)
+		return FAIL
+	}
+
+	ospf.ospfGlobalConf.AreaBdrRtrStatus = true
+	ospf.updateNssaTranslatorState(areaConfKey, areaEnt, lsdbKey.AreaId)
+	if state := ospf.AreaStateMap[areaConfKey].AreaNssaTranslatorState; state != config.NssaTranslatorEnabled {
+		fmt.Println("Wrong NSSA translator state ", state)
+		return FAIL
+	}
+	ospf.GenerateNssaTranslation()
+	tKey := LsaKey{
+		LSType:    ASExternalLSA,
+		LSId:      nssaKey.LSId,
+		AdvRouter: convertIPv4ToUint32(ospf.ospfGlobalConf.RouterId),
+	}
+	tLsa, exist := ospf.NssaTranslatedLsDb[tKey]
+	if !exist || tLsa.FwdAddr != nssaLsa.FwdAddr || tLsa.Metric != nssaLsa.Metric {
+		fmt.Println("NSSA LSA is not translated ", ospf.NssaTranslatedLsDb)
+		return FAIL
+	}
+	ospf.installNssaTranslatedLsa()
+
+	ospf.processDeleteLsa(lsa_nssa, lsdbKey.AreaId)
+	routemdata := RouteMdata{
+		ipaddr: 2,
+		mask:   100,
+		metric: 10,
+		isDel:  false,
+	}
+	ospf.processExtRouteUpd(routemdata)
+	ospf.processNSSALSAFlood(lsdbKey.AreaId, routerKey)
+	ospf.HandleNSSALsa(lsdbKey.AreaId)
+	ospf.generateDbNSSALsaList(lsdbKey.AreaId)
+	return SUCCESS
+}
+
+func checkVirtualLinkAPIs() int {
+	transitArea := config.AreaId(convertUint32ToIPv4(lsdbKey.AreaId))
+	areaConfKey := AreaConfKey{
+		AreaId: transitArea,
+	}
+	areaEnt := ospf.AreaConfMap[areaConfKey]
+	areaEnt.ImportAsExtern = config.ImportExternal
+	ospf.AreaConfMap[areaConfKey] = areaEnt
+	ospf.initLSDatabase(0)
+	ospf.initLSDatabase(lsdbKey.AreaId)
+
+	virtIfConf := config.VirtIfConf{
+		VirtIfAreaId:          transitArea,
+		VirtIfNeighbor:        config.RouterId(convertUint32ToIPv4(routerKey.AdvRouter)),
+		VirtIfTransitDelay:    1,
+		VirtIfRetransInterval: 5,
+		VirtIfHelloInterval:   10,
+		VirtIfRtrDeadInterval: 40,
+		VirtIfAuthType:        config.NoAuth,
+	}
+	err := ospf.processVirtIfConfig(virtIfConf)
+	if err != nil {
+		fmt.Println("Failed to configure virtual link ", err)
+		return FAIL
+	}
+	vKey, _ := ospf.getVirtualLinkKey(virtIfConf)
+	vIntfKey := virtualLinkIntfKey(vKey)
+	vIntf, exist := ospf.IntfConfMap[vIntfKey]
+	if !exist || vIntf.IfType != config.VirtualLink || vIntfKey == key {
+		fmt.Println("Virtual interface not configured ", vIntfKey, vIntf)
+		return FAIL
+	}
+	if virtualLinkTransitAreaId(vIntfKey) != lsdbKey.AreaId {
+		fmt.Println("Wrong transit area for virtual interface ", vIntfKey)
+		return FAIL
+	}
+
+	virtIfConf.VirtIfAreaId = config.AreaId("0.0.0.0")
+	if err = ospf.processVirtIfConfig(virtIfConf); err == nil {
+		fmt.Println("Virtual link through backbone is accepted")
+		return FAIL
+	}
+	virtIfConf.VirtIfAreaId = transitArea
+
+	ospf.TempAreaRoutingTbl = make(map[AreaIdKey]AreaRoutingTbl)
+	for _, areaId := range []uint32{0, lsdbKey.AreaId} {
+		tbl := AreaRoutingTbl{
+			RoutingTblMap: make(map[RoutingTblEntryKey]RoutingTblEntry),
+		}
+		ospf.TempAreaRoutingTbl[AreaIdKey{AreaId: areaId}] = tbl
+	}
+	path := ospf.findVirtualLinkPath(vKey)
+	if path.Reachable {
+		fmt.Println("Virtual link path found without a route to the neighbor ", path)
+		return FAIL
+	}
+	ospf.TransitAreaMap[lsdbKey.AreaId] = true
+	ospf.processRecvdLsa(lsa_summary, lsdbKey.AreaId)
+	ospf.HandleTransitAreaSummaryLsa()
+	ospf.TempAreaRoutingTbl = nil
+	delete(ospf.TransitAreaMap, lsdbKey.AreaId)
+
+	path.Reachable = true
+	path.Cost = 10
+	msg := VirtualLinkStateMsg{
+		Key:  vKey,
+		Path: path,
+	}
+	ospf.processVirtualLinkStateMsg(msg)
+	if ospf.VirtualLinkMap[vKey].Path != path {
+		fmt.Println("Virtual link path not updated ", ospf.VirtualLinkMap[vKey])
+		return FAIL
+	}
+	if _, _, err = ospf.virtualLinkNextHop(vKey.NbrRtrId); err != nil {
+		fmt.Println("No next hop for reachable virtual link ", err)
+		return FAIL
+	}
+
+	/* Transit area interface and adjacent virtual neighbor */
+	transitIntfKey := IntfConfKey{
+		IPAddr:  config.IpAddress(net.IP{10, 1, 2, 2}),
+		IntfIdx: config.InterfaceIndexOrZero(3),
+	}
+	transitIntf := intf
+	transitIntf.IfAreaId = []byte{10, 0, 0, 0}
+	transitIntf.IfIpAddr = net.IP{10, 1, 2, 2}
+	transitIntf.IfFSMState = config.DesignatedRouter
+	transitIntf.NeighborMap = nil
+	ospf.IntfConfMap[transitIntfKey] = transitIntf
+
+	vIntf = ospf.IntfConfMap[vIntfKey]
+	vIntf.IfFSMState = config.P2P
+	vIntf.IfIpAddr = transitIntf.IfIpAddr
+	vIntf.IfCost = path.Cost
+	ospf.IntfConfMap[vIntfKey] = vIntf
+	vNbrKey := NeighborConfKey{
+		IPAddr:  config.IpAddress(convertUint32ToIPv4(vKey.NbrRtrId)),
+		IntfIdx: vIntfKey.IntfIdx,
+	}
+	ospf.NeighborConfigMap[vNbrKey] = OspfNeighborEntry{OspfNbrRtrId: vKey.NbrRtrId}
+	ospfIntfToNbrMap[vIntfKey] = ospfNbrMdata{
+		intf:    vIntfKey,
+		nbrList: []NeighborConfKey{vNbrKey},
+	}
+
+	ospf.generateRouterLSA(0)
+	ospf.generateRouterLSA(lsdbKey.AreaId)
+	rtrId := convertIPv4ToUint32(ospf.ospfGlobalConf.RouterId)
+	selfRouterKey := LsaKey{
+		LSType:    RouterLSA,
+		LSId:      rtrId,
+		AdvRouter: rtrId,
+	}
+	bbLsa, ret := ospf.getRouterLsaFromLsdb(0, selfRouterKey)
+	vLinkFound := false
+	for _, link := range bbLsa.LinkDetails {
+		if link.LinkType == VirtualLink && link.LinkId == vKey.NbrRtrId && link.LinkMetric == uint16(path.Cost) {
+			vLinkFound = true
+		}
+	}
+	if ret != LsdbEntryFound || !vLinkFound {
+		fmt.Println("No virtual link in backbone router LSA ", bbLsa)
+		return FAIL
+	}
+	transitLsa, ret := ospf.getRouterLsaFromLsdb(lsdbKey.AreaId, selfRouterKey)
+	if ret != LsdbEntryFound || !transitLsa.BitV {
+		fmt.Println("V bit not set in transit area router LSA ", transitLsa)
+		return FAIL
+	}
+
+	delete(ospfIntfToNbrMap, vIntfKey)
+	delete(ospf.NeighborConfigMap, vNbrKey)
+	delete(ospf.IntfConfMap, transitIntfKey)
+	ospf.processVirtIfDelete(virtIfConf)
+	if _, exist := ospf.VirtualLinkMap[vKey]; exist {
+		fmt.Println("Virtual link not deleted ", ospf.VirtualLinkMap)
+		return FAIL
+	}
+	if _, exist := ospf.IntfConfMap[vIntfKey]; exist {
+		fmt.Println("Virtual interface not deleted ", vIntfKey)
+		return FAIL
+	}
+	return SUCCESS
+}
+
 func checkSPFAPIs(selfOrMap map[LsaKey]bool) {
 	lsaKey, err := findSelfOrigRouterLsaKey(selfOrMap)
 	fmt.Println("Found router Key ", lsaKey, " error ", err)
@@ -35,6 +35,7 @@ func (server *OSPFServer) HandleSummaryLsa(areaId uint32) {
 	server.HandleSummaryType3Lsa(areaId)
 	server.HandleSummaryType4Lsa(areaId)
 	server.HandleASExternalLsa(areaId)
+	server.HandleNSSALsa(areaId)
 }
 
 func (server *OSPFServer) HandleSummaryType3Lsa(areaId uint32) {
@@ -42,8 +43,102 @@ func (server *OSPFServer) HandleSummaryType3Lsa(areaId uint32) {
 	server.CalcInterAreaRoutes(areaId)
 }
 
+func findBdrRtrEntry(tbl AreaRoutingTbl, rtrId uint32) (RoutingTblEntry, bool) {
+	for _, destType := range []DestType{AreaBdrRouter, ASAreaBdrRouter} {
+		rKey := RoutingTblEntryKey{
+			DestId:   rtrId,
+			AddrMask: 0,
+			DestType: destType,
+		}
+		if rEnt, exist := tbl.RoutingTblMap[rKey]; exist {
+			return rEnt, true
+		}
+	}
+	return RoutingTblEntry{}, false
+}
+
+/*
+@fn HandleTransitAreaSummaryLsa
+RFC 2328 16.3: Summary LSAs of transit areas are examined
+for better paths to destinations in the backbone routing
+table. The better path goes through the transit area
+instead of the virtual link.
+*/
 func (server *OSPFServer) HandleTransitAreaSummaryLsa() {
+	bbTbl, exist := server.TempAreaRoutingTbl[AreaIdKey{AreaId: 0}]
+	if !exist || bbTbl.RoutingTblMap == nil {
+		return
+	}
+	for aKey, _ := range server.AreaConfMap {
+		areaId := convertAreaOrRouterIdUint32(string(aKey.AreaId))
+		if areaId == 0 || server.TransitAreaMap[areaId] == false {
+			continue
+		}
+		lsDbEnt, exist := server.AreaLsdb[LsdbKey{AreaId: areaId}]
+		if !exist {
+			continue
+		}
+		tbl := server.TempAreaRoutingTbl[AreaIdKey{AreaId: areaId}]
+		for lsaKey, lsaEnt := range lsDbEnt.Summary3LsaMap {
+			rKey := RoutingTblEntryKey{
+				DestId:   lsaKey.LSId & lsaEnt.Netmask,
+				AddrMask: lsaEnt.Netmask,
+				DestType: Network,
+			}
+			server.examineTransitSummaryLsa(bbTbl, tbl, rKey, lsaKey, lsaEnt)
+		}
+		for lsaKey, lsaEnt := range lsDbEnt.Summary4LsaMap {
+			for _, destType := range []DestType{ASBdrRouter, ASAreaBdrRouter} {
+				rKey := RoutingTblEntryKey{
+					DestId:   lsaKey.LSId,
+					AddrMask: 0,
+					DestType: destType,
+				}
+				if _, exist := bbTbl.RoutingTblMap[rKey]; exist {
+					server.examineTransitSummaryLsa(bbTbl, tbl, rKey, lsaKey, lsaEnt)
+					break
+				}
+			}
+		}
+	}
+}
 
+func (server *OSPFServer) examineTransitSummaryLsa(bbTbl AreaRoutingTbl, transitTbl AreaRoutingTbl,
+	rKey RoutingTblEntryKey, lsaKey LsaKey, lsaEnt SummaryLsa) {
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	if lsaEnt.Metric == LSInfinity ||
+		lsaEnt.LsaMd.LSAge == config.MaxAge ||
+		lsaKey.AdvRouter == rtrId {
+		return
+	}
+	rEnt, exist := bbTbl.RoutingTblMap[rKey]
+	if !exist ||
+		(rEnt.PathType != IntraArea && rEnt.PathType != InterArea) {
+		return
+	}
+	bEnt, exist := findBdrRtrEntry(transitTbl, lsaKey.AdvRouter)
+	if !exist || bEnt.NumOfPaths == 0 {
+		return
+	}
+	cost := bEnt.Cost + uint16(lsaEnt.Metric)
+	if cost > rEnt.Cost {
+		return
+	}
+	nextHops := make(map[NextHop]bool)
+	if cost == rEnt.Cost {
+		for key, _ := range rEnt.NextHops {
+			nextHops[key] = true
+		}
+	}
+	for key, _ := range bEnt.NextHops {
+		key.AdvRtr = lsaKey.AdvRouter
+		nextHops[key] = true
+	}
+	server.logger.Info(fmt.Sprintln("Transit area path for:", rKey, "cost:", cost, "old cost:", rEnt.Cost))
+	rEnt.Cost = cost
+	rEnt.NextHops = nextHops
+	rEnt.NumOfPaths = len(nextHops)
+	bbTbl.RoutingTblMap[rKey] = rEnt
 }
 
 // Handling Summary LSA in case of FS is internal router
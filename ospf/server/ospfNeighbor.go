@@ -753,11 +753,17 @@ func (server *OSPFServer) generateDbSummaryList(nbrConfKey NeighborConfKey) {
 		db_list = append(db_list, summary4_list...)
 	}
 
+	/* RFC 2328 10.3: AS external LSAs are omitted on virtual links */
 	asExternal_list := server.generateDbasExternalList(areaId)
-	if asExternal_list != nil {
+	if asExternal_list != nil && intf.IfType != config.VirtualLink {
 		db_list = append(db_list, asExternal_list...)
 	}
 
+	nssa_list := server.generateDbNSSALsaList(areaId)
+	if nssa_list != nil {
+		db_list = append(db_list, nssa_list...)
+	}
+
 	for lsa := range db_list {
 		rtr_id := convertUint32ToIPv4(db_list[lsa].lsa_headers.adv_router_id)
 		server.logger.Info(fmt.Sprintln(lsa, ": ", rtr_id, " lsatype ", db_list[lsa].lsa_headers.ls_type))
@@ -806,6 +812,43 @@ func (server *OSPFServer) generateDbasExternalList(self_areaId uint32) []*ospfNe
 	return db_list
 }
 
+/*@fn generateDbNSSALsaList
+This function generates NSSA LSA list if the area is NSSA
+*/
+func (server *OSPFServer) generateDbNSSALsaList(self_areaId uint32) []*ospfNeighborDBSummary {
+	if !server.isNssaArea(config.AreaId(convertUint32ToIPv4(self_areaId))) {
+		return nil
+	}
+	db_list := []*ospfNeighborDBSummary{}
+	lsdbKey := LsdbKey{
+		AreaId: self_areaId,
+	}
+
+	area_lsa, exist := server.AreaLsdb[lsdbKey]
+	if !exist {
+		server.logger.Err(fmt.Sprintln("negotiation: NSSA LSA doesnt exist"))
+		return nil
+	}
+
+	for lsaKey, _ := range area_lsa.NSSALsaMap {
+		db_nssa := newospfNeighborDBSummary()
+		dnlsa, ret := server.getNSSALsaFromLsdb(self_areaId, lsaKey)
+		if ret == LsdbEntryNotFound {
+			continue
+		}
+		db_nssa.lsa_headers = getLsaHeaderFromLsa(dnlsa.LsaMd.LSAge, dnlsa.LsaMd.Options,
+			NSSALSA, lsaKey.LSId, lsaKey.AdvRouter,
+			uint32(dnlsa.LsaMd.LSSequenceNum), dnlsa.LsaMd.LSChecksum,
+			dnlsa.LsaMd.LSLen)
+		db_nssa.valid = true
+		/* add entry to the db summary list  */
+		db_list = append(db_list, db_nssa)
+		lsid := convertUint32ToIPv4(lsaKey.LSId)
+		server.logger.Info(fmt.Sprintln("negotiation: db_list NSSA append lsid  ", lsid))
+	}
+	return db_list
+}
+
 /* @fn generateDbsummaryLsaList
 This function will attach summary LSAs if the router is ABR
 */
@@ -54,6 +54,7 @@ const (
 	LSASUMMARYFLOOD = 4 //flood summary LSAs in different areas.
 	LSAEXTFLOOD     = 5 //flood AS External summary LSA
 	LSAROUTERFLOOD  = 6 //flood only router LSA
+	LSANSSAFLOOD    = 7 //flood NSSA LSA in the NSSA area
 )
 
 type NeighborConfKey struct {
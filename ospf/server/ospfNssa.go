@@ -0,0 +1,252 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+)
+
+/*
+@fn updateNssaTranslatorState
+RFC 3101 3.1 NSSA translator election. Border router with
+translator role Always is the translator. Candidate is elected
+if no other reachable NSSA border router has the Nt bit set or a
+higher router id.
+*/
+func (server *OSPFServer) updateNssaTranslatorState(areaConfKey AreaConfKey, areaConf AreaConf, areaId uint32) {
+	state := config.NssaTranslatorDisabled
+	if areaConf.ImportAsExtern == config.ImportNssa &&
+		server.ospfGlobalConf.AreaBdrRtrStatus == true {
+		if areaConf.AreaNssaTranslatorRole == config.Always {
+			state = config.NssaTranslatorEnabled
+		} else if server.electNssaTranslator(areaId) {
+			state = config.NssaTranslatorElected
+		}
+	}
+
+	ent, exist := server.AreaStateMap[areaConfKey]
+	if !exist || ent.AreaNssaTranslatorState == state {
+		return
+	}
+	server.logger.Info(fmt.Sprintln("NSSA: Translator state change area ", areaConfKey.AreaId,
+		" old ", ent.AreaNssaTranslatorState, " new ", state))
+	ent.AreaNssaTranslatorState = state
+	ent.AreaNssaTranslatorEvents++
+	server.AreaStateMap[areaConfKey] = ent
+}
+
+func (server *OSPFServer) electNssaTranslator(areaId uint32) bool {
+	lsdbKey := LsdbKey{
+		AreaId: areaId,
+	}
+	lsDbEnt, exist := server.AreaLsdb[lsdbKey]
+	if !exist {
+		return false
+	}
+	areaIdKey := AreaIdKey{
+		AreaId: areaId,
+	}
+	tempAreaRoutingTbl := server.TempAreaRoutingTbl[areaIdKey]
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	for lsaKey, lsaEnt := range lsDbEnt.RouterLsaMap {
+		if lsaKey.AdvRouter == rtrId ||
+			lsaEnt.BitB == false ||
+			lsaEnt.LsaMd.LSAge == config.MaxAge {
+			continue
+		}
+		if !isBdrRtrReachable(tempAreaRoutingTbl, lsaKey.AdvRouter) {
+			continue
+		}
+		if lsaEnt.BitNt == true || lsaKey.AdvRouter > rtrId {
+			return false
+		}
+	}
+	return true
+}
+
+func isBdrRtrReachable(tbl AreaRoutingTbl, rtrId uint32) bool {
+	_, exist := findBdrRtrEntry(tbl, rtrId)
+	return exist
+}
+
+/*
+@fn GenerateNssaTranslation
+RFC 3101 3.2 Translation of NSSA LSAs into AS external LSAs.
+NSSA LSAs with P bit and non zero forwarding address are
+translated in the NSSA areas where this router is the translator.
+*/
+func (server *OSPFServer) GenerateNssaTranslation() {
+	server.NssaTranslatedLsDb = make(map[LsaKey]ASExternalLsa)
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	for aKey, aEnt := range server.AreaConfMap {
+		if len(aEnt.IntfListMap) == 0 {
+			continue
+		}
+		aState, _ := server.AreaStateMap[aKey]
+		if aState.AreaNssaTranslatorState != config.NssaTranslatorEnabled &&
+			aState.AreaNssaTranslatorState != config.NssaTranslatorElected {
+			continue
+		}
+		lsdbKey := LsdbKey{
+			AreaId: convertAreaOrRouterIdUint32(string(aKey.AreaId)),
+		}
+		lsDbEnt, exist := server.AreaLsdb[lsdbKey]
+		if !exist {
+			continue
+		}
+		for lsaKey, lsaEnt := range lsDbEnt.NSSALsaMap {
+			if lsaKey.AdvRouter == rtrId ||
+				lsaEnt.LsaMd.Options&NPOption == 0 ||
+				lsaEnt.FwdAddr == 0 ||
+				lsaEnt.Metric >= LSInfinity ||
+				lsaEnt.LsaMd.LSAge == config.MaxAge {
+				continue
+			}
+			tKey := LsaKey{
+				LSType:    ASExternalLSA,
+				LSId:      lsaKey.LSId,
+				AdvRouter: rtrId,
+			}
+			tEnt, exist := server.NssaTranslatedLsDb[tKey]
+			if exist && !nssaTranslationPreferred(lsaEnt, tEnt) {
+				continue
+			}
+			tEnt.LsaMd.Options = 0x20
+			tEnt.LsaMd.LSLen = uint16(OSPF_LSA_HEADER_SIZE + 16)
+			tEnt.Netmask = lsaEnt.Netmask
+			tEnt.BitE = lsaEnt.BitE
+			tEnt.Metric = lsaEnt.Metric
+			tEnt.FwdAddr = lsaEnt.FwdAddr
+			tEnt.ExtRouteTag = lsaEnt.ExtRouteTag
+			server.NssaTranslatedLsDb[tKey] = tEnt
+		}
+	}
+}
+
+/* Type 1 metric is preferred over Type 2 and then lower metric */
+func nssaTranslationPreferred(lsaEnt ASExternalLsa, tEnt ASExternalLsa) bool {
+	if lsaEnt.BitE != tEnt.BitE {
+		return lsaEnt.BitE == false
+	}
+	return lsaEnt.Metric < tEnt.Metric
+}
+
+/*
+@fn installNssaTranslatedLsa
+Install translated AS external LSAs in the LSDB and flood them.
+Translations which are not valid anymore are flushed.
+*/
+func (server *OSPFServer) installNssaTranslatedLsa() {
+	if server.NssaTranslatedLsDb == nil {
+		return
+	}
+	ifkey := IntfConfKey{}
+	nbr := NeighborConfKey{}
+	for lsaKey, lsaEnt := range server.NssaTranslatedLsDb {
+		installed := false
+		flood := false
+		for lsdbKey, lsDbEnt := range server.AreaLsdb {
+			areaId := config.AreaId(convertUint32ToIPv4(lsdbKey.AreaId))
+			if server.isStubArea(areaId) || server.isNssaArea(areaId) {
+				continue
+			}
+			selfOrigLsaEnt, _ := server.AreaSelfOrigLsa[lsdbKey]
+			ent, exist := lsDbEnt.ASExternalLsaMap[lsaKey]
+			if exist && selfOrigLsaEnt[lsaKey] && !server.NssaTranslatedLsa[lsaKey] {
+				// AS external LSA for the redistributed route takes precedence
+				continue
+			}
+			installed = true
+			if exist && ent.LsaMd.LSAge != config.MaxAge &&
+				ent.Netmask == lsaEnt.Netmask &&
+				ent.BitE == lsaEnt.BitE &&
+				ent.Metric == lsaEnt.Metric &&
+				ent.FwdAddr == lsaEnt.FwdAddr {
+				continue
+			}
+			if !exist {
+				lsaEnt.LsaMd.LSSequenceNum = InitialSequenceNumber
+			} else {
+				lsaEnt.LsaMd.LSSequenceNum = ent.LsaMd.LSSequenceNum + 1
+			}
+			lsaEnt.LsaMd.LSAge = 0
+			lsaEnt.LsaMd.LSChecksum = 0
+			LsaEnc := encodeASExternalLsa(lsaEnt, lsaKey)
+			checksumOffset := uint16(14)
+			lsaEnt.LsaMd.LSChecksum = computeFletcherChecksum(LsaEnc[2:], checksumOffset)
+			lsDbEnt.ASExternalLsaMap[lsaKey] = lsaEnt
+			server.AreaLsdb[lsdbKey] = lsDbEnt
+			selfOrigLsaEnt[lsaKey] = true
+			server.AreaSelfOrigLsa[lsdbKey] = selfOrigLsaEnt
+			if !exist {
+				var val LsdbSliceEnt
+				val.AreaId = lsdbKey.AreaId
+				val.LSType = lsaKey.LSType
+				val.LSId = lsaKey.LSId
+				val.AdvRtr = lsaKey.AdvRouter
+				server.LsdbSlice = append(server.LsdbSlice, val)
+				msg := DbLsdbMsg{
+					entry: val,
+					op:    true,
+				}
+				server.DbLsdbOp <- msg
+			}
+			flood = true
+		}
+		if installed {
+			server.NssaTranslatedLsa[lsaKey] = true
+		}
+		if flood {
+			server.logger.Info(fmt.Sprintln("NSSA: Send message to flood translated LSA ", lsaKey))
+			server.sendLsdbToNeighborEvent(ifkey, nbr, 0, 0, 0, lsaKey, LSAEXTFLOOD)
+		}
+	}
+
+	for lsaKey, _ := range server.NssaTranslatedLsa {
+		if _, exist := server.NssaTranslatedLsDb[lsaKey]; exist {
+			continue
+		}
+		server.flushNssaTranslatedLsa(lsaKey)
+		delete(server.NssaTranslatedLsa, lsaKey)
+	}
+	server.NssaTranslatedLsDb = nil
+}
+
+/* Translated LSA is flushed by the LSDB aging ticker */
+func (server *OSPFServer) flushNssaTranslatedLsa(lsaKey LsaKey) {
+	server.logger.Info(fmt.Sprintln("NSSA: Need to flush translated LSA ", lsaKey))
+	for lsdbKey, lsDbEnt := range server.AreaLsdb {
+		ent, exist := lsDbEnt.ASExternalLsaMap[lsaKey]
+		if !exist {
+			continue
+		}
+		ent.LsaMd.LSAge = config.MaxAge
+		lsDbEnt.ASExternalLsaMap[lsaKey] = ent
+		server.AreaLsdb[lsdbKey] = lsDbEnt
+		selfOrigLsaEnt, _ := server.AreaSelfOrigLsa[lsdbKey]
+		delete(selfOrigLsaEnt, lsaKey)
+		server.AreaSelfOrigLsa[lsdbKey] = selfOrigLsaEnt
+	}
+}
@@ -214,6 +214,11 @@ func (server *OSPFServer) findP2PNextHopIP(vFirst VertexKey, vSecond VertexKey,
 	} else {
 		flag = false
 	}
+	if firstLink.LinkType == VirtualLink {
+		/* Backbone paths over a virtual link use the
+		   next hop through the transit area */
+		return server.virtualLinkNextHop(vSecond.AdvRtr)
+	}
 	for _, link := range secondLsa.LinkDetails {
 		if link.LinkId == vFirst.AdvRtr &&
 			link.LinkType == P2PLink {
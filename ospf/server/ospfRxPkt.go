@@ -55,7 +55,8 @@ func (server *OSPFServer) processIPv4Layer(ipLayer gopacket.Layer, ipAddr net.IP
 
 	if ipAddr.Equal(ipPkt.DstIP) == false &&
 		allSPFRouter.Equal(ipPkt.DstIP) == false &&
-		allDRouter.Equal(ipPkt.DstIP) == false {
+		allDRouter.Equal(ipPkt.DstIP) == false &&
+		server.isVirtualLinkDstIP(ipPkt.DstIP) == false {
 		err := errors.New(fmt.Sprintln("Incorrect DstIP", ipPkt.DstIP, "hence dicarding the packet"))
 		return err
 	}
@@ -182,6 +183,7 @@ func (server *OSPFServer) ProcessOspfRecvPkt(key IntfConfKey, pkt gopacket.Packe
 
 	ospfHdrMd := NewOspfHdrMetadata()
 	ospfPkt := ipLayer.LayerPayload()
+	key = server.virtualLinkRxIntfKey(ospfPkt, key, ipHdrMd)
 	err = server.processOspfHeader(ospfPkt, key, ospfHdrMd)
 	if err != nil {
 		server.logger.Err(fmt.Sprintln("Dropped because of Ospf Header processing", err))
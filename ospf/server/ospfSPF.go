@@ -296,15 +296,7 @@ func (server *OSPFServer) UpdateAreaGraphRouterLsa(lsaEnt RouterLsa, lsaKey LsaK
 		return nil
 	}
 	if lsaEnt.BitV == true {
-		area := config.AreaId(convertUint32ToIPv4(areaId))
-		areaConfKey := AreaConfKey{
-			AreaId: area,
-		}
-		aEnt, exist := server.AreaConfMap[areaConfKey]
-		if exist && aEnt.TransitCapability == false {
-			aEnt.TransitCapability = true
-			server.AreaConfMap[areaConfKey] = aEnt
-		}
+		server.TransitAreaMap[areaId] = true
 	}
 	ent.NbrVertexKey = make([]VertexKey, 0)
 	ent.NbrVertexCost = make([]uint16, 0)
@@ -354,7 +346,8 @@ func (server *OSPFServer) UpdateAreaGraphRouterLsa(lsaEnt RouterLsa, lsaKey LsaK
 			sentry.LsaKey = lsaKey
 			sentry.LinkStateId = lsaKey.LSId
 			server.AreaStubs[vKey] = sentry
-		} else if linkDetail.LinkType == P2PLink {
+		} else if linkDetail.LinkType == P2PLink ||
+			linkDetail.LinkType == VirtualLink {
 			server.logger.Info("===It is P2PLink===")
 			vKey = VertexKey{
 				Type:   RouterVertex,
@@ -801,8 +794,9 @@ func (server *OSPFServer) spfCalculation() {
 			if len(aEnt.IntfListMap) == 0 {
 				continue
 			}
-			aEnt.TransitCapability = false
 			areaId := convertAreaOrRouterIdUint32(string(key.AreaId))
+			/* Transit capability is owned by the SPF, set again by the V bit of the router LSAs */
+			delete(server.TransitAreaMap, areaId)
 			server.initialiseSPFStructs()
 			areaIdKey := AreaIdKey{
 				AreaId: areaId,
@@ -838,10 +832,16 @@ func (server *OSPFServer) spfCalculation() {
 			server.logger.Info("==============Handling Stub links...====================")
 			server.HandleStubs(vKey, areaId)
 			server.HandleSummaryLsa(areaId)
+			server.updateNssaTranslatorState(key, aEnt, areaId)
 			server.AreaGraph = nil
 			server.AreaStubs = nil
 			server.SPFTree = nil
 		}
+		server.updateVirtualLinks()
+		if server.ospfGlobalConf.AreaBdrRtrStatus == true {
+			server.logger.Info("Examine transit areas, Summary LSA...")
+			server.HandleTransitAreaSummaryLsa()
+		}
 		/*
 			server.dumpRoutingTbl()
 		*/
@@ -868,12 +868,12 @@ func (server *OSPFServer) spfCalculation() {
 		server.TempGlobalRoutingTbl = nil
 		//server.dumpGlobalRoutingTbl()
 		if server.ospfGlobalConf.AreaBdrRtrStatus == true {
-			server.logger.Info("Examine transit areas, Summary LSA...")
-			server.HandleTransitAreaSummaryLsa()
 			server.logger.Info("Generate Summary LSA...")
 			server.GenerateSummaryLsa()
 			server.logger.Info(fmt.Sprintln("========", server.SummaryLsDb, "=========="))
 		}
+		server.GenerateNssaTranslation()
+		server.publishLinkState()
 		server.DoneCalcSPFCh <- true
 	}
 }
@@ -0,0 +1,509 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"net"
+)
+
+type VirtualLinkKey struct {
+	TransitAreaId uint32
+	NbrRtrId      uint32
+}
+
+/*
+Intra area path to the virtual neighbor through the
+transit area, as computed by the last SPF run.
+*/
+type VirtualLinkPath struct {
+	Reachable bool
+	Cost      uint32
+	IfIpAddr  uint32 // Transit area interface address
+	NextHopIP uint32
+	NbrIpAddr uint32 // Virtual neighbor's transit area address
+}
+
+type VirtualLinkEntry struct {
+	IntfKey IntfConfKey
+	Path    VirtualLinkPath
+}
+
+type VirtualLinkStateMsg struct {
+	Key  VirtualLinkKey
+	Path VirtualLinkPath
+}
+
+/*
+Virtual interfaces are kept in IntfConfMap. The key uses the
+neighbor's router id and a negative index derived from the
+transit area id. L3 interfaces have non negative ifindexes.
+*/
+func virtualLinkIntfKey(key VirtualLinkKey) IntfConfKey {
+	return IntfConfKey{
+		IPAddr:  config.IpAddress(convertUint32ToIPv4(key.NbrRtrId)),
+		IntfIdx: config.InterfaceIndexOrZero(-1 - int64(key.TransitAreaId)),
+	}
+}
+
+func virtualLinkTransitAreaId(intfKey IntfConfKey) uint32 {
+	return uint32(-1 - int64(intfKey.IntfIdx))
+}
+
+func (server *OSPFServer) getVirtualLinkKey(conf config.VirtIfConf) (VirtualLinkKey, error) {
+	var key VirtualLinkKey
+	areaId := convertAreaOrRouterId(string(conf.VirtIfAreaId))
+	if areaId == nil {
+		return key, errors.New("Invalid transit area id")
+	}
+	nbrRtrId := convertAreaOrRouterId(string(conf.VirtIfNeighbor))
+	if nbrRtrId == nil {
+		return key, errors.New("Invalid virtual neighbor router id")
+	}
+	key.TransitAreaId = convertIPv4ToUint32(areaId)
+	key.NbrRtrId = convertIPv4ToUint32(nbrRtrId)
+	return key, nil
+}
+
+/*
+@fn processVirtIfConfig
+RFC 2328 15: A virtual link is configured with the transit
+area and the router id of the other endpoint. The virtual
+interface belongs to the backbone and comes up when SPF
+finds an intra area path to the neighbor through the transit area.
+*/
+func (server *OSPFServer) processVirtIfConfig(conf config.VirtIfConf) error {
+	key, err := server.getVirtualLinkKey(conf)
+	if err != nil {
+		return err
+	}
+	if key.TransitAreaId == 0 {
+		return errors.New("Backbone cannot be a transit area")
+	}
+	transitArea := config.AreaId(convertUint32ToIPv4(key.TransitAreaId))
+	if _, exist := server.AreaConfMap[AreaConfKey{AreaId: transitArea}]; !exist {
+		return errors.New("No such transit area exists")
+	}
+	if server.isStubArea(transitArea) || server.isNssaArea(transitArea) {
+		return errors.New("Virtual links cannot be configured through stub or NSSA areas")
+	}
+	authKey := convertAuthKey(conf.VirtIfAuthKey)
+	if authKey == nil {
+		authKey = convertAuthKey("0.0.0.0.0.0.0.0")
+	}
+
+	/* Timers and authentication apply on the next start */
+	server.stopVirtualLink(key)
+	vLink, _ := server.VirtualLinkMap[key]
+
+	intfKey := virtualLinkIntfKey(key)
+	ent, _ := server.IntfConfMap[intfKey]
+	if ent.FSMCtrlCh == nil {
+		ent.FSMCtrlCh = make(chan bool)
+		ent.FSMCtrlStatusCh = make(chan bool)
+		ent.BackupSeenCh = make(chan BackupSeenMsg)
+		ent.NeighCreateCh = make(chan NeighCreateMsg)
+		ent.NeighChangeCh = make(chan NeighChangeMsg)
+		ent.NbrStateChangeCh = make(chan NbrStateChangeMsg)
+		ent.NbrFullStateCh = make(chan NbrFullStateMsg)
+		ent.IfMetricTOSMap = make(map[uint8]uint32)
+	}
+	ent.IfAreaId = []byte{0, 0, 0, 0}
+	ent.IfType = config.VirtualLink
+	ent.IfAdminStat = config.Disabled
+	ent.IfRtrPriority = 0
+	ent.IfTransitDelay = conf.VirtIfTransitDelay
+	ent.IfRetransInterval = conf.VirtIfRetransInterval
+	ent.IfHelloInterval = uint16(conf.VirtIfHelloInterval)
+	ent.IfRtrDeadInterval = uint32(conf.VirtIfRtrDeadInterval)
+	ent.IfAuthKey = authKey
+	ent.IfAuthType = uint16(conf.VirtIfAuthType)
+	ent.IfMulticastForwarding = config.Blocked
+	ent.IfDemand = false
+	ent.WaitTimer = nil
+	ent.HelloIntervalTicker = nil
+	ent.IfNetmask = []byte{0, 0, 0, 0}
+	ent.IfDRIp = []byte{0, 0, 0, 0}
+	ent.IfBDRIp = []byte{0, 0, 0, 0}
+	ent.IfDRtrId = 0
+	ent.IfBDRtrId = 0
+	ent.IfFSMState = config.Down
+	server.IntfConfMap[intfKey] = ent
+
+	vLink.IntfKey = intfKey
+	server.VirtualLinkMutex.Lock()
+	server.VirtualLinkMap[key] = vLink
+	server.VirtualLinkMutex.Unlock()
+	server.logger.Info(fmt.Sprintln("VLINK: Configured virtual link transit area ",
+		transitArea, " nbr ", conf.VirtIfNeighbor))
+
+	if vLink.Path.Reachable {
+		server.startVirtualLink(key)
+	} else if server.ospfGlobalConf.AdminStat == config.Enabled {
+		/* Run SPF to find the path through the transit area */
+		msg := NetworkLSAChangeMsg{
+			areaId:  key.TransitAreaId,
+			intfKey: intfKey,
+		}
+		server.IntfStateChangeCh <- msg
+	}
+	return nil
+}
+
+func (server *OSPFServer) processVirtIfDelete(conf config.VirtIfConf) {
+	key, err := server.getVirtualLinkKey(conf)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("VLINK: Invalid virtual link ", conf, err))
+		return
+	}
+	vLink, exist := server.VirtualLinkMap[key]
+	if !exist {
+		server.logger.Err(fmt.Sprintln("VLINK: No such virtual link exists ", conf))
+		return
+	}
+	server.stopVirtualLink(key)
+	delete(server.IntfConfMap, vLink.IntfKey)
+	server.VirtualLinkMutex.Lock()
+	delete(server.VirtualLinkMap, key)
+	server.VirtualLinkMutex.Unlock()
+}
+
+/*
+@fn startVirtualLink
+The virtual interface uses the transit area interface of the
+path for sending. Its cost is the cost of the path.
+*/
+func (server *OSPFServer) startVirtualLink(key VirtualLinkKey) {
+	vLink, exist := server.VirtualLinkMap[key]
+	if !exist {
+		return
+	}
+	ent, exist := server.IntfConfMap[vLink.IntfKey]
+	if !exist {
+		return
+	}
+	transitKey, transitEnt, found := server.findTransitIntf(key.TransitAreaId, vLink.Path.IfIpAddr)
+	if !found {
+		server.logger.Err(fmt.Sprintln("VLINK: No transit interface for virtual link ", vLink.IntfKey))
+		return
+	}
+	txEntry, exist := server.IntfTxMap[transitKey]
+	if !exist {
+		server.logger.Err(fmt.Sprintln("VLINK: No tx handle for transit interface ", transitKey))
+		return
+	}
+	nextHopKey := NeighborConfKey{
+		IPAddr:  config.IpAddress(convertUint32ToIPv4(vLink.Path.NextHopIP)),
+		IntfIdx: transitKey.IntfIdx,
+	}
+	nextHopMac, exist := ospfNeighborIPToMAC[nextHopKey]
+	if !exist {
+		server.logger.Err(fmt.Sprintln("VLINK: Next hop mac unknown for virtual link ", vLink.IntfKey, nextHopKey))
+		return
+	}
+	server.IntfTxMap[vLink.IntfKey] = txEntry
+	ent.IfName = transitEnt.IfName
+	ent.IfIpAddr = transitEnt.IfIpAddr
+	ent.IfMacAddr = transitEnt.IfMacAddr
+	ent.IfMtu = transitEnt.IfMtu
+	ent.IfCost = vLink.Path.Cost
+	ent.IfVirtNbrIpAddr = net.ParseIP(convertUint32ToIPv4(vLink.Path.NbrIpAddr)).To4()
+	ent.IfVirtNextHopMac = nextHopMac
+	ent.IfAdminStat = config.Enabled
+	server.IntfConfMap[vLink.IntfKey] = ent
+	server.updateIntfToAreaMap(vLink.IntfKey, "none", "0.0.0.0")
+	server.logger.Info(fmt.Sprintln("VLINK: Start virtual link ", vLink.IntfKey, " via ", transitEnt.IfIpAddr))
+	if server.ospfGlobalConf.AdminStat == config.Enabled {
+		server.StartSendRecvPkts(vLink.IntfKey)
+	}
+}
+
+func (server *OSPFServer) stopVirtualLink(key VirtualLinkKey) {
+	vLink, exist := server.VirtualLinkMap[key]
+	if !exist {
+		return
+	}
+	ent, exist := server.IntfConfMap[vLink.IntfKey]
+	if !exist || ent.IfAdminStat != config.Enabled {
+		return
+	}
+	server.logger.Info(fmt.Sprintln("VLINK: Stop virtual link ", vLink.IntfKey))
+	if server.ospfGlobalConf.AdminStat == config.Enabled {
+		server.StopSendRecvPkts(vLink.IntfKey)
+	}
+	ent, _ = server.IntfConfMap[vLink.IntfKey]
+	ent.IfAdminStat = config.Disabled
+	server.IntfConfMap[vLink.IntfKey] = ent
+	server.updateIntfToAreaMap(vLink.IntfKey, "0.0.0.0", "none")
+	delete(server.IntfTxMap, vLink.IntfKey)
+	if server.ospfGlobalConf.AdminStat == config.Enabled {
+		/* Withdraw the virtual link and the V bit of the transit area */
+		for _, areaId := range []uint32{0, key.TransitAreaId} {
+			msg := NetworkLSAChangeMsg{
+				areaId:  areaId,
+				intfKey: vLink.IntfKey,
+			}
+			server.IntfStateChangeCh <- msg
+		}
+	}
+}
+
+func (server *OSPFServer) findTransitIntf(areaId uint32, ipAddr uint32) (IntfConfKey, IntfConf, bool) {
+	for key, ent := range server.IntfConfMap {
+		if ent.IfType == config.VirtualLink ||
+			convertIPv4ToUint32(ent.IfAreaId) != areaId {
+			continue
+		}
+		if convertAreaOrRouterIdUint32(ent.IfIpAddr.String()) == ipAddr {
+			return key, ent, true
+		}
+	}
+	return IntfConfKey{}, IntfConf{}, false
+}
+
+/*
+@fn processVirtualLinkStateMsg
+Path updates from the SPF. A path change restarts the
+virtual link, a cost change only regenerates the router LSA.
+*/
+func (server *OSPFServer) processVirtualLinkStateMsg(msg VirtualLinkStateMsg) {
+	vLink, exist := server.VirtualLinkMap[msg.Key]
+	if !exist || vLink.Path == msg.Path {
+		return
+	}
+	oldPath := vLink.Path
+	vLink.Path = msg.Path
+	server.VirtualLinkMutex.Lock()
+	server.VirtualLinkMap[msg.Key] = vLink
+	server.VirtualLinkMutex.Unlock()
+	server.logger.Info(fmt.Sprintln("VLINK: Path changed for virtual link ", vLink.IntfKey,
+		" old ", oldPath, " new ", msg.Path))
+
+	ent, _ := server.IntfConfMap[vLink.IntfKey]
+	if ent.IfAdminStat == config.Enabled && msg.Path.Reachable &&
+		oldPath.IfIpAddr == msg.Path.IfIpAddr &&
+		oldPath.NextHopIP == msg.Path.NextHopIP &&
+		oldPath.NbrIpAddr == msg.Path.NbrIpAddr {
+		ent.IfCost = msg.Path.Cost
+		server.IntfConfMap[vLink.IntfKey] = ent
+		if nbrMdata, exist := ospfIntfToNbrMap[vLink.IntfKey]; exist &&
+			server.ospfGlobalConf.AdminStat == config.Enabled {
+			server.CreateNetworkLSACh <- nbrMdata
+		}
+		return
+	}
+	server.stopVirtualLink(msg.Key)
+	if msg.Path.Reachable {
+		server.startVirtualLink(msg.Key)
+	}
+}
+
+/*
+@fn updateVirtualLinks
+Called from the SPF after the per area routing tables are
+calculated. RFC 2328 16.1: the virtual link is up when the
+other endpoint is reachable through the transit area.
+VirtualLinkMap is changed only by the main goroutine, the SPF
+reads it under the lock and sends the path changes to it.
+*/
+func (server *OSPFServer) updateVirtualLinks() {
+	msgs := make([]VirtualLinkStateMsg, 0)
+	server.VirtualLinkMutex.RLock()
+	for key, vLink := range server.VirtualLinkMap {
+		path := server.findVirtualLinkPath(key)
+		if path == vLink.Path {
+			continue
+		}
+		msg := VirtualLinkStateMsg{
+			Key:  key,
+			Path: path,
+		}
+		msgs = append(msgs, msg)
+	}
+	server.VirtualLinkMutex.RUnlock()
+	for _, msg := range msgs {
+		server.VirtualLinkStateCh <- msg
+	}
+}
+
+func (server *OSPFServer) findVirtualLinkPath(key VirtualLinkKey) VirtualLinkPath {
+	var path VirtualLinkPath
+	tbl, exist := server.TempAreaRoutingTbl[AreaIdKey{AreaId: key.TransitAreaId}]
+	if !exist || tbl.RoutingTblMap == nil {
+		return path
+	}
+	rEnt, exist := findBdrRtrEntry(tbl, key.NbrRtrId)
+	if !exist || len(rEnt.NextHops) == 0 {
+		return path
+	}
+	/* Pick the lowest next hop so that the path is stable */
+	var nextHop NextHop
+	first := true
+	for nh, _ := range rEnt.NextHops {
+		if first || nh.NextHopIP < nextHop.NextHopIP {
+			nextHop = nh
+			first = false
+		}
+	}
+	nbrIpAddr := server.findVirtualNbrIpAddr(key, nextHop.NextHopIP)
+	if nbrIpAddr == 0 {
+		return path
+	}
+	path.Reachable = true
+	path.Cost = uint32(rEnt.Cost)
+	path.IfIpAddr = nextHop.IfIPAddr
+	path.NextHopIP = nextHop.NextHopIP
+	path.NbrIpAddr = nbrIpAddr
+	return path
+}
+
+/*
+RFC 2328 15: The virtual neighbor's IP address is one of its
+interface addresses in the transit area, taken from its router LSA.
+The address of the next hop is preferred when the neighbor is adjacent.
+*/
+func (server *OSPFServer) findVirtualNbrIpAddr(key VirtualLinkKey, nextHopIP uint32) uint32 {
+	lsDbEnt, exist := server.AreaLsdb[LsdbKey{AreaId: key.TransitAreaId}]
+	if !exist {
+		return 0
+	}
+	lsaKey := LsaKey{
+		LSType:    RouterLSA,
+		LSId:      key.NbrRtrId,
+		AdvRouter: key.NbrRtrId,
+	}
+	lsaEnt, exist := lsDbEnt.RouterLsaMap[lsaKey]
+	if !exist {
+		return 0
+	}
+	var ipAddr uint32
+	for _, link := range lsaEnt.LinkDetails {
+		if link.LinkType != TransitLink && link.LinkType != P2PLink {
+			continue
+		}
+		if link.LinkData == nextHopIP {
+			return link.LinkData
+		}
+		if ipAddr == 0 || link.LinkData < ipAddr {
+			ipAddr = link.LinkData
+		}
+	}
+	return ipAddr
+}
+
+/*
+Next hop of the backbone paths that go over the virtual link.
+*/
+func (server *OSPFServer) virtualLinkNextHop(nbrRtrId uint32) (ifIPAddr uint32, nextHopIP uint32, err error) {
+	server.VirtualLinkMutex.RLock()
+	defer server.VirtualLinkMutex.RUnlock()
+	for key, vLink := range server.VirtualLinkMap {
+		if key.NbrRtrId == nbrRtrId && vLink.Path.Reachable {
+			return vLink.Path.IfIpAddr, vLink.Path.NextHopIP, nil
+		}
+	}
+	err = errors.New("No reachable virtual link to the router")
+	return 0, 0, err
+}
+
+/*
+RFC 2328 A.4.2: V bit is set in the router LSA of the transit
+area when the router is an endpoint of an adjacent virtual link.
+*/
+func (server *OSPFServer) isVirtualLinkTransitArea(areaId uint32) bool {
+	server.VirtualLinkMutex.RLock()
+	defer server.VirtualLinkMutex.RUnlock()
+	for key, vLink := range server.VirtualLinkMap {
+		if key.TransitAreaId != areaId {
+			continue
+		}
+		if nbrData, exist := ospfIntfToNbrMap[vLink.IntfKey]; exist &&
+			len(nbrData.nbrList) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+RFC 2328 8.2: Backbone packets received on a transit area
+interface belong to the virtual link with the sending router.
+*/
+func (server *OSPFServer) virtualLinkRxIntfKey(ospfPkt []byte, key IntfConfKey, ipHdrMd *IpHdrMetadata) IntfConfKey {
+	if len(ospfPkt) < OSPF_HEADER_SIZE || ipHdrMd.dstIPType != Normal {
+		return key
+	}
+	ent, exist := server.IntfConfMap[key]
+	if !exist || ent.IfType == config.VirtualLink {
+		return key
+	}
+	areaId := convertIPv4ToUint32(ent.IfAreaId)
+	if areaId == 0 || binary.BigEndian.Uint32(ospfPkt[8:12]) != 0 {
+		return key
+	}
+	vKey := VirtualLinkKey{
+		TransitAreaId: areaId,
+		NbrRtrId:      binary.BigEndian.Uint32(ospfPkt[4:8]),
+	}
+	server.VirtualLinkMutex.RLock()
+	vLink, exist := server.VirtualLinkMap[vKey]
+	server.VirtualLinkMutex.RUnlock()
+	if !exist {
+		return key
+	}
+	vEnt, exist := server.IntfConfMap[vLink.IntfKey]
+	if !exist || vEnt.IfFSMState != config.P2P {
+		return key
+	}
+	return vLink.IntfKey
+}
+
+/*
+Virtual link packets are unicast to the virtual neighbor
+and routed through the transit area.
+*/
+func (server *OSPFServer) isVirtualLinkDstIP(dstIP net.IP) bool {
+	server.VirtualLinkMutex.RLock()
+	defer server.VirtualLinkMutex.RUnlock()
+	for key, _ := range server.VirtualLinkMap {
+		for _, ent := range server.IntfConfMap {
+			if ent.IfType != config.VirtualLink &&
+				convertIPv4ToUint32(ent.IfAreaId) == key.TransitAreaId &&
+				ent.IfIpAddr.Equal(dstIP) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ospfPktDst(ent IntfConf, dstMAC net.HardwareAddr, dstIp net.IP) (net.HardwareAddr, net.IP, uint8) {
+	if ent.IfType == config.VirtualLink {
+		return ent.IfVirtNextHopMac, ent.IfVirtNbrIpAddr, uint8(VIRTUAL_LINK_TTL)
+	}
+	return dstMAC, dstIp, uint8(1)
+}
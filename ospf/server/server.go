@@ -33,6 +33,7 @@ import (
 	nanomsg "github.com/op/go-nanomsg"
 	"io/ioutil"
 	"l3/ospf/config"
+	"l3/ospf/ospfdCommonDefs"
 	"ribd"
 	"strconv"
 	"sync"
@@ -82,6 +83,8 @@ type OSPFServer struct {
 	AreaConfigCh           chan config.AreaConf
 	IntfConfigCh           chan config.InterfaceConf
 	IfMetricConfCh         chan config.IfMetricConf
+	VirtIfConfigCh         chan config.VirtIfConf
+	VirtIfDeleteCh         chan config.VirtIfConf
 	GlobalConfigRetCh      chan error
 	AreaConfigRetCh        chan error
 	IntfConfigRetCh        chan error
@@ -107,6 +110,7 @@ type OSPFServer struct {
 	asicdSubSocket        *nanomsg.SubSocket
 	asicdSubSocketCh      chan []byte
 	asicdSubSocketErrCh   chan error
+	linkStatePubSocket    *nanomsg.PubSocket
 	AreaConfMap           map[AreaConfKey]AreaConf
 	IntfConfMap           map[IntfConfKey]IntfConf
 	IntfTxMap             map[IntfConfKey]IntfTxHandle
@@ -156,6 +160,14 @@ type OSPFServer struct {
 
 	SummaryLsDb map[LsdbKey]SummaryLsaMap
 
+	NssaTranslatedLsDb map[LsaKey]ASExternalLsa
+	NssaTranslatedLsa  map[LsaKey]bool
+
+	VirtualLinkMap     map[VirtualLinkKey]VirtualLinkEntry
+	VirtualLinkMutex   sync.RWMutex
+	VirtualLinkStateCh chan VirtualLinkStateMsg
+	TransitAreaMap     map[uint32]bool
+
 	StartCalcSPFCh chan bool
 	DoneCalcSPFCh  chan bool
 	AreaGraph      map[VertexKey]Vertex
@@ -176,6 +188,8 @@ func NewOSPFServer(logger *logging.Writer) *OSPFServer {
 	ospfServer.AreaConfigCh = make(chan config.AreaConf)
 	ospfServer.IntfConfigCh = make(chan config.InterfaceConf)
 	ospfServer.IfMetricConfCh = make(chan config.IfMetricConf)
+	ospfServer.VirtIfConfigCh = make(chan config.VirtIfConf)
+	ospfServer.VirtIfDeleteCh = make(chan config.VirtIfConf)
 	ospfServer.GlobalConfigRetCh = make(chan error)
 	ospfServer.AreaConfigRetCh = make(chan error)
 	ospfServer.IntfConfigRetCh = make(chan error)
@@ -189,6 +203,11 @@ func NewOSPFServer(logger *logging.Writer) *OSPFServer {
 	ospfServer.IntfRxMap = make(map[IntfConfKey]IntfRxHandle)
 	ospfServer.AreaLsdb = make(map[LsdbKey]LSDatabase)
 	ospfServer.AreaSelfOrigLsa = make(map[LsdbKey]SelfOrigLsa)
+	ospfServer.NssaTranslatedLsa = make(map[LsaKey]bool)
+	ospfServer.VirtualLinkMap = make(map[VirtualLinkKey]VirtualLinkEntry)
+	ospfServer.VirtualLinkMutex = sync.RWMutex{}
+	ospfServer.VirtualLinkStateCh = make(chan VirtualLinkStateMsg, 10)
+	ospfServer.TransitAreaMap = make(map[uint32]bool)
 	ospfServer.IntfStateChangeCh = make(chan NetworkLSAChangeMsg)
 	ospfServer.NetworkDRChangeCh = make(chan DrChangeMsg)
 	ospfServer.CreateNetworkLSACh = make(chan ospfNbrMdata)
@@ -326,6 +345,7 @@ func (server *OSPFServer) InitServer(paramFile string) {
 	server.logger.Info("Listen for ASICd updates")
 	server.listenForASICdUpdates(asicdCommonDefs.PUB_SOCKET_ADDR)
 	go server.createASICdSubscriber()
+	server.initLinkStatePublisher(ospfdCommonDefs.PUB_SOCKET_LINK_STATE_ADDR)
 
 	server.BuildOspfInfra()
 	err := server.InitializeDB()
@@ -382,6 +402,17 @@ func (server *OSPFServer) StartServer(paramFile string) {
 			if err == nil {
 
 			}
+		case virtIfConf := <-server.VirtIfConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing Virtual Intf Configuration", virtIfConf))
+			err := server.processVirtIfConfig(virtIfConf)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("Virtual Intf Configuration failed", err))
+			}
+		case virtIfConf := <-server.VirtIfDeleteCh:
+			server.logger.Info(fmt.Sprintln("Received call for deleting Virtual Intf Configuration", virtIfConf))
+			server.processVirtIfDelete(virtIfConf)
+		case msg := <-server.VirtualLinkStateCh:
+			server.processVirtualLinkStateMsg(msg)
 		case asicdrxBuf := <-server.asicdSubSocketCh:
 			server.processAsicdNotification(asicdrxBuf)
 		case <-server.asicdSubSocketErrCh:
@@ -147,6 +147,19 @@ struct IPv6RouteState {
 	7 : list<string> PolicyList
 	8 : NextBestRouteInfo NextBestRoute
 }
+struct NextHopGroup {
+	1 : i32 GroupId
+	2 : string Vrf
+	3 : RouteNextHopInfo NextHop
+	4 : RouteNextHopInfo BackupNextHop
+}
+struct NextHopGroupRoute {
+	1 : string DestinationNw
+	2 : string NetworkMask
+	3 : string Protocol
+	4 : string NextHopIp
+	5 : i32 GroupId
+}
 struct ApplyPolicyInfo {
 	1: string Source     
 	2: string Policy     
@@ -167,6 +180,10 @@ service RIBDINTServices
 	int GetTotalv6RouteCount();
 	string Getv4RouteCreatedTime(1:int number);
 	oneway void OnewayCreateBulkIPv4Route(1: list<IPv4RouteConfig> config);
+	oneway void OnewayCreateNextHopGroup(1: NextHopGroup config);
+	oneway void OnewayDeleteNextHopGroup(1: NextHopGroup config);
+	oneway void OnewayAddNextHopGroupRoute(1: NextHopGroupRoute config);
+	oneway void OnewayDeleteNextHopGroupRoute(1: NextHopGroupRoute config);
 	bool CreatePolicyAction(1: PolicyAction config);
 	bool UpdatePolicyAction(1: PolicyAction origconfig, 2: PolicyAction newconfig, 3: list<bool> attrset, 4: list<PatchOpInfo> op);
 	bool DeletePolicyAction(1: PolicyAction config);
@@ -0,0 +1,80 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// ribdNextHopGroupApis.go
+package rpc
+
+import (
+	"l3/rib/server"
+	"ribdInt"
+)
+
+/*
+   OnewayCreate API for next hop group
+*/
+func (m RIBDServicesHandler) OnewayCreateNextHopGroup(cfg *ribdInt.NextHopGroup) (err error) {
+	logger.Info("OnewayCreateNextHopGroup - Received create request for next hop group ", cfg.GroupId)
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "addNextHopGroup",
+	}
+	return err
+}
+
+/*
+   OnewayDelete API for next hop group
+*/
+func (m RIBDServicesHandler) OnewayDeleteNextHopGroup(cfg *ribdInt.NextHopGroup) (err error) {
+	logger.Info("OnewayDeleteNextHopGroup - Received delete request for next hop group ", cfg.GroupId)
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "delNextHopGroup",
+	}
+	return err
+}
+
+/*
+   Oneway API to add a route to the next hop group it is installed with
+*/
+func (m RIBDServicesHandler) OnewayAddNextHopGroupRoute(cfg *ribdInt.NextHopGroupRoute) (err error) {
+	logger.Info("OnewayAddNextHopGroupRoute - Received add request for route ", cfg.DestinationNw, " mask ",
+		cfg.NetworkMask, " to next hop group ", cfg.GroupId)
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "addNextHopGroupRoute",
+	}
+	return err
+}
+
+/*
+   Oneway API to remove a route from its next hop group
+*/
+func (m RIBDServicesHandler) OnewayDeleteNextHopGroupRoute(cfg *ribdInt.NextHopGroupRoute) (err error) {
+	logger.Info("OnewayDeleteNextHopGroupRoute - Received delete request for route ", cfg.DestinationNw, " mask ",
+		cfg.NetworkMask, " from next hop group ", cfg.GroupId)
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "delNextHopGroupRoute",
+	}
+	return err
+}
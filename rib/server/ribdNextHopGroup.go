@@ -0,0 +1,207 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// ribdNextHopGroup.go
+package server
+
+import (
+	"net"
+	"ribd"
+	"ribdInt"
+	"strconv"
+	netutils "utils/netUtils"
+)
+
+/*
+   A next hop group is a next hop with a pre-installed backup next hop (BGP PIC). The routes installed over the
+   next hop are members of the group. When the interface of the next hop goes down the group switches to the
+   backup next hop and reprograms its members from RIBd, without waiting for the protocol to select new paths.
+*/
+type NextHopGroupRouteKey struct {
+	destNetIp   string
+	networkMask string
+	protocol    string
+	nextHopIp   string
+}
+
+type NextHopGroupInfo struct {
+	groupId  int32
+	nextHop  ribdInt.RouteNextHopInfo
+	backup   ribdInt.RouteNextHopInfo
+	onBackup bool
+	routes   map[NextHopGroupRouteKey]bool
+}
+
+/*
+   Returns the next hop the members of the group are installed with
+*/
+func (group *NextHopGroupInfo) activeNextHop() ribdInt.RouteNextHopInfo {
+	if group.onBackup {
+		return group.backup
+	}
+	return group.nextHop
+}
+
+func (m *RIBDServer) ProcessNextHopGroupCreate(cfg *ribdInt.NextHopGroup) {
+	logger.Info("ProcessNextHopGroupCreate: group ", cfg.GroupId, " next hop ", cfg.NextHop, " backup ",
+		cfg.BackupNextHop)
+	if cfg.NextHop == nil || cfg.BackupNextHop == nil {
+		logger.Err("Next hop group ", cfg.GroupId, " without next hop or backup next hop")
+		return
+	}
+	if _, ok := m.NextHopGroupMap[cfg.GroupId]; ok {
+		logger.Err("Next hop group ", cfg.GroupId, " already exists")
+		return
+	}
+	m.NextHopGroupMap[cfg.GroupId] = &NextHopGroupInfo{
+		groupId: cfg.GroupId,
+		nextHop: *cfg.NextHop,
+		backup:  *cfg.BackupNextHop,
+		routes:  make(map[NextHopGroupRouteKey]bool),
+	}
+}
+
+func (m *RIBDServer) ProcessNextHopGroupDelete(cfg *ribdInt.NextHopGroup) {
+	logger.Info("ProcessNextHopGroupDelete: group ", cfg.GroupId)
+	group, ok := m.NextHopGroupMap[cfg.GroupId]
+	if !ok {
+		logger.Err("Next hop group ", cfg.GroupId, " not found")
+		return
+	}
+	for routeKey, _ := range group.routes {
+		delete(m.NextHopGroupRouteMap, routeKey)
+	}
+	delete(m.NextHopGroupMap, cfg.GroupId)
+}
+
+/*
+   Adds the route installed over the next hop to the group, the route is moved out of the group it was using
+   before for the next hop
+*/
+func (m *RIBDServer) ProcessNextHopGroupRouteAdd(cfg *ribdInt.NextHopGroupRoute) {
+	routeKey := NextHopGroupRouteKey{cfg.DestinationNw, cfg.NetworkMask, cfg.Protocol, cfg.NextHopIp}
+	m.ProcessNextHopGroupRouteDelete(cfg)
+	group, ok := m.NextHopGroupMap[cfg.GroupId]
+	if !ok {
+		logger.Err("Next hop group ", cfg.GroupId, " not found for route ", cfg.DestinationNw, ":",
+			cfg.NetworkMask)
+		return
+	}
+	group.routes[routeKey] = true
+	m.NextHopGroupRouteMap[routeKey] = cfg.GroupId
+}
+
+func (m *RIBDServer) ProcessNextHopGroupRouteDelete(cfg *ribdInt.NextHopGroupRoute) {
+	routeKey := NextHopGroupRouteKey{cfg.DestinationNw, cfg.NetworkMask, cfg.Protocol, cfg.NextHopIp}
+	groupId, ok := m.NextHopGroupRouteMap[routeKey]
+	if !ok {
+		return
+	}
+	if group, ok := m.NextHopGroupMap[groupId]; ok {
+		delete(group.routes, routeKey)
+	}
+	delete(m.NextHopGroupRouteMap, routeKey)
+}
+
+/*
+   Returns true if the next hop is reached over the interface
+*/
+func isNextHopOverIntf(nextHop ribdInt.RouteNextHopInfo, ipNet *net.IPNet, ifIndex int32) bool {
+	if ifIndex != -1 && nextHop.NextHopIntRef == strconv.Itoa(int(ifIndex)) {
+		return true
+	}
+	nextHopIp := net.ParseIP(nextHop.NextHopIp)
+	return nextHopIp != nil && ipNet.Contains(nextHopIp)
+}
+
+/*
+   Switches the groups whose next hop is reached over the interface to their backup next hop (intfUp false), or
+   back to their next hop (intfUp true). Returns the groups that switched.
+*/
+func (m *RIBDServer) switchNextHopGroups(ipAddr string, ifIndex int32, intfUp bool) []*NextHopGroupInfo {
+	_, ipNet, err := net.ParseCIDR(ipAddr)
+	if err != nil {
+		return nil
+	}
+	switched := make([]*NextHopGroupInfo, 0)
+	for _, group := range m.NextHopGroupMap {
+		if group.onBackup != intfUp || !isNextHopOverIntf(group.nextHop, ipNet, ifIndex) {
+			continue
+		}
+		if !intfUp && isNextHopOverIntf(group.backup, ipNet, ifIndex) {
+			// The backup next hop is lost with the interface as well
+			continue
+		}
+		group.onBackup = !intfUp
+		switched = append(switched, group)
+	}
+	return switched
+}
+
+/*
+   Installs the member routes of the group over newNextHop and removes oldNextHop from them
+*/
+func (m *RIBDServer) updateNextHopGroupRoutes(group *NextHopGroupInfo,
+	oldNextHop, newNextHop ribdInt.RouteNextHopInfo) {
+	for routeKey, _ := range group.routes {
+		oldNh := ribd.NextHopInfo{NextHopIp: oldNextHop.NextHopIp, NextHopIntRef: oldNextHop.NextHopIntRef}
+		newNh := ribd.NextHopInfo{NextHopIp: newNextHop.NextHopIp, NextHopIntRef: newNextHop.NextHopIntRef}
+		if netutils.IsIPv6Addr(routeKey.destNetIp) {
+			cfg := ribd.IPv6Route{
+				DestinationNw: routeKey.destNetIp,
+				NetworkMask:   routeKey.networkMask,
+				Protocol:      routeKey.protocol,
+			}
+			cfg.NextHop = []*ribd.NextHopInfo{&newNh}
+			m.ProcessV6RouteCreateConfig(&cfg, FIBAndRIB, ribd.Int(len(destNetSlice)))
+			cfg.NextHop = []*ribd.NextHopInfo{&oldNh}
+			m.ProcessV6RouteDeleteConfig(&cfg, FIBAndRIB)
+		} else {
+			cfg := ribd.IPv4Route{
+				DestinationNw: routeKey.destNetIp,
+				NetworkMask:   routeKey.networkMask,
+				Protocol:      routeKey.protocol,
+			}
+			cfg.NextHop = []*ribd.NextHopInfo{&newNh}
+			m.ProcessV4RouteCreateConfig(&cfg, FIBAndRIB, ribd.Int(len(destNetSlice)))
+			cfg.NextHop = []*ribd.NextHopInfo{&oldNh}
+			m.ProcessV4RouteDeleteConfig(&cfg, FIBAndRIB)
+		}
+	}
+}
+
+/*
+   Handles the interface state change for the next hop groups. The switch is done once per group, the member
+   routes are then reprogrammed from the group.
+*/
+func (m *RIBDServer) ProcessNextHopGroupIntfStateChange(ipAddr string, ifIndex int32, intfUp bool) {
+	for _, group := range m.switchNextHopGroups(ipAddr, ifIndex, intfUp) {
+		logger.Info("Next hop group ", group.groupId, " switched to next hop ", group.activeNextHop().NextHopIp,
+			" for ", len(group.routes), " routes")
+		if intfUp {
+			m.updateNextHopGroupRoutes(group, group.backup, group.nextHop)
+		} else {
+			m.updateNextHopGroupRoutes(group, group.nextHop, group.backup)
+		}
+	}
+}
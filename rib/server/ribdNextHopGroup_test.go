@@ -0,0 +1,123 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//       Unless required by applicable law or agreed to in writing, software
+//       distributed under the License is distributed on an "AS IS" BASIS,
+//       WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//       See the License for the specific language governing permissions and
+//       limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"ribdInt"
+	"testing"
+)
+
+func getNextHopGroupTestServer(t *testing.T) *RIBDServer {
+	var err error
+	logger, err = RIBdNewLogger("ribd", "RIBDTEST")
+	if err != nil {
+		t.Fatal("ribdtest: creating logger failed")
+	}
+	return &RIBDServer{
+		NextHopGroupMap:      make(map[int32]*NextHopGroupInfo),
+		NextHopGroupRouteMap: make(map[NextHopGroupRouteKey]int32),
+	}
+}
+
+func TestNextHopGroupRoutes(t *testing.T) {
+	m := getNextHopGroupTestServer(t)
+	for _, groupId := range []int32{1, 2} {
+		m.ProcessNextHopGroupCreate(&ribdInt.NextHopGroup{
+			GroupId:       groupId,
+			NextHop:       &ribdInt.RouteNextHopInfo{NextHopIp: "11.1.10.2", NextHopIntRef: "1"},
+			BackupNextHop: &ribdInt.RouteNextHopInfo{NextHopIp: "21.1.10.2", NextHopIntRef: "2"},
+		})
+	}
+	route := &ribdInt.NextHopGroupRoute{
+		DestinationNw: "50.1.1.0",
+		NetworkMask:   "255.255.255.0",
+		Protocol:      "EBGP",
+		NextHopIp:     "11.1.10.2",
+		GroupId:       1,
+	}
+	m.ProcessNextHopGroupRouteAdd(route)
+	if len(m.NextHopGroupMap[1].routes) != 1 {
+		t.Fatal("Route not added to next hop group 1")
+	}
+
+	// The route moves to group 2
+	route.GroupId = 2
+	m.ProcessNextHopGroupRouteAdd(route)
+	if len(m.NextHopGroupMap[1].routes) != 0 || len(m.NextHopGroupMap[2].routes) != 1 {
+		t.Fatal("Route not moved from next hop group 1 to group 2")
+	}
+
+	m.ProcessNextHopGroupRouteDelete(route)
+	if len(m.NextHopGroupMap[2].routes) != 0 || len(m.NextHopGroupRouteMap) != 0 {
+		t.Fatal("Route not deleted from next hop group 2")
+	}
+
+	m.ProcessNextHopGroupRouteAdd(route)
+	m.ProcessNextHopGroupDelete(&ribdInt.NextHopGroup{GroupId: 2})
+	if _, ok := m.NextHopGroupMap[2]; ok || len(m.NextHopGroupRouteMap) != 0 {
+		t.Fatal("Next hop group 2 or its routes not deleted")
+	}
+}
+
+func TestSwitchNextHopGroups(t *testing.T) {
+	m := getNextHopGroupTestServer(t)
+	m.ProcessNextHopGroupCreate(&ribdInt.NextHopGroup{
+		GroupId:       1,
+		NextHop:       &ribdInt.RouteNextHopInfo{NextHopIp: "11.1.10.2", NextHopIntRef: "1"},
+		BackupNextHop: &ribdInt.RouteNextHopInfo{NextHopIp: "21.1.10.2", NextHopIntRef: "2"},
+	})
+	m.ProcessNextHopGroupCreate(&ribdInt.NextHopGroup{
+		GroupId:       2,
+		NextHop:       &ribdInt.RouteNextHopInfo{NextHopIp: "31.1.10.2", NextHopIntRef: "3"},
+		BackupNextHop: &ribdInt.RouteNextHopInfo{NextHopIp: "21.1.10.2", NextHopIntRef: "2"},
+	})
+
+	// Group 1 switches to the backup next hop when the interface of its next hop goes down
+	switched := m.switchNextHopGroups("11.1.10.1/24", 1, false)
+	if len(switched) != 1 || switched[0].groupId != 1 {
+		t.Fatal("Expected next hop group 1 to switch to the backup next hop, switched", switched)
+	}
+	if nextHop := m.NextHopGroupMap[1].activeNextHop(); nextHop.NextHopIp != "21.1.10.2" {
+		t.Error("Expected next hop group 1 to use the backup next hop, found", nextHop.NextHopIp)
+	}
+	if m.NextHopGroupMap[2].onBackup {
+		t.Error("Next hop group 2 switched to the backup next hop")
+	}
+
+	// The group switches once
+	if switched = m.switchNextHopGroups("11.1.10.1/24", 1, false); len(switched) != 0 {
+		t.Error("Expected no next hop group to switch again, switched", switched)
+	}
+
+	// The groups don't switch when the backup next hop goes down too
+	if switched = m.switchNextHopGroups("21.1.10.1/24", 2, false); len(switched) != 0 {
+		t.Error("Expected no next hop group to switch when the backup interface goes down, switched", switched)
+	}
+
+	// Group 1 switches back to its next hop when the interface comes up
+	switched = m.switchNextHopGroups("11.1.10.1/24", 1, true)
+	if len(switched) != 1 || switched[0].groupId != 1 || m.NextHopGroupMap[1].onBackup {
+		t.Fatal("Expected next hop group 1 to switch back to its next hop, switched", switched)
+	}
+}
@@ -27,6 +27,7 @@ package server
 import (
 	"l3/rib/ribdCommonDefs"
 	"ribd"
+	"ribdInt"
 	"strconv"
 )
 
@@ -358,6 +359,19 @@ func (ribdServiceHandler *RIBDServer) StartRouteProcessServer() {
 				} else {
 					ribdServiceHandler.Processv6RoutePatchUpdateConfig(routeConf.OrigConfigObject.(*ribd.IPv6Route), routeConf.NewConfigObject.(*ribd.IPv6Route), routeConf.PatchOp)
 				}
+			} else if routeConf.Op == "addNextHopGroup" {
+				ribdServiceHandler.ProcessNextHopGroupCreate(routeConf.OrigConfigObject.(*ribdInt.NextHopGroup))
+			} else if routeConf.Op == "delNextHopGroup" {
+				ribdServiceHandler.ProcessNextHopGroupDelete(routeConf.OrigConfigObject.(*ribdInt.NextHopGroup))
+			} else if routeConf.Op == "addNextHopGroupRoute" {
+				ribdServiceHandler.ProcessNextHopGroupRouteAdd(routeConf.OrigConfigObject.(*ribdInt.NextHopGroupRoute))
+			} else if routeConf.Op == "delNextHopGroupRoute" {
+				ribdServiceHandler.ProcessNextHopGroupRouteDelete(routeConf.OrigConfigObject.(*ribdInt.NextHopGroupRoute))
+			} else if routeConf.Op == "nextHopGroupIntfDown" {
+				//switch the next hop groups over the interface to their backup next hop
+				ribdServiceHandler.ProcessNextHopGroupIntfStateChange(routeConf.OrigConfigObject.(string), routeConf.AdditionalParams.(int32), false)
+			} else if routeConf.Op == "nextHopGroupIntfUp" {
+				ribdServiceHandler.ProcessNextHopGroupIntfStateChange(routeConf.OrigConfigObject.(string), routeConf.AdditionalParams.(int32), true)
 			}
 		}
 	}
@@ -81,6 +81,8 @@ type RIBDServer struct {
 	ArpdRouteCh          chan RIBdServerConfig
 	NotificationChannel  chan NotificationMsg
 	NextHopInfoMap       map[NextHopInfoKey]NextHopInfo
+	NextHopGroupMap      map[int32]*NextHopGroupInfo
+	NextHopGroupRouteMap map[NextHopGroupRouteKey]int32
 	/*PolicyConditionConfCh  chan RIBdServerConfig
 	PolicyActionConfCh     chan RIBdServerConfig
 	PolicyStmtConfCh       chan RIBdServerConfig*/
@@ -186,6 +188,11 @@ func (ribdServiceHandler *RIBDServer) ProcessIPv4IntfDownEvent(ipAddr string, if
 		OrigConfigObject: &cfg,
 		Op:               "delFIBOnly",
 	}
+	ribdServiceHandler.RouteConfCh <- RIBdServerConfig{
+		OrigConfigObject: ipAddr,
+		Op:               "nextHopGroupIntfDown",
+		AdditionalParams: ifIndex,
+	}
 	/*	for i := 0; i < len(ConnectedRoutes); i++ {
 		if ConnectedRoutes[i].Ipaddr == ipAddrStr && ConnectedRoutes[i].Mask == ipMaskStr {
 			if ifIndex != -1 && ConnectedRoutes[i].IfIndex != ribdInt.Int(ifIndex) {
@@ -240,6 +247,11 @@ func (ribdServiceHandler *RIBDServer) ProcessIPv6IntfDownEvent(ipAddr string, if
 		OrigConfigObject: &cfg,
 		Op:               "delv6FIBOnly",
 	}
+	ribdServiceHandler.RouteConfCh <- RIBdServerConfig{
+		OrigConfigObject: ipAddr,
+		Op:               "nextHopGroupIntfDown",
+		AdditionalParams: ifIndex,
+	}
 	/*	for i := 0; i < len(ConnectedRoutes); i++ {
 		if ConnectedRoutes[i].Ipaddr == ipAddrStr && ConnectedRoutes[i].Mask == ipMaskStr {
 			if ifIndex != -1 && ConnectedRoutes[i].IfIndex != ribdInt.Int(ifIndex) {
@@ -314,6 +326,11 @@ func (ribdServiceHandler *RIBDServer) ProcessIPv4IntfUpEvent(ipAddr string, ifIn
 			}
 		}
 	}
+	ribdServiceHandler.RouteConfCh <- RIBdServerConfig{
+		OrigConfigObject: ipAddr,
+		Op:               "nextHopGroupIntfUp",
+		AdditionalParams: ifIndex,
+	}
 }
 func (ribdServiceHandler *RIBDServer) ProcessIPv6IntfUpEvent(ipAddr string, ifIndex int32) {
 	logger.Debug("processIPv6IntfUpEvent")
@@ -359,6 +376,11 @@ func (ribdServiceHandler *RIBDServer) ProcessIPv6IntfUpEvent(ipAddr string, ifIn
 			}
 		}
 	}
+	ribdServiceHandler.RouteConfCh <- RIBdServerConfig{
+		OrigConfigObject: ipAddr,
+		Op:               "nextHopGroupIntfUp",
+		AdditionalParams: ifIndex,
+	}
 }
 
 func getLogicalIntfInfo() {
@@ -561,6 +583,8 @@ func NewRIBDServicesHandler(dbHdl *dbutils.DBUtil, loggerC *logging.Writer) *RIB
 	ProtocolAdminDistanceMapDB = make(map[string]RouteDistanceConfig)
 	PublisherInfoMap = make(map[string]PublisherMapInfo)
 	ribdServicesHandler.NextHopInfoMap = make(map[NextHopInfoKey]NextHopInfo)
+	ribdServicesHandler.NextHopGroupMap = make(map[int32]*NextHopGroupInfo)
+	ribdServicesHandler.NextHopGroupRouteMap = make(map[NextHopGroupRouteKey]int32)
 	ribdServicesHandler.TrackReachabilityCh = make(chan TrackReachabilityInfo, 1000)
 	ribdServicesHandler.RouteConfCh = make(chan RIBdServerConfig, 100000)
 	ribdServicesHandler.AsicdRouteCh = make(chan RIBdServerConfig, 100000)
@@ -141,6 +141,9 @@ func (intf VXLANSnapClient) ConnectToClients(clientFile string) {
 	go intf.createRIBdSubscriber()
 	// need to listen for por vlan membership notifications
 	go intf.createASICdSubscriber()
+	// need to publish local vni/mac to and listen for remote vtep/mac from bgpd
+	intf.createEVPNPublisher()
+	go intf.createBGPdSubscriber()
 }
 
 func asicDGetLoopbackInfo() (success bool, lbname string, mac net.HardwareAddr, ip net.IP) {
@@ -0,0 +1,183 @@
+// vxlanBgpd.go
+package snapclient
+
+import (
+	"encoding/json"
+	"fmt"
+	nanomsg "github.com/op/go-nanomsg"
+	vxlan "l3/tunnel/vxlan/protocol"
+	"l3/tunnel/vxlan/vxlandCommonDefs"
+	"net"
+)
+
+// publisher socket for the local VNIs and MACs advertised by bgpd using EVPN
+var evpnPubSocket *nanomsg.PubSocket
+
+func (intf VXLANSnapClient) createEVPNPublisher() error {
+	address := vxlandCommonDefs.PUB_SOCKET_EVPN_ADDR
+	socket, err := nanomsg.NewPubSocket()
+	if err != nil {
+		logger.Err(fmt.Sprintln("Failed to create EVPN publisher socket, error:", err))
+		return err
+	}
+
+	if _, err = socket.Bind(address); err != nil {
+		logger.Err(fmt.Sprintln("Failed to bind EVPN publisher socket, address:", address, "error:", err))
+		return err
+	}
+
+	if err = socket.SetSendBuffer(1024 * 1024); err != nil {
+		logger.Err(fmt.Sprintln("Failed to set the buffer size for EVPN publisher socket, error:", err))
+		return err
+	}
+	logger.Info(fmt.Sprintln("Bound EVPN publisher at address:", address))
+	evpnPubSocket = socket
+	return nil
+}
+
+func (intf VXLANSnapClient) createBGPdSubscriber() error {
+	logger.Info("Listen for BGPd EVPN updates")
+	address := vxlandCommonDefs.PUB_SOCKET_BGPD_EVPN_ADDR
+	var err error
+	if intf.bgpdSubSocket, err = nanomsg.NewSubSocket(); err != nil {
+		logger.Err(fmt.Sprintln("Failed to create BGPd subscribe socket, error:", err))
+		return err
+	}
+
+	if _, err = intf.bgpdSubSocket.Connect(address); err != nil {
+		logger.Err(fmt.Sprintln("Failed to connect to BGPd publisher socket, address:", address, "error:", err))
+		return err
+	}
+
+	if err = intf.bgpdSubSocket.Subscribe(""); err != nil {
+		logger.Err(fmt.Sprintln("Failed to subscribe to \"\" on BGPd subscribe socket, error:", err))
+		return err
+	}
+
+	logger.Info(fmt.Sprintln("Connected to BGPd publisher at address:", address))
+	if err = intf.bgpdSubSocket.SetRecvBuffer(1024 * 1024); err != nil {
+		logger.Err(fmt.Sprintln("Failed to set the buffer size for BGPd publisher socket, error:", err))
+		return err
+	}
+	for {
+		rxBuf, err := intf.bgpdSubSocket.Recv(0)
+		if err != nil {
+			logger.Err(fmt.Sprintln("Recv on BGPd subscriber socket failed with error:", err))
+			intf.bgpdSubSocketErrCh <- err
+			continue
+		}
+		intf.bgpdSubSocketCh <- rxBuf
+	}
+	return nil
+}
+
+func (intf VXLANSnapClient) processBgpdNotification(rxBuf []byte) error {
+	var msg vxlandCommonDefs.VxlandNotifyMsg
+	err := json.Unmarshal(rxBuf, &msg)
+	if err != nil {
+		logger.Err(fmt.Sprintln("Unable to unmarshal rxBuf:", rxBuf))
+		return err
+	}
+	switch msg.MsgType {
+	case vxlandCommonDefs.NOTIFY_EVPN_REMOTE_VTEP_CREATE, vxlandCommonDefs.NOTIFY_EVPN_REMOTE_VTEP_DELETE:
+		var msgInfo vxlandCommonDefs.EVPNVniInfo
+		err = json.Unmarshal(msg.MsgBuf, &msgInfo)
+		if err != nil {
+			logger.Err(fmt.Sprintln("Unable to unmarshal msg:", msg.MsgBuf))
+			return err
+		}
+		command := vxlan.VxlanCommandCreate
+		if msg.MsgType == vxlandCommonDefs.NOTIFY_EVPN_REMOTE_VTEP_DELETE {
+			command = vxlan.VxlanCommandDelete
+		}
+		logger.Info(fmt.Sprintln("Received EVPN remote vtep", msgInfo.VtepIp, "vni", msgInfo.Vni, "command", command))
+		serverchannels.VxlanEVPNRemoteVtep <- vxlan.EVPNVtepInfo{
+			Command: command,
+			Vni:     msgInfo.Vni,
+			VtepIp:  net.ParseIP(msgInfo.VtepIp),
+		}
+
+	case vxlandCommonDefs.NOTIFY_EVPN_REMOTE_MAC_CREATE, vxlandCommonDefs.NOTIFY_EVPN_REMOTE_MAC_DELETE:
+		var msgInfo vxlandCommonDefs.EVPNMacInfo
+		err = json.Unmarshal(msg.MsgBuf, &msgInfo)
+		if err != nil {
+			logger.Err(fmt.Sprintln("Unable to unmarshal msg:", msg.MsgBuf))
+			return err
+		}
+		mac, err := net.ParseMAC(msgInfo.Mac)
+		if err != nil {
+			logger.Err(fmt.Sprintln("Received invalid EVPN remote mac", msgInfo.Mac))
+			return err
+		}
+		command := vxlan.VxlanCommandCreate
+		if msg.MsgType == vxlandCommonDefs.NOTIFY_EVPN_REMOTE_MAC_DELETE {
+			command = vxlan.VxlanCommandDelete
+		}
+		logger.Info(fmt.Sprintln("Received EVPN remote mac", msgInfo.Mac, "vni", msgInfo.Vni, "command", command))
+		serverchannels.VxlanEVPNRemoteMac <- vxlan.EVPNMacInfo{
+			Command: command,
+			Vni:     msgInfo.Vni,
+			Mac:     mac,
+			Ip:      net.ParseIP(msgInfo.Ip),
+			VtepIp:  net.ParseIP(msgInfo.VtepIp),
+		}
+	}
+	return nil
+}
+
+func publishEVPNMsg(msgType uint16, info interface{}) {
+	if evpnPubSocket == nil {
+		return
+	}
+
+	msgBuf, err := json.Marshal(info)
+	if err != nil {
+		logger.Err(fmt.Sprintln("Failed to marshal EVPN info", info, "error:", err))
+		return
+	}
+
+	buf, err := json.Marshal(vxlandCommonDefs.VxlandNotifyMsg{MsgType: msgType, MsgBuf: msgBuf})
+	if err != nil {
+		logger.Err(fmt.Sprintln("Failed to marshal EVPN msg", msgType, "error:", err))
+		return
+	}
+
+	if _, err = evpnPubSocket.Send(buf, nanomsg.DontWait); err != nil {
+		logger.Err(fmt.Sprintln("Failed to publish EVPN msg", msgType, "error:", err))
+	}
+}
+
+func ipToStr(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// LocalVniUpdate:
+// advertise the local vni to bgpd
+func (intf VXLANSnapClient) LocalVniUpdate(vni *vxlan.EVPNVtepInfo) {
+	msgType := vxlandCommonDefs.NOTIFY_EVPN_LOCAL_VNI_CREATE
+	if vni.Command == vxlan.VxlanCommandDelete {
+		msgType = vxlandCommonDefs.NOTIFY_EVPN_LOCAL_VNI_DELETE
+	}
+	publishEVPNMsg(msgType, vxlandCommonDefs.EVPNVniInfo{
+		Vni:    vni.Vni,
+		VtepIp: ipToStr(vni.VtepIp),
+	})
+}
+
+// LocalMacUpdate:
+// advertise the local mac to bgpd
+func (intf VXLANSnapClient) LocalMacUpdate(mac *vxlan.EVPNMacInfo) {
+	msgType := vxlandCommonDefs.NOTIFY_EVPN_LOCAL_MAC_CREATE
+	if mac.Command == vxlan.VxlanCommandDelete {
+		msgType = vxlandCommonDefs.NOTIFY_EVPN_LOCAL_MAC_DELETE
+	}
+	publishEVPNMsg(msgType, vxlandCommonDefs.EVPNMacInfo{
+		Vni:    mac.Vni,
+		Mac:    mac.Mac.String(),
+		Ip:     ipToStr(mac.Ip),
+		VtepIp: ipToStr(mac.VtepIp),
+	})
+}
@@ -64,6 +64,7 @@ func main() {
 	// which client interface to use
 	client := snapclient.NewVXLANSnapClient(logger)
 	vxlan.RegisterClients(*client)
+	vxlan.RegisterEVPNClients(*client)
 
 	// create a new vxlan server
 	server := vxlan.NewVXLANServer(logger, path)
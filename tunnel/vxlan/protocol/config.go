@@ -29,6 +29,7 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"time"
 	//"strings"
 	"errors"
 	"vxland"
@@ -51,6 +52,9 @@ type VxLanConfigChannels struct {
 	VxlanNextHopUpdate        chan VxlanNextHopIp
 	VxlanPortCreate           chan PortConfig
 	Vxlanintfinfo             chan VxlanIntfInfo
+	VxlanEVPNRemoteVtep       chan EVPNVtepInfo
+	VxlanEVPNRemoteMac        chan EVPNMacInfo
+	VxlanEVPNLocalMac         chan EVPNMacInfo
 }
 
 type VxlanIntfInfo struct {
@@ -309,6 +313,7 @@ func (s *VXLANServer) updateThriftVtep(c *VtepUpdate) {
 func (s *VXLANServer) ConfigListener() {
 
 	go func(cc *VxLanConfigChannels) {
+		evpnAgingTicker := time.NewTicker(evpnLocalMacAgingInterval)
 		for {
 			select {
 
@@ -363,6 +368,29 @@ func (s *VXLANServer) ConfigListener() {
                                         logger.Info("Saving Port Config to db", *portcfg)
 					PortConfigMap[port.IfIndex] = portcfg
 				}
+			case vtepinfo := <-cc.VxlanEVPNRemoteVtep:
+				// remote vtep learned from EVPN
+				if vtepinfo.Command == VxlanCommandCreate {
+					CreateEVPNRemoteVtep(&vtepinfo)
+				} else if vtepinfo.Command == VxlanCommandDelete {
+					DeleteEVPNRemoteVtep(&vtepinfo)
+				}
+
+			case macinfo := <-cc.VxlanEVPNRemoteMac:
+				// remote mac learned from EVPN
+				if macinfo.Command == VxlanCommandCreate {
+					CreateEVPNRemoteMac(&macinfo)
+				} else if macinfo.Command == VxlanCommandDelete {
+					DeleteEVPNRemoteMac(&macinfo)
+				}
+
+			case macinfo := <-cc.VxlanEVPNLocalMac:
+				// local mac seen by a vtep listener
+				refreshEVPNLocalMac(&macinfo)
+
+			case <-evpnAgingTicker.C:
+				ageEVPNLocalMacs()
+
 			case intfinfo := <-cc.Vxlanintfinfo:
 				for _, vtep := range GetVtepDB() {
 					logger.Info(fmt.Sprintln("received intf info", intfinfo, vtep))
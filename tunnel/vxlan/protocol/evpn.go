@@ -0,0 +1,320 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// evpn.go
+// EVPN integration, local VNIs and MACs are advertised by the EVPN clients
+// and the remote VTEPs and MACs learned by them are programmed here
+package vxlan
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+type EVPNVtepInfo struct {
+	Command int
+	Vni     uint32
+	VtepIp  net.IP
+}
+
+type EVPNMacInfo struct {
+	Command int
+	Vni     uint32
+	Mac     net.HardwareAddr
+	Ip      net.IP
+	VtepIp  net.IP
+}
+
+// VXLANEVPNClientIntf:
+// Clients which advertise the local VNIs and MACs using EVPN
+type VXLANEVPNClientIntf interface {
+	LocalVniUpdate(vni *EVPNVtepInfo)
+	LocalMacUpdate(mac *EVPNMacInfo)
+}
+
+type evpnMacKey struct {
+	vni uint32
+	mac string
+}
+
+type evpnVtepKey struct {
+	vni uint32
+	ip  string
+}
+
+const (
+	// local macs not seen for the aging time are withdrawn
+	evpnLocalMacAgingTime = 300 * time.Second
+	// a vtep listener reports a local mac again after the refresh time
+	// to keep it from aging out
+	evpnLocalMacRefreshTime = evpnLocalMacAgingTime / 3
+	// interval at which the local macs are aged
+	evpnLocalMacAgingInterval = 30 * time.Second
+)
+
+var EVPNClientIntf []VXLANEVPNClientIntf
+
+// vni + remote mac to remote vtep ip. The map is only updated by the config
+// listener, the vtep listeners read the snapshot stored in evpnRemoteMacs
+// for every frame without taking a lock
+var evpnRemoteMacDB map[evpnMacKey]net.IP
+var evpnRemoteMacs atomic.Value
+
+// vni + local mac advertised to the EVPN clients to the last time it was
+// seen, owned by the config listener
+var evpnLocalMacDB map[evpnMacKey]time.Time
+
+// vni + remote vtep ip to the vtep name created for it
+var evpnRemoteVtepDB map[evpnVtepKey]string
+var evpnRemoteVtepId uint32
+
+// RegisterEVPNClients:
+// Register a client which will be notified of the local VNIs and MACs
+func RegisterEVPNClients(intf VXLANEVPNClientIntf) {
+	logger.Info(fmt.Sprintf("VXLAN Registering EVPN client interface %#v", intf))
+	if EVPNClientIntf == nil {
+		EVPNClientIntf = make([]VXLANEVPNClientIntf, 0)
+	}
+	EVPNClientIntf = append(EVPNClientIntf, intf)
+}
+
+func notifyEVPNLocalVni(command int, vni uint32) {
+	info := &EVPNVtepInfo{
+		Command: command,
+		Vni:     vni,
+		VtepIp:  VxlanVtepSrcIp,
+	}
+	for _, client := range EVPNClientIntf {
+		client.LocalVniUpdate(info)
+	}
+}
+
+func notifyEVPNLocalMac(command int, vni uint32, mac net.HardwareAddr) {
+	info := &EVPNMacInfo{
+		Command: command,
+		Vni:     vni,
+		Mac:     mac,
+		VtepIp:  VxlanVtepSrcIp,
+	}
+	for _, client := range EVPNClientIntf {
+		client.LocalMacUpdate(info)
+	}
+}
+
+// LearnEVPNLocalMac:
+// Called by the vtep listeners when a source mac is seen for the first time
+// or after the refresh time. The mac is handed to the config listener, the
+// frame is never held up, the mac is reported again at the next refresh
+// if the channel is full
+func LearnEVPNLocalMac(vni uint32, mac net.HardwareAddr) {
+	if len(EVPNClientIntf) == 0 || VxlanServer == nil || mac == nil || mac[0]&0x01 != 0 {
+		return
+	}
+
+	info := EVPNMacInfo{
+		Command: VxlanCommandCreate,
+		Vni:     vni,
+		Mac:     append(net.HardwareAddr(nil), mac...),
+	}
+	select {
+	case VxlanServer.Configchans.VxlanEVPNLocalMac <- info:
+	default:
+	}
+}
+
+// refreshEVPNLocalMac:
+// A new source mac is advertised to the EVPN clients unless it was learned
+// from a remote vtep, a known one is kept from aging out
+func refreshEVPNLocalMac(c *EVPNMacInfo) {
+	if GetEVPNRemoteMacVtep(c.Vni, c.Mac) != nil {
+		return
+	}
+
+	key := evpnMacKey{
+		vni: c.Vni,
+		mac: c.Mac.String(),
+	}
+	_, ok := evpnLocalMacDB[key]
+	evpnLocalMacDB[key] = time.Now()
+	if ok {
+		return
+	}
+
+	logger.Info(fmt.Sprintln("EVPN learned local mac", c.Mac, "vni", c.Vni))
+	notifyEVPNLocalMac(VxlanCommandCreate, c.Vni, c.Mac)
+}
+
+// ageEVPNLocalMacs:
+// Withdraw the local macs which were not seen for the aging time
+func ageEVPNLocalMacs() {
+	now := time.Now()
+	for key, lastSeen := range evpnLocalMacDB {
+		if now.Sub(lastSeen) < evpnLocalMacAgingTime {
+			continue
+		}
+		delete(evpnLocalMacDB, key)
+		mac, _ := net.ParseMAC(key.mac)
+		logger.Info(fmt.Sprintln("EVPN aged out local mac", mac, "vni", key.vni))
+		notifyEVPNLocalMac(VxlanCommandDelete, key.vni, mac)
+	}
+}
+
+// flushEVPNLocalMacs:
+// Withdraw all the local macs learned for the vni
+func flushEVPNLocalMacs(vni uint32) {
+	for key := range evpnLocalMacDB {
+		if key.vni == vni {
+			mac, _ := net.ParseMAC(key.mac)
+			delete(evpnLocalMacDB, key)
+			notifyEVPNLocalMac(VxlanCommandDelete, vni, mac)
+		}
+	}
+}
+
+// GetEVPNRemoteMacVtep:
+// returns the remote vtep ip the mac was learned from
+func GetEVPNRemoteMacVtep(vni uint32, mac net.HardwareAddr) net.IP {
+	remoteMacs, _ := evpnRemoteMacs.Load().(map[evpnMacKey]net.IP)
+	key := evpnMacKey{
+		vni: vni,
+		mac: mac.String(),
+	}
+	if ip, ok := remoteMacs[key]; ok {
+		return ip
+	}
+	return nil
+}
+
+// storeEVPNRemoteMacs:
+// Publish a copy of the remote mac db to the vtep listeners
+func storeEVPNRemoteMacs() {
+	remoteMacs := make(map[evpnMacKey]net.IP, len(evpnRemoteMacDB))
+	for key, ip := range evpnRemoteMacDB {
+		remoteMacs[key] = ip
+	}
+	evpnRemoteMacs.Store(remoteMacs)
+}
+
+// CreateEVPNRemoteMac:
+// Program the remote mac learned from EVPN, known unicast frames to the mac
+// are only sent to the vtep of the remote vtep ip
+func CreateEVPNRemoteMac(c *EVPNMacInfo) {
+	key := evpnMacKey{
+		vni: c.Vni,
+		mac: c.Mac.String(),
+	}
+	evpnRemoteMacDB[key] = c.VtepIp
+	storeEVPNRemoteMacs()
+	logger.Info(fmt.Sprintln("EVPN remote mac", c.Mac, "vni", c.Vni, "vtep", c.VtepIp))
+
+	// the mac moved behind the remote vtep
+	if _, ok := evpnLocalMacDB[key]; ok {
+		delete(evpnLocalMacDB, key)
+		notifyEVPNLocalMac(VxlanCommandDelete, c.Vni, c.Mac)
+	}
+}
+
+// DeleteEVPNRemoteMac:
+// Remove the remote mac learned from EVPN, frames to the mac are flooded
+// to all the vteps of the vni again
+func DeleteEVPNRemoteMac(c *EVPNMacInfo) {
+	key := evpnMacKey{
+		vni: c.Vni,
+		mac: c.Mac.String(),
+	}
+	delete(evpnRemoteMacDB, key)
+	storeEVPNRemoteMacs()
+	logger.Info(fmt.Sprintln("EVPN remove remote mac", c.Mac, "vni", c.Vni))
+}
+
+// isEVPNForwardedToVtep:
+// A frame to a unicast mac learned from EVPN is only sent to the vtep of
+// the remote vtep ip the mac is behind, other frames are flooded
+func isEVPNForwardedToVtep(vni uint32, dstMac net.HardwareAddr, vtepIp net.IP) bool {
+	if len(dstMac) == 0 || dstMac[0]&0x01 != 0 {
+		return true
+	}
+	if ip := GetEVPNRemoteMacVtep(vni, dstMac); ip != nil {
+		return ip.Equal(vtepIp)
+	}
+	return true
+}
+
+// CreateEVPNRemoteVtep:
+// Create a vtep towards the remote vtep ip, the tunnel source info is
+// taken from a locally configured vtep in the same vni
+func CreateEVPNRemoteVtep(c *EVPNVtepInfo) {
+	key := evpnVtepKey{
+		vni: c.Vni,
+		ip:  c.VtepIp.String(),
+	}
+	if _, ok := evpnRemoteVtepDB[key]; ok {
+		return
+	}
+
+	var local *VtepDbEntry
+	for _, vtep := range GetVtepDB() {
+		if vtep.Vni == c.Vni {
+			local = vtep
+			break
+		}
+	}
+	if local == nil {
+		logger.Info(fmt.Sprintln("EVPN no local vtep found for vni", c.Vni, "can't create remote vtep", c.VtepIp))
+		return
+	}
+
+	evpnRemoteVtepId++
+	cfg := &VtepConfig{
+		Vni:          c.Vni,
+		VtepName:     fmt.Sprintf("%s.%d", local.VtepName, evpnRemoteVtepId),
+		SrcIfName:    local.SrcIfName,
+		UDP:          local.UDP,
+		TTL:          local.TTL,
+		TunnelSrcIp:  local.SrcIp,
+		TunnelDstIp:  c.VtepIp,
+		VlanId:       local.VlanId,
+		TunnelSrcMac: local.SrcMac,
+	}
+	evpnRemoteVtepDB[key] = cfg.VtepName
+	logger.Info(fmt.Sprintln("EVPN create remote vtep", cfg.VtepName, "vni", c.Vni, "dst", c.VtepIp))
+	CreateVtep(cfg)
+}
+
+// DeleteEVPNRemoteVtep:
+// Delete the vtep created for the remote vtep ip
+func DeleteEVPNRemoteVtep(c *EVPNVtepInfo) {
+	key := evpnVtepKey{
+		vni: c.Vni,
+		ip:  c.VtepIp.String(),
+	}
+	if name, ok := evpnRemoteVtepDB[key]; ok {
+		logger.Info(fmt.Sprintln("EVPN delete remote vtep", name, "vni", c.Vni, "dst", c.VtepIp))
+		DeleteVtep(&VtepConfig{
+			VtepName: name,
+		})
+		delete(evpnRemoteVtepDB, key)
+	}
+}
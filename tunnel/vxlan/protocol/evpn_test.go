@@ -0,0 +1,94 @@
+// evpn_test.go
+package vxlan
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+type mockEVPNClient struct {
+	macs map[string]int
+}
+
+func (c *mockEVPNClient) LocalVniUpdate(vni *EVPNVtepInfo) {
+}
+
+func (c *mockEVPNClient) LocalMacUpdate(mac *EVPNMacInfo) {
+	c.macs[mac.Mac.String()] = mac.Command
+}
+
+func setupEVPNTest() *mockEVPNClient {
+	setVxlanTestLogger()
+	client := &mockEVPNClient{macs: make(map[string]int)}
+	EVPNClientIntf = []VXLANEVPNClientIntf{client}
+	evpnLocalMacDB = make(map[evpnMacKey]time.Time)
+	evpnRemoteMacDB = make(map[evpnMacKey]net.IP)
+	storeEVPNRemoteMacs()
+	return client
+}
+
+func TestEVPNLocalMacAging(t *testing.T) {
+	client := setupEVPNTest()
+	defer func() { EVPNClientIntf = nil }()
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+
+	refreshEVPNLocalMac(&EVPNMacInfo{Vni: 100, Mac: mac})
+	if client.macs[mac.String()] != VxlanCommandCreate {
+		t.Fatal("Local mac not advertised")
+	}
+
+	// a mac seen within the aging time is kept
+	ageEVPNLocalMacs()
+	if client.macs[mac.String()] != VxlanCommandCreate {
+		t.Fatal("Local mac withdrawn before the aging time")
+	}
+
+	evpnLocalMacDB[evpnMacKey{100, mac.String()}] = time.Now().Add(-evpnLocalMacAgingTime)
+	ageEVPNLocalMacs()
+	if client.macs[mac.String()] != VxlanCommandDelete {
+		t.Error("Local mac not withdrawn after the aging time")
+	}
+	if len(evpnLocalMacDB) != 0 {
+		t.Error("Aged out local mac still in the local mac db")
+	}
+}
+
+func TestEVPNRemoteMacForwarding(t *testing.T) {
+	client := setupEVPNTest()
+	defer func() { EVPNClientIntf = nil }()
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	broadcast, _ := net.ParseMAC("ff:ff:ff:ff:ff:ff")
+	vtepIp := net.ParseIP("10.1.1.1")
+	otherVtepIp := net.ParseIP("10.1.1.2")
+
+	// unknown unicast is flooded
+	if !isEVPNForwardedToVtep(100, mac, otherVtepIp) {
+		t.Error("Unknown unicast not flooded")
+	}
+
+	// the mac moves from local to the remote vtep
+	refreshEVPNLocalMac(&EVPNMacInfo{Vni: 100, Mac: mac})
+	CreateEVPNRemoteMac(&EVPNMacInfo{Vni: 100, Mac: mac, VtepIp: vtepIp})
+	if client.macs[mac.String()] != VxlanCommandDelete {
+		t.Error("Local mac not withdrawn when learned from a remote vtep")
+	}
+
+	if !isEVPNForwardedToVtep(100, mac, vtepIp) {
+		t.Error("Known unicast not sent to the vtep the mac is behind")
+	}
+	if isEVPNForwardedToVtep(100, mac, otherVtepIp) {
+		t.Error("Known unicast sent to another vtep")
+	}
+	if !isEVPNForwardedToVtep(200, mac, otherVtepIp) {
+		t.Error("Mac learned in another vni not flooded")
+	}
+	if !isEVPNForwardedToVtep(100, broadcast, otherVtepIp) {
+		t.Error("Broadcast not flooded")
+	}
+
+	DeleteEVPNRemoteMac(&EVPNMacInfo{Vni: 100, Mac: mac})
+	if !isEVPNForwardedToVtep(100, mac, otherVtepIp) {
+		t.Error("Unicast not flooded after the remote mac is deleted")
+	}
+}
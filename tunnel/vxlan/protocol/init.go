@@ -24,13 +24,20 @@
 // init.go
 package vxlan
 
-import ()
+import (
+	"net"
+	"time"
+)
 
 func init() {
 	// initialize the various db maps
 	vtepDB = make(map[VtepDbKey]*VtepDbEntry, 0)
 	vxlanDB = make(map[uint32]*vxlanDbEntry, 0)
 	vxlanVlanToVniDb = make(map[uint16]uint32, 0)
+	evpnRemoteMacDB = make(map[evpnMacKey]net.IP, 0)
+	evpnRemoteMacs.Store(make(map[evpnMacKey]net.IP, 0))
+	evpnLocalMacDB = make(map[evpnMacKey]time.Time, 0)
+	evpnRemoteVtepDB = make(map[evpnVtepKey]string, 0)
 
 	PortConfigMap = make(map[int32]*PortConfig, 0)
 	portDB = make(map[string]*VxlanPort, 0)
@@ -110,6 +110,9 @@ func NewVXLANServer(l *logging.Writer, paramspath string) *VXLANServer {
 				VxlanAccessPortVlanUpdate: make(chan VxlanAccessPortVlan, 0),
 				VxlanNextHopUpdate:        make(chan VxlanNextHopIp, 0),
 				VxlanPortCreate:           make(chan PortConfig, 0),
+				VxlanEVPNRemoteVtep:       make(chan EVPNVtepInfo, 0),
+				VxlanEVPNRemoteMac:        make(chan EVPNMacInfo, 0),
+				VxlanEVPNLocalMac:         make(chan EVPNMacInfo, 1000),
 			},
 		}
 
@@ -307,18 +307,33 @@ func (vtep *VtepDbEntry) createVtepSenderListener() error {
 	in := src.Packets()
 
 	go func(rxchan chan gopacket.Packet) {
+		// local macs seen by this listener to the last time they were
+		// reported to EVPN, only accessed by this go routine
+		localMacs := make(map[string]time.Time)
+		agingTicker := time.NewTicker(evpnLocalMacAgingInterval)
+		defer agingTicker.Stop()
 		for {
 			select {
 			// packets received from applications which should be sent out
 			case packet, ok := <-rxchan:
 				if ok {
 					if !vtep.filterPacket(packet) {
-						go vtep.encapAndDispatchPkt(packet)
+						vtep.learnLocalMac(packet, localMacs)
+						if vtep.forwardPacket(packet) {
+							go vtep.encapAndDispatchPkt(packet)
+						}
 					}
 				} else {
 					// channel closed
 					return
 				}
+			case <-agingTicker.C:
+				now := time.Now()
+				for mac, lastSeen := range localMacs {
+					if now.Sub(lastSeen) >= evpnLocalMacRefreshTime {
+						delete(localMacs, mac)
+					}
+				}
 			}
 		}
 	}(in)
@@ -326,6 +341,34 @@ func (vtep *VtepDbEntry) createVtepSenderListener() error {
 	return nil
 }
 
+// source macs of packets to be sent out are local to this vtep's vni, a mac
+// is reported to EVPN when it is first seen and then once per refresh time
+func (vtep *VtepDbEntry) learnLocalMac(packet gopacket.Packet, localMacs map[string]time.Time) {
+	if len(EVPNClientIntf) == 0 {
+		return
+	}
+	ethernetL := packet.Layer(layers.LayerTypeEthernet)
+	if ethernetL != nil {
+		mac := ethernetL.(*layers.Ethernet).SrcMAC
+		now := time.Now()
+		if lastSeen, ok := localMacs[string(mac)]; ok && now.Sub(lastSeen) < evpnLocalMacRefreshTime {
+			return
+		}
+		localMacs[string(mac)] = now
+		LearnEVPNLocalMac(vtep.Vni, mac)
+	}
+}
+
+// known unicast packets are only sent out of the vtep the destination mac
+// was learned against by EVPN
+func (vtep *VtepDbEntry) forwardPacket(packet gopacket.Packet) bool {
+	ethernetL := packet.Layer(layers.LayerTypeEthernet)
+	if ethernetL != nil {
+		return isEVPNForwardedToVtep(vtep.Vni, ethernetL.(*layers.Ethernet).DstMAC, vtep.DstIp)
+	}
+	return true
+}
+
 // do not process packets which contain the vtep src mac
 func (vtep *VtepDbEntry) filterPacket(packet gopacket.Packet) bool {
 
@@ -73,6 +73,9 @@ func CreateVxLAN(c *VxlanConfig) {
 		client.CreateVxlan(c)
 	}
 
+	// advertise the vni to the EVPN clients
+	notifyEVPNLocalVni(VxlanCommandCreate, c.VNI)
+
 	// lets find all the vteps which are in VtepStatusConfigPending state
 	// and initiate a hwConfig
 	for _, vtep := range GetVtepDB() {
@@ -95,6 +98,10 @@ func DeleteVxLAN(c *VxlanConfig) {
 		client.DeleteVxlan(c)
 	}
 
+	// withdraw the vni and its macs from the EVPN clients
+	flushEVPNLocalMacs(c.VNI)
+	notifyEVPNLocalVni(VxlanCommandDelete, c.VNI)
+
 	delete(vxlanDB, c.VNI)
 
 	logger.Info(fmt.Sprintln("DeleteVxLAN", c.VNI))
@@ -0,0 +1,59 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package vxlandCommonDefs
+
+const (
+	// vxland publishes the local VNIs and MACs for bgpd to advertise
+	PUB_SOCKET_EVPN_ADDR = "ipc:///tmp/vxland_evpn.ipc"
+	// bgpd publishes the remote VTEPs and MACs learned from EVPN routes
+	PUB_SOCKET_BGPD_EVPN_ADDR = "ipc:///tmp/bgpd_evpn.ipc"
+)
+
+const (
+	NOTIFY_EVPN_LOCAL_VNI_CREATE uint16 = iota + 1
+	NOTIFY_EVPN_LOCAL_VNI_DELETE
+	NOTIFY_EVPN_LOCAL_MAC_CREATE
+	NOTIFY_EVPN_LOCAL_MAC_DELETE
+	NOTIFY_EVPN_REMOTE_VTEP_CREATE
+	NOTIFY_EVPN_REMOTE_VTEP_DELETE
+	NOTIFY_EVPN_REMOTE_MAC_CREATE
+	NOTIFY_EVPN_REMOTE_MAC_DELETE
+)
+
+type VxlandNotifyMsg struct {
+	MsgType uint16
+	MsgBuf  []byte
+}
+
+type EVPNVniInfo struct {
+	Vni    uint32
+	VtepIp string
+}
+
+type EVPNMacInfo struct {
+	Vni    uint32
+	Mac    string
+	Ip     string
+	VtepIp string
+}